@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"time"
 
@@ -12,10 +13,12 @@ import (
 	"github.com/gabrielrondon/zapiki/internal/metrics"
 	"github.com/gabrielrondon/zapiki/internal/prover"
 	"github.com/gabrielrondon/zapiki/internal/prover/commitment"
+	"github.com/gabrielrondon/zapiki/internal/prover/pedersen"
 	"github.com/gabrielrondon/zapiki/internal/prover/snark/gnark"
 	"github.com/gabrielrondon/zapiki/internal/prover/stark"
 	"github.com/gabrielrondon/zapiki/internal/queue"
 	"github.com/gabrielrondon/zapiki/internal/service"
+	"github.com/gabrielrondon/zapiki/internal/storage/artifact"
 	"github.com/gabrielrondon/zapiki/internal/storage/postgres"
 	"github.com/gabrielrondon/zapiki/internal/storage/redis"
 	"github.com/joho/godotenv"
@@ -55,16 +58,50 @@ func main() {
 	templateRepo := postgres.NewTemplateRepository(pgStore)
 	auditRepo := postgres.NewAuditRepository(pgStore)
 	usageMetricRepo := postgres.NewUsageMetricRepository(pgStore)
+	sanctionsRepo := postgres.NewSanctionsRepository(pgStore)
+	ceremonyRepo := postgres.NewCeremonyRepository(pgStore)
 
 	// Initialize proof system factory
 	factory := prover.NewFactory()
 
+	// Initialize the Groth16/PLONK key cache, persisted when a directory is configured
+	var keyStore gnark.KeyStore
+	if cfg.Proof.KeyCacheDir != "" {
+		fileKeyStore, err := gnark.NewFileKeyStore(cfg.Proof.KeyCacheDir)
+		if err != nil {
+			log.Fatalf("Failed to create key store: %v", err)
+		}
+		keyStore = fileKeyStore
+		log.Printf("Persisting circuit keys to %s", cfg.Proof.KeyCacheDir)
+	}
+	keyCache := gnark.NewKeyCache(keyStore)
+
+	// Initialize the artifact store holding user circuit keys
+	artifactStore, err := artifact.New(&cfg.Storage)
+	if err != nil {
+		log.Fatalf("Failed to create artifact store: %v", err)
+	}
+	if s3Store, ok := artifactStore.(*artifact.S3Store); ok {
+		if err := s3Store.EnsureBucket(context.Background()); err != nil {
+			log.Fatalf("Failed to prepare artifact bucket: %v", err)
+		}
+	}
+	log.Printf("Storing circuit artifacts in %s backend", cfg.Storage.Backend)
+
 	// Register proof systems based on config
 	if cfg.Proof.EnableCommitment {
 		commitmentProver, err := commitment.NewCommitmentProver()
 		if err != nil {
 			log.Fatalf("Failed to create commitment prover: %v", err)
 		}
+		if cfg.Proof.CommitmentKeyStoreFile != "" {
+			keys, err := commitment.NewFileKeyStore(cfg.Proof.CommitmentKeyStoreFile, cfg.Proof.CommitmentKeyStorePassphrase)
+			if err != nil {
+				log.Fatalf("Failed to open commitment key store: %v", err)
+			}
+			keys.SetRotationInterval(cfg.Proof.CommitmentKeyRotation)
+			commitmentProver.SetKeyStore(keys)
+		}
 		if err := factory.Register(commitmentProver); err != nil {
 			log.Fatalf("Failed to register commitment prover: %v", err)
 		}
@@ -72,7 +109,19 @@ func main() {
 	}
 
 	if cfg.Proof.EnableGroth16 {
-		groth16Prover := gnark.NewGroth16Prover()
+		groth16Prover := gnark.NewGroth16ProverWithCache(keyCache)
+		groth16Prover.SetArtifactStore(artifactStore)
+		if cfg.Proof.Groth16Phase1File != "" {
+			phase1, err := gnark.NewFilePhase1(cfg.Proof.Groth16Phase1File, cfg.Proof.Groth16Phase1Checksum)
+			if err != nil {
+				log.Fatalf("Failed to load Groth16 phase-1 parameters: %v", err)
+			}
+			groth16Prover.SetPhase1(phase1)
+			log.Printf("Loaded Groth16 phase-1 parameters from %s", cfg.Proof.Groth16Phase1File)
+		} else if cfg.Server.Environment != "production" {
+			groth16Prover.SetPhase1(gnark.NewDevPhase1())
+			log.Println("WARNING: Groth16 ceremonies use generated phase-1 parameters (development only)")
+		}
 		if err := factory.Register(groth16Prover); err != nil {
 			log.Fatalf("Failed to register Groth16 prover: %v", err)
 		}
@@ -80,7 +129,18 @@ func main() {
 	}
 
 	if cfg.Proof.EnablePLONK {
-		plonkProver := gnark.NewPLONKProver()
+		plonkProver := gnark.NewPLONKProverWithCache(keyCache)
+		plonkProver.SetArtifactStore(artifactStore)
+		if cfg.Proof.PLONKSRSDir != "" {
+			srs, err := gnark.NewFileSRS(cfg.Proof.PLONKSRSDir, cfg.Proof.PLONKSRSChecksums)
+			if err != nil {
+				log.Fatalf("Failed to load PLONK SRS: %v", err)
+			}
+			plonkProver.SetSRS(srs)
+			log.Printf("Loaded PLONK SRS from %s", cfg.Proof.PLONKSRSDir)
+		} else {
+			log.Println("WARNING: PLONK setup uses a generated test SRS (development only)")
+		}
 		if err := factory.Register(plonkProver); err != nil {
 			log.Fatalf("Failed to register PLONK prover: %v", err)
 		}
@@ -89,12 +149,30 @@ func main() {
 
 	if cfg.Proof.EnableSTARK {
 		starkProver := stark.NewSTARKProver()
+		if err := starkProver.SetOptions(stark.Options{
+			BlowupFactor: cfg.Proof.STARKBlowupFactor,
+			NumQueries:   cfg.Proof.STARKNumQueries,
+			GrindingBits: cfg.Proof.STARKGrindingBits,
+		}); err != nil {
+			log.Fatalf("Failed to configure STARK prover: %v", err)
+		}
 		if err := factory.Register(starkProver); err != nil {
 			log.Fatalf("Failed to register STARK prover: %v", err)
 		}
 		log.Println("Registered STARK proof system")
 	}
 
+	if cfg.Proof.EnablePedersen {
+		pedersenProver, err := pedersen.NewPedersenProver()
+		if err != nil {
+			log.Fatalf("Failed to create Pedersen prover: %v", err)
+		}
+		if err := factory.Register(pedersenProver); err != nil {
+			log.Fatalf("Failed to register Pedersen prover: %v", err)
+		}
+		log.Println("Registered Pedersen proof system")
+	}
+
 	// Initialize queue client for async proof generation
 	queueClient := queue.NewClient(cfg.Redis.Addr(), cfg.Redis.Password)
 	log.Println("Initialized queue client")
@@ -105,11 +183,19 @@ func main() {
 		MaxInputBytes:       cfg.DataProtection.MaxInputBytes,
 		MaxPublicInputBytes: cfg.DataProtection.MaxPublicInputBytes,
 	})
+	proofService.SetCircuitRepository(circuitRepo)
 	verifyService := service.NewVerifyService(factory)
-	circuitService := service.NewCircuitService(factory, circuitRepo)
+	verifyService.SetCircuitRepository(circuitRepo)
+	circuitService := service.NewCircuitService(factory, circuitRepo, artifactStore)
+	circuitService.SetQueueClient(queueClient)
 	templateService := service.NewTemplateService(templateRepo, circuitRepo, proofService)
 	auditService := service.NewAuditService(auditRepo)
 	usageMetricService := service.NewUsageMetricService(usageMetricRepo)
+	sanctionsService := service.NewSanctionsService(sanctionsRepo, artifactStore)
+	ceremonyService := service.NewCeremonyService(factory, ceremonyRepo, circuitService, artifactStore)
+	commitmentService := service.NewCommitmentService(factory)
+	aggregationService := service.NewAggregationService(factory, proofRepo, circuitRepo)
+	aggregationService.SetQueueClient(queueClient)
 
 	// Initialize metrics
 	metricsCollector := metrics.New()
@@ -123,6 +209,9 @@ func main() {
 	systemHandler := handlers.NewSystemHandler(factory, pgStore, redisStore)
 	jobHandler := handlers.NewJobHandler(jobRepo)
 	circuitHandler := handlers.NewCircuitHandler(circuitService)
+	ceremonyHandler := handlers.NewCeremonyHandler(ceremonyService)
+	commitmentHandler := handlers.NewCommitmentHandler(commitmentService)
+	aggregationHandler := handlers.NewAggregationHandler(aggregationService)
 	templateHandler := handlers.NewTemplateHandler(templateService, auditService)
 	planHandler := handlers.NewPlanHandler(cfg.RateLimit)
 	auditHandler := handlers.NewAuditHandler(auditRepo)
@@ -130,6 +219,8 @@ func main() {
 	portalHandler := handlers.NewPortalHandler(usageMetricRepo, auditRepo, cfg.RateLimit)
 	batchHandler := handlers.NewBatchHandler(proofService)
 	amlHandler := handlers.NewAMLHandler(proofService)
+	amlHandler.SetSanctionsService(sanctionsService)
+	sanctionsHandler := handlers.NewSanctionsHandler(sanctionsService)
 	log.Println("Initialized AML/KYC compliance handlers")
 
 	// Initialize middleware
@@ -139,21 +230,25 @@ func main() {
 
 	// Setup router
 	router := routes.NewRouter(&routes.RouterConfig{
-		ProofHandler:    proofHandler,
-		VerifyHandler:   verifyHandler,
-		SystemHandler:   systemHandler,
-		JobHandler:      jobHandler,
-		CircuitHandler:  circuitHandler,
-		TemplateHandler: templateHandler,
-		PlanHandler:     planHandler,
-		AuditHandler:    auditHandler,
-		UsageHandler:    usageHandler,
-		PortalHandler:   portalHandler,
-		BatchHandler:    batchHandler,
-		AMLHandler:      amlHandler,
-		AuthMiddleware:  authMiddleware,
-		RateLimiter:     rateLimitMiddleware,
-		Metrics:         metricsCollector,
+		ProofHandler:       proofHandler,
+		VerifyHandler:      verifyHandler,
+		SystemHandler:      systemHandler,
+		JobHandler:         jobHandler,
+		CircuitHandler:     circuitHandler,
+		TemplateHandler:    templateHandler,
+		PlanHandler:        planHandler,
+		AuditHandler:       auditHandler,
+		UsageHandler:       usageHandler,
+		PortalHandler:      portalHandler,
+		BatchHandler:       batchHandler,
+		AMLHandler:         amlHandler,
+		SanctionsHandler:   sanctionsHandler,
+		CeremonyHandler:    ceremonyHandler,
+		CommitmentHandler:  commitmentHandler,
+		AggregationHandler: aggregationHandler,
+		AuthMiddleware:     authMiddleware,
+		RateLimiter:        rateLimitMiddleware,
+		Metrics:            metricsCollector,
 	})
 
 	// Create and start server
@@ -0,0 +1,52 @@
+// Command ceremony contributes to a Groth16 setup ceremony. It downloads the
+// latest state, adds fresh local randomness and uploads the result; the
+// randomness never leaves the machine and is discarded afterwards.
+//
+//	ceremony -url https://api.zapiki.io -key $ZAPIKI_API_KEY \
+//	    -ceremony <id> -participant "Alice (example.org)"
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/gabrielrondon/zapiki/internal/prover/snark/gnark"
+	"github.com/gabrielrondon/zapiki/pkg/client"
+)
+
+func main() {
+	baseURL := flag.String("url", "http://localhost:8080", "Zapiki API base URL")
+	apiKey := flag.String("key", os.Getenv("ZAPIKI_API_KEY"), "API key (default $ZAPIKI_API_KEY)")
+	ceremonyID := flag.String("ceremony", "", "ceremony ID")
+	participant := flag.String("participant", "", "public name recorded with the contribution")
+	flag.Parse()
+
+	if *ceremonyID == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx := context.Background()
+	c := client.NewClient(*baseURL, *apiKey).WithTimeout(10 * time.Minute)
+
+	state, err := c.GetCeremonyState(ctx, *ceremonyID)
+	if err != nil {
+		log.Fatalf("Failed to download ceremony state: %v", err)
+	}
+	log.Printf("Contributing to state %d (%s)", state.Index, state.Hash)
+
+	contribution, err := gnark.ContributePhase2(state.Data)
+	if err != nil {
+		log.Fatalf("Failed to contribute: %v", err)
+	}
+
+	result, err := c.Contribute(ctx, *ceremonyID, *participant, contribution)
+	if err != nil {
+		log.Fatalf("Failed to upload contribution: %v", err)
+	}
+
+	log.Printf("Contribution %d accepted: %s", result.Index, result.Hash)
+}
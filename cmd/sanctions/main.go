@@ -0,0 +1,115 @@
+// Command sanctions ingests sanctions list files as a new list version.
+//
+//	sanctions -list ofac -effective 2026-01-15 \
+//	    -source ofac_sdn_csv=sdn.csv -source ofac_alt_csv=alt.csv
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gabrielrondon/zapiki/internal/config"
+	"github.com/gabrielrondon/zapiki/internal/prover/snark/gnark/circuits"
+	"github.com/gabrielrondon/zapiki/internal/sanctions"
+	"github.com/gabrielrondon/zapiki/internal/service"
+	"github.com/gabrielrondon/zapiki/internal/storage/artifact"
+	"github.com/gabrielrondon/zapiki/internal/storage/postgres"
+	"github.com/joho/godotenv"
+)
+
+// sourceFlags collects repeated -source format=path flags
+type sourceFlags []string
+
+func (s *sourceFlags) String() string { return strings.Join(*s, ",") }
+
+func (s *sourceFlags) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+func main() {
+	var sources sourceFlags
+	listName := flag.String("list", "", "list name, e.g. ofac, un or eu")
+	effective := flag.String("effective", time.Now().UTC().Format("2006-01-02"), "effective date (YYYY-MM-DD)")
+	depth := flag.Int("depth", circuits.DefaultTreeDepth, "Merkle tree depth")
+	hash := flag.String("hash", string(circuits.DefaultHash), "tree hash function (mimc or poseidon2)")
+	flag.Var(&sources, "source", fmt.Sprintf("format=path of a list file (repeatable); formats: %v", sanctions.Formats))
+	flag.Parse()
+
+	if *listName == "" || len(sources) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	effectiveDate, err := time.Parse("2006-01-02", *effective)
+	if err != nil {
+		log.Fatalf("Invalid effective date: %v", err)
+	}
+
+	// Load .env file if it exists
+	_ = godotenv.Load()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	pgStore, err := postgres.New(&cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to connect to PostgreSQL: %v", err)
+	}
+	defer pgStore.Close()
+
+	artifactStore, err := artifact.New(&cfg.Storage)
+	if err != nil {
+		log.Fatalf("Failed to create artifact store: %v", err)
+	}
+	if s3Store, ok := artifactStore.(*artifact.S3Store); ok {
+		if err := s3Store.EnsureBucket(context.Background()); err != nil {
+			log.Fatalf("Failed to prepare artifact bucket: %v", err)
+		}
+	}
+
+	req := &service.IngestSanctionsRequest{
+		ListName:      *listName,
+		EffectiveDate: effectiveDate,
+		TreeDepth:     *depth,
+		Hash:          circuits.HashFunc(*hash),
+	}
+
+	for _, source := range sources {
+		format, path, ok := strings.Cut(source, "=")
+		if !ok {
+			log.Fatalf("Invalid source %q: expected format=path", source)
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			log.Fatalf("Failed to open %s: %v", path, err)
+		}
+		defer file.Close()
+
+		req.Sources = append(req.Sources, service.SanctionsSource{
+			Format: sanctions.Format(format),
+			Name:   filepath.Base(path),
+			Reader: file,
+		})
+	}
+
+	sanctionsService := service.NewSanctionsService(postgres.NewSanctionsRepository(pgStore), artifactStore)
+	version, err := sanctionsService.Ingest(context.Background(), req)
+	if err != nil {
+		log.Fatalf("Failed to ingest sanctions list: %v", err)
+	}
+
+	log.Printf("Ingested %s version %d (%s): %d entries, %d identifiers, effective %s",
+		version.ListName, version.Version, version.ID, version.EntryCount,
+		version.IdentifierCount, version.EffectiveDate.Format("2006-01-02"))
+	log.Printf("Root: %s", version.Root)
+}
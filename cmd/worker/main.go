@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"os/signal"
@@ -9,9 +10,12 @@ import (
 	"github.com/gabrielrondon/zapiki/internal/config"
 	"github.com/gabrielrondon/zapiki/internal/prover"
 	"github.com/gabrielrondon/zapiki/internal/prover/commitment"
+	"github.com/gabrielrondon/zapiki/internal/prover/pedersen"
 	"github.com/gabrielrondon/zapiki/internal/prover/snark/gnark"
 	"github.com/gabrielrondon/zapiki/internal/prover/stark"
 	"github.com/gabrielrondon/zapiki/internal/queue"
+	"github.com/gabrielrondon/zapiki/internal/service"
+	"github.com/gabrielrondon/zapiki/internal/storage/artifact"
 	"github.com/gabrielrondon/zapiki/internal/storage/postgres"
 	"github.com/gabrielrondon/zapiki/internal/worker"
 	"github.com/hibiken/asynq"
@@ -39,16 +43,49 @@ func main() {
 	// Initialize repositories
 	proofRepo := postgres.NewProofRepository(pgStore)
 	jobRepo := postgres.NewJobRepository(pgStore)
+	circuitRepo := postgres.NewCircuitRepository(pgStore)
 
 	// Initialize proof system factory
 	factory := prover.NewFactory()
 
+	// Initialize the Groth16/PLONK key cache, persisted when a directory is configured
+	var keyStore gnark.KeyStore
+	if cfg.Proof.KeyCacheDir != "" {
+		fileKeyStore, err := gnark.NewFileKeyStore(cfg.Proof.KeyCacheDir)
+		if err != nil {
+			log.Fatalf("Failed to create key store: %v", err)
+		}
+		keyStore = fileKeyStore
+		log.Printf("Persisting circuit keys to %s", cfg.Proof.KeyCacheDir)
+	}
+	keyCache := gnark.NewKeyCache(keyStore)
+
+	// Initialize the artifact store holding user circuit keys
+	artifactStore, err := artifact.New(&cfg.Storage)
+	if err != nil {
+		log.Fatalf("Failed to create artifact store: %v", err)
+	}
+	if s3Store, ok := artifactStore.(*artifact.S3Store); ok {
+		if err := s3Store.EnsureBucket(context.Background()); err != nil {
+			log.Fatalf("Failed to prepare artifact bucket: %v", err)
+		}
+	}
+	log.Printf("Storing circuit artifacts in %s backend", cfg.Storage.Backend)
+
 	// Register proof systems based on config
 	if cfg.Proof.EnableCommitment {
 		commitmentProver, err := commitment.NewCommitmentProver()
 		if err != nil {
 			log.Fatalf("Failed to create commitment prover: %v", err)
 		}
+		if cfg.Proof.CommitmentKeyStoreFile != "" {
+			keys, err := commitment.NewFileKeyStore(cfg.Proof.CommitmentKeyStoreFile, cfg.Proof.CommitmentKeyStorePassphrase)
+			if err != nil {
+				log.Fatalf("Failed to open commitment key store: %v", err)
+			}
+			keys.SetRotationInterval(cfg.Proof.CommitmentKeyRotation)
+			commitmentProver.SetKeyStore(keys)
+		}
 		if err := factory.Register(commitmentProver); err != nil {
 			log.Fatalf("Failed to register commitment prover: %v", err)
 		}
@@ -56,35 +93,85 @@ func main() {
 	}
 
 	if cfg.Proof.EnableGroth16 {
-		groth16Prover := gnark.NewGroth16Prover()
+		groth16Prover := gnark.NewGroth16ProverWithCache(keyCache)
+		groth16Prover.SetArtifactStore(artifactStore)
 		if err := factory.Register(groth16Prover); err != nil {
 			log.Fatalf("Failed to register Groth16 prover: %v", err)
 		}
 		log.Println("Registered Groth16 proof system")
+
+		if cfg.Proof.WarmUpKeys {
+			if err := groth16Prover.WarmUp(context.Background(), gnark.BuiltinCircuits); err != nil {
+				log.Printf("Groth16 key warm-up failed: %v", err)
+			} else {
+				log.Printf("Warmed up Groth16 keys for %d circuits", len(gnark.BuiltinCircuits))
+			}
+		}
 	}
 
 	if cfg.Proof.EnablePLONK {
-		plonkProver := gnark.NewPLONKProver()
+		plonkProver := gnark.NewPLONKProverWithCache(keyCache)
+		plonkProver.SetArtifactStore(artifactStore)
+		if cfg.Proof.PLONKSRSDir != "" {
+			srs, err := gnark.NewFileSRS(cfg.Proof.PLONKSRSDir, cfg.Proof.PLONKSRSChecksums)
+			if err != nil {
+				log.Fatalf("Failed to load PLONK SRS: %v", err)
+			}
+			plonkProver.SetSRS(srs)
+			log.Printf("Loaded PLONK SRS from %s", cfg.Proof.PLONKSRSDir)
+		} else {
+			log.Println("WARNING: PLONK setup uses a generated test SRS (development only)")
+		}
 		if err := factory.Register(plonkProver); err != nil {
 			log.Fatalf("Failed to register PLONK prover: %v", err)
 		}
 		log.Println("Registered PLONK proof system")
+
+		if cfg.Proof.WarmUpKeys {
+			if err := plonkProver.WarmUp(context.Background(), gnark.BuiltinCircuits); err != nil {
+				log.Printf("PLONK key warm-up failed: %v", err)
+			} else {
+				log.Printf("Warmed up PLONK keys for %d circuits", len(gnark.BuiltinCircuits))
+			}
+		}
 	}
 
 	if cfg.Proof.EnableSTARK {
 		starkProver := stark.NewSTARKProver()
+		if err := starkProver.SetOptions(stark.Options{
+			BlowupFactor: cfg.Proof.STARKBlowupFactor,
+			NumQueries:   cfg.Proof.STARKNumQueries,
+			GrindingBits: cfg.Proof.STARKGrindingBits,
+		}); err != nil {
+			log.Fatalf("Failed to configure STARK prover: %v", err)
+		}
 		if err := factory.Register(starkProver); err != nil {
 			log.Fatalf("Failed to register STARK prover: %v", err)
 		}
 		log.Println("Registered STARK proof system")
 	}
 
+	if cfg.Proof.EnablePedersen {
+		pedersenProver, err := pedersen.NewPedersenProver()
+		if err != nil {
+			log.Fatalf("Failed to create Pedersen prover: %v", err)
+		}
+		if err := factory.Register(pedersenProver); err != nil {
+			log.Fatalf("Failed to register Pedersen prover: %v", err)
+		}
+		log.Println("Registered Pedersen proof system")
+	}
+
 	// Initialize worker processor
-	processor := worker.NewProcessor(factory, proofRepo, jobRepo)
+	processor := worker.NewProcessor(factory, proofRepo, jobRepo, circuitRepo)
+	setupProcessor := worker.NewSetupProcessor(service.NewCircuitService(factory, circuitRepo, artifactStore))
+	aggregationProcessor := worker.NewAggregationProcessor(service.NewAggregationService(factory, proofRepo, circuitRepo))
 
 	// Create asynq mux and register handlers
 	mux := asynq.NewServeMux()
 	mux.HandleFunc(queue.TypeProofGeneration, processor.HandleProofGeneration)
+	mux.HandleFunc(queue.TypeCircuitSetup, setupProcessor.HandleCircuitSetup)
+	mux.HandleFunc(queue.TypeProofAggregation, aggregationProcessor.HandleProofAggregation)
 
 	// Create and start queue server
 	redisAddr := cfg.Redis.Addr()
@@ -0,0 +1,9 @@
+-- Circuit setup status tracking, for databases created before it was added
+-- to schema.sql. Existing circuits were set up synchronously, so are ready.
+
+ALTER TABLE circuits ADD COLUMN IF NOT EXISTS setup_status VARCHAR(20) NOT NULL DEFAULT 'ready';
+ALTER TABLE circuits ADD COLUMN IF NOT EXISTS setup_error TEXT NOT NULL DEFAULT '';
+ALTER TABLE circuits ADD COLUMN IF NOT EXISTS setup_completed_at TIMESTAMP;
+ALTER TABLE circuits ADD COLUMN IF NOT EXISTS setup_metadata JSONB;
+
+CREATE INDEX IF NOT EXISTS idx_circuits_setup_status ON circuits(setup_status);
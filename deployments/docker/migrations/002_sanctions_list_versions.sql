@@ -0,0 +1,25 @@
+-- Sanctions list versions, and the version each sanctions proof was made
+-- against, for databases created before they were added to schema.sql
+
+CREATE TABLE IF NOT EXISTS sanctions_list_versions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    list_name VARCHAR(100) NOT NULL,
+    version INTEGER NOT NULL,
+    root TEXT NOT NULL,
+    curve VARCHAR(20) NOT NULL,
+    hash_func VARCHAR(20) NOT NULL,
+    tree_depth INTEGER NOT NULL,
+    entry_count INTEGER NOT NULL,
+    identifier_count INTEGER NOT NULL,
+    sources TEXT[] NOT NULL DEFAULT '{}',
+    identifiers_url TEXT NOT NULL,
+    effective_date DATE NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (list_name, version)
+);
+
+CREATE INDEX IF NOT EXISTS idx_sanctions_list_versions_root ON sanctions_list_versions(root);
+CREATE INDEX IF NOT EXISTS idx_sanctions_list_versions_effective ON sanctions_list_versions(list_name, effective_date);
+
+ALTER TABLE proofs ADD COLUMN IF NOT EXISTS sanctions_list_version_id UUID
+    REFERENCES sanctions_list_versions(id) ON DELETE SET NULL;
@@ -0,0 +1,29 @@
+-- Groth16 phase-2 setup ceremonies, for databases created before they were
+-- added to schema.sql
+
+CREATE TABLE IF NOT EXISTS setup_ceremonies (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    circuit_id UUID NOT NULL UNIQUE REFERENCES circuits(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'open',
+    phase1 TEXT NOT NULL,
+    initial_hash TEXT NOT NULL,
+    initial_state_url TEXT NOT NULL,
+    contribution_count INTEGER NOT NULL DEFAULT 0,
+    current_hash TEXT NOT NULL,
+    beacon TEXT NOT NULL DEFAULT '',
+    verification_key_hash TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    finalized_at TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS ceremony_contributions (
+    ceremony_id UUID NOT NULL REFERENCES setup_ceremonies(id) ON DELETE CASCADE,
+    contribution_index INTEGER NOT NULL,
+    user_id UUID NOT NULL,
+    participant VARCHAR(255) NOT NULL DEFAULT '',
+    hash TEXT NOT NULL,
+    state_url TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (ceremony_id, contribution_index)
+);
@@ -0,0 +1,4 @@
+-- The statements covered by aggregated proofs, for databases created before
+-- the column was added to schema.sql
+
+ALTER TABLE proofs ADD COLUMN IF NOT EXISTS aggregation JSONB;
@@ -0,0 +1,4 @@
+-- The envelope stored with every completed proof, for databases created
+-- before the column was added to schema.sql
+
+ALTER TABLE proofs ADD COLUMN IF NOT EXISTS envelope JSONB;
@@ -44,6 +44,10 @@ CREATE TABLE circuits (
     circuit_definition JSONB NOT NULL,
     proving_key_url TEXT,
     verification_key_url TEXT,
+    setup_status VARCHAR(20) NOT NULL DEFAULT 'ready',
+    setup_error TEXT NOT NULL DEFAULT '',
+    setup_completed_at TIMESTAMP,
+    setup_metadata JSONB,
     is_public BOOLEAN NOT NULL DEFAULT false,
     created_at TIMESTAMP NOT NULL DEFAULT NOW(),
     updated_at TIMESTAMP NOT NULL DEFAULT NOW()
@@ -52,6 +56,57 @@ CREATE TABLE circuits (
 CREATE INDEX idx_circuits_user_id ON circuits(user_id);
 CREATE INDEX idx_circuits_proof_system ON circuits(proof_system);
 CREATE INDEX idx_circuits_is_public ON circuits(is_public);
+CREATE INDEX idx_circuits_setup_status ON circuits(setup_status);
+
+-- Sanctions list versions (sorted Merkle trees of hashed identifiers)
+CREATE TABLE sanctions_list_versions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    list_name VARCHAR(100) NOT NULL,
+    version INTEGER NOT NULL,
+    root TEXT NOT NULL,
+    curve VARCHAR(20) NOT NULL,
+    hash_func VARCHAR(20) NOT NULL,
+    tree_depth INTEGER NOT NULL,
+    entry_count INTEGER NOT NULL,
+    identifier_count INTEGER NOT NULL,
+    sources TEXT[] NOT NULL DEFAULT '{}',
+    identifiers_url TEXT NOT NULL,
+    effective_date DATE NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (list_name, version)
+);
+
+CREATE INDEX idx_sanctions_list_versions_root ON sanctions_list_versions(root);
+CREATE INDEX idx_sanctions_list_versions_effective ON sanctions_list_versions(list_name, effective_date);
+
+-- Groth16 phase-2 setup ceremonies (one per circuit)
+CREATE TABLE setup_ceremonies (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    circuit_id UUID NOT NULL UNIQUE REFERENCES circuits(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'open',
+    phase1 TEXT NOT NULL,
+    initial_hash TEXT NOT NULL,
+    initial_state_url TEXT NOT NULL,
+    contribution_count INTEGER NOT NULL DEFAULT 0,
+    current_hash TEXT NOT NULL,
+    beacon TEXT NOT NULL DEFAULT '',
+    verification_key_hash TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    finalized_at TIMESTAMP
+);
+
+-- Verified contributions, in order; participants are kept for the transcript
+CREATE TABLE ceremony_contributions (
+    ceremony_id UUID NOT NULL REFERENCES setup_ceremonies(id) ON DELETE CASCADE,
+    contribution_index INTEGER NOT NULL,
+    user_id UUID NOT NULL,
+    participant VARCHAR(255) NOT NULL DEFAULT '',
+    hash TEXT NOT NULL,
+    state_url TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (ceremony_id, contribution_index)
+);
 
 -- Proofs table
 CREATE TABLE proofs (
@@ -67,6 +122,9 @@ CREATE TABLE proofs (
     proof_url TEXT,
     error_message TEXT,
     generation_time_ms BIGINT,
+    sanctions_list_version_id UUID REFERENCES sanctions_list_versions(id) ON DELETE SET NULL,
+    aggregation JSONB,
+    envelope JSONB,
     created_at TIMESTAMP NOT NULL DEFAULT NOW(),
     completed_at TIMESTAMP
 );
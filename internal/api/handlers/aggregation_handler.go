@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gabrielrondon/zapiki/internal/api/middleware"
+	"github.com/gabrielrondon/zapiki/internal/prover"
+	"github.com/gabrielrondon/zapiki/internal/service"
+)
+
+// AggregationHandler handles recursive proof aggregation requests
+type AggregationHandler struct {
+	aggregationService *service.AggregationService
+}
+
+// NewAggregationHandler creates a new aggregation handler
+func NewAggregationHandler(aggregationService *service.AggregationService) *AggregationHandler {
+	return &AggregationHandler{
+		aggregationService: aggregationService,
+	}
+}
+
+// Aggregate handles POST /api/v1/proofs/aggregate
+func (h *AggregationHandler) Aggregate(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req service.AggregateProofsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	req.UserID = userID
+
+	if len(req.ProofIDs) == 0 {
+		writeError(w, http.StatusBadRequest, "proof_ids are required")
+		return
+	}
+
+	resp, err := h.aggregationService.Aggregate(r.Context(), &req)
+	if err != nil {
+		writeError(w, aggregationErrorStatus(err), err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// aggregationErrorStatus maps aggregation service errors to an HTTP status
+func aggregationErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, prover.ErrInvalidProofAggregation):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrAggregationUnsupported):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -8,11 +8,13 @@ import (
 	"github.com/gabrielrondon/zapiki/internal/api/middleware"
 	"github.com/gabrielrondon/zapiki/internal/models"
 	"github.com/gabrielrondon/zapiki/internal/service"
+	"github.com/google/uuid"
 )
 
 // AMLHandler handles AML/KYC compliance proof endpoints
 type AMLHandler struct {
-	proofService *service.ProofService
+	proofService     *service.ProofService
+	sanctionsService *service.SanctionsService
 }
 
 // NewAMLHandler creates a new AML handler
@@ -22,6 +24,13 @@ func NewAMLHandler(proofService *service.ProofService) *AMLHandler {
 	}
 }
 
+// SetSanctionsService ties sanctions checks to ingested list versions:
+// the root must belong to a known version, missing witness fields are
+// filled in, and the proof records the version
+func (h *AMLHandler) SetSanctionsService(sanctionsService *service.SanctionsService) {
+	h.sanctionsService = sanctionsService
+}
+
 // AgeVerificationRequest contains the request for age verification
 type AgeVerificationRequest struct {
 	MinimumAge  int    `json:"minimum_age"`
@@ -91,7 +100,7 @@ func (h *AMLHandler) AgeVerification(w http.ResponseWriter, r *http.Request) {
 	// Generate proof
 	resp, err := h.proofService.Generate(r.Context(), proofReq)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeError(w, proofErrorStatus(err), err.Error())
 		return
 	}
 
@@ -99,10 +108,21 @@ func (h *AMLHandler) AgeVerification(w http.ResponseWriter, r *http.Request) {
 }
 
 // SanctionsCheckRequest contains the request for sanctions check
+// along with the non-membership witness for the sorted sanctions tree
 type SanctionsCheckRequest struct {
-	SanctionsListRoot string `json:"sanctions_list_root"`
-	CurrentTimestamp  int64  `json:"current_timestamp"`
-	UserIdentifier    string `json:"user_identifier"` // Hashed user ID
+	ListVersionID     *uuid.UUID `json:"list_version_id,omitempty"` // Ingested list version
+	SanctionsListRoot string     `json:"sanctions_list_root"`
+	CurrentTimestamp  int64      `json:"current_timestamp"`
+	UserIdentifier    string     `json:"user_identifier"`           // Hashed user ID
+	IdentifierSalt    string     `json:"identifier_salt,omitempty"` // Random if omitted
+
+	// Adjacent leaves around user_identifier and their Merkle paths
+	LowLeaf  string   `json:"low_leaf"`
+	HighLeaf string   `json:"high_leaf"`
+	LowIndex uint64   `json:"low_index"`
+	LowPath  []string `json:"low_path"`
+	HighPath []string `json:"high_path"`
+	Hash     string   `json:"hash,omitempty"` // mimc (default) or poseidon2
 }
 
 // SanctionsCheck generates a proof that user is NOT on sanctions list
@@ -119,23 +139,78 @@ func (h *AMLHandler) SanctionsCheck(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.UserIdentifier == "" {
+		writeError(w, http.StatusBadRequest, "user_identifier is required")
+		return
+	}
+
+	var listVersionID *uuid.UUID
+	if h.sanctionsService != nil {
+		if req.ListVersionID == nil && req.SanctionsListRoot == "" {
+			writeError(w, http.StatusBadRequest, "list_version_id or sanctions_list_root is required")
+			return
+		}
+
+		version, err := h.sanctionsService.ResolveVersion(r.Context(), req.ListVersionID, req.SanctionsListRoot)
+		if err != nil {
+			writeError(w, sanctionsErrorStatus(err), err.Error())
+			return
+		}
+		listVersionID = &version.ID
+
+		// Fill in the witness when the caller only sent the identifier
+		if req.LowLeaf == "" {
+			witness, err := h.sanctionsService.Witness(r.Context(), version, req.UserIdentifier, req.IdentifierSalt)
+			if err != nil {
+				writeError(w, sanctionsErrorStatus(err), err.Error())
+				return
+			}
+			req.applyWitness(witness.Witness)
+		}
+		req.SanctionsListRoot = version.Root
+		req.Hash = version.HashFunc
+	}
+
 	if req.SanctionsListRoot == "" {
 		writeError(w, http.StatusBadRequest, "sanctions_list_root is required")
 		return
 	}
-	if req.UserIdentifier == "" {
-		writeError(w, http.StatusBadRequest, "user_identifier is required")
+	if req.LowLeaf == "" || req.HighLeaf == "" {
+		writeError(w, http.StatusBadRequest, "low_leaf and high_leaf are required")
+		return
+	}
+	if len(req.LowPath) == 0 || len(req.LowPath) != len(req.HighPath) {
+		writeError(w, http.StatusBadRequest, "low_path and high_path must be non-empty and of equal length")
 		return
 	}
 	if req.CurrentTimestamp == 0 {
 		req.CurrentTimestamp = time.Now().Unix()
 	}
 
-	dataValue, err := json.Marshal(map[string]interface{}{
-		"sanctions_list_root": req.SanctionsListRoot,
-		"current_timestamp":   req.CurrentTimestamp,
-		"user_identifier":     req.UserIdentifier,
-	})
+	// Bind the proof to the identifier through a public commitment
+	commitment, salt, err := service.CommitSanctionsIdentifier(req.UserIdentifier, req.IdentifierSalt, req.Hash)
+	if err != nil {
+		writeError(w, sanctionsErrorStatus(err), err.Error())
+		return
+	}
+
+	data := map[string]interface{}{
+		"sanctions_list_root":   req.SanctionsListRoot,
+		"current_timestamp":     req.CurrentTimestamp,
+		"identifier_commitment": commitment,
+		"user_identifier":       req.UserIdentifier,
+		"identifier_salt":       salt,
+		"low_leaf":              req.LowLeaf,
+		"high_leaf":             req.HighLeaf,
+		"low_index":             req.LowIndex,
+		"low_path":              req.LowPath,
+		"high_path":             req.HighPath,
+	}
+	if req.Hash != "" {
+		data["hash"] = req.Hash
+	}
+
+	dataValue, err := json.Marshal(data)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "Failed to marshal data")
 		return
@@ -148,15 +223,42 @@ func (h *AMLHandler) SanctionsCheck(w http.ResponseWriter, r *http.Request) {
 			Type:  models.DataTypeJSON,
 			Value: dataValue,
 		},
+		Options: &models.ProofOptions{
+			SanctionsListVersionID: listVersionID,
+		},
 	}
 
 	resp, err := h.proofService.Generate(r.Context(), proofReq)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeError(w, proofErrorStatus(err), err.Error())
 		return
 	}
 
-	writeJSON(w, http.StatusAccepted, resp)
+	writeJSON(w, http.StatusAccepted, &SanctionsCheckResponse{
+		GenerateProofResponse: resp,
+		IdentifierCommitment:  commitment,
+		IdentifierSalt:        salt,
+	})
+}
+
+// SanctionsCheckResponse is a sanctions proof with the commitment binding it
+// to the user. The user reveals user_identifier and identifier_salt to a
+// relying party, which checks that they hash to identifier_commitment, the
+// proof's third public input.
+type SanctionsCheckResponse struct {
+	*service.GenerateProofResponse
+	IdentifierCommitment string `json:"identifier_commitment"`
+	IdentifierSalt       string `json:"identifier_salt"`
+}
+
+// applyWitness copies a served non-membership witness into the request
+func (req *SanctionsCheckRequest) applyWitness(witness map[string]interface{}) {
+	req.IdentifierSalt, _ = witness["identifier_salt"].(string)
+	req.LowLeaf, _ = witness["low_leaf"].(string)
+	req.HighLeaf, _ = witness["high_leaf"].(string)
+	req.LowIndex, _ = witness["low_index"].(uint64)
+	req.LowPath, _ = witness["low_path"].([]string)
+	req.HighPath, _ = witness["high_path"].([]string)
 }
 
 // ResidencyProofRequest contains the request for residency proof
@@ -215,7 +317,7 @@ func (h *AMLHandler) ResidencyProof(w http.ResponseWriter, r *http.Request) {
 
 	resp, err := h.proofService.Generate(r.Context(), proofReq)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeError(w, proofErrorStatus(err), err.Error())
 		return
 	}
 
@@ -278,7 +380,7 @@ func (h *AMLHandler) IncomeVerification(w http.ResponseWriter, r *http.Request)
 
 	resp, err := h.proofService.Generate(r.Context(), proofReq)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeError(w, proofErrorStatus(err), err.Error())
 		return
 	}
 
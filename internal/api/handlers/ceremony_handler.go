@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gabrielrondon/zapiki/internal/api/middleware"
+	"github.com/gabrielrondon/zapiki/internal/prover"
+	"github.com/gabrielrondon/zapiki/internal/service"
+	"github.com/gabrielrondon/zapiki/internal/storage/postgres"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// maxCeremonyStateSize bounds an uploaded contribution
+const maxCeremonyStateSize = 512 << 20
+
+// CeremonyHandler handles setup ceremony requests
+type CeremonyHandler struct {
+	ceremonyService *service.CeremonyService
+}
+
+// NewCeremonyHandler creates a new ceremony handler
+func NewCeremonyHandler(ceremonyService *service.CeremonyService) *CeremonyHandler {
+	return &CeremonyHandler{
+		ceremonyService: ceremonyService,
+	}
+}
+
+// Open handles POST /api/v1/circuits/{id}/ceremony
+func (h *CeremonyHandler) Open(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	circuitID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid circuit ID")
+		return
+	}
+
+	ceremony, err := h.ceremonyService.Open(r.Context(), circuitID, userID)
+	if err != nil {
+		writeError(w, ceremonyErrorStatus(err), err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, ceremony)
+}
+
+// Get handles GET /api/v1/ceremonies/{id}
+func (h *CeremonyHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, ceremonyID, ok := ceremonyRequest(w, r)
+	if !ok {
+		return
+	}
+
+	transcript, err := h.ceremonyService.Transcript(r.Context(), ceremonyID, userID)
+	if err != nil {
+		writeError(w, ceremonyErrorStatus(err), err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, transcript)
+}
+
+// State handles GET /api/v1/ceremonies/{id}/state
+func (h *CeremonyHandler) State(w http.ResponseWriter, r *http.Request) {
+	userID, ceremonyID, ok := ceremonyRequest(w, r)
+	if !ok {
+		return
+	}
+
+	state, index, err := h.ceremonyService.State(r.Context(), ceremonyID, userID)
+	if err != nil {
+		writeError(w, ceremonyErrorStatus(err), err.Error())
+		return
+	}
+
+	w.Header().Set("X-Ceremony-Index", strconv.Itoa(index))
+	writeState(w, state)
+}
+
+// ContributionState handles GET /api/v1/ceremonies/{id}/contributions/{index}
+func (h *CeremonyHandler) ContributionState(w http.ResponseWriter, r *http.Request) {
+	userID, ceremonyID, ok := ceremonyRequest(w, r)
+	if !ok {
+		return
+	}
+
+	index, err := strconv.Atoi(chi.URLParam(r, "index"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid contribution index")
+		return
+	}
+
+	state, err := h.ceremonyService.ContributionState(r.Context(), ceremonyID, userID, index)
+	if err != nil {
+		writeError(w, ceremonyErrorStatus(err), err.Error())
+		return
+	}
+
+	writeState(w, state)
+}
+
+// Contribute handles POST /api/v1/ceremonies/{id}/contributions. The body is
+// the serialized state produced by contributing to the latest state.
+func (h *CeremonyHandler) Contribute(w http.ResponseWriter, r *http.Request) {
+	userID, ceremonyID, ok := ceremonyRequest(w, r)
+	if !ok {
+		return
+	}
+
+	contribution, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxCeremonyStateSize))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, "Contribution is too large")
+		return
+	}
+	if len(contribution) == 0 {
+		writeError(w, http.StatusBadRequest, "Request body must contain the contributed state")
+		return
+	}
+
+	participant := r.URL.Query().Get("participant")
+	if len(participant) > 255 {
+		writeError(w, http.StatusBadRequest, "participant must be at most 255 characters")
+		return
+	}
+
+	record, err := h.ceremonyService.Contribute(r.Context(), ceremonyID, userID, participant, contribution)
+	if err != nil {
+		writeError(w, ceremonyErrorStatus(err), err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, record)
+}
+
+// FinalizeCeremonyRequest contains the public beacon that seals a ceremony
+type FinalizeCeremonyRequest struct {
+	// Beacon is hex-encoded public randomness published after the last
+	// contribution, e.g. a drand round
+	Beacon string `json:"beacon"`
+}
+
+// Finalize handles POST /api/v1/ceremonies/{id}/finalize
+func (h *CeremonyHandler) Finalize(w http.ResponseWriter, r *http.Request) {
+	userID, ceremonyID, ok := ceremonyRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req FinalizeCeremonyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	beacon, err := hex.DecodeString(req.Beacon)
+	if err != nil || len(beacon) == 0 {
+		writeError(w, http.StatusBadRequest, "beacon must be non-empty hex")
+		return
+	}
+
+	ceremony, err := h.ceremonyService.Finalize(r.Context(), ceremonyID, userID, beacon)
+	if err != nil {
+		writeError(w, ceremonyErrorStatus(err), err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ceremony)
+}
+
+// ceremonyRequest parses the caller and ceremony ID, writing an error response on failure
+func ceremonyRequest(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	ceremonyID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid ceremony ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return userID, ceremonyID, true
+}
+
+// writeState writes a serialized ceremony state with its hash, which the
+// next contribution commits to
+func writeState(w http.ResponseWriter, state []byte) {
+	sum := sha256.Sum256(state)
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("X-Ceremony-State-Hash", hex.EncodeToString(sum[:]))
+	w.WriteHeader(http.StatusOK)
+	w.Write(state)
+}
+
+// ceremonyErrorStatus maps ceremony errors to an HTTP status
+func ceremonyErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, postgres.ErrCeremonyNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrNotCeremonyCoordinator):
+		return http.StatusForbidden
+	case errors.Is(err, prover.ErrInvalidContribution):
+		return http.StatusBadRequest
+	case errors.Is(err, prover.ErrCeremonyUnsupported):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, postgres.ErrStaleCeremonyState), errors.Is(err, service.ErrCeremonyClosed),
+		errors.Is(err, service.ErrCeremonyExists), errors.Is(err, service.ErrNoContributions):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -1,15 +1,26 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"strconv"
 
 	"github.com/gabrielrondon/zapiki/internal/api/middleware"
+	"github.com/gabrielrondon/zapiki/internal/models"
+	"github.com/gabrielrondon/zapiki/internal/prover"
 	"github.com/gabrielrondon/zapiki/internal/service"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 )
 
+// maxCircuitUploadSize bounds the files uploaded to import a circuit
+const maxCircuitUploadSize = 256 << 20
+
 // CircuitHandler handles circuit-related requests
 type CircuitHandler struct {
 	circuitService *service.CircuitService
@@ -31,9 +42,14 @@ func (h *CircuitHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse request
+	// Parse request, as JSON or as a multipart upload of circuit files
 	var req service.CreateCircuitRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		if err := readCircuitUpload(w, r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
@@ -48,19 +64,90 @@ func (h *CircuitHandler) Create(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "proof_system is required")
 		return
 	}
-	if req.CircuitDefinition == nil {
+	if req.CircuitDefinition == nil && len(req.Files) == 0 {
 		writeError(w, http.StatusBadRequest, "circuit_definition is required")
 		return
 	}
 
 	// Create circuit
 	resp, err := h.circuitService.Create(r.Context(), &req)
+	if errors.Is(err, prover.ErrInvalidCircuit) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if errors.Is(err, prover.ErrCeremonyUnsupported) || errors.Is(err, prover.ErrImportUnsupported) {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	writeJSON(w, http.StatusCreated, resp)
+	// Setup continues in the background when it was queued
+	status := http.StatusCreated
+	if resp.SetupInProgress {
+		status = http.StatusAccepted
+	}
+
+	writeJSON(w, status, resp)
+}
+
+// readCircuitUpload reads a multipart circuit creation request. Form
+// fields carry the JSON request fields, circuit_definition as JSON, and
+// file parts the circuit files by field name (e.g. a Circom "r1cs").
+func readCircuitUpload(w http.ResponseWriter, r *http.Request, req *service.CreateCircuitRequest) error {
+	r.Body = http.MaxBytesReader(w, r.Body, maxCircuitUploadSize)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return fmt.Errorf("invalid multipart body: %v", err)
+	}
+
+	req.Files = map[string][]byte{}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("invalid multipart body: %v", err)
+		}
+		data, err := io.ReadAll(part)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %v", part.FormName(), err)
+		}
+
+		if part.FileName() != "" {
+			req.Files[part.FormName()] = data
+			continue
+		}
+		switch part.FormName() {
+		case "name":
+			req.Name = string(data)
+		case "description":
+			req.Description = string(data)
+		case "proof_system":
+			req.ProofSystem = models.ProofSystemType(data)
+		case "circuit_definition":
+			if !json.Valid(data) {
+				return fmt.Errorf("circuit_definition must be JSON")
+			}
+			req.CircuitDefinition = data
+		case "is_public", "ceremony":
+			flag, err := strconv.ParseBool(string(data))
+			if err != nil {
+				return fmt.Errorf("%s must be true or false", part.FormName())
+			}
+			if part.FormName() == "is_public" {
+				req.IsPublic = flag
+			} else {
+				req.Ceremony = flag
+			}
+		default:
+			return fmt.Errorf("unexpected field %q", part.FormName())
+		}
+	}
+	return nil
 }
 
 // Get handles GET /api/v1/circuits/{id}
@@ -90,6 +177,68 @@ func (h *CircuitHandler) Get(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, circuit)
 }
 
+// GetSetupStatus handles GET /api/v1/circuits/{id}/setup
+func (h *CircuitHandler) GetSetupStatus(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Parse circuit ID
+	circuitIDStr := chi.URLParam(r, "id")
+	circuitID, err := uuid.Parse(circuitIDStr)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid circuit ID")
+		return
+	}
+
+	// Get setup status
+	status, err := h.circuitService.GetSetupStatus(r.Context(), circuitID, userID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "Circuit not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, status)
+}
+
+// VerifierContract handles GET /api/v1/circuits/{id}/verifier.sol
+func (h *CircuitHandler) VerifierContract(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Parse circuit ID
+	circuitIDStr := chi.URLParam(r, "id")
+	circuitID, err := uuid.Parse(circuitIDStr)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid circuit ID")
+		return
+	}
+
+	circuit, err := h.circuitService.Get(r.Context(), circuitID, userID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "Circuit not found")
+		return
+	}
+
+	// Render fully before writing so failures can still be reported as JSON
+	var contract bytes.Buffer
+	if err := h.circuitService.ExportVerifier(r.Context(), circuit, &contract); err != nil {
+		writeError(w, solidityErrorStatus(err), err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = contract.WriteTo(w)
+}
+
 // List handles GET /api/v1/circuits
 func (h *CircuitHandler) List(w http.ResponseWriter, r *http.Request) {
 	// Get user ID from context
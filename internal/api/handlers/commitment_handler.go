@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gabrielrondon/zapiki/internal/prover"
+	"github.com/gabrielrondon/zapiki/internal/service"
+)
+
+// CommitmentHandler handles commitment opening requests
+type CommitmentHandler struct {
+	commitmentService *service.CommitmentService
+}
+
+// NewCommitmentHandler creates a new commitment handler
+func NewCommitmentHandler(commitmentService *service.CommitmentService) *CommitmentHandler {
+	return &CommitmentHandler{
+		commitmentService: commitmentService,
+	}
+}
+
+// Open handles POST /api/v1/commitments/open
+func (h *CommitmentHandler) Open(w http.ResponseWriter, r *http.Request) {
+	var opening prover.Opening
+	if err := json.NewDecoder(r.Body).Decode(&opening); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if opening.Proof == nil || opening.Data == nil || opening.Nonce == "" {
+		writeError(w, http.StatusBadRequest, "proof, data and nonce are required")
+		return
+	}
+
+	resp, err := h.commitmentService.Open(r.Context(), &opening)
+	if err != nil {
+		writeError(w, commitmentErrorStatus(err), err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// Equality handles POST /api/v1/commitments/equality
+func (h *CommitmentHandler) Equality(w http.ResponseWriter, r *http.Request) {
+	var req service.EqualityRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	resp, err := h.commitmentService.ProveEquality(r.Context(), &req)
+	if err != nil {
+		writeError(w, commitmentErrorStatus(err), err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// VerifyEquality handles POST /api/v1/commitments/equality/verify
+func (h *CommitmentHandler) VerifyEquality(w http.ResponseWriter, r *http.Request) {
+	var req service.EqualityVerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Proof == nil {
+		writeError(w, http.StatusBadRequest, "proof is required")
+		return
+	}
+
+	resp, err := h.commitmentService.VerifyEquality(r.Context(), &req)
+	if err != nil {
+		writeError(w, commitmentErrorStatus(err), err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// batchCommitmentHeader is the first line of a batch commitment response
+type batchCommitmentHeader struct {
+	Root            json.RawMessage `json:"root"`
+	VerificationKey json.RawMessage `json:"verification_key"`
+	Count           int             `json:"count"`
+}
+
+// Batch handles POST /api/v1/commitments/batch. The body is NDJSON, one
+// input data object per line. The response is NDJSON too: the signed root,
+// then one inclusion proof per item, in input order.
+func (h *CommitmentHandler) Batch(w http.ResponseWriter, r *http.Request) {
+	batch, err := h.commitmentService.CommitBatch(r.Context(), r.Body)
+	if err != nil {
+		writeError(w, commitmentErrorStatus(err), err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	out := bufio.NewWriter(w)
+	encoder := json.NewEncoder(out)
+	if err := encoder.Encode(batchCommitmentHeader{
+		Root:            batch.Root(),
+		VerificationKey: batch.VerificationKey(),
+		Count:           batch.Len(),
+	}); err != nil {
+		return
+	}
+	for i := 0; i < batch.Len(); i++ {
+		inclusion, err := batch.InclusionProof(i)
+		if err != nil {
+			log.Printf("Failed to build inclusion proof %d: %v", i, err)
+			return
+		}
+		if err := encoder.Encode(inclusion); err != nil {
+			return
+		}
+	}
+	_ = out.Flush()
+}
+
+// VerifyInclusion handles POST /api/v1/commitments/batch/verify
+func (h *CommitmentHandler) VerifyInclusion(w http.ResponseWriter, r *http.Request) {
+	var req prover.InclusionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Root == nil || req.Inclusion == nil {
+		writeError(w, http.StatusBadRequest, "root and inclusion are required")
+		return
+	}
+
+	resp, err := h.commitmentService.VerifyInclusion(r.Context(), &req)
+	if err != nil {
+		writeError(w, commitmentErrorStatus(err), err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// Aggregate handles POST /api/v1/commitments/aggregate
+func (h *CommitmentHandler) Aggregate(w http.ResponseWriter, r *http.Request) {
+	var req service.AggregateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if len(req.Proofs) == 0 {
+		writeError(w, http.StatusBadRequest, "proofs are required")
+		return
+	}
+
+	resp, err := h.commitmentService.Aggregate(r.Context(), &req)
+	if err != nil {
+		writeError(w, commitmentErrorStatus(err), err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// commitmentErrorStatus maps commitment service errors to an HTTP status
+func commitmentErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, prover.ErrInvalidOpening), errors.Is(err, prover.ErrInvalidBatchItem),
+		errors.Is(err, prover.ErrInvalidAggregate):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrOpeningUnsupported):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
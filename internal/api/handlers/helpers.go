@@ -2,7 +2,11 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+
+	"github.com/gabrielrondon/zapiki/internal/prover"
+	"github.com/gabrielrondon/zapiki/internal/service"
 )
 
 // writeJSON writes a JSON response
@@ -18,3 +22,54 @@ func writeError(w http.ResponseWriter, status int, message string) {
 		"error": message,
 	})
 }
+
+// proofErrorStatus maps proof generation errors to an HTTP status
+func proofErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrCircuitNotReady):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrCircuitAccessDenied):
+		return http.StatusForbidden
+	case errors.Is(err, prover.ErrInvalidWitness), errors.Is(err, prover.ErrInvalidCircuit),
+		errors.Is(err, service.ErrCircuitProofSystem):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// solidityErrorStatus maps verifier export and calldata errors to an HTTP status
+func solidityErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, prover.ErrSolidityUnsupported):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, service.ErrCircuitNotReady), errors.Is(err, service.ErrProofNotCompleted):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// verifyErrorStatus maps verification errors to an HTTP status
+func verifyErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrCircuitAccessDenied):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrCircuitProofSystem):
+		return http.StatusBadRequest
+	default:
+		return formatErrorStatus(err)
+	}
+}
+
+// formatErrorStatus maps proof conversion errors to an HTTP status
+func formatErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, prover.ErrFormatUnsupported):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, service.ErrProofNotCompleted):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/gabrielrondon/zapiki/internal/api/middleware"
+	"github.com/gabrielrondon/zapiki/internal/prover"
 	"github.com/gabrielrondon/zapiki/internal/service"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
@@ -52,7 +53,7 @@ func (h *ProofHandler) Generate(w http.ResponseWriter, r *http.Request) {
 	// Generate proof
 	resp, err := h.proofService.Generate(r.Context(), &req)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeError(w, proofErrorStatus(err), err.Error())
 		return
 	}
 
@@ -83,9 +84,52 @@ func (h *ProofHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// ?format=snarkjs or ?format=arkworks converts the proof for those tools
+	if format := r.URL.Query().Get("format"); format != "" && format != prover.FormatGnark {
+		exported, err := h.proofService.Export(r.Context(), proof, format)
+		if err != nil {
+			writeError(w, formatErrorStatus(err), err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, exported)
+		return
+	}
+
 	writeJSON(w, http.StatusOK, proof)
 }
 
+// Calldata handles GET /api/v1/proofs/{id}/calldata
+func (h *ProofHandler) Calldata(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Parse proof ID
+	proofIDStr := chi.URLParam(r, "id")
+	proofID, err := uuid.Parse(proofIDStr)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid proof ID")
+		return
+	}
+
+	proof, err := h.proofService.GetProof(r.Context(), proofID, userID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "Proof not found")
+		return
+	}
+
+	calldata, err := h.proofService.Calldata(r.Context(), proof)
+	if err != nil {
+		writeError(w, solidityErrorStatus(err), err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, calldata)
+}
+
 // List handles GET /api/v1/proofs
 func (h *ProofHandler) List(w http.ResponseWriter, r *http.Request) {
 	// Get user ID from context
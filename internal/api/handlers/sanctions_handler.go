@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+
+	"github.com/gabrielrondon/zapiki/internal/service"
+	"github.com/gabrielrondon/zapiki/internal/storage/postgres"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// SanctionsHandler serves sanctions list versions and non-membership witnesses
+type SanctionsHandler struct {
+	sanctionsService *service.SanctionsService
+}
+
+// NewSanctionsHandler creates a new sanctions handler
+func NewSanctionsHandler(sanctionsService *service.SanctionsService) *SanctionsHandler {
+	return &SanctionsHandler{
+		sanctionsService: sanctionsService,
+	}
+}
+
+// List handles GET /api/v1/aml/sanctions-lists
+func (h *SanctionsHandler) List(w http.ResponseWriter, r *http.Request) {
+	versions, err := h.sanctionsService.ListVersions(r.Context(), r.URL.Query().Get("list"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"versions": versions,
+		"count":    len(versions),
+	})
+}
+
+// Get handles GET /api/v1/aml/sanctions-lists/{id}
+func (h *SanctionsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid list version ID")
+		return
+	}
+
+	version, err := h.sanctionsService.GetVersion(r.Context(), id)
+	if err != nil {
+		writeError(w, sanctionsErrorStatus(err), err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, version)
+}
+
+// WitnessRequest contains the identifier to prove non-membership of
+type WitnessRequest struct {
+	UserIdentifier string `json:"user_identifier"`
+	IdentifierSalt string `json:"identifier_salt,omitempty"` // Random if omitted
+}
+
+// Witness handles POST /api/v1/aml/sanctions-lists/{id}/witness
+func (h *SanctionsHandler) Witness(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid list version ID")
+		return
+	}
+
+	var req WitnessRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if _, ok := new(big.Int).SetString(req.UserIdentifier, 0); !ok {
+		writeError(w, http.StatusBadRequest, "user_identifier must be a decimal or 0x-prefixed hashed identifier")
+		return
+	}
+
+	version, err := h.sanctionsService.GetVersion(r.Context(), id)
+	if err != nil {
+		writeError(w, sanctionsErrorStatus(err), err.Error())
+		return
+	}
+
+	witness, err := h.sanctionsService.Witness(r.Context(), version, req.UserIdentifier, req.IdentifierSalt)
+	if err != nil {
+		writeError(w, sanctionsErrorStatus(err), err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, witness)
+}
+
+// sanctionsErrorStatus maps sanctions list errors to an HTTP status
+func sanctionsErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, postgres.ErrSanctionsListNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrInvalidSanctionsRoot), errors.Is(err, service.ErrInvalidIdentifier):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrIdentifierListed):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
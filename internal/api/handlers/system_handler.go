@@ -3,9 +3,11 @@ package handlers
 import (
 	"net/http"
 
+	"github.com/gabrielrondon/zapiki/internal/models"
 	"github.com/gabrielrondon/zapiki/internal/prover"
 	"github.com/gabrielrondon/zapiki/internal/storage/postgres"
 	"github.com/gabrielrondon/zapiki/internal/storage/redis"
+	"github.com/go-chi/chi/v5"
 )
 
 // SystemHandler handles system-level requests
@@ -62,13 +64,44 @@ func (h *SystemHandler) Systems(w http.ResponseWriter, r *http.Request) {
 	systemsInfo := make([]map[string]interface{}, 0, len(systems))
 	for _, sys := range systems {
 		caps := sys.Capabilities()
-		systemsInfo = append(systemsInfo, map[string]interface{}{
+		info := map[string]interface{}{
 			"name":         sys.Name(),
 			"capabilities": caps,
-		})
+		}
+		// Proof systems with built-in circuit types list them
+		if catalog, ok := sys.(prover.CircuitCatalog); ok {
+			info["circuits"] = catalog.Circuits()
+		}
+		systemsInfo = append(systemsInfo, info)
 	}
 
 	writeJSON(w, http.StatusOK, map[string]interface{}{
 		"systems": systemsInfo,
 	})
 }
+
+// Keys handles GET /keys/{system}, publishing the keys a proof system signs
+// proofs with, retired ones included
+func (h *SystemHandler) Keys(w http.ResponseWriter, r *http.Request) {
+	system, err := h.factory.Get(models.ProofSystemType(chi.URLParam(r, "system")))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "Proof system not found")
+		return
+	}
+	publisher, ok := system.(prover.KeyPublisher)
+	if !ok {
+		writeError(w, http.StatusNotFound, "Proof system does not sign proofs")
+		return
+	}
+
+	keys, err := publisher.PublicKeys()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to list keys")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"proof_system": system.Name(),
+		"keys":         keys,
+	})
+}
@@ -89,7 +89,7 @@ func (h *TemplateHandler) Generate(w http.ResponseWriter, r *http.Request) {
 	// Generate proof from template
 	resp, err := h.templateService.GenerateFromTemplate(r.Context(), templateID, &req)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeError(w, proofErrorStatus(err), err.Error())
 		return
 	}
 
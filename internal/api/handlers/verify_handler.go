@@ -2,11 +2,17 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
+	"github.com/gabrielrondon/zapiki/internal/api/middleware"
 	"github.com/gabrielrondon/zapiki/internal/service"
 )
 
+// maxBatchVerifySize bounds a batch verification request body, which holds
+// up to service.MaxBatchVerifyProofs proofs with their keys
+const maxBatchVerifySize = 64 << 20
+
 // VerifyHandler handles verification requests
 type VerifyHandler struct {
 	verifyService *service.VerifyService
@@ -21,12 +27,26 @@ func NewVerifyHandler(verifyService *service.VerifyService) *VerifyHandler {
 
 // Verify handles POST /api/v1/verify
 func (h *VerifyHandler) Verify(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
 	// Parse request
 	var req service.VerifyRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
+	req.UserID = userID
+
+	// An envelope supplies the proof system, proof and public inputs
+	if err := req.ApplyEnvelope(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	// Validate request
 	if req.ProofSystem == "" {
@@ -37,14 +57,44 @@ func (h *VerifyHandler) Verify(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "proof is required")
 		return
 	}
-	if req.VerificationKey == nil {
-		writeError(w, http.StatusBadRequest, "verification_key is required")
+	if req.VerificationKey == nil && req.CircuitID == nil {
+		writeError(w, http.StatusBadRequest, "verification_key or circuit_id is required")
 		return
 	}
 
 	// Verify proof
 	resp, err := h.verifyService.Verify(r.Context(), &req)
 	if err != nil {
+		writeError(w, verifyErrorStatus(err), err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// VerifyBatch handles POST /api/v1/verify/batch
+func (h *VerifyHandler) VerifyBatch(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxBatchVerifySize)
+
+	var req service.BatchVerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	req.UserID = userID
+
+	resp, err := h.verifyService.VerifyBatch(r.Context(), &req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidBatchVerify) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -13,21 +13,25 @@ import (
 
 // RouterConfig holds configuration for setting up routes
 type RouterConfig struct {
-	ProofHandler    *handlers.ProofHandler
-	VerifyHandler   *handlers.VerifyHandler
-	SystemHandler   *handlers.SystemHandler
-	JobHandler      *handlers.JobHandler
-	CircuitHandler  *handlers.CircuitHandler
-	TemplateHandler *handlers.TemplateHandler
-	PlanHandler     *handlers.PlanHandler
-	AuditHandler    *handlers.AuditHandler
-	UsageHandler    *handlers.UsageHandler
-	PortalHandler   *handlers.PortalHandler
-	BatchHandler    *handlers.BatchHandler
-	AMLHandler      *handlers.AMLHandler
-	AuthMiddleware  *middleware.Auth
-	RateLimiter     *middleware.RateLimit
-	Metrics         *metrics.Metrics
+	ProofHandler       *handlers.ProofHandler
+	VerifyHandler      *handlers.VerifyHandler
+	SystemHandler      *handlers.SystemHandler
+	JobHandler         *handlers.JobHandler
+	CircuitHandler     *handlers.CircuitHandler
+	TemplateHandler    *handlers.TemplateHandler
+	PlanHandler        *handlers.PlanHandler
+	AuditHandler       *handlers.AuditHandler
+	UsageHandler       *handlers.UsageHandler
+	PortalHandler      *handlers.PortalHandler
+	BatchHandler       *handlers.BatchHandler
+	AMLHandler         *handlers.AMLHandler
+	SanctionsHandler   *handlers.SanctionsHandler
+	CeremonyHandler    *handlers.CeremonyHandler
+	CommitmentHandler  *handlers.CommitmentHandler
+	AggregationHandler *handlers.AggregationHandler
+	AuthMiddleware     *middleware.Auth
+	RateLimiter        *middleware.RateLimit
+	Metrics            *metrics.Metrics
 }
 
 // NewRouter creates a new Chi router with all routes configured
@@ -50,6 +54,9 @@ func NewRouter(cfg *RouterConfig) *chi.Mux {
 	// Health endpoint (no auth required)
 	r.Get("/health", cfg.SystemHandler.Health)
 
+	// Published signing keys (no auth required, for third-party verifiers)
+	r.Get("/keys/{system}", cfg.SystemHandler.Keys)
+
 	// Metrics endpoint (no auth required, for Prometheus)
 	if cfg.Metrics != nil {
 		r.Handle("/metrics", promhttp.Handler())
@@ -84,16 +91,36 @@ func NewRouter(cfg *RouterConfig) *chi.Mux {
 			r.Post("/", cfg.ProofHandler.Generate)
 			r.Get("/", cfg.ProofHandler.List)
 			r.Get("/{id}", cfg.ProofHandler.Get)
+			r.Get("/{id}/calldata", cfg.ProofHandler.Calldata)
 			r.Delete("/{id}", cfg.ProofHandler.Delete)
 
 			// Batch operations
 			if cfg.BatchHandler != nil {
 				r.Post("/batch", cfg.BatchHandler.GenerateBatch)
 			}
+
+			// Recursive aggregation of Groth16 proofs
+			if cfg.AggregationHandler != nil {
+				r.Post("/aggregate", cfg.AggregationHandler.Aggregate)
+			}
 		})
 
 		// Verification endpoint
 		r.Post("/verify", cfg.VerifyHandler.Verify)
+		r.Post("/verify/batch", cfg.VerifyHandler.VerifyBatch)
+
+		// Commitment openings, equality proofs, batch commitments and
+		// Pedersen aggregates
+		if cfg.CommitmentHandler != nil {
+			r.Route("/commitments", func(r chi.Router) {
+				r.Post("/open", cfg.CommitmentHandler.Open)
+				r.Post("/equality", cfg.CommitmentHandler.Equality)
+				r.Post("/equality/verify", cfg.CommitmentHandler.VerifyEquality)
+				r.Post("/batch", cfg.CommitmentHandler.Batch)
+				r.Post("/batch/verify", cfg.CommitmentHandler.VerifyInclusion)
+				r.Post("/aggregate", cfg.CommitmentHandler.Aggregate)
+			})
+		}
 
 		// Job endpoints
 		r.Route("/jobs", func(r chi.Router) {
@@ -106,9 +133,25 @@ func NewRouter(cfg *RouterConfig) *chi.Mux {
 			r.Post("/", cfg.CircuitHandler.Create)
 			r.Get("/", cfg.CircuitHandler.List)
 			r.Get("/{id}", cfg.CircuitHandler.Get)
+			r.Get("/{id}/setup", cfg.CircuitHandler.GetSetupStatus)
+			r.Get("/{id}/verifier.sol", cfg.CircuitHandler.VerifierContract)
 			r.Delete("/{id}", cfg.CircuitHandler.Delete)
+			if cfg.CeremonyHandler != nil {
+				r.Post("/{id}/ceremony", cfg.CeremonyHandler.Open)
+			}
 		})
 
+		// Setup ceremony endpoints
+		if cfg.CeremonyHandler != nil {
+			r.Route("/ceremonies", func(r chi.Router) {
+				r.Get("/{id}", cfg.CeremonyHandler.Get)
+				r.Get("/{id}/state", cfg.CeremonyHandler.State)
+				r.Get("/{id}/contributions/{index}", cfg.CeremonyHandler.ContributionState)
+				r.Post("/{id}/contributions", cfg.CeremonyHandler.Contribute)
+				r.Post("/{id}/finalize", cfg.CeremonyHandler.Finalize)
+			})
+		}
+
 		// Template endpoints
 		r.Route("/templates", func(r chi.Router) {
 			r.Get("/", cfg.TemplateHandler.List)
@@ -124,6 +167,13 @@ func NewRouter(cfg *RouterConfig) *chi.Mux {
 				r.Post("/sanctions-check", cfg.AMLHandler.SanctionsCheck)
 				r.Post("/residency-proof", cfg.AMLHandler.ResidencyProof)
 				r.Post("/income-verification", cfg.AMLHandler.IncomeVerification)
+
+				// Sanctions list versions and non-membership witnesses
+				if cfg.SanctionsHandler != nil {
+					r.Get("/sanctions-lists", cfg.SanctionsHandler.List)
+					r.Get("/sanctions-lists/{id}", cfg.SanctionsHandler.Get)
+					r.Post("/sanctions-lists/{id}/witness", cfg.SanctionsHandler.Witness)
+				}
 			})
 		}
 	})
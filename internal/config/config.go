@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -48,7 +49,10 @@ type RedisConfig struct {
 
 // StorageConfig holds object storage configuration
 type StorageConfig struct {
+	Backend   string // "local" or "s3"
+	LocalDir  string
 	Endpoint  string
+	Region    string
 	Bucket    string
 	AccessKey string
 	SecretKey string
@@ -61,6 +65,39 @@ type ProofConfig struct {
 	EnableGroth16    bool
 	EnablePLONK      bool
 	EnableSTARK      bool
+	EnablePedersen   bool
+
+	// KeyCacheDir persists Groth16/PLONK keys across restarts (empty = memory only)
+	KeyCacheDir string
+	// WarmUpKeys runs setup for the built-in circuits when a worker starts
+	WarmUpKeys bool
+
+	// PLONKSRSDir holds the KZG SRS files (<curve>.srs) used for PLONK setup
+	PLONKSRSDir string
+	// PLONKSRSChecksums pins the SHA-256 of each SRS file, keyed by curve
+	PLONKSRSChecksums map[string]string
+	// PLONKDevSRS generates a local test SRS instead (development only)
+	PLONKDevSRS bool
+
+	// STARK security parameters (see stark.Options)
+	STARKBlowupFactor int
+	STARKNumQueries   int
+	STARKGrindingBits int
+
+	// CommitmentKeyStoreFile is the encrypted file holding the commitment
+	// signing keys, shared by the API and the workers (empty = a new key
+	// per process, outside production)
+	CommitmentKeyStoreFile       string
+	CommitmentKeyStorePassphrase string
+	// CommitmentKeyRotation is the age at which the signing key is rotated
+	// (zero disables scheduled rotation)
+	CommitmentKeyRotation time.Duration
+
+	// Groth16Phase1File holds BN254 powers of tau for Groth16 setup
+	// ceremonies (empty = generated test parameters outside production)
+	Groth16Phase1File string
+	// Groth16Phase1Checksum pins the SHA-256 of the phase-1 file
+	Groth16Phase1Checksum string
 }
 
 // RateLimitConfig holds rate limiting configuration
@@ -96,17 +133,37 @@ func Load() (*Config, error) {
 			DB:       getEnvAsInt("REDIS_DB", 0),
 		},
 		Storage: StorageConfig{
+			Backend:   getEnv("STORAGE_BACKEND", "local"),
+			LocalDir:  getEnv("STORAGE_LOCAL_DIR", "./data/artifacts"),
 			Endpoint:  getEnv("S3_ENDPOINT", "http://localhost:9000"),
+			Region:    getEnv("S3_REGION", "us-east-1"),
 			Bucket:    getEnv("S3_BUCKET", "zapiki-proofs"),
 			AccessKey: getEnv("S3_ACCESS_KEY", ""),
 			SecretKey: getEnv("S3_SECRET_KEY", ""),
 			UseSSL:    getEnvAsBool("S3_USE_SSL", false),
 		},
 		Proof: ProofConfig{
-			EnableCommitment: getEnvAsBool("ENABLE_COMMITMENT", true),
-			EnableGroth16:    getEnvAsBool("ENABLE_GROTH16", false),
-			EnablePLONK:      getEnvAsBool("ENABLE_PLONK", false),
-			EnableSTARK:      getEnvAsBool("ENABLE_STARK", false),
+			EnableCommitment:  getEnvAsBool("ENABLE_COMMITMENT", true),
+			EnableGroth16:     getEnvAsBool("ENABLE_GROTH16", false),
+			EnablePLONK:       getEnvAsBool("ENABLE_PLONK", false),
+			EnableSTARK:       getEnvAsBool("ENABLE_STARK", false),
+			EnablePedersen:    getEnvAsBool("ENABLE_PEDERSEN", false),
+			KeyCacheDir:       getEnv("KEY_CACHE_DIR", ""),
+			WarmUpKeys:        getEnvAsBool("KEY_CACHE_WARMUP", true),
+			PLONKSRSDir:       getEnv("PLONK_SRS_DIR", ""),
+			PLONKSRSChecksums: getEnvAsMap("PLONK_SRS_CHECKSUMS"),
+			PLONKDevSRS:       getEnvAsBool("PLONK_DEV_SRS", false),
+
+			Groth16Phase1File:     getEnv("GROTH16_PHASE1_FILE", ""),
+			Groth16Phase1Checksum: getEnv("GROTH16_PHASE1_CHECKSUM", ""),
+
+			STARKBlowupFactor: getEnvAsInt("STARK_BLOWUP_FACTOR", 8),
+			STARKNumQueries:   getEnvAsInt("STARK_NUM_QUERIES", 28),
+			STARKGrindingBits: getEnvAsInt("STARK_GRINDING_BITS", 16),
+
+			CommitmentKeyStoreFile:       getEnv("COMMITMENT_KEYSTORE_FILE", ""),
+			CommitmentKeyStorePassphrase: getEnv("COMMITMENT_KEYSTORE_PASSPHRASE", ""),
+			CommitmentKeyRotation:        time.Duration(getEnvAsInt("COMMITMENT_KEY_ROTATION_DAYS", 90)) * 24 * time.Hour,
 		},
 		RateLimit: RateLimitConfig{
 			FreeTier: getEnvAsInt("RATE_LIMIT_FREE_TIER", 10),
@@ -127,10 +184,28 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("POSTGRES_PASSWORD is required")
 	}
 
-	if !c.Proof.EnableCommitment && !c.Proof.EnableGroth16 && !c.Proof.EnablePLONK && !c.Proof.EnableSTARK {
+	if !c.Proof.EnableCommitment && !c.Proof.EnableGroth16 && !c.Proof.EnablePLONK && !c.Proof.EnableSTARK && !c.Proof.EnablePedersen {
 		return fmt.Errorf("at least one proof system must be enabled")
 	}
 
+	if c.Proof.EnablePLONK {
+		if c.Proof.PLONKSRSDir == "" && !c.Proof.PLONKDevSRS {
+			return fmt.Errorf("PLONK_SRS_DIR is required when PLONK is enabled (or set PLONK_DEV_SRS=true for development)")
+		}
+		if c.Proof.PLONKDevSRS && c.Server.Environment == "production" {
+			return fmt.Errorf("PLONK_DEV_SRS must not be enabled in production")
+		}
+	}
+
+	if c.Proof.EnableCommitment {
+		if c.Proof.CommitmentKeyStoreFile == "" && c.Server.Environment == "production" {
+			return fmt.Errorf("COMMITMENT_KEYSTORE_FILE is required when commitment proofs are enabled in production")
+		}
+		if c.Proof.CommitmentKeyStoreFile != "" && c.Proof.CommitmentKeyStorePassphrase == "" {
+			return fmt.Errorf("COMMITMENT_KEYSTORE_PASSPHRASE is required with COMMITMENT_KEYSTORE_FILE")
+		}
+	}
+
 	return nil
 }
 
@@ -171,3 +246,15 @@ func getEnvAsBool(key string, defaultValue bool) bool {
 	}
 	return defaultValue
 }
+
+// getEnvAsMap parses a "key=value,key=value" list
+func getEnvAsMap(key string) map[string]string {
+	values := make(map[string]string)
+	for _, pair := range strings.Split(getEnv(key, ""), ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if ok {
+			values[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return values
+}
@@ -15,6 +15,7 @@ const (
 	ProofSystemGroth16    ProofSystemType = "groth16"
 	ProofSystemPLONK      ProofSystemType = "plonk"
 	ProofSystemSTARK      ProofSystemType = "stark"
+	ProofSystemPedersen   ProofSystemType = "pedersen"
 )
 
 // ProofStatus represents the status of a proof generation job
@@ -27,6 +28,19 @@ const (
 	ProofStatusFailed    ProofStatus = "failed"
 )
 
+// CircuitSetupStatus represents the state of a circuit's key setup
+type CircuitSetupStatus string
+
+const (
+	CircuitSetupPending CircuitSetupStatus = "pending"
+	CircuitSetupReady   CircuitSetupStatus = "ready"
+	CircuitSetupFailed  CircuitSetupStatus = "failed"
+
+	// CircuitSetupCeremony marks a circuit whose keys come from a setup
+	// ceremony that has not been finalized yet
+	CircuitSetupCeremony CircuitSetupStatus = "ceremony"
+)
+
 // DataType represents the type of input data
 type DataType string
 
@@ -69,6 +83,12 @@ type Circuit struct {
 	CircuitDefinition  json.RawMessage `json:"circuit_definition" db:"circuit_definition"`
 	ProvingKeyURL      string          `json:"proving_key_url,omitempty" db:"proving_key_url"`
 	VerificationKeyURL string          `json:"verification_key_url,omitempty" db:"verification_key_url"`
+	SetupStatus        CircuitSetupStatus `json:"setup_status" db:"setup_status"`
+	SetupError         string          `json:"setup_error,omitempty" db:"setup_error"`
+	SetupCompletedAt   *time.Time      `json:"setup_completed_at,omitempty" db:"setup_completed_at"`
+	// SetupMetadata describes the compiled circuit instance, such as its
+	// resolved params and constraint count
+	SetupMetadata json.RawMessage `json:"setup_metadata,omitempty" db:"setup_metadata"`
 	IsPublic           bool            `json:"is_public" db:"is_public"`
 	CreatedAt          time.Time       `json:"created_at" db:"created_at"`
 	UpdatedAt          time.Time       `json:"updated_at" db:"updated_at"`
@@ -88,10 +108,91 @@ type Proof struct {
 	ProofURL      string          `json:"proof_url,omitempty" db:"proof_url"`
 	ErrorMessage  string          `json:"error_message,omitempty" db:"error_message"`
 	GenerationTimeMs int64        `json:"generation_time_ms,omitempty" db:"generation_time_ms"`
+	SanctionsListVersionID *uuid.UUID `json:"sanctions_list_version_id,omitempty" db:"sanctions_list_version_id"`
+	// Aggregation describes the proofs an aggregated proof attests to
+	// (see ProofAggregation)
+	Aggregation   json.RawMessage `json:"aggregation,omitempty" db:"aggregation"`
+	// Envelope describes a completed proof on its own (see ProofEnvelope)
+	Envelope      json.RawMessage `json:"envelope,omitempty" db:"envelope"`
 	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
 	CompletedAt   *time.Time      `json:"completed_at,omitempty" db:"completed_at"`
 }
 
+// ProofAggregation records the proofs one recursive proof attests to
+type ProofAggregation struct {
+	ProofIDs []uuid.UUID `json:"proof_ids"`
+	// CircuitID is the circuit of the aggregated proofs, if any
+	CircuitID *uuid.UUID `json:"circuit_id,omitempty"`
+	// InnerCurve is the curve the aggregated proofs were generated on
+	InnerCurve string `json:"inner_curve,omitempty"`
+	// InnerVerificationKey verifies the aggregated proofs when they have no circuit
+	InnerVerificationKey json.RawMessage `json:"inner_verification_key,omitempty"`
+	// Curve is the curve of the recursive proof
+	Curve string `json:"curve,omitempty"`
+	// Statements lists each aggregated proof's public inputs as decimal
+	// strings, in the order the recursive proof's public inputs encode them
+	Statements [][]string `json:"statements,omitempty"`
+	// VerificationKey verifies the recursive proof
+	VerificationKey json.RawMessage `json:"verification_key,omitempty"`
+}
+
+// ProofEnvelopeVersion is the version of the ProofEnvelope format
+const ProofEnvelopeVersion = 1
+
+// ProofEnvelope is a self-describing proof: the proof and its public
+// inputs with what is needed to interpret them and where they came from.
+// It is stored with every completed proof and accepted by verification in
+// place of proof_system, proof and public_inputs.
+type ProofEnvelope struct {
+	Version     int             `json:"envelope_version"`
+	ProofSystem ProofSystemType `json:"proof_system"`
+	// Curve is the curve of SNARK and Pedersen proofs
+	Curve       string     `json:"curve,omitempty"`
+	CircuitID   *uuid.UUID `json:"circuit_id,omitempty"`
+	CircuitType string     `json:"circuit_type,omitempty"`
+	// CircuitHash is the hex SHA-256 of the circuit's definition
+	CircuitHash string `json:"circuit_sha256,omitempty"`
+	// VerificationKeyHash is the hex SHA-256 of the verification key
+	// returned with the proof, as compact JSON
+	VerificationKeyHash string `json:"verification_key_sha256,omitempty"`
+	// KeyID identifies the key the proof was generated with: the signing
+	// key of commitment proofs, the cached proving key of SNARK proofs
+	KeyID string `json:"key_id,omitempty"`
+	// HashAlgorithm and SignatureAlgorithm are those of commitment proofs
+	HashAlgorithm      string          `json:"hash_algo,omitempty"`
+	SignatureAlgorithm string          `json:"sig_algo,omitempty"`
+	Proof              json.RawMessage `json:"proof"`
+	PublicInputs       json.RawMessage `json:"public_inputs,omitempty"`
+	// NamedPublicInputs lists the public inputs' values by name, in order,
+	// for proof systems that know their names
+	NamedPublicInputs []NamedPublicInput `json:"named_public_inputs,omitempty"`
+	Prover            ProverInfo         `json:"prover"`
+	// WorkerID identifies the process that generated the proof
+	WorkerID string       `json:"worker_id,omitempty"`
+	Timings  ProofTimings `json:"timings"`
+}
+
+// NamedPublicInput is a public input's value, as a decimal string
+type NamedPublicInput struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// ProverInfo names the library that generated a proof and its version
+type ProverInfo struct {
+	Library string `json:"library"`
+	Version string `json:"version"`
+}
+
+// ProofTimings records when a proof was requested, started and completed
+type ProofTimings struct {
+	CreatedAt        time.Time `json:"created_at"`
+	StartedAt        time.Time `json:"started_at"`
+	CompletedAt      time.Time `json:"completed_at"`
+	QueueTimeMs      int64     `json:"queue_time_ms"`
+	GenerationTimeMs int64     `json:"generation_time_ms"`
+}
+
 // Verification represents a verification record
 type Verification struct {
 	ID           uuid.UUID       `json:"id" db:"id"`
@@ -156,4 +257,67 @@ type ProofOptions struct {
 	TemplateID *uuid.UUID `json:"template_id,omitempty"`
 	CircuitID  *uuid.UUID `json:"circuit_id,omitempty"`
 	Async      bool       `json:"async,omitempty"`
+
+	// Curve selects the elliptic curve for SNARK proofs (default bn254)
+	Curve string `json:"curve,omitempty"`
+
+	// SanctionsListVersionID records the list version a sanctions check is
+	// proven against. It is set by the sanctions check after resolving the
+	// version, never from a request body.
+	SanctionsListVersionID *uuid.UUID `json:"-"`
+}
+
+// SanctionsListVersion is one ingested version of a sanctions list,
+// committed to as a sorted Merkle tree of hashed identifiers
+type SanctionsListVersion struct {
+	ID              uuid.UUID `json:"id" db:"id"`
+	ListName        string    `json:"list_name" db:"list_name"`
+	Version         int       `json:"version" db:"version"`
+	Root            string    `json:"root" db:"root"`
+	Curve           string    `json:"curve" db:"curve"`
+	HashFunc        string    `json:"hash" db:"hash_func"`
+	TreeDepth       int       `json:"tree_depth" db:"tree_depth"`
+	EntryCount      int       `json:"entry_count" db:"entry_count"`
+	IdentifierCount int       `json:"identifier_count" db:"identifier_count"`
+	Sources         []string  `json:"sources" db:"sources"`
+	IdentifiersURL  string    `json:"-" db:"identifiers_url"`
+	EffectiveDate   time.Time `json:"effective_date" db:"effective_date"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+}
+
+// CeremonyStatus represents the state of a setup ceremony
+type CeremonyStatus string
+
+const (
+	CeremonyStatusOpen      CeremonyStatus = "open"
+	CeremonyStatusFinalized CeremonyStatus = "finalized"
+)
+
+// Ceremony is a multi-party trusted setup producing one circuit's keys.
+// Hashes are hex SHA-256 digests of serialized ceremony states.
+type Ceremony struct {
+	ID                  uuid.UUID      `json:"id" db:"id"`
+	CircuitID           uuid.UUID      `json:"circuit_id" db:"circuit_id"`
+	UserID              uuid.UUID      `json:"user_id" db:"user_id"`
+	Status              CeremonyStatus `json:"status" db:"status"`
+	Phase1              string         `json:"phase1" db:"phase1"`
+	InitialHash         string         `json:"initial_hash" db:"initial_hash"`
+	InitialStateURL     string         `json:"-" db:"initial_state_url"`
+	ContributionCount   int            `json:"contribution_count" db:"contribution_count"`
+	CurrentHash         string         `json:"current_hash" db:"current_hash"`
+	Beacon              string         `json:"beacon,omitempty" db:"beacon"`
+	VerificationKeyHash string         `json:"verification_key_hash,omitempty" db:"verification_key_hash"`
+	CreatedAt           time.Time      `json:"created_at" db:"created_at"`
+	FinalizedAt         *time.Time     `json:"finalized_at,omitempty" db:"finalized_at"`
+}
+
+// CeremonyContribution is one participant's verified ceremony contribution
+type CeremonyContribution struct {
+	CeremonyID  uuid.UUID `json:"-" db:"ceremony_id"`
+	Index       int       `json:"index" db:"contribution_index"`
+	UserID      uuid.UUID `json:"user_id" db:"user_id"`
+	Participant string    `json:"participant,omitempty" db:"participant"`
+	Hash        string    `json:"hash" db:"hash"`
+	StateURL    string    `json:"-" db:"state_url"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 }
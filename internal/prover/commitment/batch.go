@@ -0,0 +1,298 @@
+package commitment
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/gabrielrondon/zapiki/internal/models"
+	"github.com/gabrielrondon/zapiki/internal/prover"
+)
+
+// batchDomain separates batch root signatures from other signatures
+const batchDomain = "zapiki-commitment-batch-v1"
+
+// Merkle tree node prefixes, so a leaf cannot pass for an inner node
+const (
+	leafPrefix = 0x00
+	nodePrefix = 0x01
+)
+
+// BatchRoot is the signed root of a Merkle tree over a batch of
+// commitments. Leaves are SHA256(0x00 || commitment) and nodes
+// SHA256(0x01 || left || right); a node without a sibling moves up a level
+// unchanged.
+type BatchRoot struct {
+	Root      string    `json:"root"`
+	Count     int       `json:"count"`
+	Signature string    `json:"signature"`
+	Timestamp time.Time `json:"timestamp"`
+	PublicKey string    `json:"public_key"`
+	KeyID     string    `json:"key_id"`
+}
+
+// InclusionProof shows that one item's commitment is under a batch root.
+// As with single commitment proofs, the nonce opens the commitment and is
+// removed before sharing when the item should stay hidden.
+type InclusionProof struct {
+	Root       string   `json:"root"`
+	Index      int      `json:"index"`
+	Commitment string   `json:"commitment"`
+	Nonce      string   `json:"nonce,omitempty"`
+	Path       []string `json:"path"`
+}
+
+// batchCommitment holds the tree built by CommitBatch
+type batchCommitment struct {
+	root   json.RawMessage
+	vk     json.RawMessage
+	levels [][][]byte
+	nonces [][]byte
+}
+
+func (b *batchCommitment) Root() json.RawMessage            { return b.root }
+func (b *batchCommitment) VerificationKey() json.RawMessage { return b.vk }
+func (b *batchCommitment) Len() int                         { return len(b.nonces) }
+
+// InclusionProof returns the proof for item index, with its nonce
+func (b *batchCommitment) InclusionProof(index int) (json.RawMessage, error) {
+	if index < 0 || index >= b.Len() {
+		return nil, fmt.Errorf("item %d is outside the batch", index)
+	}
+
+	top := b.levels[len(b.levels)-1][0]
+	proof := InclusionProof{
+		Root:       hex.EncodeToString(top),
+		Index:      index,
+		Commitment: hex.EncodeToString(b.levels[0][index]),
+		Nonce:      hex.EncodeToString(b.nonces[index]),
+		Path:       []string{},
+	}
+	i := index
+	for _, level := range b.levels[1 : len(b.levels)-1] {
+		if sibling := i ^ 1; sibling < len(level) {
+			proof.Path = append(proof.Path, hex.EncodeToString(level[sibling]))
+		}
+		i /= 2
+	}
+	return json.Marshal(proof)
+}
+
+// CommitBatch commits to each item with its own nonce and signs the root of
+// a Merkle tree over the commitments
+func (p *CommitmentProver) CommitBatch(ctx context.Context, next func() (*models.InputData, error)) (prover.BatchCommitment, error) {
+	var commitments, nonces [][]byte
+	for {
+		data, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		dataBytes, err := committedBytes(data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: item %d: %v", prover.ErrInvalidBatchItem, len(commitments), err)
+		}
+		nonce := make([]byte, nonceSize)
+		if _, err := rand.Read(nonce); err != nil {
+			return nil, fmt.Errorf("failed to generate nonce: %w", err)
+		}
+		commitments = append(commitments, commitmentHash(dataBytes, nonce))
+		nonces = append(nonces, nonce)
+	}
+	if len(commitments) == 0 {
+		return nil, fmt.Errorf("%w: batch has no items", prover.ErrInvalidBatchItem)
+	}
+
+	levels := merkleLevels(commitments)
+	signer, err := p.keys.Signer()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get signing key: %w", err)
+	}
+	root := BatchRoot{
+		Root:      hex.EncodeToString(levels[len(levels)-1][0]),
+		Count:     len(commitments),
+		Timestamp: time.Now().UTC(),
+		PublicKey: hex.EncodeToString(signer.PublicKey()),
+		KeyID:     signer.KeyID(),
+	}
+	signature, err := signer.Sign(root.message())
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign batch root: %w", err)
+	}
+	root.Signature = hex.EncodeToString(signature)
+
+	rootJSON, err := json.Marshal(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal batch root: %w", err)
+	}
+	vkJSON, err := verificationKey(signer)
+	if err != nil {
+		return nil, err
+	}
+
+	return &batchCommitment{root: rootJSON, vk: vkJSON, levels: levels, nonces: nonces}, nil
+}
+
+// VerifyInclusion checks the root's signature and the item's path to it,
+// then the item's opening when data is given
+func (p *CommitmentProver) VerifyInclusion(ctx context.Context, req *prover.InclusionRequest) (*prover.VerifyResponse, error) {
+	if err := p.verifyInclusion(req); err != nil {
+		return &prover.VerifyResponse{
+			Valid:        false,
+			ErrorMessage: err.Error(),
+		}, nil
+	}
+	return &prover.VerifyResponse{Valid: true}, nil
+}
+
+func (p *CommitmentProver) verifyInclusion(req *prover.InclusionRequest) error {
+	var root BatchRoot
+	if err := json.Unmarshal(req.Root, &root); err != nil {
+		return fmt.Errorf("failed to parse batch root: %v", err)
+	}
+	var inclusion InclusionProof
+	if err := json.Unmarshal(req.Inclusion, &inclusion); err != nil {
+		return fmt.Errorf("failed to parse inclusion proof: %v", err)
+	}
+
+	publicKey, err := p.publicKey(req.VerificationKey, root.KeyID)
+	if err != nil {
+		return err
+	}
+	signature, err := hex.DecodeString(root.Signature)
+	if err != nil {
+		return fmt.Errorf("failed to decode signature: %v", err)
+	}
+	if !ed25519.Verify(publicKey, root.message(), signature) {
+		return fmt.Errorf("batch root signature does not verify")
+	}
+
+	commitment, err := hex.DecodeString(inclusion.Commitment)
+	if err != nil || len(commitment) != sha256.Size {
+		return fmt.Errorf("invalid commitment")
+	}
+	if req.Data != nil {
+		nonce := req.Nonce
+		if nonce == "" {
+			nonce = inclusion.Nonce
+		}
+		dataBytes, err := committedBytes(req.Data)
+		if err != nil {
+			return err
+		}
+		nonceBytes, err := hex.DecodeString(nonce)
+		if err != nil || len(nonceBytes) != nonceSize {
+			return fmt.Errorf("invalid nonce: must be %d hex-encoded bytes", nonceSize)
+		}
+		if !bytes.Equal(commitmentHash(dataBytes, nonceBytes), commitment) {
+			return fmt.Errorf("data and nonce do not open the commitment")
+		}
+	}
+
+	path := make([][]byte, len(inclusion.Path))
+	for i, node := range inclusion.Path {
+		if path[i], err = hex.DecodeString(node); err != nil || len(path[i]) != sha256.Size {
+			return fmt.Errorf("invalid inclusion path")
+		}
+	}
+	computed, err := merkleRoot(commitment, inclusion.Index, root.Count, path)
+	if err != nil {
+		return err
+	}
+	if hex.EncodeToString(computed) != root.Root {
+		return fmt.Errorf("item is not in the batch")
+	}
+	return nil
+}
+
+// message is what a batch root's signature covers
+func (r *BatchRoot) message() []byte {
+	return []byte(fmt.Sprintf("%s\n%s\n%d\n%s", batchDomain, r.Root, r.Count, r.Timestamp.Format(time.RFC3339Nano)))
+}
+
+// commitmentHash computes SHA256(data || nonce)
+func commitmentHash(data, nonce []byte) []byte {
+	hasher := sha256.New()
+	hasher.Write(data)
+	hasher.Write(nonce)
+	return hasher.Sum(nil)
+}
+
+func leafHash(commitment []byte) []byte {
+	hasher := sha256.New()
+	hasher.Write([]byte{leafPrefix})
+	hasher.Write(commitment)
+	return hasher.Sum(nil)
+}
+
+func nodeHash(left, right []byte) []byte {
+	hasher := sha256.New()
+	hasher.Write([]byte{nodePrefix})
+	hasher.Write(left)
+	hasher.Write(right)
+	return hasher.Sum(nil)
+}
+
+// merkleLevels builds the tree bottom-up. Level 0 holds the commitments
+// themselves, level 1 their leaf hashes, and the last level the root.
+func merkleLevels(commitments [][]byte) [][][]byte {
+	leaves := make([][]byte, len(commitments))
+	for i, c := range commitments {
+		leaves[i] = leafHash(c)
+	}
+	levels := [][][]byte{commitments, leaves}
+	for level := leaves; len(level) > 1; {
+		next := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 < len(level) {
+				next = append(next, nodeHash(level[i], level[i+1]))
+			} else {
+				next = append(next, level[i])
+			}
+		}
+		levels = append(levels, next)
+		level = next
+	}
+	return levels
+}
+
+// merkleRoot recomputes the root of a tree of count leaves from one
+// commitment and its path
+func merkleRoot(commitment []byte, index, count int, path [][]byte) ([]byte, error) {
+	if count <= 0 || index < 0 || index >= count {
+		return nil, fmt.Errorf("item %d is outside a batch of %d", index, count)
+	}
+	node := leafHash(commitment)
+	for n := count; n > 1; n = (n + 1) / 2 {
+		if sibling := index ^ 1; sibling < n {
+			if len(path) == 0 {
+				return nil, fmt.Errorf("inclusion path is too short")
+			}
+			if index%2 == 0 {
+				node = nodeHash(node, path[0])
+			} else {
+				node = nodeHash(path[0], node)
+			}
+			path = path[1:]
+		}
+		index /= 2
+	}
+	if len(path) != 0 {
+		return nil, fmt.Errorf("inclusion path is too long")
+	}
+	return node, nil
+}
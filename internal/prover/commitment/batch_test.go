@@ -0,0 +1,134 @@
+package commitment
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/gabrielrondon/zapiki/internal/models"
+	"github.com/gabrielrondon/zapiki/internal/prover"
+)
+
+func commitBatch(t *testing.T, p *CommitmentProver, items []*models.InputData) prover.BatchCommitment {
+	t.Helper()
+	i := 0
+	batch, err := p.CommitBatch(context.Background(), func() (*models.InputData, error) {
+		if i == len(items) {
+			return nil, io.EOF
+		}
+		i++
+		return items[i-1], nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to commit batch: %v", err)
+	}
+	return batch
+}
+
+func TestCommitmentProver_CommitBatch(t *testing.T) {
+	p, err := NewCommitmentProver()
+	if err != nil {
+		t.Fatalf("Failed to create prover: %v", err)
+	}
+	ctx := context.Background()
+
+	// Sizes with and without a full binary tree
+	for _, size := range []int{1, 2, 5, 8, 13} {
+		items := make([]*models.InputData, size)
+		for i := range items {
+			items[i] = stringData(fmt.Sprintf("record %d", i))
+		}
+		batch := commitBatch(t, p, items)
+		if batch.Len() != size {
+			t.Fatalf("Expected %d items, got %d", size, batch.Len())
+		}
+
+		for i := range items {
+			inclusion, err := batch.InclusionProof(i)
+			if err != nil {
+				t.Fatalf("Failed to get inclusion proof: %v", err)
+			}
+			resp, err := p.VerifyInclusion(ctx, &prover.InclusionRequest{
+				Root:            batch.Root(),
+				Inclusion:       inclusion,
+				VerificationKey: batch.VerificationKey(),
+				Data:            items[i],
+			})
+			if err != nil {
+				t.Fatalf("Failed to verify inclusion: %v", err)
+			}
+			if !resp.Valid {
+				t.Errorf("Batch of %d: item %d should verify, got: %s", size, i, resp.ErrorMessage)
+			}
+		}
+	}
+}
+
+func TestCommitmentProver_VerifyInclusion_Rejects(t *testing.T) {
+	p, err := NewCommitmentProver()
+	if err != nil {
+		t.Fatalf("Failed to create prover: %v", err)
+	}
+	ctx := context.Background()
+	items := []*models.InputData{stringData("a"), stringData("b"), stringData("c")}
+	batch := commitBatch(t, p, items)
+
+	inclusionJSON, _ := batch.InclusionProof(1)
+	var inclusion InclusionProof
+	if err := json.Unmarshal(inclusionJSON, &inclusion); err != nil {
+		t.Fatalf("Failed to parse inclusion proof: %v", err)
+	}
+	var root BatchRoot
+	if err := json.Unmarshal(batch.Root(), &root); err != nil {
+		t.Fatalf("Failed to parse batch root: %v", err)
+	}
+
+	verify := func(rootValue BatchRoot, proof InclusionProof, data *models.InputData) bool {
+		rootJSON, _ := json.Marshal(rootValue)
+		proofJSON, _ := json.Marshal(proof)
+		resp, err := p.VerifyInclusion(ctx, &prover.InclusionRequest{Root: rootJSON, Inclusion: proofJSON, Data: data})
+		if err != nil {
+			t.Fatalf("Failed to verify inclusion: %v", err)
+		}
+		return resp.Valid
+	}
+
+	// Without data only membership is checked, so the nonce can be withheld
+	withheld := inclusion
+	withheld.Nonce = ""
+	if !verify(root, withheld, nil) {
+		t.Fatal("Inclusion without data should verify")
+	}
+
+	if verify(root, inclusion, stringData("x")) {
+		t.Error("Inclusion should not open to other data")
+	}
+
+	// Moving the data into the nonce leaves SHA256(data || nonce) unchanged
+	shifted := inclusion
+	shifted.Nonce = hex.EncodeToString([]byte("b")) + inclusion.Nonce
+	if verify(root, shifted, stringData("")) {
+		t.Error("Inclusion should not open with bytes moved from the data to the nonce")
+	}
+
+	moved := inclusion
+	moved.Index = 0
+	if verify(root, moved, nil) {
+		t.Error("Inclusion should not verify at another index")
+	}
+
+	forged := inclusion
+	forged.Commitment = inclusion.Path[0]
+	if verify(root, forged, nil) {
+		t.Error("Inclusion of another commitment should not verify")
+	}
+
+	resized := root
+	resized.Count = 2
+	if verify(resized, inclusion, nil) {
+		t.Error("Root with a changed count should not verify")
+	}
+}
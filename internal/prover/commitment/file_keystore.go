@@ -0,0 +1,332 @@
+package commitment
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gabrielrondon/zapiki/internal/prover"
+	"golang.org/x/crypto/scrypt"
+)
+
+// keyFileVersion identifies the encrypted key file format
+const keyFileVersion = 1
+
+// keyFileAAD binds the ciphertext to its purpose
+var keyFileAAD = []byte("zapiki-commitment-keystore-v1")
+
+// scrypt parameters for deriving the file key from the passphrase
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// staleLockAge is how old a lock file must be before it is assumed to be
+// left behind by a crashed process
+const staleLockAge = time.Minute
+
+// keyFile is the on-disk format: the key ring encrypted with AES-256-GCM
+// under a key derived from the passphrase with scrypt
+type keyFile struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	N          int    `json:"n"`
+	R          int    `json:"r"`
+	P          int    `json:"p"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// FileKeyStore keeps signing keys in a passphrase-encrypted file. Processes
+// sharing the file (the API server and the workers) sign with the same key:
+// the file is re-read whenever it changes, and writes are serialized with a
+// lock file next to it.
+type FileKeyStore struct {
+	path       string
+	passphrase []byte
+	rotation   time.Duration
+
+	mu      sync.Mutex
+	ring    keyRing
+	modTime time.Time
+	size    int64
+}
+
+// NewFileKeyStore opens the key file at path, creating it with a fresh key
+// if it does not exist
+func NewFileKeyStore(path, passphrase string) (*FileKeyStore, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("a passphrase is required to encrypt the key store")
+	}
+	s := &FileKeyStore{path: path, passphrase: []byte(passphrase)}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return nil, fmt.Errorf("failed to create key store directory: %w", err)
+		}
+		if _, err := s.rotateLocked(false); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	if _, err := s.ring.active(); err != nil {
+		return nil, fmt.Errorf("invalid key store %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// SetRotationInterval rotates the active key once it is older than
+// interval, when the next proof is signed (zero disables rotation)
+func (s *FileKeyStore) SetRotationInterval(interval time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rotation = interval
+}
+
+// Signer returns the active signing key, rotating it first when it is due
+func (s *FileKeyStore) Signer() (Signer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+	key, err := s.ring.active()
+	if err != nil {
+		return nil, err
+	}
+	if s.rotationDue(key) {
+		if _, err := s.rotateLocked(true); err != nil {
+			return nil, err
+		}
+		if key, err = s.ring.active(); err != nil {
+			return nil, err
+		}
+	}
+	return newEd25519Signer(key.PrivateKey), nil
+}
+
+// Key returns a current or retired key by ID
+func (s *FileKeyStore) Key(keyID string) (*prover.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+	return s.ring.find(keyID)
+}
+
+// Keys lists every key, the active one first
+func (s *FileKeyStore) Keys() ([]prover.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+	return s.ring.list(), nil
+}
+
+// Rotate makes a new key active and retires the previous one
+func (s *FileKeyStore) Rotate() (*prover.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rotateLocked(false)
+}
+
+func (s *FileKeyStore) rotationDue(key *storedKey) bool {
+	return s.rotation > 0 && time.Since(key.CreatedAt) >= s.rotation
+}
+
+// rotateLocked rotates under the file lock, re-reading the file first so a
+// rotation by another process is not lost. With onlyIfDue, it returns the
+// active key if another process rotated in the meantime.
+func (s *FileKeyStore) rotateLocked(onlyIfDue bool) (*prover.PublicKey, error) {
+	unlock, err := lockFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if _, err := os.Stat(s.path); err == nil {
+		if err := s.load(); err != nil {
+			return nil, err
+		}
+	}
+	if onlyIfDue {
+		if key, err := s.ring.active(); err == nil && !s.rotationDue(key) {
+			info := key.PublicKey
+			return &info, nil
+		}
+	}
+
+	info, err := s.ring.rotate(time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// refresh re-reads the file if another process changed it
+func (s *FileKeyStore) refresh() error {
+	stat, err := os.Stat(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to stat key store: %w", err)
+	}
+	if stat.ModTime().Equal(s.modTime) && stat.Size() == s.size {
+		return nil
+	}
+	return s.load()
+}
+
+func (s *FileKeyStore) load() error {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to read key store: %w", err)
+	}
+	stat, err := os.Stat(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to stat key store: %w", err)
+	}
+
+	var file keyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse key store: %w", err)
+	}
+	if file.Version != keyFileVersion || file.KDF != "scrypt" {
+		return fmt.Errorf("unsupported key store version %d (%s)", file.Version, file.KDF)
+	}
+
+	aead, err := s.cipher(file.Salt, file.N, file.R, file.P)
+	if err != nil {
+		return err
+	}
+	plaintext, err := aead.Open(nil, file.Nonce, file.Ciphertext, keyFileAAD)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt key store: wrong passphrase or corrupted file")
+	}
+
+	var ring keyRing
+	if err := json.Unmarshal(plaintext, &ring); err != nil {
+		return fmt.Errorf("failed to parse key store: %w", err)
+	}
+
+	s.ring = ring
+	s.modTime = stat.ModTime()
+	s.size = stat.Size()
+	return nil
+}
+
+// save encrypts the key ring under a fresh salt and nonce and replaces the
+// file atomically
+func (s *FileKeyStore) save() error {
+	plaintext, err := json.Marshal(&s.ring)
+	if err != nil {
+		return fmt.Errorf("failed to marshal key store: %w", err)
+	}
+
+	file := keyFile{Version: keyFileVersion, KDF: "scrypt", N: scryptN, R: scryptR, P: scryptP}
+	file.Salt = make([]byte, 16)
+	if _, err := rand.Read(file.Salt); err != nil {
+		return fmt.Errorf("failed to generate salt: %w", err)
+	}
+	aead, err := s.cipher(file.Salt, file.N, file.R, file.P)
+	if err != nil {
+		return err
+	}
+	file.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(file.Nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	file.Ciphertext = aead.Seal(nil, file.Nonce, plaintext, keyFileAAD)
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal key store: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write key store: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write key store: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write key store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write key store: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to replace key store: %w", err)
+	}
+
+	stat, err := os.Stat(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to stat key store: %w", err)
+	}
+	s.modTime = stat.ModTime()
+	s.size = stat.Size()
+	return nil
+}
+
+func (s *FileKeyStore) cipher(salt []byte, n, r, p int) (cipher.AEAD, error) {
+	key, err := scrypt.Key(s.passphrase, salt, n, r, p, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key store key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// lockFile takes an exclusive lock on path by creating path.lock, waiting
+// for other holders, and returns the function releasing it
+func lockFile(path string) (func(), error) {
+	lockPath := path + ".lock"
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			f.Close()
+			return func() { _ = os.Remove(lockPath) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("failed to lock key store: %w", err)
+		}
+
+		// Break locks left behind by a crashed process
+		if stat, err := os.Stat(lockPath); err == nil && time.Since(stat.ModTime()) > staleLockAge {
+			_ = os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for key store lock %s", lockPath)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
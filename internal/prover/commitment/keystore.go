@@ -0,0 +1,195 @@
+package commitment
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gabrielrondon/zapiki/internal/prover"
+)
+
+// ErrKeyNotFound is returned for key IDs a key store does not know
+var ErrKeyNotFound = errors.New("signing key not found")
+
+// Signer signs commitments with one Ed25519 key. It is implemented by key
+// stores holding the private key and could be backed by an HSM.
+type Signer interface {
+	// KeyID identifies the key in proofs and in the published key set
+	KeyID() string
+	PublicKey() ed25519.PublicKey
+	Sign(message []byte) ([]byte, error)
+}
+
+// KeyStore holds the active signing key and the public keys of retired
+// ones, so proofs signed before a rotation still verify
+type KeyStore interface {
+	// Signer returns the active signing key
+	Signer() (Signer, error)
+	// Key returns a current or retired key by ID
+	Key(keyID string) (*prover.PublicKey, error)
+	// Keys lists every key, the active one first
+	Keys() ([]prover.PublicKey, error)
+	// Rotate makes a new key active and retires the previous one
+	Rotate() (*prover.PublicKey, error)
+}
+
+// Lifecycle states of a signing key
+const (
+	KeyStatusActive  = "active"
+	KeyStatusRetired = "retired"
+)
+
+// keyID derives a key's ID from its public key, so it cannot be forged for
+// another key
+func keyID(publicKey ed25519.PublicKey) string {
+	sum := sha256.Sum256(publicKey)
+	return hex.EncodeToString(sum[:8])
+}
+
+// ed25519Signer signs with a private key held in memory
+type ed25519Signer struct {
+	id         string
+	privateKey ed25519.PrivateKey
+}
+
+func newEd25519Signer(privateKey ed25519.PrivateKey) *ed25519Signer {
+	return &ed25519Signer{
+		id:         keyID(privateKey.Public().(ed25519.PublicKey)),
+		privateKey: privateKey,
+	}
+}
+
+func (s *ed25519Signer) KeyID() string { return s.id }
+
+func (s *ed25519Signer) PublicKey() ed25519.PublicKey {
+	return s.privateKey.Public().(ed25519.PublicKey)
+}
+
+func (s *ed25519Signer) Sign(message []byte) ([]byte, error) {
+	return ed25519.Sign(s.privateKey, message), nil
+}
+
+// storedKey is a key as kept by the key stores; retired keys drop their
+// private key
+type storedKey struct {
+	prover.PublicKey
+	PrivateKey []byte `json:"private_key,omitempty"`
+}
+
+// keyRing is the set of keys shared by the key store implementations
+type keyRing struct {
+	Keys []storedKey `json:"keys"`
+}
+
+func (r *keyRing) active() (*storedKey, error) {
+	for i := range r.Keys {
+		if r.Keys[i].Status == KeyStatusActive {
+			if len(r.Keys[i].PrivateKey) != ed25519.PrivateKeySize {
+				return nil, fmt.Errorf("active key %s has no valid private key", r.Keys[i].KeyID)
+			}
+			return &r.Keys[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no active signing key")
+}
+
+func (r *keyRing) find(id string) (*prover.PublicKey, error) {
+	for i := range r.Keys {
+		if r.Keys[i].KeyID == id {
+			info := r.Keys[i].PublicKey
+			return &info, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, id)
+}
+
+func (r *keyRing) list() []prover.PublicKey {
+	keys := make([]prover.PublicKey, 0, len(r.Keys))
+	for _, key := range r.Keys {
+		keys = append(keys, key.PublicKey)
+	}
+	return keys
+}
+
+// rotate generates a new active key, placing it first
+func (r *keyRing) rotate(now time.Time) (*prover.PublicKey, error) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key pair: %w", err)
+	}
+
+	for i := range r.Keys {
+		if r.Keys[i].Status == KeyStatusActive {
+			retiredAt := now
+			r.Keys[i].Status = KeyStatusRetired
+			r.Keys[i].RetiredAt = &retiredAt
+			r.Keys[i].PrivateKey = nil
+		}
+	}
+
+	key := storedKey{
+		PublicKey: prover.PublicKey{
+			KeyID:     keyID(publicKey),
+			Algorithm: "ed25519",
+			PublicKey: hex.EncodeToString(publicKey),
+			Status:    KeyStatusActive,
+			CreatedAt: now,
+		},
+		PrivateKey: privateKey,
+	}
+	r.Keys = append([]storedKey{key}, r.Keys...)
+	return &key.PublicKey, nil
+}
+
+// MemoryKeyStore keeps keys in memory only: every process gets its own keys
+// and loses them on exit. It suits tests and local development.
+type MemoryKeyStore struct {
+	mu   sync.RWMutex
+	ring keyRing
+}
+
+// NewMemoryKeyStore creates a key store with a fresh active key
+func NewMemoryKeyStore() (*MemoryKeyStore, error) {
+	s := &MemoryKeyStore{}
+	if _, err := s.Rotate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Signer returns the active signing key
+func (s *MemoryKeyStore) Signer() (Signer, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, err := s.ring.active()
+	if err != nil {
+		return nil, err
+	}
+	return newEd25519Signer(key.PrivateKey), nil
+}
+
+// Key returns a current or retired key by ID
+func (s *MemoryKeyStore) Key(keyID string) (*prover.PublicKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.ring.find(keyID)
+}
+
+// Keys lists every key, the active one first
+func (s *MemoryKeyStore) Keys() ([]prover.PublicKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.ring.list(), nil
+}
+
+// Rotate makes a new key active and retires the previous one
+func (s *MemoryKeyStore) Rotate() (*prover.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ring.rotate(time.Now().UTC())
+}
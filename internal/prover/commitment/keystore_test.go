@@ -0,0 +1,153 @@
+package commitment
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gabrielrondon/zapiki/internal/models"
+	"github.com/gabrielrondon/zapiki/internal/prover"
+)
+
+func TestFileKeyStore_Persistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+
+	store, err := NewFileKeyStore(path, "correct horse battery staple")
+	if err != nil {
+		t.Fatalf("Failed to create key store: %v", err)
+	}
+	signer, err := store.Signer()
+	if err != nil {
+		t.Fatalf("Failed to get signer: %v", err)
+	}
+
+	// Another process opening the file signs with the same key
+	reopened, err := NewFileKeyStore(path, "correct horse battery staple")
+	if err != nil {
+		t.Fatalf("Failed to reopen key store: %v", err)
+	}
+	other, err := reopened.Signer()
+	if err != nil {
+		t.Fatalf("Failed to get signer: %v", err)
+	}
+	if other.KeyID() != signer.KeyID() {
+		t.Errorf("Expected key %s after reopening, got %s", signer.KeyID(), other.KeyID())
+	}
+
+	if _, err := NewFileKeyStore(path, "wrong passphrase"); err == nil {
+		t.Error("Opening the key store with a wrong passphrase should fail")
+	}
+}
+
+func TestFileKeyStore_Rotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	store, err := NewFileKeyStore(path, "passphrase")
+	if err != nil {
+		t.Fatalf("Failed to create key store: %v", err)
+	}
+	peer, err := NewFileKeyStore(path, "passphrase")
+	if err != nil {
+		t.Fatalf("Failed to open key store: %v", err)
+	}
+
+	before, _ := store.Signer()
+	rotated, err := store.Rotate()
+	if err != nil {
+		t.Fatalf("Failed to rotate: %v", err)
+	}
+	if rotated.KeyID == before.KeyID() {
+		t.Fatal("Rotation should create a new key")
+	}
+
+	// The peer picks up the rotation from the file
+	after, err := peer.Signer()
+	if err != nil {
+		t.Fatalf("Failed to get signer: %v", err)
+	}
+	if after.KeyID() != rotated.KeyID {
+		t.Errorf("Expected peer to sign with %s, got %s", rotated.KeyID, after.KeyID())
+	}
+
+	keys, err := peer.Keys()
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	if len(keys) != 2 || keys[0].Status != KeyStatusActive || keys[1].Status != KeyStatusRetired || keys[1].RetiredAt == nil {
+		t.Errorf("Expected the active key then the retired one, got %+v", keys)
+	}
+}
+
+func TestFileKeyStore_ScheduledRotation(t *testing.T) {
+	store, err := NewFileKeyStore(filepath.Join(t.TempDir(), "keys.json"), "passphrase")
+	if err != nil {
+		t.Fatalf("Failed to create key store: %v", err)
+	}
+	first, _ := store.Signer()
+
+	store.SetRotationInterval(time.Nanosecond)
+	second, err := store.Signer()
+	if err != nil {
+		t.Fatalf("Failed to get signer: %v", err)
+	}
+	if second.KeyID() == first.KeyID() {
+		t.Error("Expected the key to be rotated once due")
+	}
+}
+
+func TestCommitmentProver_VerifyAfterRotation(t *testing.T) {
+	p, err := NewCommitmentProver()
+	if err != nil {
+		t.Fatalf("Failed to create prover: %v", err)
+	}
+	store, err := NewFileKeyStore(filepath.Join(t.TempDir(), "keys.json"), "passphrase")
+	if err != nil {
+		t.Fatalf("Failed to create key store: %v", err)
+	}
+	p.SetKeyStore(store)
+
+	dataJSON, _ := json.Marshal("signed before rotation")
+	ctx := context.Background()
+	resp, err := p.Generate(ctx, &prover.ProofRequest{
+		Data: &models.InputData{Type: models.DataTypeString, Value: dataJSON},
+	})
+	if err != nil {
+		t.Fatalf("Failed to generate proof: %v", err)
+	}
+
+	var proof CommitmentProof
+	if err := json.Unmarshal(resp.Proof, &proof); err != nil {
+		t.Fatalf("Failed to parse proof: %v", err)
+	}
+	if proof.KeyID == "" {
+		t.Fatal("Expected the proof to carry a key ID")
+	}
+
+	if _, err := store.Rotate(); err != nil {
+		t.Fatalf("Failed to rotate: %v", err)
+	}
+
+	// The retired key is found by the proof's key ID
+	verifyResp, err := p.Verify(ctx, &prover.VerifyRequest{
+		Proof:           resp.Proof,
+		VerificationKey: json.RawMessage(`{}`),
+	})
+	if err != nil {
+		t.Fatalf("Failed to verify proof: %v", err)
+	}
+	if !verifyResp.Valid {
+		t.Errorf("Expected proof to verify after rotation, got: %s", verifyResp.ErrorMessage)
+	}
+
+	// A proof does not verify under another key's ID
+	keys, _ := p.PublicKeys()
+	vk, _ := json.Marshal(map[string]string{"key_id": keys[0].KeyID})
+	verifyResp, err = p.Verify(ctx, &prover.VerifyRequest{Proof: resp.Proof, VerificationKey: vk})
+	if err != nil {
+		t.Fatalf("Failed to verify proof: %v", err)
+	}
+	if verifyResp.Valid {
+		t.Error("Proof should not verify against the new key")
+	}
+}
@@ -0,0 +1,177 @@
+package commitment
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gabrielrondon/zapiki/internal/prover"
+)
+
+// equalityDomain separates equality signatures from commitment signatures
+const equalityDomain = "zapiki-commitment-equality-v1"
+
+// EqualityProof states that two commitment proofs hide the same data. The
+// service checks both openings before signing it, so a relying party learns
+// that the data is equal without seeing it.
+type EqualityProof struct {
+	Commitments [2]string `json:"commitments"`
+	Signature   string    `json:"signature"`
+	Timestamp   time.Time `json:"timestamp"`
+	PublicKey   string    `json:"public_key"`
+	KeyID       string    `json:"key_id"`
+}
+
+// VerifyOpening checks the proof's signature, then that SHA256(data||nonce)
+// is the signed commitment
+func (p *CommitmentProver) VerifyOpening(ctx context.Context, opening *prover.Opening) (*prover.VerifyResponse, error) {
+	if _, err := p.open(ctx, opening); err != nil {
+		return &prover.VerifyResponse{
+			Valid:        false,
+			ErrorMessage: err.Error(),
+		}, nil
+	}
+	return &prover.VerifyResponse{Valid: true}, nil
+}
+
+// ProveEquality signs that both commitments open to the same data. Both
+// proofs must have been signed by this service's keys.
+func (p *CommitmentProver) ProveEquality(ctx context.Context, first, second *prover.Opening) (*prover.ProofResponse, error) {
+	startTime := time.Now()
+
+	var commitments [2]string
+	var data [2][]byte
+	for i, opening := range []*prover.Opening{first, second} {
+		// Ignore the caller's verification key: the proofs must verify
+		// under the published keys
+		own := *opening
+		own.VerificationKey = nil
+		proof, err := p.open(ctx, &own)
+		if err != nil {
+			return nil, fmt.Errorf("%w: opening %d: %v", prover.ErrInvalidOpening, i+1, err)
+		}
+		if data[i], err = committedBytes(opening.Data); err != nil {
+			return nil, fmt.Errorf("%w: opening %d: %v", prover.ErrInvalidOpening, i+1, err)
+		}
+		commitments[i] = proof.Commitment
+	}
+	if !bytes.Equal(data[0], data[1]) {
+		return nil, fmt.Errorf("%w: openings reveal different data", prover.ErrInvalidOpening)
+	}
+
+	signer, err := p.keys.Signer()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get signing key: %w", err)
+	}
+	proof := EqualityProof{
+		Commitments: commitments,
+		Timestamp:   time.Now().UTC(),
+		PublicKey:   hex.EncodeToString(signer.PublicKey()),
+		KeyID:       signer.KeyID(),
+	}
+	signature, err := signer.Sign(proof.message())
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign equality proof: %w", err)
+	}
+	proof.Signature = hex.EncodeToString(signature)
+
+	proofJSON, err := json.Marshal(proof)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal proof: %w", err)
+	}
+	vkJSON, err := verificationKey(signer)
+	if err != nil {
+		return nil, err
+	}
+
+	return &prover.ProofResponse{
+		Proof:            proofJSON,
+		VerificationKey:  vkJSON,
+		GenerationTimeMs: time.Since(startTime).Milliseconds(),
+		Metadata: map[string]interface{}{
+			"proof_type": "commitment-equality",
+			"sig_algo":   "ed25519",
+			"key_id":     signer.KeyID(),
+		},
+	}, nil
+}
+
+// VerifyEquality verifies an equality proof's signature
+func (p *CommitmentProver) VerifyEquality(ctx context.Context, req *prover.VerifyRequest) (*prover.VerifyResponse, error) {
+	var proof EqualityProof
+	if err := json.Unmarshal(req.Proof, &proof); err != nil {
+		return &prover.VerifyResponse{
+			Valid:        false,
+			ErrorMessage: fmt.Sprintf("failed to parse proof: %v", err),
+		}, nil
+	}
+
+	publicKey, err := p.publicKey(req.VerificationKey, proof.KeyID)
+	if err != nil {
+		return &prover.VerifyResponse{
+			Valid:        false,
+			ErrorMessage: err.Error(),
+		}, nil
+	}
+	signature, err := hex.DecodeString(proof.Signature)
+	if err != nil {
+		return &prover.VerifyResponse{
+			Valid:        false,
+			ErrorMessage: fmt.Sprintf("failed to decode signature: %v", err),
+		}, nil
+	}
+
+	return &prover.VerifyResponse{
+		Valid: ed25519.Verify(publicKey, proof.message(), signature),
+	}, nil
+}
+
+// message is what an equality proof's signature covers
+func (e *EqualityProof) message() []byte {
+	return []byte(fmt.Sprintf("%s\n%s\n%s\n%s", equalityDomain, e.Commitments[0], e.Commitments[1], e.Timestamp.Format(time.RFC3339Nano)))
+}
+
+// open verifies a commitment proof and checks that the opening recomputes
+// its commitment
+func (p *CommitmentProver) open(ctx context.Context, opening *prover.Opening) (*CommitmentProof, error) {
+	verifyResp, err := p.Verify(ctx, &prover.VerifyRequest{
+		Proof:           opening.Proof,
+		VerificationKey: opening.VerificationKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !verifyResp.Valid {
+		if verifyResp.ErrorMessage != "" {
+			return nil, fmt.Errorf("invalid commitment proof: %s", verifyResp.ErrorMessage)
+		}
+		return nil, fmt.Errorf("invalid commitment proof: signature does not verify")
+	}
+
+	var proof CommitmentProof
+	if err := json.Unmarshal(opening.Proof, &proof); err != nil {
+		return nil, fmt.Errorf("failed to parse proof: %w", err)
+	}
+	dataBytes, err := committedBytes(opening.Data)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hex.DecodeString(opening.Nonce)
+	if err != nil || len(nonce) != nonceSize {
+		return nil, fmt.Errorf("invalid nonce: must be %d hex-encoded bytes", nonceSize)
+	}
+	commitment, err := hex.DecodeString(proof.Commitment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode commitment: %w", err)
+	}
+
+	if subtle.ConstantTimeCompare(commitmentHash(dataBytes, nonce), commitment) != 1 {
+		return nil, fmt.Errorf("data and nonce do not open the commitment")
+	}
+	return &proof, nil
+}
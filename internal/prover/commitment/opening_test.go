@@ -0,0 +1,137 @@
+package commitment
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/gabrielrondon/zapiki/internal/models"
+	"github.com/gabrielrondon/zapiki/internal/prover"
+)
+
+func stringData(s string) *models.InputData {
+	value, _ := json.Marshal(s)
+	return &models.InputData{Type: models.DataTypeString, Value: value}
+}
+
+// commit generates a proof and returns its opening, with the nonce removed
+// from the proof as the holder would before sharing it
+func commit(t *testing.T, p *CommitmentProver, data *models.InputData) *prover.Opening {
+	t.Helper()
+	resp, err := p.Generate(context.Background(), &prover.ProofRequest{Data: data})
+	if err != nil {
+		t.Fatalf("Failed to generate proof: %v", err)
+	}
+	var proof CommitmentProof
+	if err := json.Unmarshal(resp.Proof, &proof); err != nil {
+		t.Fatalf("Failed to parse proof: %v", err)
+	}
+	nonce := proof.Nonce
+	proof.Nonce = ""
+	shared, _ := json.Marshal(proof)
+	return &prover.Opening{Proof: shared, Data: data, Nonce: nonce}
+}
+
+func TestCommitmentProver_VerifyOpening(t *testing.T) {
+	p, err := NewCommitmentProver()
+	if err != nil {
+		t.Fatalf("Failed to create prover: %v", err)
+	}
+	ctx := context.Background()
+	opening := commit(t, p, stringData("salary: 85000"))
+
+	resp, err := p.VerifyOpening(ctx, opening)
+	if err != nil {
+		t.Fatalf("Failed to verify opening: %v", err)
+	}
+	if !resp.Valid {
+		t.Errorf("Expected opening to verify, got: %s", resp.ErrorMessage)
+	}
+
+	wrongData := *opening
+	wrongData.Data = stringData("salary: 95000")
+	if resp, _ := p.VerifyOpening(ctx, &wrongData); resp.Valid {
+		t.Error("Opening with different data should not verify")
+	}
+
+	wrongNonce := *opening
+	wrongNonce.Nonce = "00"
+	if resp, _ := p.VerifyOpening(ctx, &wrongNonce); resp.Valid {
+		t.Error("Opening with a different nonce should not verify")
+	}
+
+	// The commitment must carry this service's signature
+	other, _ := NewCommitmentProver()
+	if resp, _ := other.VerifyOpening(ctx, opening); resp.Valid {
+		t.Error("Opening of a proof signed by an unknown key should not verify")
+	}
+}
+
+func TestCommitmentProver_VerifyOpening_RejectsShiftedBytes(t *testing.T) {
+	p, err := NewCommitmentProver()
+	if err != nil {
+		t.Fatalf("Failed to create prover: %v", err)
+	}
+	ctx := context.Background()
+	opening := commit(t, p, stringData("salary: 85000"))
+
+	// Moving the last byte of the data to the front of the nonce leaves
+	// SHA256(data || nonce) unchanged
+	shifted := *opening
+	shifted.Data = stringData("salary: 8500")
+	shifted.Nonce = hex.EncodeToString([]byte("0")) + opening.Nonce
+	resp, err := p.VerifyOpening(ctx, &shifted)
+	if err != nil {
+		t.Fatalf("Failed to verify opening: %v", err)
+	}
+	if resp.Valid {
+		t.Error("Opening with bytes moved from the data to the nonce should not verify")
+	}
+}
+
+func TestCommitmentProver_Equality(t *testing.T) {
+	p, err := NewCommitmentProver()
+	if err != nil {
+		t.Fatalf("Failed to create prover: %v", err)
+	}
+	ctx := context.Background()
+	first := commit(t, p, stringData("passport 123456789"))
+	second := commit(t, p, stringData("passport 123456789"))
+
+	resp, err := p.ProveEquality(ctx, first, second)
+	if err != nil {
+		t.Fatalf("Failed to prove equality: %v", err)
+	}
+	var proof EqualityProof
+	if err := json.Unmarshal(resp.Proof, &proof); err != nil {
+		t.Fatalf("Failed to parse proof: %v", err)
+	}
+
+	verifyResp, err := p.VerifyEquality(ctx, &prover.VerifyRequest{Proof: resp.Proof, VerificationKey: resp.VerificationKey})
+	if err != nil {
+		t.Fatalf("Failed to verify equality: %v", err)
+	}
+	if !verifyResp.Valid {
+		t.Errorf("Expected equality proof to verify, got: %s", verifyResp.ErrorMessage)
+	}
+
+	// The proof names other commitments
+	proof.Commitments[1] = proof.Commitments[0]
+	tampered, _ := json.Marshal(proof)
+	verifyResp, _ = p.VerifyEquality(ctx, &prover.VerifyRequest{Proof: tampered, VerificationKey: resp.VerificationKey})
+	if verifyResp.Valid {
+		t.Error("Tampered equality proof should not verify")
+	}
+
+	// A commitment signature is not an equality proof
+	verifyResp, _ = p.VerifyEquality(ctx, &prover.VerifyRequest{Proof: first.Proof, VerificationKey: resp.VerificationKey})
+	if verifyResp.Valid {
+		t.Error("Commitment proof should not verify as an equality proof")
+	}
+
+	different := commit(t, p, stringData("passport 987654321"))
+	if _, err := p.ProveEquality(ctx, first, different); err == nil {
+		t.Error("Equality of different data should not be proven")
+	}
+}
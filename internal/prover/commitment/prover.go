@@ -4,9 +4,9 @@ import (
 	"context"
 	"crypto/ed25519"
 	"crypto/rand"
-	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -14,36 +14,61 @@ import (
 	"github.com/gabrielrondon/zapiki/internal/prover"
 )
 
+// nonceSize is the length of a commitment nonce. Openings must use exactly
+// this many bytes: SHA256(data || nonce) does not separate the two, so a
+// nonce of any other length could absorb bytes of the data.
+const nonceSize = 32
+
 // CommitmentProver implements a simple commitment-based proof system
 // using SHA256 hashing and Ed25519 signatures
 type CommitmentProver struct {
-	privateKey ed25519.PrivateKey
-	publicKey  ed25519.PublicKey
+	keys KeyStore
 }
 
-// CommitmentProof represents a commitment proof
+// CommitmentProof represents a commitment proof. The nonce opens the
+// commitment: the holder removes it before sharing the proof when the data
+// should stay hidden.
 type CommitmentProof struct {
 	Commitment string    `json:"commitment"`
-	Nonce      string    `json:"nonce"`
+	Nonce      string    `json:"nonce,omitempty"`
 	Signature  string    `json:"signature"`
 	Timestamp  time.Time `json:"timestamp"`
 	PublicKey  string    `json:"public_key"`
+	// KeyID identifies the signing key in the published key set
+	KeyID string `json:"key_id,omitempty"`
+}
+
+// commitmentVerificationKey is the verification key of commitment proofs.
+// Either field locates the signing key; KeyID looks it up in the key store.
+type commitmentVerificationKey struct {
+	PublicKey string `json:"public_key,omitempty"`
+	KeyID     string `json:"key_id,omitempty"`
 }
 
-// NewCommitmentProver creates a new commitment prover
+// NewCommitmentProver creates a new commitment prover that signs with an
+// in-memory key; use SetKeyStore for a key that survives restarts
 func NewCommitmentProver() (*CommitmentProver, error) {
-	// Generate Ed25519 key pair
-	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	keys, err := NewMemoryKeyStore()
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate key pair: %w", err)
+		return nil, err
 	}
 
 	return &CommitmentProver{
-		privateKey: privateKey,
-		publicKey:  publicKey,
+		keys: keys,
 	}, nil
 }
 
+// SetKeyStore sets where the signing keys come from
+func (p *CommitmentProver) SetKeyStore(keys KeyStore) {
+	p.keys = keys
+}
+
+// PublicKeys returns the published key set: the active key and every
+// retired key proofs may have been signed with
+func (p *CommitmentProver) PublicKeys() ([]prover.PublicKey, error) {
+	return p.keys.Keys()
+}
+
 // Name returns the proof system name
 func (p *CommitmentProver) Name() models.ProofSystemType {
 	return models.ProofSystemCommitment
@@ -51,9 +76,18 @@ func (p *CommitmentProver) Name() models.ProofSystemType {
 
 // Setup is a no-op for commitment proofs
 func (p *CommitmentProver) Setup(ctx context.Context, circuit *models.Circuit) (*prover.SetupResult, error) {
+	signer, err := p.keys.Signer()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get signing key: %w", err)
+	}
+	vkJSON, err := verificationKey(signer)
+	if err != nil {
+		return nil, err
+	}
+
 	return &prover.SetupResult{
 		ProvingKey:      json.RawMessage(`{}`),
-		VerificationKey: json.RawMessage(fmt.Sprintf(`{"public_key":"%s"}`, hex.EncodeToString(p.publicKey))),
+		VerificationKey: vkJSON,
 		Metadata: map[string]interface{}{
 			"setup_required": false,
 		},
@@ -64,48 +98,29 @@ func (p *CommitmentProver) Setup(ctx context.Context, circuit *models.Circuit) (
 func (p *CommitmentProver) Generate(ctx context.Context, req *prover.ProofRequest) (*prover.ProofResponse, error) {
 	startTime := time.Now()
 
-	// Extract data bytes
-	var dataBytes []byte
-	switch req.Data.Type {
-	case models.DataTypeString:
-		var str string
-		if err := json.Unmarshal(req.Data.Value, &str); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal string data: %w", err)
-		}
-		dataBytes = []byte(str)
-
-	case models.DataTypeJSON:
-		dataBytes = []byte(req.Data.Value)
-
-	case models.DataTypeBytes:
-		var hexStr string
-		if err := json.Unmarshal(req.Data.Value, &hexStr); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal bytes data: %w", err)
-		}
-		var err error
-		dataBytes, err = hex.DecodeString(hexStr)
-		if err != nil {
-			return nil, fmt.Errorf("failed to decode hex string: %w", err)
-		}
-
-	default:
-		return nil, fmt.Errorf("unsupported data type: %s", req.Data.Type)
+	dataBytes, err := committedBytes(req.Data)
+	if err != nil {
+		return nil, err
 	}
 
 	// Generate random nonce
-	nonce := make([]byte, 32)
+	nonce := make([]byte, nonceSize)
 	if _, err := rand.Read(nonce); err != nil {
 		return nil, fmt.Errorf("failed to generate nonce: %w", err)
 	}
 
 	// Create commitment: SHA256(data || nonce)
-	hasher := sha256.New()
-	hasher.Write(dataBytes)
-	hasher.Write(nonce)
-	commitment := hasher.Sum(nil)
+	commitment := commitmentHash(dataBytes, nonce)
 
-	// Sign the commitment
-	signature := ed25519.Sign(p.privateKey, commitment)
+	// Sign the commitment with the active key
+	signer, err := p.keys.Signer()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get signing key: %w", err)
+	}
+	signature, err := signer.Sign(commitment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign commitment: %w", err)
+	}
 
 	// Create proof structure
 	proof := CommitmentProof{
@@ -113,7 +128,8 @@ func (p *CommitmentProver) Generate(ctx context.Context, req *prover.ProofReques
 		Nonce:      hex.EncodeToString(nonce),
 		Signature:  hex.EncodeToString(signature),
 		Timestamp:  time.Now().UTC(),
-		PublicKey:  hex.EncodeToString(p.publicKey),
+		PublicKey:  hex.EncodeToString(signer.PublicKey()),
+		KeyID:      signer.KeyID(),
 	}
 
 	proofJSON, err := json.Marshal(proof)
@@ -121,18 +137,24 @@ func (p *CommitmentProver) Generate(ctx context.Context, req *prover.ProofReques
 		return nil, fmt.Errorf("failed to marshal proof: %w", err)
 	}
 
+	vkJSON, err := verificationKey(signer)
+	if err != nil {
+		return nil, err
+	}
+
 	// Calculate generation time
 	generationTime := time.Since(startTime).Milliseconds()
 
 	return &prover.ProofResponse{
 		Proof:            proofJSON,
 		PublicInputs:     json.RawMessage(`{}`),
-		VerificationKey:  json.RawMessage(fmt.Sprintf(`{"public_key":"%s"}`, hex.EncodeToString(p.publicKey))),
+		VerificationKey:  vkJSON,
 		GenerationTimeMs: generationTime,
 		Metadata: map[string]interface{}{
 			"proof_type": "commitment",
 			"hash_algo":  "sha256",
 			"sig_algo":   "ed25519",
+			"key_id":     signer.KeyID(),
 		},
 	}, nil
 }
@@ -148,23 +170,11 @@ func (p *CommitmentProver) Verify(ctx context.Context, req *prover.VerifyRequest
 		}, nil
 	}
 
-	// Parse verification key to get public key
-	var vk struct {
-		PublicKey string `json:"public_key"`
-	}
-	if err := json.Unmarshal(req.VerificationKey, &vk); err != nil {
-		return &prover.VerifyResponse{
-			Valid:        false,
-			ErrorMessage: fmt.Sprintf("failed to parse verification key: %v", err),
-		}, nil
-	}
-
-	// Decode public key
-	publicKeyBytes, err := hex.DecodeString(vk.PublicKey)
+	publicKey, err := p.publicKey(req.VerificationKey, proof.KeyID)
 	if err != nil {
 		return &prover.VerifyResponse{
 			Valid:        false,
-			ErrorMessage: fmt.Sprintf("failed to decode public key: %v", err),
+			ErrorMessage: err.Error(),
 		}, nil
 	}
 
@@ -186,13 +196,129 @@ func (p *CommitmentProver) Verify(ctx context.Context, req *prover.VerifyRequest
 	}
 
 	// Verify signature
-	valid := ed25519.Verify(ed25519.PublicKey(publicKeyBytes), commitment, signature)
+	valid := ed25519.Verify(publicKey, commitment, signature)
 
 	return &prover.VerifyResponse{
 		Valid: valid,
 	}, nil
 }
 
+// publicKey resolves the key a proof signed with proofKeyID verifies under. A
+// key ID, from the verification key or the proof, selects the published key
+// with that ID; a public key given alongside must be that key, or must
+// derive to the ID when the key is not published here. Without a key ID the
+// verification key's public key is used as given.
+func (p *CommitmentProver) publicKey(vkJSON json.RawMessage, proofKeyID string) (ed25519.PublicKey, error) {
+	var vk commitmentVerificationKey
+	if len(vkJSON) > 0 {
+		if err := json.Unmarshal(vkJSON, &vk); err != nil {
+			return nil, fmt.Errorf("failed to parse verification key: %v", err)
+		}
+	}
+
+	// A proof names the key that signed it; it must be the one the
+	// verification key refers to
+	if vk.KeyID != "" && proofKeyID != "" && vk.KeyID != proofKeyID {
+		return nil, fmt.Errorf("proof was signed with a different key")
+	}
+	id := vk.KeyID
+	if id == "" {
+		id = proofKeyID
+	}
+
+	var given ed25519.PublicKey
+	if vk.PublicKey != "" {
+		var err error
+		if given, err = decodePublicKey(vk.PublicKey); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case id == "" && given == nil:
+		return nil, fmt.Errorf("verification key has no public key or key ID")
+	case id == "":
+		return given, nil
+	}
+
+	key, err := p.keys.Key(id)
+	switch {
+	case err == nil:
+		published, err := decodePublicKey(key.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		if given != nil && !given.Equal(published) {
+			return nil, fmt.Errorf("public key does not match key %s", id)
+		}
+		return published, nil
+	case !errors.Is(err, ErrKeyNotFound) || given == nil:
+		return nil, err
+	case keyID(given) != id:
+		// The ID is derived from the key, so a key cannot claim another's ID
+		return nil, fmt.Errorf("public key does not match key %s", id)
+	default:
+		return given, nil
+	}
+}
+
+// decodePublicKey decodes a hex Ed25519 public key
+func decodePublicKey(encoded string) (ed25519.PublicKey, error) {
+	publicKeyBytes, err := hex.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode public key: %v", err)
+	}
+	if len(publicKeyBytes) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key length")
+	}
+	return ed25519.PublicKey(publicKeyBytes), nil
+}
+
+// committedBytes returns the bytes a commitment is computed over
+func committedBytes(data *models.InputData) ([]byte, error) {
+	if data == nil {
+		return nil, fmt.Errorf("data is required")
+	}
+
+	switch data.Type {
+	case models.DataTypeString:
+		var str string
+		if err := json.Unmarshal(data.Value, &str); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal string data: %w", err)
+		}
+		return []byte(str), nil
+
+	case models.DataTypeJSON:
+		return []byte(data.Value), nil
+
+	case models.DataTypeBytes:
+		var hexStr string
+		if err := json.Unmarshal(data.Value, &hexStr); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal bytes data: %w", err)
+		}
+		dataBytes, err := hex.DecodeString(hexStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode hex string: %w", err)
+		}
+		return dataBytes, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported data type: %s", data.Type)
+	}
+}
+
+// verificationKey returns the verification key for proofs signed by signer
+func verificationKey(signer Signer) (json.RawMessage, error) {
+	vkJSON, err := json.Marshal(commitmentVerificationKey{
+		PublicKey: hex.EncodeToString(signer.PublicKey()),
+		KeyID:     signer.KeyID(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal verification key: %w", err)
+	}
+	return vkJSON, nil
+}
+
 // Capabilities returns the capabilities of the commitment proof system
 func (p *CommitmentProver) Capabilities() prover.Capabilities {
 	return prover.Capabilities{
@@ -202,6 +328,7 @@ func (p *CommitmentProver) Capabilities() prover.Capabilities {
 		AsyncOnly:              false,
 		TypicalGenerationTime:  50, // ~50ms
 		MaxProofSize:           512, // ~512 bytes
+		Library:                "crypto/ed25519",
 		Features: []string{
 			"fast-generation",
 			"simple-commitment",
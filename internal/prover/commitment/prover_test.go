@@ -2,6 +2,10 @@ package commitment
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"testing"
 
@@ -106,6 +110,61 @@ func TestCommitmentProver_VerifyInvalidProof(t *testing.T) {
 	}
 }
 
+func TestCommitmentProver_VerifyRejectsForeignKey(t *testing.T) {
+	p, err := NewCommitmentProver()
+	if err != nil {
+		t.Fatalf("Failed to create prover: %v", err)
+	}
+	signer, err := p.keys.Signer()
+	if err != nil {
+		t.Fatalf("Failed to get signing key: %v", err)
+	}
+
+	// Sign a commitment with another key
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	sign := func(id string) json.RawMessage {
+		commitment := sha256.Sum256([]byte("forged"))
+		proof, _ := json.Marshal(CommitmentProof{
+			Commitment: hex.EncodeToString(commitment[:]),
+			Signature:  hex.EncodeToString(ed25519.Sign(privateKey, commitment[:])),
+			PublicKey:  hex.EncodeToString(publicKey),
+			KeyID:      id,
+		})
+		return proof
+	}
+	ownKey := json.RawMessage(`{"public_key":"` + hex.EncodeToString(publicKey) + `"}`)
+
+	ctx := context.Background()
+	for name, req := range map[string]*prover.VerifyRequest{
+		"service key ID in the proof": {Proof: sign(signer.KeyID()), VerificationKey: ownKey},
+		"service key ID in the key": {
+			Proof:           sign(""),
+			VerificationKey: json.RawMessage(`{"public_key":"` + hex.EncodeToString(publicKey) + `","key_id":"` + signer.KeyID() + `"}`),
+		},
+		"unpublished key ID": {Proof: sign("0123456789abcdef"), VerificationKey: ownKey},
+	} {
+		verifyResp, err := p.Verify(ctx, req)
+		if err != nil {
+			t.Fatalf("%s: Verify returned error: %v", name, err)
+		}
+		if verifyResp.Valid {
+			t.Errorf("%s: expected a proof signed with another key to be rejected", name)
+		}
+	}
+
+	// A key that is not published verifies under its own ID
+	verifyResp, err := p.Verify(ctx, &prover.VerifyRequest{Proof: sign(keyID(publicKey)), VerificationKey: ownKey})
+	if err != nil {
+		t.Fatalf("Verify returned error: %v", err)
+	}
+	if !verifyResp.Valid {
+		t.Errorf("Expected a proof to verify under its own key, got: %s", verifyResp.ErrorMessage)
+	}
+}
+
 func TestCommitmentProver_Capabilities(t *testing.T) {
 	p, err := NewCommitmentProver()
 	if err != nil {
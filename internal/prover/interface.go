@@ -3,6 +3,9 @@ package prover
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
+	"time"
 
 	"github.com/gabrielrondon/zapiki/internal/models"
 )
@@ -26,11 +29,297 @@ type ProofSystem interface {
 	Capabilities() Capabilities
 }
 
+// ErrSolidityUnsupported is returned when a proof or circuit cannot be
+// verified by a Solidity contract
+var ErrSolidityUnsupported = errors.New("solidity verification is not supported")
+
+// SolidityExporter is implemented by proof systems whose proofs can be
+// verified on EVM chains
+type SolidityExporter interface {
+	// ExportSolidity writes a verifier contract for the circuit's stored verification key
+	ExportSolidity(ctx context.Context, circuit *models.Circuit, w io.Writer) error
+
+	// SolidityCalldata ABI-encodes a proof and its public inputs as a call
+	// to the exported verifier contract
+	SolidityCalldata(ctx context.Context, req *VerifyRequest) (*SolidityCalldata, error)
+}
+
+// SolidityCalldata is a call to an exported verifier contract
+type SolidityCalldata struct {
+	// Function is the contract function signature, e.g. "verifyProof(uint256[8],uint256[1])"
+	Function string `json:"function"`
+	// Calldata is the 0x-prefixed ABI encoding, including the function selector
+	Calldata string `json:"calldata"`
+	// Proof is the 0x-prefixed proof argument
+	Proof string `json:"proof"`
+	// PublicInputs are the public input arguments as decimal strings
+	PublicInputs []string `json:"public_inputs"`
+}
+
+// ErrInvalidCircuit is returned when a circuit definition cannot be used
+// by its proof system
+var ErrInvalidCircuit = errors.New("invalid circuit definition")
+
+// ErrInvalidWitness is returned when proof inputs cannot be assigned to a
+// circuit's witness
+var ErrInvalidWitness = errors.New("invalid witness")
+
+// CircuitValidator is implemented by proof systems that check a circuit's
+// definition when the circuit is created
+type CircuitValidator interface {
+	// ValidateCircuit returns an error wrapping ErrInvalidCircuit for
+	// definitions the proof system cannot prove
+	ValidateCircuit(ctx context.Context, circuit *models.Circuit) error
+}
+
+// CircuitCatalog is implemented by proof systems with built-in circuit types
+type CircuitCatalog interface {
+	// Circuits lists the circuit types, by name
+	Circuits() []CircuitInfo
+}
+
+// CircuitInfo describes a circuit type a proof system can prove
+type CircuitInfo struct {
+	Name        string         `json:"name"`
+	Version     int            `json:"version"`
+	Description string         `json:"description"`
+	Params      []CircuitParam `json:"params,omitempty"`
+	// InputSchema is the JSON Schema of the proof inputs, when it does not
+	// depend on the circuit definition
+	InputSchema json.RawMessage `json:"input_schema,omitempty"`
+	// PublicInputs names the public inputs in the order proofs list them
+	PublicInputs []string `json:"public_inputs,omitempty"`
+}
+
+// CircuitParam is a circuit_definition param a circuit type accepts
+type CircuitParam struct {
+	Name        string      `json:"name"`
+	Type        string      `json:"type"`
+	Default     interface{} `json:"default,omitempty"`
+	Description string      `json:"description"`
+}
+
+// ErrImportUnsupported is returned when a proof system cannot import
+// circuit files
+var ErrImportUnsupported = errors.New("circuit import is not supported")
+
+// CircuitImporter is implemented by proof systems that prove circuits
+// compiled by other toolchains, such as Circom
+type CircuitImporter interface {
+	// ImportCircuit reads uploaded circuit files by name, stores what
+	// proving needs and sets the circuit's definition to describe the
+	// imported circuit. Errors about the files wrap ErrInvalidCircuit.
+	ImportCircuit(ctx context.Context, circuit *models.Circuit, files map[string][]byte) error
+
+	// DeleteImport removes what ImportCircuit stored for a circuit
+	DeleteImport(ctx context.Context, circuit *models.Circuit) error
+}
+
+// ErrCeremonyUnsupported is returned when a circuit's keys cannot come from
+// a setup ceremony
+var ErrCeremonyUnsupported = errors.New("setup ceremony is not supported")
+
+// ErrInvalidContribution is returned when a ceremony contribution does not
+// verify against the state it claims to extend
+var ErrInvalidContribution = errors.New("invalid ceremony contribution")
+
+// SetupCeremony is implemented by proof systems whose circuit keys can come
+// from a multi-party trusted setup ceremony instead of Setup. States are
+// opaque serialized parameters that participants download, contribute
+// randomness to and upload.
+type SetupCeremony interface {
+	// CeremonyStart returns the initial state for a circuit and an
+	// identifier of the circuit-independent parameters it builds on
+	CeremonyStart(ctx context.Context, circuit *models.Circuit) (state []byte, base string, err error)
+
+	// CeremonyVerify checks that next is a valid contribution on top of
+	// prev and returns next in canonical form
+	CeremonyVerify(ctx context.Context, prev, next []byte) ([]byte, error)
+
+	// CeremonyFinalize re-verifies every contribution from the initial
+	// state, applies the public beacon and returns the circuit's keys
+	CeremonyFinalize(ctx context.Context, circuit *models.Circuit, contributions [][]byte, beacon []byte) (*SetupResult, error)
+}
+
+// KeyPublisher is implemented by proof systems whose proofs are signed
+// with a service key. Retired keys stay published so proofs signed before
+// a rotation can still be checked.
+type KeyPublisher interface {
+	// PublicKeys lists the signing keys, the active one first
+	PublicKeys() ([]PublicKey, error)
+}
+
+// PublicKey is the public part of a service signing key
+type PublicKey struct {
+	KeyID     string     `json:"key_id"`
+	Algorithm string     `json:"algorithm"`
+	PublicKey string     `json:"public_key"` // hex
+	Status    string     `json:"status"`
+	CreatedAt time.Time  `json:"created_at"`
+	RetiredAt *time.Time `json:"retired_at,omitempty"`
+}
+
+// ErrInvalidOpening is returned when revealed data and nonce do not open a
+// commitment proof
+var ErrInvalidOpening = errors.New("invalid commitment opening")
+
+// CommitmentOpener is implemented by proof systems whose proofs commit to
+// data the holder can reveal later
+type CommitmentOpener interface {
+	// VerifyOpening checks that the revealed data and nonce are what the
+	// proof's signed commitment binds to
+	VerifyOpening(ctx context.Context, opening *Opening) (*VerifyResponse, error)
+
+	// ProveEquality checks that two openings reveal the same data and
+	// returns a proof that their commitments are equal, without the data.
+	// Errors about the openings wrap ErrInvalidOpening.
+	ProveEquality(ctx context.Context, first, second *Opening) (*ProofResponse, error)
+
+	// VerifyEquality verifies a proof returned by ProveEquality
+	VerifyEquality(ctx context.Context, req *VerifyRequest) (*VerifyResponse, error)
+}
+
+// Opening reveals the data and nonce behind a commitment proof
+type Opening struct {
+	Proof json.RawMessage `json:"proof"`
+	// VerificationKey is optional; the proof's key ID locates the key
+	VerificationKey json.RawMessage   `json:"verification_key,omitempty"`
+	Data            *models.InputData `json:"data"`
+	Nonce           string            `json:"nonce"` // hex
+}
+
+// ErrInvalidBatchItem is returned for batch items that cannot be committed to
+var ErrInvalidBatchItem = errors.New("invalid batch item")
+
+// BatchCommitter is implemented by proof systems that commit to many items
+// under one signature, with a Merkle inclusion proof per item
+type BatchCommitter interface {
+	// CommitBatch commits to every item next returns until io.EOF and
+	// signs the root of a Merkle tree over the commitments. Errors about
+	// the items wrap ErrInvalidBatchItem.
+	CommitBatch(ctx context.Context, next func() (*models.InputData, error)) (BatchCommitment, error)
+
+	// VerifyInclusion checks an item's inclusion proof against a signed
+	// batch root, and that the item opens to the given data if any
+	VerifyInclusion(ctx context.Context, req *InclusionRequest) (*VerifyResponse, error)
+}
+
+// BatchCommitment is a signed batch root and the items' inclusion proofs
+type BatchCommitment interface {
+	// Root returns the signed root
+	Root() json.RawMessage
+	VerificationKey() json.RawMessage
+	// Len returns the number of items
+	Len() int
+	// InclusionProof returns the proof that item index is under the root
+	InclusionProof(index int) (json.RawMessage, error)
+}
+
+// InclusionRequest asks to verify one item of a batch commitment
+type InclusionRequest struct {
+	Root      json.RawMessage `json:"root"`
+	Inclusion json.RawMessage `json:"inclusion"`
+	// VerificationKey is optional; the root's key ID locates the key
+	VerificationKey json.RawMessage `json:"verification_key,omitempty"`
+	// Data and Nonce optionally open the item
+	Data  *models.InputData `json:"data,omitempty"`
+	Nonce string            `json:"nonce,omitempty"`
+}
+
+// ErrInvalidAggregate is returned for commitments that cannot be aggregated
+var ErrInvalidAggregate = errors.New("invalid commitment aggregate")
+
+// CommitmentAggregator is implemented by proof systems with additively
+// homomorphic commitments
+type CommitmentAggregator interface {
+	// Aggregate sums commitment proofs into one commitment to the sum of
+	// their values. Errors about the proofs wrap ErrInvalidAggregate.
+	Aggregate(ctx context.Context, proofs []json.RawMessage) (*ProofResponse, error)
+}
+
+// ErrInvalidProofAggregation is returned for proofs that cannot be
+// aggregated into one recursive proof
+var ErrInvalidProofAggregation = errors.New("invalid proof aggregation")
+
+// ProofAggregator is implemented by proof systems that prove, with one outer
+// proof, that many proofs of the same circuit verify
+type ProofAggregator interface {
+	// AggregateProofs verifies every proof inside an outer circuit whose
+	// public inputs are the proofs' public inputs, in order. Errors about
+	// the proofs wrap ErrInvalidProofAggregation.
+	AggregateProofs(ctx context.Context, req *AggregationRequest) (*ProofResponse, error)
+}
+
+// AggregationRequest asks to aggregate proofs of one circuit
+type AggregationRequest struct {
+	Proofs []AggregatedProof `json:"proofs"`
+	// VerificationKey is the circuit's key the proofs verify against
+	VerificationKey json.RawMessage `json:"verification_key,omitempty"`
+	// VerificationKeyURL locates a stored key when VerificationKey is empty
+	VerificationKeyURL string `json:"verification_key_url,omitempty"`
+	// Curve is the curve the proofs were generated on
+	Curve string `json:"curve,omitempty"`
+	// Circuit, when known, supplies the curve its keys were generated on
+	Circuit *models.Circuit `json:"circuit,omitempty"`
+}
+
+// AggregatedProof is one proof to aggregate and its public inputs
+type AggregatedProof struct {
+	Proof        json.RawMessage `json:"proof"`
+	PublicInputs json.RawMessage `json:"public_inputs"`
+}
+
+// Proof formats that proofs, verifying keys and public inputs can be
+// converted to and from
+const (
+	// FormatGnark is the proof systems' own encoding
+	FormatGnark = "gnark"
+	// FormatSnarkJS is snarkjs JSON: proof.json, verification_key.json
+	// and public.json
+	FormatSnarkJS = "snarkjs"
+	// FormatArkworks is arkworks' canonical serialization
+	FormatArkworks = "arkworks"
+)
+
+// ErrFormatUnsupported is returned for proofs that cannot be converted to
+// or from a format
+var ErrFormatUnsupported = errors.New("proof format is not supported")
+
+// ProofConverter is implemented by proof systems whose proofs can be
+// converted to other tools' formats. Their Verify accepts the converted
+// forms when VerifyRequest.Format names the format.
+type ProofConverter interface {
+	// ExportProof converts a proof, its public inputs and, when given or
+	// stored, its verifying key to format. Proofs or curves the format
+	// cannot represent return errors wrapping ErrFormatUnsupported.
+	ExportProof(ctx context.Context, req *VerifyRequest, format string) (*ExportedProof, error)
+}
+
+// ExportedProof is a proof converted to another tool's format
+type ExportedProof struct {
+	Format          string          `json:"format"`
+	Proof           json.RawMessage `json:"proof"`
+	PublicInputs    json.RawMessage `json:"public_inputs"`
+	VerificationKey json.RawMessage `json:"verification_key,omitempty"`
+}
+
+// BatchVerifier is implemented by proof systems that verify many proofs
+// faster together than one at a time
+type BatchVerifier interface {
+	// VerifyBatch verifies every request and returns one response per
+	// request, in order. Invalid proofs are reported in their response as
+	// Verify reports them; an error means the batch was not verified.
+	VerifyBatch(ctx context.Context, reqs []*VerifyRequest) ([]*VerifyResponse, error)
+}
+
 // SetupResult contains the results of a circuit setup
 type SetupResult struct {
 	ProvingKey      json.RawMessage `json:"proving_key"`
 	VerificationKey json.RawMessage `json:"verification_key"`
 	Metadata        map[string]interface{} `json:"metadata,omitempty"`
+	// CircuitDefinition, when set, is the circuit's definition with the
+	// params setup compiled it with filled in, to be stored on the circuit
+	CircuitDefinition json.RawMessage `json:"circuit_definition,omitempty"`
 }
 
 // ProofRequest represents a request to generate a proof
@@ -39,6 +328,8 @@ type ProofRequest struct {
 	Data         *models.InputData  `json:"data"`
 	PublicInputs json.RawMessage    `json:"public_inputs,omitempty"`
 	ProvingKey   json.RawMessage    `json:"proving_key,omitempty"`
+	// VerificationKey must accompany ProvingKey so the returned key matches it
+	VerificationKey json.RawMessage `json:"verification_key,omitempty"`
 	Options      map[string]interface{} `json:"options,omitempty"`
 }
 
@@ -49,6 +340,9 @@ type ProofResponse struct {
 	VerificationKey  json.RawMessage `json:"verification_key,omitempty"`
 	GenerationTimeMs int64           `json:"generation_time_ms"`
 	Metadata         map[string]interface{} `json:"metadata,omitempty"`
+	// NamedPublicInputs names PublicInputs' values, in order, when the
+	// proof system knows their names
+	NamedPublicInputs []models.NamedPublicInput `json:"named_public_inputs,omitempty"`
 }
 
 // VerifyRequest represents a request to verify a proof
@@ -56,6 +350,15 @@ type VerifyRequest struct {
 	Proof           json.RawMessage `json:"proof"`
 	VerificationKey json.RawMessage `json:"verification_key"`
 	PublicInputs    json.RawMessage `json:"public_inputs,omitempty"`
+	// VerificationKeyURL locates a stored key when VerificationKey is empty
+	VerificationKeyURL string `json:"verification_key_url,omitempty"`
+	// Curve is the curve the proof was generated on, for systems that support several
+	Curve string `json:"curve,omitempty"`
+	// Circuit, when known, supplies the curve its keys were generated on
+	Circuit *models.Circuit `json:"circuit,omitempty"`
+	// Format is the encoding of Proof, PublicInputs and VerificationKey
+	// when one is given (empty means FormatGnark)
+	Format string `json:"format,omitempty"`
 }
 
 // VerifyResponse contains the verification result
@@ -84,6 +387,13 @@ type Capabilities struct {
 	// MaxProofSize is the maximum proof size in bytes
 	MaxProofSize int64 `json:"max_proof_size"`
 
+	// Curves lists the elliptic curves a proof can be generated on, if any
+	Curves []string `json:"curves,omitempty"`
+
+	// Library is the Go package path of the library generating proofs,
+	// whose version is recorded in each proof's envelope
+	Library string `json:"library,omitempty"`
+
 	// Features lists specific features (e.g., "zero-knowledge", "post-quantum")
 	Features []string `json:"features"`
 }
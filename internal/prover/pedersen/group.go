@@ -0,0 +1,139 @@
+package pedersen
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	bls12381fr "github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	bn254fr "github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+// generatorMessage is hashed to the curve to derive the second generator H,
+// so nobody knows its discrete logarithm with respect to G
+const generatorMessage = "zapiki pedersen generator H"
+
+// group is G1 of a curve, with the generators G and H that commitments
+// v·G + r·H are built from. Points are compressed.
+type group interface {
+	// commit returns v·G + r·H
+	commit(v, r *big.Int) []byte
+	// add sums points, checking that each is in the group
+	add(points [][]byte) ([]byte, error)
+	// check returns an error if point is not in the group
+	check(point []byte) error
+	// order is the group order, the modulus of values and blindings
+	order() *big.Int
+	generators() (g, h []byte)
+}
+
+// newGroup returns the group of a supported curve
+func newGroup(curve ecc.ID) (group, error) {
+	dst := []byte(fmt.Sprintf("ZAPIKI-PEDERSEN-V1-%s_XMD:SHA-256_SSWU_RO_", curve.String()))
+	switch curve {
+	case ecc.BN254:
+		_, _, g, _ := bn254.Generators()
+		h, err := bn254.HashToG1([]byte(generatorMessage), dst)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive generator: %w", err)
+		}
+		return &bn254Group{g: g, h: h}, nil
+	case ecc.BLS12_381:
+		_, _, g, _ := bls12381.Generators()
+		h, err := bls12381.HashToG1([]byte(generatorMessage), dst)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive generator: %w", err)
+		}
+		return &bls12381Group{g: g, h: h}, nil
+	default:
+		return nil, fmt.Errorf("unsupported curve %s", curve)
+	}
+}
+
+type bn254Group struct {
+	g, h bn254.G1Affine
+}
+
+func (c *bn254Group) commit(v, r *big.Int) []byte {
+	var vg, rh bn254.G1Affine
+	vg.ScalarMultiplication(&c.g, v)
+	rh.ScalarMultiplication(&c.h, r)
+	vg.Add(&vg, &rh)
+	b := vg.Bytes()
+	return b[:]
+}
+
+func (c *bn254Group) add(points [][]byte) ([]byte, error) {
+	var sum bn254.G1Jac
+	for i, point := range points {
+		var p bn254.G1Affine
+		if n, err := p.SetBytes(point); err != nil || n != len(point) {
+			return nil, fmt.Errorf("invalid point %d", i)
+		}
+		sum.AddMixed(&p)
+	}
+	var result bn254.G1Affine
+	result.FromJacobian(&sum)
+	b := result.Bytes()
+	return b[:], nil
+}
+
+func (c *bn254Group) check(point []byte) error {
+	var p bn254.G1Affine
+	if n, err := p.SetBytes(point); err != nil || n != len(point) {
+		return fmt.Errorf("invalid point")
+	}
+	return nil
+}
+
+func (c *bn254Group) order() *big.Int { return bn254fr.Modulus() }
+
+func (c *bn254Group) generators() (g, h []byte) {
+	gb, hb := c.g.Bytes(), c.h.Bytes()
+	return gb[:], hb[:]
+}
+
+type bls12381Group struct {
+	g, h bls12381.G1Affine
+}
+
+func (c *bls12381Group) commit(v, r *big.Int) []byte {
+	var vg, rh bls12381.G1Affine
+	vg.ScalarMultiplication(&c.g, v)
+	rh.ScalarMultiplication(&c.h, r)
+	vg.Add(&vg, &rh)
+	b := vg.Bytes()
+	return b[:]
+}
+
+func (c *bls12381Group) add(points [][]byte) ([]byte, error) {
+	var sum bls12381.G1Jac
+	for i, point := range points {
+		var p bls12381.G1Affine
+		if n, err := p.SetBytes(point); err != nil || n != len(point) {
+			return nil, fmt.Errorf("invalid point %d", i)
+		}
+		sum.AddMixed(&p)
+	}
+	var result bls12381.G1Affine
+	result.FromJacobian(&sum)
+	b := result.Bytes()
+	return b[:], nil
+}
+
+func (c *bls12381Group) check(point []byte) error {
+	var p bls12381.G1Affine
+	if n, err := p.SetBytes(point); err != nil || n != len(point) {
+		return fmt.Errorf("invalid point")
+	}
+	return nil
+}
+
+func (c *bls12381Group) order() *big.Int { return bls12381fr.Modulus() }
+
+func (c *bls12381Group) generators() (g, h []byte) {
+	gb, hb := c.g.Bytes(), c.h.Bytes()
+	return gb[:], hb[:]
+}
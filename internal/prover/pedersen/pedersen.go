@@ -0,0 +1,479 @@
+package pedersen
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/gabrielrondon/zapiki/internal/models"
+	"github.com/gabrielrondon/zapiki/internal/prover"
+)
+
+// DefaultCurve is used when a request does not select a curve
+const DefaultCurve = ecc.BN254
+
+// SupportedCurves lists the curves commitments can be made on
+var SupportedCurves = []ecc.ID{ecc.BN254, ecc.BLS12_381}
+
+// maxAggregateSize bounds the commitments summed in one aggregate
+const maxAggregateSize = 100_000
+
+// MaxValueBits bounds committed values. Commitments carry no range proof,
+// so the bound is only checked where values are given; it keeps honest
+// totals far below the group order, where they cannot wrap around it.
+const MaxValueBits = 64
+
+// maxAggregateValueBits bounds the values of aggregates, which may sum
+// aggregates of up to maxAggregateSize values in turn
+const maxAggregateValueBits = 128
+
+// PedersenProver commits to numeric values with Pedersen commitments
+// C = v·G + r·H on G1 of a pairing-friendly curve. Commitments are
+// perfectly hiding and add up: the sum of commitments to values is a
+// commitment to the sum of the values, opened by the sum of the blindings.
+type PedersenProver struct {
+	groups map[ecc.ID]group
+}
+
+// PedersenProof is a commitment to a value. Blinding opens it together with
+// the value; the holder removes it before sharing the proof.
+type PedersenProof struct {
+	Curve      string `json:"curve"`
+	Commitment string `json:"commitment"` // hex, compressed G1 point
+	// Commitments, when set, are the commitments Commitment is the sum of
+	Commitments []string `json:"commitments,omitempty"`
+	// Value and Blinding open the commitment
+	Value    string `json:"value,omitempty"`    // decimal
+	Blinding string `json:"blinding,omitempty"` // hex
+}
+
+// PedersenVerificationKey names the curve and generators of commitments
+type PedersenVerificationKey struct {
+	Curve string `json:"curve"`
+	G     string `json:"g"`
+	H     string `json:"h"`
+}
+
+// NewPedersenProver creates a new Pedersen commitment prover
+func NewPedersenProver() (*PedersenProver, error) {
+	groups := make(map[ecc.ID]group, len(SupportedCurves))
+	for _, curve := range SupportedCurves {
+		g, err := newGroup(curve)
+		if err != nil {
+			return nil, err
+		}
+		groups[curve] = g
+	}
+
+	return &PedersenProver{
+		groups: groups,
+	}, nil
+}
+
+// Name returns the proof system name
+func (p *PedersenProver) Name() models.ProofSystemType {
+	return models.ProofSystemPedersen
+}
+
+// Setup returns the generators for the circuit's curve; there is nothing to
+// set up, since H is derived by hashing to the curve
+func (p *PedersenProver) Setup(ctx context.Context, circuit *models.Circuit) (*prover.SetupResult, error) {
+	curve := DefaultCurve
+	var def struct {
+		Curve string `json:"curve"`
+	}
+	if circuit != nil && len(circuit.CircuitDefinition) > 0 && json.Unmarshal(circuit.CircuitDefinition, &def) == nil && def.Curve != "" {
+		var err error
+		if curve, err = parseCurve(def.Curve); err != nil {
+			return nil, err
+		}
+	}
+
+	vkJSON, err := p.verificationKey(curve)
+	if err != nil {
+		return nil, err
+	}
+
+	return &prover.SetupResult{
+		ProvingKey:      json.RawMessage(`{}`),
+		VerificationKey: vkJSON,
+		Metadata: map[string]interface{}{
+			"setup_required": false,
+		},
+	}, nil
+}
+
+// Generate commits to a non-negative integer, given as a JSON number or a
+// decimal string
+func (p *PedersenProver) Generate(ctx context.Context, req *prover.ProofRequest) (*prover.ProofResponse, error) {
+	startTime := time.Now()
+
+	requested, _ := req.Options["curve"].(string)
+	curve, err := parseCurve(requested)
+	if err != nil {
+		return nil, err
+	}
+	grp := p.groups[curve]
+
+	value, err := parseValue(req.Data)
+	if err != nil {
+		return nil, err
+	}
+	blinding, err := rand.Int(rand.Reader, grp.order())
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate blinding factor: %w", err)
+	}
+
+	proof := PedersenProof{
+		Curve:      curve.String(),
+		Commitment: hex.EncodeToString(grp.commit(value, blinding)),
+		Blinding:   formatScalar(blinding),
+	}
+	proofJSON, err := json.Marshal(proof)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal proof: %w", err)
+	}
+
+	vkJSON, err := p.verificationKey(curve)
+	if err != nil {
+		return nil, err
+	}
+
+	return &prover.ProofResponse{
+		Proof:            proofJSON,
+		PublicInputs:     json.RawMessage(`{}`),
+		VerificationKey:  vkJSON,
+		GenerationTimeMs: time.Since(startTime).Milliseconds(),
+		Metadata: map[string]interface{}{
+			"proof_type": "pedersen",
+			"curve":      curve.String(),
+		},
+	}, nil
+}
+
+// Verify checks that the commitment is a group element, that it is the
+// sum of Commitments when they are given, and that Value and Blinding open
+// it when they are given
+func (p *PedersenProver) Verify(ctx context.Context, req *prover.VerifyRequest) (*prover.VerifyResponse, error) {
+	if err := p.verify(req); err != nil {
+		return &prover.VerifyResponse{
+			Valid:        false,
+			ErrorMessage: err.Error(),
+		}, nil
+	}
+	return &prover.VerifyResponse{Valid: true}, nil
+}
+
+func (p *PedersenProver) verify(req *prover.VerifyRequest) error {
+	var proof PedersenProof
+	if err := json.Unmarshal(req.Proof, &proof); err != nil {
+		return fmt.Errorf("failed to parse proof: %v", err)
+	}
+	name := proof.Curve
+	if name == "" {
+		name = req.Curve
+	}
+	curve, err := parseCurve(name)
+	if err != nil {
+		return err
+	}
+	grp := p.groups[curve]
+
+	// The verification key, if any, must name the same curve and generators
+	if len(req.VerificationKey) > 0 {
+		var vk PedersenVerificationKey
+		if err := json.Unmarshal(req.VerificationKey, &vk); err != nil {
+			return fmt.Errorf("failed to parse verification key: %v", err)
+		}
+		g, h := grp.generators()
+		if (vk.Curve != "" && vk.Curve != curve.String()) ||
+			(vk.G != "" && vk.G != hex.EncodeToString(g)) || (vk.H != "" && vk.H != hex.EncodeToString(h)) {
+			return fmt.Errorf("verification key is for other generators")
+		}
+	}
+
+	commitment, err := p.commitment(grp, &proof)
+	if err != nil {
+		return err
+	}
+
+	if proof.Value == "" && proof.Blinding == "" {
+		return nil
+	}
+	if proof.Value == "" || proof.Blinding == "" {
+		return fmt.Errorf("an opening needs both value and blinding")
+	}
+	value, err := parseDecimal(proof.Value, valueBits(&proof))
+	if err != nil {
+		return err
+	}
+	blinding, err := parseScalar(proof.Blinding, grp.order())
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(grp.commit(value, blinding), commitment) {
+		return fmt.Errorf("value and blinding do not open the commitment")
+	}
+	return nil
+}
+
+// commitment returns the proof's commitment, checking it against the sum of
+// its Commitments
+func (p *PedersenProver) commitment(grp group, proof *PedersenProof) ([]byte, error) {
+	if len(proof.Commitments) > 0 {
+		if len(proof.Commitments) > maxAggregateSize {
+			return nil, fmt.Errorf("at most %d commitments can be aggregated", maxAggregateSize)
+		}
+		points := make([][]byte, len(proof.Commitments))
+		for i, c := range proof.Commitments {
+			var err error
+			if points[i], err = hex.DecodeString(c); err != nil {
+				return nil, fmt.Errorf("invalid commitment %d", i)
+			}
+		}
+		sum, err := grp.add(points)
+		if err != nil {
+			return nil, err
+		}
+		if proof.Commitment != "" && proof.Commitment != hex.EncodeToString(sum) {
+			return nil, fmt.Errorf("commitment is not the sum of the commitments")
+		}
+		return sum, nil
+	}
+
+	commitment, err := hex.DecodeString(proof.Commitment)
+	if err != nil || grp.check(commitment) != nil {
+		return nil, fmt.Errorf("invalid commitment")
+	}
+	return commitment, nil
+}
+
+// Aggregate sums commitments on one curve into a commitment to the sum of
+// their values. The blindings, and the values, are summed too when every
+// proof carries one, so the holder of all openings can open the aggregate.
+func (p *PedersenProver) Aggregate(ctx context.Context, proofs []json.RawMessage) (*prover.ProofResponse, error) {
+	startTime := time.Now()
+
+	if len(proofs) == 0 || len(proofs) > maxAggregateSize {
+		return nil, fmt.Errorf("%w: between 1 and %d proofs are required", prover.ErrInvalidAggregate, maxAggregateSize)
+	}
+
+	var curve ecc.ID
+	var grp group
+	commitments := make([]string, len(proofs))
+	points := make([][]byte, len(proofs))
+	blinding, value := new(big.Int), new(big.Int)
+	allBlindings, allValues := true, true
+	for i, raw := range proofs {
+		var proof PedersenProof
+		if err := json.Unmarshal(raw, &proof); err != nil {
+			return nil, fmt.Errorf("%w: proof %d: %v", prover.ErrInvalidAggregate, i, err)
+		}
+		c, err := parseCurve(proof.Curve)
+		if err != nil {
+			return nil, fmt.Errorf("%w: proof %d: %v", prover.ErrInvalidAggregate, i, err)
+		}
+		if i == 0 {
+			curve, grp = c, p.groups[c]
+		} else if c != curve {
+			return nil, fmt.Errorf("%w: proof %d is on %s, not %s", prover.ErrInvalidAggregate, i, c, curve)
+		}
+
+		if points[i], err = p.commitment(grp, &proof); err != nil {
+			return nil, fmt.Errorf("%w: proof %d: %v", prover.ErrInvalidAggregate, i, err)
+		}
+		commitments[i] = hex.EncodeToString(points[i])
+
+		if proof.Blinding == "" {
+			allBlindings = false
+		} else if allBlindings {
+			r, err := parseScalar(proof.Blinding, grp.order())
+			if err != nil {
+				return nil, fmt.Errorf("%w: proof %d: %v", prover.ErrInvalidAggregate, i, err)
+			}
+			blinding.Add(blinding, r)
+		}
+		if proof.Value == "" {
+			allValues = false
+		} else if allValues {
+			v, err := parseDecimal(proof.Value, valueBits(&proof))
+			if err != nil {
+				return nil, fmt.Errorf("%w: proof %d: %v", prover.ErrInvalidAggregate, i, err)
+			}
+			value.Add(value, v)
+		}
+	}
+
+	sum, err := grp.add(points)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", prover.ErrInvalidAggregate, err)
+	}
+	aggregate := PedersenProof{
+		Curve:       curve.String(),
+		Commitment:  hex.EncodeToString(sum),
+		Commitments: commitments,
+	}
+	if allBlindings {
+		aggregate.Blinding = formatScalar(blinding.Mod(blinding, grp.order()))
+	}
+	if allValues {
+		// A reduced total would open the aggregate to less than the sum
+		if value.BitLen() > maxAggregateValueBits {
+			return nil, fmt.Errorf("%w: total value exceeds %d bits", prover.ErrInvalidAggregate, maxAggregateValueBits)
+		}
+		aggregate.Value = value.String()
+	}
+
+	proofJSON, err := json.Marshal(aggregate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal proof: %w", err)
+	}
+	vkJSON, err := p.verificationKey(curve)
+	if err != nil {
+		return nil, err
+	}
+
+	return &prover.ProofResponse{
+		Proof:            proofJSON,
+		PublicInputs:     json.RawMessage(`{}`),
+		VerificationKey:  vkJSON,
+		GenerationTimeMs: time.Since(startTime).Milliseconds(),
+		Metadata: map[string]interface{}{
+			"proof_type": "pedersen-aggregate",
+			"curve":      curve.String(),
+			"count":      len(proofs),
+		},
+	}, nil
+}
+
+// Capabilities returns the capabilities of the Pedersen proof system
+func (p *PedersenProver) Capabilities() prover.Capabilities {
+	curves := make([]string, len(SupportedCurves))
+	for i, curve := range SupportedCurves {
+		curves[i] = curve.String()
+	}
+
+	return prover.Capabilities{
+		SupportsSetup:          false,
+		RequiresTrustedSetup:   false,
+		SupportsCustomCircuits: false,
+		AsyncOnly:              false,
+		TypicalGenerationTime:  5,   // ~5ms
+		MaxProofSize:           256, // ~256 bytes
+		Curves:                 curves,
+		Library:                "github.com/consensys/gnark-crypto",
+		Features: []string{
+			"perfectly-hiding",
+			"homomorphic-addition",
+			"numeric-values",
+		},
+	}
+}
+
+func (p *PedersenProver) verificationKey(curve ecc.ID) (json.RawMessage, error) {
+	g, h := p.groups[curve].generators()
+	vkJSON, err := json.Marshal(PedersenVerificationKey{
+		Curve: curve.String(),
+		G:     hex.EncodeToString(g),
+		H:     hex.EncodeToString(h),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal verification key: %w", err)
+	}
+	return vkJSON, nil
+}
+
+// parseCurve parses a curve name such as "bn254" or "bls12-381"; an empty
+// name selects DefaultCurve
+func parseCurve(name string) (ecc.ID, error) {
+	if name == "" {
+		return DefaultCurve, nil
+	}
+	id, err := ecc.IDFromString(strings.ReplaceAll(name, "-", "_"))
+	if err == nil {
+		for _, supported := range SupportedCurves {
+			if id == supported {
+				return id, nil
+			}
+		}
+	}
+	return ecc.UNKNOWN, fmt.Errorf("unsupported curve %q (supported: bn254, bls12_381)", name)
+}
+
+// parseValue reads the committed value from the request data
+func parseValue(data *models.InputData) (*big.Int, error) {
+	if data == nil {
+		return nil, fmt.Errorf("data is required")
+	}
+
+	var text string
+	switch data.Type {
+	case models.DataTypeString:
+		if err := json.Unmarshal(data.Value, &text); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal string data: %w", err)
+		}
+	case models.DataTypeJSON:
+		decoder := json.NewDecoder(bytes.NewReader(data.Value))
+		decoder.UseNumber()
+		var v interface{}
+		if err := decoder.Decode(&v); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal json data: %w", err)
+		}
+		switch v := v.(type) {
+		case json.Number:
+			text = v.String()
+		case string:
+			text = v
+		default:
+			return nil, fmt.Errorf("value must be a number or a decimal string")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported data type: %s (use string or json)", data.Type)
+	}
+
+	return parseDecimal(text, MaxValueBits)
+}
+
+// valueBits bounds the value that opens proof: a committed value, or the
+// total of an aggregate
+func valueBits(proof *PedersenProof) int {
+	if len(proof.Commitments) > 0 {
+		return maxAggregateValueBits
+	}
+	return MaxValueBits
+}
+
+// parseDecimal parses a non-negative integer of at most bits bits
+func parseDecimal(text string, bits int) (*big.Int, error) {
+	v, ok := new(big.Int).SetString(strings.TrimSpace(text), 10)
+	if !ok {
+		return nil, fmt.Errorf("value %q is not an integer", text)
+	}
+	if v.Sign() < 0 || v.BitLen() > bits {
+		return nil, fmt.Errorf("value must be between 0 and 2^%d-1", bits)
+	}
+	return v, nil
+}
+
+func parseScalar(text string, order *big.Int) (*big.Int, error) {
+	b, err := hex.DecodeString(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid blinding factor")
+	}
+	r := new(big.Int).SetBytes(b)
+	if r.Cmp(order) >= 0 {
+		return nil, fmt.Errorf("blinding factor is not reduced")
+	}
+	return r, nil
+}
+
+func formatScalar(r *big.Int) string {
+	return hex.EncodeToString(r.FillBytes(make([]byte, 32)))
+}
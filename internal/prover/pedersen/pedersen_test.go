@@ -0,0 +1,197 @@
+package pedersen
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/gabrielrondon/zapiki/internal/models"
+	"github.com/gabrielrondon/zapiki/internal/prover"
+)
+
+// commit generates a commitment to amount on curve and returns its proof
+func commit(t *testing.T, p *PedersenProver, amount, curve string) PedersenProof {
+	t.Helper()
+	resp, err := p.Generate(context.Background(), &prover.ProofRequest{
+		Data:    &models.InputData{Type: models.DataTypeJSON, Value: json.RawMessage(amount)},
+		Options: map[string]interface{}{"curve": curve},
+	})
+	if err != nil {
+		t.Fatalf("Failed to generate proof: %v", err)
+	}
+	var proof PedersenProof
+	if err := json.Unmarshal(resp.Proof, &proof); err != nil {
+		t.Fatalf("Failed to parse proof: %v", err)
+	}
+	return proof
+}
+
+func verify(t *testing.T, p *PedersenProver, proof PedersenProof) bool {
+	t.Helper()
+	proofJSON, _ := json.Marshal(proof)
+	resp, err := p.Verify(context.Background(), &prover.VerifyRequest{Proof: proofJSON})
+	if err != nil {
+		t.Fatalf("Failed to verify proof: %v", err)
+	}
+	return resp.Valid
+}
+
+func TestPedersenProver_Open(t *testing.T) {
+	p, err := NewPedersenProver()
+	if err != nil {
+		t.Fatalf("Failed to create prover: %v", err)
+	}
+
+	for _, curve := range []string{"bn254", "bls12-381"} {
+		proof := commit(t, p, "1250", curve)
+		if proof.Value != "" {
+			t.Fatal("Proof should not reveal the value")
+		}
+
+		opened := proof
+		opened.Value = "1250"
+		if !verify(t, p, opened) {
+			t.Errorf("%s: value and blinding should open the commitment", curve)
+		}
+
+		opened.Value = "1251"
+		if verify(t, p, opened) {
+			t.Errorf("%s: commitment should not open to another value", curve)
+		}
+
+		// The commitment alone is checked to be a group element
+		proof.Blinding = ""
+		if !verify(t, p, proof) {
+			t.Errorf("%s: commitment without an opening should verify", curve)
+		}
+	}
+}
+
+func TestPedersenProver_Aggregate(t *testing.T) {
+	p, err := NewPedersenProver()
+	if err != nil {
+		t.Fatalf("Failed to create prover: %v", err)
+	}
+	ctx := context.Background()
+
+	// Each party shares its commitment with the auditor, and the blinding
+	// with the party that reports the total
+	amounts := []string{"100", `"2500"`, "37"}
+	shared := make([]json.RawMessage, len(amounts))
+	openings := make([]json.RawMessage, len(amounts))
+	for i, amount := range amounts {
+		proof := commit(t, p, amount, "")
+		openings[i], _ = json.Marshal(proof)
+		proof.Blinding = ""
+		shared[i], _ = json.Marshal(proof)
+	}
+
+	resp, err := p.Aggregate(ctx, openings)
+	if err != nil {
+		t.Fatalf("Failed to aggregate: %v", err)
+	}
+	var total PedersenProof
+	if err := json.Unmarshal(resp.Proof, &total); err != nil {
+		t.Fatalf("Failed to parse aggregate: %v", err)
+	}
+	if total.Value != "" || total.Blinding == "" {
+		t.Fatal("Aggregate should carry the summed blinding but no value")
+	}
+
+	// The auditor sums the shared commitments and checks the reported total
+	audited, err := p.Aggregate(ctx, shared)
+	if err != nil {
+		t.Fatalf("Failed to aggregate: %v", err)
+	}
+	var check PedersenProof
+	if err := json.Unmarshal(audited.Proof, &check); err != nil {
+		t.Fatalf("Failed to parse aggregate: %v", err)
+	}
+	if check.Commitment != total.Commitment {
+		t.Fatal("Aggregates of the same commitments should match")
+	}
+	check.Value, check.Blinding = "2637", total.Blinding
+	if !verify(t, p, check) {
+		t.Error("Total should open the aggregate")
+	}
+	check.Value = "2638"
+	if verify(t, p, check) {
+		t.Error("Wrong total should not open the aggregate")
+	}
+
+	// A commitment that is not the sum of the listed ones is rejected
+	check.Value = "2637"
+	check.Commitments = check.Commitments[:2]
+	if verify(t, p, check) {
+		t.Error("Aggregate of other commitments should not verify")
+	}
+}
+
+func TestPedersenProver_Aggregate_Rejects(t *testing.T) {
+	p, err := NewPedersenProver()
+	if err != nil {
+		t.Fatalf("Failed to create prover: %v", err)
+	}
+	ctx := context.Background()
+
+	bn, _ := json.Marshal(commit(t, p, "1", "bn254"))
+	bls, _ := json.Marshal(commit(t, p, "2", "bls12_381"))
+	if _, err := p.Aggregate(ctx, []json.RawMessage{bn, bls}); err == nil {
+		t.Error("Commitments on different curves should not aggregate")
+	}
+
+	bad, _ := json.Marshal(PedersenProof{Curve: "bn254", Commitment: "00"})
+	if _, err := p.Aggregate(ctx, []json.RawMessage{bn, bad}); err == nil {
+		t.Error("Invalid commitments should not aggregate")
+	}
+
+	if _, err := p.Aggregate(ctx, nil); err == nil {
+		t.Error("Empty aggregates should be rejected")
+	}
+}
+
+func TestPedersenProver_ValueBounds(t *testing.T) {
+	p, err := NewPedersenProver()
+	if err != nil {
+		t.Fatalf("Failed to create prover: %v", err)
+	}
+	ctx := context.Background()
+
+	// r-1 opens a commitment to -1, which would lower an aggregate's total
+	for _, amount := range []string{"-1", `"18446744073709551616"`, `"21888242871839275222246405745257275088548364400416034343698204186575808495616"`} {
+		_, err := p.Generate(ctx, &prover.ProofRequest{
+			Data: &models.InputData{Type: models.DataTypeJSON, Value: json.RawMessage(amount)},
+		})
+		if err == nil {
+			t.Errorf("Value %s should be rejected", amount)
+		}
+	}
+
+	proof := commit(t, p, `"18446744073709551615"`, "bn254")
+	proof.Value = "18446744073709551615"
+	if !verify(t, p, proof) {
+		t.Error("Largest value should open its commitment")
+	}
+	opening, _ := json.Marshal(proof)
+	resp, err := p.Aggregate(ctx, []json.RawMessage{opening, opening})
+	if err != nil {
+		t.Fatalf("Failed to aggregate: %v", err)
+	}
+	var total PedersenProof
+	if err := json.Unmarshal(resp.Proof, &total); err != nil {
+		t.Fatalf("Failed to parse aggregate: %v", err)
+	}
+	if total.Value != "36893488147419103230" || !verify(t, p, total) {
+		t.Errorf("Expected an unreduced total of 36893488147419103230, got %s", total.Value)
+	}
+
+	// An opening above the bound is rejected, however it was committed
+	proof.Value = "18446744073709551616"
+	if verify(t, p, proof) {
+		t.Error("Values above 64 bits should not open a commitment")
+	}
+	opening, _ = json.Marshal(proof)
+	if _, err := p.Aggregate(ctx, []json.RawMessage{opening}); err == nil {
+		t.Error("Values above 64 bits should not aggregate")
+	}
+}
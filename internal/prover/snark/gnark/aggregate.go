@@ -0,0 +1,291 @@
+package gnark
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	fr_bls12377 "github.com/consensys/gnark-crypto/ecc/bls12-377/fr"
+	fr_bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+	fr_bn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/std/algebra"
+	"github.com/consensys/gnark/std/algebra/emulated/sw_bn254"
+	"github.com/consensys/gnark/std/algebra/native/sw_bls12377"
+	"github.com/consensys/gnark/std/math/emulated"
+	stdgroth16 "github.com/consensys/gnark/std/recursion/groth16"
+	"github.com/gabrielrondon/zapiki/internal/models"
+	"github.com/gabrielrondon/zapiki/internal/prover"
+)
+
+// MaxAggregatedProofs bounds the proofs one outer proof verifies
+const MaxAggregatedProofs = 32
+
+// MaxEmulatedAggregatedProofs bounds the proofs one emulated outer proof
+// verifies. Each emulated pairing costs millions of constraints, so outer
+// keys for more proofs outgrow a worker's memory.
+const MaxEmulatedAggregatedProofs = 2
+
+// aggregationCircuitType names outer circuits in the key cache
+const aggregationCircuitType = "groth16_aggregation"
+
+// AggregationCurves maps the curves inner proofs can be aggregated from to
+// the curve of the outer proof. BLS12-377 proofs are verified natively on
+// BW6-761; BN254 proofs are verified on BN254 with field emulation, which
+// costs far more constraints but keeps the outer proof verifiable on
+// Ethereum.
+var AggregationCurves = map[ecc.ID]ecc.ID{
+	ecc.BLS12_377: ecc.BW6_761,
+	ecc.BN254:     ecc.BN254,
+}
+
+// aggregationCircuit verifies Proofs against a verifying key fixed at
+// compile time. Each proof's public inputs are public inputs of the outer
+// circuit, so the outer proof names the statements it covers.
+type aggregationCircuit[FR emulated.FieldParams, G1El algebra.G1ElementT, G2El algebra.G2ElementT, GtEl algebra.GtElementT] struct {
+	Proofs       []stdgroth16.Proof[G1El, G2El]
+	Statements   []stdgroth16.Witness[FR]                  `gnark:",public"`
+	VerifyingKey stdgroth16.VerifyingKey[G1El, G2El, GtEl] `gnark:"-"`
+}
+
+// Define asserts that every proof verifies. The outer proof must hold for
+// adversarial inner proofs, not only the ones checked natively before
+// proving, so points get complete arithmetic and subgroup checks.
+func (c *aggregationCircuit[FR, G1El, G2El, GtEl]) Define(api frontend.API) error {
+	verifier, err := stdgroth16.NewVerifier[FR, G1El, G2El, GtEl](api)
+	if err != nil {
+		return err
+	}
+
+	for i := range c.Proofs {
+		err := verifier.AssertProof(c.VerifyingKey, c.Proofs[i], c.Statements[i],
+			stdgroth16.WithCompleteArithmetic(), stdgroth16.WithSubgroupCheck())
+		if err != nil {
+			return fmt.Errorf("proof %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// newAggregation returns the outer circuit verifying proofs under vk and
+// its assignment
+func newAggregation[FR emulated.FieldParams, G1El algebra.G1ElementT, G2El algebra.G2ElementT, GtEl algebra.GtElementT](vk groth16.VerifyingKey, proofs []groth16.Proof, publicWitnesses []witness.Witness) (frontend.Circuit, frontend.Circuit, error) {
+	fixedVK, err := stdgroth16.ValueOfVerifyingKeyFixed[G1El, G2El, GtEl](vk)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", prover.ErrInvalidProofAggregation, err)
+	}
+	// Stored proofs hash commitments with the native hash, which the
+	// in-circuit verifier cannot recompute
+	if len(fixedVK.CommitmentKeys) > 0 {
+		return nil, nil, fmt.Errorf("%w: circuits with commitments cannot be aggregated", prover.ErrInvalidProofAggregation)
+	}
+
+	circuit := &aggregationCircuit[FR, G1El, G2El, GtEl]{
+		Proofs:       make([]stdgroth16.Proof[G1El, G2El], len(proofs)),
+		Statements:   make([]stdgroth16.Witness[FR], len(proofs)),
+		VerifyingKey: fixedVK,
+	}
+	assignment := &aggregationCircuit[FR, G1El, G2El, GtEl]{
+		Proofs:       make([]stdgroth16.Proof[G1El, G2El], len(proofs)),
+		Statements:   make([]stdgroth16.Witness[FR], len(proofs)),
+		VerifyingKey: fixedVK,
+	}
+
+	for i := range proofs {
+		circuit.Statements[i] = stdgroth16.Witness[FR]{
+			Public: make([]emulated.Element[FR], vk.NbPublicWitness()),
+		}
+
+		if assignment.Proofs[i], err = stdgroth16.ValueOfProof[G1El, G2El](proofs[i]); err != nil {
+			return nil, nil, fmt.Errorf("%w: proof %d: %v", prover.ErrInvalidProofAggregation, i, err)
+		}
+		if assignment.Statements[i], err = stdgroth16.ValueOfWitness[FR](publicWitnesses[i]); err != nil {
+			return nil, nil, fmt.Errorf("%w: proof %d: %v", prover.ErrInvalidProofAggregation, i, err)
+		}
+	}
+
+	return circuit, assignment, nil
+}
+
+// aggregationFor instantiates the outer circuit for proofs on curve
+func aggregationFor(curve ecc.ID, vk groth16.VerifyingKey, proofs []groth16.Proof, publicWitnesses []witness.Witness) (frontend.Circuit, frontend.Circuit, error) {
+	switch curve {
+	case ecc.BLS12_377:
+		return newAggregation[sw_bls12377.ScalarField, sw_bls12377.G1Affine, sw_bls12377.G2Affine, sw_bls12377.GT](vk, proofs, publicWitnesses)
+	case ecc.BN254:
+		return newAggregation[sw_bn254.ScalarField, sw_bn254.G1Affine, sw_bn254.G2Affine, sw_bn254.GTEl](vk, proofs, publicWitnesses)
+	default:
+		return nil, nil, fmt.Errorf("%w: %s proofs cannot be aggregated", prover.ErrInvalidProofAggregation, curve)
+	}
+}
+
+// AggregateProofs proves with one Groth16 proof that every given proof of
+// a circuit verifies. The outer proof's public inputs are the inner proofs'
+// public inputs, in order, as emulated field elements; the metadata lists
+// them as decimal strings under "statements".
+func (p *Groth16Prover) AggregateProofs(ctx context.Context, req *prover.AggregationRequest) (*prover.ProofResponse, error) {
+	startTime := time.Now()
+
+	if len(req.Proofs) == 0 || len(req.Proofs) > MaxAggregatedProofs {
+		return nil, fmt.Errorf("%w: between 1 and %d proofs are required", prover.ErrInvalidProofAggregation, MaxAggregatedProofs)
+	}
+
+	curve, err := verifyCurve(&prover.VerifyRequest{Curve: req.Curve, Circuit: req.Circuit}, p.curve)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", prover.ErrInvalidProofAggregation, err)
+	}
+	outerCurve, ok := AggregationCurves[curve]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s proofs cannot be aggregated", prover.ErrInvalidProofAggregation, curve)
+	}
+	if outerCurve == curve && len(req.Proofs) > MaxEmulatedAggregatedProofs {
+		return nil, fmt.Errorf("%w: at most %d %s proofs can be aggregated", prover.ErrInvalidProofAggregation, MaxEmulatedAggregatedProofs, curve)
+	}
+
+	verificationKey := req.VerificationKey
+	if len(verificationKey) == 0 {
+		if verificationKey, err = loadVerificationKey(ctx, p.artifacts, req.VerificationKeyURL); err != nil {
+			return nil, fmt.Errorf("%w: %v", prover.ErrInvalidProofAggregation, err)
+		}
+	}
+	vk := groth16.NewVerifyingKey(curve)
+	if err := decodeInto(vk, "verification_key", verificationKey); err != nil {
+		return nil, fmt.Errorf("%w: failed to deserialize verification key: %v", prover.ErrInvalidProofAggregation, err)
+	}
+	vkBytes, err := writeKey(vk)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize verification key: %w", err)
+	}
+
+	// Check every proof natively first, so a bad proof is named instead of
+	// failing the outer proof as a whole
+	proofs := make([]groth16.Proof, len(req.Proofs))
+	publicWitnesses := make([]witness.Witness, len(req.Proofs))
+	statements := make([][]string, len(req.Proofs))
+	for i, item := range req.Proofs {
+		proofs[i] = groth16.NewProof(curve)
+		if err := decodeInto(proofs[i], "proof", item.Proof); err != nil {
+			return nil, fmt.Errorf("%w: proof %d: failed to deserialize proof: %v", prover.ErrInvalidProofAggregation, i, err)
+		}
+		if publicWitnesses[i], err = frontend.NewWitness(nil, curve.ScalarField()); err != nil {
+			return nil, fmt.Errorf("failed to create witness: %w", err)
+		}
+		if err := decodeInto(publicWitnesses[i], "public_inputs", item.PublicInputs); err != nil {
+			return nil, fmt.Errorf("%w: proof %d: failed to deserialize public inputs: %v", prover.ErrInvalidProofAggregation, i, err)
+		}
+		if err := groth16.Verify(proofs[i], vk, publicWitnesses[i]); err != nil {
+			return nil, fmt.Errorf("%w: proof %d does not verify: %v", prover.ErrInvalidProofAggregation, i, err)
+		}
+		if statements[i], err = publicInputStrings(publicWitnesses[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	circuit, assignment, err := aggregationFor(curve, vk, proofs, publicWitnesses)
+	if err != nil {
+		return nil, err
+	}
+
+	// Outer keys depend on the inner key and the number of proofs
+	vkSum := sha256.Sum256(vkBytes)
+	key := CacheKey{
+		System:      models.ProofSystemGroth16,
+		Curve:       outerCurve,
+		CircuitType: aggregationCircuitType,
+		Params: map[string]interface{}{
+			"inner_curve":      curve.String(),
+			"verification_key": hex.EncodeToString(vkSum[:]),
+			"proofs":           len(proofs),
+		},
+	}
+	keys, err := p.keys.get(ctx, key, keyBackend{
+		compile: func() (constraint.ConstraintSystem, error) {
+			return frontend.Compile(outerCurve.ScalarField(), r1cs.NewBuilder, circuit)
+		},
+		setup: func(ccs constraint.ConstraintSystem) (Key, Key, error) {
+			pk, vk, err := groth16.Setup(ccs)
+			return pk, vk, err
+		},
+		newKeys: func() (Key, Key) {
+			return groth16.NewProvingKey(outerCurve), groth16.NewVerifyingKey(outerCurve)
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fullWitness, err := frontend.NewWitness(assignment, outerCurve.ScalarField())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create witness: %w", err)
+	}
+
+	proof, err := groth16.Prove(keys.CCS, keys.ProvingKey.(groth16.ProvingKey), fullWitness)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate proof: %w", err)
+	}
+
+	proofBytes, err := writeKey(proof)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize proof: %w", err)
+	}
+
+	outerVKBytes, err := writeKey(keys.VerifyingKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize verification key: %w", err)
+	}
+
+	publicWitness, err := fullWitness.Public()
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract public inputs: %w", err)
+	}
+
+	publicBytes, err := writeKey(publicWitness)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize public inputs: %w", err)
+	}
+
+	return &prover.ProofResponse{
+		Proof:            encodeBinary("proof", proofBytes),
+		PublicInputs:     encodeBinary("public_inputs", publicBytes),
+		VerificationKey:  encodeBinary("verification_key", outerVKBytes),
+		GenerationTimeMs: time.Since(startTime).Milliseconds(),
+		Metadata: map[string]interface{}{
+			"proof_system": "groth16",
+			"curve":        outerCurve.String(),
+			"inner_curve":  curve.String(),
+			"emulated":     outerCurve == curve,
+			"constraints":  keys.CCS.GetNbConstraints(),
+			"key_id":       key.String(),
+			"statements":   statements,
+		},
+	}, nil
+}
+
+// publicInputStrings lists a public witness as decimal strings
+func publicInputStrings(w witness.Witness) ([]string, error) {
+	inputs := []string{}
+	switch vector := w.Vector().(type) {
+	case fr_bn254.Vector:
+		for i := range vector {
+			inputs = append(inputs, vector[i].String())
+		}
+	case fr_bls12377.Vector:
+		for i := range vector {
+			inputs = append(inputs, vector[i].String())
+		}
+	case fr_bls12381.Vector:
+		for i := range vector {
+			inputs = append(inputs, vector[i].String())
+		}
+	default:
+		return nil, fmt.Errorf("unsupported public witness %T", vector)
+	}
+	return inputs, nil
+}
@@ -0,0 +1,180 @@
+package gnark
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/test"
+	"github.com/gabrielrondon/zapiki/internal/models"
+	"github.com/gabrielrondon/zapiki/internal/prover"
+)
+
+func simpleProof(t *testing.T, p *Groth16Prover, curve string, x, y int) *prover.ProofResponse {
+	t.Helper()
+
+	inputJSON, _ := json.Marshal(map[string]interface{}{"x": x, "y": y, "z": x * y})
+	circuitDefJSON, _ := json.Marshal(map[string]interface{}{"circuit_type": "simple", "curve": curve})
+
+	resp, err := p.Generate(context.Background(), &prover.ProofRequest{
+		Circuit: &models.Circuit{CircuitDefinition: circuitDefJSON},
+		Data: &models.InputData{
+			Type:  models.DataTypeJSON,
+			Value: inputJSON,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to generate proof: %v", err)
+	}
+	return resp
+}
+
+func TestGroth16_AggregateProofs(t *testing.T) {
+	ctx := context.Background()
+	p := NewGroth16Prover()
+
+	// Outer setup on BW6-761 dominates, so one proof keeps this test short
+	first := simpleProof(t, p, "bls12_377", 3, 5)
+
+	resp, err := p.AggregateProofs(ctx, &prover.AggregationRequest{
+		Proofs: []prover.AggregatedProof{
+			{Proof: first.Proof, PublicInputs: first.PublicInputs},
+		},
+		VerificationKey: first.VerificationKey,
+		Curve:           "bls12_377",
+	})
+	if err != nil {
+		t.Fatalf("Failed to aggregate proofs: %v", err)
+	}
+
+	if resp.Metadata["curve"] != "bw6_761" {
+		t.Errorf("Expected a bw6_761 outer proof, got %v", resp.Metadata["curve"])
+	}
+	statements, _ := resp.Metadata["statements"].([][]string)
+	if len(statements) != 1 || len(statements[0]) != 1 || statements[0][0] != "15" {
+		t.Errorf("Expected statements [[15]], got %v", resp.Metadata["statements"])
+	}
+
+	verifyResp, err := p.Verify(ctx, &prover.VerifyRequest{
+		Proof:           resp.Proof,
+		VerificationKey: resp.VerificationKey,
+		PublicInputs:    resp.PublicInputs,
+		Curve:           "bw6_761",
+	})
+	if err != nil {
+		t.Fatalf("Failed to verify proof: %v", err)
+	}
+	if !verifyResp.Valid {
+		t.Errorf("Expected the aggregated proof to verify: %s", verifyResp.ErrorMessage)
+	}
+
+	// A second aggregation of the same circuit reuses the outer keys
+	second := simpleProof(t, p, "bls12_377", 4, 7)
+	other, err := p.AggregateProofs(ctx, &prover.AggregationRequest{
+		Proofs: []prover.AggregatedProof{
+			{Proof: second.Proof, PublicInputs: second.PublicInputs},
+		},
+		VerificationKey: second.VerificationKey,
+		Curve:           "bls12_377",
+	})
+	if err != nil {
+		t.Fatalf("Failed to aggregate proofs: %v", err)
+	}
+	if other.Metadata["key_id"] != resp.Metadata["key_id"] {
+		t.Errorf("Expected the outer keys to be reused, got %v and %v", resp.Metadata["key_id"], other.Metadata["key_id"])
+	}
+
+	// The outer proof is bound to the statements it was proven for
+	verifyResp, err = p.Verify(ctx, &prover.VerifyRequest{
+		Proof:           resp.Proof,
+		VerificationKey: resp.VerificationKey,
+		PublicInputs:    other.PublicInputs,
+		Curve:           "bw6_761",
+	})
+	if err != nil {
+		t.Fatalf("Failed to verify proof: %v", err)
+	}
+	if verifyResp.Valid {
+		t.Error("Expected the aggregated proof not to verify against other statements")
+	}
+}
+
+func TestGroth16_AggregateProofsRejectsInvalidProofs(t *testing.T) {
+	ctx := context.Background()
+	p := NewGroth16Prover()
+
+	first := simpleProof(t, p, "bls12_377", 3, 5)
+	second := simpleProof(t, p, "bls12_377", 4, 7)
+	emulated := simpleProof(t, p, "bn254", 3, 5)
+
+	tests := map[string]*prover.AggregationRequest{
+		"no proofs": {
+			VerificationKey: first.VerificationKey,
+			Curve:           "bls12_377",
+		},
+		"swapped public inputs": {
+			Proofs: []prover.AggregatedProof{
+				{Proof: first.Proof, PublicInputs: second.PublicInputs},
+			},
+			VerificationKey: first.VerificationKey,
+			Curve:           "bls12_377",
+		},
+		"unsupported curve": {
+			Proofs: []prover.AggregatedProof{
+				{Proof: first.Proof, PublicInputs: first.PublicInputs},
+			},
+			VerificationKey: first.VerificationKey,
+			Curve:           "bls12_381",
+		},
+		"too many emulated proofs": {
+			Proofs: []prover.AggregatedProof{
+				{Proof: emulated.Proof, PublicInputs: emulated.PublicInputs},
+				{Proof: emulated.Proof, PublicInputs: emulated.PublicInputs},
+				{Proof: emulated.Proof, PublicInputs: emulated.PublicInputs},
+			},
+			VerificationKey: emulated.VerificationKey,
+			Curve:           "bn254",
+		},
+	}
+
+	for name, req := range tests {
+		if _, err := p.AggregateProofs(ctx, req); !errors.Is(err, prover.ErrInvalidProofAggregation) {
+			t.Errorf("%s: expected ErrInvalidProofAggregation, got %v", name, err)
+		}
+	}
+}
+
+func TestGroth16_AggregationCircuitBN254(t *testing.T) {
+	p := NewGroth16Prover()
+	inner := simpleProof(t, p, "bn254", 3, 5)
+
+	vk := groth16.NewVerifyingKey(ecc.BN254)
+	if err := decodeInto(vk, "verification_key", inner.VerificationKey); err != nil {
+		t.Fatalf("Failed to decode verification key: %v", err)
+	}
+	proof := groth16.NewProof(ecc.BN254)
+	if err := decodeInto(proof, "proof", inner.Proof); err != nil {
+		t.Fatalf("Failed to decode proof: %v", err)
+	}
+	publicWitness, err := frontend.NewWitness(nil, ecc.BN254.ScalarField())
+	if err != nil {
+		t.Fatalf("Failed to create witness: %v", err)
+	}
+	if err := decodeInto(publicWitness, "public_inputs", inner.PublicInputs); err != nil {
+		t.Fatalf("Failed to decode public inputs: %v", err)
+	}
+
+	// Solving the emulated verifier is far cheaper than its outer setup
+	circuit, assignment, err := aggregationFor(ecc.BN254, vk, []groth16.Proof{proof}, []witness.Witness{publicWitness})
+	if err != nil {
+		t.Fatalf("Failed to build aggregation circuit: %v", err)
+	}
+	if err := test.IsSolved(circuit, assignment, ecc.BN254.ScalarField()); err != nil {
+		t.Errorf("Expected the BN254 aggregation circuit to be solved: %v", err)
+	}
+}
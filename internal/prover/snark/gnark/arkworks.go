@@ -0,0 +1,315 @@
+package gnark
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"slices"
+)
+
+// arkworks' CanonicalSerialize writes a Groth16 proof as A | B | C, a
+// verifying key as alpha_g1 | beta_g2 | gamma_g2 | delta_g2 | gamma_abc_g1
+// and public inputs as a Vec<Fr>, vectors being prefixed by their length
+// as a little-endian u64. Exports are compressed, as serialize_compressed
+// writes them; imports may be either.
+//
+// Field elements are little-endian with the point's flags in the top bits
+// of the last byte, except on BLS12-381 where ark-bls12-381 uses the zcash
+// encoding that gnark-crypto also uses.
+
+// Flags arkworks sets in a point's last byte
+const (
+	arkworksYIsNegative = 1 << 7
+	arkworksInfinity    = 1 << 6
+	arkworksFlags       = arkworksYIsNegative | arkworksInfinity
+)
+
+// gnark-crypto's compressed BN254 point masks, which the arkworks flags map to
+const (
+	bn254CompressedSmallest = 0b10 << 6
+	bn254CompressedLargest  = 0b11 << 6
+	bn254CompressedInfinity = 0b01 << 6
+)
+
+// zcash encoding flags in a point's first byte
+const (
+	zcashCompressed = 1 << 7
+	zcashInfinity   = 1 << 6
+	zcashLargest    = 1 << 5
+)
+
+// arkworksProof serializes a proof
+func (c *interopCurve) arkworksProof(points *groth16ProofPoints) []byte {
+	buf := c.arkworksG1(points.A)
+	buf = append(buf, c.arkworksG2(points.B)...)
+	return append(buf, c.arkworksG1(points.C)...)
+}
+
+// parseArkworksProof deserializes a compressed or uncompressed proof
+func (c *interopCurve) parseArkworksProof(data []byte) (*groth16ProofPoints, error) {
+	n := c.fpBytes
+	var compressed bool
+	switch len(data) {
+	case 4 * n:
+		compressed = true
+	case 8 * n:
+	default:
+		return nil, fmt.Errorf("invalid arkworks proof: %d bytes, expected %d compressed or %d uncompressed", len(data), 4*n, 8*n)
+	}
+
+	r := &arkworksReader{c: c, data: data, compressed: compressed}
+	points := &groth16ProofPoints{A: r.g1(), B: r.g2(), C: r.g1()}
+	if r.err != nil {
+		return nil, fmt.Errorf("invalid arkworks proof: %w", r.err)
+	}
+	return points, nil
+}
+
+// arkworksKey serializes a verifying key
+func (c *interopCurve) arkworksKey(points *groth16KeyPoints) []byte {
+	buf := c.arkworksG1(points.Alpha)
+	for _, p := range []affineG2{points.Beta, points.Gamma, points.Delta} {
+		buf = append(buf, c.arkworksG2(p)...)
+	}
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(len(points.IC)))
+	for _, p := range points.IC {
+		buf = append(buf, c.arkworksG1(p)...)
+	}
+	return buf
+}
+
+// parseArkworksKey deserializes a compressed or uncompressed verifying key
+func (c *interopCurve) parseArkworksKey(data []byte) (*groth16KeyPoints, error) {
+	// Try both point sizes against the length prefix of gamma_abc_g1
+	for _, compressed := range []bool{true, false} {
+		g1Size := c.fpBytes
+		if !compressed {
+			g1Size *= 2
+		}
+		fixed := 7 * g1Size
+		if len(data) < fixed+8 {
+			continue
+		}
+		count := binary.LittleEndian.Uint64(data[fixed:])
+		if count > uint64(len(data)) || len(data) != fixed+8+int(count)*g1Size {
+			continue
+		}
+
+		r := &arkworksReader{c: c, data: data, compressed: compressed}
+		points := &groth16KeyPoints{Alpha: r.g1(), Beta: r.g2(), Gamma: r.g2(), Delta: r.g2()}
+		r.next(8)
+		for range count {
+			points.IC = append(points.IC, r.g1())
+		}
+		if r.err != nil {
+			return nil, fmt.Errorf("invalid arkworks verification key: %w", r.err)
+		}
+		return points, nil
+	}
+	return nil, fmt.Errorf("invalid arkworks verification key: %d bytes match neither point encoding", len(data))
+}
+
+// arkworksPublic serializes public inputs as a Vec<Fr>
+func (c *interopCurve) arkworksPublic(values []*big.Int) []byte {
+	buf := binary.LittleEndian.AppendUint64(nil, uint64(len(values)))
+	for _, v := range values {
+		buf = append(buf, littleEndian(v, c.frBytes)...)
+	}
+	return buf
+}
+
+// parseArkworksPublic deserializes a Vec<Fr>
+func (c *interopCurve) parseArkworksPublic(data []byte) ([]*big.Int, error) {
+	if len(data) < 8 {
+		return nil, fmt.Errorf("invalid arkworks public inputs: %d bytes", len(data))
+	}
+	count := binary.LittleEndian.Uint64(data)
+	if count > uint64(len(data)) || len(data) != 8+int(count)*c.frBytes {
+		return nil, fmt.Errorf("invalid arkworks public inputs: %d bytes for %d inputs", len(data), count)
+	}
+
+	values := make([]*big.Int, count)
+	for i := range values {
+		offset := 8 + i*c.frBytes
+		values[i] = fromLittleEndian(data[offset : offset+c.frBytes])
+	}
+	return values, nil
+}
+
+// arkworksG1 writes a compressed G1 point
+func (c *interopCurve) arkworksG1(p affineG1) []byte {
+	infinity := p[0].Sign() == 0 && p[1].Sign() == 0
+	if c.zcash {
+		buf := make([]byte, c.fpBytes)
+		p[0].FillBytes(buf)
+		buf[0] |= zcashFlags(infinity, c.largest(p[1]))
+		return buf
+	}
+
+	buf := littleEndian(p[0], c.fpBytes)
+	buf[len(buf)-1] |= arkworksFlagsFor(infinity, c.largest(p[1]))
+	return buf
+}
+
+// arkworksG2 writes a compressed G2 point
+func (c *interopCurve) arkworksG2(p affineG2) []byte {
+	infinity := p[0][0].Sign() == 0 && p[0][1].Sign() == 0 && p[1][0].Sign() == 0 && p[1][1].Sign() == 0
+	n := c.fpBytes
+	if c.zcash {
+		buf := make([]byte, 2*n)
+		p[0][1].FillBytes(buf[:n])
+		p[0][0].FillBytes(buf[n:])
+		buf[0] |= zcashFlags(infinity, c.largestE2(p[1]))
+		return buf
+	}
+
+	buf := append(littleEndian(p[0][0], n), littleEndian(p[0][1], n)...)
+	buf[len(buf)-1] |= arkworksFlagsFor(infinity, c.largestE2(p[1]))
+	return buf
+}
+
+// zcashFlags returns the first-byte flags of a compressed zcash point
+func zcashFlags(infinity, largest bool) byte {
+	switch {
+	case infinity:
+		return zcashCompressed | zcashInfinity
+	case largest:
+		return zcashCompressed | zcashLargest
+	default:
+		return zcashCompressed
+	}
+}
+
+// arkworksFlagsFor returns the last-byte flags of an arkworks point
+func arkworksFlagsFor(infinity, largest bool) byte {
+	switch {
+	case infinity:
+		return arkworksInfinity
+	case largest:
+		return arkworksYIsNegative
+	default:
+		return 0
+	}
+}
+
+// arkworksReader reads consecutive points, keeping the first error
+type arkworksReader struct {
+	c          *interopCurve
+	data       []byte
+	compressed bool
+	err        error
+}
+
+// next returns the next n bytes
+func (r *arkworksReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if len(r.data) < n {
+		r.err = fmt.Errorf("unexpected end of data")
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+// g1 reads a G1 point
+func (r *arkworksReader) g1() affineG1 {
+	raw := r.point(r.c.newG1(), 1)
+	if raw == nil {
+		return affineG1{}
+	}
+	return r.c.g1FromRaw(raw)
+}
+
+// g2 reads a G2 point
+func (r *arkworksReader) g2() affineG2 {
+	raw := r.point(r.c.newG2(), 2)
+	if raw == nil {
+		return affineG2{}
+	}
+	return r.c.g2FromRaw(raw)
+}
+
+// point reads a point whose coordinates are made of elements base field
+// elements into p, which checks it, and returns it in gnark's uncompressed
+// encoding
+func (r *arkworksReader) point(p curvePoint, elements int) []byte {
+	size := elements * r.c.fpBytes
+	if !r.compressed {
+		size *= 2
+	}
+	b := r.next(size)
+	if b == nil {
+		return nil
+	}
+
+	encoded := b
+	if !r.c.zcash {
+		var err error
+		if encoded, err = gnarkFromArkworks(b, r.compressed); err != nil {
+			r.err = err
+			return nil
+		}
+	}
+	if _, err := p.SetBytes(encoded); err != nil {
+		r.err = fmt.Errorf("invalid point: %w", err)
+		return nil
+	}
+	return p.Marshal()
+}
+
+// gnarkFromArkworks converts an arkworks BN254 point to gnark's encoding.
+// Both are the coordinates' bytes, little-endian for arkworks and
+// big-endian for gnark, with flags in the top bits of the most significant
+// byte.
+func gnarkFromArkworks(b []byte, compressed bool) ([]byte, error) {
+	if compressed {
+		g := slices.Clone(b)
+		slices.Reverse(g)
+		flags := g[0] & arkworksFlags
+		g[0] &^= arkworksFlags
+		switch flags {
+		case arkworksInfinity:
+			g[0] |= bn254CompressedInfinity
+		case arkworksYIsNegative:
+			g[0] |= bn254CompressedLargest
+		case 0:
+			g[0] |= bn254CompressedSmallest
+		default:
+			return nil, fmt.Errorf("invalid point flags %#x", flags)
+		}
+		return g, nil
+	}
+
+	// Uncompressed points are X | Y; the flags sit in Y
+	half := len(b) / 2
+	x, y := slices.Clone(b[:half]), slices.Clone(b[half:])
+	slices.Reverse(x)
+	slices.Reverse(y)
+	flags := y[0] & arkworksFlags
+	y[0] &^= arkworksFlags
+	switch flags {
+	case arkworksInfinity:
+		return make([]byte, len(b)), nil
+	case arkworksFlags:
+		return nil, fmt.Errorf("invalid point flags %#x", flags)
+	}
+	return append(x, y...), nil
+}
+
+// littleEndian writes v as size little-endian bytes
+func littleEndian(v *big.Int, size int) []byte {
+	buf := make([]byte, size)
+	v.FillBytes(buf)
+	slices.Reverse(buf)
+	return buf
+}
+
+// fromLittleEndian reads a little-endian integer
+func fromLittleEndian(b []byte) *big.Int {
+	be := slices.Clone(b)
+	slices.Reverse(be)
+	return new(big.Int).SetBytes(be)
+}
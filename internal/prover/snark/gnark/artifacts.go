@@ -0,0 +1,59 @@
+package gnark
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gabrielrondon/zapiki/internal/models"
+	"github.com/gabrielrondon/zapiki/internal/storage/artifact"
+)
+
+// storedKeyURL reports whether url points at the artifact store. Circuits
+// created before keys were persisted carry "db:<id>:pk" placeholders.
+func storedKeyURL(url string) bool {
+	return url != "" && !strings.HasPrefix(url, "db:")
+}
+
+// hasStoredKeys reports whether loadCircuitKeys loads keys for circuit
+func hasStoredKeys(store artifact.Store, circuit *models.Circuit) bool {
+	return store != nil && circuit != nil && storedKeyURL(circuit.ProvingKeyURL)
+}
+
+// loadCircuitKeys loads the key pair recorded on a circuit from the artifact
+// store. It returns nil keys when the circuit has no stored keys.
+func loadCircuitKeys(ctx context.Context, store artifact.Store, circuit *models.Circuit) (json.RawMessage, json.RawMessage, error) {
+	if !hasStoredKeys(store, circuit) {
+		return nil, nil, nil
+	}
+
+	pk, err := store.Get(ctx, circuit.ProvingKeyURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load proving key: %w", err)
+	}
+
+	vk, err := loadVerificationKey(ctx, store, circuit.VerificationKeyURL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return pk, vk, nil
+}
+
+// loadVerificationKey loads a verification key from the artifact store
+func loadVerificationKey(ctx context.Context, store artifact.Store, url string) (json.RawMessage, error) {
+	if !storedKeyURL(url) {
+		return nil, fmt.Errorf("verification key is required")
+	}
+	if store == nil {
+		return nil, fmt.Errorf("no artifact store configured to load %s", url)
+	}
+
+	vk, err := store.Get(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load verification key: %w", err)
+	}
+
+	return vk, nil
+}
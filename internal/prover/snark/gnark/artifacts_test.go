@@ -0,0 +1,187 @@
+package gnark
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gabrielrondon/zapiki/internal/models"
+	"github.com/gabrielrondon/zapiki/internal/prover"
+	"github.com/gabrielrondon/zapiki/internal/storage/artifact"
+)
+
+func TestGroth16Prover_LoadsKeysByURL(t *testing.T) {
+	ctx := context.Background()
+
+	store, err := artifact.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create artifact store: %v", err)
+	}
+
+	// Keys come from one prover and are used by another with its own cache,
+	// as with the API and worker processes
+	req := simpleProofRequest(t)
+	setup, err := NewGroth16Prover().Setup(ctx, req.Circuit)
+	if err != nil {
+		t.Fatalf("Failed to setup: %v", err)
+	}
+
+	req.Circuit.ProvingKeyURL, err = store.Put(ctx, "circuits/test/proving_key.json", setup.ProvingKey)
+	if err != nil {
+		t.Fatalf("Failed to store proving key: %v", err)
+	}
+	req.Circuit.VerificationKeyURL, err = store.Put(ctx, "circuits/test/verification_key.json", setup.VerificationKey)
+	if err != nil {
+		t.Fatalf("Failed to store verification key: %v", err)
+	}
+
+	p := NewGroth16Prover()
+	p.SetArtifactStore(store)
+
+	resp, err := p.Generate(ctx, req)
+	if err != nil {
+		t.Fatalf("Failed to generate proof: %v", err)
+	}
+
+	if !bytes.Equal(resp.VerificationKey, setup.VerificationKey) {
+		t.Fatal("Expected the proof to use the stored verification key")
+	}
+
+	verifyResp, err := p.Verify(ctx, &prover.VerifyRequest{
+		Proof:              resp.Proof,
+		PublicInputs:       resp.PublicInputs,
+		VerificationKeyURL: req.Circuit.VerificationKeyURL,
+	})
+	if err != nil {
+		t.Fatalf("Failed to verify: %v", err)
+	}
+	if !verifyResp.Valid {
+		t.Errorf("Expected proof to verify against the stored key, got: %s", verifyResp.ErrorMessage)
+	}
+}
+
+func TestPLONKProver_LoadsKeysByURL(t *testing.T) {
+	ctx := context.Background()
+
+	store, err := artifact.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create artifact store: %v", err)
+	}
+
+	req := simpleProofRequest(t)
+	setup, err := NewPLONKProver().Setup(ctx, req.Circuit)
+	if err != nil {
+		t.Fatalf("Failed to setup: %v", err)
+	}
+
+	req.Circuit.ProvingKeyURL, _ = store.Put(ctx, "circuits/test/proving_key.json", setup.ProvingKey)
+	req.Circuit.VerificationKeyURL, _ = store.Put(ctx, "circuits/test/verification_key.json", setup.VerificationKey)
+
+	p := NewPLONKProver()
+	p.SetArtifactStore(store)
+
+	resp, err := p.Generate(ctx, req)
+	if err != nil {
+		t.Fatalf("Failed to generate proof: %v", err)
+	}
+
+	verifyResp, err := p.Verify(ctx, &prover.VerifyRequest{
+		Proof:              resp.Proof,
+		PublicInputs:       resp.PublicInputs,
+		VerificationKeyURL: req.Circuit.VerificationKeyURL,
+	})
+	if err != nil {
+		t.Fatalf("Failed to verify: %v", err)
+	}
+	if !verifyResp.Valid {
+		t.Errorf("Expected proof to verify against the stored key, got: %s", verifyResp.ErrorMessage)
+	}
+}
+
+func TestGroth16Prover_StoredKeysFixTheParams(t *testing.T) {
+	ctx := context.Background()
+
+	store, err := artifact.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create artifact store: %v", err)
+	}
+
+	// Created without params, the circuit is set up with the default set size
+	residency := func(codes ...interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"allowed_country_codes": codes,
+			"current_timestamp":     1700000000,
+			"user_country_code":     250,
+			"address_hash":          "0x1234",
+		}
+	}
+	req := hashCircuitRequest(t, "aml_residency_proof", nil, residency(250))
+	setup, err := NewGroth16Prover().Setup(ctx, req.Circuit)
+	if err != nil {
+		t.Fatalf("Failed to setup: %v", err)
+	}
+
+	var circuitDef circuitDefinition
+	if err := json.Unmarshal(setup.CircuitDefinition, &circuitDef); err != nil {
+		t.Fatalf("Failed to parse the resolved definition: %v", err)
+	}
+	if circuitDef.CircuitType != "aml_residency_proof" || fmt.Sprint(circuitDef.Params["set_size"]) != "1" {
+		t.Fatalf("Expected the definition to record set_size 1, got %s", setup.CircuitDefinition)
+	}
+
+	req.Circuit.ProvingKeyURL, _ = store.Put(ctx, "circuits/test/proving_key.json", setup.ProvingKey)
+	req.Circuit.VerificationKeyURL, _ = store.Put(ctx, "circuits/test/verification_key.json", setup.VerificationKey)
+
+	p := NewGroth16Prover()
+	p.SetArtifactStore(store)
+
+	// A witness of another shape is rejected rather than compiled, whether
+	// or not the definition records the params (as for circuits set up
+	// before it did)
+	for _, definition := range []json.RawMessage{req.Circuit.CircuitDefinition, setup.CircuitDefinition} {
+		circuit := *req.Circuit
+		circuit.CircuitDefinition = definition
+		wider := &prover.ProofRequest{Circuit: &circuit, Data: &models.InputData{Type: models.DataTypeJSON}}
+		wider.Data.Value, _ = json.Marshal(residency(76, 250, 276))
+		if _, err := p.Generate(ctx, wider); !errors.Is(err, prover.ErrInvalidWitness) {
+			t.Fatalf("Expected a witness for another set size to be invalid with %s, got %v", definition, err)
+		}
+	}
+
+	req.Circuit.CircuitDefinition = setup.CircuitDefinition
+
+	resp, err := p.Generate(ctx, req)
+	if err != nil {
+		t.Fatalf("Failed to generate proof: %v", err)
+	}
+	verifyResp, err := p.Verify(ctx, &prover.VerifyRequest{
+		Proof:              resp.Proof,
+		PublicInputs:       resp.PublicInputs,
+		VerificationKeyURL: req.Circuit.VerificationKeyURL,
+	})
+	if err != nil {
+		t.Fatalf("Failed to verify: %v", err)
+	}
+	if !verifyResp.Valid {
+		t.Errorf("Expected proof to verify against the stored key, got: %s", verifyResp.ErrorMessage)
+	}
+}
+
+func TestLoadCircuitKeys_IgnoresLegacyPlaceholders(t *testing.T) {
+	store, err := artifact.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create artifact store: %v", err)
+	}
+
+	circuit := simpleProofRequest(t).Circuit
+	circuit.ProvingKeyURL = "db:123:pk"
+	circuit.VerificationKeyURL = "db:123:vk"
+
+	pk, vk, err := loadCircuitKeys(context.Background(), store, circuit)
+	if err != nil || pk != nil || vk != nil {
+		t.Errorf("Expected placeholders to be skipped, got pk=%v vk=%v err=%v", pk != nil, vk != nil, err)
+	}
+}
@@ -0,0 +1,206 @@
+package gnark
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/backend/groth16"
+	groth16bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/gabrielrondon/zapiki/internal/prover"
+)
+
+// minBatchSize is the fewest proofs under one verifying key that are
+// checked together; smaller groups are verified one by one
+const minBatchSize = 2
+
+// batchProof is a decoded proof of a batch and its position in the batch
+type batchProof struct {
+	index         int
+	proof         groth16.Proof
+	publicWitness witness.Witness
+}
+
+// batchGroup holds the proofs of a batch that share a verifying key
+type batchGroup struct {
+	vk     groth16.VerifyingKey
+	proofs []*batchProof
+}
+
+// VerifyBatch verifies many Groth16 proofs. Proofs under the same verifying
+// key are checked together with one multi-pairing over a random linear
+// combination of their verification equations. A group that fails the
+// combined check is verified proof by proof to find the invalid proofs.
+func (p *Groth16Prover) VerifyBatch(ctx context.Context, reqs []*prover.VerifyRequest) ([]*prover.VerifyResponse, error) {
+	resps := make([]*prover.VerifyResponse, len(reqs))
+	storedKeys := make(map[string]json.RawMessage)
+	groups := make(map[string]*batchGroup)
+	var order []string
+
+	for i, req := range reqs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		// Proofs of one circuit usually share its stored key, so load it once
+		verificationKey := req.VerificationKey
+		if len(verificationKey) == 0 {
+			stored, ok := storedKeys[req.VerificationKeyURL]
+			if !ok {
+				var err error
+				stored, err = loadVerificationKey(ctx, p.artifacts, req.VerificationKeyURL)
+				if err != nil {
+					resps[i] = &prover.VerifyResponse{Valid: false, ErrorMessage: err.Error()}
+					continue
+				}
+				storedKeys[req.VerificationKeyURL] = stored
+			}
+			verificationKey = stored
+		}
+
+		curve, err := verifyCurve(req, formatCurve(req, p.curve))
+		if err != nil {
+			resps[i] = &prover.VerifyResponse{Valid: false, ErrorMessage: err.Error()}
+			continue
+		}
+
+		digest := sha256.Sum256(verificationKey)
+		groupKey := curve.String() + ":" + keyFormat(req) + ":" + hex.EncodeToString(digest[:])
+		group, ok := groups[groupKey]
+		if !ok {
+			vk, err := decodeGroth16Key(curve, keyFormat(req), verificationKey)
+			if err != nil {
+				resps[i] = &prover.VerifyResponse{
+					Valid:        false,
+					ErrorMessage: fmt.Sprintf("failed to deserialize verification key: %v", err),
+				}
+				continue
+			}
+			group = &batchGroup{vk: vk}
+			groups[groupKey] = group
+			order = append(order, groupKey)
+		}
+
+		proof, publicWitness, err := decodeGroth16Proof(curve, req)
+		if err != nil {
+			resps[i] = &prover.VerifyResponse{Valid: false, ErrorMessage: err.Error()}
+			continue
+		}
+		group.proofs = append(group.proofs, &batchProof{
+			index:         i,
+			proof:         proof,
+			publicWitness: publicWitness,
+		})
+	}
+
+	for _, groupKey := range order {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		group := groups[groupKey]
+		if len(group.proofs) >= minBatchSize && batchVerify(group.vk, group.proofs) {
+			for _, bp := range group.proofs {
+				resps[bp.index] = &prover.VerifyResponse{Valid: true}
+			}
+			continue
+		}
+
+		for _, bp := range group.proofs {
+			resps[bp.index] = verifyGroth16(bp.proof, group.vk, bp.publicWitness)
+		}
+	}
+
+	return resps, nil
+}
+
+// batchVerify reports whether every proof verifies under vk. It returns
+// false when any proof is invalid and for keys it cannot batch, whose
+// proofs the caller then verifies one by one.
+func batchVerify(vk groth16.VerifyingKey, proofs []*batchProof) bool {
+	switch vk := vk.(type) {
+	case *groth16bn254.VerifyingKey:
+		return batchVerifyBN254(vk, proofs)
+	default:
+		return false
+	}
+}
+
+// batchVerifyBN254 checks, for random scalars r_i, that
+//
+//	Π e(r_i·A_i, B_i) · e(-Σ r_i·L_i, γ) · e(-Σ r_i·C_i, δ) · e(-(Σ r_i)·α, β) = 1
+//
+// where L_i = K_0 + Σ_j x_ij·K_j commits to proof i's public inputs. This
+// costs n+3 Miller loops and one final exponentiation instead of n
+// pairing checks, and a batch holding an invalid proof passes with
+// negligible probability.
+func batchVerifyBN254(vk *groth16bn254.VerifyingKey, proofs []*batchProof) bool {
+	// Commitment extensions add a proof of knowledge per proof
+	if len(vk.CommitmentKeys) > 0 || len(vk.G1.K) == 0 {
+		return false
+	}
+
+	n := len(proofs)
+	rs := make(fr.Vector, n)
+	// kScalars[0] is Σ r_i and kScalars[j] is Σ r_i·x_ij
+	kScalars := make(fr.Vector, len(vk.G1.K))
+	krs := make([]bn254.G1Affine, n)
+	g1 := make([]bn254.G1Affine, n+3)
+	g2 := make([]bn254.G2Affine, n+3)
+
+	var r big.Int
+	for i, bp := range proofs {
+		proof, ok := bp.proof.(*groth16bn254.Proof)
+		if !ok || len(proof.Commitments) > 0 {
+			return false
+		}
+		inputs, ok := bp.publicWitness.Vector().(fr.Vector)
+		if !ok || len(inputs) != len(vk.G1.K)-1 {
+			return false
+		}
+		if !proof.Ar.IsInSubGroup() || !proof.Bs.IsInSubGroup() || !proof.Krs.IsInSubGroup() {
+			return false
+		}
+
+		if _, err := rs[i].SetRandom(); err != nil {
+			return false
+		}
+		kScalars[0].Add(&kScalars[0], &rs[i])
+		for j := range inputs {
+			var term fr.Element
+			term.Mul(&rs[i], &inputs[j])
+			kScalars[j+1].Add(&kScalars[j+1], &term)
+		}
+
+		g1[i].ScalarMultiplication(&proof.Ar, rs[i].BigInt(&r))
+		g2[i] = proof.Bs
+		krs[i] = proof.Krs
+	}
+
+	var sum bn254.G1Affine
+	if _, err := sum.MultiExp(vk.G1.K, kScalars, ecc.MultiExpConfig{}); err != nil {
+		return false
+	}
+	g1[n].Neg(&sum)
+	g2[n] = vk.G2.Gamma
+
+	if _, err := sum.MultiExp(krs, rs, ecc.MultiExpConfig{}); err != nil {
+		return false
+	}
+	g1[n+1].Neg(&sum)
+	g2[n+1] = vk.G2.Delta
+
+	sum.ScalarMultiplication(&vk.G1.Alpha, kScalars[0].BigInt(&r))
+	g1[n+2].Neg(&sum)
+	g2[n+2] = vk.G2.Beta
+
+	ok, err := bn254.PairingCheck(g1, g2)
+	return err == nil && ok
+}
@@ -0,0 +1,82 @@
+package gnark
+
+import (
+	"context"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/gabrielrondon/zapiki/internal/prover"
+)
+
+func TestGroth16_VerifyBatch(t *testing.T) {
+	ctx := context.Background()
+	p := NewGroth16Prover()
+
+	first := simpleProof(t, p, "bn254", 3, 5)
+	second := simpleProof(t, p, "bn254", 4, 7)
+	third := simpleProof(t, p, "bn254", 2, 9)
+	other := simpleProof(t, p, "bls12_381", 6, 6)
+
+	reqs := []*prover.VerifyRequest{
+		{Proof: first.Proof, VerificationKey: first.VerificationKey, PublicInputs: first.PublicInputs},
+		{Proof: second.Proof, VerificationKey: second.VerificationKey, PublicInputs: second.PublicInputs},
+		{Proof: third.Proof, VerificationKey: third.VerificationKey, PublicInputs: first.PublicInputs},
+		{Proof: other.Proof, VerificationKey: other.VerificationKey, PublicInputs: other.PublicInputs, Curve: "bls12_381"},
+		{Proof: []byte(`{"proof":"not base64"}`), VerificationKey: first.VerificationKey, PublicInputs: first.PublicInputs},
+	}
+
+	resps, err := p.VerifyBatch(ctx, reqs)
+	if err != nil {
+		t.Fatalf("Failed to verify batch: %v", err)
+	}
+	if len(resps) != len(reqs) {
+		t.Fatalf("Expected %d responses, got %d", len(reqs), len(resps))
+	}
+
+	expected := []bool{true, true, false, true, false}
+	for i, resp := range resps {
+		if resp.Valid != expected[i] {
+			t.Errorf("Proof %d: expected valid=%v, got %v (%s)", i, expected[i], resp.Valid, resp.ErrorMessage)
+		}
+		if !resp.Valid && resp.ErrorMessage == "" {
+			t.Errorf("Proof %d: expected an error message", i)
+		}
+	}
+}
+
+func TestBatchVerifyBN254(t *testing.T) {
+	p := NewGroth16Prover()
+
+	var proofs []*batchProof
+	for i, xy := range [][2]int{{3, 5}, {4, 7}, {2, 9}} {
+		resp := simpleProof(t, p, "bn254", xy[0], xy[1])
+		proof, publicWitness, err := decodeGroth16Proof(ecc.BN254, &prover.VerifyRequest{
+			Proof:        resp.Proof,
+			PublicInputs: resp.PublicInputs,
+		})
+		if err != nil {
+			t.Fatalf("Failed to decode proof: %v", err)
+		}
+		proofs = append(proofs, &batchProof{index: i, proof: proof, publicWitness: publicWitness})
+	}
+
+	vkJSON, err := p.VerificationKey(context.Background(), ecc.BN254, "simple", nil)
+	if err != nil {
+		t.Fatalf("Failed to get verification key: %v", err)
+	}
+	vk := groth16.NewVerifyingKey(ecc.BN254)
+	if err := decodeInto(vk, "verification_key", vkJSON); err != nil {
+		t.Fatalf("Failed to decode verification key: %v", err)
+	}
+
+	if !batchVerify(vk, proofs) {
+		t.Error("Expected the batch of valid proofs to verify")
+	}
+
+	// Swapping one proof's statement must fail the combined check
+	proofs[1].publicWitness, proofs[2].publicWitness = proofs[2].publicWitness, proofs[1].publicWitness
+	if batchVerify(vk, proofs) {
+		t.Error("Expected the batch with swapped public inputs not to verify")
+	}
+}
@@ -0,0 +1,372 @@
+package gnark
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/backend/groth16/bn254/mpcsetup"
+	csbn254 "github.com/consensys/gnark/constraint/bn254"
+	"github.com/gabrielrondon/zapiki/internal/models"
+	"github.com/gabrielrondon/zapiki/internal/prover"
+)
+
+// Groth16 keys can come from a multi-party ceremony instead of Setup.
+// Phase 1 (powers of τ, α and β) is circuit independent and supplied by a
+// Phase1Source; phase 2 (δ) runs per circuit on top of it using gnark's
+// mpcsetup. The keys are secure as long as one participant discarded their
+// randomness. Ceremonies run on BN254 only.
+
+// Phase1Source supplies the phase-1 parameters phase-2 ceremonies build on
+type Phase1Source interface {
+	// ID identifies the parameters, recorded in ceremony transcripts
+	ID() string
+
+	// Commons returns the parameters for an FFT domain of domainSize
+	Commons(domainSize uint64) (*mpcsetup.SrsCommons, error)
+}
+
+// DevPhase1 generates phase-1 parameters with a single contribution made
+// in this process. It must not be used in production.
+type DevPhase1 struct {
+	mu      sync.Mutex
+	commons map[uint64]*mpcsetup.SrsCommons
+}
+
+// NewDevPhase1 creates a development phase-1 source
+func NewDevPhase1() *DevPhase1 {
+	return &DevPhase1{commons: make(map[uint64]*mpcsetup.SrsCommons)}
+}
+
+// ID implements Phase1Source
+func (s *DevPhase1) ID() string {
+	return "unsafe-dev"
+}
+
+// Commons implements Phase1Source
+func (s *DevPhase1) Commons(domainSize uint64) (*mpcsetup.SrsCommons, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if commons, ok := s.commons[domainSize]; ok {
+		return commons, nil
+	}
+
+	phase1 := mpcsetup.NewPhase1(domainSize)
+	phase1.Contribute()
+	commons := phase1.Seal([]byte("zapiki dev phase 1"))
+	s.commons[domainSize] = &commons
+	return &commons, nil
+}
+
+// FilePhase1 serves phase-1 parameters loaded from the output of a gnark
+// mpcsetup phase-1 ceremony (mpcsetup.SrsCommons serialization), trimmed
+// to each circuit's domain
+type FilePhase1 struct {
+	commons *mpcsetup.SrsCommons
+	digest  string
+}
+
+// NewFilePhase1 loads and checks phase-1 parameters. checksum optionally
+// pins the hex SHA-256 of the file.
+func NewFilePhase1(path, checksum string) (*FilePhase1, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open phase-1 file: %w", err)
+	}
+	defer f.Close()
+
+	// ReadFrom also checks every point is on the curve and in the subgroup
+	h := sha256.New()
+	var commons mpcsetup.SrsCommons
+	if _, err := commons.ReadFrom(io.TeeReader(f, h)); err != nil {
+		return nil, fmt.Errorf("failed to read phase-1 file %s: %w", path, err)
+	}
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, fmt.Errorf("failed to read phase-1 file %s: %w", path, err)
+	}
+
+	digest := hex.EncodeToString(h.Sum(nil))
+	if checksum != "" && checksum != digest {
+		return nil, fmt.Errorf("%w: %s has sha256 %s, expected %s", ErrSRSIntegrity, path, digest, checksum)
+	}
+
+	if err := checkPhase1(&commons); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrSRSIntegrity, path, err)
+	}
+
+	return &FilePhase1{commons: &commons, digest: digest}, nil
+}
+
+// ID implements Phase1Source
+func (s *FilePhase1) ID() string {
+	return "sha256-" + s.digest[:16]
+}
+
+// Commons implements Phase1Source
+func (s *FilePhase1) Commons(domainSize uint64) (*mpcsetup.SrsCommons, error) {
+	if available := uint64(len(s.commons.G2.Tau)); available < domainSize {
+		return nil, fmt.Errorf("phase-1 parameters support a domain of %d, circuit needs %d", available, domainSize)
+	}
+
+	// Prefixes of powers of τ are the parameters for a smaller domain
+	var trimmed mpcsetup.SrsCommons
+	trimmed.G1.Tau = s.commons.G1.Tau[:2*domainSize-1]
+	trimmed.G1.AlphaTau = s.commons.G1.AlphaTau[:domainSize]
+	trimmed.G1.BetaTau = s.commons.G1.BetaTau[:domainSize]
+	trimmed.G2.Tau = s.commons.G2.Tau[:domainSize]
+	trimmed.G2.Beta = s.commons.G2.Beta
+	return &trimmed, nil
+}
+
+// checkPhase1 checks that phase-1 parameters are consistent powers of one
+// τ, scaled by one α and one β, using random linear combinations
+func checkPhase1(c *mpcsetup.SrsCommons) error {
+	n := len(c.G2.Tau)
+	if n < 2 || len(c.G1.Tau) != 2*n-1 || len(c.G1.AlphaTau) != n || len(c.G1.BetaTau) != n {
+		return fmt.Errorf("inconsistent parameter sizes")
+	}
+	_, _, g1, g2 := bn254.Generators()
+
+	// e(Σ rᵢ[τⁱ]₁, [τ]₂) = e(Σ rᵢ[τⁱ⁺¹]₁, [1]₂), and the same for [ατⁱ]₁
+	for _, powers := range [][]bn254.G1Affine{c.G1.Tau, c.G1.AlphaTau} {
+		a, b, err := g1Combinations(powers[:len(powers)-1], powers[1:])
+		if err != nil {
+			return err
+		}
+		if err := samePairing(a, c.G2.Tau[1], b, g2); err != nil {
+			return fmt.Errorf("G1 points are not successive powers of tau")
+		}
+	}
+
+	// e([τ]₁, Σ rᵢ[τⁱ]₂) = e([1]₁, Σ rᵢ[τⁱ⁺¹]₂)
+	r, err := randomScalars(n - 1)
+	if err != nil {
+		return err
+	}
+	var a2, b2 bn254.G2Affine
+	if _, err := a2.MultiExp(c.G2.Tau[:n-1], r, ecc.MultiExpConfig{}); err != nil {
+		return err
+	}
+	if _, err := b2.MultiExp(c.G2.Tau[1:], r, ecc.MultiExpConfig{}); err != nil {
+		return err
+	}
+	if err := samePairing(c.G1.Tau[1], a2, g1, b2); err != nil {
+		return fmt.Errorf("G2 points are not successive powers of tau")
+	}
+
+	// e(Σ rᵢ[βτⁱ]₁, [1]₂) = e(Σ rᵢ[τⁱ]₁, [β]₂)
+	beta, tau, err := g1Combinations(c.G1.BetaTau, c.G1.Tau[:n])
+	if err != nil {
+		return err
+	}
+	if err := samePairing(beta, g2, tau, c.G2.Beta); err != nil {
+		return fmt.Errorf("beta points do not match [β]₂")
+	}
+
+	return nil
+}
+
+// g1Combinations returns Σ rᵢ·x[i] and Σ rᵢ·y[i] for the same random rᵢ
+func g1Combinations(x, y []bn254.G1Affine) (bn254.G1Affine, bn254.G1Affine, error) {
+	var a, b bn254.G1Affine
+	r, err := randomScalars(len(x))
+	if err != nil {
+		return a, b, err
+	}
+	if _, err := a.MultiExp(x, r, ecc.MultiExpConfig{}); err != nil {
+		return a, b, err
+	}
+	if _, err := b.MultiExp(y, r, ecc.MultiExpConfig{}); err != nil {
+		return a, b, err
+	}
+	return a, b, nil
+}
+
+func randomScalars(n int) ([]fr.Element, error) {
+	r := make([]fr.Element, n)
+	for i := range r {
+		if _, err := r[i].SetRandom(); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// samePairing checks e(a1, a2) = e(b1, b2)
+func samePairing(a1 bn254.G1Affine, a2 bn254.G2Affine, b1 bn254.G1Affine, b2 bn254.G2Affine) error {
+	b1.Neg(&b1)
+	return pairingResult(bn254.PairingCheck([]bn254.G1Affine{a1, b1}, []bn254.G2Affine{a2, b2}))
+}
+
+// SetPhase1 enables setup ceremonies on top of the given phase-1 parameters
+func (p *Groth16Prover) SetPhase1(source Phase1Source) {
+	p.phase1 = source
+}
+
+// ceremonyCircuit compiles a circuit for a ceremony and returns it with
+// phase-1 parameters sized for it and the circuit's definition with the
+// params it was compiled with
+func (p *Groth16Prover) ceremonyCircuit(circuit *models.Circuit) (*csbn254.R1CS, *mpcsetup.SrsCommons, json.RawMessage, error) {
+	if p.phase1 == nil {
+		return nil, nil, nil, fmt.Errorf("%w: no phase-1 parameters configured", prover.ErrCeremonyUnsupported)
+	}
+
+	var circuitDef circuitDefinition
+	if err := json.Unmarshal(circuit.CircuitDefinition, &circuitDef); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to parse circuit definition: %w", err)
+	}
+
+	params, err := circuitParams(circuitDef.CircuitType, circuitDef.allParams(), nil)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	curve, err := resolveCurve("", circuitDef.Curve, p.curve)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if curve != ecc.BN254 {
+		return nil, nil, nil, fmt.Errorf("%w: curve %s (ceremonies run on bn254)", prover.ErrCeremonyUnsupported, curve)
+	}
+
+	ccs, err := compileR1CS(curve, circuitDef.CircuitType, params)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to compile circuit: %w", err)
+	}
+
+	commons, err := p.phase1.Commons(ecc.NextPowerOfTwo(uint64(ccs.GetNbConstraints())))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	definition, err := resolvedDefinition(circuit.CircuitDefinition, circuitDef.CircuitType, params)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return ccs.(*csbn254.R1CS), commons, definition, nil
+}
+
+// CeremonyStart returns the initial phase-2 state for a circuit
+func (p *Groth16Prover) CeremonyStart(ctx context.Context, circuit *models.Circuit) ([]byte, string, error) {
+	ccs, commons, _, err := p.ceremonyCircuit(circuit)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var phase2 mpcsetup.Phase2
+	phase2.Initialize(ccs, commons)
+
+	state, err := writeKey(&phase2)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to serialize ceremony state: %w", err)
+	}
+	return state, p.phase1.ID(), nil
+}
+
+// CeremonyVerify checks a phase-2 contribution against the state it extends
+func (p *Groth16Prover) CeremonyVerify(ctx context.Context, prev, next []byte) ([]byte, error) {
+	prevState, err := readPhase2(prev)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ceremony state: %w", err)
+	}
+	nextState, err := readPhase2(next)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", prover.ErrInvalidContribution, err)
+	}
+
+	if err := prevState.Verify(nextState); err != nil {
+		return nil, fmt.Errorf("%w: %v", prover.ErrInvalidContribution, err)
+	}
+
+	return writeKey(nextState)
+}
+
+// CeremonyFinalize verifies the whole phase-2 transcript and derives the
+// circuit's keys from its last contribution and the beacon
+func (p *Groth16Prover) CeremonyFinalize(ctx context.Context, circuit *models.Circuit, contributions [][]byte, beacon []byte) (*prover.SetupResult, error) {
+	if len(contributions) == 0 {
+		return nil, fmt.Errorf("at least one contribution is required")
+	}
+	if len(beacon) == 0 {
+		return nil, fmt.Errorf("a beacon is required")
+	}
+
+	ccs, commons, definition, err := p.ceremonyCircuit(circuit)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]*mpcsetup.Phase2, len(contributions))
+	for i, contribution := range contributions {
+		if states[i], err = readPhase2(contribution); err != nil {
+			return nil, fmt.Errorf("%w: contribution %d: %v", prover.ErrInvalidContribution, i+1, err)
+		}
+	}
+
+	// VerifyPhase2 recomputes the initial state rather than trusting a stored one
+	pk, vk, err := mpcsetup.VerifyPhase2(ccs, commons, beacon, states...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", prover.ErrInvalidContribution, err)
+	}
+
+	pkBytes, err := writeKey(pk)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize proving key: %w", err)
+	}
+
+	vkBytes, err := writeKey(vk)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize verification key: %w", err)
+	}
+
+	return &prover.SetupResult{
+		ProvingKey:        encodeBinary("proving_key", pkBytes),
+		VerificationKey:   encodeBinary("verification_key", vkBytes),
+		CircuitDefinition: definition,
+		Metadata: map[string]interface{}{
+			"curve":         ecc.BN254.String(),
+			"constraints":   ccs.GetNbConstraints(),
+			"variables":     ccs.GetNbSecretVariables() + ccs.GetNbPublicVariables(),
+			"setup_type":    "ceremony",
+			"phase1":        p.phase1.ID(),
+			"contributions": len(contributions),
+		},
+	}, nil
+}
+
+// ContributePhase2 adds fresh randomness to a downloaded ceremony state and
+// returns the contribution to upload. The randomness never leaves this
+// function, so participants should run it on their own machine.
+func ContributePhase2(state []byte) ([]byte, error) {
+	phase2, err := readPhase2(state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ceremony state: %w", err)
+	}
+
+	phase2.Contribute()
+	return writeKey(phase2)
+}
+
+// readPhase2 deserializes a phase-2 state, rejecting trailing data
+func readPhase2(data []byte) (*mpcsetup.Phase2, error) {
+	var phase2 mpcsetup.Phase2
+	n, err := phase2.ReadFrom(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if n != int64(len(data)) {
+		return nil, fmt.Errorf("unexpected data after ceremony state")
+	}
+	return &phase2, nil
+}
@@ -0,0 +1,231 @@
+package gnark
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gabrielrondon/zapiki/internal/models"
+	"github.com/gabrielrondon/zapiki/internal/prover"
+	"github.com/gabrielrondon/zapiki/internal/storage/artifact"
+)
+
+func ceremonyTestCircuit(curve string) *models.Circuit {
+	def := map[string]interface{}{"circuit_type": "simple"}
+	if curve != "" {
+		def["curve"] = curve
+	}
+	circuitDefJSON, _ := json.Marshal(def)
+	return &models.Circuit{CircuitDefinition: circuitDefJSON}
+}
+
+// runCeremony starts a ceremony and applies n verified contributions
+func runCeremony(t *testing.T, p *Groth16Prover, circuit *models.Circuit, n int) [][]byte {
+	t.Helper()
+	ctx := context.Background()
+
+	state, base, err := p.CeremonyStart(ctx, circuit)
+	if err != nil {
+		t.Fatalf("Failed to start ceremony: %v", err)
+	}
+	if base != "unsafe-dev" {
+		t.Errorf("Expected dev phase-1 parameters, got %s", base)
+	}
+
+	var contributions [][]byte
+	for i := 0; i < n; i++ {
+		next, err := ContributePhase2(state)
+		if err != nil {
+			t.Fatalf("Failed to contribute: %v", err)
+		}
+		state, err = p.CeremonyVerify(ctx, state, next)
+		if err != nil {
+			t.Fatalf("Failed to verify contribution %d: %v", i+1, err)
+		}
+		contributions = append(contributions, state)
+	}
+	return contributions
+}
+
+func TestGroth16Prover_Ceremony(t *testing.T) {
+	ctx := context.Background()
+
+	store, err := artifact.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create artifact store: %v", err)
+	}
+
+	p := NewGroth16Prover()
+	p.SetArtifactStore(store)
+	p.SetPhase1(NewDevPhase1())
+
+	circuit := ceremonyTestCircuit("")
+	contributions := runCeremony(t, p, circuit, 3)
+
+	setup, err := p.CeremonyFinalize(ctx, circuit, contributions, []byte("beacon"))
+	if err != nil {
+		t.Fatalf("Failed to finalize ceremony: %v", err)
+	}
+	if setup.Metadata["setup_type"] != "ceremony" || setup.Metadata["contributions"] != 3 {
+		t.Errorf("Unexpected setup metadata: %v", setup.Metadata)
+	}
+
+	// Proofs for the circuit now use the ceremony keys
+	circuit.ProvingKeyURL, _ = store.Put(ctx, "circuits/test/proving_key.json", setup.ProvingKey)
+	circuit.VerificationKeyURL, _ = store.Put(ctx, "circuits/test/verification_key.json", setup.VerificationKey)
+
+	resp := simpleCircuitProof(t, p, circuit, 3, 5, 15)
+	if string(resp.VerificationKey) != string(setup.VerificationKey) {
+		t.Fatal("Expected the proof to be generated with the ceremony keys")
+	}
+
+	verifyResp, err := p.Verify(ctx, &prover.VerifyRequest{
+		Proof:              resp.Proof,
+		PublicInputs:       resp.PublicInputs,
+		VerificationKeyURL: circuit.VerificationKeyURL,
+	})
+	if err != nil {
+		t.Fatalf("Failed to verify: %v", err)
+	}
+	if !verifyResp.Valid {
+		t.Errorf("Expected proof to be valid, got error: %s", verifyResp.ErrorMessage)
+	}
+
+	// The keys come from the contributions, not from the beacon alone
+	again, err := p.CeremonyFinalize(ctx, circuit, runCeremony(t, p, circuit, 1), []byte("beacon"))
+	if err != nil {
+		t.Fatalf("Failed to finalize ceremony: %v", err)
+	}
+	if string(again.VerificationKey) == string(setup.VerificationKey) {
+		t.Error("Expected different contributions to give different keys")
+	}
+}
+
+func TestGroth16Prover_CeremonyRejectsInvalidContributions(t *testing.T) {
+	ctx := context.Background()
+
+	p := NewGroth16Prover()
+	p.SetPhase1(NewDevPhase1())
+	circuit := ceremonyTestCircuit("")
+
+	initial, _, err := p.CeremonyStart(ctx, circuit)
+	if err != nil {
+		t.Fatalf("Failed to start ceremony: %v", err)
+	}
+	first, err := ContributePhase2(initial)
+	if err != nil {
+		t.Fatalf("Failed to contribute: %v", err)
+	}
+	// A second participant who contributed to the same, now stale, state
+	stale, err := ContributePhase2(initial)
+	if err != nil {
+		t.Fatalf("Failed to contribute: %v", err)
+	}
+
+	truncated := first[:len(first)-10]
+	tampered := append([]byte(nil), first...)
+	tampered[len(tampered)/2] ^= 1
+
+	tests := []struct {
+		name string
+		prev []byte
+		next []byte
+	}{
+		{"unchanged state", initial, initial},
+		{"stale state", first, stale},
+		{"truncated", initial, truncated},
+		{"tampered", initial, tampered},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := p.CeremonyVerify(ctx, tt.prev, tt.next)
+			if !errors.Is(err, prover.ErrInvalidContribution) {
+				t.Fatalf("Expected ErrInvalidContribution, got %v", err)
+			}
+		})
+	}
+
+	// Finalization re-verifies the chain, so an unverified upload still fails
+	if _, err := p.CeremonyFinalize(ctx, circuit, [][]byte{first, stale}, []byte("beacon")); !errors.Is(err, prover.ErrInvalidContribution) {
+		t.Fatalf("Expected ErrInvalidContribution, got %v", err)
+	}
+}
+
+func TestGroth16Prover_CeremonyUnsupported(t *testing.T) {
+	ctx := context.Background()
+
+	p := NewGroth16Prover()
+	if _, _, err := p.CeremonyStart(ctx, ceremonyTestCircuit("")); !errors.Is(err, prover.ErrCeremonyUnsupported) {
+		t.Fatalf("Expected ErrCeremonyUnsupported without phase-1 parameters, got %v", err)
+	}
+
+	p.SetPhase1(NewDevPhase1())
+	if _, _, err := p.CeremonyStart(ctx, ceremonyTestCircuit("bls12_381")); !errors.Is(err, prover.ErrCeremonyUnsupported) {
+		t.Fatalf("Expected ErrCeremonyUnsupported on bls12_381, got %v", err)
+	}
+}
+
+func TestNewFilePhase1(t *testing.T) {
+	commons, err := NewDevPhase1().Commons(16)
+	if err != nil {
+		t.Fatalf("Failed to create phase-1 parameters: %v", err)
+	}
+
+	write := func(t *testing.T, tamper bool) (string, string) {
+		t.Helper()
+		c := *commons
+		if tamper {
+			c.G1.AlphaTau = append(c.G1.AlphaTau[:0:0], c.G1.AlphaTau...)
+			c.G1.AlphaTau[3].Add(&c.G1.AlphaTau[3], &c.G1.Tau[0])
+		}
+		data, err := writeKey(&c)
+		if err != nil {
+			t.Fatalf("Failed to serialize phase-1 parameters: %v", err)
+		}
+		path := filepath.Join(t.TempDir(), "phase1.bin")
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatalf("Failed to write phase-1 file: %v", err)
+		}
+		sum := sha256.Sum256(data)
+		return path, hex.EncodeToString(sum[:])
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		path, checksum := write(t, false)
+		source, err := NewFilePhase1(path, checksum)
+		if err != nil {
+			t.Fatalf("Failed to load phase-1 file: %v", err)
+		}
+
+		trimmed, err := source.Commons(4)
+		if err != nil {
+			t.Fatalf("Failed to trim phase-1 parameters: %v", err)
+		}
+		if len(trimmed.G2.Tau) != 4 || len(trimmed.G1.Tau) != 7 {
+			t.Errorf("Expected a domain of 4, got %d G2 and %d G1 powers", len(trimmed.G2.Tau), len(trimmed.G1.Tau))
+		}
+		if _, err := source.Commons(32); err == nil {
+			t.Error("Expected an error for a domain larger than the parameters")
+		}
+	})
+
+	t.Run("checksum mismatch", func(t *testing.T) {
+		path, _ := write(t, false)
+		if _, err := NewFilePhase1(path, hex.EncodeToString(make([]byte, 32))); !errors.Is(err, ErrSRSIntegrity) {
+			t.Fatalf("Expected ErrSRSIntegrity, got %v", err)
+		}
+	})
+
+	t.Run("tampered point", func(t *testing.T) {
+		path, _ := write(t, true)
+		if _, err := NewFilePhase1(path, ""); !errors.Is(err, ErrSRSIntegrity) {
+			t.Fatalf("Expected ErrSRSIntegrity, got %v", err)
+		}
+	})
+}
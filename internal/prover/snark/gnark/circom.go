@@ -0,0 +1,219 @@
+package gnark
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/gabrielrondon/zapiki/internal/models"
+	"github.com/gabrielrondon/zapiki/internal/prover"
+	"github.com/gabrielrondon/zapiki/internal/prover/snark/gnark/circom"
+	"github.com/gabrielrondon/zapiki/internal/storage/artifact"
+)
+
+// circomCircuitType is the circuit type of circuits imported from Circom
+const circomCircuitType = "circom"
+
+// circomImport describes an imported Circom circuit in its definition
+type circomImport struct {
+	R1CSURL  string `json:"r1cs_url,omitempty"`
+	R1CSHash string `json:"r1cs_sha256,omitempty"`
+	// PublicSignals names the public signals in witness order, outputs first
+	PublicSignals []string `json:"public_signals"`
+	Constraints   int      `json:"constraints,omitempty"`
+	Wires         int      `json:"wires,omitempty"`
+}
+
+// importCircom reads the files of a Circom circuit: "r1cs" (required),
+// "sym" to name the public signals unless the definition lists them, and
+// "wtns", a witness the circuit is checked against. The .r1cs is stored in
+// the artifact store and the definition rewritten to point at it.
+func importCircom(ctx context.Context, store artifact.Store, circuit *models.Circuit, files map[string][]byte) error {
+	if store == nil {
+		return fmt.Errorf("no artifact store configured for imported circuits")
+	}
+
+	var circuitDef circuitDefinition
+	if len(circuit.CircuitDefinition) > 0 {
+		if err := json.Unmarshal(circuit.CircuitDefinition, &circuitDef); err != nil {
+			return fmt.Errorf("%w: %v", prover.ErrInvalidCircuit, err)
+		}
+	}
+	if circuitDef.CircuitType == "" {
+		circuitDef.CircuitType = circomCircuitType
+	}
+	if circuitDef.CircuitType != circomCircuitType {
+		return fmt.Errorf("%w: only %q circuits can be imported from files", prover.ErrInvalidCircuit, circomCircuitType)
+	}
+	for name := range files {
+		if name != "r1cs" && name != "sym" && name != "wtns" {
+			return fmt.Errorf("%w: unexpected file %q (expected r1cs, sym or wtns)", prover.ErrInvalidCircuit, name)
+		}
+	}
+
+	data, ok := files["r1cs"]
+	if !ok {
+		return fmt.Errorf("%w: the r1cs file is required", prover.ErrInvalidCircuit)
+	}
+	r, err := circom.ParseR1CS(data)
+	if err != nil {
+		return fmt.Errorf("%w: r1cs: %v", prover.ErrInvalidCircuit, err)
+	}
+
+	curve := strings.ToLower(r.Curve.String())
+	if circuitDef.Curve != "" {
+		id, err := ParseCurve(circuitDef.Curve)
+		if err != nil {
+			return fmt.Errorf("%w: %v", prover.ErrInvalidCircuit, err)
+		}
+		if id != r.Curve {
+			return fmt.Errorf("%w: r1cs is compiled for %s, not %s", prover.ErrInvalidCircuit, curve, circuitDef.Curve)
+		}
+	}
+
+	var names []string
+	if circuitDef.Circom != nil {
+		names = circuitDef.Circom.PublicSignals
+	}
+	if sym, ok := files["sym"]; ok {
+		if names, err = r.PublicSignals(sym); err != nil {
+			return fmt.Errorf("%w: sym: %v", prover.ErrInvalidCircuit, err)
+		}
+	}
+	if len(names) != r.NbPublic() {
+		return fmt.Errorf("%w: the circuit has %d public signals; upload the sym file or list their names in circom.public_signals",
+			prover.ErrInvalidCircuit, r.NbPublic())
+	}
+	for i, name := range names {
+		if name == "" {
+			return fmt.Errorf("%w: public signal %d has no name", prover.ErrInvalidCircuit, i+1)
+		}
+	}
+
+	if wtns, ok := files["wtns"]; ok {
+		witness, err := r.ParseWitness(wtns)
+		if err == nil {
+			err = r.Check(witness)
+		}
+		if err != nil {
+			return fmt.Errorf("%w: wtns: %v", prover.ErrInvalidCircuit, err)
+		}
+	}
+
+	url, err := store.Put(ctx, fmt.Sprintf("circuits/%s/circuit.r1cs", circuit.ID), data)
+	if err != nil {
+		return fmt.Errorf("failed to store r1cs: %w", err)
+	}
+
+	circuitDef.Curve = curve
+	circuitDef.Circom = &circomImport{
+		R1CSURL:       url,
+		R1CSHash:      r.Hash,
+		PublicSignals: names,
+		Constraints:   len(r.Constraints),
+		Wires:         r.Wires,
+	}
+	definition, err := json.Marshal(circuitDef)
+	if err != nil {
+		_ = store.Delete(ctx, url)
+		return fmt.Errorf("failed to encode circuit definition: %w", err)
+	}
+	circuit.CircuitDefinition = definition
+	return nil
+}
+
+// deleteCircom removes the stored .r1cs of an imported circuit
+func deleteCircom(ctx context.Context, store artifact.Store, circuit *models.Circuit) error {
+	var circuitDef circuitDefinition
+	if err := json.Unmarshal(circuit.CircuitDefinition, &circuitDef); err != nil {
+		return fmt.Errorf("failed to parse circuit definition: %w", err)
+	}
+	if circuitDef.CircuitType != circomCircuitType || circuitDef.Circom == nil || circuitDef.Circom.R1CSURL == "" || store == nil {
+		return nil
+	}
+	return store.Delete(ctx, circuitDef.Circom.R1CSURL)
+}
+
+// loadCircom loads the constraint system of an imported circuit into the
+// definition's params, for circuitParams
+func (d *circuitDefinition) loadCircom(ctx context.Context, store artifact.Store) error {
+	if d.CircuitType != circomCircuitType || d.Circom == nil || d.Circom.R1CSURL == "" {
+		return nil
+	}
+	if store == nil {
+		return fmt.Errorf("no artifact store configured to load %s", d.Circom.R1CSURL)
+	}
+
+	data, err := store.Get(ctx, d.Circom.R1CSURL)
+	if err != nil {
+		return fmt.Errorf("failed to load r1cs: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != d.Circom.R1CSHash {
+		return fmt.Errorf("stored r1cs does not match the circuit definition")
+	}
+	r, err := circom.ParseR1CS(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse r1cs: %w", err)
+	}
+
+	d.Params = map[string]interface{}{"r1cs": r}
+	return nil
+}
+
+// circomR1CS reads the constraint system of an imported circuit
+func circomR1CS(params map[string]interface{}) (*circom.R1CS, error) {
+	r, ok := params["r1cs"].(*circom.R1CS)
+	if !ok {
+		return nil, fmt.Errorf("circom circuits are created by uploading their r1cs file")
+	}
+	return r, nil
+}
+
+// circomWitness assigns the full witness of an imported circuit, given as
+// "wtns" (a base64 .wtns file) or "witness" (the JSON array written by
+// `snarkjs wtns export json`)
+func circomWitness(params map[string]interface{}, in *Inputs) (frontend.Circuit, error) {
+	r, err := circomR1CS(params)
+	if err != nil {
+		return nil, err
+	}
+	inputData := in.Values()
+
+	var witness []*big.Int
+	switch {
+	case inputData["wtns"] != nil:
+		encoded, _ := inputData["wtns"].(string)
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("%w: wtns must be a base64 .wtns file: %v", prover.ErrInvalidWitness, err)
+		}
+		witness, err = r.ParseWitness(data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: wtns: %v", prover.ErrInvalidWitness, err)
+		}
+	case inputData["witness"] != nil:
+		values, ok := inputData["witness"].([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%w: witness must be an array of decimal strings", prover.ErrInvalidWitness)
+		}
+		witness, err = r.DecodeWitness(values)
+		if err != nil {
+			return nil, fmt.Errorf("%w: witness: %v", prover.ErrInvalidWitness, err)
+		}
+	default:
+		return nil, fmt.Errorf("%w: circom circuits take a \"wtns\" file or a \"witness\" array", prover.ErrInvalidWitness)
+	}
+
+	c, err := r.Assign(witness)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", prover.ErrInvalidWitness, err)
+	}
+	return c, nil
+}
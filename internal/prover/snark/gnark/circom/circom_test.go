@@ -0,0 +1,204 @@
+package circom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/test"
+)
+
+// file writes an iden3 binary file with the given sections
+func file(magic string, version uint32, secs map[uint32][]byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(magic)
+	binary.Write(&buf, binary.LittleEndian, version)
+	binary.Write(&buf, binary.LittleEndian, uint32(len(secs)))
+	for kind := uint32(1); kind <= 5; kind++ {
+		content, ok := secs[kind]
+		if !ok {
+			continue
+		}
+		binary.Write(&buf, binary.LittleEndian, kind)
+		binary.Write(&buf, binary.LittleEndian, uint64(len(content)))
+		buf.Write(content)
+	}
+	return buf.Bytes()
+}
+
+// element encodes a field element in 32 little-endian bytes
+func element(buf *bytes.Buffer, v *big.Int) {
+	be := v.FillBytes(make([]byte, 32))
+	for i := len(be) - 1; i >= 0; i-- {
+		buf.WriteByte(be[i])
+	}
+}
+
+// multiplier is the .r1cs of a Circom template computing out <== a * b + c
+// with out public, a and b private and c a public input:
+//
+//	wires: 0 one, 1 out, 2 c, 3 a, 4 b, 5 ab
+//	a * b = ab
+//	(ab + c) * 1 = out
+func multiplier() []byte {
+	return multiplierWithWires(6)
+}
+
+// multiplierWithWires is multiplier with the header declaring wires wires
+func multiplierWithWires(wires uint32) []byte {
+	modulus := ecc.BN254.ScalarField()
+	one := big.NewInt(1)
+
+	var header bytes.Buffer
+	binary.Write(&header, binary.LittleEndian, uint32(32))
+	element(&header, modulus)
+	binary.Write(&header, binary.LittleEndian, []uint32{wires, 1, 1, 2})
+	binary.Write(&header, binary.LittleEndian, uint64(6))
+	binary.Write(&header, binary.LittleEndian, uint32(2))
+
+	var constraints bytes.Buffer
+	lc := func(terms ...int) {
+		binary.Write(&constraints, binary.LittleEndian, uint32(len(terms)))
+		for _, wire := range terms {
+			binary.Write(&constraints, binary.LittleEndian, uint32(wire))
+			element(&constraints, one)
+		}
+	}
+	lc(3)
+	lc(4)
+	lc(5)
+	lc(5, 2)
+	lc(0)
+	lc(1)
+
+	return file("r1cs", 1, map[uint32][]byte{1: header.Bytes(), 2: constraints.Bytes()})
+}
+
+// wtns encodes a .wtns file
+func wtns(values ...int64) []byte {
+	var header bytes.Buffer
+	binary.Write(&header, binary.LittleEndian, uint32(32))
+	element(&header, ecc.BN254.ScalarField())
+	binary.Write(&header, binary.LittleEndian, uint32(len(values)))
+
+	var body bytes.Buffer
+	for _, v := range values {
+		element(&body, big.NewInt(v))
+	}
+	return file("wtns", 2, map[uint32][]byte{1: header.Bytes(), 2: body.Bytes()})
+}
+
+const multiplierSymbols = `1,1,0,main.out
+2,2,0,main.c
+3,3,0,main.a
+4,4,0,main.b
+5,5,0,main.ab
+6,-1,0,main.unused
+`
+
+func TestR1CS_Multiplier(t *testing.T) {
+	r, err := ParseR1CS(multiplier())
+	if err != nil {
+		t.Fatalf("Failed to parse r1cs: %v", err)
+	}
+	if r.Curve != ecc.BN254 || r.NbPublic() != 2 || len(r.Constraints) != 2 {
+		t.Fatalf("Unexpected constraint system: curve %s, %d public, %d constraints", r.Curve, r.NbPublic(), len(r.Constraints))
+	}
+
+	names, err := r.PublicSignals([]byte(multiplierSymbols))
+	if err != nil {
+		t.Fatalf("Failed to read symbols: %v", err)
+	}
+	if len(names) != 2 || names[0] != "out" || names[1] != "c" {
+		t.Errorf("Expected public signals [out c], got %v", names)
+	}
+
+	// out = 3 * 5 + 2
+	witness, err := r.ParseWitness(wtns(1, 17, 2, 3, 5, 15))
+	if err != nil {
+		t.Fatalf("Failed to parse witness: %v", err)
+	}
+	if err := r.Check(witness); err != nil {
+		t.Errorf("Expected the witness to satisfy the constraints: %v", err)
+	}
+	assignment, err := r.Assign(witness)
+	if err != nil {
+		t.Fatalf("Failed to assign witness: %v", err)
+	}
+	if err := test.IsSolved(r.Circuit(), assignment, ecc.BN254.ScalarField()); err != nil {
+		t.Errorf("Expected the circuit to be satisfied: %v", err)
+	}
+
+	bad, err := r.DecodeWitness([]interface{}{"1", "18", "2", "3", "5", "15"})
+	if err != nil {
+		t.Fatalf("Failed to decode witness: %v", err)
+	}
+	if err := r.Check(bad); err == nil {
+		t.Error("Wrong output should not satisfy the constraints")
+	}
+	assignment, _ = r.Assign(bad)
+	if err := test.IsSolved(r.Circuit(), assignment, ecc.BN254.ScalarField()); err == nil {
+		t.Error("Wrong output should not satisfy the circuit")
+	}
+}
+
+// The multiplier files in testdata are used by the gnark prover tests
+func TestTestdata(t *testing.T) {
+	files := map[string][]byte{
+		"testdata/multiplier.r1cs": multiplier(),
+		"testdata/multiplier.wtns": wtns(1, 17, 2, 3, 5, 15),
+		"testdata/multiplier.sym":  []byte(multiplierSymbols),
+	}
+	for name, want := range files {
+		got, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", name, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s is out of date", name)
+		}
+	}
+}
+
+func TestParseR1CS_Rejects(t *testing.T) {
+	valid := multiplier()
+	cases := map[string][]byte{
+		"empty":     nil,
+		"magic":     append([]byte("wtns"), valid[4:]...),
+		"truncated": valid[:len(valid)-5],
+		"no header": file("r1cs", 1, map[uint32][]byte{2: {}}),
+		"custom gates": file("r1cs", 1, map[uint32][]byte{
+			1: {}, 2: {}, 4: {},
+		}),
+		"oversized header": multiplierWithWires(MaxWires + 1),
+		"maximum wires":    multiplierWithWires(math.MaxUint32),
+	}
+	for name, data := range cases {
+		if _, err := ParseR1CS(data); err == nil {
+			t.Errorf("%s: expected the file to be rejected", name)
+		}
+	}
+
+	r, err := ParseR1CS(valid)
+	if err != nil {
+		t.Fatalf("Failed to parse r1cs: %v", err)
+	}
+	witnesses := map[string][]interface{}{
+		"length":        {"1", "17", "2", "3", "5"},
+		"no constant":   {"0", "17", "2", "3", "5", "15"},
+		"not a number":  {"1", "17", "2", "3", "5", "x"},
+		"outside field": {"1", "17", "2", "3", "5", ecc.BN254.ScalarField().String()},
+	}
+	for name, values := range witnesses {
+		if _, err := r.DecodeWitness(values); err == nil {
+			t.Errorf("%s: expected the witness to be rejected", name)
+		}
+	}
+	if _, err := r.PublicSignals([]byte("3,3,0,main.a\n")); err == nil {
+		t.Error("Symbols missing public signals should be rejected")
+	}
+}
@@ -0,0 +1,82 @@
+package circom
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark/frontend"
+)
+
+// Circuit replays a Circom constraint system. Public holds the public
+// signals (wires 1 to NbPublic) and Secret the remaining wires, so the
+// public witness lists the signals in Circom's order.
+type Circuit struct {
+	Public []frontend.Variable `gnark:",public"`
+	Secret []frontend.Variable `gnark:",secret"`
+
+	// R1CS is the constraint system (not part of the witness)
+	R1CS *R1CS `gnark:"-"`
+}
+
+// Circuit returns an unassigned circuit, to compile
+func (r *R1CS) Circuit() *Circuit {
+	return &Circuit{
+		Public: make([]frontend.Variable, r.NbPublic()),
+		Secret: make([]frontend.Variable, r.Wires-1-r.NbPublic()),
+		R1CS:   r,
+	}
+}
+
+// Assign returns the circuit with a full witness assigned
+func (r *R1CS) Assign(witness []*big.Int) (*Circuit, error) {
+	if err := r.checkWitness(witness); err != nil {
+		return nil, err
+	}
+
+	c := r.Circuit()
+	for i := range c.Public {
+		c.Public[i] = witness[1+i]
+	}
+	for i := range c.Secret {
+		c.Secret[i] = witness[1+len(c.Public)+i]
+	}
+	return c, nil
+}
+
+// Define implements frontend.Circuit. Each Circom constraint costs one
+// gnark constraint when A or B is constant and two otherwise.
+func (c *Circuit) Define(api frontend.API) error {
+	if c.R1CS == nil {
+		return fmt.Errorf("circuit has no constraint system")
+	}
+
+	wire := func(i int) frontend.Variable {
+		switch {
+		case i == 0:
+			return 1
+		case i <= len(c.Public):
+			return c.Public[i-1]
+		default:
+			return c.Secret[i-1-len(c.Public)]
+		}
+	}
+	eval := func(lc LinearCombination) frontend.Variable {
+		if len(lc) == 0 {
+			return 0
+		}
+		terms := make([]frontend.Variable, len(lc))
+		for i, t := range lc {
+			terms[i] = api.Mul(t.Coeff, wire(t.Wire))
+		}
+		if len(terms) == 1 {
+			return terms[0]
+		}
+		return api.Add(terms[0], terms[1], terms[2:]...)
+	}
+
+	for _, constraint := range c.R1CS.Constraints {
+		api.AssertIsEqual(api.Mul(eval(constraint.A), eval(constraint.B)), eval(constraint.C))
+	}
+
+	return nil
+}
@@ -0,0 +1,300 @@
+// Package circom reads circuits compiled by Circom (.r1cs constraint
+// systems, .sym symbol tables and .wtns witnesses) and replays them as gnark
+// circuits, so they can be proven with the gnark backends.
+package circom
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+)
+
+// Section types of the .r1cs format
+const (
+	sectionHeader      = 1
+	sectionConstraints = 2
+	sectionWireLabels  = 3
+	sectionGateList    = 4
+	sectionGateApps    = 5
+)
+
+// MaxWires bounds the wires of an imported circuit. The header's wire count
+// sizes the gnark circuit's variables, so it is checked before anything is
+// allocated from it.
+const MaxWires = 1 << 24
+
+// curves lists the curves Circom compiles for that gnark can prove on
+var curves = []ecc.ID{ecc.BN254, ecc.BLS12_381, ecc.BLS12_377}
+
+// Term is a coefficient applied to a wire
+type Term struct {
+	Wire  int
+	Coeff *big.Int
+}
+
+// LinearCombination is a sum of terms
+type LinearCombination []Term
+
+// Constraint is a rank-1 constraint A * B = C
+type Constraint struct {
+	A, B, C LinearCombination
+}
+
+// R1CS is a constraint system read from a .r1cs file. Wire 0 is the
+// constant 1 and is followed by the public outputs, the public inputs, the
+// private inputs and the internal signals.
+type R1CS struct {
+	Curve         ecc.ID
+	Wires         int
+	PublicOutputs int
+	PublicInputs  int
+	PrivateInputs int
+	Constraints   []Constraint
+
+	// Hash is the hex SHA-256 of the file, identifying the circuit
+	Hash string
+}
+
+// NbPublic returns the number of public signals, outputs first
+func (r *R1CS) NbPublic() int {
+	return r.PublicOutputs + r.PublicInputs
+}
+
+// MarshalJSON encodes the constraint system by its hash, so circuits
+// sharing a file share compiled keys
+func (r *R1CS) MarshalJSON() ([]byte, error) {
+	return json.Marshal("sha256:" + r.Hash)
+}
+
+// reader decodes the little-endian fields of the binary formats, failing
+// on truncated input instead of panicking
+type reader struct {
+	data []byte
+	err  error
+}
+
+func (r *reader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.data) {
+		r.err = fmt.Errorf("file is truncated")
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *reader) uint32() uint32 {
+	b := r.next(4)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(b)
+}
+
+func (r *reader) uint64() uint64 {
+	b := r.next(8)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(b)
+}
+
+// element reads an n8-byte little-endian field element
+func (r *reader) element(n8 int) *big.Int {
+	b := r.next(n8)
+	if b == nil {
+		return nil
+	}
+	be := make([]byte, n8)
+	for i := range b {
+		be[n8-1-i] = b[i]
+	}
+	return new(big.Int).SetBytes(be)
+}
+
+// sections splits an iden3 binary file into its sections by type, after
+// checking the magic and version
+func sections(data []byte, magic string, version uint32) (map[uint32][]byte, error) {
+	r := &reader{data: data}
+	if !bytes.Equal(r.next(4), []byte(magic)) {
+		return nil, fmt.Errorf("not a %s file", magic)
+	}
+	if v := r.uint32(); r.err == nil && v != version {
+		return nil, fmt.Errorf("unsupported %s version %d", magic, v)
+	}
+
+	count := r.uint32()
+	result := map[uint32][]byte{}
+	for i := uint32(0); i < count && r.err == nil; i++ {
+		kind := r.uint32()
+		size := r.uint64()
+		if size > uint64(len(r.data)) {
+			return nil, fmt.Errorf("section %d is truncated", kind)
+		}
+		content := r.next(int(size))
+		if _, ok := result[kind]; ok {
+			return nil, fmt.Errorf("duplicate section %d", kind)
+		}
+		result[kind] = content
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return result, nil
+}
+
+// fieldCurve returns the curve whose scalar field has the given modulus
+func fieldCurve(prime *big.Int) (ecc.ID, error) {
+	for _, id := range curves {
+		if id.ScalarField().Cmp(prime) == 0 {
+			return id, nil
+		}
+	}
+	return ecc.UNKNOWN, fmt.Errorf("unsupported field modulus %s (compile for bn128 or bls12381)", prime)
+}
+
+// ParseR1CS reads a Circom .r1cs file. Circuits with custom PLONK gates
+// are rejected, as they have no R1CS form.
+func ParseR1CS(data []byte) (*R1CS, error) {
+	secs, err := sections(data, "r1cs", 1)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := secs[sectionGateList]; ok {
+		return nil, fmt.Errorf("custom gates are not supported")
+	}
+	if _, ok := secs[sectionGateApps]; ok {
+		return nil, fmt.Errorf("custom gates are not supported")
+	}
+
+	header, ok := secs[sectionHeader]
+	if !ok {
+		return nil, fmt.Errorf("header section is missing")
+	}
+	h := &reader{data: header}
+	n8 := int(h.uint32())
+	if h.err == nil && (n8 == 0 || n8 > 64 || n8%8 != 0) {
+		return nil, fmt.Errorf("invalid field size %d", n8)
+	}
+	prime := h.element(n8)
+	wires := h.uint32()
+	outputs := h.uint32()
+	inputs := h.uint32()
+	private := h.uint32()
+	h.uint64() // labels
+	count := h.uint32()
+	if h.err != nil {
+		return nil, fmt.Errorf("header: %w", h.err)
+	}
+
+	curve, err := fieldCurve(prime)
+	if err != nil {
+		return nil, err
+	}
+	if wires > MaxWires {
+		return nil, fmt.Errorf("header declares %d wires, more than the %d supported", wires, MaxWires)
+	}
+	if wires == 0 || uint64(outputs)+uint64(inputs)+uint64(private) >= uint64(wires) {
+		return nil, fmt.Errorf("header declares %d inputs for %d wires", outputs+inputs+private, wires)
+	}
+
+	body, ok := secs[sectionConstraints]
+	if !ok {
+		return nil, fmt.Errorf("constraints section is missing")
+	}
+	// Every constraint holds at least three term counts
+	if uint64(count)*12 > uint64(len(body)) {
+		return nil, fmt.Errorf("constraints section is truncated")
+	}
+
+	r1cs := &R1CS{
+		Curve:         curve,
+		Wires:         int(wires),
+		PublicOutputs: int(outputs),
+		PublicInputs:  int(inputs),
+		PrivateInputs: int(private),
+		Constraints:   make([]Constraint, count),
+	}
+	c := &reader{data: body}
+	for i := range r1cs.Constraints {
+		for _, lc := range []*LinearCombination{&r1cs.Constraints[i].A, &r1cs.Constraints[i].B, &r1cs.Constraints[i].C} {
+			terms := c.uint32()
+			if uint64(terms)*uint64(4+n8) > uint64(len(c.data)) {
+				return nil, fmt.Errorf("constraint %d is truncated", i)
+			}
+			*lc = make(LinearCombination, terms)
+			for j := range *lc {
+				wire := c.uint32()
+				coeff := c.element(n8)
+				if c.err != nil {
+					return nil, fmt.Errorf("constraint %d: %w", i, c.err)
+				}
+				if wire >= wires {
+					return nil, fmt.Errorf("constraint %d refers to wire %d of %d", i, wire, wires)
+				}
+				if coeff.Cmp(prime) >= 0 {
+					return nil, fmt.Errorf("constraint %d has a coefficient outside the field", i)
+				}
+				(*lc)[j] = Term{Wire: int(wire), Coeff: coeff}
+			}
+		}
+	}
+	if len(c.data) != 0 {
+		return nil, fmt.Errorf("constraints section has %d trailing bytes", len(c.data))
+	}
+
+	sum := sha256.Sum256(data)
+	r1cs.Hash = hex.EncodeToString(sum[:])
+	return r1cs, nil
+}
+
+// Check evaluates the constraints on a full witness
+func (r *R1CS) Check(witness []*big.Int) error {
+	if err := r.checkWitness(witness); err != nil {
+		return err
+	}
+
+	modulus := r.Curve.ScalarField()
+	eval := func(lc LinearCombination) *big.Int {
+		sum := new(big.Int)
+		for _, t := range lc {
+			sum.Add(sum, new(big.Int).Mul(t.Coeff, witness[t.Wire]))
+		}
+		return sum.Mod(sum, modulus)
+	}
+	for i, c := range r.Constraints {
+		left := eval(c.A)
+		left.Mul(left, eval(c.B)).Mod(left, modulus)
+		if left.Cmp(eval(c.C)) != 0 {
+			return fmt.Errorf("witness does not satisfy constraint %d", i)
+		}
+	}
+	return nil
+}
+
+// checkWitness checks a full witness has one field element per wire and
+// starts with the constant 1
+func (r *R1CS) checkWitness(witness []*big.Int) error {
+	if len(witness) != r.Wires {
+		return fmt.Errorf("witness has %d values for %d wires", len(witness), r.Wires)
+	}
+	if witness[0] == nil || witness[0].Cmp(big.NewInt(1)) != 0 {
+		return fmt.Errorf("witness must start with the constant 1")
+	}
+	modulus := r.Curve.ScalarField()
+	for i, w := range witness {
+		if w == nil || w.Sign() < 0 || w.Cmp(modulus) >= 0 {
+			return fmt.Errorf("witness value %d is not a field element", i)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package circom
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Section types of the .wtns format
+const (
+	sectionWitnessHeader = 1
+	sectionWitnessValues = 2
+)
+
+// ParseWitness reads a .wtns file as written by the Circom witness
+// generators and snarkjs, for the given constraint system
+func (r *R1CS) ParseWitness(data []byte) ([]*big.Int, error) {
+	secs, err := sections(data, "wtns", 2)
+	if err != nil {
+		return nil, err
+	}
+
+	header, ok := secs[sectionWitnessHeader]
+	if !ok {
+		return nil, fmt.Errorf("header section is missing")
+	}
+	h := &reader{data: header}
+	n8 := int(h.uint32())
+	if h.err == nil && (n8 == 0 || n8 > 64 || n8%8 != 0) {
+		return nil, fmt.Errorf("invalid field size %d", n8)
+	}
+	prime := h.element(n8)
+	count := h.uint32()
+	if h.err != nil {
+		return nil, fmt.Errorf("header: %w", h.err)
+	}
+	if prime.Cmp(r.Curve.ScalarField()) != 0 {
+		return nil, fmt.Errorf("witness is for another field than the circuit")
+	}
+
+	values, ok := secs[sectionWitnessValues]
+	if !ok {
+		return nil, fmt.Errorf("values section is missing")
+	}
+	if uint64(len(values)) != uint64(count)*uint64(n8) {
+		return nil, fmt.Errorf("values section holds %d bytes for %d values", len(values), count)
+	}
+
+	v := &reader{data: values}
+	witness := make([]*big.Int, count)
+	for i := range witness {
+		witness[i] = v.element(n8)
+	}
+	if err := r.checkWitness(witness); err != nil {
+		return nil, err
+	}
+	return witness, nil
+}
+
+// DecodeWitness reads a witness given as JSON values, as written by
+// `snarkjs wtns export json`: decimal strings or exact integers, one per wire
+func (r *R1CS) DecodeWitness(values []interface{}) ([]*big.Int, error) {
+	witness := make([]*big.Int, len(values))
+	for i, v := range values {
+		var ok bool
+		switch v := v.(type) {
+		case string:
+			witness[i], ok = new(big.Int).SetString(v, 10)
+		case json.Number:
+			witness[i], ok = new(big.Int).SetString(v.String(), 10)
+		case float64:
+			if ok = v == float64(int64(v)) && v >= 0 && v <= 1<<53; ok {
+				witness[i] = big.NewInt(int64(v))
+			}
+		}
+		if !ok {
+			return nil, fmt.Errorf("witness value %d must be a decimal string", i)
+		}
+	}
+	if err := r.checkWitness(witness); err != nil {
+		return nil, err
+	}
+	return witness, nil
+}
+
+// PublicSignals names the public signals from a .sym file, outputs first.
+// Names drop the "main." prefix of the top-level component.
+func (r *R1CS) PublicSignals(sym []byte) ([]string, error) {
+	names := make([]string, r.NbPublic())
+	scanner := bufio.NewScanner(bytes.NewReader(sym))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		// label index, witness index (-1 when optimized away), component, name
+		fields := strings.SplitN(text, ",", 4)
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("symbols line %d is malformed", line)
+		}
+		wire, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("symbols line %d is malformed", line)
+		}
+		if wire < 1 || wire > len(names) || names[wire-1] != "" {
+			continue
+		}
+		names[wire-1] = strings.TrimPrefix(fields[3], "main.")
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read symbols: %w", err)
+	}
+
+	for i, name := range names {
+		if name == "" {
+			return nil, fmt.Errorf("symbols do not name public signal %d", i+1)
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,158 @@
+package gnark
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/gabrielrondon/zapiki/internal/models"
+	"github.com/gabrielrondon/zapiki/internal/prover"
+	"github.com/gabrielrondon/zapiki/internal/storage/artifact"
+	"github.com/google/uuid"
+)
+
+// multiplierFiles reads the Circom circuit out <== a * b + c (see the
+// circom package tests), with public signals out and c
+func multiplierFiles(t *testing.T) map[string][]byte {
+	t.Helper()
+	files := map[string][]byte{}
+	for _, ext := range []string{"r1cs", "sym", "wtns"} {
+		data, err := os.ReadFile("circom/testdata/multiplier." + ext)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", ext, err)
+		}
+		files[ext] = data
+	}
+	return files
+}
+
+func TestGroth16Prover_ImportCircom(t *testing.T) {
+	ctx := context.Background()
+	store, err := artifact.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create artifact store: %v", err)
+	}
+	p := NewGroth16Prover()
+	p.SetArtifactStore(store)
+
+	circuit := &models.Circuit{ID: uuid.New(), ProofSystem: models.ProofSystemGroth16}
+	files := multiplierFiles(t)
+	if err := p.ImportCircuit(ctx, circuit, files); err != nil {
+		t.Fatalf("Failed to import circuit: %v", err)
+	}
+
+	var circuitDef circuitDefinition
+	if err := json.Unmarshal(circuit.CircuitDefinition, &circuitDef); err != nil {
+		t.Fatalf("Failed to parse circuit definition: %v", err)
+	}
+	if circuitDef.CircuitType != circomCircuitType || circuitDef.Curve != "bn254" {
+		t.Errorf("Expected a bn254 circom circuit, got %s on %s", circuitDef.CircuitType, circuitDef.Curve)
+	}
+	if names := circuitDef.Circom.PublicSignals; len(names) != 2 || names[0] != "out" || names[1] != "c" {
+		t.Errorf("Expected public signals [out c], got %v", names)
+	}
+
+	setup, err := p.Setup(ctx, circuit)
+	if err != nil {
+		t.Fatalf("Failed to setup: %v", err)
+	}
+
+	// The witness is accepted as a .wtns file and as snarkjs JSON
+	inputs := []map[string]interface{}{
+		{"wtns": base64.StdEncoding.EncodeToString(files["wtns"])},
+		{"witness": []string{"1", "17", "2", "3", "5", "15"}},
+	}
+	for _, input := range inputs {
+		inputJSON, _ := json.Marshal(input)
+		resp, err := p.Generate(ctx, &prover.ProofRequest{
+			Circuit:         circuit,
+			Data:            &models.InputData{Type: models.DataTypeJSON, Value: inputJSON},
+			ProvingKey:      setup.ProvingKey,
+			VerificationKey: setup.VerificationKey,
+		})
+		if err != nil {
+			t.Fatalf("Failed to generate proof: %v", err)
+		}
+		named := resp.NamedPublicInputs
+		if len(named) != 2 || named[0] != (models.NamedPublicInput{Name: "out", Value: "17"}) || named[1] != (models.NamedPublicInput{Name: "c", Value: "2"}) {
+			t.Errorf("Expected public inputs out=17 and c=2, got %v", named)
+		}
+
+		verifyResp, err := p.Verify(ctx, &prover.VerifyRequest{
+			Proof:           resp.Proof,
+			PublicInputs:    resp.PublicInputs,
+			VerificationKey: setup.VerificationKey,
+		})
+		if err != nil {
+			t.Fatalf("Failed to verify: %v", err)
+		}
+		if !verifyResp.Valid {
+			t.Errorf("Expected proof to verify, got: %s", verifyResp.ErrorMessage)
+		}
+	}
+
+	inputJSON, _ := json.Marshal(map[string]interface{}{"witness": []string{"1", "18", "2", "3", "5", "15"}})
+	if _, err := p.Generate(ctx, &prover.ProofRequest{
+		Circuit: circuit,
+		Data:    &models.InputData{Type: models.DataTypeJSON, Value: inputJSON},
+	}); err == nil {
+		t.Error("Witness that does not satisfy the circuit should not prove")
+	}
+
+	if err := p.DeleteImport(ctx, circuit); err != nil {
+		t.Fatalf("Failed to delete import: %v", err)
+	}
+	if _, err := p.Setup(ctx, circuit); err == nil {
+		t.Error("Setup should fail once the r1cs is deleted")
+	}
+}
+
+func TestGroth16Prover_ImportCircom_Rejects(t *testing.T) {
+	ctx := context.Background()
+	store, err := artifact.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create artifact store: %v", err)
+	}
+	p := NewGroth16Prover()
+	p.SetArtifactStore(store)
+
+	valid := multiplierFiles(t)
+	cases := map[string]struct {
+		definition string
+		files      map[string][]byte
+	}{
+		"no r1cs":         {files: map[string][]byte{"sym": valid["sym"]}},
+		"no names":        {files: map[string][]byte{"r1cs": valid["r1cs"]}},
+		"too few names":   {`{"circom": {"public_signals": ["out"]}}`, map[string][]byte{"r1cs": valid["r1cs"]}},
+		"other type":      {`{"circuit_type": "simple"}`, valid},
+		"other curve":     {`{"curve": "bls12_381"}`, valid},
+		"unexpected file": {files: map[string][]byte{"r1cs": valid["r1cs"], "sym": valid["sym"], "zkey": {1}}},
+		"bad witness":     {files: map[string][]byte{"r1cs": valid["r1cs"], "sym": valid["sym"], "wtns": valid["r1cs"]}},
+	}
+	for name, tc := range cases {
+		circuit := &models.Circuit{ID: uuid.New(), CircuitDefinition: json.RawMessage(tc.definition)}
+		if err := p.ImportCircuit(ctx, circuit, tc.files); !errors.Is(err, prover.ErrInvalidCircuit) {
+			t.Errorf("%s: expected ErrInvalidCircuit, got %v", name, err)
+		}
+	}
+
+	// Names may be listed instead of uploading the symbols
+	circuit := &models.Circuit{ID: uuid.New(), CircuitDefinition: json.RawMessage(`{"circom": {"public_signals": ["result", "offset"]}}`)}
+	if err := p.ImportCircuit(ctx, circuit, map[string][]byte{"r1cs": valid["r1cs"]}); err != nil {
+		t.Errorf("Expected listed names to be accepted: %v", err)
+	}
+
+	// Circom circuits cannot be described without their files
+	for _, definition := range []string{
+		`{"circuit_type": "circom"}`,
+		`{"circuit_type": "simple", "circom": {"r1cs_url": "file:///tmp/other.r1cs"}}`,
+	} {
+		circuit = &models.Circuit{CircuitDefinition: json.RawMessage(definition)}
+		if err := p.ValidateCircuit(ctx, circuit); !errors.Is(err, prover.ErrInvalidCircuit) {
+			t.Errorf("%s: expected ErrInvalidCircuit, got %v", definition, err)
+		}
+	}
+}
@@ -1,7 +1,17 @@
 package gnark
 
 import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
 	"github.com/consensys/gnark/frontend"
+	"github.com/gabrielrondon/zapiki/internal/models"
+	"github.com/gabrielrondon/zapiki/internal/prover"
+	"github.com/gabrielrondon/zapiki/internal/prover/snark/gnark/circuits"
+	"github.com/gabrielrondon/zapiki/internal/prover/snark/gnark/dsl"
 )
 
 // Circuit represents a generic gnark circuit interface
@@ -11,6 +21,47 @@ type Circuit interface {
 	GetPrivateInputs() []frontend.Variable
 }
 
+// circuitDefinition is the circuit_definition JSON stored on a circuit
+type circuitDefinition struct {
+	CircuitType string                 `json:"circuit_type"`
+	Params      map[string]interface{} `json:"params"`
+	// Curve selects the curve for setup and proving, e.g. "bls12_381"
+	Curve string `json:"curve,omitempty"`
+	// Circuit describes a "custom" circuit (see dsl.Definition)
+	Circuit json.RawMessage `json:"circuit,omitempty"`
+	// Circom describes a circuit imported from Circom (see importCircom)
+	Circom *circomImport `json:"circom,omitempty"`
+}
+
+// customCircuitType is the circuit type of circuits described in JSON
+const customCircuitType = "custom"
+
+// allParams returns the params with a custom circuit's description added,
+// for circuitParams
+func (d *circuitDefinition) allParams() map[string]interface{} {
+	if len(d.Circuit) == 0 {
+		return d.Params
+	}
+	params := map[string]interface{}{}
+	for k, v := range d.Params {
+		params[k] = v
+	}
+	params["circuit"] = d.Circuit
+	return params
+}
+
+// BuiltinCircuits lists the circuit types served by the API and templates.
+// Workers warm up their keys at start so the first proof skips setup.
+var BuiltinCircuits = []string{
+	"simple",
+	"range_proof",
+	"age_verification",
+	"aml_age_verification",
+	"aml_sanctions_check",
+	"aml_residency_proof",
+	"aml_income_verification",
+}
+
 // SimpleCircuit is a basic example circuit: x * y = z
 type SimpleCircuit struct {
 	X frontend.Variable `gnark:",secret"`
@@ -26,12 +77,31 @@ func (circuit *SimpleCircuit) Define(api frontend.API) error {
 	return nil
 }
 
+// Bounds of the circuit params
+const (
+	// DefaultRangeBits is the bit width of range proof differences
+	DefaultRangeBits = 64
+	// MaxRangeBits keeps differences below every supported scalar field
+	MaxRangeBits = 248
+
+	// DefaultMaxIncome bounds income verification inputs
+	DefaultMaxIncome = 10000000
+	// MaxIncomeBound keeps the bound an exact JSON number
+	MaxIncomeBound = 1 << 53
+
+	// MaxCountrySetSize bounds the allowed countries of a residency proof
+	MaxCountrySetSize = 64
+)
+
 // RangeProofCircuit proves a value is within a range [min, max]
 type RangeProofCircuit struct {
 	Value    frontend.Variable `gnark:",secret"`
 	Min      frontend.Variable `gnark:",public"`
 	Max      frontend.Variable `gnark:",public"`
 	InRange  frontend.Variable `gnark:",public"`
+
+	// Bits is the width of value - min and max - value (not part of the witness)
+	Bits int `gnark:"-"`
 }
 
 // Define implements the range proof logic
@@ -47,8 +117,12 @@ func (circuit *RangeProofCircuit) Define(api frontend.API) error {
 	api.AssertIsEqual(circuit.InRange, 1)
 
 	// Ensure constraints are satisfied
-	api.ToBinary(geMin, 64)
-	api.ToBinary(leMax, 64)
+	bits := circuit.Bits
+	if bits == 0 {
+		bits = DefaultRangeBits
+	}
+	api.ToBinary(geMin, bits)
+	api.ToBinary(leMax, bits)
 
 	return nil
 }
@@ -78,40 +152,62 @@ func (circuit *AgeVerificationCircuit) Define(api frontend.API) error {
 type HashPreimageCircuit struct {
 	Preimage frontend.Variable `gnark:",secret"`
 	Hash     frontend.Variable `gnark:",public"`
+
+	// HashFunc selects MiMC or Poseidon2 (not part of the witness)
+	HashFunc circuits.HashFunc `gnark:"-"`
 }
 
 // Define implements hash preimage proof
 func (circuit *HashPreimageCircuit) Define(api frontend.API) error {
-	// Use Poseidon hash (SNARK-friendly)
-	hash := api.Mul(circuit.Preimage, circuit.Preimage) // Simplified for demo
-	api.AssertIsEqual(hash, circuit.Hash)
+	h, err := circuits.NewHasher(api, circuit.HashFunc)
+	if err != nil {
+		return err
+	}
+
+	h.Write(circuit.Preimage)
+	api.AssertIsEqual(h.Sum(), circuit.Hash)
 	return nil
 }
 
 // MerkleProofCircuit proves membership in a Merkle tree
+// (see circuits.MerkleRoot for the tree layout)
 type MerkleProofCircuit struct {
 	Leaf       frontend.Variable   `gnark:",secret"`
 	Root       frontend.Variable   `gnark:",public"`
 	Path       []frontend.Variable `gnark:",secret"`
 	Directions []frontend.Variable `gnark:",secret"` // 0 = left, 1 = right
+
+	// HashFunc selects MiMC or Poseidon2 (not part of the witness)
+	HashFunc circuits.HashFunc `gnark:"-"`
+}
+
+// NewMerkleProofCircuit returns a Merkle proof circuit for a tree of the given depth
+func NewMerkleProofCircuit(depth int, fn circuits.HashFunc) *MerkleProofCircuit {
+	return &MerkleProofCircuit{
+		Path:       make([]frontend.Variable, depth),
+		Directions: make([]frontend.Variable, depth),
+		HashFunc:   fn,
+	}
 }
 
 // Define implements Merkle proof verification
 func (circuit *MerkleProofCircuit) Define(api frontend.API) error {
-	currentHash := circuit.Leaf
+	if len(circuit.Directions) != len(circuit.Path) {
+		return fmt.Errorf("got %d directions for a path of %d", len(circuit.Directions), len(circuit.Path))
+	}
 
-	// Traverse up the tree
-	for i := 0; i < len(circuit.Path); i++ {
-		// Simplified hash: if left child, hash(current, path[i]), else hash(path[i], current)
-		left := api.Select(circuit.Directions[i], circuit.Path[i], currentHash)
-		right := api.Select(circuit.Directions[i], currentHash, circuit.Path[i])
+	h, err := circuits.NewHasher(api, circuit.HashFunc)
+	if err != nil {
+		return err
+	}
 
-		// Compute parent hash (simplified)
-		currentHash = api.Add(api.Mul(left, 2), right)
+	for _, d := range circuit.Directions {
+		api.AssertIsBoolean(d)
 	}
 
 	// Final hash should equal root
-	api.AssertIsEqual(currentHash, circuit.Root)
+	root := circuits.MerkleRoot(api, h, circuit.Leaf, circuit.Directions, circuit.Path)
+	api.AssertIsEqual(root, circuit.Root)
 	return nil
 }
 
@@ -146,42 +242,84 @@ func (circuit *AMLAgeVerificationCircuit) Define(api frontend.API) error {
 }
 
 // AMLSanctionsCheckCircuit proves user is NOT on sanctions list
+// by showing the user's hashed identifier falls strictly between two
+// adjacent leaves of the sorted sanctions Merkle tree. The identifier is
+// bound to the public IdentifierCommitment, H(identifier, salt).
 type AMLSanctionsCheckCircuit struct {
 	// Public inputs
-	SanctionsListRoot frontend.Variable `gnark:",public"`
-	CurrentTimestamp  frontend.Variable `gnark:",public"`
+	SanctionsListRoot    frontend.Variable `gnark:",public"`
+	CurrentTimestamp     frontend.Variable `gnark:",public"`
+	IdentifierCommitment frontend.Variable `gnark:",public"`
 
 	// Private inputs
-	UserIdentifier frontend.Variable `gnark:"userIdentifier"`
+	UserIdentifier frontend.Variable   `gnark:"userIdentifier"`
+	IdentifierSalt frontend.Variable   `gnark:"identifierSalt"`
+	LowLeaf        frontend.Variable   `gnark:"lowLeaf"`
+	HighLeaf       frontend.Variable   `gnark:"highLeaf"`
+	LowIndex       frontend.Variable   `gnark:"lowIndex"`
+	LowPath        []frontend.Variable `gnark:"lowPath"`
+	HighPath       []frontend.Variable `gnark:"highPath"`
+
+	// Hash is the tree's hash function (not part of the witness)
+	Hash circuits.HashFunc `gnark:"-"`
 }
 
-// Define implements sanctions check (simplified)
+// NewAMLSanctionsCheckCircuit returns a sanctions circuit for a tree of the given depth
+func NewAMLSanctionsCheckCircuit(depth int, fn circuits.HashFunc) *AMLSanctionsCheckCircuit {
+	return &AMLSanctionsCheckCircuit{
+		LowPath:  make([]frontend.Variable, depth),
+		HighPath: make([]frontend.Variable, depth),
+		Hash:     fn,
+	}
+}
+
+// Define implements the sorted Merkle tree non-membership check
 func (circuit *AMLSanctionsCheckCircuit) Define(api frontend.API) error {
-	// Include inputs in constraints
-	_ = circuit.SanctionsListRoot
+	// The timestamp is bound to the proof as a public input
 	_ = circuit.CurrentTimestamp
-	_ = circuit.UserIdentifier
 
-	// In production: implement Merkle non-membership proof
-	// For now: basic constraint that user identifier is known
-	return nil
+	if err := circuits.AssertIdentifierCommitment(api, circuit.Hash, circuit.IdentifierCommitment, circuit.UserIdentifier, circuit.IdentifierSalt); err != nil {
+		return err
+	}
+
+	return circuits.AssertSortedNonMembership(api, circuit.Hash,
+		circuit.SanctionsListRoot, circuit.UserIdentifier,
+		circuit.LowLeaf, circuit.HighLeaf, circuit.LowIndex,
+		circuit.LowPath, circuit.HighPath,
+	)
 }
 
-// AMLResidencyProofCircuit proves residency in allowed country
+// AMLResidencyProofCircuit proves residency in one of a set of allowed countries
 type AMLResidencyProofCircuit struct {
 	// Public inputs
-	AllowedCountryCode frontend.Variable `gnark:",public"`
-	CurrentTimestamp   frontend.Variable `gnark:",public"`
+	AllowedCountryCodes []frontend.Variable `gnark:",public"`
+	CurrentTimestamp    frontend.Variable   `gnark:",public"`
 
 	// Private inputs
 	UserCountryCode frontend.Variable `gnark:"userCountryCode"`
 	AddressHash     frontend.Variable `gnark:"addressHash"`
 }
 
+// NewAMLResidencyProofCircuit returns a residency circuit for setSize allowed countries
+func NewAMLResidencyProofCircuit(setSize int) *AMLResidencyProofCircuit {
+	return &AMLResidencyProofCircuit{
+		AllowedCountryCodes: make([]frontend.Variable, setSize),
+	}
+}
+
 // Define implements residency proof
 func (circuit *AMLResidencyProofCircuit) Define(api frontend.API) error {
-	// Constraint: userCountryCode == allowedCountryCode
-	api.AssertIsEqual(circuit.UserCountryCode, circuit.AllowedCountryCode)
+	if len(circuit.AllowedCountryCodes) == 0 {
+		return fmt.Errorf("residency proofs need at least one allowed country")
+	}
+
+	// Constraint: userCountryCode is one of the allowed codes, i.e. the
+	// product of the differences is zero
+	product := frontend.Variable(1)
+	for _, code := range circuit.AllowedCountryCodes {
+		product = api.Mul(product, api.Sub(circuit.UserCountryCode, code))
+	}
+	api.AssertIsEqual(product, 0)
 
 	// Include address hash (commitment)
 	_ = circuit.AddressHash
@@ -199,6 +337,9 @@ type AMLIncomeVerificationCircuit struct {
 	// Private inputs
 	ActualIncome     frontend.Variable `gnark:"actualIncome"`
 	IncomeSourceHash frontend.Variable `gnark:"incomeSourceHash"`
+
+	// MaxIncome bounds the actual income (not part of the witness)
+	MaxIncome int64 `gnark:"-"`
 }
 
 // Define implements income verification
@@ -207,7 +348,11 @@ func (circuit *AMLIncomeVerificationCircuit) Define(api frontend.API) error {
 	api.AssertIsLessOrEqual(circuit.MinimumIncome, circuit.ActualIncome)
 
 	// Sanity check: income within reasonable bounds
-	api.AssertIsLessOrEqual(circuit.ActualIncome, 10000000)
+	maxIncome := circuit.MaxIncome
+	if maxIncome == 0 {
+		maxIncome = DefaultMaxIncome
+	}
+	api.AssertIsLessOrEqual(circuit.ActualIncome, maxIncome)
 
 	// Include source hash (commitment)
 	_ = circuit.IncomeSourceHash
@@ -216,31 +361,281 @@ func (circuit *AMLIncomeVerificationCircuit) Define(api frontend.API) error {
 	return nil
 }
 
-// GetCircuitByName returns a circuit instance by name
-func GetCircuitByName(name string) (frontend.Circuit, error) {
-	switch name {
-	case "simple":
-		return &SimpleCircuit{}, nil
-	case "range_proof":
-		return &RangeProofCircuit{}, nil
-	case "age_verification":
-		return &AgeVerificationCircuit{}, nil
-	case "hash_preimage":
-		return &HashPreimageCircuit{}, nil
-	case "merkle_proof":
-		return &MerkleProofCircuit{}, nil
-
-	// AML/KYC Compliance circuits
-	case "aml_age_verification":
-		return &AMLAgeVerificationCircuit{}, nil
-	case "aml_sanctions_check":
-		return &AMLSanctionsCheckCircuit{}, nil
-	case "aml_residency_proof":
-		return &AMLResidencyProofCircuit{}, nil
-	case "aml_income_verification":
-		return &AMLIncomeVerificationCircuit{}, nil
+// NewCircuit returns a circuit instance shaped by params, as normalized by
+// circuitParams
+func NewCircuit(name string, params map[string]interface{}) (frontend.Circuit, error) {
+	spec, err := LookupCircuit(name)
+	if err != nil {
+		return nil, err
+	}
+	return spec.Circuit(params)
+}
 
+// circuitParams normalizes the params of a circuit type so equal circuits
+// share one key cache entry. Params missing from the circuit definition are
+// taken from the witness when it determines the circuit shape.
+//
+// hash_preimage takes "hash" ("mimc" or "poseidon2"). merkle_proof and
+// aml_sanctions_check also take "depth" (Merkle tree depth, default
+// circuits.DefaultTreeDepth). custom takes "circuit", the circuit
+// description, and circom "r1cs", the constraint system loaded by
+// loadCircom.
+func circuitParams(circuitType string, params, inputData map[string]interface{}) (map[string]interface{}, error) {
+	spec, err := LookupCircuit(circuitType)
+	if err != nil {
+		return nil, err
+	}
+	if spec.Normalize == nil {
+		return nil, nil
+	}
+	return spec.Normalize(params, inputData)
+}
+
+// reportedParams returns the params of a circuit instance that describe
+// its shape, leaving out descriptions such as a custom circuit's
+func reportedParams(circuitType string, params map[string]interface{}) map[string]interface{} {
+	spec, err := LookupCircuit(circuitType)
+	if err != nil {
+		return nil
+	}
+
+	reported := map[string]interface{}{}
+	for _, p := range spec.Params {
+		if v, ok := params[p.Name]; ok && p.Type != "object" {
+			reported[p.Name] = v
+		}
+	}
+	return reported
+}
+
+// resolvedDefinition returns a circuit definition with its params replaced
+// by those setup resolved, so that proofs against the stored keys compile
+// the same circuit whatever their witness. Other fields are kept as given.
+func resolvedDefinition(definition json.RawMessage, circuitType string, params map[string]interface{}) (json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(definition, &fields); err != nil {
+		return nil, fmt.Errorf("failed to parse circuit definition: %w", err)
+	}
+
+	reported := reportedParams(circuitType, params)
+	if len(reported) == 0 {
+		return definition, nil
+	}
+	encoded, err := json.Marshal(reported)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode params: %w", err)
+	}
+	fields["params"] = encoded
+	return json.Marshal(fields)
+}
+
+// checkParams rejects params a circuit type does not accept
+func checkParams(spec *CircuitSpec, params map[string]interface{}) error {
+	var unknown []string
+	for name := range params {
+		accepted := false
+		for _, p := range spec.Params {
+			accepted = accepted || p.Name == name
+		}
+		if !accepted {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("%s circuits do not take params: %s", spec.Name, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// mergeParams overlays the definition's params on those read from the witness
+func mergeParams(params, fromInput map[string]interface{}) map[string]interface{} {
+	merged := map[string]interface{}{}
+	for k, v := range fromInput {
+		merged[k] = v
+	}
+	for k, v := range params {
+		merged[k] = v
+	}
+	return merged
+}
+
+// hashNormalizer resolves the params of circuits that only select a hash
+func hashNormalizer(params, inputData map[string]interface{}) (map[string]interface{}, error) {
+	fromInput := map[string]interface{}{}
+	if fn, ok := inputData["hash"].(string); ok {
+		fromInput["hash"] = fn
+	}
+
+	fn, err := hashParam(mergeParams(params, fromInput))
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"hash": string(fn)}, nil
+}
+
+// intNormalizer resolves a single integer param
+func intNormalizer(name string, def, min, max int) func(params, inputData map[string]interface{}) (map[string]interface{}, error) {
+	return func(params, _ map[string]interface{}) (map[string]interface{}, error) {
+		n, err := intParam(params, name, def, min, max)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{name: n}, nil
+	}
+}
+
+// countrySetNormalizer resolves the set size of residency proofs, which a
+// witness gives by the length of allowed_country_codes
+func countrySetNormalizer(params, inputData map[string]interface{}) (map[string]interface{}, error) {
+	fromInput := map[string]interface{}{}
+	if codes, ok := inputData["allowed_country_codes"].([]interface{}); ok {
+		fromInput["set_size"] = len(codes)
+	}
+
+	n, err := intParam(mergeParams(params, fromInput), "set_size", 1, 1, MaxCountrySetSize)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"set_size": n}, nil
+}
+
+// treeNormalizer resolves the params of circuits over a Merkle tree, whose
+// depth a witness gives by the length of its pathField
+func treeNormalizer(pathField string) func(params, inputData map[string]interface{}) (map[string]interface{}, error) {
+	return func(params, inputData map[string]interface{}) (map[string]interface{}, error) {
+		fromInput := map[string]interface{}{}
+		if path, ok := inputData[pathField].([]interface{}); ok {
+			fromInput["depth"] = len(path)
+		}
+		if fn, ok := inputData["hash"].(string); ok {
+			fromInput["hash"] = fn
+		}
+
+		depth, fn, err := treeParams(mergeParams(params, fromInput))
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"depth": depth, "hash": string(fn)}, nil
+	}
+}
+
+// customDefinition reads the description of a custom circuit
+func customDefinition(params map[string]interface{}) (*dsl.Definition, error) {
+	switch circuit := params["circuit"].(type) {
+	case *dsl.Definition:
+		return circuit, nil
+	case nil:
+		return nil, fmt.Errorf("custom circuits need a circuit description")
 	default:
-		return &SimpleCircuit{}, nil
+		data, err := json.Marshal(circuit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read circuit description: %w", err)
+		}
+		return dsl.Parse(data)
+	}
+}
+
+// customWitness assigns the inputs of a custom circuit
+func customWitness(params map[string]interface{}, in *Inputs) (frontend.Circuit, error) {
+	def, err := customDefinition(params)
+	if err != nil {
+		return nil, err
+	}
+	c, err := def.Assign(in.Values(), in.Element)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", prover.ErrInvalidWitness, err)
+	}
+	return c, nil
+}
+
+// validateCircuit checks a circuit's definition, including the description
+// of custom circuits
+func validateCircuit(circuit *models.Circuit) error {
+	var circuitDef circuitDefinition
+	if err := json.Unmarshal(circuit.CircuitDefinition, &circuitDef); err != nil {
+		return fmt.Errorf("%w: %v", prover.ErrInvalidCircuit, err)
+	}
+	spec, err := lookupCircuitFor(circuit.ProofSystem, circuitDef.CircuitType)
+	if err != nil {
+		return err
+	}
+	if err := checkParams(spec, circuitDef.Params); err != nil {
+		return fmt.Errorf("%w: %v", prover.ErrInvalidCircuit, err)
+	}
+	if len(circuitDef.Circuit) > 0 && circuitDef.CircuitType != customCircuitType {
+		return fmt.Errorf("%w: a circuit description needs circuit_type %q", prover.ErrInvalidCircuit, customCircuitType)
+	}
+	// Imports are described by ImportCircuit, from the uploaded files
+	if circuitDef.Circom != nil {
+		return fmt.Errorf("%w: circom circuits are created by uploading their r1cs file", prover.ErrInvalidCircuit)
+	}
+	if _, err := circuitParams(circuitDef.CircuitType, circuitDef.allParams(), nil); err != nil {
+		return fmt.Errorf("%w: %v", prover.ErrInvalidCircuit, err)
+	}
+	if circuitDef.Curve != "" {
+		if _, err := ParseCurve(circuitDef.Curve); err != nil {
+			return fmt.Errorf("%w: %v", prover.ErrInvalidCircuit, err)
+		}
+	}
+	return nil
+}
+
+// hashParam reads the hash function param
+func hashParam(params map[string]interface{}) (circuits.HashFunc, error) {
+	name, _ := params["hash"].(string)
+	return circuits.ParseHashFunc(name)
+}
+
+// treeParams reads the params of circuits over a Merkle tree
+func treeParams(params map[string]interface{}) (int, circuits.HashFunc, error) {
+	depth, err := intParam(params, "depth", circuits.DefaultTreeDepth, 1, circuits.MaxTreeDepth)
+	if err != nil {
+		return 0, "", err
+	}
+
+	fn, err := hashParam(params)
+	if err != nil {
+		return 0, "", err
+	}
+
+	return depth, fn, nil
+}
+
+// intParam reads an integer param between min and max, defaulting to def
+func intParam(params map[string]interface{}, name string, def, min, max int) (int, error) {
+	v, ok := params[name]
+	if !ok {
+		return def, nil
+	}
+
+	var n int64
+	switch v := v.(type) {
+	case int:
+		n, ok = int64(v), true
+	case int64:
+		n, ok = v, true
+	case float64:
+		n, ok = int64(v), v == math.Trunc(v) && math.Abs(v) <= 1<<53
+	case json.Number:
+		var err error
+		n, err = v.Int64()
+		ok = err == nil
+	default:
+		ok = false
+	}
+	if !ok || n < int64(min) || n > int64(max) {
+		return 0, fmt.Errorf("%s must be an integer between %d and %d", name, min, max)
+	}
+	return int(n), nil
+}
+
+// GetCircuitByName returns an instance of a registered circuit type with
+// its default params
+func GetCircuitByName(name string) (frontend.Circuit, error) {
+	params, err := circuitParams(name, nil, nil)
+	if err != nil {
+		return nil, err
 	}
+	return NewCircuit(name, params)
 }
@@ -0,0 +1,475 @@
+package gnark
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/gabrielrondon/zapiki/internal/models"
+	"github.com/gabrielrondon/zapiki/internal/prover"
+	"github.com/gabrielrondon/zapiki/internal/prover/snark/gnark/circuits"
+	"github.com/gabrielrondon/zapiki/internal/prover/snark/gnark/dsl"
+)
+
+func sanctionsProofRequest(t *testing.T, fn circuits.HashFunc, listed []string, user string) *prover.ProofRequest {
+	t.Helper()
+
+	var values []*big.Int
+	for _, name := range listed {
+		values = append(values, circuits.HashIdentifier([]byte(name)))
+	}
+
+	tree, err := circuits.NewSortedMerkleTree(ecc.BN254, fn, 4, values)
+	if err != nil {
+		t.Fatalf("Failed to build sanctions tree: %v", err)
+	}
+
+	proof, err := tree.ProveNonMembership(circuits.HashIdentifier([]byte(user)))
+	if err != nil {
+		t.Fatalf("Failed to prove non-membership: %v", err)
+	}
+	salt := big.NewInt(7)
+	commitment, err := circuits.CommitIdentifier(ecc.BN254, fn, proof.Value, salt)
+	if err != nil {
+		t.Fatalf("Failed to commit identifier: %v", err)
+	}
+
+	inputs := proof.Inputs()
+	inputs["current_timestamp"] = 1700000000
+	inputs["identifier_salt"] = salt.String()
+	inputs["identifier_commitment"] = commitment.String()
+	inputJSON, _ := json.Marshal(inputs)
+	circuitDefJSON, _ := json.Marshal(map[string]interface{}{
+		"circuit_type": "aml_sanctions_check",
+		"params":       map[string]interface{}{"depth": 4, "hash": string(fn)},
+	})
+
+	return &prover.ProofRequest{
+		Circuit: &models.Circuit{CircuitDefinition: circuitDefJSON},
+		Data: &models.InputData{
+			Type:  models.DataTypeJSON,
+			Value: inputJSON,
+		},
+	}
+}
+
+func TestGroth16Prover_SanctionsCheck(t *testing.T) {
+	p := NewGroth16Prover()
+	ctx := context.Background()
+
+	for _, fn := range []circuits.HashFunc{circuits.HashMiMC, circuits.HashPoseidon2} {
+		t.Run(string(fn), func(t *testing.T) {
+			req := sanctionsProofRequest(t, fn, []string{"alice", "bob", "carol"}, "mallory")
+
+			resp, err := p.Generate(ctx, req)
+			if err != nil {
+				t.Fatalf("Failed to generate proof: %v", err)
+			}
+
+			verifyResp, err := p.Verify(ctx, &prover.VerifyRequest{
+				Proof:           resp.Proof,
+				VerificationKey: resp.VerificationKey,
+				PublicInputs:    resp.PublicInputs,
+			})
+			if err != nil {
+				t.Fatalf("Failed to verify proof: %v", err)
+			}
+			if !verifyResp.Valid {
+				t.Errorf("Expected proof to be valid, got: %v", verifyResp.ErrorMessage)
+			}
+		})
+	}
+}
+
+func TestGroth16Prover_SanctionsCheckRejectsWrongDepth(t *testing.T) {
+	p := NewGroth16Prover()
+
+	req := sanctionsProofRequest(t, circuits.HashMiMC, []string{"alice"}, "mallory")
+	req.Circuit.CircuitDefinition, _ = json.Marshal(map[string]interface{}{
+		"circuit_type": "aml_sanctions_check",
+		"params":       map[string]interface{}{"depth": 5},
+	})
+
+	if _, err := p.Generate(context.Background(), req); err == nil {
+		t.Fatal("Expected a witness of the wrong depth to be rejected")
+	}
+}
+
+func TestCircuitParams_Sanctions(t *testing.T) {
+	params, err := circuitParams("aml_sanctions_check", nil, map[string]interface{}{
+		"low_path": []interface{}{"1", "2", "3"},
+		"hash":     "poseidon2",
+	})
+	if err != nil {
+		t.Fatalf("Failed to resolve params: %v", err)
+	}
+	if params["depth"] != 3 || params["hash"] != "poseidon2" {
+		t.Errorf("Expected depth 3 and poseidon2, got %v", params)
+	}
+
+	params, err = circuitParams("aml_sanctions_check", nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to resolve default params: %v", err)
+	}
+	if params["depth"] != circuits.DefaultTreeDepth || params["hash"] != string(circuits.DefaultHash) {
+		t.Errorf("Expected default params, got %v", params)
+	}
+
+	if _, err := circuitParams("aml_sanctions_check", map[string]interface{}{"hash": "sha256"}, nil); err == nil {
+		t.Error("Expected an unsupported hash to be rejected")
+	}
+	if _, err := circuitParams("aml_sanctions_check", map[string]interface{}{"depth": 64}, nil); err == nil {
+		t.Error("Expected an oversized depth to be rejected")
+	}
+}
+
+func hashCircuitRequest(t *testing.T, circuitType string, params, inputs map[string]interface{}) *prover.ProofRequest {
+	t.Helper()
+
+	inputJSON, _ := json.Marshal(inputs)
+	circuitDefJSON, _ := json.Marshal(map[string]interface{}{
+		"circuit_type": circuitType,
+		"params":       params,
+	})
+
+	return &prover.ProofRequest{
+		Circuit: &models.Circuit{CircuitDefinition: circuitDefJSON},
+		Data: &models.InputData{
+			Type:  models.DataTypeJSON,
+			Value: inputJSON,
+		},
+	}
+}
+
+func TestHashCircuits_ProveAndVerify(t *testing.T) {
+	ctx := context.Background()
+
+	for _, fn := range []circuits.HashFunc{circuits.HashMiMC, circuits.HashPoseidon2} {
+		digest, err := circuits.NativeHash(ecc.BN254, fn, big.NewInt(42))
+		if err != nil {
+			t.Fatalf("Failed to hash preimage: %v", err)
+		}
+
+		leaves := []*big.Int{big.NewInt(10), big.NewInt(20), big.NewInt(30)}
+		tree, err := circuits.NewMerkleTree(ecc.BN254, fn, 3, leaves, big.NewInt(0))
+		if err != nil {
+			t.Fatalf("Failed to build tree: %v", err)
+		}
+		directions, path, err := tree.Path(2)
+		if err != nil {
+			t.Fatalf("Failed to get path: %v", err)
+		}
+		pathStrings := make([]string, len(path))
+		for i, p := range path {
+			pathStrings[i] = p.String()
+		}
+
+		requests := map[string]*prover.ProofRequest{
+			"hash_preimage": hashCircuitRequest(t, "hash_preimage",
+				map[string]interface{}{"hash": string(fn)},
+				map[string]interface{}{"preimage": 42, "hash_value": digest.String()}),
+			"merkle_proof": hashCircuitRequest(t, "merkle_proof",
+				map[string]interface{}{"hash": string(fn)},
+				map[string]interface{}{"leaf": 30, "root": tree.Root().String(), "path": pathStrings, "directions": directions}),
+		}
+
+		for name, req := range requests {
+			for _, p := range []prover.ProofSystem{NewGroth16Prover(), NewPLONKProver()} {
+				t.Run(fmt.Sprintf("%s/%s/%s", name, fn, p.Name()), func(t *testing.T) {
+					resp, err := p.Generate(ctx, req)
+					if err != nil {
+						t.Fatalf("Failed to generate proof: %v", err)
+					}
+
+					verifyResp, err := p.Verify(ctx, &prover.VerifyRequest{
+						Proof:           resp.Proof,
+						VerificationKey: resp.VerificationKey,
+						PublicInputs:    resp.PublicInputs,
+					})
+					if err != nil {
+						t.Fatalf("Failed to verify proof: %v", err)
+					}
+					if !verifyResp.Valid {
+						t.Errorf("Expected proof to be valid, got: %v", verifyResp.ErrorMessage)
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestHashPreimage_RejectsWrongDigest(t *testing.T) {
+	req := hashCircuitRequest(t, "hash_preimage", nil,
+		map[string]interface{}{"preimage": 42, "hash_value": "1764"}) // 42*42
+
+	if _, err := NewGroth16Prover().Generate(context.Background(), req); err == nil {
+		t.Fatal("Expected a non-MiMC digest to be rejected")
+	}
+}
+
+const customThresholdCircuit = `{
+	"circuit_type": "custom",
+	"circuit": {
+		"public": ["threshold"],
+		"secret": ["balance"],
+		"constraints": [
+			{"op": "range", "args": ["balance"], "bits": 64},
+			{"op": "gte", "args": ["balance", "threshold"]}
+		]
+	}
+}`
+
+func customCircuitRequest(definition, inputs string) *prover.ProofRequest {
+	return &prover.ProofRequest{
+		Circuit: &models.Circuit{CircuitDefinition: json.RawMessage(definition)},
+		Data: &models.InputData{
+			Type:  models.DataTypeJSON,
+			Value: json.RawMessage(inputs),
+		},
+	}
+}
+
+func TestCustomCircuit_ProveAndVerify(t *testing.T) {
+	ctx := context.Background()
+
+	for _, p := range []prover.ProofSystem{NewGroth16Prover(), NewPLONKProver()} {
+		t.Run(string(p.Name()), func(t *testing.T) {
+			resp, err := p.Generate(ctx, customCircuitRequest(customThresholdCircuit, `{"balance": 1500, "threshold": 1000}`))
+			if err != nil {
+				t.Fatalf("Failed to generate proof: %v", err)
+			}
+			if resp.Metadata["circuit_type"] != "custom" {
+				t.Errorf("Expected circuit_type custom, got %v", resp.Metadata["circuit_type"])
+			}
+
+			verifyResp, err := p.Verify(ctx, &prover.VerifyRequest{
+				Proof:           resp.Proof,
+				VerificationKey: resp.VerificationKey,
+				PublicInputs:    resp.PublicInputs,
+			})
+			if err != nil {
+				t.Fatalf("Failed to verify proof: %v", err)
+			}
+			if !verifyResp.Valid {
+				t.Errorf("Expected proof to be valid, got: %v", verifyResp.ErrorMessage)
+			}
+
+			if _, err := p.Generate(ctx, customCircuitRequest(customThresholdCircuit, `{"balance": 900, "threshold": 1000}`)); err == nil {
+				t.Error("Expected a balance below the threshold to fail")
+			}
+		})
+	}
+}
+
+func TestCustomWitness_DecodesStrictly(t *testing.T) {
+	var circuitDef struct {
+		Circuit map[string]interface{} `json:"circuit"`
+	}
+	if err := json.Unmarshal([]byte(customThresholdCircuit), &circuitDef); err != nil {
+		t.Fatalf("Failed to parse definition: %v", err)
+	}
+	params := map[string]interface{}{"circuit": circuitDef.Circuit}
+	assign := func(threshold string) (*big.Int, error) {
+		values, err := decodeInputs(json.RawMessage(`{"balance": 1500, "threshold": ` + threshold + `}`))
+		if err != nil {
+			t.Fatalf("Failed to decode inputs: %v", err)
+		}
+		c, err := customWitness(params, NewInputs(values, ecc.BN254.ScalarField()))
+		if err != nil {
+			return nil, err
+		}
+		return c.(*dsl.Circuit).Public[0].(*big.Int), nil
+	}
+
+	for _, threshold := range []string{`1000`, `"1000"`, `"0x3e8"`, `"A+g="`} {
+		got, err := assign(threshold)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", threshold, err)
+			continue
+		}
+		if got.Int64() != 1000 {
+			t.Errorf("%s: got %s, want 1000", threshold, got)
+		}
+	}
+
+	modulus := ecc.BN254.ScalarField().String()
+	for _, threshold := range []string{`-1`, `"-1"`, `"` + modulus + `"`, `"deadbeef"`, `1.5`} {
+		if _, err := assign(threshold); !errors.Is(err, prover.ErrInvalidWitness) {
+			t.Errorf("%s: expected an invalid witness, got %v", threshold, err)
+		}
+	}
+}
+
+func TestCustomCircuit_KeysFollowTheDescription(t *testing.T) {
+	params := func(definition string) map[string]interface{} {
+		var circuitDef circuitDefinition
+		if err := json.Unmarshal([]byte(definition), &circuitDef); err != nil {
+			t.Fatalf("Failed to parse definition: %v", err)
+		}
+		params, err := circuitParams(circuitDef.CircuitType, circuitDef.allParams(), nil)
+		if err != nil {
+			t.Fatalf("Failed to resolve params: %v", err)
+		}
+		return params
+	}
+
+	p := NewGroth16Prover()
+	reformatted := strings.Join(strings.Fields(customThresholdCircuit), " ")
+	other := strings.Replace(customThresholdCircuit, `"bits": 64`, `"bits": 32`, 1)
+
+	key := p.cacheKey(ecc.BN254, "custom", params(customThresholdCircuit)).String()
+	if same := p.cacheKey(ecc.BN254, "custom", params(reformatted)).String(); same != key {
+		t.Errorf("Expected equal descriptions to share keys, got %s and %s", key, same)
+	}
+	if different := p.cacheKey(ecc.BN254, "custom", params(other)).String(); different == key {
+		t.Error("Expected different descriptions to use different keys")
+	}
+}
+
+func TestValidateCircuit_Custom(t *testing.T) {
+	ctx := context.Background()
+	p := NewGroth16Prover()
+
+	valid := &models.Circuit{CircuitDefinition: json.RawMessage(customThresholdCircuit)}
+	if err := p.ValidateCircuit(ctx, valid); err != nil {
+		t.Errorf("Expected the circuit to be valid, got: %v", err)
+	}
+
+	invalid := map[string]string{
+		"unknown op":     strings.Replace(customThresholdCircuit, `"gte"`, `"geq"`, 1),
+		"no description": `{"circuit_type": "custom"}`,
+		"wrong type":     strings.Replace(customThresholdCircuit, `"custom"`, `"simple"`, 1),
+	}
+	for name, definition := range invalid {
+		err := p.ValidateCircuit(ctx, &models.Circuit{CircuitDefinition: json.RawMessage(definition)})
+		if !errors.Is(err, prover.ErrInvalidCircuit) {
+			t.Errorf("%s: expected ErrInvalidCircuit, got %v", name, err)
+		}
+	}
+}
+
+func TestParameterizedCircuits_ProveAndVerify(t *testing.T) {
+	ctx := context.Background()
+
+	requests := map[string]*prover.ProofRequest{
+		"range_proof/bits=16": hashCircuitRequest(t, "range_proof",
+			map[string]interface{}{"bits": 16},
+			map[string]interface{}{"value": 500, "min": 100, "max": 1000, "in_range": 1}),
+		"aml_residency_proof/set_size=3": hashCircuitRequest(t, "aml_residency_proof",
+			map[string]interface{}{"set_size": 3},
+			map[string]interface{}{
+				"allowed_country_codes": []interface{}{76, 250, 276},
+				"current_timestamp":     1700000000,
+				"user_country_code":     250,
+				"address_hash":          "0x1234",
+			}),
+		"aml_income_verification/max_income=1000000000": hashCircuitRequest(t, "aml_income_verification",
+			map[string]interface{}{"max_income": 1000000000},
+			map[string]interface{}{
+				"minimum_income":     50000,
+				"current_timestamp":  1700000000,
+				"actual_income":      250000000,
+				"income_source_hash": "0xabcd",
+			}),
+	}
+
+	for name, req := range requests {
+		for _, p := range []prover.ProofSystem{NewGroth16Prover(), NewPLONKProver()} {
+			t.Run(fmt.Sprintf("%s/%s", name, p.Name()), func(t *testing.T) {
+				resp, err := p.Generate(ctx, req)
+				if err != nil {
+					t.Fatalf("Failed to generate proof: %v", err)
+				}
+
+				verifyResp, err := p.Verify(ctx, &prover.VerifyRequest{
+					Proof:           resp.Proof,
+					VerificationKey: resp.VerificationKey,
+					PublicInputs:    resp.PublicInputs,
+				})
+				if err != nil {
+					t.Fatalf("Failed to verify proof: %v", err)
+				}
+				if !verifyResp.Valid {
+					t.Errorf("Expected proof to be valid, got: %v", verifyResp.ErrorMessage)
+				}
+			})
+		}
+	}
+}
+
+func TestParameterizedCircuits_ParamsShapeTheCircuit(t *testing.T) {
+	p := NewGroth16Prover()
+	ctx := context.Background()
+
+	// A bound below the income fails with the bound raised by params
+	incomeInputs := map[string]interface{}{
+		"minimum_income":     50000,
+		"current_timestamp":  1700000000,
+		"actual_income":      20000000,
+		"income_source_hash": "1",
+	}
+	if _, err := p.Generate(ctx, hashCircuitRequest(t, "aml_income_verification", nil, incomeInputs)); err == nil {
+		t.Error("Expected an income above the default bound to be rejected")
+	}
+
+	// A value whose distance to max needs more than bits bits fails
+	rangeInputs := map[string]interface{}{"value": 500, "min": 100, "max": 1000, "in_range": 1}
+	if _, err := p.Generate(ctx, hashCircuitRequest(t, "range_proof", map[string]interface{}{"bits": 8}, rangeInputs)); err == nil {
+		t.Error("Expected a difference wider than 8 bits to be rejected")
+	}
+
+	// Each param set gets its own keys, reported in the setup metadata
+	keyIDs := map[interface{}]bool{}
+	for _, bits := range []int{16, 32} {
+		circuitDefJSON, _ := json.Marshal(map[string]interface{}{
+			"circuit_type": "range_proof",
+			"params":       map[string]interface{}{"bits": bits},
+		})
+		result, err := p.Setup(ctx, &models.Circuit{CircuitDefinition: circuitDefJSON})
+		if err != nil {
+			t.Fatalf("Failed to run setup: %v", err)
+		}
+		params, _ := result.Metadata["params"].(map[string]interface{})
+		if params["bits"] != bits {
+			t.Errorf("Expected params bits=%d in the setup metadata, got %v", bits, result.Metadata["params"])
+		}
+		keyIDs[result.Metadata["key_id"]] = true
+	}
+	if len(keyIDs) != 2 {
+		t.Errorf("Expected a key per param set, got %v", keyIDs)
+	}
+}
+
+func TestValidateCircuit_Params(t *testing.T) {
+	tests := map[string]struct {
+		definition string
+		valid      bool
+	}{
+		"range bits":         {`{"circuit_type": "range_proof", "params": {"bits": 32}}`, true},
+		"range bits too big": {`{"circuit_type": "range_proof", "params": {"bits": 300}}`, false},
+		"fractional bits":    {`{"circuit_type": "range_proof", "params": {"bits": 3.5}}`, false},
+		"set size":           {`{"circuit_type": "aml_residency_proof", "params": {"set_size": 5}}`, true},
+		"empty set":          {`{"circuit_type": "aml_residency_proof", "params": {"set_size": 0}}`, false},
+		"max income":         {`{"circuit_type": "aml_income_verification", "params": {"max_income": 5000}}`, true},
+		"unknown param":      {`{"circuit_type": "simple", "params": {"depth": 4}}`, false},
+		"misspelled param":   {`{"circuit_type": "merkle_proof", "params": {"dept": 4}}`, false},
+	}
+
+	for name, tc := range tests {
+		err := validateCircuit(&models.Circuit{
+			ProofSystem:       models.ProofSystemGroth16,
+			CircuitDefinition: json.RawMessage(tc.definition),
+		})
+		if tc.valid && err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+		}
+		if !tc.valid && !errors.Is(err, prover.ErrInvalidCircuit) {
+			t.Errorf("%s: expected ErrInvalidCircuit, got %v", name, err)
+		}
+	}
+}
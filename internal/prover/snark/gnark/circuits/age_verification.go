@@ -44,37 +44,55 @@ func (circuit *AgeVerificationCircuit) Define(api frontend.API) error {
 
 // SanctionsCheckCircuit proves user is NOT on a sanctions list
 // Use case: AML compliance - prove not on OFAC/UN list without revealing identity
+//
+// The list is a sorted Merkle tree (see SortedMerkleTree). The proof shows the
+// user's hashed identifier lies strictly between two adjacent leaves, so it
+// cannot be one of them. The identifier is bound to the public
+// IdentifierCommitment (see CommitIdentifier), so the proof is about one user.
 type SanctionsCheckCircuit struct {
 	// Public inputs
 	SanctionsListRoot frontend.Variable `gnark:",public"` // Merkle root of sanctions list
 	CurrentTimestamp frontend.Variable `gnark:",public"`   // Proof timestamp
+	IdentifierCommitment frontend.Variable `gnark:",public"` // H(userID, identifierSalt)
 
 	// Private inputs
 	UserID frontend.Variable `gnark:"userID"` // User's identifier (hash)
+	IdentifierSalt frontend.Variable `gnark:"identifierSalt"` // Hides userID in the commitment
 
-	// Merkle proof that userID is NOT in the tree
-	// (Simplified - in production, use Merkle tree exclusion proof)
-	ProofPath []frontend.Variable `gnark:"proofPath"`
-	ProofIndices []frontend.Variable `gnark:"proofIndices"`
+	// Neighbouring leaves around userID and their Merkle paths
+	LowLeaf  frontend.Variable   `gnark:"lowLeaf"`
+	HighLeaf frontend.Variable   `gnark:"highLeaf"`
+	LowIndex frontend.Variable   `gnark:"lowIndex"`
+	LowPath  []frontend.Variable `gnark:"lowPath"`
+	HighPath []frontend.Variable `gnark:"highPath"`
+
+	// Hash is the tree's hash function (not part of the witness)
+	Hash HashFunc `gnark:"-"`
+}
+
+// NewSanctionsCheckCircuit returns a circuit for a tree of the given depth
+func NewSanctionsCheckCircuit(depth int, fn HashFunc) *SanctionsCheckCircuit {
+	return &SanctionsCheckCircuit{
+		LowPath:  make([]frontend.Variable, depth),
+		HighPath: make([]frontend.Variable, depth),
+		Hash:     fn,
+	}
 }
 
 // Define implements the sanctions check constraints
 func (circuit *SanctionsCheckCircuit) Define(api frontend.API) error {
-	// In a production implementation, we would:
-	// 1. Compute Merkle root from userID + proofPath + proofIndices
-	// 2. Assert it does NOT match sanctionsListRoot (exclusion proof)
-	// 3. Or use a non-membership proof structure
-
-	// Simplified constraint for MVP:
-	// We assert that userID is known to the prover (included in witness)
-	_ = circuit.UserID
-	_ = circuit.SanctionsListRoot
+	// The timestamp is bound to the proof as a public input
 	_ = circuit.CurrentTimestamp
 
-	// TODO: Implement full Merkle non-membership proof
-	// For now, this serves as a placeholder
+	if err := AssertIdentifierCommitment(api, circuit.Hash, circuit.IdentifierCommitment, circuit.UserID, circuit.IdentifierSalt); err != nil {
+		return err
+	}
 
-	return nil
+	return AssertSortedNonMembership(api, circuit.Hash,
+		circuit.SanctionsListRoot, circuit.UserID,
+		circuit.LowLeaf, circuit.HighLeaf, circuit.LowIndex,
+		circuit.LowPath, circuit.HighPath,
+	)
 }
 
 // ResidencyProofCircuit proves user resides in allowed country without revealing address
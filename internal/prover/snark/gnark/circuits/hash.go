@@ -0,0 +1,151 @@
+package circuits
+
+import (
+	"fmt"
+	stdhash "hash"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/hash"
+	"github.com/consensys/gnark/frontend"
+	gnarkhash "github.com/consensys/gnark/std/hash"
+	"github.com/consensys/gnark/std/hash/mimc"
+	"github.com/consensys/gnark/std/permutation/poseidon2"
+
+	poseidon2bls12377 "github.com/consensys/gnark-crypto/ecc/bls12-377/fr/poseidon2"
+	poseidon2bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381/fr/poseidon2"
+	poseidon2bn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr/poseidon2"
+	poseidon2bw6761 "github.com/consensys/gnark-crypto/ecc/bw6-761/fr/poseidon2"
+
+	// Register the native MiMC implementations used by NativeHasher
+	_ "github.com/consensys/gnark-crypto/ecc/bls12-377/fr/mimc"
+	_ "github.com/consensys/gnark-crypto/ecc/bls12-381/fr/mimc"
+	_ "github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+	_ "github.com/consensys/gnark-crypto/ecc/bw6-761/fr/mimc"
+)
+
+// HashFunc names a SNARK-friendly hash function
+type HashFunc string
+
+const (
+	HashMiMC      HashFunc = "mimc"
+	HashPoseidon2 HashFunc = "poseidon2"
+)
+
+// DefaultHash is used when a circuit does not select a hash function
+const DefaultHash = HashMiMC
+
+// ParseHashFunc validates a hash function name, defaulting to DefaultHash
+func ParseHashFunc(name string) (HashFunc, error) {
+	switch HashFunc(name) {
+	case "":
+		return DefaultHash, nil
+	case HashMiMC, HashPoseidon2:
+		return HashFunc(name), nil
+	default:
+		return "", fmt.Errorf("unsupported hash function: %s", name)
+	}
+}
+
+// NewHasher returns the in-circuit hasher for fn
+func NewHasher(api frontend.API, fn HashFunc) (gnarkhash.FieldHasher, error) {
+	switch fn {
+	case HashMiMC, "":
+		return mimc.New(api)
+	case HashPoseidon2:
+		return newPoseidon2Hasher(api)
+	default:
+		return nil, fmt.Errorf("unsupported hash function: %s", fn)
+	}
+}
+
+// newPoseidon2Hasher mirrors the native Poseidon2 Merkle-Damgard hasher of
+// each curve: width 2, the curve's default round counts and a zero IV.
+// gnark's own constructor only ships defaults for BLS12-377.
+func newPoseidon2Hasher(api frontend.API) (gnarkhash.FieldHasher, error) {
+	var fullRounds, partialRounds int
+	field := api.Compiler().Field()
+	switch {
+	case field.Cmp(ecc.BN254.ScalarField()) == 0:
+		params := poseidon2bn254.GetDefaultParameters()
+		fullRounds, partialRounds = params.NbFullRounds, params.NbPartialRounds
+	case field.Cmp(ecc.BLS12_381.ScalarField()) == 0:
+		params := poseidon2bls12381.GetDefaultParameters()
+		fullRounds, partialRounds = params.NbFullRounds, params.NbPartialRounds
+	case field.Cmp(ecc.BLS12_377.ScalarField()) == 0:
+		params := poseidon2bls12377.GetDefaultParameters()
+		fullRounds, partialRounds = params.NbFullRounds, params.NbPartialRounds
+	case field.Cmp(ecc.BW6_761.ScalarField()) == 0:
+		params := poseidon2bw6761.GetDefaultParameters()
+		fullRounds, partialRounds = params.NbFullRounds, params.NbPartialRounds
+	default:
+		return nil, fmt.Errorf("poseidon2 is not supported over field %s", field)
+	}
+
+	perm, err := poseidon2.NewPoseidon2FromParameters(api, 2, fullRounds, partialRounds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create poseidon2 permutation: %w", err)
+	}
+
+	return gnarkhash.NewMerkleDamgardHasher(api, perm, 0), nil
+}
+
+// NativeHasher returns the out-of-circuit counterpart of NewHasher for curve
+func NativeHasher(curve ecc.ID, fn HashFunc) (stdhash.Hash, error) {
+	var h hash.Hash
+	switch {
+	case fn == HashMiMC || fn == "":
+		switch curve {
+		case ecc.BN254:
+			h = hash.MIMC_BN254
+		case ecc.BLS12_381:
+			h = hash.MIMC_BLS12_381
+		case ecc.BLS12_377:
+			h = hash.MIMC_BLS12_377
+		case ecc.BW6_761:
+			h = hash.MIMC_BW6_761
+		default:
+			return nil, fmt.Errorf("unsupported curve for %s: %s", fn, curve)
+		}
+	case fn == HashPoseidon2:
+		switch curve {
+		case ecc.BN254:
+			h = hash.POSEIDON2_BN254
+		case ecc.BLS12_381:
+			h = hash.POSEIDON2_BLS12_381
+		case ecc.BLS12_377:
+			h = hash.POSEIDON2_BLS12_377
+		case ecc.BW6_761:
+			h = hash.POSEIDON2_BW6_761
+		default:
+			return nil, fmt.Errorf("unsupported curve for %s: %s", fn, curve)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported hash function: %s", fn)
+	}
+
+	return h.New(), nil
+}
+
+// NativeHash hashes field elements outside the circuit, matching an
+// in-circuit hasher fed the same values with Write
+func NativeHash(curve ecc.ID, fn HashFunc, values ...*big.Int) (*big.Int, error) {
+	h, err := NativeHasher(curve, fn)
+	if err != nil {
+		return nil, err
+	}
+
+	modulus := curve.ScalarField()
+	block := make([]byte, h.BlockSize())
+	for _, v := range values {
+		if v.Sign() < 0 || v.Cmp(modulus) >= 0 {
+			return nil, fmt.Errorf("value %s is not a %s field element", v, curve)
+		}
+		v.FillBytes(block)
+		if _, err := h.Write(block); err != nil {
+			return nil, fmt.Errorf("failed to hash value: %w", err)
+		}
+	}
+
+	return new(big.Int).SetBytes(h.Sum(nil)), nil
+}
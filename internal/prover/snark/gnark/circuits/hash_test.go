@@ -0,0 +1,132 @@
+package circuits
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/test"
+)
+
+type hashCircuit struct {
+	A, B   frontend.Variable
+	Digest frontend.Variable `gnark:",public"`
+	Hash   HashFunc          `gnark:"-"`
+}
+
+func (c *hashCircuit) Define(api frontend.API) error {
+	h, err := NewHasher(api, c.Hash)
+	if err != nil {
+		return err
+	}
+	h.Write(c.A, c.B)
+	api.AssertIsEqual(h.Sum(), c.Digest)
+	return nil
+}
+
+type preimageCircuit struct {
+	Preimage frontend.Variable
+	Digest   frontend.Variable `gnark:",public"`
+	Hash     HashFunc          `gnark:"-"`
+}
+
+func (c *preimageCircuit) Define(api frontend.API) error {
+	h, err := NewHasher(api, c.Hash)
+	if err != nil {
+		return err
+	}
+	h.Write(c.Preimage)
+	api.AssertIsEqual(h.Sum(), c.Digest)
+	return nil
+}
+
+func bigString(t *testing.T, s string) *big.Int {
+	t.Helper()
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		t.Fatalf("Invalid test vector %q", s)
+	}
+	return v
+}
+
+// BN254 test vectors shared by the native helpers and the circuits
+var hashVectors = []struct {
+	hash   HashFunc
+	single string // H(42)
+	pair   string // H(1, 2)
+}{
+	{
+		hash:   HashMiMC,
+		single: "9859286970797740035380527431348382675909558438535884267813507963157263542611",
+		pair:   "3603165980089455451357404308887091638931600328866147122487556210589417494548",
+	},
+	{
+		hash:   HashPoseidon2,
+		single: "13565356373417538528006943412468816847646309577018621962105802276859759061675",
+		pair:   "4443443265955166080716935670700081889283598504231460571509928329665379862364",
+	},
+}
+
+func TestHashVectors(t *testing.T) {
+	for _, v := range hashVectors {
+		t.Run(string(v.hash), func(t *testing.T) {
+			single, err := NativeHash(ecc.BN254, v.hash, big.NewInt(42))
+			if err != nil {
+				t.Fatalf("Failed to hash natively: %v", err)
+			}
+			if single.Cmp(bigString(t, v.single)) != 0 {
+				t.Errorf("Native H(42) = %s, expected %s", single, v.single)
+			}
+
+			pair, err := NativeHash(ecc.BN254, v.hash, big.NewInt(1), big.NewInt(2))
+			if err != nil {
+				t.Fatalf("Failed to hash natively: %v", err)
+			}
+			if pair.Cmp(bigString(t, v.pair)) != 0 {
+				t.Errorf("Native H(1, 2) = %s, expected %s", pair, v.pair)
+			}
+
+			err = test.IsSolved(&preimageCircuit{Hash: v.hash},
+				&preimageCircuit{Preimage: 42, Digest: v.single, Hash: v.hash}, ecc.BN254.ScalarField())
+			if err != nil {
+				t.Errorf("Circuit H(42) does not match the vector: %v", err)
+			}
+
+			err = test.IsSolved(&hashCircuit{Hash: v.hash},
+				&hashCircuit{A: 1, B: 2, Digest: v.pair, Hash: v.hash}, ecc.BN254.ScalarField())
+			if err != nil {
+				t.Errorf("Circuit H(1, 2) does not match the vector: %v", err)
+			}
+		})
+	}
+}
+
+func TestNativeHash_MatchesCircuit(t *testing.T) {
+	for _, curve := range []ecc.ID{ecc.BN254, ecc.BLS12_381, ecc.BLS12_377, ecc.BW6_761} {
+		for _, fn := range []HashFunc{HashMiMC, HashPoseidon2} {
+			expected, err := NativeHash(curve, fn, big.NewInt(1), big.NewInt(2))
+			if err != nil {
+				t.Fatalf("Failed to hash natively on %s with %s: %v", curve, fn, err)
+			}
+
+			circuit := &hashCircuit{Hash: fn}
+			witness := &hashCircuit{A: 1, B: 2, Digest: expected, Hash: fn}
+			if err := test.IsSolved(circuit, witness, curve.ScalarField()); err != nil {
+				t.Errorf("Native %s hash on %s does not match the circuit: %v", fn, curve, err)
+			}
+		}
+	}
+}
+
+func TestNativeHash_RejectsOutOfFieldValues(t *testing.T) {
+	if _, err := NativeHash(ecc.BN254, HashMiMC, ecc.BN254.ScalarField()); err == nil {
+		t.Error("Expected the field modulus to be rejected")
+	}
+	if _, err := NativeHash(ecc.BN254, HashMiMC, big.NewInt(-1)); err == nil {
+		t.Error("Expected a negative value to be rejected")
+	}
+	if _, err := ParseHashFunc("sha256"); err == nil {
+		t.Error("Expected an unsupported hash function to be rejected")
+	}
+}
@@ -0,0 +1,165 @@
+package circuits
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	gnarkhash "github.com/consensys/gnark/std/hash"
+)
+
+// Merkle trees hash each leaf value as H(v) and each node as H(left, right).
+// A path lists sibling hashes from the leaf level up; direction bit i is 1
+// when the node at level i is a right child.
+
+// MerkleRoot recomputes the root from a leaf value, its direction bits
+// and the sibling path
+func MerkleRoot(api frontend.API, h gnarkhash.FieldHasher, leaf frontend.Variable, directions, path []frontend.Variable) frontend.Variable {
+	h.Reset()
+	h.Write(leaf)
+	node := h.Sum()
+
+	for i := range path {
+		left := api.Select(directions[i], path[i], node)
+		right := api.Select(directions[i], node, path[i])
+
+		h.Reset()
+		h.Write(left, right)
+		node = h.Sum()
+	}
+
+	return node
+}
+
+// NativeMerkleRoot is the out-of-circuit counterpart of MerkleRoot
+func NativeMerkleRoot(curve ecc.ID, fn HashFunc, leaf *big.Int, directions []uint, path []*big.Int) (*big.Int, error) {
+	if len(directions) != len(path) {
+		return nil, fmt.Errorf("got %d directions for a path of %d", len(directions), len(path))
+	}
+
+	node, err := NativeHash(curve, fn, leaf)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, sibling := range path {
+		switch directions[i] {
+		case 0:
+			node, err = NativeHash(curve, fn, node, sibling)
+		case 1:
+			node, err = NativeHash(curve, fn, sibling, node)
+		default:
+			return nil, fmt.Errorf("direction %d is not a bit", directions[i])
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return node, nil
+}
+
+// MerkleTree is a fixed-depth Merkle tree over leaf values in the given
+// order. Leaves past the end hold the padding value.
+type MerkleTree struct {
+	curve ecc.ID
+	hash  HashFunc
+	depth int
+
+	// levels[0] holds leaf hashes; nodes past a level's end are padding
+	levels  [][]*big.Int
+	padding []*big.Int
+}
+
+// NewMerkleTree builds a tree of the given depth over leaves
+func NewMerkleTree(curve ecc.ID, fn HashFunc, depth int, leaves []*big.Int, padding *big.Int) (*MerkleTree, error) {
+	if depth < 1 || depth > MaxTreeDepth {
+		return nil, fmt.Errorf("tree depth must be between 1 and %d", MaxTreeDepth)
+	}
+	if uint64(len(leaves)) > uint64(1)<<depth {
+		return nil, fmt.Errorf("%d leaves do not fit a tree of depth %d", len(leaves), depth)
+	}
+
+	level := make([]*big.Int, len(leaves))
+	for i, leaf := range leaves {
+		h, err := NativeHash(curve, fn, leaf)
+		if err != nil {
+			return nil, err
+		}
+		level[i] = h
+	}
+
+	pad, err := NativeHash(curve, fn, padding)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &MerkleTree{
+		curve:   curve,
+		hash:    fn,
+		depth:   depth,
+		levels:  [][]*big.Int{level},
+		padding: []*big.Int{pad},
+	}
+
+	for d := 0; d < depth; d++ {
+		next := make([]*big.Int, (len(level)+1)/2)
+		for i := range next {
+			right := pad
+			if 2*i+1 < len(level) {
+				right = level[2*i+1]
+			}
+			if next[i], err = NativeHash(curve, fn, level[2*i], right); err != nil {
+				return nil, err
+			}
+		}
+
+		if pad, err = NativeHash(curve, fn, pad, pad); err != nil {
+			return nil, err
+		}
+
+		level = next
+		t.levels = append(t.levels, level)
+		t.padding = append(t.padding, pad)
+	}
+
+	// An empty tree is all padding
+	if len(level) == 0 {
+		t.levels[depth] = []*big.Int{pad}
+	}
+
+	return t, nil
+}
+
+// Root returns the tree root
+func (t *MerkleTree) Root() *big.Int {
+	return t.levels[t.depth][0]
+}
+
+// Depth returns the tree depth
+func (t *MerkleTree) Depth() int {
+	return t.depth
+}
+
+// Path returns the direction bits and sibling path of the leaf at index
+func (t *MerkleTree) Path(index uint64) ([]uint, []*big.Int, error) {
+	if index >= uint64(1)<<t.depth {
+		return nil, nil, fmt.Errorf("leaf index %d is outside a tree of depth %d", index, t.depth)
+	}
+
+	directions := make([]uint, t.depth)
+	path := make([]*big.Int, t.depth)
+	for d := 0; d < t.depth; d++ {
+		directions[d] = uint(index & 1)
+		sibling := index ^ 1
+		if sibling < uint64(len(t.levels[d])) {
+			path[d] = t.levels[d][sibling]
+		} else {
+			path[d] = t.padding[d]
+		}
+		index >>= 1
+	}
+
+	return directions, path, nil
+}
@@ -0,0 +1,107 @@
+package circuits
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/test"
+)
+
+type merkleCircuit struct {
+	Leaf       frontend.Variable
+	Root       frontend.Variable `gnark:",public"`
+	Directions []frontend.Variable
+	Path       []frontend.Variable
+	Hash       HashFunc `gnark:"-"`
+}
+
+func (c *merkleCircuit) Define(api frontend.API) error {
+	h, err := NewHasher(api, c.Hash)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(MerkleRoot(api, h, c.Leaf, c.Directions, c.Path), c.Root)
+	return nil
+}
+
+// Roots of the depth-2 tree over leaves 1, 2, 3, 4 on BN254
+var merkleVectors = map[HashFunc]string{
+	HashMiMC:      "223255651461969734180070294521551881357546470453458988024347136547934681396",
+	HashPoseidon2: "3758117083283989318841750093572975936158818817265511569204439359360027903410",
+}
+
+func TestMerkleTreeVectors(t *testing.T) {
+	leaves := []*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3), big.NewInt(4)}
+
+	for fn, expected := range merkleVectors {
+		t.Run(string(fn), func(t *testing.T) {
+			tree, err := NewMerkleTree(ecc.BN254, fn, 2, leaves, big.NewInt(0))
+			if err != nil {
+				t.Fatalf("Failed to build tree: %v", err)
+			}
+			if tree.Root().Cmp(bigString(t, expected)) != 0 {
+				t.Fatalf("Root = %s, expected %s", tree.Root(), expected)
+			}
+
+			for i, leaf := range leaves {
+				directions, path, err := tree.Path(uint64(i))
+				if err != nil {
+					t.Fatalf("Failed to get path: %v", err)
+				}
+
+				root, err := NativeMerkleRoot(ecc.BN254, fn, leaf, directions, path)
+				if err != nil {
+					t.Fatalf("Failed to recompute root: %v", err)
+				}
+				if root.Cmp(tree.Root()) != 0 {
+					t.Errorf("Leaf %d: native root %s does not match the tree", i, root)
+				}
+
+				circuit := &merkleCircuit{
+					Directions: make([]frontend.Variable, 2),
+					Path:       make([]frontend.Variable, 2),
+					Hash:       fn,
+				}
+				witness := &merkleCircuit{
+					Leaf:       leaf,
+					Root:       expected,
+					Directions: []frontend.Variable{directions[0], directions[1]},
+					Path:       []frontend.Variable{path[0], path[1]},
+					Hash:       fn,
+				}
+				if err := test.IsSolved(circuit, witness, ecc.BN254.ScalarField()); err != nil {
+					t.Errorf("Leaf %d: circuit rejected the native path: %v", i, err)
+				}
+			}
+		})
+	}
+}
+
+func TestMerkleTree_Padding(t *testing.T) {
+	tree, err := NewMerkleTree(ecc.BN254, HashMiMC, 3, []*big.Int{big.NewInt(7)}, big.NewInt(0))
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+
+	// The padded leaf at the far end of the tree still proves against the root
+	directions, path, err := tree.Path(7)
+	if err != nil {
+		t.Fatalf("Failed to get path: %v", err)
+	}
+	root, err := NativeMerkleRoot(ecc.BN254, HashMiMC, big.NewInt(0), directions, path)
+	if err != nil {
+		t.Fatalf("Failed to recompute root: %v", err)
+	}
+	if root.Cmp(tree.Root()) != 0 {
+		t.Error("Expected a padding leaf to prove against the root")
+	}
+
+	if _, _, err := tree.Path(8); err == nil {
+		t.Error("Expected an index outside the tree to be rejected")
+	}
+	if _, err := NewMerkleTree(ecc.BN254, HashMiMC, 1, []*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3)}, big.NewInt(0)); err == nil {
+		t.Error("Expected too many leaves to be rejected")
+	}
+}
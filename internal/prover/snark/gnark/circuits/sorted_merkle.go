@@ -0,0 +1,209 @@
+package circuits
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+)
+
+// IdentifierBits bounds hashed identifiers so they fit every supported
+// scalar field and leave room for the sentinel leaves
+const IdentifierBits = 248
+
+const (
+	// DefaultTreeDepth fits about a million list entries
+	DefaultTreeDepth = 20
+
+	// MaxTreeDepth bounds the circuit size a caller can request
+	MaxTreeDepth = 32
+)
+
+var (
+	// MinSentinel is the first leaf of every sorted tree
+	MinSentinel = big.NewInt(0)
+
+	// MaxSentinel is the last leaf of every sorted tree and pads unused leaves
+	MaxSentinel = new(big.Int).Lsh(big.NewInt(1), IdentifierBits)
+)
+
+// HashIdentifier maps an identifier to the value stored in sorted trees:
+// SHA-256 truncated to IdentifierBits
+func HashIdentifier(data []byte) *big.Int {
+	sum := sha256.Sum256(data)
+	return new(big.Int).SetBytes(sum[:IdentifierBits/8])
+}
+
+// NewIdentifierSalt returns a random salt for CommitIdentifier
+func NewIdentifierSalt() (*big.Int, error) {
+	salt, err := rand.Int(rand.Reader, MaxSentinel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	return salt, nil
+}
+
+// CommitIdentifier returns H(identifier, salt), the public commitment that
+// binds a non-membership proof to a hashed identifier without revealing it.
+// Whoever knows the identifier and salt can check a proof is about them.
+func CommitIdentifier(curve ecc.ID, fn HashFunc, identifier, salt *big.Int) (*big.Int, error) {
+	return NativeHash(curve, fn, identifier, salt)
+}
+
+// AssertIdentifierCommitment constrains commitment to be
+// CommitIdentifier(identifier, salt)
+func AssertIdentifierCommitment(api frontend.API, fn HashFunc, commitment, identifier, salt frontend.Variable) error {
+	h, err := NewHasher(api, fn)
+	if err != nil {
+		return err
+	}
+
+	h.Write(identifier, salt)
+	api.AssertIsEqual(h.Sum(), commitment)
+	return nil
+}
+
+// AssertSortedNonMembership constrains value to lie strictly between two
+// adjacent leaves (low at lowIndex, high at lowIndex+1) of the sorted
+// Merkle tree with the given root. The tree depth is len(lowPath).
+func AssertSortedNonMembership(api frontend.API, fn HashFunc, root, value, low, high, lowIndex frontend.Variable, lowPath, highPath []frontend.Variable) error {
+	depth := len(lowPath)
+	if depth == 0 || depth > MaxTreeDepth || len(highPath) != depth {
+		return fmt.Errorf("invalid Merkle path lengths: %d and %d", len(lowPath), len(highPath))
+	}
+
+	h, err := NewHasher(api, fn)
+	if err != nil {
+		return err
+	}
+
+	// Bound value so value+1 cannot wrap around the field
+	api.ToBinary(value, IdentifierBits)
+
+	// low < value < high
+	api.AssertIsLessOrEqual(api.Add(low, 1), value)
+	api.AssertIsLessOrEqual(api.Add(value, 1), high)
+
+	// The leaves are adjacent: ToBinary also rejects lowIndex+1 == 2^depth
+	lowBits := api.ToBinary(lowIndex, depth)
+	highBits := api.ToBinary(api.Add(lowIndex, 1), depth)
+
+	api.AssertIsEqual(MerkleRoot(api, h, low, lowBits, lowPath), root)
+	api.AssertIsEqual(MerkleRoot(api, h, high, highBits, highPath), root)
+
+	return nil
+}
+
+// SortedMerkleTree is the native counterpart of AssertSortedNonMembership.
+// Leaves are the sorted, de-duplicated values between MinSentinel and
+// MaxSentinel; unused leaves are padded with MaxSentinel.
+type SortedMerkleTree struct {
+	*MerkleTree
+	leaves []*big.Int
+}
+
+// NewSortedMerkleTree builds a sorted tree of the given depth over values
+func NewSortedMerkleTree(curve ecc.ID, fn HashFunc, depth int, values []*big.Int) (*SortedMerkleTree, error) {
+	sorted := make([]*big.Int, 0, len(values))
+	for _, v := range values {
+		if v.Cmp(MinSentinel) <= 0 || v.Cmp(MaxSentinel) >= 0 {
+			return nil, fmt.Errorf("value %s is outside the identifier range", v)
+		}
+		sorted = append(sorted, v)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Cmp(sorted[j]) < 0 })
+
+	leaves := []*big.Int{MinSentinel}
+	for _, v := range sorted {
+		if v.Cmp(leaves[len(leaves)-1]) != 0 {
+			leaves = append(leaves, v)
+		}
+	}
+	leaves = append(leaves, MaxSentinel)
+
+	tree, err := NewMerkleTree(curve, fn, depth, leaves, MaxSentinel)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SortedMerkleTree{MerkleTree: tree, leaves: leaves}, nil
+}
+
+// Len returns the number of list values in the tree, excluding sentinels
+func (t *SortedMerkleTree) Len() int {
+	return len(t.leaves) - 2
+}
+
+// Contains reports whether value is a list value in the tree
+func (t *SortedMerkleTree) Contains(value *big.Int) bool {
+	i := sort.Search(len(t.leaves), func(i int) bool { return t.leaves[i].Cmp(value) >= 0 })
+	return i < len(t.leaves) && t.leaves[i].Cmp(value) == 0 && i != 0 && i != len(t.leaves)-1
+}
+
+// path returns the sibling path of the leaf at index
+func (t *SortedMerkleTree) path(index int) []*big.Int {
+	_, path, _ := t.Path(uint64(index))
+	return path
+}
+
+// NonMembershipProof is the witness that a value is absent from a sorted tree
+type NonMembershipProof struct {
+	Root     *big.Int
+	Value    *big.Int
+	LowLeaf  *big.Int
+	HighLeaf *big.Int
+	LowIndex uint64
+	LowPath  []*big.Int
+	HighPath []*big.Int
+}
+
+// ProveNonMembership returns the neighbouring leaves and their paths for a
+// value that is not in the tree
+func (t *SortedMerkleTree) ProveNonMembership(value *big.Int) (*NonMembershipProof, error) {
+	if value.Cmp(MinSentinel) <= 0 || value.Cmp(MaxSentinel) >= 0 {
+		return nil, fmt.Errorf("value is outside the identifier range")
+	}
+
+	// First leaf greater than or equal to value; the max sentinel guarantees one
+	high := sort.Search(len(t.leaves), func(i int) bool { return t.leaves[i].Cmp(value) >= 0 })
+	if t.leaves[high].Cmp(value) == 0 {
+		return nil, fmt.Errorf("value is in the tree")
+	}
+	low := high - 1
+
+	return &NonMembershipProof{
+		Root:     t.Root(),
+		Value:    value,
+		LowLeaf:  t.leaves[low],
+		HighLeaf: t.leaves[high],
+		LowIndex: uint64(low),
+		LowPath:  t.path(low),
+		HighPath: t.path(high),
+	}, nil
+}
+
+// Inputs returns the proof as aml_sanctions_check witness inputs, with
+// field elements encoded as decimal strings
+func (p *NonMembershipProof) Inputs() map[string]interface{} {
+	return map[string]interface{}{
+		"sanctions_list_root": p.Root.String(),
+		"user_identifier":     p.Value.String(),
+		"low_leaf":            p.LowLeaf.String(),
+		"high_leaf":           p.HighLeaf.String(),
+		"low_index":           p.LowIndex,
+		"low_path":            decimalStrings(p.LowPath),
+		"high_path":           decimalStrings(p.HighPath),
+	}
+}
+
+func decimalStrings(values []*big.Int) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = v.String()
+	}
+	return out
+}
@@ -0,0 +1,145 @@
+package circuits
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/test"
+)
+
+const testDepth = 4
+
+func testTree(t *testing.T, fn HashFunc) *SortedMerkleTree {
+	t.Helper()
+
+	var values []*big.Int
+	for _, name := range []string{"alice", "bob", "carol", "dave", "eve"} {
+		values = append(values, HashIdentifier([]byte(name)))
+	}
+
+	tree, err := NewSortedMerkleTree(ecc.BN254, fn, testDepth, values)
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+	return tree
+}
+
+var testSalt = big.NewInt(424242)
+
+func assignment(p *NonMembershipProof, fn HashFunc) *SanctionsCheckCircuit {
+	commitment, err := CommitIdentifier(ecc.BN254, fn, p.Value, testSalt)
+	if err != nil {
+		panic(err)
+	}
+
+	c := NewSanctionsCheckCircuit(len(p.LowPath), fn)
+	c.SanctionsListRoot = p.Root
+	c.CurrentTimestamp = 1700000000
+	c.IdentifierCommitment = commitment
+	c.UserID = p.Value
+	c.IdentifierSalt = testSalt
+	c.LowLeaf = p.LowLeaf
+	c.HighLeaf = p.HighLeaf
+	c.LowIndex = p.LowIndex
+	for i := range p.LowPath {
+		c.LowPath[i] = p.LowPath[i]
+		c.HighPath[i] = p.HighPath[i]
+	}
+	return c
+}
+
+func TestSanctionsCheckCircuit_NonMember(t *testing.T) {
+	for _, fn := range []HashFunc{HashMiMC, HashPoseidon2} {
+		t.Run(string(fn), func(t *testing.T) {
+			tree := testTree(t, fn)
+
+			value := HashIdentifier([]byte("mallory"))
+			proof, err := tree.ProveNonMembership(value)
+			if err != nil {
+				t.Fatalf("Failed to prove non-membership: %v", err)
+			}
+
+			err = test.IsSolved(NewSanctionsCheckCircuit(testDepth, fn), assignment(proof, fn), ecc.BN254.ScalarField())
+			if err != nil {
+				t.Fatalf("Expected circuit to be satisfied: %v", err)
+			}
+		})
+	}
+}
+
+func TestSanctionsCheckCircuit_RejectsMember(t *testing.T) {
+	tree := testTree(t, HashMiMC)
+
+	member := HashIdentifier([]byte("carol"))
+	if !tree.Contains(member) {
+		t.Fatal("Expected tree to contain carol")
+	}
+	if _, err := tree.ProveNonMembership(member); err == nil {
+		t.Fatal("Expected non-membership proof of a member to fail")
+	}
+
+	// Reuse a neighbour pair of a non-member and swap in the listed value
+	proof, err := tree.ProveNonMembership(new(big.Int).Add(member, big.NewInt(1)))
+	if err != nil {
+		t.Fatalf("Failed to prove non-membership: %v", err)
+	}
+	proof.Value = member
+
+	err = test.IsSolved(NewSanctionsCheckCircuit(testDepth, HashMiMC), assignment(proof, HashMiMC), ecc.BN254.ScalarField())
+	if err == nil {
+		t.Fatal("Expected circuit to reject a listed identifier")
+	}
+}
+
+func TestSanctionsCheckCircuit_RejectsNonAdjacentLeaves(t *testing.T) {
+	tree := testTree(t, HashMiMC)
+
+	// The sentinels bracket every value but are not adjacent
+	value := HashIdentifier([]byte("mallory"))
+	proof, err := tree.ProveNonMembership(value)
+	if err != nil {
+		t.Fatalf("Failed to prove non-membership: %v", err)
+	}
+	proof.LowLeaf = MinSentinel
+	proof.LowIndex = 0
+	proof.LowPath = tree.path(0)
+
+	if proof.HighLeaf.Cmp(MaxSentinel) != 0 {
+		last := tree.Len() + 1
+		proof.HighLeaf = MaxSentinel
+		proof.HighPath = tree.path(last)
+	}
+
+	err = test.IsSolved(NewSanctionsCheckCircuit(testDepth, HashMiMC), assignment(proof, HashMiMC), ecc.BN254.ScalarField())
+	if err == nil {
+		t.Fatal("Expected circuit to reject non-adjacent leaves")
+	}
+}
+
+func TestSanctionsCheckCircuit_RejectsOtherCommitment(t *testing.T) {
+	tree := testTree(t, HashMiMC)
+
+	// A valid non-membership proof for mallory cannot claim trent's commitment
+	proof, err := tree.ProveNonMembership(HashIdentifier([]byte("mallory")))
+	if err != nil {
+		t.Fatalf("Failed to prove non-membership: %v", err)
+	}
+	c := assignment(proof, HashMiMC)
+	c.IdentifierCommitment, err = CommitIdentifier(ecc.BN254, HashMiMC, HashIdentifier([]byte("trent")), testSalt)
+	if err != nil {
+		t.Fatalf("Failed to commit identifier: %v", err)
+	}
+
+	err = test.IsSolved(NewSanctionsCheckCircuit(testDepth, HashMiMC), c, ecc.BN254.ScalarField())
+	if err == nil {
+		t.Fatal("Expected circuit to reject a commitment to another identifier")
+	}
+
+	// Nor can it change the salt
+	c = assignment(proof, HashMiMC)
+	c.IdentifierSalt = big.NewInt(1)
+	if err := test.IsSolved(NewSanctionsCheckCircuit(testDepth, HashMiMC), c, ecc.BN254.ScalarField()); err == nil {
+		t.Fatal("Expected circuit to reject a different salt")
+	}
+}
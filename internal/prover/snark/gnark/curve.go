@@ -0,0 +1,107 @@
+package gnark
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/gabrielrondon/zapiki/internal/models"
+	"github.com/gabrielrondon/zapiki/internal/prover"
+)
+
+// DefaultCurve is used when neither the circuit definition nor the proof
+// options select a curve
+const DefaultCurve = ecc.BN254
+
+// SupportedCurves lists the curves the gnark provers accept. BN254 is
+// verifiable on Ethereum, BLS12-381 by most pairing libraries, and the
+// BLS12-377/BW6-761 pair supports one level of recursion.
+var SupportedCurves = []ecc.ID{
+	ecc.BN254,
+	ecc.BLS12_381,
+	ecc.BLS12_377,
+	ecc.BW6_761,
+}
+
+// ParseCurve parses a curve name such as "bn254" or "bls12-381". An empty
+// name selects DefaultCurve.
+func ParseCurve(name string) (ecc.ID, error) {
+	if name == "" {
+		return DefaultCurve, nil
+	}
+
+	id, err := ecc.IDFromString(strings.ReplaceAll(name, "-", "_"))
+	if err == nil {
+		for _, supported := range SupportedCurves {
+			if id == supported {
+				return id, nil
+			}
+		}
+	}
+
+	return ecc.UNKNOWN, fmt.Errorf("unsupported curve %q (supported: %s)", name, strings.Join(CurveNames(), ", "))
+}
+
+// CurveNames returns the names of SupportedCurves as advertised by the API
+func CurveNames() []string {
+	names := make([]string, len(SupportedCurves))
+	for i, id := range SupportedCurves {
+		names[i] = id.String()
+	}
+	return names
+}
+
+// resolveCurve picks the curve for a request. The circuit definition wins
+// because stored keys were generated on its curve; a requested curve that
+// disagrees with it is an error rather than a silent switch.
+func resolveCurve(requested, circuit string, fallback ecc.ID) (ecc.ID, error) {
+	if circuit != "" {
+		id, err := ParseCurve(circuit)
+		if err != nil {
+			return ecc.UNKNOWN, err
+		}
+		if requested != "" {
+			other, err := ParseCurve(requested)
+			if err != nil {
+				return ecc.UNKNOWN, err
+			}
+			if other != id {
+				return ecc.UNKNOWN, fmt.Errorf("curve %s does not match the circuit curve %s", other, id)
+			}
+		}
+		return id, nil
+	}
+
+	if requested != "" {
+		return ParseCurve(requested)
+	}
+	return fallback, nil
+}
+
+// optionString returns a string proof option, or "" when it is not set
+func optionString(options map[string]interface{}, name string) string {
+	s, _ := options[name].(string)
+	return s
+}
+
+// definitionCurve returns the curve recorded in a circuit's definition
+func definitionCurve(circuit *models.Circuit) (string, error) {
+	if circuit == nil || circuit.CircuitDefinition == nil {
+		return "", nil
+	}
+	var def circuitDefinition
+	if err := json.Unmarshal(circuit.CircuitDefinition, &def); err != nil {
+		return "", fmt.Errorf("failed to parse circuit definition: %w", err)
+	}
+	return def.Curve, nil
+}
+
+// verifyCurve returns the curve a proof to be verified was generated on
+func verifyCurve(req *prover.VerifyRequest, fallback ecc.ID) (ecc.ID, error) {
+	circuitCurve, err := definitionCurve(req.Circuit)
+	if err != nil {
+		return ecc.UNKNOWN, err
+	}
+	return resolveCurve(req.Curve, circuitCurve, fallback)
+}
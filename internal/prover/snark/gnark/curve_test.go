@@ -0,0 +1,165 @@
+package gnark
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/gabrielrondon/zapiki/internal/models"
+	"github.com/gabrielrondon/zapiki/internal/prover"
+)
+
+func curveProofRequest(t *testing.T, curve string) *prover.ProofRequest {
+	t.Helper()
+
+	inputJSON, _ := json.Marshal(map[string]interface{}{"x": 3, "y": 5, "z": 15})
+	circuitDefJSON, _ := json.Marshal(map[string]interface{}{"circuit_type": "simple", "curve": curve})
+
+	return &prover.ProofRequest{
+		Circuit: &models.Circuit{CircuitDefinition: circuitDefJSON},
+		Data: &models.InputData{
+			Type:  models.DataTypeJSON,
+			Value: inputJSON,
+		},
+	}
+}
+
+func TestParseCurve(t *testing.T) {
+	tests := []struct {
+		name  string
+		want  ecc.ID
+		valid bool
+	}{
+		{"", DefaultCurve, true},
+		{"bn254", ecc.BN254, true},
+		{"BLS12-381", ecc.BLS12_381, true},
+		{"bls12_377", ecc.BLS12_377, true},
+		{"bw6-761", ecc.BW6_761, true},
+		{"bls24_315", ecc.UNKNOWN, false},
+		{"secp256k1", ecc.UNKNOWN, false},
+		{"nope", ecc.UNKNOWN, false},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseCurve(tt.name)
+		if tt.valid && err != nil {
+			t.Errorf("ParseCurve(%q) failed: %v", tt.name, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("ParseCurve(%q) expected an error", tt.name)
+		}
+		if tt.valid && got != tt.want {
+			t.Errorf("ParseCurve(%q) = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestProvers_ProveAndVerifyOnEachCurve(t *testing.T) {
+	ctx := context.Background()
+
+	for _, p := range []prover.ProofSystem{NewGroth16Prover(), NewPLONKProver()} {
+		for _, curve := range CurveNames() {
+			t.Run(string(p.Name())+"/"+curve, func(t *testing.T) {
+				resp, err := p.Generate(ctx, curveProofRequest(t, curve))
+				if err != nil {
+					t.Fatalf("Failed to generate proof: %v", err)
+				}
+				if resp.Metadata["curve"] != curve {
+					t.Errorf("Expected curve %s in metadata, got %v", curve, resp.Metadata["curve"])
+				}
+
+				verifyResp, err := p.Verify(ctx, &prover.VerifyRequest{
+					Proof:           resp.Proof,
+					VerificationKey: resp.VerificationKey,
+					PublicInputs:    resp.PublicInputs,
+					Curve:           curve,
+				})
+				if err != nil {
+					t.Fatalf("Failed to verify proof: %v", err)
+				}
+				if !verifyResp.Valid {
+					t.Errorf("Expected proof to be valid, got: %s", verifyResp.ErrorMessage)
+				}
+			})
+		}
+	}
+}
+
+func TestGroth16Prover_CurveFromOptions(t *testing.T) {
+	p := NewGroth16Prover()
+	ctx := context.Background()
+
+	req := simpleProofRequest(t)
+	req.Options = map[string]interface{}{"curve": "bls12_381"}
+
+	resp, err := p.Generate(ctx, req)
+	if err != nil {
+		t.Fatalf("Failed to generate proof: %v", err)
+	}
+	if resp.Metadata["curve"] != "bls12_381" {
+		t.Errorf("Expected curve bls12_381, got %v", resp.Metadata["curve"])
+	}
+
+	// The proof only verifies on the curve it was generated on
+	verifyResp, err := p.Verify(ctx, &prover.VerifyRequest{
+		Proof:           resp.Proof,
+		VerificationKey: resp.VerificationKey,
+		PublicInputs:    resp.PublicInputs,
+	})
+	if err != nil {
+		t.Fatalf("Failed to verify proof: %v", err)
+	}
+	if verifyResp.Valid {
+		t.Error("Expected verification on the default curve to fail")
+	}
+}
+
+func TestGroth16Prover_RejectsConflictingCurve(t *testing.T) {
+	p := NewGroth16Prover()
+
+	req := curveProofRequest(t, "bls12_377")
+	req.Options = map[string]interface{}{"curve": "bn254"}
+
+	if _, err := p.Generate(context.Background(), req); err == nil {
+		t.Fatal("Expected a curve that disagrees with the circuit to be rejected")
+	}
+}
+
+func TestGroth16Prover_SetupOnCurve(t *testing.T) {
+	p := NewGroth16Prover()
+
+	circuitDefJSON, _ := json.Marshal(map[string]interface{}{"circuit_type": "simple", "curve": "bw6_761"})
+	circuit := &models.Circuit{CircuitDefinition: circuitDefJSON}
+
+	setup, err := p.Setup(context.Background(), circuit)
+	if err != nil {
+		t.Fatalf("Failed to run setup: %v", err)
+	}
+	if setup.Metadata["curve"] != "bw6_761" {
+		t.Errorf("Expected curve bw6_761, got %v", setup.Metadata["curve"])
+	}
+
+	// Proofs against the circuit reuse its curve without the caller naming it
+	req := curveProofRequest(t, "bw6_761")
+	req.ProvingKey = setup.ProvingKey
+	req.VerificationKey = setup.VerificationKey
+
+	resp, err := p.Generate(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Failed to generate proof: %v", err)
+	}
+
+	verifyResp, err := p.Verify(context.Background(), &prover.VerifyRequest{
+		Proof:           resp.Proof,
+		VerificationKey: setup.VerificationKey,
+		PublicInputs:    resp.PublicInputs,
+		Circuit:         circuit,
+	})
+	if err != nil {
+		t.Fatalf("Failed to verify proof: %v", err)
+	}
+	if !verifyResp.Valid {
+		t.Errorf("Expected proof to be valid, got: %s", verifyResp.ErrorMessage)
+	}
+}
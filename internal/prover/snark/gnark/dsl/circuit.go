@@ -0,0 +1,366 @@
+package dsl
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/consensys/gnark/frontend"
+	gnarkhash "github.com/consensys/gnark/std/hash"
+	"github.com/gabrielrondon/zapiki/internal/prover/snark/gnark/circuits"
+)
+
+// Circuit is a compiled Definition. Public and Secret hold the input
+// elements in declaration order, arrays flattened.
+type Circuit struct {
+	Public []frontend.Variable `gnark:",public"`
+	Secret []frontend.Variable `gnark:",secret"`
+
+	// Definition is the circuit description (not part of the witness)
+	Definition *Definition `gnark:"-"`
+}
+
+// Circuit returns an unassigned circuit, to compile
+func (d *Definition) Circuit() *Circuit {
+	return &Circuit{
+		Public:     make([]frontend.Variable, d.publicSize),
+		Secret:     make([]frontend.Variable, d.secretSize),
+		Definition: d,
+	}
+}
+
+// PublicNames names the public input elements in witness order: "name",
+// or "name[i]" for array elements
+func (c *Circuit) PublicNames() []string {
+	var names []string
+	for _, decl := range c.Definition.Public {
+		name, length, err := parseDeclaration(decl)
+		if err != nil {
+			return nil
+		}
+		if length < 0 {
+			names = append(names, name)
+			continue
+		}
+		for i := range length {
+			names = append(names, fmt.Sprintf("%s[%d]", name, i))
+		}
+	}
+	return names
+}
+
+// Assign returns the circuit with the inputs assigned, each value read as
+// a field element by element. Every declared input is required, as an
+// array of values for arrays; other inputs are rejected.
+func (d *Definition) Assign(inputs map[string]interface{}, element func(interface{}) (*big.Int, error)) (*Circuit, error) {
+	var unknown []string
+	for name := range inputs {
+		if v, ok := d.vars[name]; !ok || !v.input {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown inputs: %s", strings.Join(unknown, ", "))
+	}
+
+	c := d.Circuit()
+	for name, v := range d.vars {
+		if !v.input {
+			continue
+		}
+		value, ok := inputs[name]
+		if !ok {
+			return nil, fmt.Errorf("input %q is required", name)
+		}
+		elements := c.Secret
+		if v.public {
+			elements = c.Public
+		}
+
+		if !v.kind.isArray() {
+			n, err := element(value)
+			if err != nil {
+				return nil, fmt.Errorf("input %q: %w", name, err)
+			}
+			elements[v.offset] = n
+			continue
+		}
+		items, ok := value.([]interface{})
+		if !ok || len(items) != v.kind.length {
+			return nil, fmt.Errorf("input %q must be an array of %d elements", name, v.kind.length)
+		}
+		for i, item := range items {
+			n, err := element(item)
+			if err != nil {
+				return nil, fmt.Errorf("input %q[%d]: %w", name, i, err)
+			}
+			elements[v.offset+i] = n
+		}
+	}
+	return c, nil
+}
+
+// value is a field element or an array of them
+type value struct {
+	scalar frontend.Variable
+	array  []frontend.Variable
+}
+
+// compiler evaluates expressions against the frontend API
+type compiler struct {
+	api      frontend.API
+	circuit  *Circuit
+	bindings []value
+}
+
+// Define implements frontend.Circuit
+func (c *Circuit) Define(api frontend.API) error {
+	if c.Definition == nil {
+		return fmt.Errorf("circuit has no definition")
+	}
+	d := c.Definition
+	comp := &compiler{api: api, circuit: c, bindings: make([]value, len(d.Let))}
+
+	for i, b := range d.Let {
+		v, err := comp.eval(b.Value)
+		if err != nil {
+			return fmt.Errorf("let %q: %w", b.Name, err)
+		}
+		comp.bindings[i] = v
+	}
+	for i, constraint := range d.Constraints {
+		if err := comp.assert(constraint); err != nil {
+			return fmt.Errorf("constraint %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// assert constrains a top-level expression to hold, using the cheaper
+// assertions for comparisons
+func (c *compiler) assert(e *Expr) error {
+	api := c.api
+	switch e.Op {
+	case "eq", "neq", "lte", "gte", "range", "member":
+		args, err := c.scalars(e.Args[:1])
+		if err != nil {
+			return err
+		}
+		if e.Op == "range" {
+			api.ToBinary(args[0], e.Bits)
+			return nil
+		}
+		if e.Op == "member" {
+			set, err := c.eval(e.Args[1])
+			if err != nil {
+				return err
+			}
+			api.AssertIsEqual(c.vanishing(args[0], set.array), 0)
+			return nil
+		}
+
+		right, err := c.scalars(e.Args[1:])
+		if err != nil {
+			return err
+		}
+		a, b := args[0], right[0]
+		switch e.Op {
+		case "eq":
+			api.AssertIsEqual(a, b)
+		case "neq":
+			api.AssertIsDifferent(a, b)
+		case "lte":
+			api.AssertIsLessOrEqual(a, b)
+		case "gte":
+			api.AssertIsLessOrEqual(b, a)
+		}
+		return nil
+	default:
+		v, err := c.eval(e)
+		if err != nil {
+			return err
+		}
+		api.AssertIsEqual(v.scalar, 1)
+		return nil
+	}
+}
+
+// eval computes an expression checked by Definition.typeOf
+func (c *compiler) eval(e *Expr) (value, error) {
+	api := c.api
+	switch {
+	case e.ref != "":
+		v, err := c.lookup(e.ref)
+		if err != nil {
+			return value{}, err
+		}
+		if e.index >= 0 {
+			return value{scalar: v.array[e.index]}, nil
+		}
+		return v, nil
+	case e.isList:
+		items, err := c.scalars(e.list)
+		if err != nil {
+			return value{}, err
+		}
+		return value{array: items}, nil
+	case e.Op == "":
+		n, err := parseConstant(e.Const)
+		if err != nil {
+			return value{}, err
+		}
+		return value{scalar: n}, nil
+	}
+
+	switch e.Op {
+	case "hash":
+		h, err := c.hasher(e.Hash)
+		if err != nil {
+			return value{}, err
+		}
+		for _, arg := range e.Args {
+			v, err := c.eval(arg)
+			if err != nil {
+				return value{}, err
+			}
+			if v.array != nil {
+				h.Write(v.array...)
+			} else {
+				h.Write(v.scalar)
+			}
+		}
+		return value{scalar: h.Sum()}, nil
+	case "member":
+		x, err := c.scalars(e.Args[:1])
+		if err != nil {
+			return value{}, err
+		}
+		set, err := c.eval(e.Args[1])
+		if err != nil {
+			return value{}, err
+		}
+		return value{scalar: api.IsZero(c.vanishing(x[0], set.array))}, nil
+	case "merkle_root":
+		leaf, err := c.scalars(e.Args[:1])
+		if err != nil {
+			return value{}, err
+		}
+		path, err := c.eval(e.Args[1])
+		if err != nil {
+			return value{}, err
+		}
+		directions, err := c.eval(e.Args[2])
+		if err != nil {
+			return value{}, err
+		}
+		for _, d := range directions.array {
+			api.AssertIsBoolean(d)
+		}
+		h, err := c.hasher(e.Hash)
+		if err != nil {
+			return value{}, err
+		}
+		return value{scalar: circuits.MerkleRoot(api, h, leaf[0], directions.array, path.array)}, nil
+	}
+
+	args, err := c.scalars(e.Args)
+	if err != nil {
+		return value{}, err
+	}
+	var result frontend.Variable
+	switch e.Op {
+	case "add":
+		result = api.Add(args[0], args[1], args[2:]...)
+	case "sub":
+		result = api.Sub(args[0], args[1])
+	case "mul":
+		result = api.Mul(args[0], args[1], args[2:]...)
+	case "div":
+		result = api.Div(args[0], args[1])
+	case "neg":
+		result = api.Neg(args[0])
+	case "and":
+		result = args[0]
+		for _, arg := range args[1:] {
+			result = api.And(result, arg)
+		}
+	case "or":
+		result = args[0]
+		for _, arg := range args[1:] {
+			result = api.Or(result, arg)
+		}
+	case "not":
+		api.AssertIsBoolean(args[0])
+		result = api.Sub(1, args[0])
+	case "select":
+		result = api.Select(args[0], args[1], args[2])
+	case "eq":
+		result = api.IsZero(api.Sub(args[0], args[1]))
+	case "neq":
+		result = api.Sub(1, api.IsZero(api.Sub(args[0], args[1])))
+	case "lt":
+		result = api.IsZero(api.Add(api.Cmp(args[0], args[1]), 1))
+	case "gt":
+		result = api.IsZero(api.Sub(api.Cmp(args[0], args[1]), 1))
+	case "lte":
+		result = api.Sub(1, api.IsZero(api.Sub(api.Cmp(args[0], args[1]), 1)))
+	case "gte":
+		result = api.Sub(1, api.IsZero(api.Add(api.Cmp(args[0], args[1]), 1)))
+	default:
+		return value{}, fmt.Errorf("op %q cannot be used here", e.Op)
+	}
+	return value{scalar: result}, nil
+}
+
+// scalars evaluates expressions that are field elements
+func (c *compiler) scalars(exprs []*Expr) ([]frontend.Variable, error) {
+	values := make([]frontend.Variable, len(exprs))
+	for i, e := range exprs {
+		v, err := c.eval(e)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v.scalar
+	}
+	return values, nil
+}
+
+// lookup returns the value of an input or binding
+func (c *compiler) lookup(name string) (value, error) {
+	v, ok := c.circuit.Definition.vars[name]
+	if !ok {
+		return value{}, fmt.Errorf("unknown name %q", name)
+	}
+	if !v.input {
+		return c.bindings[v.binding], nil
+	}
+
+	elements := c.circuit.Secret
+	if v.public {
+		elements = c.circuit.Public
+	}
+	if !v.kind.isArray() {
+		return value{scalar: elements[v.offset]}, nil
+	}
+	return value{array: elements[v.offset : v.offset+v.kind.length]}, nil
+}
+
+// vanishing returns the product of x - s over the set, zero exactly when x
+// is a member
+func (c *compiler) vanishing(x frontend.Variable, set []frontend.Variable) frontend.Variable {
+	product := c.api.Sub(x, set[0])
+	for _, s := range set[1:] {
+		product = c.api.Mul(product, c.api.Sub(x, s))
+	}
+	return product
+}
+
+func (c *compiler) hasher(name string) (gnarkhash.FieldHasher, error) {
+	fn, err := circuits.ParseHashFunc(name)
+	if err != nil {
+		return nil, err
+	}
+	return circuits.NewHasher(c.api, fn)
+}
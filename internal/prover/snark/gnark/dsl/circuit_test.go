@@ -0,0 +1,211 @@
+package dsl
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/test"
+	"github.com/gabrielrondon/zapiki/internal/prover/snark/gnark/circuits"
+)
+
+const allowlistDefinition = `{
+	"public": ["threshold", "allowlist[4]"],
+	"secret": ["balance", "account"],
+	"let": [{"name": "id", "value": {"op": "hash", "args": ["account"]}}],
+	"constraints": [
+		{"op": "range", "args": ["balance"], "bits": 64},
+		{"op": "gte", "args": ["balance", "threshold"]},
+		{"op": "member", "args": ["id", "allowlist"]}
+	]
+}`
+
+func parse(t *testing.T, definition string) *Definition {
+	t.Helper()
+	def, err := Parse([]byte(definition))
+	if err != nil {
+		t.Fatalf("Failed to parse definition: %v", err)
+	}
+	return def
+}
+
+// decimal reads test inputs: exact JSON numbers and decimal strings
+func decimal(v interface{}) (*big.Int, error) {
+	switch v := v.(type) {
+	case float64:
+		if v != float64(int64(v)) {
+			return nil, fmt.Errorf("%v is not an integer", v)
+		}
+		return big.NewInt(int64(v)), nil
+	case string:
+		if n, ok := new(big.Int).SetString(v, 10); ok {
+			return n, nil
+		}
+	}
+	return nil, fmt.Errorf("%v is not a decimal", v)
+}
+
+func solved(t *testing.T, def *Definition, inputs string) error {
+	t.Helper()
+	var values map[string]interface{}
+	if err := json.Unmarshal([]byte(inputs), &values); err != nil {
+		t.Fatalf("Failed to parse inputs: %v", err)
+	}
+	assignment, err := def.Assign(values, decimal)
+	if err != nil {
+		t.Fatalf("Failed to assign inputs: %v", err)
+	}
+	return test.IsSolved(def.Circuit(), assignment, ecc.BN254.ScalarField())
+}
+
+func TestDefinition_Allowlist(t *testing.T) {
+	def := parse(t, allowlistDefinition)
+
+	id, err := circuits.NativeHash(ecc.BN254, circuits.HashMiMC, big.NewInt(42))
+	if err != nil {
+		t.Fatalf("Failed to hash account: %v", err)
+	}
+	inputs := func(balance, threshold int, allowed string) string {
+		return `{"balance": ` + strconv.Itoa(balance) + `, "account": 42, "threshold": ` + strconv.Itoa(threshold) +
+			`, "allowlist": ["7", "` + allowed + `", "9", "11"]}`
+	}
+
+	if err := solved(t, def, inputs(1500, 1000, id.String())); err != nil {
+		t.Errorf("Balance above threshold on the allowlist should satisfy the circuit: %v", err)
+	}
+	if err := solved(t, def, inputs(900, 1000, id.String())); err == nil {
+		t.Error("Balance below threshold should not satisfy the circuit")
+	}
+	if err := solved(t, def, inputs(1500, 1000, "8")); err == nil {
+		t.Error("Account off the allowlist should not satisfy the circuit")
+	}
+}
+
+func TestCircuit_PublicNames(t *testing.T) {
+	names := parse(t, allowlistDefinition).Circuit().PublicNames()
+	want := "threshold allowlist[0] allowlist[1] allowlist[2] allowlist[3]"
+	if got := strings.Join(names, " "); got != want {
+		t.Errorf("Expected public names %q, got %q", want, got)
+	}
+}
+
+func TestDefinition_Expressions(t *testing.T) {
+	def := parse(t, `{
+		"public": ["out"],
+		"secret": ["a", "b", "flag"],
+		"let": [
+			{"name": "big", "value": {"op": "gt", "args": ["a", "b"]}},
+			{"name": "pick", "value": {"op": "select", "args": ["flag", {"op": "mul", "args": ["a", "b"]}, {"op": "sub", "args": ["a", "b"]}]}}
+		],
+		"constraints": [
+			{"op": "eq", "args": ["pick", "out"]},
+			{"op": "or", "args": ["big", {"op": "not", "args": ["flag"]}]},
+			{"op": "neq", "args": [{"op": "div", "args": ["a", "b"]}, 0]},
+			{"op": "member", "args": ["b", [3, 4, {"const": "5"}]]}
+		]
+	}`)
+
+	if err := solved(t, def, `{"a": 7, "b": 4, "flag": 1, "out": 28}`); err != nil {
+		t.Errorf("Expected the circuit to be satisfied: %v", err)
+	}
+	if err := solved(t, def, `{"a": 7, "b": 4, "flag": 0, "out": 3}`); err != nil {
+		t.Errorf("Expected the circuit to be satisfied: %v", err)
+	}
+	// flag set but a <= b
+	if err := solved(t, def, `{"a": 3, "b": 4, "flag": 1, "out": 12}`); err == nil {
+		t.Error("Failed boolean constraint should not satisfy the circuit")
+	}
+	if err := solved(t, def, `{"a": 7, "b": 6, "flag": 1, "out": 42}`); err == nil {
+		t.Error("Value outside the set should not satisfy the circuit")
+	}
+}
+
+func TestDefinition_MerkleRoot(t *testing.T) {
+	def := parse(t, `{
+		"public": ["root"],
+		"secret": ["leaf", "path[2]", "directions[2]"],
+		"constraints": [
+			{"op": "eq", "args": [{"op": "merkle_root", "hash": "poseidon2", "args": ["leaf", "path", "directions"]}, "root"]}
+		]
+	}`)
+
+	path := []*big.Int{big.NewInt(11), big.NewInt(12)}
+	root, err := circuits.NativeMerkleRoot(ecc.BN254, circuits.HashPoseidon2, big.NewInt(10), []uint{1, 0}, path)
+	if err != nil {
+		t.Fatalf("Failed to compute root: %v", err)
+	}
+
+	inputs := `{"leaf": 10, "path": [11, 12], "directions": [1, 0], "root": "` + root.String() + `"}`
+	if err := solved(t, def, inputs); err != nil {
+		t.Errorf("Leaf on the path should satisfy the circuit: %v", err)
+	}
+	inputs = `{"leaf": 10, "path": [11, 12], "directions": [0, 0], "root": "` + root.String() + `"}`
+	if err := solved(t, def, inputs); err == nil {
+		t.Error("Wrong directions should not satisfy the circuit")
+	}
+}
+
+func TestParse_Rejects(t *testing.T) {
+	cases := map[string]string{
+		"no constraints":      `{"public": ["x"], "secret": [], "constraints": []}`,
+		"unknown name":        `{"public": ["x"], "secret": [], "constraints": [{"op": "eq", "args": ["x", "y"]}]}`,
+		"unknown op":          `{"public": ["x"], "secret": [], "constraints": [{"op": "pow", "args": ["x", 2]}]}`,
+		"non-boolean":         `{"public": ["x"], "secret": [], "constraints": [{"op": "add", "args": ["x", 2]}]}`,
+		"duplicate":           `{"public": ["x"], "secret": ["x"], "constraints": [{"op": "eq", "args": ["x", 2]}]}`,
+		"array as value":      `{"public": ["x[2]"], "secret": [], "constraints": [{"op": "eq", "args": ["x", 2]}]}`,
+		"index out of range":  `{"public": ["x[2]"], "secret": [], "constraints": [{"op": "eq", "args": ["x[2]", 2]}]}`,
+		"nested range":        `{"public": ["x"], "secret": [], "constraints": [{"op": "not", "args": [{"op": "range", "args": ["x"], "bits": 8}]}]}`,
+		"range bits":          `{"public": ["x"], "secret": [], "constraints": [{"op": "range", "args": ["x"], "bits": 0}]}`,
+		"arity":               `{"public": ["x"], "secret": [], "constraints": [{"op": "eq", "args": ["x"]}]}`,
+		"unknown hash":        `{"public": ["x"], "secret": [], "constraints": [{"op": "eq", "args": [{"op": "hash", "hash": "sha1", "args": ["x"]}, 1]}]}`,
+		"negative constant":   `{"public": ["x"], "secret": [], "constraints": [{"op": "eq", "args": ["x", -1]}]}`,
+		"fractional constant": `{"public": ["x"], "secret": [], "constraints": [{"op": "eq", "args": ["x", 1.5]}]}`,
+		"unknown field":       `{"public": ["x"], "secret": [], "constraints": [{"op": "eq", "args": ["x", 1], "label": "a"}]}`,
+		"forward reference":   `{"public": ["x"], "secret": [], "let": [{"name": "a", "value": "b"}, {"name": "b", "value": "x"}], "constraints": [{"op": "eq", "args": ["a", 1]}]}`,
+	}
+	for name, definition := range cases {
+		if _, err := Parse([]byte(definition)); err == nil {
+			t.Errorf("%s: expected the definition to be rejected", name)
+		}
+	}
+}
+
+func TestAssign_Rejects(t *testing.T) {
+	def := parse(t, allowlistDefinition)
+
+	cases := map[string]string{
+		"missing input": `{"balance": 1, "account": 2, "threshold": 1}`,
+		"unknown input": `{"balance": 1, "account": 2, "threshold": 1, "allowlist": [1, 2, 3, 4], "extra": 1}`,
+		"array length":  `{"balance": 1, "account": 2, "threshold": 1, "allowlist": [1, 2, 3]}`,
+		"inexact float": `{"balance": 1.5, "account": 2, "threshold": 1, "allowlist": [1, 2, 3, 4]}`,
+	}
+	for name, inputs := range cases {
+		var values map[string]interface{}
+		if err := json.Unmarshal([]byte(inputs), &values); err != nil {
+			t.Fatalf("Failed to parse inputs: %v", err)
+		}
+		if _, err := def.Assign(values, decimal); err == nil {
+			t.Errorf("%s: expected the inputs to be rejected", name)
+		}
+	}
+}
+
+func TestDefinition_RoundTrip(t *testing.T) {
+	def := parse(t, allowlistDefinition)
+	canonical, err := json.Marshal(def)
+	if err != nil {
+		t.Fatalf("Failed to marshal definition: %v", err)
+	}
+	again := parse(t, string(canonical))
+	second, _ := json.Marshal(again)
+	if string(canonical) != string(second) {
+		t.Errorf("Expected a stable encoding, got %s and %s", canonical, second)
+	}
+	if !strings.Contains(string(canonical), `"allowlist[4]"`) {
+		t.Errorf("Expected declarations to survive encoding, got %s", canonical)
+	}
+}
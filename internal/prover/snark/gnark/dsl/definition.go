@@ -0,0 +1,520 @@
+// Package dsl compiles JSON circuit descriptions into gnark circuits.
+package dsl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/gabrielrondon/zapiki/internal/prover/snark/gnark/circuits"
+)
+
+// Definition describes a circuit in JSON. Inputs are declared by name,
+// arrays with their length; named values are computed from them in order
+// and every constraint must hold. For example, a balance above a public
+// threshold, owned by an account on a public allowlist:
+//
+//	{
+//	  "public": ["threshold", "allowlist[4]"],
+//	  "secret": ["balance", "account"],
+//	  "let": [{"name": "id", "value": {"op": "hash", "args": ["account"]}}],
+//	  "constraints": [
+//	    {"op": "range", "args": ["balance"], "bits": 64},
+//	    {"op": "gte", "args": ["balance", "threshold"]},
+//	    {"op": "member", "args": ["id", "allowlist"]}
+//	  ]
+//	}
+type Definition struct {
+	// Public and Secret declare inputs: "name", or "name[n]" for an array
+	Public []string `json:"public"`
+	Secret []string `json:"secret"`
+	// Let names intermediate values; each may use the inputs and the
+	// values before it
+	Let []Binding `json:"let,omitempty"`
+	// Constraints are comparisons, range checks or boolean expressions
+	// that must hold
+	Constraints []*Expr `json:"constraints"`
+
+	// vars holds the inputs and bindings, filled in by Parse
+	vars map[string]*variable
+	// publicSize and secretSize count input elements
+	publicSize, secretSize int
+}
+
+// Binding names the value of an expression
+type Binding struct {
+	Name  string `json:"name"`
+	Value *Expr  `json:"value"`
+}
+
+// Expr is a JSON expression: a number, a name (or "name[i]" for an array
+// element), an array of expressions, {"const": "<decimal>"} for constants
+// wider than a JSON number, or an operation:
+//
+//	{"op": "<operation>", "args": [...]}
+//
+// Arithmetic: add, sub, mul, div (field division), neg. Comparisons of
+// field elements as integers: eq, neq, lt, lte, gt, gte. Boolean: and, or,
+// not, and select (args: condition, then, else). Range check: range, with
+// "bits", as a constraint only. hash (with an optional "hash" of "mimc" or
+// "poseidon2") hashes its args; member (args: value, array) tests set
+// membership; merkle_root (args: leaf, path array, direction bits) computes
+// a Merkle root as the merkle_proof circuit does.
+type Expr struct {
+	Op    string  `json:"op,omitempty"`
+	Args  []*Expr `json:"args,omitempty"`
+	Hash  string  `json:"hash,omitempty"`
+	Bits  int     `json:"bits,omitempty"`
+	Const string  `json:"const,omitempty"`
+
+	// ref is a name, with index >= 0 for an array element
+	ref   string
+	index int
+	// list is a literal array
+	list []*Expr
+	// isList tells a literal array from an operation
+	isList bool
+}
+
+// Limits on circuit descriptions
+const (
+	maxInputElements = 4096
+	maxArrayLength   = 1024
+	maxBindings      = 1024
+	maxConstraints   = 1024
+	maxDepth         = 64
+	maxRangeBits     = 252
+)
+
+// variable is a declared input or binding
+type variable struct {
+	public bool
+	input  bool
+	// offset is an input's first element in Circuit.Public or Circuit.Secret
+	offset int
+	kind   kind
+	// binding is the index of a Let binding
+	binding int
+}
+
+// kind is the static type of an expression
+type kind struct {
+	// length is the length of an array, or -1 for a field element
+	length int
+	// boolean marks field elements known to be 0 or 1
+	boolean bool
+}
+
+var (
+	scalarKind  = kind{length: -1}
+	booleanKind = kind{length: -1, boolean: true}
+)
+
+func (k kind) isArray() bool { return k.length >= 0 }
+
+// Parse decodes and validates a circuit description
+func Parse(data []byte) (*Definition, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var def Definition
+	if err := decoder.Decode(&def); err != nil {
+		return nil, fmt.Errorf("invalid circuit description: %w", err)
+	}
+	if err := def.check(); err != nil {
+		return nil, err
+	}
+	return &def, nil
+}
+
+// check resolves names and checks the expressions' types
+func (d *Definition) check() error {
+	switch {
+	case len(d.Constraints) == 0:
+		return fmt.Errorf("circuit has no constraints")
+	case len(d.Constraints) > maxConstraints:
+		return fmt.Errorf("at most %d constraints are supported", maxConstraints)
+	case len(d.Let) > maxBindings:
+		return fmt.Errorf("at most %d let bindings are supported", maxBindings)
+	}
+
+	d.vars = map[string]*variable{}
+	declare := func(name string, v *variable) error {
+		if !isName(name) {
+			return fmt.Errorf("invalid name %q", name)
+		}
+		if _, ok := d.vars[name]; ok {
+			return fmt.Errorf("name %q is declared twice", name)
+		}
+		d.vars[name] = v
+		return nil
+	}
+	declareInputs := func(decls []string, public bool) (int, error) {
+		size := 0
+		for _, decl := range decls {
+			name, length, err := parseDeclaration(decl)
+			if err != nil {
+				return 0, err
+			}
+			v := &variable{public: public, input: true, offset: size, kind: scalarKind}
+			if length >= 0 {
+				v.kind = kind{length: length}
+				size += length
+			} else {
+				size++
+			}
+			if err := declare(name, v); err != nil {
+				return 0, err
+			}
+		}
+		return size, nil
+	}
+
+	var err error
+	if d.publicSize, err = declareInputs(d.Public, true); err != nil {
+		return err
+	}
+	if d.secretSize, err = declareInputs(d.Secret, false); err != nil {
+		return err
+	}
+	if d.publicSize+d.secretSize > maxInputElements {
+		return fmt.Errorf("at most %d input elements are supported", maxInputElements)
+	}
+
+	for i, b := range d.Let {
+		if b.Value == nil {
+			return fmt.Errorf("let %q has no value", b.Name)
+		}
+		k, err := d.typeOf(b.Value, 0)
+		if err != nil {
+			return fmt.Errorf("let %q: %w", b.Name, err)
+		}
+		if err := declare(b.Name, &variable{kind: k, binding: i}); err != nil {
+			return err
+		}
+	}
+
+	for i, c := range d.Constraints {
+		if c == nil {
+			return fmt.Errorf("constraint %d is empty", i)
+		}
+		k, err := d.typeOf(c, 0)
+		if err != nil {
+			return fmt.Errorf("constraint %d: %w", i, err)
+		}
+		if c.Op != "range" && !k.boolean {
+			return fmt.Errorf("constraint %d must be a comparison, range check or boolean expression", i)
+		}
+	}
+	return nil
+}
+
+// typeOf checks an expression and returns its type
+func (d *Definition) typeOf(e *Expr, depth int) (kind, error) {
+	if e == nil {
+		return kind{}, fmt.Errorf("missing expression")
+	}
+	if depth > maxDepth {
+		return kind{}, fmt.Errorf("expressions nest deeper than %d", maxDepth)
+	}
+
+	switch {
+	case e.ref != "":
+		v, ok := d.vars[e.ref]
+		if !ok {
+			return kind{}, fmt.Errorf("unknown name %q", e.ref)
+		}
+		if e.index < 0 {
+			return v.kind, nil
+		}
+		if !v.kind.isArray() {
+			return kind{}, fmt.Errorf("%q is not an array", e.ref)
+		}
+		if e.index >= v.kind.length {
+			return kind{}, fmt.Errorf("index %d is outside %q of length %d", e.index, e.ref, v.kind.length)
+		}
+		return scalarKind, nil
+	case e.isList:
+		if len(e.list) > maxArrayLength {
+			return kind{}, fmt.Errorf("arrays hold at most %d elements", maxArrayLength)
+		}
+		for _, item := range e.list {
+			if err := d.scalar(item, depth+1); err != nil {
+				return kind{}, err
+			}
+		}
+		return kind{length: len(e.list)}, nil
+	case e.Op == "":
+		if e.Const == "" || len(e.Args) > 0 || e.Hash != "" || e.Bits != 0 {
+			return kind{}, fmt.Errorf("expression needs an op")
+		}
+		if _, err := parseConstant(e.Const); err != nil {
+			return kind{}, err
+		}
+		if e.Const == "0" || e.Const == "1" {
+			return booleanKind, nil
+		}
+		return scalarKind, nil
+	}
+
+	if e.Const != "" {
+		return kind{}, fmt.Errorf("%s: const cannot be combined with op", e.Op)
+	}
+	if e.Hash != "" && e.Op != "hash" && e.Op != "merkle_root" {
+		return kind{}, fmt.Errorf("%s does not take a hash", e.Op)
+	}
+	if e.Bits != 0 && e.Op != "range" {
+		return kind{}, fmt.Errorf("%s does not take bits", e.Op)
+	}
+	arity := func(n int) error {
+		if len(e.Args) != n {
+			return fmt.Errorf("%s takes %d arguments, got %d", e.Op, n, len(e.Args))
+		}
+		return nil
+	}
+	scalars := func(args []*Expr) error {
+		for _, arg := range args {
+			if err := d.scalar(arg, depth+1); err != nil {
+				return fmt.Errorf("%s: %w", e.Op, err)
+			}
+		}
+		return nil
+	}
+
+	switch e.Op {
+	case "add", "mul", "and", "or":
+		if len(e.Args) < 2 {
+			return kind{}, fmt.Errorf("%s takes at least 2 arguments", e.Op)
+		}
+		if err := scalars(e.Args); err != nil {
+			return kind{}, err
+		}
+		if e.Op == "and" || e.Op == "or" {
+			return booleanKind, nil
+		}
+		return scalarKind, nil
+	case "sub", "div":
+		if err := arity(2); err != nil {
+			return kind{}, err
+		}
+		return scalarKind, scalars(e.Args)
+	case "neg":
+		if err := arity(1); err != nil {
+			return kind{}, err
+		}
+		return scalarKind, scalars(e.Args)
+	case "not":
+		if err := arity(1); err != nil {
+			return kind{}, err
+		}
+		return booleanKind, scalars(e.Args)
+	case "eq", "neq", "lt", "lte", "gt", "gte":
+		if err := arity(2); err != nil {
+			return kind{}, err
+		}
+		return booleanKind, scalars(e.Args)
+	case "select":
+		if err := arity(3); err != nil {
+			return kind{}, err
+		}
+		if err := scalars(e.Args); err != nil {
+			return kind{}, err
+		}
+		then, _ := d.typeOf(e.Args[1], depth+1)
+		otherwise, _ := d.typeOf(e.Args[2], depth+1)
+		return kind{length: -1, boolean: then.boolean && otherwise.boolean}, nil
+	case "range":
+		if depth > 0 {
+			return kind{}, fmt.Errorf("range is only allowed as a constraint")
+		}
+		if err := arity(1); err != nil {
+			return kind{}, err
+		}
+		if e.Bits < 1 || e.Bits > maxRangeBits {
+			return kind{}, fmt.Errorf("range bits must be between 1 and %d", maxRangeBits)
+		}
+		return scalarKind, scalars(e.Args)
+	case "hash":
+		if len(e.Args) == 0 {
+			return kind{}, fmt.Errorf("hash takes at least 1 argument")
+		}
+		if _, err := circuits.ParseHashFunc(e.Hash); err != nil {
+			return kind{}, err
+		}
+		// Arrays are hashed element by element
+		for _, arg := range e.Args {
+			if _, err := d.typeOf(arg, depth+1); err != nil {
+				return kind{}, err
+			}
+		}
+		return scalarKind, nil
+	case "member":
+		if err := arity(2); err != nil {
+			return kind{}, err
+		}
+		if err := d.scalar(e.Args[0], depth+1); err != nil {
+			return kind{}, fmt.Errorf("member: %w", err)
+		}
+		set, err := d.typeOf(e.Args[1], depth+1)
+		if err != nil {
+			return kind{}, err
+		}
+		if !set.isArray() || set.length == 0 {
+			return kind{}, fmt.Errorf("member: the second argument must be a non-empty array")
+		}
+		return booleanKind, nil
+	case "merkle_root":
+		if err := arity(3); err != nil {
+			return kind{}, err
+		}
+		if _, err := circuits.ParseHashFunc(e.Hash); err != nil {
+			return kind{}, err
+		}
+		if err := d.scalar(e.Args[0], depth+1); err != nil {
+			return kind{}, fmt.Errorf("merkle_root: %w", err)
+		}
+		path, err := d.typeOf(e.Args[1], depth+1)
+		if err != nil {
+			return kind{}, err
+		}
+		directions, err := d.typeOf(e.Args[2], depth+1)
+		if err != nil {
+			return kind{}, err
+		}
+		if !path.isArray() || path.length == 0 || directions.length != path.length {
+			return kind{}, fmt.Errorf("merkle_root: path and directions must be arrays of one length")
+		}
+		return scalarKind, nil
+	default:
+		return kind{}, fmt.Errorf("unknown op %q", e.Op)
+	}
+}
+
+// scalar checks that an expression is a field element
+func (d *Definition) scalar(e *Expr, depth int) error {
+	k, err := d.typeOf(e, depth)
+	if err != nil {
+		return err
+	}
+	if k.isArray() {
+		return fmt.Errorf("an array cannot be used as a value")
+	}
+	return nil
+}
+
+// UnmarshalJSON accepts numbers, names, arrays and objects
+func (e *Expr) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return fmt.Errorf("empty expression")
+	}
+
+	e.index = -1
+	switch data[0] {
+	case '"':
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		name, index, err := parseReference(s)
+		if err != nil {
+			return err
+		}
+		e.ref, e.index = name, index
+		return nil
+	case '[':
+		e.isList = true
+		e.list = []*Expr{}
+		return json.Unmarshal(data, &e.list)
+	case '{':
+		type plain Expr
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode((*plain)(e)); err != nil {
+			return err
+		}
+		e.index = -1
+		return nil
+	default:
+		var n json.Number
+		if err := json.Unmarshal(data, &n); err != nil {
+			return fmt.Errorf("expression must be a number, name, array or object")
+		}
+		e.Const = n.String()
+		return nil
+	}
+}
+
+// MarshalJSON writes the expression back in the form it was parsed from
+func (e *Expr) MarshalJSON() ([]byte, error) {
+	switch {
+	case e.ref != "" && e.index >= 0:
+		return json.Marshal(fmt.Sprintf("%s[%d]", e.ref, e.index))
+	case e.ref != "":
+		return json.Marshal(e.ref)
+	case e.isList:
+		return json.Marshal(e.list)
+	default:
+		type plain Expr
+		return json.Marshal((*plain)(e))
+	}
+}
+
+// parseDeclaration parses "name" (length -1) or "name[n]"
+func parseDeclaration(decl string) (string, int, error) {
+	name, index, err := parseReference(decl)
+	if err != nil {
+		return "", 0, err
+	}
+	if index == 0 || index > maxArrayLength {
+		return "", 0, fmt.Errorf("array %q must have between 1 and %d elements", name, maxArrayLength)
+	}
+	return name, index, nil
+}
+
+// parseReference parses "name" (index -1) or "name[i]"
+func parseReference(s string) (string, int, error) {
+	open := strings.IndexByte(s, '[')
+	if open < 0 {
+		if !isName(s) {
+			return "", 0, fmt.Errorf("invalid name %q", s)
+		}
+		return s, -1, nil
+	}
+	name := s[:open]
+	if !isName(name) || !strings.HasSuffix(s, "]") {
+		return "", 0, fmt.Errorf("invalid name %q", s)
+	}
+	index, err := strconv.Atoi(s[open+1 : len(s)-1])
+	if err != nil || index < 0 {
+		return "", 0, fmt.Errorf("invalid index in %q", s)
+	}
+	return name, index, nil
+}
+
+// parseConstant parses a non-negative decimal integer
+func parseConstant(s string) (*big.Int, error) {
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok || v.Sign() < 0 {
+		return nil, fmt.Errorf("constant %q must be a non-negative integer", s)
+	}
+	return v, nil
+}
+
+func isName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
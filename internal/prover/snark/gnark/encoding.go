@@ -0,0 +1,70 @@
+package gnark
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// writeKey serializes a key, proof or witness to bytes
+func writeKey(w io.WriterTo) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if _, err := w.WriteTo(buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// readKey deserializes a key, proof or witness from bytes
+func readKey(r io.ReaderFrom, data []byte) error {
+	_, err := r.ReadFrom(bytes.NewReader(data))
+	return err
+}
+
+// encodeBinary wraps binary data as {"<field>": "<base64>"} so it can be
+// stored in JSONB columns and returned in API responses
+func encodeBinary(field string, data []byte) json.RawMessage {
+	encoded, _ := json.Marshal(map[string]string{
+		field: base64.StdEncoding.EncodeToString(data),
+	})
+	return encoded
+}
+
+// decodeBinary accepts the {"<field>": "<base64>"} shape produced by
+// encodeBinary, a bare base64 JSON string, or raw binary bytes
+func decodeBinary(field string, raw json.RawMessage) ([]byte, error) {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 {
+		return nil, fmt.Errorf("%s is empty", field)
+	}
+
+	switch trimmed[0] {
+	case '{':
+		var wrapped map[string]string
+		if err := json.Unmarshal(trimmed, &wrapped); err == nil {
+			encoded, ok := wrapped[field]
+			if !ok {
+				return nil, fmt.Errorf("missing %q field", field)
+			}
+			return base64.StdEncoding.DecodeString(encoded)
+		}
+	case '"':
+		var encoded string
+		if err := json.Unmarshal(trimmed, &encoded); err == nil {
+			return base64.StdEncoding.DecodeString(encoded)
+		}
+	}
+
+	return raw, nil
+}
+
+// decodeInto decodes raw with decodeBinary and deserializes it into r
+func decodeInto(r io.ReaderFrom, field string, raw json.RawMessage) error {
+	data, err := decodeBinary(field, raw)
+	if err != nil {
+		return err
+	}
+	return readKey(r, data)
+}
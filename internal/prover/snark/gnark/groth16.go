@@ -1,102 +1,216 @@
 package gnark
 
 import (
-	"bytes"
 	"context"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"time"
 
 	"github.com/consensys/gnark-crypto/ecc"
 	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
 	"github.com/consensys/gnark/frontend"
 	"github.com/consensys/gnark/frontend/cs/r1cs"
 	"github.com/gabrielrondon/zapiki/internal/models"
 	"github.com/gabrielrondon/zapiki/internal/prover"
+	"github.com/gabrielrondon/zapiki/internal/storage/artifact"
 )
 
 // Groth16Prover implements Groth16 SNARK proof system
 type Groth16Prover struct {
+	// curve is used when a request does not select one
 	curve ecc.ID
+	keys  *KeyCache
+
+	// artifacts holds keys persisted for user circuits
+	artifacts artifact.Store
+
+	// phase1 enables setup ceremonies (nil = unsupported)
+	phase1 Phase1Source
 }
 
-// NewGroth16Prover creates a new Groth16 prover
+// NewGroth16Prover creates a new Groth16 prover with a memory-only key cache
 func NewGroth16Prover() *Groth16Prover {
+	return NewGroth16ProverWithCache(NewKeyCache(nil))
+}
+
+// NewGroth16ProverWithCache creates a new Groth16 prover that shares keys through cache
+func NewGroth16ProverWithCache(cache *KeyCache) *Groth16Prover {
 	return &Groth16Prover{
 		curve: ecc.BN254, // BN254 curve (widely used, ~128-bit security)
+		keys:  cache,
 	}
 }
 
+// SetArtifactStore sets the store used to load circuit keys by URL
+func (p *Groth16Prover) SetArtifactStore(store artifact.Store) {
+	p.artifacts = store
+}
+
 // Name returns the proof system name
 func (p *Groth16Prover) Name() models.ProofSystemType {
 	return models.ProofSystemGroth16
 }
 
+// cacheKey returns the key cache entry identifier for a circuit instance
+func (p *Groth16Prover) cacheKey(curve ecc.ID, circuitType string, params map[string]interface{}) CacheKey {
+	return CacheKey{
+		System:      models.ProofSystemGroth16,
+		Curve:       curve,
+		CircuitType: circuitType,
+		Params:      params,
+		Version:     circuitVersion(circuitType),
+	}
+}
+
+// circuitKeys returns the compiled circuit and its keys, running setup only
+// the first time a circuit instance is seen
+func (p *Groth16Prover) circuitKeys(ctx context.Context, curve ecc.ID, circuitType string, params map[string]interface{}) (*CircuitKeys, error) {
+	return p.keys.get(ctx, p.cacheKey(curve, circuitType, params), keyBackend{
+		compile: func() (constraint.ConstraintSystem, error) {
+			return compileR1CS(curve, circuitType, params)
+		},
+		setup: func(ccs constraint.ConstraintSystem) (Key, Key, error) {
+			pk, vk, err := groth16.Setup(ccs)
+			return pk, vk, err
+		},
+		newKeys: func() (Key, Key) {
+			return groth16.NewProvingKey(curve), groth16.NewVerifyingKey(curve)
+		},
+	})
+}
+
+// compileR1CS compiles a circuit instance to the R1CS Groth16 proves over
+func compileR1CS(curve ecc.ID, circuitType string, params map[string]interface{}) (constraint.ConstraintSystem, error) {
+	circuitInstance, err := NewCircuit(circuitType, params)
+	if err != nil {
+		return nil, err
+	}
+	return frontend.Compile(curve.ScalarField(), r1cs.NewBuilder, circuitInstance)
+}
+
+// WarmUp runs setup for the given circuit types ahead of the first proof
+func (p *Groth16Prover) WarmUp(ctx context.Context, circuitTypes []string) error {
+	for _, circuitType := range circuitTypes {
+		params, err := circuitParams(circuitType, nil, nil)
+		if err != nil {
+			return fmt.Errorf("failed to warm up %s: %w", circuitType, err)
+		}
+		if _, err := p.circuitKeys(ctx, p.curve, circuitType, params); err != nil {
+			return fmt.Errorf("failed to warm up %s: %w", circuitType, err)
+		}
+	}
+	return nil
+}
+
+// VerificationKey returns the published verification key for a circuit type on curve
+func (p *Groth16Prover) VerificationKey(ctx context.Context, curve ecc.ID, circuitType string, params map[string]interface{}) (json.RawMessage, error) {
+	params, err := circuitParams(circuitType, params, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := p.circuitKeys(ctx, curve, circuitType, params)
+	if err != nil {
+		return nil, err
+	}
+
+	vkBytes, err := writeKey(keys.VerifyingKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize verification key: %w", err)
+	}
+
+	return encodeBinary("verification_key", vkBytes), nil
+}
+
 // Setup performs trusted setup for a circuit
 func (p *Groth16Prover) Setup(ctx context.Context, circuit *models.Circuit) (*prover.SetupResult, error) {
 	// Parse circuit definition to get circuit type
-	var circuitDef struct {
-		CircuitType string                 `json:"circuit_type"`
-		Params      map[string]interface{} `json:"params"`
-	}
-
+	var circuitDef circuitDefinition
 	if err := json.Unmarshal(circuit.CircuitDefinition, &circuitDef); err != nil {
 		return nil, fmt.Errorf("failed to parse circuit definition: %w", err)
 	}
 
-	// Get circuit instance
-	circuitInstance, err := GetCircuitByName(circuitDef.CircuitType)
+	// Imported circuits keep their constraint system in the artifact store
+	if err := circuitDef.loadCircom(ctx, p.artifacts); err != nil {
+		return nil, err
+	}
+
+	params, err := circuitParams(circuitDef.CircuitType, circuitDef.allParams(), nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get circuit: %w", err)
+		return nil, err
 	}
+	circuitDef.Params = params
 
-	// Compile circuit to R1CS
-	ccs, err := frontend.Compile(p.curve.ScalarField(), r1cs.NewBuilder, circuitInstance)
+	curve, err := resolveCurve("", circuitDef.Curve, p.curve)
 	if err != nil {
-		return nil, fmt.Errorf("failed to compile circuit: %w", err)
+		return nil, err
 	}
 
-	// Run trusted setup
-	pk, vk, err := groth16.Setup(ccs)
+	// Compile and run setup (or reuse the cached keys)
+	keys, err := p.circuitKeys(ctx, curve, circuitDef.CircuitType, circuitDef.Params)
 	if err != nil {
-		return nil, fmt.Errorf("failed to setup: %w", err)
+		return nil, err
 	}
 
 	// Serialize keys
-	pkBuf := new(bytes.Buffer)
-	if _, err := pk.WriteTo(pkBuf); err != nil {
+	pkBytes, err := writeKey(keys.ProvingKey)
+	if err != nil {
 		return nil, fmt.Errorf("failed to serialize proving key: %w", err)
 	}
 
-	vkBuf := new(bytes.Buffer)
-	if _, err := vk.WriteTo(vkBuf); err != nil {
+	vkBytes, err := writeKey(keys.VerifyingKey)
+	if err != nil {
 		return nil, fmt.Errorf("failed to serialize verification key: %w", err)
 	}
 
+	definition, err := resolvedDefinition(circuit.CircuitDefinition, circuitDef.CircuitType, circuitDef.Params)
+	if err != nil {
+		return nil, err
+	}
+
 	return &prover.SetupResult{
-		ProvingKey:      pkBuf.Bytes(),
-		VerificationKey: vkBuf.Bytes(),
+		ProvingKey:        encodeBinary("proving_key", pkBytes),
+		VerificationKey:   encodeBinary("verification_key", vkBytes),
+		CircuitDefinition: definition,
 		Metadata: map[string]interface{}{
-			"curve":       p.curve.String(),
-			"constraints": ccs.GetNbConstraints(),
-			"variables":   ccs.GetNbSecretVariables() + ccs.GetNbPublicVariables(),
+			"curve":       curve.String(),
+			"constraints": keys.CCS.GetNbConstraints(),
+			"variables":   keys.CCS.GetNbSecretVariables() + keys.CCS.GetNbPublicVariables(),
+			"key_id":      p.cacheKey(curve, circuitDef.CircuitType, circuitDef.Params).String(),
+			"params":      reportedParams(circuitDef.CircuitType, circuitDef.Params),
 		},
 	}, nil
 }
 
+// ValidateCircuit checks a circuit's definition before it is created
+func (p *Groth16Prover) ValidateCircuit(ctx context.Context, circuit *models.Circuit) error {
+	return validateCircuit(circuit)
+}
+
+// ImportCircuit imports a circuit compiled by Circom (see importCircom)
+func (p *Groth16Prover) ImportCircuit(ctx context.Context, circuit *models.Circuit, files map[string][]byte) error {
+	return importCircom(ctx, p.artifacts, circuit, files)
+}
+
+// DeleteImport removes the stored .r1cs of an imported circuit
+func (p *Groth16Prover) DeleteImport(ctx context.Context, circuit *models.Circuit) error {
+	return deleteCircom(ctx, p.artifacts, circuit)
+}
+
 // Generate creates a Groth16 proof
 func (p *Groth16Prover) Generate(ctx context.Context, req *prover.ProofRequest) (*prover.ProofResponse, error) {
 	startTime := time.Now()
 
 	// Parse circuit and input data
-	var circuitDef struct {
-		CircuitType string                 `json:"circuit_type"`
-		Params      map[string]interface{} `json:"params"`
-	}
+	var circuitDef circuitDefinition
+
+	// Stored keys were compiled from the circuit's definition alone
+	storedKeys := len(req.ProvingKey) == 0 && hasStoredKeys(p.artifacts, req.Circuit)
 
 	// Check for circuit_type in Options first (new method)
-	if req.Options != nil {
+	if req.Options != nil && !storedKeys {
 		if ct, ok := req.Options["circuit_type"].(string); ok {
 			circuitDef.CircuitType = ct
 		}
@@ -110,8 +224,8 @@ func (p *Groth16Prover) Generate(ctx context.Context, req *prover.ProofRequest)
 	}
 
 	// Parse input data first (needed for auto-detection)
-	var inputData map[string]interface{}
-	if err := json.Unmarshal(req.Data.Value, &inputData); err != nil {
+	inputData, err := decodeInputs(req.Data.Value)
+	if err != nil {
 		return nil, fmt.Errorf("failed to parse input data: %w", err)
 	}
 
@@ -120,48 +234,93 @@ func (p *Groth16Prover) Generate(ctx context.Context, req *prover.ProofRequest)
 		circuitDef.CircuitType = detectCircuitType(inputData)
 	}
 
-	// Default to simple circuit if still not detected
 	if circuitDef.CircuitType == "" {
-		circuitDef.CircuitType = "simple"
+		return nil, fmt.Errorf("%w: no circuit_type given and the inputs match no registered circuit", prover.ErrInvalidCircuit)
+	}
+	if _, err := lookupCircuitFor(p.Name(), circuitDef.CircuitType); err != nil {
+		return nil, err
 	}
 
-	// Get circuit instance and assign values
-	witness, err := p.createWitness(circuitDef.CircuitType, inputData)
+	if err := circuitDef.loadCircom(ctx, p.artifacts); err != nil {
+		return nil, err
+	}
+
+	// Resolve the circuit shape, falling back to the witness for missing
+	// params unless the shape is fixed by stored keys
+	fromWitness := inputData
+	if storedKeys {
+		fromWitness = nil
+	}
+	params, err := circuitParams(circuitDef.CircuitType, circuitDef.allParams(), fromWitness)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create witness: %w", err)
+		return nil, err
+	}
+	circuitDef.Params = params
+
+	// The circuit's curve wins over the options, which win over the default
+	circuitCurve, err := definitionCurve(req.Circuit)
+	if err != nil {
+		return nil, err
+	}
+	curve, err := resolveCurve(optionString(req.Options, "curve"), circuitCurve, p.curve)
+	if err != nil {
+		return nil, err
 	}
 
-	// Compile circuit
-	circuitInstance, _ := GetCircuitByName(circuitDef.CircuitType)
-	ccs, err := frontend.Compile(p.curve.ScalarField(), r1cs.NewBuilder, circuitInstance)
+	// Get circuit instance and assign values
+	witness, err := createWitness(curve, circuitDef.CircuitType, circuitDef.Params, inputData)
 	if err != nil {
-		return nil, fmt.Errorf("failed to compile circuit: %w", err)
+		return nil, fmt.Errorf("failed to create witness: %w", err)
 	}
 
-	// Check if we have proving key or need to generate it
+	// Use the caller's key pair, then the circuit's stored keys, then the cache
+	var ccs constraint.ConstraintSystem
 	var pk groth16.ProvingKey
 	var vk groth16.VerifyingKey
 
-	if req.ProvingKey != nil && len(req.ProvingKey) > 0 {
-		// Deserialize existing proving key
-		pkBuf := bytes.NewReader(req.ProvingKey)
-		pk = groth16.NewProvingKey(p.curve)
-		if _, err := pk.ReadFrom(pkBuf); err != nil {
+	provingKey, verificationKey := req.ProvingKey, req.VerificationKey
+	if len(provingKey) == 0 {
+		provingKey, verificationKey, err = loadCircuitKeys(ctx, p.artifacts, req.Circuit)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(provingKey) > 0 {
+		if len(verificationKey) == 0 {
+			return nil, fmt.Errorf("verification key is required when a proving key is supplied")
+		}
+
+		pk = groth16.NewProvingKey(curve)
+		if err := decodeInto(pk, "proving_key", provingKey); err != nil {
 			return nil, fmt.Errorf("failed to deserialize proving key: %w", err)
 		}
 
-		// We need vk too, run setup again (in real implementation, cache this)
-		_, vk, _ = groth16.Setup(ccs)
+		vk = groth16.NewVerifyingKey(curve)
+		if err := decodeInto(vk, "verification_key", verificationKey); err != nil {
+			return nil, fmt.Errorf("failed to deserialize verification key: %w", err)
+		}
+
+		circuitInstance, err := NewCircuit(circuitDef.CircuitType, circuitDef.Params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get circuit: %w", err)
+		}
+		ccs, err = frontend.Compile(curve.ScalarField(), r1cs.NewBuilder, circuitInstance)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile circuit: %w", err)
+		}
 	} else {
-		// Run setup (in production, this should be done once and cached)
-		pk, vk, err = groth16.Setup(ccs)
+		keys, err := p.circuitKeys(ctx, curve, circuitDef.CircuitType, circuitDef.Params)
 		if err != nil {
-			return nil, fmt.Errorf("failed to setup: %w", err)
+			return nil, err
 		}
+		ccs = keys.CCS
+		pk = keys.ProvingKey.(groth16.ProvingKey)
+		vk = keys.VerifyingKey.(groth16.VerifyingKey)
 	}
 
 	// Generate witness
-	fullWitness, err := frontend.NewWitness(witness, p.curve.ScalarField())
+	fullWitness, err := frontend.NewWitness(witness, curve.ScalarField())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create witness: %w", err)
 	}
@@ -173,14 +332,14 @@ func (p *Groth16Prover) Generate(ctx context.Context, req *prover.ProofRequest)
 	}
 
 	// Serialize proof
-	proofBuf := new(bytes.Buffer)
-	if _, err := proof.WriteTo(proofBuf); err != nil {
+	proofBytes, err := writeKey(proof)
+	if err != nil {
 		return nil, fmt.Errorf("failed to serialize proof: %w", err)
 	}
 
 	// Serialize verification key
-	vkBuf := new(bytes.Buffer)
-	if _, err := vk.WriteTo(vkBuf); err != nil {
+	vkBytes, err := writeKey(vk)
+	if err != nil {
 		return nil, fmt.Errorf("failed to serialize verification key: %w", err)
 	}
 
@@ -190,88 +349,151 @@ func (p *Groth16Prover) Generate(ctx context.Context, req *prover.ProofRequest)
 		return nil, fmt.Errorf("failed to extract public inputs: %w", err)
 	}
 
-	publicBuf := new(bytes.Buffer)
-	if _, err := publicWitness.WriteTo(publicBuf); err != nil {
+	publicBytes, err := writeKey(publicWitness)
+	if err != nil {
 		return nil, fmt.Errorf("failed to serialize public inputs: %w", err)
 	}
 
 	generationTime := time.Since(startTime).Milliseconds()
 
 	// Encode binary data as base64-encoded JSON for database storage
-	proofJSON, _ := json.Marshal(map[string]string{
-		"proof": base64.StdEncoding.EncodeToString(proofBuf.Bytes()),
-	})
-	publicInputsJSON, _ := json.Marshal(map[string]string{
-		"public_inputs": base64.StdEncoding.EncodeToString(publicBuf.Bytes()),
-	})
-	vkJSON, _ := json.Marshal(map[string]string{
-		"verification_key": base64.StdEncoding.EncodeToString(vkBuf.Bytes()),
-	})
-
 	return &prover.ProofResponse{
-		Proof:            json.RawMessage(proofJSON),
-		PublicInputs:     json.RawMessage(publicInputsJSON),
-		VerificationKey:  json.RawMessage(vkJSON),
+		Proof:            encodeBinary("proof", proofBytes),
+		PublicInputs:     encodeBinary("public_inputs", publicBytes),
+		VerificationKey:  encodeBinary("verification_key", vkBytes),
 		GenerationTimeMs: generationTime,
 		Metadata: map[string]interface{}{
 			"proof_system": "groth16",
-			"curve":        p.curve.String(),
+			"curve":        curve.String(),
 			"circuit_type": circuitDef.CircuitType,
+			"key_id":       p.cacheKey(curve, circuitDef.CircuitType, circuitDef.Params).String(),
+			"params":       reportedParams(circuitDef.CircuitType, circuitDef.Params),
 		},
+		NamedPublicInputs: namedPublicInputs(&circuitDef, witness, curve.ScalarField(), publicWitness),
 	}, nil
 }
 
 // Verify verifies a Groth16 proof
 func (p *Groth16Prover) Verify(ctx context.Context, req *prover.VerifyRequest) (*prover.VerifyResponse, error) {
-	// Deserialize verification key
-	vkBuf := bytes.NewReader(req.VerificationKey)
-	vk := groth16.NewVerifyingKey(p.curve)
-	if _, err := vk.ReadFrom(vkBuf); err != nil {
-		return &prover.VerifyResponse{
-			Valid:        false,
-			ErrorMessage: fmt.Sprintf("failed to deserialize verification key: %v", err),
-		}, nil
+	// Fall back to the circuit's stored key when none is supplied
+	verificationKey := req.VerificationKey
+	if len(verificationKey) == 0 {
+		var err error
+		verificationKey, err = loadVerificationKey(ctx, p.artifacts, req.VerificationKeyURL)
+		if err != nil {
+			return &prover.VerifyResponse{
+				Valid:        false,
+				ErrorMessage: err.Error(),
+			}, nil
+		}
 	}
 
-	// Deserialize proof
-	proofBuf := bytes.NewReader(req.Proof)
-	proof := groth16.NewProof(p.curve)
-	if _, err := proof.ReadFrom(proofBuf); err != nil {
+	curve, err := verifyCurve(req, formatCurve(req, p.curve))
+	if err != nil {
 		return &prover.VerifyResponse{
 			Valid:        false,
-			ErrorMessage: fmt.Sprintf("failed to deserialize proof: %v", err),
+			ErrorMessage: err.Error(),
 		}, nil
 	}
 
-	// Deserialize public inputs
-	publicBuf := bytes.NewReader(req.PublicInputs)
-	publicWitness, err := frontend.NewWitness(nil, p.curve.ScalarField())
+	// Deserialize verification key
+	vk, err := decodeGroth16Key(curve, keyFormat(req), verificationKey)
 	if err != nil {
 		return &prover.VerifyResponse{
 			Valid:        false,
-			ErrorMessage: fmt.Sprintf("failed to create witness: %v", err),
+			ErrorMessage: fmt.Sprintf("failed to deserialize verification key: %v", err),
 		}, nil
 	}
 
-	if _, err := publicWitness.ReadFrom(publicBuf); err != nil {
+	proof, publicWitness, err := decodeGroth16Proof(curve, req)
+	if err != nil {
 		return &prover.VerifyResponse{
 			Valid:        false,
-			ErrorMessage: fmt.Sprintf("failed to deserialize public inputs: %v", err),
+			ErrorMessage: err.Error(),
 		}, nil
 	}
 
-	// Verify proof
-	err = groth16.Verify(proof, vk, publicWitness)
+	return verifyGroth16(proof, vk, publicWitness), nil
+}
+
+// ExportProof converts a Groth16 proof, its public inputs and verifying key
+// to snarkjs JSON or arkworks' canonical serialization
+func (p *Groth16Prover) ExportProof(ctx context.Context, req *prover.VerifyRequest, format string) (*prover.ExportedProof, error) {
+	curve, err := verifyCurve(req, formatCurve(req, p.curve))
 	if err != nil {
+		return nil, err
+	}
+
+	proof, publicWitness, err := decodeGroth16Proof(curve, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Proofs without a circuit have no stored key to export
+	var vk groth16.VerifyingKey
+	verificationKey := req.VerificationKey
+	if len(verificationKey) == 0 && req.VerificationKeyURL != "" {
+		if verificationKey, err = loadVerificationKey(ctx, p.artifacts, req.VerificationKeyURL); err != nil {
+			return nil, err
+		}
+	}
+	if len(verificationKey) > 0 {
+		if vk, err = decodeGroth16Key(curve, keyFormat(req), verificationKey); err != nil {
+			return nil, fmt.Errorf("failed to deserialize verification key: %v", err)
+		}
+	}
+
+	return exportGroth16(curve, format, proof, publicWitness, vk)
+}
+
+// keyFormat is the format of a verify request's key: the request's format
+// for a key it carries, gnark's for a stored key
+func keyFormat(req *prover.VerifyRequest) string {
+	if len(req.VerificationKey) == 0 {
+		return prover.FormatGnark
+	}
+	return req.Format
+}
+
+// decodeGroth16Proof deserializes a verify request's proof and public
+// inputs, converting them from the request's format
+func decodeGroth16Proof(curve ecc.ID, req *prover.VerifyRequest) (groth16.Proof, witness.Witness, error) {
+	if foreignFormat(req.Format) {
+		return decodeForeignProof(curve, req)
+	}
+
+	proof := groth16.NewProof(curve)
+	if err := decodeInto(proof, "proof", req.Proof); err != nil {
+		return nil, nil, fmt.Errorf("failed to deserialize proof: %v", err)
+	}
+
+	publicWitness, err := frontend.NewWitness(nil, curve.ScalarField())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create witness: %v", err)
+	}
+	if err := decodeInto(publicWitness, "public_inputs", req.PublicInputs); err != nil {
+		return nil, nil, fmt.Errorf("failed to deserialize public inputs: %v", err)
+	}
+	return proof, publicWitness, nil
+}
+
+// verifyGroth16 verifies one decoded proof
+func verifyGroth16(proof groth16.Proof, vk groth16.VerifyingKey, publicWitness witness.Witness) *prover.VerifyResponse {
+	if err := groth16.Verify(proof, vk, publicWitness); err != nil {
 		return &prover.VerifyResponse{
 			Valid:        false,
 			ErrorMessage: fmt.Sprintf("verification failed: %v", err),
-		}, nil
+		}
 	}
 
 	return &prover.VerifyResponse{
 		Valid: true,
-	}, nil
+	}
+}
+
+// Circuits lists the registered circuit types the prover can prove
+func (p *Groth16Prover) Circuits() []prover.CircuitInfo {
+	return circuitInfos(p.Name())
 }
 
 // Capabilities returns Groth16 capabilities
@@ -283,6 +505,8 @@ func (p *Groth16Prover) Capabilities() prover.Capabilities {
 		AsyncOnly:              true, // Groth16 proof generation can take seconds
 		TypicalGenerationTime:  30000, // ~30 seconds for medium circuits
 		MaxProofSize:           1024,  // ~1KB proof size
+		Curves:                 CurveNames(),
+		Library:                "github.com/consensys/gnark",
 		Features: []string{
 			"zero-knowledge",
 			"succinct-proofs",
@@ -292,143 +516,55 @@ func (p *Groth16Prover) Capabilities() prover.Capabilities {
 	}
 }
 
-// createWitness creates a witness from input data
-// Helper function to convert interface{} to int, handling both float64 and int
-func toInt(v interface{}) int {
-	switch val := v.(type) {
-	case float64:
-		return int(val)
-	case int:
-		return val
-	case int64:
-		return int(val)
-	default:
-		return 0
+// hashPreimageWitness builds a hash_preimage assignment
+func hashPreimageWitness(params map[string]interface{}, in *Inputs) (frontend.Circuit, error) {
+	fn, err := hashParam(params)
+	if err != nil {
+		return nil, err
 	}
-}
-
-func (p *Groth16Prover) createWitness(circuitType string, inputData map[string]interface{}) (frontend.Circuit, error) {
-	switch circuitType {
-	case "simple":
-		return &SimpleCircuit{
-			X: toInt(inputData["x"]),
-			Y: toInt(inputData["y"]),
-			Z: toInt(inputData["z"]),
-		}, nil
-
-	case "age_verification":
-		return &AgeVerificationCircuit{
-			Age:     toInt(inputData["age"]),
-			MinAge:  toInt(inputData["min_age"]),
-			IsAdult: toInt(inputData["is_adult"]),
-		}, nil
-
-	case "range_proof":
-		return &RangeProofCircuit{
-			Value:   toInt(inputData["value"]),
-			Min:     toInt(inputData["min"]),
-			Max:     toInt(inputData["max"]),
-			InRange: toInt(inputData["in_range"]),
-		}, nil
 
-	// AML/KYC Compliance circuits
-	case "aml_age_verification":
-		return &AMLAgeVerificationCircuit{
-			MinimumAge:  toInt(inputData["minimum_age"]),
-			CurrentYear: toInt(inputData["current_year"]),
-			BirthYear:   toInt(inputData["birth_year"]),
-			Nonce:       toInt(inputData["nonce"]),
-		}, nil
-
-	case "aml_sanctions_check":
-		return &AMLSanctionsCheckCircuit{
-			SanctionsListRoot: toInt(inputData["sanctions_list_root"]),
-			CurrentTimestamp:  toInt(inputData["current_timestamp"]),
-			UserIdentifier:    toInt(inputData["user_identifier"]),
-		}, nil
-
-	case "aml_residency_proof":
-		return &AMLResidencyProofCircuit{
-			AllowedCountryCode: toInt(inputData["allowed_country_code"]),
-			CurrentTimestamp:   toInt(inputData["current_timestamp"]),
-			UserCountryCode:    toInt(inputData["user_country_code"]),
-			AddressHash:        toInt(inputData["address_hash"]),
-		}, nil
-
-	case "aml_income_verification":
-		return &AMLIncomeVerificationCircuit{
-			MinimumIncome:    toInt(inputData["minimum_income"]),
-			CurrentTimestamp: toInt(inputData["current_timestamp"]),
-			ActualIncome:     toInt(inputData["actual_income"]),
-			IncomeSourceHash: toInt(inputData["income_source_hash"]),
-		}, nil
-
-	default:
-		return &SimpleCircuit{
-			X: 3,
-			Y: 5,
-			Z: 15,
-		}, nil
-	}
+	return &HashPreimageCircuit{
+		Preimage: in.Field("preimage"),
+		Hash:     in.Field("hash_value"),
+		HashFunc: fn,
+	}, in.Err()
 }
 
-// detectCircuitType automatically detects the circuit type based on input fields
-func detectCircuitType(inputData map[string]interface{}) string {
-	// AML Age Verification: has minimum_age, current_year, birth_year
-	if _, hasMinAge := inputData["minimum_age"]; hasMinAge {
-		if _, hasCurYear := inputData["current_year"]; hasCurYear {
-			if _, hasBirthYear := inputData["birth_year"]; hasBirthYear {
-				return "aml_age_verification"
-			}
-		}
-	}
-
-	// AML Sanctions Check: has sanctions_list_root, user_identifier
-	if _, hasSanctions := inputData["sanctions_list_root"]; hasSanctions {
-		if _, hasUser := inputData["user_identifier"]; hasUser {
-			return "aml_sanctions_check"
-		}
-	}
-
-	// AML Residency: has allowed_country_code, user_country_code
-	if _, hasAllowed := inputData["allowed_country_code"]; hasAllowed {
-		if _, hasUser := inputData["user_country_code"]; hasUser {
-			return "aml_residency_proof"
-		}
-	}
-
-	// AML Income: has minimum_income, actual_income
-	if _, hasMinIncome := inputData["minimum_income"]; hasMinIncome {
-		if _, hasActual := inputData["actual_income"]; hasActual {
-			return "aml_income_verification"
-		}
-	}
-
-	// Old age verification: has age, min_age, is_adult
-	if _, hasAge := inputData["age"]; hasAge {
-		if _, hasMinAge := inputData["min_age"]; hasMinAge {
-			return "age_verification"
-		}
-	}
-
-	// Range proof: has value, min, max
-	if _, hasValue := inputData["value"]; hasValue {
-		if _, hasMin := inputData["min"]; hasMin {
-			if _, hasMax := inputData["max"]; hasMax {
-				return "range_proof"
-			}
-		}
+// merkleProofWitness builds a merkle_proof assignment
+func merkleProofWitness(params map[string]interface{}, in *Inputs) (frontend.Circuit, error) {
+	depth, fn, err := treeParams(params)
+	if err != nil {
+		return nil, err
 	}
 
-	// Simple circuit: has x, y, z
-	if _, hasX := inputData["x"]; hasX {
-		if _, hasY := inputData["y"]; hasY {
-			if _, hasZ := inputData["z"]; hasZ {
-				return "simple"
-			}
-		}
-	}
+	return &MerkleProofCircuit{
+		Leaf:       in.Field("leaf"),
+		Root:       in.Field("root"),
+		Path:       in.Fields("path", depth),
+		Directions: in.Fields("directions", depth),
+		HashFunc:   fn,
+	}, in.Err()
+}
 
-	// Unknown - return empty string
-	return ""
+// sanctionsWitness builds an aml_sanctions_check assignment from a
+// non-membership witness (see circuits.NonMembershipProof.Inputs)
+func sanctionsWitness(params map[string]interface{}, in *Inputs) (frontend.Circuit, error) {
+	depth, fn, err := treeParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AMLSanctionsCheckCircuit{
+		SanctionsListRoot:    in.Field("sanctions_list_root"),
+		CurrentTimestamp:     in.Field("current_timestamp"),
+		IdentifierCommitment: in.Field("identifier_commitment"),
+		UserIdentifier:       in.Field("user_identifier"),
+		IdentifierSalt:       in.Field("identifier_salt"),
+		LowLeaf:              in.Field("low_leaf"),
+		HighLeaf:             in.Field("high_leaf"),
+		LowIndex:             in.Field("low_index"),
+		LowPath:              in.Fields("low_path", depth),
+		HighPath:             in.Fields("high_path", depth),
+		Hash:                 fn,
+	}, in.Err()
 }
@@ -3,6 +3,7 @@ package gnark
 import (
 	"context"
 	"encoding/json"
+	"reflect"
 	"testing"
 
 	"github.com/gabrielrondon/zapiki/internal/models"
@@ -117,6 +118,12 @@ func TestGroth16Prover_AgeVerification(t *testing.T) {
 
 	t.Logf("Proof generated in %dms", resp.GenerationTimeMs)
 
+	// Public inputs are named as the circuit type lists them
+	want := []models.NamedPublicInput{{Name: "min_age", Value: "18"}, {Name: "is_adult", Value: "1"}}
+	if !reflect.DeepEqual(resp.NamedPublicInputs, want) {
+		t.Errorf("Expected named public inputs %v, got %v", want, resp.NamedPublicInputs)
+	}
+
 	// Verify proof
 	verifyReq := &prover.VerifyRequest{
 		Proof:           resp.Proof,
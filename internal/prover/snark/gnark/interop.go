@@ -0,0 +1,531 @@
+package gnark
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bls12-381"
+	fp_bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381/fp"
+	fr_bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	fp_bn254 "github.com/consensys/gnark-crypto/ecc/bn254/fp"
+	fr_bn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/backend/groth16"
+	groth16bls12381 "github.com/consensys/gnark/backend/groth16/bls12-381"
+	groth16bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/gabrielrondon/zapiki/internal/prover"
+)
+
+// Groth16 proofs and keys are converted to other tools' formats through
+// their affine point coordinates. Points are read out of gnark's
+// uncompressed encoding and imported points are written back through it,
+// so gnark-crypto checks that every imported point is on the curve and in
+// the right subgroup. PLONK proofs are not converted: snarkjs implements a
+// different PLONK variant and arkworks has none, so a converted proof could
+// not be verified by either.
+
+// affineG1 is an affine G1 point; the point at infinity is (0, 0)
+type affineG1 [2]*big.Int
+
+// affineG2 is an affine G2 point whose coordinates are [c0, c1] for c0 + c1·u
+type affineG2 [2][2]*big.Int
+
+// groth16ProofPoints is a Groth16 proof as its points A, B and C
+type groth16ProofPoints struct {
+	A affineG1
+	B affineG2
+	C affineG1
+}
+
+// groth16KeyPoints is a Groth16 verifying key as points. IC[0] is the
+// constant term of the public input commitment and IC[i] the term of
+// public input i (gnark's K).
+type groth16KeyPoints struct {
+	Alpha              affineG1
+	Beta, Gamma, Delta affineG2
+	IC                 []affineG1
+}
+
+// curvePoint is a gnark-crypto point of either group
+type curvePoint interface {
+	Marshal() []byte
+	Unmarshal(buf []byte) error
+	SetBytes(buf []byte) (int, error)
+}
+
+// interopCurve describes a curve whose Groth16 proofs convert to other
+// formats
+type interopCurve struct {
+	id ecc.ID
+	// snarkjs is the curve's name in snarkjs files
+	snarkjs string
+	fpBytes int
+	frBytes int
+	fp, fr  *big.Int
+	// mask covers the metadata bits gnark sets in a point's first byte
+	mask byte
+	// zcash is set when arkworks serializes the curve's points in the
+	// zcash encoding, which is also gnark's, rather than its default one
+	zcash bool
+	newG1 func() curvePoint
+	newG2 func() curvePoint
+}
+
+// interopCurves are the curves both snarkjs and arkworks implement
+var interopCurves = map[ecc.ID]*interopCurve{
+	ecc.BN254: {
+		id:      ecc.BN254,
+		snarkjs: "bn128",
+		fpBytes: fp_bn254.Bytes,
+		frBytes: fr_bn254.Bytes,
+		fp:      fp_bn254.Modulus(),
+		fr:      fr_bn254.Modulus(),
+		mask:    0b11 << 6,
+		newG1:   func() curvePoint { return new(bn254.G1Affine) },
+		newG2:   func() curvePoint { return new(bn254.G2Affine) },
+	},
+	ecc.BLS12_381: {
+		id:      ecc.BLS12_381,
+		snarkjs: "bls12381",
+		fpBytes: fp_bls12381.Bytes,
+		frBytes: fr_bls12381.Bytes,
+		fp:      fp_bls12381.Modulus(),
+		fr:      fr_bls12381.Modulus(),
+		mask:    0b111 << 5,
+		zcash:   true,
+		newG1:   func() curvePoint { return new(bls12381.G1Affine) },
+		newG2:   func() curvePoint { return new(bls12381.G2Affine) },
+	},
+}
+
+// interopCurveFor returns the curve's description, or an error wrapping
+// ErrFormatUnsupported for curves other tools do not implement
+func interopCurveFor(curve ecc.ID, format string) (*interopCurve, error) {
+	c, ok := interopCurves[curve]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s proofs on %s (supported curves: bn254, bls12_381)", prover.ErrFormatUnsupported, format, curve)
+	}
+	return c, nil
+}
+
+// g1FromRaw reads a point from gnark's uncompressed encoding, X | Y
+func (c *interopCurve) g1FromRaw(raw []byte) affineG1 {
+	n := c.fpBytes
+	return affineG1{c.coordinate(raw[:n], true), c.coordinate(raw[n:2*n], false)}
+}
+
+// g2FromRaw reads a point from gnark's uncompressed encoding,
+// X.A1 | X.A0 | Y.A1 | Y.A0
+func (c *interopCurve) g2FromRaw(raw []byte) affineG2 {
+	n := c.fpBytes
+	return affineG2{
+		{c.coordinate(raw[n:2*n], false), c.coordinate(raw[:n], true)},
+		{c.coordinate(raw[3*n:4*n], false), c.coordinate(raw[2*n:3*n], false)},
+	}
+}
+
+// coordinate reads a big-endian coordinate, clearing gnark's metadata bits
+// from the first coordinate of a point
+func (c *interopCurve) coordinate(b []byte, first bool) *big.Int {
+	if first {
+		b = append([]byte(nil), b...)
+		b[0] &^= c.mask
+	}
+	return new(big.Int).SetBytes(b)
+}
+
+// g1Raw writes a point in gnark's uncompressed encoding
+func (c *interopCurve) g1Raw(p affineG1) ([]byte, error) {
+	return c.raw(p[0], p[1])
+}
+
+// g2Raw writes a point in gnark's uncompressed encoding
+func (c *interopCurve) g2Raw(p affineG2) ([]byte, error) {
+	return c.raw(p[0][1], p[0][0], p[1][1], p[1][0])
+}
+
+// raw concatenates big-endian coordinates, checking they are field elements
+func (c *interopCurve) raw(coordinates ...*big.Int) ([]byte, error) {
+	n := c.fpBytes
+	raw := make([]byte, n*len(coordinates))
+	for i, v := range coordinates {
+		if v == nil || v.Sign() < 0 || v.Cmp(c.fp) >= 0 {
+			return nil, fmt.Errorf("coordinate %v is not a base field element", v)
+		}
+		v.FillBytes(raw[i*n : (i+1)*n])
+	}
+	return raw, nil
+}
+
+// largest reports whether y is greater than -y, comparing integers
+func (c *interopCurve) largest(y *big.Int) bool {
+	neg := new(big.Int).Sub(c.fp, y)
+	return y.Sign() != 0 && y.Cmp(neg) > 0
+}
+
+// largestE2 reports whether y is greater than -y, comparing c1 first as
+// gnark and arkworks order extension field elements
+func (c *interopCurve) largestE2(y [2]*big.Int) bool {
+	if y[1].Sign() != 0 {
+		return c.largest(y[1])
+	}
+	return c.largest(y[0])
+}
+
+// setPoints writes points in gnark's uncompressed encoding into gnark
+// points, which checks them
+func setPoints(raws [][]byte, points ...curvePoint) error {
+	for i, point := range points {
+		if err := point.Unmarshal(raws[i]); err != nil {
+			return fmt.Errorf("invalid point: %w", err)
+		}
+	}
+	return nil
+}
+
+// marshalPoints returns the gnark encoding of each point of a slice
+func marshalPoints[T any, P interface {
+	*T
+	curvePoint
+}](points []T) [][]byte {
+	raws := make([][]byte, len(points))
+	for i := range points {
+		raws[i] = P(&points[i]).Marshal()
+	}
+	return raws
+}
+
+// pointRefs returns a gnark point reference to each point of a slice
+func pointRefs[T any, P interface {
+	*T
+	curvePoint
+}](points []T) []curvePoint {
+	refs := make([]curvePoint, len(points))
+	for i := range points {
+		refs[i] = P(&points[i])
+	}
+	return refs
+}
+
+// proofPoints reads a gnark Groth16 proof's points
+func (c *interopCurve) proofPoints(proof groth16.Proof) (*groth16ProofPoints, error) {
+	var a, b, krs curvePoint
+	var commitments int
+	switch proof := proof.(type) {
+	case *groth16bn254.Proof:
+		a, b, krs, commitments = &proof.Ar, &proof.Bs, &proof.Krs, len(proof.Commitments)
+	case *groth16bls12381.Proof:
+		a, b, krs, commitments = &proof.Ar, &proof.Bs, &proof.Krs, len(proof.Commitments)
+	default:
+		return nil, fmt.Errorf("%w: proof type %T", prover.ErrFormatUnsupported, proof)
+	}
+	if commitments > 0 {
+		return nil, fmt.Errorf("%w: proofs of circuits with commitments", prover.ErrFormatUnsupported)
+	}
+
+	return &groth16ProofPoints{
+		A: c.g1FromRaw(a.Marshal()),
+		B: c.g2FromRaw(b.Marshal()),
+		C: c.g1FromRaw(krs.Marshal()),
+	}, nil
+}
+
+// newProof builds a gnark Groth16 proof from its points
+func (c *interopCurve) newProof(points *groth16ProofPoints) (groth16.Proof, error) {
+	raws := make([][]byte, 3)
+	var err error
+	if raws[0], err = c.g1Raw(points.A); err != nil {
+		return nil, err
+	}
+	if raws[1], err = c.g2Raw(points.B); err != nil {
+		return nil, err
+	}
+	if raws[2], err = c.g1Raw(points.C); err != nil {
+		return nil, err
+	}
+
+	proof := groth16.NewProof(c.id)
+	switch proof := proof.(type) {
+	case *groth16bn254.Proof:
+		err = setPoints(raws, &proof.Ar, &proof.Bs, &proof.Krs)
+	case *groth16bls12381.Proof:
+		err = setPoints(raws, &proof.Ar, &proof.Bs, &proof.Krs)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return proof, nil
+}
+
+// keyPoints reads a gnark Groth16 verifying key's points
+func (c *interopCurve) keyPoints(vk groth16.VerifyingKey) (*groth16KeyPoints, error) {
+	var alpha, beta, gamma, delta curvePoint
+	var k [][]byte
+	var commitments int
+	switch vk := vk.(type) {
+	case *groth16bn254.VerifyingKey:
+		alpha, beta, gamma, delta = &vk.G1.Alpha, &vk.G2.Beta, &vk.G2.Gamma, &vk.G2.Delta
+		k, commitments = marshalPoints(vk.G1.K), len(vk.CommitmentKeys)
+	case *groth16bls12381.VerifyingKey:
+		alpha, beta, gamma, delta = &vk.G1.Alpha, &vk.G2.Beta, &vk.G2.Gamma, &vk.G2.Delta
+		k, commitments = marshalPoints(vk.G1.K), len(vk.CommitmentKeys)
+	default:
+		return nil, fmt.Errorf("%w: verifying key type %T", prover.ErrFormatUnsupported, vk)
+	}
+	if commitments > 0 {
+		return nil, fmt.Errorf("%w: verifying keys of circuits with commitments", prover.ErrFormatUnsupported)
+	}
+
+	points := &groth16KeyPoints{
+		Alpha: c.g1FromRaw(alpha.Marshal()),
+		Beta:  c.g2FromRaw(beta.Marshal()),
+		Gamma: c.g2FromRaw(gamma.Marshal()),
+		Delta: c.g2FromRaw(delta.Marshal()),
+	}
+	for _, raw := range k {
+		points.IC = append(points.IC, c.g1FromRaw(raw))
+	}
+	return points, nil
+}
+
+// newKey builds a gnark Groth16 verifying key from its points
+func (c *interopCurve) newKey(points *groth16KeyPoints) (groth16.VerifyingKey, error) {
+	if len(points.IC) == 0 {
+		return nil, fmt.Errorf("verifying key has no IC points")
+	}
+
+	alpha, err := c.g1Raw(points.Alpha)
+	if err != nil {
+		return nil, err
+	}
+	raws := [][]byte{alpha}
+	for _, p := range []affineG2{points.Beta, points.Gamma, points.Delta} {
+		raw, err := c.g2Raw(p)
+		if err != nil {
+			return nil, err
+		}
+		raws = append(raws, raw)
+	}
+	for _, p := range points.IC {
+		raw, err := c.g1Raw(p)
+		if err != nil {
+			return nil, err
+		}
+		raws = append(raws, raw)
+	}
+
+	vk := groth16.NewVerifyingKey(c.id)
+	switch vk := vk.(type) {
+	case *groth16bn254.VerifyingKey:
+		vk.G1.K = make([]bn254.G1Affine, len(points.IC))
+		refs := append([]curvePoint{&vk.G1.Alpha, &vk.G2.Beta, &vk.G2.Gamma, &vk.G2.Delta}, pointRefs(vk.G1.K)...)
+		if err = setPoints(raws, refs...); err == nil {
+			err = vk.Precompute()
+		}
+	case *groth16bls12381.VerifyingKey:
+		vk.G1.K = make([]bls12381.G1Affine, len(points.IC))
+		refs := append([]curvePoint{&vk.G1.Alpha, &vk.G2.Beta, &vk.G2.Gamma, &vk.G2.Delta}, pointRefs(vk.G1.K)...)
+		if err = setPoints(raws, refs...); err == nil {
+			err = vk.Precompute()
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return vk, nil
+}
+
+// publicInputs reads a public witness as integers
+func (c *interopCurve) publicInputs(w witness.Witness) ([]*big.Int, error) {
+	inputs, err := publicInputStrings(w)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]*big.Int, len(inputs))
+	for i, input := range inputs {
+		values[i], _ = new(big.Int).SetString(input, 10)
+	}
+	return values, nil
+}
+
+// newPublicWitness builds a public witness from integers
+func (c *interopCurve) newPublicWitness(values []*big.Int) (witness.Witness, error) {
+	ch := make(chan any, len(values))
+	for i, v := range values {
+		if v.Sign() < 0 || v.Cmp(c.fr) >= 0 {
+			return nil, fmt.Errorf("public input %d is not a scalar field element", i)
+		}
+		ch <- v
+	}
+	close(ch)
+
+	w, err := witness.New(c.id.ScalarField())
+	if err != nil {
+		return nil, err
+	}
+	if err := w.Fill(len(values), 0, ch); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// checkFormat rejects formats Groth16 proofs cannot be converted to
+func checkFormat(format string) error {
+	switch format {
+	case "", prover.FormatGnark, prover.FormatSnarkJS, prover.FormatArkworks:
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown format %q (supported: %s, %s, %s)", prover.ErrFormatUnsupported, format, prover.FormatGnark, prover.FormatSnarkJS, prover.FormatArkworks)
+	}
+}
+
+// foreignFormat reports whether format is another tool's format
+func foreignFormat(format string) bool {
+	return format != "" && format != prover.FormatGnark
+}
+
+// formatCurve is the curve a verify request is on when it names none and
+// has no circuit: snarkjs proofs name their curve, others use fallback
+func formatCurve(req *prover.VerifyRequest, fallback ecc.ID) ecc.ID {
+	if req.Format == prover.FormatSnarkJS {
+		if id, ok := snarkjsCurve(req.Proof); ok {
+			return id
+		}
+	}
+	return fallback
+}
+
+// decodeGroth16Key deserializes a verifying key encoded in format
+func decodeGroth16Key(curve ecc.ID, format string, raw json.RawMessage) (groth16.VerifyingKey, error) {
+	if err := checkFormat(format); err != nil {
+		return nil, err
+	}
+	if !foreignFormat(format) {
+		vk := groth16.NewVerifyingKey(curve)
+		if err := decodeInto(vk, "verification_key", raw); err != nil {
+			return nil, err
+		}
+		return vk, nil
+	}
+
+	c, err := interopCurveFor(curve, format)
+	if err != nil {
+		return nil, err
+	}
+	var points *groth16KeyPoints
+	if format == prover.FormatSnarkJS {
+		points, err = c.parseSnarkJSKey(raw)
+	} else {
+		var data []byte
+		if data, err = decodeBinary("verification_key", raw); err == nil {
+			points, err = c.parseArkworksKey(data)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c.newKey(points)
+}
+
+// decodeForeignProof deserializes a proof and public inputs in another
+// tool's format
+func decodeForeignProof(curve ecc.ID, req *prover.VerifyRequest) (groth16.Proof, witness.Witness, error) {
+	if err := checkFormat(req.Format); err != nil {
+		return nil, nil, err
+	}
+	c, err := interopCurveFor(curve, req.Format)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var points *groth16ProofPoints
+	var inputs []*big.Int
+	if req.Format == prover.FormatSnarkJS {
+		if points, err = c.parseSnarkJSProof(req.Proof); err != nil {
+			return nil, nil, err
+		}
+		if inputs, err = parseSnarkJSPublic(req.PublicInputs); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		data, err := decodeBinary("proof", req.Proof)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to deserialize proof: %v", err)
+		}
+		if points, err = c.parseArkworksProof(data); err != nil {
+			return nil, nil, err
+		}
+		if data, err = decodeBinary("public_inputs", req.PublicInputs); err != nil {
+			return nil, nil, fmt.Errorf("failed to deserialize public inputs: %v", err)
+		}
+		if inputs, err = c.parseArkworksPublic(data); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	proof, err := c.newProof(points)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid %s proof: %w", req.Format, err)
+	}
+	publicWitness, err := c.newPublicWitness(inputs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid %s public inputs: %w", req.Format, err)
+	}
+	return proof, publicWitness, nil
+}
+
+// exportGroth16 converts a decoded proof, its public inputs and, when not
+// nil, its verifying key to format
+func exportGroth16(curve ecc.ID, format string, proof groth16.Proof, publicWitness witness.Witness, vk groth16.VerifyingKey) (*prover.ExportedProof, error) {
+	if err := checkFormat(format); err != nil {
+		return nil, err
+	}
+	if !foreignFormat(format) {
+		return nil, fmt.Errorf("%w: proofs are already in %s format", prover.ErrFormatUnsupported, prover.FormatGnark)
+	}
+	c, err := interopCurveFor(curve, format)
+	if err != nil {
+		return nil, err
+	}
+
+	proofPoints, err := c.proofPoints(proof)
+	if err != nil {
+		return nil, err
+	}
+	inputs, err := c.publicInputs(publicWitness)
+	if err != nil {
+		return nil, err
+	}
+	var keyPoints *groth16KeyPoints
+	if vk != nil {
+		if keyPoints, err = c.keyPoints(vk); err != nil {
+			return nil, err
+		}
+	}
+
+	exported := &prover.ExportedProof{Format: format}
+	if format == prover.FormatSnarkJS {
+		if exported.Proof, err = c.snarkjsProofJSON(proofPoints); err != nil {
+			return nil, err
+		}
+		if exported.PublicInputs, err = snarkjsPublicJSON(inputs); err != nil {
+			return nil, err
+		}
+		if keyPoints != nil {
+			if exported.VerificationKey, err = c.snarkjsKeyJSON(keyPoints); err != nil {
+				return nil, err
+			}
+		}
+		return exported, nil
+	}
+
+	exported.Proof = encodeBinary("proof", c.arkworksProof(proofPoints))
+	exported.PublicInputs = encodeBinary("public_inputs", c.arkworksPublic(inputs))
+	if keyPoints != nil {
+		exported.VerificationKey = encodeBinary("verification_key", c.arkworksKey(keyPoints))
+	}
+	return exported, nil
+}
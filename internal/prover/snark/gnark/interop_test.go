@@ -0,0 +1,205 @@
+package gnark
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/gabrielrondon/zapiki/internal/prover"
+)
+
+func TestGroth16_ExportProofRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	p := NewGroth16Prover()
+
+	for _, curve := range []string{"bn254", "bls12_381"} {
+		resp := simpleProof(t, p, curve, 3, 5)
+
+		for _, format := range []string{prover.FormatSnarkJS, prover.FormatArkworks} {
+			exported, err := p.ExportProof(ctx, &prover.VerifyRequest{
+				Proof:           resp.Proof,
+				PublicInputs:    resp.PublicInputs,
+				VerificationKey: resp.VerificationKey,
+				Curve:           curve,
+			}, format)
+			if err != nil {
+				t.Fatalf("%s/%s: failed to export proof: %v", curve, format, err)
+			}
+			if exported.Format != format || len(exported.VerificationKey) == 0 {
+				t.Fatalf("%s/%s: unexpected export %+v", curve, format, exported)
+			}
+
+			// snarkjs files name their curve, so the request needs none
+			req := &prover.VerifyRequest{
+				Proof:           exported.Proof,
+				PublicInputs:    exported.PublicInputs,
+				VerificationKey: exported.VerificationKey,
+				Format:          format,
+			}
+			if format == prover.FormatArkworks {
+				req.Curve = curve
+			}
+			verifyResp, err := p.Verify(ctx, req)
+			if err != nil {
+				t.Fatalf("%s/%s: failed to verify proof: %v", curve, format, err)
+			}
+			if !verifyResp.Valid {
+				t.Errorf("%s/%s: expected the exported proof to verify: %s", curve, format, verifyResp.ErrorMessage)
+			}
+		}
+	}
+}
+
+func TestGroth16_ExportProofSnarkJS(t *testing.T) {
+	ctx := context.Background()
+	p := NewGroth16Prover()
+	resp := simpleProof(t, p, "bn254", 3, 5)
+
+	exported, err := p.ExportProof(ctx, &prover.VerifyRequest{
+		Proof:           resp.Proof,
+		PublicInputs:    resp.PublicInputs,
+		VerificationKey: resp.VerificationKey,
+	}, prover.FormatSnarkJS)
+	if err != nil {
+		t.Fatalf("Failed to export proof: %v", err)
+	}
+
+	var proof snarkjsProof
+	var key snarkjsKey
+	var public []string
+	if err := json.Unmarshal(exported.Proof, &proof); err != nil {
+		t.Fatalf("Failed to parse proof.json: %v", err)
+	}
+	if err := json.Unmarshal(exported.VerificationKey, &key); err != nil {
+		t.Fatalf("Failed to parse verification_key.json: %v", err)
+	}
+	if err := json.Unmarshal(exported.PublicInputs, &public); err != nil {
+		t.Fatalf("Failed to parse public.json: %v", err)
+	}
+
+	if proof.Protocol != "groth16" || proof.Curve != "bn128" || key.Curve != "bn128" {
+		t.Errorf("Expected a groth16 bn128 proof, got %s %s", proof.Protocol, proof.Curve)
+	}
+	if key.NPublic != 1 || len(key.IC) != 2 {
+		t.Errorf("Expected one public input, got nPublic %d with %d IC points", key.NPublic, len(key.IC))
+	}
+	if len(public) != 1 || public[0] != "15" {
+		t.Errorf("Expected public inputs [15], got %v", public)
+	}
+
+	// Replay snarkjs's check e(-A, B)·e(α, β)·e(L, γ)·e(C, δ) = 1 on the
+	// JSON coordinates, G2 elements being [c0, c1]
+	g1 := func(c []string) bn254.G1Affine {
+		var p bn254.G1Affine
+		p.X.SetString(c[0])
+		p.Y.SetString(c[1])
+		return p
+	}
+	g2 := func(c [][]string) bn254.G2Affine {
+		var p bn254.G2Affine
+		p.X.A0.SetString(c[0][0])
+		p.X.A1.SetString(c[0][1])
+		p.Y.A0.SetString(c[1][0])
+		p.Y.A1.SetString(c[1][1])
+		return p
+	}
+
+	var l, term bn254.G1Affine
+	l = g1(key.IC[0])
+	ic1 := g1(key.IC[1])
+	term.ScalarMultiplication(&ic1, big.NewInt(15))
+	l.Add(&l, &term)
+
+	a := g1(proof.A)
+	a.Neg(&a)
+	ok, err := bn254.PairingCheck(
+		[]bn254.G1Affine{a, g1(key.Alpha), l, g1(proof.C)},
+		[]bn254.G2Affine{g2(proof.B), g2(key.Beta), g2(key.Gamma), g2(key.Delta)},
+	)
+	if err != nil || !ok {
+		t.Errorf("Expected the snarkjs pairing check to pass (err %v)", err)
+	}
+
+	// Other statements do not verify
+	verifyResp, err := p.Verify(ctx, &prover.VerifyRequest{
+		Proof:           exported.Proof,
+		PublicInputs:    json.RawMessage(`["16"]`),
+		VerificationKey: exported.VerificationKey,
+		Format:          prover.FormatSnarkJS,
+	})
+	if err != nil {
+		t.Fatalf("Failed to verify proof: %v", err)
+	}
+	if verifyResp.Valid {
+		t.Error("Expected the proof not to verify against other public inputs")
+	}
+}
+
+func TestArkworksPoints(t *testing.T) {
+	// The BN254 G1 generator (1, 2): x little-endian, y is the smaller root
+	bn := interopCurves[ecc.BN254]
+	compressed := make([]byte, 32)
+	compressed[0] = 1
+	uncompressed := make([]byte, 64)
+	uncompressed[0], uncompressed[32] = 1, 2
+
+	for name, data := range map[string][]byte{"compressed": compressed, "uncompressed": uncompressed} {
+		r := &arkworksReader{c: bn, data: data, compressed: name == "compressed"}
+		p := r.g1()
+		if r.err != nil {
+			t.Fatalf("bn254 %s: failed to read point: %v", name, r.err)
+		}
+		if p[0].Int64() != 1 || p[1].Int64() != 2 {
+			t.Errorf("bn254 %s: expected (1, 2), got (%s, %s)", name, p[0], p[1])
+		}
+	}
+	if got := bn.arkworksG1(affineG1{big.NewInt(1), big.NewInt(2)}); hex.EncodeToString(got) != hex.EncodeToString(compressed) {
+		t.Errorf("bn254: expected generator %x, got %x", compressed, got)
+	}
+
+	// The BLS12-381 G1 generator in the zcash encoding
+	bls := interopCurves[ecc.BLS12_381]
+	generator, _ := hex.DecodeString("97f1d3a73197d7942695638c4fa9ac0fc3688c4f9774b905a14e3a3f171bac586c55e83ff97a1aeffb3af00adb22c6bb")
+	r := &arkworksReader{c: bls, data: generator, compressed: true}
+	p := r.g1()
+	if r.err != nil {
+		t.Fatalf("bls12_381: failed to read point: %v", r.err)
+	}
+	_, _, g1, _ := bls12381.Generators()
+	if p[0].Cmp(g1.X.BigInt(new(big.Int))) != 0 || p[1].Cmp(g1.Y.BigInt(new(big.Int))) != 0 {
+		t.Error("bls12_381: expected the generator")
+	}
+	if got := bls.arkworksG1(p); hex.EncodeToString(got) != hex.EncodeToString(generator) {
+		t.Errorf("bls12_381: expected generator %x, got %x", generator, got)
+	}
+
+	// Points off the curve are rejected
+	uncompressed[32] = 3
+	r = &arkworksReader{c: bn, data: uncompressed}
+	if r.g1(); r.err == nil {
+		t.Error("Expected (1, 3) to be rejected")
+	}
+}
+
+func TestGroth16_ExportProofRejectsUnsupported(t *testing.T) {
+	ctx := context.Background()
+	p := NewGroth16Prover()
+	resp := simpleProof(t, p, "bls12_377", 3, 5)
+
+	req := &prover.VerifyRequest{
+		Proof:        resp.Proof,
+		PublicInputs: resp.PublicInputs,
+		Curve:        "bls12_377",
+	}
+	for _, format := range []string{prover.FormatSnarkJS, "zokrates", prover.FormatGnark} {
+		if _, err := p.ExportProof(ctx, req, format); !errors.Is(err, prover.ErrFormatUnsupported) {
+			t.Errorf("%s: expected ErrFormatUnsupported, got %v", format, err)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package gnark
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/constraint"
+	"github.com/gabrielrondon/zapiki/internal/models"
+	"golang.org/x/sync/singleflight"
+)
+
+// ErrKeysNotFound is returned by a KeyStore when no keys exist for an ID
+var ErrKeysNotFound = errors.New("keys not found")
+
+// KeyStore persists serialized proving and verifying keys so that setup
+// output survives restarts and is shared between the API and the workers
+type KeyStore interface {
+	// Load returns the serialized keys stored under id, or ErrKeysNotFound
+	Load(ctx context.Context, id string) (pk []byte, vk []byte, err error)
+
+	// Save stores the serialized keys under id
+	Save(ctx context.Context, id string, pk []byte, vk []byte) error
+}
+
+// Key is a proving or verifying key that can be (de)serialized
+type Key interface {
+	io.WriterTo
+	io.ReaderFrom
+}
+
+// CacheKey identifies a compiled circuit instance and its keys
+type CacheKey struct {
+	System      models.ProofSystemType
+	Curve       ecc.ID
+	CircuitType string
+	Params      map[string]interface{}
+
+	// Version is the circuit type's registered version, so that keys of
+	// an older version of a circuit are never reused
+	Version int
+
+	// SRS identifies the reference string universal setups were run with
+	SRS string
+}
+
+// String returns a stable identifier, safe to use as a file name
+func (k CacheKey) String() string {
+	// encoding/json sorts map keys, so equal params always hash the same
+	params, _ := json.Marshal(k.Params)
+	sum := sha256.Sum256(params)
+
+	id := fmt.Sprintf("%s-%s-%s-%s",
+		k.System,
+		strings.ToLower(k.Curve.String()),
+		k.CircuitType,
+		hex.EncodeToString(sum[:8]),
+	)
+	// Version 1 keys keep the identifiers they were stored under
+	if k.Version > 1 {
+		id += fmt.Sprintf("-v%d", k.Version)
+	}
+	if k.SRS != "" {
+		id += "-" + k.SRS
+	}
+	return id
+}
+
+// CircuitKeys holds a compiled constraint system and the keys produced by setup
+type CircuitKeys struct {
+	CCS          constraint.ConstraintSystem
+	ProvingKey   Key
+	VerifyingKey Key
+}
+
+// keyBackend captures the proof-system specific steps the cache needs
+type keyBackend struct {
+	compile func() (constraint.ConstraintSystem, error)
+	setup   func(ccs constraint.ConstraintSystem) (Key, Key, error)
+	newKeys func() (Key, Key)
+}
+
+// KeyCache caches compiled circuits and their keys, keyed by proof system,
+// curve, circuit type and params. Setup runs at most once per key: concurrent
+// callers share a single in-flight setup, and when a KeyStore is configured
+// the keys are loaded from it instead of re-running setup after a restart.
+type KeyCache struct {
+	mu      sync.RWMutex
+	entries map[string]*CircuitKeys
+	group   singleflight.Group
+	store   KeyStore
+}
+
+// NewKeyCache creates a key cache. store may be nil for a memory-only cache.
+func NewKeyCache(store KeyStore) *KeyCache {
+	return &KeyCache{
+		entries: make(map[string]*CircuitKeys),
+		store:   store,
+	}
+}
+
+// Len returns the number of cached circuit instances
+func (c *KeyCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.entries)
+}
+
+// get returns the keys for key, compiling and running setup when needed
+func (c *KeyCache) get(ctx context.Context, key CacheKey, backend keyBackend) (*CircuitKeys, error) {
+	id := key.String()
+
+	if keys := c.lookup(id); keys != nil {
+		return keys, nil
+	}
+
+	result, err, _ := c.group.Do(id, func() (interface{}, error) {
+		// Another caller may have finished while we were waiting
+		if keys := c.lookup(id); keys != nil {
+			return keys, nil
+		}
+
+		ccs, err := backend.compile()
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile circuit: %w", err)
+		}
+
+		keys, err := c.loadOrSetup(ctx, id, ccs, backend)
+		if err != nil {
+			return nil, err
+		}
+
+		c.mu.Lock()
+		c.entries[id] = keys
+		c.mu.Unlock()
+
+		return keys, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*CircuitKeys), nil
+}
+
+func (c *KeyCache) lookup(id string) *CircuitKeys {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.entries[id]
+}
+
+// loadOrSetup loads persisted keys for id, or runs setup and persists the result
+func (c *KeyCache) loadOrSetup(ctx context.Context, id string, ccs constraint.ConstraintSystem, backend keyBackend) (*CircuitKeys, error) {
+	if c.store != nil {
+		pkBytes, vkBytes, err := c.store.Load(ctx, id)
+		switch {
+		case err == nil:
+			pk, vk := backend.newKeys()
+			if err := readKey(pk, pkBytes); err != nil {
+				return nil, fmt.Errorf("failed to deserialize stored proving key: %w", err)
+			}
+			if err := readKey(vk, vkBytes); err != nil {
+				return nil, fmt.Errorf("failed to deserialize stored verification key: %w", err)
+			}
+			return &CircuitKeys{CCS: ccs, ProvingKey: pk, VerifyingKey: vk}, nil
+		case !errors.Is(err, ErrKeysNotFound):
+			return nil, fmt.Errorf("failed to load keys: %w", err)
+		}
+	}
+
+	pk, vk, err := backend.setup(ccs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup: %w", err)
+	}
+
+	if c.store != nil {
+		pkBytes, err := writeKey(pk)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize proving key: %w", err)
+		}
+		vkBytes, err := writeKey(vk)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize verification key: %w", err)
+		}
+		if err := c.store.Save(ctx, id, pkBytes, vkBytes); err != nil {
+			return nil, fmt.Errorf("failed to persist keys: %w", err)
+		}
+	}
+
+	return &CircuitKeys{CCS: ccs, ProvingKey: pk, VerifyingKey: vk}, nil
+}
+
+// FileKeyStore is a KeyStore backed by a local directory
+type FileKeyStore struct {
+	dir string
+}
+
+// NewFileKeyStore creates a file key store rooted at dir, creating it if needed
+func NewFileKeyStore(dir string) (*FileKeyStore, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create key directory: %w", err)
+	}
+	return &FileKeyStore{dir: dir}, nil
+}
+
+// Load reads the keys stored under id
+func (s *FileKeyStore) Load(ctx context.Context, id string) ([]byte, []byte, error) {
+	pk, err := os.ReadFile(s.path(id, "pk"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, ErrKeysNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	vk, err := os.ReadFile(s.path(id, "vk"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, ErrKeysNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return pk, vk, nil
+}
+
+// Save writes the keys under id. The verifying key is written last so a
+// partially written pair is never reported as present.
+func (s *FileKeyStore) Save(ctx context.Context, id string, pk []byte, vk []byte) error {
+	if err := writeFileAtomic(s.path(id, "pk"), pk); err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path(id, "vk"), vk)
+}
+
+func (s *FileKeyStore) path(id, ext string) string {
+	return filepath.Join(s.dir, filepath.Base(id)+"."+ext)
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
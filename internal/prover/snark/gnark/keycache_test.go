@@ -0,0 +1,200 @@
+package gnark
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/gabrielrondon/zapiki/internal/models"
+	"github.com/gabrielrondon/zapiki/internal/prover"
+)
+
+func simpleProofRequest(t *testing.T) *prover.ProofRequest {
+	t.Helper()
+
+	inputJSON, _ := json.Marshal(map[string]interface{}{"x": 3, "y": 5, "z": 15})
+	circuitDefJSON, _ := json.Marshal(map[string]interface{}{"circuit_type": "simple"})
+
+	return &prover.ProofRequest{
+		Circuit: &models.Circuit{CircuitDefinition: circuitDefJSON},
+		Data: &models.InputData{
+			Type:  models.DataTypeJSON,
+			Value: inputJSON,
+		},
+	}
+}
+
+// countingBackend returns a Groth16 backend that counts setup runs
+func countingBackend(p *Groth16Prover, setups *int32) keyBackend {
+	return keyBackend{
+		compile: func() (constraint.ConstraintSystem, error) {
+			return frontend.Compile(p.curve.ScalarField(), r1cs.NewBuilder, &SimpleCircuit{})
+		},
+		setup: func(ccs constraint.ConstraintSystem) (Key, Key, error) {
+			atomic.AddInt32(setups, 1)
+			pk, vk, err := groth16.Setup(ccs)
+			return pk, vk, err
+		},
+		newKeys: func() (Key, Key) {
+			return groth16.NewProvingKey(p.curve), groth16.NewVerifyingKey(p.curve)
+		},
+	}
+}
+
+func TestGroth16Prover_StableVerificationKey(t *testing.T) {
+	p := NewGroth16Prover()
+	ctx := context.Background()
+
+	first, err := p.Generate(ctx, simpleProofRequest(t))
+	if err != nil {
+		t.Fatalf("Failed to generate first proof: %v", err)
+	}
+
+	second, err := p.Generate(ctx, simpleProofRequest(t))
+	if err != nil {
+		t.Fatalf("Failed to generate second proof: %v", err)
+	}
+
+	if !bytes.Equal(first.VerificationKey, second.VerificationKey) {
+		t.Fatal("Expected both proofs to share one verification key")
+	}
+
+	published, err := p.VerificationKey(ctx, DefaultCurve, "simple", nil)
+	if err != nil {
+		t.Fatalf("Failed to get published verification key: %v", err)
+	}
+
+	for i, resp := range []*prover.ProofResponse{first, second} {
+		verifyResp, err := p.Verify(ctx, &prover.VerifyRequest{
+			Proof:           resp.Proof,
+			VerificationKey: published,
+			PublicInputs:    resp.PublicInputs,
+		})
+		if err != nil {
+			t.Fatalf("Failed to verify proof %d: %v", i, err)
+		}
+		if !verifyResp.Valid {
+			t.Errorf("Expected proof %d to verify against the published key, got: %v", i, verifyResp.ErrorMessage)
+		}
+	}
+}
+
+func TestGroth16Prover_SuppliedProvingKeyRequiresVerificationKey(t *testing.T) {
+	p := NewGroth16Prover()
+	ctx := context.Background()
+
+	circuitDefJSON, _ := json.Marshal(map[string]interface{}{"circuit_type": "simple"})
+	setup, err := p.Setup(ctx, &models.Circuit{CircuitDefinition: circuitDefJSON})
+	if err != nil {
+		t.Fatalf("Failed to setup: %v", err)
+	}
+
+	req := simpleProofRequest(t)
+	req.ProvingKey = setup.ProvingKey
+	if _, err := p.Generate(ctx, req); err == nil {
+		t.Fatal("Expected an error when the verification key is missing")
+	}
+
+	req.VerificationKey = setup.VerificationKey
+	resp, err := p.Generate(ctx, req)
+	if err != nil {
+		t.Fatalf("Failed to generate proof with supplied keys: %v", err)
+	}
+
+	if !bytes.Equal(resp.VerificationKey, setup.VerificationKey) {
+		t.Error("Expected the supplied verification key to be returned")
+	}
+}
+
+func TestKeyCache_SingleSetupUnderConcurrency(t *testing.T) {
+	p := NewGroth16Prover()
+	cache := NewKeyCache(nil)
+	key := p.cacheKey(DefaultCurve, "simple", nil)
+
+	var setups int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := cache.get(context.Background(), key, countingBackend(p, &setups)); err != nil {
+				t.Errorf("Failed to get keys: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if setups != 1 {
+		t.Errorf("Expected setup to run once, ran %d times", setups)
+	}
+	if cache.Len() != 1 {
+		t.Errorf("Expected 1 cached entry, got %d", cache.Len())
+	}
+}
+
+func TestKeyCache_PersistsAcrossRestarts(t *testing.T) {
+	p := NewGroth16Prover()
+	key := p.cacheKey(DefaultCurve, "simple", nil)
+	ctx := context.Background()
+
+	store, err := NewFileKeyStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create key store: %v", err)
+	}
+
+	var setups int32
+	before, err := NewKeyCache(store).get(ctx, key, countingBackend(p, &setups))
+	if err != nil {
+		t.Fatalf("Failed to get keys: %v", err)
+	}
+
+	// A fresh cache simulates a restarted worker
+	after, err := NewKeyCache(store).get(ctx, key, countingBackend(p, &setups))
+	if err != nil {
+		t.Fatalf("Failed to reload keys: %v", err)
+	}
+
+	if setups != 1 {
+		t.Errorf("Expected setup to run once across restarts, ran %d times", setups)
+	}
+
+	beforeVK, _ := writeKey(before.VerifyingKey)
+	afterVK, _ := writeKey(after.VerifyingKey)
+	if !bytes.Equal(beforeVK, afterVK) {
+		t.Error("Expected the reloaded verification key to match")
+	}
+}
+
+func TestCacheKey_String(t *testing.T) {
+	p := NewGroth16Prover()
+
+	a := p.cacheKey(DefaultCurve, "merkle_proof", map[string]interface{}{"depth": 4, "hash": "mimc"})
+	b := p.cacheKey(DefaultCurve, "merkle_proof", map[string]interface{}{"hash": "mimc", "depth": 4})
+	c := p.cacheKey(DefaultCurve, "merkle_proof", map[string]interface{}{"depth": 8, "hash": "mimc"})
+
+	if a.String() != b.String() {
+		t.Errorf("Expected params order not to matter: %s != %s", a, b)
+	}
+	if a.String() == c.String() {
+		t.Error("Expected different params to produce different keys")
+	}
+
+	d := p.cacheKey(ecc.BLS12_381, "merkle_proof", map[string]interface{}{"depth": 4, "hash": "mimc"})
+	if a.String() == d.String() {
+		t.Error("Expected different curves to produce different keys")
+	}
+
+	e := a
+	e.Version = a.Version + 1
+	if a.String() == e.String() {
+		t.Error("Expected different circuit versions to produce different keys")
+	}
+}
@@ -1,7 +1,6 @@
 package gnark
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -9,148 +8,282 @@ import (
 
 	"github.com/consensys/gnark-crypto/ecc"
 	"github.com/consensys/gnark/backend/plonk"
+	"github.com/consensys/gnark/constraint"
 	"github.com/consensys/gnark/frontend"
 	"github.com/consensys/gnark/frontend/cs/scs"
 	"github.com/gabrielrondon/zapiki/internal/models"
 	"github.com/gabrielrondon/zapiki/internal/prover"
+	"github.com/gabrielrondon/zapiki/internal/storage/artifact"
 )
 
 // PLONKProver implements PLONK SNARK proof system
 type PLONKProver struct {
+	// curve is used when a request does not select one
 	curve ecc.ID
-	// Universal SRS (Structured Reference String) - can be reused across circuits
-	srs plonk.ProvingKey
+	keys  *KeyCache
+
+	// srs supplies the KZG reference string for setup
+	srs SRSProvider
+
+	// artifacts holds keys persisted for user circuits
+	artifacts artifact.Store
 }
 
-// NewPLONKProver creates a new PLONK prover
+// NewPLONKProver creates a new PLONK prover with a memory-only key cache
 func NewPLONKProver() *PLONKProver {
+	return NewPLONKProverWithCache(NewKeyCache(nil))
+}
+
+// NewPLONKProverWithCache creates a new PLONK prover that shares keys through cache
+func NewPLONKProverWithCache(cache *KeyCache) *PLONKProver {
 	return &PLONKProver{
 		curve: ecc.BN254, // BN254 curve (same as Groth16)
+		keys:  cache,
+		srs:   NewDevSRS(),
 	}
 }
 
+// SetSRS sets the KZG reference string used for setup. Keys already cached
+// under a different SRS are not reused.
+func (p *PLONKProver) SetSRS(srs SRSProvider) {
+	p.srs = srs
+}
+
+// SetArtifactStore sets the store used to load circuit keys by URL
+func (p *PLONKProver) SetArtifactStore(store artifact.Store) {
+	p.artifacts = store
+}
+
 // Name returns the proof system name
 func (p *PLONKProver) Name() models.ProofSystemType {
 	return models.ProofSystemPLONK
 }
 
+// cacheKey returns the key cache entry identifier for a circuit instance
+func (p *PLONKProver) cacheKey(curve ecc.ID, circuitType string, params map[string]interface{}) CacheKey {
+	return CacheKey{
+		System:      models.ProofSystemPLONK,
+		Curve:       curve,
+		CircuitType: circuitType,
+		Params:      params,
+		Version:     circuitVersion(circuitType),
+		SRS:         p.srs.ID(curve),
+	}
+}
+
+// circuitKeys returns the compiled circuit and its keys, running setup only
+// the first time a circuit instance is seen
+func (p *PLONKProver) circuitKeys(ctx context.Context, curve ecc.ID, circuitType string, params map[string]interface{}) (*CircuitKeys, error) {
+	return p.keys.get(ctx, p.cacheKey(curve, circuitType, params), keyBackend{
+		compile: func() (constraint.ConstraintSystem, error) {
+			circuitInstance, err := NewCircuit(circuitType, params)
+			if err != nil {
+				return nil, err
+			}
+			// Compile circuit to SCS (Sparse Constraint System - used by PLONK)
+			return frontend.Compile(curve.ScalarField(), scs.NewBuilder, circuitInstance)
+		},
+		setup: func(ccs constraint.ConstraintSystem) (Key, Key, error) {
+			// PLONK uses a universal SRS, trimmed to the circuit size
+			srs, srsLagrange, err := p.srs.SRS(ccs)
+			if err != nil {
+				return nil, nil, err
+			}
+			pk, vk, err := plonk.Setup(ccs, srs, srsLagrange)
+			return pk, vk, err
+		},
+		newKeys: func() (Key, Key) {
+			return plonk.NewProvingKey(curve), plonk.NewVerifyingKey(curve)
+		},
+	})
+}
+
+// WarmUp runs setup for the given circuit types ahead of the first proof
+func (p *PLONKProver) WarmUp(ctx context.Context, circuitTypes []string) error {
+	for _, circuitType := range circuitTypes {
+		params, err := circuitParams(circuitType, nil, nil)
+		if err != nil {
+			return fmt.Errorf("failed to warm up %s: %w", circuitType, err)
+		}
+		if _, err := p.circuitKeys(ctx, p.curve, circuitType, params); err != nil {
+			return fmt.Errorf("failed to warm up %s: %w", circuitType, err)
+		}
+	}
+	return nil
+}
+
 // Setup performs universal setup (or circuit-specific compilation)
 func (p *PLONKProver) Setup(ctx context.Context, circuit *models.Circuit) (*prover.SetupResult, error) {
 	// Parse circuit definition
-	var circuitDef struct {
-		CircuitType string                 `json:"circuit_type"`
-		Params      map[string]interface{} `json:"params"`
-	}
-
+	var circuitDef circuitDefinition
 	if err := json.Unmarshal(circuit.CircuitDefinition, &circuitDef); err != nil {
 		return nil, fmt.Errorf("failed to parse circuit definition: %w", err)
 	}
 
-	// Get circuit instance
-	circuitInstance, err := GetCircuitByName(circuitDef.CircuitType)
+	params, err := circuitParams(circuitDef.CircuitType, circuitDef.allParams(), nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get circuit: %w", err)
+		return nil, err
 	}
+	circuitDef.Params = params
 
-	// Compile circuit to SCS (Sparse Constraint System - used by PLONK)
-	ccs, err := frontend.Compile(p.curve.ScalarField(), scs.NewBuilder, circuitInstance)
+	curve, err := resolveCurve("", circuitDef.Curve, p.curve)
 	if err != nil {
-		return nil, fmt.Errorf("failed to compile circuit: %w", err)
+		return nil, err
 	}
 
-	// Setup PLONK (generates proving and verification keys)
-	// Note: PLONK uses a universal SRS
-	// For simplicity, we use the dummy setup (in production, use real trusted setup ceremony)
-	pk, vk, err := plonk.Setup(ccs, nil, nil)
+	// Compile and run setup (or reuse the cached keys)
+	keys, err := p.circuitKeys(ctx, curve, circuitDef.CircuitType, circuitDef.Params)
 	if err != nil {
-		return nil, fmt.Errorf("failed to setup: %w", err)
+		return nil, err
 	}
 
 	// Serialize keys
-	pkBuf := new(bytes.Buffer)
-	if _, err := pk.WriteTo(pkBuf); err != nil {
+	pkBytes, err := writeKey(keys.ProvingKey)
+	if err != nil {
 		return nil, fmt.Errorf("failed to serialize proving key: %w", err)
 	}
 
-	vkBuf := new(bytes.Buffer)
-	if _, err := vk.WriteTo(vkBuf); err != nil {
+	vkBytes, err := writeKey(keys.VerifyingKey)
+	if err != nil {
 		return nil, fmt.Errorf("failed to serialize verification key: %w", err)
 	}
 
+	definition, err := resolvedDefinition(circuit.CircuitDefinition, circuitDef.CircuitType, circuitDef.Params)
+	if err != nil {
+		return nil, err
+	}
+
 	return &prover.SetupResult{
-		ProvingKey:      pkBuf.Bytes(),
-		VerificationKey: vkBuf.Bytes(),
+		ProvingKey:        encodeBinary("proving_key", pkBytes),
+		VerificationKey:   encodeBinary("verification_key", vkBytes),
+		CircuitDefinition: definition,
 		Metadata: map[string]interface{}{
-			"curve":       p.curve.String(),
-			"constraints": ccs.GetNbConstraints(),
-			"variables":   ccs.GetNbSecretVariables() + ccs.GetNbPublicVariables(),
+			"curve":       curve.String(),
+			"constraints": keys.CCS.GetNbConstraints(),
+			"variables":   keys.CCS.GetNbSecretVariables() + keys.CCS.GetNbPublicVariables(),
 			"setup_type":  "universal", // PLONK's key advantage
+			"srs":         p.srs.ID(curve),
+			"key_id":      p.cacheKey(curve, circuitDef.CircuitType, circuitDef.Params).String(),
+			"params":      reportedParams(circuitDef.CircuitType, circuitDef.Params),
 		},
 	}, nil
 }
 
+// ValidateCircuit checks a circuit's definition before it is created
+func (p *PLONKProver) ValidateCircuit(ctx context.Context, circuit *models.Circuit) error {
+	return validateCircuit(circuit)
+}
+
 // Generate creates a PLONK proof
 func (p *PLONKProver) Generate(ctx context.Context, req *prover.ProofRequest) (*prover.ProofResponse, error) {
 	startTime := time.Now()
 
-	// Parse circuit definition
-	var circuitDef struct {
-		CircuitType string                 `json:"circuit_type"`
-		Params      map[string]interface{} `json:"params"`
-	}
+	// Stored keys were compiled from the circuit's definition alone
+	storedKeys := len(req.ProvingKey) == 0 && hasStoredKeys(p.artifacts, req.Circuit)
 
+	// Parse circuit definition
+	var circuitDef circuitDefinition
 	if req.Circuit != nil && req.Circuit.CircuitDefinition != nil {
 		if err := json.Unmarshal(req.Circuit.CircuitDefinition, &circuitDef); err != nil {
 			return nil, fmt.Errorf("failed to parse circuit definition: %w", err)
 		}
-	} else {
-		// Default to simple circuit
-		circuitDef.CircuitType = "simple"
+	} else if ct := optionString(req.Options, "circuit_type"); ct != "" {
+		circuitDef.CircuitType = ct
 	}
 
 	// Parse input data
-	var inputData map[string]interface{}
-	if err := json.Unmarshal(req.Data.Value, &inputData); err != nil {
+	inputData, err := decodeInputs(req.Data.Value)
+	if err != nil {
 		return nil, fmt.Errorf("failed to parse input data: %w", err)
 	}
 
-	// Create witness
-	witness, err := p.createWitness(circuitDef.CircuitType, inputData)
+	// Without a circuit, detect the type from the input fields
+	if circuitDef.CircuitType == "" {
+		circuitDef.CircuitType = detectCircuitType(inputData)
+	}
+	if circuitDef.CircuitType == "" {
+		return nil, fmt.Errorf("%w: no circuit_type given and the inputs match no registered circuit", prover.ErrInvalidCircuit)
+	}
+	if _, err := lookupCircuitFor(p.Name(), circuitDef.CircuitType); err != nil {
+		return nil, err
+	}
+
+	// Resolve the circuit shape, falling back to the witness for missing
+	// params unless the shape is fixed by stored keys
+	fromWitness := inputData
+	if storedKeys {
+		fromWitness = nil
+	}
+	params, err := circuitParams(circuitDef.CircuitType, circuitDef.allParams(), fromWitness)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create witness: %w", err)
+		return nil, err
 	}
+	circuitDef.Params = params
 
-	// Compile circuit
-	circuitInstance, _ := GetCircuitByName(circuitDef.CircuitType)
-	ccs, err := frontend.Compile(p.curve.ScalarField(), scs.NewBuilder, circuitInstance)
+	// The circuit's curve wins over the options, which win over the default
+	circuitCurve, err := definitionCurve(req.Circuit)
 	if err != nil {
-		return nil, fmt.Errorf("failed to compile circuit: %w", err)
+		return nil, err
+	}
+	curve, err := resolveCurve(optionString(req.Options, "curve"), circuitCurve, p.curve)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create witness
+	witness, err := createWitness(curve, circuitDef.CircuitType, circuitDef.Params, inputData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create witness: %w", err)
 	}
 
-	// Setup or load keys
+	// Use the caller's key pair, then the circuit's stored keys, then the cache
+	var ccs constraint.ConstraintSystem
 	var pk plonk.ProvingKey
 	var vk plonk.VerifyingKey
 
-	if req.ProvingKey != nil && len(req.ProvingKey) > 0 {
-		// Deserialize existing proving key
-		pkBuf := bytes.NewReader(req.ProvingKey)
-		pk = plonk.NewProvingKey(p.curve)
-		if _, err := pk.ReadFrom(pkBuf); err != nil {
+	provingKey, verificationKey := req.ProvingKey, req.VerificationKey
+	if len(provingKey) == 0 {
+		provingKey, verificationKey, err = loadCircuitKeys(ctx, p.artifacts, req.Circuit)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(provingKey) > 0 {
+		if len(verificationKey) == 0 {
+			return nil, fmt.Errorf("verification key is required when a proving key is supplied")
+		}
+
+		pk = plonk.NewProvingKey(curve)
+		if err := decodeInto(pk, "proving_key", provingKey); err != nil {
 			return nil, fmt.Errorf("failed to deserialize proving key: %w", err)
 		}
 
-		// Need vk too, run setup again (in real implementation, cache this)
-		_, vk, _ = plonk.Setup(ccs, nil, nil)
+		vk = plonk.NewVerifyingKey(curve)
+		if err := decodeInto(vk, "verification_key", verificationKey); err != nil {
+			return nil, fmt.Errorf("failed to deserialize verification key: %w", err)
+		}
+
+		circuitInstance, err := NewCircuit(circuitDef.CircuitType, circuitDef.Params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get circuit: %w", err)
+		}
+		ccs, err = frontend.Compile(curve.ScalarField(), scs.NewBuilder, circuitInstance)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile circuit: %w", err)
+		}
 	} else {
-		// Run setup
-		pk, vk, err = plonk.Setup(ccs, nil, nil)
+		keys, err := p.circuitKeys(ctx, curve, circuitDef.CircuitType, circuitDef.Params)
 		if err != nil {
-			return nil, fmt.Errorf("failed to setup: %w", err)
+			return nil, err
 		}
+		ccs = keys.CCS
+		pk = keys.ProvingKey.(plonk.ProvingKey)
+		vk = keys.VerifyingKey.(plonk.VerifyingKey)
 	}
 
 	// Generate witness
-	fullWitness, err := frontend.NewWitness(witness, p.curve.ScalarField())
+	fullWitness, err := frontend.NewWitness(witness, curve.ScalarField())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create witness: %w", err)
 	}
@@ -162,14 +295,14 @@ func (p *PLONKProver) Generate(ctx context.Context, req *prover.ProofRequest) (*
 	}
 
 	// Serialize proof
-	proofBuf := new(bytes.Buffer)
-	if _, err := proof.WriteTo(proofBuf); err != nil {
+	proofBytes, err := writeKey(proof)
+	if err != nil {
 		return nil, fmt.Errorf("failed to serialize proof: %w", err)
 	}
 
 	// Serialize verification key
-	vkBuf := new(bytes.Buffer)
-	if _, err := vk.WriteTo(vkBuf); err != nil {
+	vkBytes, err := writeKey(vk)
+	if err != nil {
 		return nil, fmt.Errorf("failed to serialize verification key: %w", err)
 	}
 
@@ -179,33 +312,56 @@ func (p *PLONKProver) Generate(ctx context.Context, req *prover.ProofRequest) (*
 		return nil, fmt.Errorf("failed to extract public inputs: %w", err)
 	}
 
-	publicBuf := new(bytes.Buffer)
-	if _, err := publicWitness.WriteTo(publicBuf); err != nil {
+	publicBytes, err := writeKey(publicWitness)
+	if err != nil {
 		return nil, fmt.Errorf("failed to serialize public inputs: %w", err)
 	}
 
 	generationTime := time.Since(startTime).Milliseconds()
 
 	return &prover.ProofResponse{
-		Proof:            proofBuf.Bytes(),
-		PublicInputs:     publicBuf.Bytes(),
-		VerificationKey:  vkBuf.Bytes(),
+		Proof:            encodeBinary("proof", proofBytes),
+		PublicInputs:     encodeBinary("public_inputs", publicBytes),
+		VerificationKey:  encodeBinary("verification_key", vkBytes),
 		GenerationTimeMs: generationTime,
 		Metadata: map[string]interface{}{
 			"proof_system": "plonk",
-			"curve":        p.curve.String(),
+			"curve":        curve.String(),
 			"circuit_type": circuitDef.CircuitType,
 			"setup_type":   "universal",
+			"key_id":       p.cacheKey(curve, circuitDef.CircuitType, circuitDef.Params).String(),
+			"params":       reportedParams(circuitDef.CircuitType, circuitDef.Params),
 		},
+		NamedPublicInputs: namedPublicInputs(&circuitDef, witness, curve.ScalarField(), publicWitness),
 	}, nil
 }
 
 // Verify verifies a PLONK proof
 func (p *PLONKProver) Verify(ctx context.Context, req *prover.VerifyRequest) (*prover.VerifyResponse, error) {
+	// Fall back to the circuit's stored key when none is supplied
+	verificationKey := req.VerificationKey
+	if len(verificationKey) == 0 {
+		var err error
+		verificationKey, err = loadVerificationKey(ctx, p.artifacts, req.VerificationKeyURL)
+		if err != nil {
+			return &prover.VerifyResponse{
+				Valid:        false,
+				ErrorMessage: err.Error(),
+			}, nil
+		}
+	}
+
+	curve, err := verifyCurve(req, p.curve)
+	if err != nil {
+		return &prover.VerifyResponse{
+			Valid:        false,
+			ErrorMessage: err.Error(),
+		}, nil
+	}
+
 	// Deserialize verification key
-	vkBuf := bytes.NewReader(req.VerificationKey)
-	vk := plonk.NewVerifyingKey(p.curve)
-	if _, err := vk.ReadFrom(vkBuf); err != nil {
+	vk := plonk.NewVerifyingKey(curve)
+	if err := decodeInto(vk, "verification_key", verificationKey); err != nil {
 		return &prover.VerifyResponse{
 			Valid:        false,
 			ErrorMessage: fmt.Sprintf("failed to deserialize verification key: %v", err),
@@ -213,9 +369,8 @@ func (p *PLONKProver) Verify(ctx context.Context, req *prover.VerifyRequest) (*p
 	}
 
 	// Deserialize proof
-	proofBuf := bytes.NewReader(req.Proof)
-	proof := plonk.NewProof(p.curve)
-	if _, err := proof.ReadFrom(proofBuf); err != nil {
+	proof := plonk.NewProof(curve)
+	if err := decodeInto(proof, "proof", req.Proof); err != nil {
 		return &prover.VerifyResponse{
 			Valid:        false,
 			ErrorMessage: fmt.Sprintf("failed to deserialize proof: %v", err),
@@ -223,8 +378,7 @@ func (p *PLONKProver) Verify(ctx context.Context, req *prover.VerifyRequest) (*p
 	}
 
 	// Deserialize public inputs
-	publicBuf := bytes.NewReader(req.PublicInputs)
-	publicWitness, err := frontend.NewWitness(nil, p.curve.ScalarField())
+	publicWitness, err := frontend.NewWitness(nil, curve.ScalarField())
 	if err != nil {
 		return &prover.VerifyResponse{
 			Valid:        false,
@@ -232,7 +386,7 @@ func (p *PLONKProver) Verify(ctx context.Context, req *prover.VerifyRequest) (*p
 		}, nil
 	}
 
-	if _, err := publicWitness.ReadFrom(publicBuf); err != nil {
+	if err := decodeInto(publicWitness, "public_inputs", req.PublicInputs); err != nil {
 		return &prover.VerifyResponse{
 			Valid:        false,
 			ErrorMessage: fmt.Sprintf("failed to deserialize public inputs: %v", err),
@@ -253,6 +407,11 @@ func (p *PLONKProver) Verify(ctx context.Context, req *prover.VerifyRequest) (*p
 	}, nil
 }
 
+// Circuits lists the registered circuit types the prover can prove
+func (p *PLONKProver) Circuits() []prover.CircuitInfo {
+	return circuitInfos(p.Name())
+}
+
 // Capabilities returns PLONK capabilities
 func (p *PLONKProver) Capabilities() prover.Capabilities {
 	return prover.Capabilities{
@@ -262,6 +421,8 @@ func (p *PLONKProver) Capabilities() prover.Capabilities {
 		AsyncOnly:              true,
 		TypicalGenerationTime:  35000, // ~35 seconds (slightly slower than Groth16)
 		MaxProofSize:           2048,  // ~2KB (larger than Groth16)
+		Curves:                 CurveNames(),
+		Library:                "github.com/consensys/gnark",
 		Features: []string{
 			"zero-knowledge",
 			"universal-setup", // Key advantage!
@@ -271,37 +432,3 @@ func (p *PLONKProver) Capabilities() prover.Capabilities {
 		},
 	}
 }
-
-// createWitness creates a witness from input data (same as Groth16)
-func (p *PLONKProver) createWitness(circuitType string, inputData map[string]interface{}) (frontend.Circuit, error) {
-	switch circuitType {
-	case "simple":
-		return &SimpleCircuit{
-			X: inputData["x"],
-			Y: inputData["y"],
-			Z: inputData["z"],
-		}, nil
-
-	case "age_verification":
-		return &AgeVerificationCircuit{
-			Age:     inputData["age"],
-			MinAge:  inputData["min_age"],
-			IsAdult: inputData["is_adult"],
-		}, nil
-
-	case "range_proof":
-		return &RangeProofCircuit{
-			Value:   inputData["value"],
-			Min:     inputData["min"],
-			Max:     inputData["max"],
-			InRange: inputData["in_range"],
-		}, nil
-
-	default:
-		return &SimpleCircuit{
-			X: 3,
-			Y: 5,
-			Z: 15,
-		}, nil
-	}
-}
@@ -0,0 +1,573 @@
+package gnark
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	"github.com/gabrielrondon/zapiki/internal/models"
+	"github.com/gabrielrondon/zapiki/internal/prover"
+	"github.com/gabrielrondon/zapiki/internal/prover/snark/gnark/circuits"
+)
+
+// ErrUnknownCircuit is returned for circuit types that are not registered
+var ErrUnknownCircuit = fmt.Errorf("%w: unknown circuit type", prover.ErrInvalidCircuit)
+
+// CircuitSpec describes a circuit type the gnark provers can prove
+type CircuitSpec struct {
+	Name        string
+	Version     int
+	Description string
+
+	// Params lists the circuit_definition params the type accepts
+	Params []prover.CircuitParam
+	// InputSchema is the JSON Schema of the proof inputs, if fixed
+	InputSchema json.RawMessage
+	// PublicInputs names the public witness entries, in order
+	PublicInputs []string
+
+	// Detect lists the input fields that select this type when a proof
+	// request names none
+	Detect []string
+	// Systems restricts the type to some proof systems (empty = all)
+	Systems []models.ProofSystemType
+
+	// Normalize resolves the params of a circuit instance, see
+	// circuitParams. Types without params leave it nil.
+	Normalize func(params, inputData map[string]interface{}) (map[string]interface{}, error)
+	// Circuit returns an unassigned instance, to compile
+	Circuit func(params map[string]interface{}) (frontend.Circuit, error)
+	// Witness returns an instance with the proof inputs assigned
+	Witness func(params map[string]interface{}, in *Inputs) (frontend.Circuit, error)
+}
+
+// Supports reports whether system can prove the circuit type
+func (s *CircuitSpec) Supports(system models.ProofSystemType) bool {
+	if len(s.Systems) == 0 {
+		return true
+	}
+	for _, sys := range s.Systems {
+		if sys == system {
+			return true
+		}
+	}
+	return false
+}
+
+// Info describes the circuit type for API listings
+func (s *CircuitSpec) Info() prover.CircuitInfo {
+	return prover.CircuitInfo{
+		Name:         s.Name,
+		Version:      s.Version,
+		Description:  s.Description,
+		Params:       s.Params,
+		InputSchema:  s.InputSchema,
+		PublicInputs: s.PublicInputs,
+	}
+}
+
+// detects reports whether the inputs carry every Detect field
+func (s *CircuitSpec) detects(inputData map[string]interface{}) bool {
+	if len(s.Detect) == 0 {
+		return false
+	}
+	for _, field := range s.Detect {
+		if _, ok := inputData[field]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// registry holds the registered circuit types in registration order,
+// which is also the order detection tries them in
+var registry = struct {
+	sync.RWMutex
+	specs map[string]*CircuitSpec
+	order []*CircuitSpec
+}{specs: map[string]*CircuitSpec{}}
+
+// RegisterCircuit adds a circuit type. It panics if the name is taken or
+// the spec has no Circuit or Witness builder.
+func RegisterCircuit(spec CircuitSpec) {
+	if spec.Name == "" || spec.Circuit == nil || spec.Witness == nil {
+		panic(fmt.Sprintf("gnark: circuit %q needs a name, a Circuit and a Witness builder", spec.Name))
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+	if _, ok := registry.specs[spec.Name]; ok {
+		panic(fmt.Sprintf("gnark: circuit %q registered twice", spec.Name))
+	}
+	registry.specs[spec.Name] = &spec
+	registry.order = append(registry.order, &spec)
+}
+
+// LookupCircuit returns a registered circuit type
+func LookupCircuit(name string) (*CircuitSpec, error) {
+	registry.RLock()
+	defer registry.RUnlock()
+	spec, ok := registry.specs[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownCircuit, name)
+	}
+	return spec, nil
+}
+
+// circuitVersion returns the registered version of a circuit type, or 0
+// for types that are not registered
+func circuitVersion(name string) int {
+	spec, err := LookupCircuit(name)
+	if err != nil {
+		return 0
+	}
+	return spec.Version
+}
+
+// lookupCircuitFor returns a registered circuit type system can prove
+func lookupCircuitFor(system models.ProofSystemType, name string) (*CircuitSpec, error) {
+	spec, err := LookupCircuit(name)
+	if err != nil {
+		return nil, err
+	}
+	if !spec.Supports(system) {
+		return nil, fmt.Errorf("%w: %s circuits cannot be proven with %s", prover.ErrInvalidCircuit, name, system)
+	}
+	return spec, nil
+}
+
+// RegisteredCircuits lists the registered circuit types by name
+func RegisteredCircuits() []*CircuitSpec {
+	registry.RLock()
+	specs := append([]*CircuitSpec(nil), registry.order...)
+	registry.RUnlock()
+
+	sort.Slice(specs, func(i, j int) bool { return specs[i].Name < specs[j].Name })
+	return specs
+}
+
+// circuitInfos lists the circuit types system can prove
+func circuitInfos(system models.ProofSystemType) []prover.CircuitInfo {
+	var infos []prover.CircuitInfo
+	for _, spec := range RegisteredCircuits() {
+		if spec.Supports(system) {
+			infos = append(infos, spec.Info())
+		}
+	}
+	return infos
+}
+
+// detectCircuitType returns the first registered type whose Detect fields
+// the inputs carry, or "" if none does
+func detectCircuitType(inputData map[string]interface{}) string {
+	registry.RLock()
+	defer registry.RUnlock()
+	for _, spec := range registry.order {
+		if spec.detects(inputData) {
+			return spec.Name
+		}
+	}
+	return ""
+}
+
+// createWitness assigns the proof inputs to an instance of a circuit type
+// over curve's scalar field
+func createWitness(curve ecc.ID, circuitType string, params, inputData map[string]interface{}) (frontend.Circuit, error) {
+	spec, err := LookupCircuit(circuitType)
+	if err != nil {
+		return nil, err
+	}
+	return spec.Witness(params, NewInputs(inputData, curve.ScalarField()))
+}
+
+// inputField describes one property of an input schema
+type inputField struct {
+	name        string
+	description string
+	// array marks fields holding an array of field elements
+	array bool
+	// optional fields are left out of the required list
+	optional bool
+}
+
+// inputSchema returns a JSON Schema object requiring every non-optional field. Field
+// elements are integers or decimal, 0x-hex or base64 strings.
+func inputSchema(fields ...inputField) json.RawMessage {
+	element := map[string]interface{}{"type": []string{"integer", "string"}}
+
+	properties := map[string]interface{}{}
+	required := make([]string, 0, len(fields))
+	for _, f := range fields {
+		var prop map[string]interface{}
+		if f.array {
+			prop = map[string]interface{}{"type": "array", "items": element}
+		} else {
+			prop = map[string]interface{}{"type": element["type"]}
+		}
+		prop["description"] = f.description
+		properties[f.name] = prop
+		if !f.optional {
+			required = append(required, f.name)
+		}
+	}
+
+	schema, err := json.Marshal(map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	})
+	if err != nil {
+		panic(err)
+	}
+	return schema
+}
+
+var (
+	hashParamSpec = prover.CircuitParam{
+		Name:        "hash",
+		Type:        "string",
+		Default:     string(circuits.DefaultHash),
+		Description: `Hash function, "mimc" or "poseidon2"`,
+	}
+	depthParamSpec = prover.CircuitParam{
+		Name:        "depth",
+		Type:        "integer",
+		Default:     circuits.DefaultTreeDepth,
+		Description: fmt.Sprintf("Merkle tree depth, 1 to %d", circuits.MaxTreeDepth),
+	}
+)
+
+// The built-in circuit types, in detection order: the AML circuits first,
+// then the generic ones whose fields they may share
+func init() {
+	RegisterCircuit(CircuitSpec{
+		Name:        "aml_age_verification",
+		Version:     1,
+		Description: "Proves an age of at least minimum_age without revealing the birth year",
+		InputSchema: inputSchema(
+			inputField{name: "minimum_age", description: "Minimum age (public)"},
+			inputField{name: "current_year", description: "Current year (public)"},
+			inputField{name: "birth_year", description: "Birth year (private)"},
+			inputField{name: "nonce", description: "Replay protection nonce (private)"},
+		),
+		PublicInputs: []string{"minimum_age", "current_year"},
+		Detect:       []string{"minimum_age", "current_year", "birth_year"},
+		Circuit: func(map[string]interface{}) (frontend.Circuit, error) {
+			return &AMLAgeVerificationCircuit{}, nil
+		},
+		Witness: func(_ map[string]interface{}, in *Inputs) (frontend.Circuit, error) {
+			return &AMLAgeVerificationCircuit{
+				MinimumAge:  in.Field("minimum_age"),
+				CurrentYear: in.Field("current_year"),
+				BirthYear:   in.Field("birth_year"),
+				Nonce:       in.Field("nonce"),
+			}, in.Err()
+		},
+	})
+
+	RegisterCircuit(CircuitSpec{
+		Name:        "aml_sanctions_check",
+		Version:     2,
+		Description: "Proves a hashed identifier is not a leaf of a sorted sanctions Merkle tree",
+		Params:      []prover.CircuitParam{depthParamSpec, hashParamSpec},
+		InputSchema: inputSchema(
+			inputField{name: "sanctions_list_root", description: "Root of the sorted sanctions tree (public)"},
+			inputField{name: "current_timestamp", description: "Unix time of the check (public)"},
+			inputField{name: "identifier_commitment", description: "H(user_identifier, identifier_salt) with the tree's hash (public)"},
+			inputField{name: "user_identifier", description: "Hashed user identifier (private)"},
+			inputField{name: "identifier_salt", description: "Salt hiding user_identifier in the commitment (private)"},
+			inputField{name: "low_leaf", description: "Largest leaf below the identifier (private)"},
+			inputField{name: "high_leaf", description: "Smallest leaf above the identifier (private)"},
+			inputField{name: "low_index", description: "Index of low_leaf (private)"},
+			inputField{name: "low_path", description: "Merkle path of low_leaf (private)", array: true},
+			inputField{name: "high_path", description: "Merkle path of high_leaf (private)", array: true},
+		),
+		PublicInputs: []string{"sanctions_list_root", "current_timestamp", "identifier_commitment"},
+		Detect:       []string{"sanctions_list_root", "user_identifier"},
+		Normalize:    treeNormalizer("low_path"),
+		Circuit: func(params map[string]interface{}) (frontend.Circuit, error) {
+			depth, fn, err := treeParams(params)
+			if err != nil {
+				return nil, err
+			}
+			return NewAMLSanctionsCheckCircuit(depth, fn), nil
+		},
+		Witness: sanctionsWitness,
+	})
+
+	RegisterCircuit(CircuitSpec{
+		Name:        "aml_residency_proof",
+		Version:     2,
+		Description: "Proves residency in one of a set of allowed countries",
+		Params: []prover.CircuitParam{{
+			Name:        "set_size",
+			Type:        "integer",
+			Default:     1,
+			Description: fmt.Sprintf("Number of allowed countries, 1 to %d", MaxCountrySetSize),
+		}},
+		InputSchema: inputSchema(
+			inputField{name: "allowed_country_codes", description: "Numeric codes of the allowed countries (public)", array: true, optional: true},
+			inputField{name: "allowed_country_code", description: "The allowed country, when set_size is 1 (public)", optional: true},
+			inputField{name: "current_timestamp", description: "Unix time of the check (public)"},
+			inputField{name: "user_country_code", description: "Numeric country code of residence (private)"},
+			inputField{name: "address_hash", description: "Hash of the address (private)"},
+		),
+		PublicInputs: []string{"allowed_country_codes", "current_timestamp"},
+		Detect:       []string{"user_country_code"},
+		Normalize:    countrySetNormalizer,
+		Circuit: func(params map[string]interface{}) (frontend.Circuit, error) {
+			setSize, err := intParam(params, "set_size", 1, 1, MaxCountrySetSize)
+			if err != nil {
+				return nil, err
+			}
+			return NewAMLResidencyProofCircuit(setSize), nil
+		},
+		Witness: func(params map[string]interface{}, in *Inputs) (frontend.Circuit, error) {
+			setSize, err := intParam(params, "set_size", 1, 1, MaxCountrySetSize)
+			if err != nil {
+				return nil, err
+			}
+
+			var allowed []frontend.Variable
+			if _, ok := in.Values()["allowed_country_codes"]; ok || setSize > 1 {
+				allowed = in.Fields("allowed_country_codes", setSize)
+			} else {
+				allowed = []frontend.Variable{in.Field("allowed_country_code")}
+			}
+
+			return &AMLResidencyProofCircuit{
+				AllowedCountryCodes: allowed,
+				CurrentTimestamp:    in.Field("current_timestamp"),
+				UserCountryCode:     in.Field("user_country_code"),
+				AddressHash:         in.Field("address_hash"),
+			}, in.Err()
+		},
+	})
+
+	RegisterCircuit(CircuitSpec{
+		Name:        "aml_income_verification",
+		Version:     1,
+		Description: "Proves income of at least minimum_income without revealing it",
+		Params: []prover.CircuitParam{{
+			Name:        "max_income",
+			Type:        "integer",
+			Default:     DefaultMaxIncome,
+			Description: "Upper bound on actual_income",
+		}},
+		InputSchema: inputSchema(
+			inputField{name: "minimum_income", description: "Income threshold (public)"},
+			inputField{name: "current_timestamp", description: "Unix time of the check (public)"},
+			inputField{name: "actual_income", description: "Actual income (private)"},
+			inputField{name: "income_source_hash", description: "Hash of the income source (private)"},
+		),
+		PublicInputs: []string{"minimum_income", "current_timestamp"},
+		Detect:       []string{"minimum_income", "actual_income"},
+		Normalize:    intNormalizer("max_income", DefaultMaxIncome, 1, MaxIncomeBound),
+		Circuit: func(params map[string]interface{}) (frontend.Circuit, error) {
+			maxIncome, err := intParam(params, "max_income", DefaultMaxIncome, 1, MaxIncomeBound)
+			if err != nil {
+				return nil, err
+			}
+			return &AMLIncomeVerificationCircuit{MaxIncome: int64(maxIncome)}, nil
+		},
+		Witness: func(params map[string]interface{}, in *Inputs) (frontend.Circuit, error) {
+			maxIncome, err := intParam(params, "max_income", DefaultMaxIncome, 1, MaxIncomeBound)
+			if err != nil {
+				return nil, err
+			}
+
+			return &AMLIncomeVerificationCircuit{
+				MinimumIncome:    in.Field("minimum_income"),
+				CurrentTimestamp: in.Field("current_timestamp"),
+				ActualIncome:     in.Field("actual_income"),
+				IncomeSourceHash: in.Field("income_source_hash"),
+				MaxIncome:        int64(maxIncome),
+			}, in.Err()
+		},
+	})
+
+	RegisterCircuit(CircuitSpec{
+		Name:        "hash_preimage",
+		Version:     1,
+		Description: "Proves knowledge of the preimage of a hash",
+		Params:      []prover.CircuitParam{hashParamSpec},
+		InputSchema: inputSchema(
+			inputField{name: "preimage", description: "Hashed value (private)"},
+			inputField{name: "hash_value", description: "Hash of the preimage (public)"},
+		),
+		PublicInputs: []string{"hash_value"},
+		Detect:       []string{"preimage", "hash_value"},
+		Normalize:    hashNormalizer,
+		Circuit: func(params map[string]interface{}) (frontend.Circuit, error) {
+			fn, err := hashParam(params)
+			if err != nil {
+				return nil, err
+			}
+			return &HashPreimageCircuit{HashFunc: fn}, nil
+		},
+		Witness: hashPreimageWitness,
+	})
+
+	RegisterCircuit(CircuitSpec{
+		Name:        "merkle_proof",
+		Version:     1,
+		Description: "Proves a leaf is in a Merkle tree",
+		Params:      []prover.CircuitParam{depthParamSpec, hashParamSpec},
+		InputSchema: inputSchema(
+			inputField{name: "leaf", description: "Leaf value (private)"},
+			inputField{name: "root", description: "Tree root (public)"},
+			inputField{name: "path", description: "Sibling hashes from the leaf up (private)", array: true},
+			inputField{name: "directions", description: "0 when the node is a left child, 1 when right (private)", array: true},
+		),
+		PublicInputs: []string{"root"},
+		Detect:       []string{"leaf", "root", "path"},
+		Normalize:    treeNormalizer("path"),
+		Circuit: func(params map[string]interface{}) (frontend.Circuit, error) {
+			depth, fn, err := treeParams(params)
+			if err != nil {
+				return nil, err
+			}
+			return NewMerkleProofCircuit(depth, fn), nil
+		},
+		Witness: merkleProofWitness,
+	})
+
+	RegisterCircuit(CircuitSpec{
+		Name:        "age_verification",
+		Version:     1,
+		Description: "Proves age >= min_age without revealing the age",
+		InputSchema: inputSchema(
+			inputField{name: "age", description: "Age (private)"},
+			inputField{name: "min_age", description: "Minimum age (public)"},
+			inputField{name: "is_adult", description: "Must be 1 (public)"},
+		),
+		PublicInputs: []string{"min_age", "is_adult"},
+		Detect:       []string{"age", "min_age"},
+		Circuit: func(map[string]interface{}) (frontend.Circuit, error) {
+			return &AgeVerificationCircuit{}, nil
+		},
+		Witness: func(_ map[string]interface{}, in *Inputs) (frontend.Circuit, error) {
+			return &AgeVerificationCircuit{
+				Age:     in.Field("age"),
+				MinAge:  in.Field("min_age"),
+				IsAdult: in.Field("is_adult"),
+			}, in.Err()
+		},
+	})
+
+	RegisterCircuit(CircuitSpec{
+		Name:        "range_proof",
+		Version:     1,
+		Description: "Proves min <= value <= max without revealing the value",
+		Params: []prover.CircuitParam{{
+			Name:        "bits",
+			Type:        "integer",
+			Default:     DefaultRangeBits,
+			Description: fmt.Sprintf("Bit width of value - min and max - value, 1 to %d", MaxRangeBits),
+		}},
+		InputSchema: inputSchema(
+			inputField{name: "value", description: "Value (private)"},
+			inputField{name: "min", description: "Lower bound (public)"},
+			inputField{name: "max", description: "Upper bound (public)"},
+			inputField{name: "in_range", description: "Must be 1 (public)"},
+		),
+		PublicInputs: []string{"min", "max", "in_range"},
+		Detect:       []string{"value", "min", "max"},
+		Normalize:    intNormalizer("bits", DefaultRangeBits, 1, MaxRangeBits),
+		Circuit: func(params map[string]interface{}) (frontend.Circuit, error) {
+			bits, err := intParam(params, "bits", DefaultRangeBits, 1, MaxRangeBits)
+			if err != nil {
+				return nil, err
+			}
+			return &RangeProofCircuit{Bits: bits}, nil
+		},
+		Witness: func(params map[string]interface{}, in *Inputs) (frontend.Circuit, error) {
+			bits, err := intParam(params, "bits", DefaultRangeBits, 1, MaxRangeBits)
+			if err != nil {
+				return nil, err
+			}
+
+			return &RangeProofCircuit{
+				Value:   in.Field("value"),
+				Min:     in.Field("min"),
+				Max:     in.Field("max"),
+				InRange: in.Field("in_range"),
+				Bits:    bits,
+			}, in.Err()
+		},
+	})
+
+	RegisterCircuit(CircuitSpec{
+		Name:        "simple",
+		Version:     1,
+		Description: "Proves knowledge of x and y with x * y = z",
+		InputSchema: inputSchema(
+			inputField{name: "x", description: "First factor (private)"},
+			inputField{name: "y", description: "Second factor (private)"},
+			inputField{name: "z", description: "Product (public)"},
+		),
+		PublicInputs: []string{"z"},
+		Detect:       []string{"x", "y", "z"},
+		Circuit: func(map[string]interface{}) (frontend.Circuit, error) {
+			return &SimpleCircuit{}, nil
+		},
+		Witness: func(_ map[string]interface{}, in *Inputs) (frontend.Circuit, error) {
+			return &SimpleCircuit{
+				X: in.Field("x"),
+				Y: in.Field("y"),
+				Z: in.Field("z"),
+			}, in.Err()
+		},
+	})
+
+	RegisterCircuit(CircuitSpec{
+		Name:        customCircuitType,
+		Version:     1,
+		Description: "Circuit described in JSON, see the circuit field of circuit_definition",
+		Params: []prover.CircuitParam{{
+			Name:        "circuit",
+			Type:        "object",
+			Description: "Circuit description with inputs and constraints",
+		}},
+		Normalize: func(params, _ map[string]interface{}) (map[string]interface{}, error) {
+			def, err := customDefinition(params)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{"circuit": def}, nil
+		},
+		Circuit: func(params map[string]interface{}) (frontend.Circuit, error) {
+			def, err := customDefinition(params)
+			if err != nil {
+				return nil, err
+			}
+			return def.Circuit(), nil
+		},
+		Witness: customWitness,
+	})
+
+	RegisterCircuit(CircuitSpec{
+		Name:        circomCircuitType,
+		Version:     1,
+		Description: "Circuit imported from a Circom .r1cs upload",
+		Systems:     []models.ProofSystemType{models.ProofSystemGroth16},
+		Normalize: func(params, _ map[string]interface{}) (map[string]interface{}, error) {
+			r, err := circomR1CS(params)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{"r1cs": r}, nil
+		},
+		Circuit: func(params map[string]interface{}) (frontend.Circuit, error) {
+			r, err := circomR1CS(params)
+			if err != nil {
+				return nil, err
+			}
+			return r.Circuit(), nil
+		},
+		Witness: circomWitness,
+	})
+}
@@ -0,0 +1,102 @@
+package gnark
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/gabrielrondon/zapiki/internal/models"
+	"github.com/gabrielrondon/zapiki/internal/prover"
+)
+
+func TestRegistry_UnknownCircuitType(t *testing.T) {
+	if _, err := GetCircuitByName("no_such_circuit"); !errors.Is(err, ErrUnknownCircuit) {
+		t.Errorf("Expected ErrUnknownCircuit, got %v", err)
+	}
+	if _, err := createWitness(ecc.BN254, "no_such_circuit", nil, map[string]interface{}{}); !errors.Is(err, prover.ErrInvalidCircuit) {
+		t.Errorf("Expected ErrInvalidCircuit, got %v", err)
+	}
+
+	circuitDefJSON, _ := json.Marshal(map[string]interface{}{"circuit_type": "no_such_circuit"})
+	err := validateCircuit(&models.Circuit{
+		ProofSystem:       models.ProofSystemGroth16,
+		CircuitDefinition: circuitDefJSON,
+	})
+	if !errors.Is(err, ErrUnknownCircuit) {
+		t.Errorf("Expected an unknown circuit type to be rejected, got %v", err)
+	}
+}
+
+func TestRegistry_GenerateRejectsUnknownInputs(t *testing.T) {
+	req := &prover.ProofRequest{
+		Data: &models.InputData{
+			Type:  models.DataTypeJSON,
+			Value: json.RawMessage(`{"a": 1, "b": 2}`),
+		},
+	}
+
+	for _, p := range []prover.ProofSystem{NewGroth16Prover(), NewPLONKProver()} {
+		if _, err := p.Generate(context.Background(), req); !errors.Is(err, prover.ErrInvalidCircuit) {
+			t.Errorf("%s: expected undetectable inputs to be rejected, got %v", p.Name(), err)
+		}
+	}
+}
+
+func TestRegistry_DetectCircuitType(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"simple":               {"x": 3, "y": 5, "z": 15},
+		"range_proof":          {"value": 5, "min": 1, "max": 10},
+		"aml_age_verification": {"minimum_age": 18, "current_year": 2025, "birth_year": 1990},
+		"aml_sanctions_check":  {"sanctions_list_root": "1", "user_identifier": "2"},
+		"merkle_proof":         {"leaf": "1", "root": "2", "path": []interface{}{}},
+		"":                     {"a": 1},
+	}
+
+	for want, inputs := range tests {
+		if got := detectCircuitType(inputs); got != want {
+			t.Errorf("detectCircuitType(%v) = %q, want %q", inputs, got, want)
+		}
+	}
+}
+
+func TestRegistry_Circuits(t *testing.T) {
+	groth16Circuits := map[string]prover.CircuitInfo{}
+	for _, info := range NewGroth16Prover().Circuits() {
+		groth16Circuits[info.Name] = info
+	}
+	for _, name := range BuiltinCircuits {
+		info, ok := groth16Circuits[name]
+		if !ok {
+			t.Errorf("Expected built-in circuit %s to be listed", name)
+			continue
+		}
+		if len(info.InputSchema) == 0 || len(info.PublicInputs) == 0 {
+			t.Errorf("Expected %s to have an input schema and public inputs", name)
+		}
+	}
+	if _, ok := groth16Circuits[circomCircuitType]; !ok {
+		t.Error("Expected Groth16 to list circom circuits")
+	}
+
+	for _, info := range NewPLONKProver().Circuits() {
+		if info.Name == circomCircuitType {
+			t.Error("Expected PLONK not to list circom circuits")
+		}
+	}
+}
+
+func TestRegistry_RegisterCircuitTwicePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected registering a taken name to panic")
+		}
+	}()
+
+	spec, err := LookupCircuit("simple")
+	if err != nil {
+		t.Fatalf("Failed to look up simple: %v", err)
+	}
+	RegisterCircuit(*spec)
+}
@@ -0,0 +1,258 @@
+package gnark
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+)
+
+// snarkjs writes points as projective decimal coordinates, affine with
+// z = 1 in practice, and field elements of G2 as [c0, c1].
+
+// snarkjsProof is a snarkjs Groth16 proof.json
+type snarkjsProof struct {
+	A        []string   `json:"pi_a"`
+	B        [][]string `json:"pi_b"`
+	C        []string   `json:"pi_c"`
+	Protocol string     `json:"protocol"`
+	Curve    string     `json:"curve"`
+}
+
+// snarkjsKey is a snarkjs Groth16 verification_key.json. snarkjs also
+// writes vk_alphabeta_12, which its verifier does not read.
+type snarkjsKey struct {
+	Protocol string     `json:"protocol"`
+	Curve    string     `json:"curve"`
+	NPublic  int        `json:"nPublic"`
+	Alpha    []string   `json:"vk_alpha_1"`
+	Beta     [][]string `json:"vk_beta_2"`
+	Gamma    [][]string `json:"vk_gamma_2"`
+	Delta    [][]string `json:"vk_delta_2"`
+	IC       [][]string `json:"IC"`
+}
+
+// snarkjsCurve returns the curve named by a snarkjs proof or verifying key
+func snarkjsCurve(raw json.RawMessage) (ecc.ID, bool) {
+	var file struct {
+		Curve string `json:"curve"`
+	}
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return ecc.UNKNOWN, false
+	}
+	for id, c := range interopCurves {
+		if c.snarkjs == file.Curve {
+			return id, true
+		}
+	}
+	return ecc.UNKNOWN, false
+}
+
+// checkSnarkJSHeader checks the protocol and curve of a snarkjs file
+func (c *interopCurve) checkSnarkJSHeader(protocol, curve string) error {
+	if protocol != "" && protocol != "groth16" {
+		return fmt.Errorf("protocol is %q, not groth16", protocol)
+	}
+	if curve != "" && curve != c.snarkjs {
+		return fmt.Errorf("curve is %q, not %s", curve, c.snarkjs)
+	}
+	return nil
+}
+
+// snarkjsProofJSON writes a proof as proof.json
+func (c *interopCurve) snarkjsProofJSON(points *groth16ProofPoints) (json.RawMessage, error) {
+	return json.Marshal(snarkjsProof{
+		A:        snarkjsG1(points.A),
+		B:        snarkjsG2(points.B),
+		C:        snarkjsG1(points.C),
+		Protocol: "groth16",
+		Curve:    c.snarkjs,
+	})
+}
+
+// parseSnarkJSProof reads a proof.json
+func (c *interopCurve) parseSnarkJSProof(raw json.RawMessage) (*groth16ProofPoints, error) {
+	var proof snarkjsProof
+	if err := json.Unmarshal(raw, &proof); err != nil {
+		return nil, fmt.Errorf("invalid snarkjs proof: %w", err)
+	}
+	if err := c.checkSnarkJSHeader(proof.Protocol, proof.Curve); err != nil {
+		return nil, fmt.Errorf("invalid snarkjs proof: %w", err)
+	}
+
+	var points groth16ProofPoints
+	var err error
+	if points.A, err = parseSnarkJSG1(proof.A); err != nil {
+		return nil, fmt.Errorf("invalid snarkjs proof pi_a: %w", err)
+	}
+	if points.B, err = parseSnarkJSG2(proof.B); err != nil {
+		return nil, fmt.Errorf("invalid snarkjs proof pi_b: %w", err)
+	}
+	if points.C, err = parseSnarkJSG1(proof.C); err != nil {
+		return nil, fmt.Errorf("invalid snarkjs proof pi_c: %w", err)
+	}
+	return &points, nil
+}
+
+// snarkjsKeyJSON writes a verifying key as verification_key.json
+func (c *interopCurve) snarkjsKeyJSON(points *groth16KeyPoints) (json.RawMessage, error) {
+	key := snarkjsKey{
+		Protocol: "groth16",
+		Curve:    c.snarkjs,
+		NPublic:  len(points.IC) - 1,
+		Alpha:    snarkjsG1(points.Alpha),
+		Beta:     snarkjsG2(points.Beta),
+		Gamma:    snarkjsG2(points.Gamma),
+		Delta:    snarkjsG2(points.Delta),
+	}
+	for _, p := range points.IC {
+		key.IC = append(key.IC, snarkjsG1(p))
+	}
+	return json.Marshal(key)
+}
+
+// parseSnarkJSKey reads a verification_key.json
+func (c *interopCurve) parseSnarkJSKey(raw json.RawMessage) (*groth16KeyPoints, error) {
+	var key snarkjsKey
+	if err := json.Unmarshal(raw, &key); err != nil {
+		return nil, fmt.Errorf("invalid snarkjs verification key: %w", err)
+	}
+	if err := c.checkSnarkJSHeader(key.Protocol, key.Curve); err != nil {
+		return nil, fmt.Errorf("invalid snarkjs verification key: %w", err)
+	}
+	if len(key.IC) != key.NPublic+1 {
+		return nil, fmt.Errorf("invalid snarkjs verification key: %d IC points for nPublic %d", len(key.IC), key.NPublic)
+	}
+
+	var points groth16KeyPoints
+	var err error
+	if points.Alpha, err = parseSnarkJSG1(key.Alpha); err != nil {
+		return nil, fmt.Errorf("invalid snarkjs verification key vk_alpha_1: %w", err)
+	}
+	for _, g2 := range []struct {
+		name string
+		src  [][]string
+		dst  *affineG2
+	}{
+		{"vk_beta_2", key.Beta, &points.Beta},
+		{"vk_gamma_2", key.Gamma, &points.Gamma},
+		{"vk_delta_2", key.Delta, &points.Delta},
+	} {
+		if *g2.dst, err = parseSnarkJSG2(g2.src); err != nil {
+			return nil, fmt.Errorf("invalid snarkjs verification key %s: %w", g2.name, err)
+		}
+	}
+	points.IC = make([]affineG1, len(key.IC))
+	for i, p := range key.IC {
+		if points.IC[i], err = parseSnarkJSG1(p); err != nil {
+			return nil, fmt.Errorf("invalid snarkjs verification key IC[%d]: %w", i, err)
+		}
+	}
+	return &points, nil
+}
+
+// snarkjsPublicJSON writes public inputs as public.json
+func snarkjsPublicJSON(values []*big.Int) (json.RawMessage, error) {
+	inputs := make([]string, len(values))
+	for i, v := range values {
+		inputs[i] = v.String()
+	}
+	return json.Marshal(inputs)
+}
+
+// parseSnarkJSPublic reads a public.json
+func parseSnarkJSPublic(raw json.RawMessage) ([]*big.Int, error) {
+	var inputs []string
+	if err := json.Unmarshal(raw, &inputs); err != nil {
+		return nil, fmt.Errorf("invalid snarkjs public inputs: %w", err)
+	}
+	values, err := parseDecimals(inputs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snarkjs public inputs: %w", err)
+	}
+	return values, nil
+}
+
+// snarkjsG1 writes an affine point as [x, y, z]
+func snarkjsG1(p affineG1) []string {
+	if p[0].Sign() == 0 && p[1].Sign() == 0 {
+		return []string{"0", "1", "0"}
+	}
+	return []string{p[0].String(), p[1].String(), "1"}
+}
+
+// snarkjsG2 writes an affine point as [[x0, x1], [y0, y1], [z0, z1]]
+func snarkjsG2(p affineG2) [][]string {
+	if p[0][0].Sign() == 0 && p[0][1].Sign() == 0 && p[1][0].Sign() == 0 && p[1][1].Sign() == 0 {
+		return [][]string{{"0", "0"}, {"1", "0"}, {"0", "0"}}
+	}
+	return [][]string{
+		{p[0][0].String(), p[0][1].String()},
+		{p[1][0].String(), p[1][1].String()},
+		{"1", "0"},
+	}
+}
+
+// parseSnarkJSG1 reads [x, y] or [x, y, z] with z 1, or 0 for the point at
+// infinity
+func parseSnarkJSG1(coordinates []string) (affineG1, error) {
+	if len(coordinates) != 2 && len(coordinates) != 3 {
+		return affineG1{}, fmt.Errorf("expected 2 or 3 coordinates, got %d", len(coordinates))
+	}
+	values, err := parseDecimals(coordinates)
+	if err != nil {
+		return affineG1{}, err
+	}
+	if len(values) == 3 {
+		switch {
+		case values[2].Sign() == 0:
+			return affineG1{new(big.Int), new(big.Int)}, nil
+		case values[2].Cmp(big.NewInt(1)) != 0:
+			return affineG1{}, fmt.Errorf("z is %s; only affine points (z = 1) are supported", values[2])
+		}
+	}
+	return affineG1{values[0], values[1]}, nil
+}
+
+// parseSnarkJSG2 reads [[x0, x1], [y0, y1]] or the same with [z0, z1]
+// being [1, 0], or [0, 0] for the point at infinity
+func parseSnarkJSG2(coordinates [][]string) (affineG2, error) {
+	if len(coordinates) != 2 && len(coordinates) != 3 {
+		return affineG2{}, fmt.Errorf("expected 2 or 3 coordinates, got %d", len(coordinates))
+	}
+	var values [3][2]*big.Int
+	for i, pair := range coordinates {
+		if len(pair) != 2 {
+			return affineG2{}, fmt.Errorf("expected 2 components per coordinate, got %d", len(pair))
+		}
+		parsed, err := parseDecimals(pair)
+		if err != nil {
+			return affineG2{}, err
+		}
+		values[i] = [2]*big.Int{parsed[0], parsed[1]}
+	}
+	if len(coordinates) == 3 {
+		z := values[2]
+		switch {
+		case z[0].Sign() == 0 && z[1].Sign() == 0:
+			return affineG2{{new(big.Int), new(big.Int)}, {new(big.Int), new(big.Int)}}, nil
+		case z[0].Cmp(big.NewInt(1)) != 0 || z[1].Sign() != 0:
+			return affineG2{}, fmt.Errorf("z is [%s, %s]; only affine points (z = [1, 0]) are supported", z[0], z[1])
+		}
+	}
+	return affineG2{values[0], values[1]}, nil
+}
+
+// parseDecimals parses decimal strings
+func parseDecimals(decimals []string) ([]*big.Int, error) {
+	values := make([]*big.Int, len(decimals))
+	for i, s := range decimals {
+		v, ok := new(big.Int).SetString(s, 10)
+		if !ok {
+			return nil, fmt.Errorf("%q is not a decimal integer", s)
+		}
+		values[i] = v
+	}
+	return values, nil
+}
@@ -0,0 +1,205 @@
+package gnark
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/backend/groth16"
+	groth16bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+	"github.com/consensys/gnark/backend/plonk"
+	plonkbn254 "github.com/consensys/gnark/backend/plonk/bn254"
+	"github.com/consensys/gnark/frontend"
+	"github.com/gabrielrondon/zapiki/internal/models"
+	"github.com/gabrielrondon/zapiki/internal/prover"
+	"github.com/gabrielrondon/zapiki/internal/storage/artifact"
+	"golang.org/x/crypto/sha3"
+)
+
+// Solidity verifiers rely on the EVM's BN254 precompiles, so only BN254
+// circuits can be exported. The contracts are gnark's: Groth16 exposes
+// verifyProof(uint256[8],uint256[N]) and PLONK Verify(bytes,uint256[]).
+
+// checkSolidityCurve rejects curves the EVM cannot verify
+func checkSolidityCurve(curve ecc.ID) error {
+	if curve != ecc.BN254 {
+		return fmt.Errorf("%w: curve %s (only bn254 is verifiable on EVM chains)", prover.ErrSolidityUnsupported, curve)
+	}
+	return nil
+}
+
+// loadSolidityKey loads a BN254 circuit's stored verification key into vk
+func loadSolidityKey(ctx context.Context, store artifact.Store, circuit *models.Circuit, fallback ecc.ID, vk Key) error {
+	circuitCurve, err := definitionCurve(circuit)
+	if err != nil {
+		return err
+	}
+	curve, err := resolveCurve("", circuitCurve, fallback)
+	if err != nil {
+		return err
+	}
+	if err := checkSolidityCurve(curve); err != nil {
+		return err
+	}
+
+	raw, err := loadVerificationKey(ctx, store, circuit.VerificationKeyURL)
+	if err != nil {
+		return err
+	}
+	if err := decodeInto(vk, "verification_key", raw); err != nil {
+		return fmt.Errorf("failed to deserialize verification key: %w", err)
+	}
+	return nil
+}
+
+// solidityPublicInputs decodes a BN254 public witness into field elements
+func solidityPublicInputs(raw []byte) ([]*big.Int, error) {
+	publicWitness, err := frontend.NewWitness(nil, ecc.BN254.ScalarField())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create witness: %w", err)
+	}
+	if err := decodeInto(publicWitness, "public_inputs", raw); err != nil {
+		return nil, fmt.Errorf("failed to deserialize public inputs: %w", err)
+	}
+
+	vector, ok := publicWitness.Vector().(fr.Vector)
+	if !ok {
+		return nil, fmt.Errorf("public inputs are not bn254 field elements")
+	}
+
+	inputs := make([]*big.Int, len(vector))
+	for i := range vector {
+		inputs[i] = vector[i].BigInt(new(big.Int))
+	}
+	return inputs, nil
+}
+
+// ExportSolidity writes a Solidity verifier for a circuit's stored verification key
+func (p *Groth16Prover) ExportSolidity(ctx context.Context, circuit *models.Circuit, w io.Writer) error {
+	vk := groth16.NewVerifyingKey(ecc.BN254)
+	if err := loadSolidityKey(ctx, p.artifacts, circuit, p.curve, vk); err != nil {
+		return err
+	}
+
+	if len(vk.(*groth16bn254.VerifyingKey).CommitmentKeys) > 0 {
+		return fmt.Errorf("%w: circuits with commitments", prover.ErrSolidityUnsupported)
+	}
+
+	return vk.ExportSolidity(w)
+}
+
+// SolidityCalldata encodes a Groth16 proof as a verifyProof call
+func (p *Groth16Prover) SolidityCalldata(ctx context.Context, req *prover.VerifyRequest) (*prover.SolidityCalldata, error) {
+	curve, err := verifyCurve(req, p.curve)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSolidityCurve(curve); err != nil {
+		return nil, err
+	}
+
+	proof := groth16.NewProof(ecc.BN254)
+	if err := decodeInto(proof, "proof", req.Proof); err != nil {
+		return nil, fmt.Errorf("failed to deserialize proof: %w", err)
+	}
+	bnProof := proof.(*groth16bn254.Proof)
+	if len(bnProof.Commitments) > 0 {
+		return nil, fmt.Errorf("%w: proofs with commitments", prover.ErrSolidityUnsupported)
+	}
+
+	inputs, err := solidityPublicInputs(req.PublicInputs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Both arguments are fixed-size arrays, so they are encoded in place
+	proofBytes := bnProof.MarshalSolidity()
+	function := fmt.Sprintf("verifyProof(uint256[8],uint256[%d])", len(inputs))
+
+	calldata := abiSelector(function)
+	calldata = append(calldata, proofBytes...)
+	for _, input := range inputs {
+		calldata = append(calldata, abiWord(input)...)
+	}
+
+	return solidityCall(function, calldata, proofBytes, inputs), nil
+}
+
+// ExportSolidity writes a Solidity verifier for a circuit's stored verification key
+func (p *PLONKProver) ExportSolidity(ctx context.Context, circuit *models.Circuit, w io.Writer) error {
+	vk := plonk.NewVerifyingKey(ecc.BN254)
+	if err := loadSolidityKey(ctx, p.artifacts, circuit, p.curve, vk); err != nil {
+		return err
+	}
+	return vk.ExportSolidity(w)
+}
+
+// SolidityCalldata encodes a PLONK proof as a Verify call
+func (p *PLONKProver) SolidityCalldata(ctx context.Context, req *prover.VerifyRequest) (*prover.SolidityCalldata, error) {
+	curve, err := verifyCurve(req, p.curve)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSolidityCurve(curve); err != nil {
+		return nil, err
+	}
+
+	proof := plonk.NewProof(ecc.BN254)
+	if err := decodeInto(proof, "proof", req.Proof); err != nil {
+		return nil, fmt.Errorf("failed to deserialize proof: %w", err)
+	}
+
+	inputs, err := solidityPublicInputs(req.PublicInputs)
+	if err != nil {
+		return nil, err
+	}
+
+	proofBytes := proof.(*plonkbn254.Proof).MarshalSolidity()
+	function := "Verify(bytes,uint256[])"
+
+	// Head: offsets of the two dynamic arguments. Tail: each argument's
+	// length followed by its contents, padded to whole words.
+	paddedProof := (len(proofBytes) + 31) / 32 * 32
+	calldata := abiSelector(function)
+	calldata = append(calldata, abiWord(big.NewInt(64))...)
+	calldata = append(calldata, abiWord(big.NewInt(int64(64+32+paddedProof)))...)
+	calldata = append(calldata, abiWord(big.NewInt(int64(len(proofBytes))))...)
+	calldata = append(calldata, proofBytes...)
+	calldata = append(calldata, make([]byte, paddedProof-len(proofBytes))...)
+	calldata = append(calldata, abiWord(big.NewInt(int64(len(inputs))))...)
+	for _, input := range inputs {
+		calldata = append(calldata, abiWord(input)...)
+	}
+
+	return solidityCall(function, calldata, proofBytes, inputs), nil
+}
+
+// abiSelector returns the first four bytes of keccak256(signature)
+func abiSelector(signature string) []byte {
+	h := sha3.NewLegacyKeccak256()
+	h.Write([]byte(signature))
+	return h.Sum(nil)[:4]
+}
+
+// abiWord encodes an unsigned integer as a 32-byte big-endian word
+func abiWord(v *big.Int) []byte {
+	return v.FillBytes(make([]byte, 32))
+}
+
+func solidityCall(function string, calldata, proof []byte, inputs []*big.Int) *prover.SolidityCalldata {
+	publicInputs := make([]string, len(inputs))
+	for i, input := range inputs {
+		publicInputs[i] = input.String()
+	}
+
+	return &prover.SolidityCalldata{
+		Function:     function,
+		Calldata:     "0x" + hex.EncodeToString(calldata),
+		Proof:        "0x" + hex.EncodeToString(proof),
+		PublicInputs: publicInputs,
+	}
+}
@@ -0,0 +1,271 @@
+package gnark
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/gabrielrondon/zapiki/internal/models"
+	"github.com/gabrielrondon/zapiki/internal/prover"
+	"github.com/gabrielrondon/zapiki/internal/storage/artifact"
+)
+
+// solidityCircuit runs setup for a simple circuit and stores its keys the
+// way the circuit service does
+func solidityCircuit(t *testing.T, p prover.ProofSystem, store artifact.Store) *models.Circuit {
+	t.Helper()
+	ctx := context.Background()
+
+	circuitDefJSON, _ := json.Marshal(map[string]interface{}{"circuit_type": "simple"})
+	circuit := &models.Circuit{CircuitDefinition: circuitDefJSON}
+
+	setup, err := p.Setup(ctx, circuit)
+	if err != nil {
+		t.Fatalf("Failed to setup: %v", err)
+	}
+
+	circuit.ProvingKeyURL, err = store.Put(ctx, "circuits/test/proving_key.json", setup.ProvingKey)
+	if err != nil {
+		t.Fatalf("Failed to store proving key: %v", err)
+	}
+	circuit.VerificationKeyURL, err = store.Put(ctx, "circuits/test/verification_key.json", setup.VerificationKey)
+	if err != nil {
+		t.Fatalf("Failed to store verification key: %v", err)
+	}
+
+	return circuit
+}
+
+func simpleCircuitProof(t *testing.T, p prover.ProofSystem, circuit *models.Circuit, x, y, z int) *prover.ProofResponse {
+	t.Helper()
+
+	inputJSON, _ := json.Marshal(map[string]interface{}{"x": x, "y": y, "z": z})
+	resp, err := p.Generate(context.Background(), &prover.ProofRequest{
+		Circuit: circuit,
+		Data:    &models.InputData{Type: models.DataTypeJSON, Value: inputJSON},
+	})
+	if err != nil {
+		t.Fatalf("Failed to generate proof: %v", err)
+	}
+	return resp
+}
+
+// contractConstants reads the uint256 constants of an exported verifier
+func contractConstants(t *testing.T, source string) map[string]*big.Int {
+	t.Helper()
+
+	constants := make(map[string]*big.Int)
+	pattern := regexp.MustCompile(`uint256 constant (\w+) = (\d+);`)
+	for _, match := range pattern.FindAllStringSubmatch(source, -1) {
+		constants[match[1]], _ = new(big.Int).SetString(match[2], 10)
+	}
+	return constants
+}
+
+func g1Point(t *testing.T, x, y *big.Int) bn254.G1Affine {
+	t.Helper()
+
+	var p bn254.G1Affine
+	p.X.SetBigInt(x)
+	p.Y.SetBigInt(y)
+	if !p.IsOnCurve() {
+		t.Fatal("Expected G1 point on curve")
+	}
+	return p
+}
+
+// g2Point builds a G2 point from EIP-197 words: x.A1, x.A0, y.A1, y.A0
+func g2Point(t *testing.T, words ...*big.Int) bn254.G2Affine {
+	t.Helper()
+
+	var p bn254.G2Affine
+	p.X.A1.SetBigInt(words[0])
+	p.X.A0.SetBigInt(words[1])
+	p.Y.A1.SetBigInt(words[2])
+	p.Y.A0.SetBigInt(words[3])
+	if !p.IsOnCurve() {
+		t.Fatal("Expected G2 point on curve")
+	}
+	return p
+}
+
+// contractAccepts replays the pairing check of the exported Groth16
+// contract on its constants and the ABI-encoded calldata
+func contractAccepts(t *testing.T, constants map[string]*big.Int, calldata *prover.SolidityCalldata) bool {
+	t.Helper()
+
+	raw, err := hex.DecodeString(strings.TrimPrefix(calldata.Calldata, "0x"))
+	if err != nil {
+		t.Fatalf("Failed to decode calldata: %v", err)
+	}
+	if !bytes.Equal(raw[:4], abiSelector(calldata.Function)) {
+		t.Fatal("Expected calldata to start with the function selector")
+	}
+
+	var words []*big.Int
+	for i := 4; i < len(raw); i += 32 {
+		words = append(words, new(big.Int).SetBytes(raw[i:i+32]))
+	}
+
+	a := g1Point(t, words[0], words[1])
+	b := g2Point(t, words[2], words[3], words[4], words[5])
+	c := g1Point(t, words[6], words[7])
+
+	// L_pub = CONSTANT + sum(input_i * PUB_i)
+	l := g1Point(t, constants["CONSTANT_X"], constants["CONSTANT_Y"])
+	for i, input := range words[8:] {
+		name := "PUB_" + strconv.Itoa(i)
+		pub := g1Point(t, constants[name+"_X"], constants[name+"_Y"])
+		var term bn254.G1Affine
+		term.ScalarMultiplication(&pub, input)
+		l.Add(&l, &term)
+	}
+
+	alpha := g1Point(t, constants["ALPHA_X"], constants["ALPHA_Y"])
+	negG2 := func(prefix string) bn254.G2Affine {
+		return g2Point(t, constants[prefix+"_X_1"], constants[prefix+"_X_0"], constants[prefix+"_Y_1"], constants[prefix+"_Y_0"])
+	}
+
+	ok, err := bn254.PairingCheck(
+		[]bn254.G1Affine{a, c, alpha, l},
+		[]bn254.G2Affine{b, negG2("DELTA_NEG"), negG2("BETA_NEG"), negG2("GAMMA_NEG")},
+	)
+	if err != nil {
+		t.Fatalf("Failed to run pairing check: %v", err)
+	}
+	return ok
+}
+
+func TestGroth16Prover_SolidityRoundTrip(t *testing.T) {
+	ctx := context.Background()
+
+	store, err := artifact.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create artifact store: %v", err)
+	}
+
+	p := NewGroth16Prover()
+	p.SetArtifactStore(store)
+	circuit := solidityCircuit(t, p, store)
+
+	var source bytes.Buffer
+	if err := p.ExportSolidity(ctx, circuit, &source); err != nil {
+		t.Fatalf("Failed to export verifier: %v", err)
+	}
+	if !strings.Contains(source.String(), "function verifyProof(") {
+		t.Fatal("Expected a verifyProof function in the exported contract")
+	}
+	constants := contractConstants(t, source.String())
+
+	valid := simpleCircuitProof(t, p, circuit, 3, 5, 15)
+	other := simpleCircuitProof(t, p, circuit, 3, 7, 21)
+
+	tests := []struct {
+		name         string
+		publicInputs json.RawMessage
+		valid        bool
+	}{
+		{"matching inputs", valid.PublicInputs, true},
+		{"other proof's inputs", other.PublicInputs, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &prover.VerifyRequest{
+				Proof:              valid.Proof,
+				PublicInputs:       tt.publicInputs,
+				VerificationKeyURL: circuit.VerificationKeyURL,
+				Circuit:            circuit,
+			}
+
+			native, err := p.Verify(ctx, req)
+			if err != nil {
+				t.Fatalf("Failed to verify: %v", err)
+			}
+			if native.Valid != tt.valid {
+				t.Fatalf("Expected native verification %v, got %v", tt.valid, native.Valid)
+			}
+
+			calldata, err := p.SolidityCalldata(ctx, req)
+			if err != nil {
+				t.Fatalf("Failed to encode calldata: %v", err)
+			}
+			if calldata.Function != "verifyProof(uint256[8],uint256[1])" {
+				t.Errorf("Unexpected function %s", calldata.Function)
+			}
+
+			if got := contractAccepts(t, constants, calldata); got != native.Valid {
+				t.Errorf("Contract pairing check returned %v, native verifier %v", got, native.Valid)
+			}
+		})
+	}
+}
+
+func TestPLONKProver_SolidityCalldata(t *testing.T) {
+	ctx := context.Background()
+
+	store, err := artifact.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create artifact store: %v", err)
+	}
+
+	p := NewPLONKProver()
+	p.SetArtifactStore(store)
+	circuit := solidityCircuit(t, p, store)
+
+	var source bytes.Buffer
+	if err := p.ExportSolidity(ctx, circuit, &source); err != nil {
+		t.Fatalf("Failed to export verifier: %v", err)
+	}
+	if !strings.Contains(source.String(), "function Verify(bytes calldata proof, uint256[] calldata public_inputs)") {
+		t.Fatal("Expected a Verify function in the exported contract")
+	}
+
+	resp := simpleCircuitProof(t, p, circuit, 3, 5, 15)
+	calldata, err := p.SolidityCalldata(ctx, &prover.VerifyRequest{
+		Proof:        resp.Proof,
+		PublicInputs: resp.PublicInputs,
+		Circuit:      circuit,
+	})
+	if err != nil {
+		t.Fatalf("Failed to encode calldata: %v", err)
+	}
+
+	raw, _ := hex.DecodeString(strings.TrimPrefix(calldata.Calldata, "0x"))
+	proof, _ := hex.DecodeString(strings.TrimPrefix(calldata.Proof, "0x"))
+	word := func(offset int) int64 {
+		return new(big.Int).SetBytes(raw[4+offset : 4+offset+32]).Int64()
+	}
+
+	// Verify(bytes proof, uint256[] public_inputs)
+	proofOffset, inputsOffset := int(word(0)), int(word(32))
+	if word(proofOffset) != int64(len(proof)) {
+		t.Fatalf("Expected proof length %d, got %d", len(proof), word(proofOffset))
+	}
+	if !bytes.Equal(raw[4+proofOffset+32:4+proofOffset+32+len(proof)], proof) {
+		t.Error("Expected the encoded proof to match")
+	}
+	if word(inputsOffset) != 1 || word(inputsOffset+32) != 15 {
+		t.Errorf("Expected public inputs [15], got %v", calldata.PublicInputs)
+	}
+}
+
+func TestSolidityExport_RejectsNonBN254(t *testing.T) {
+	p := NewGroth16Prover()
+
+	circuitDefJSON, _ := json.Marshal(map[string]interface{}{"circuit_type": "simple", "curve": "bls12_381"})
+	circuit := &models.Circuit{CircuitDefinition: circuitDefJSON, VerificationKeyURL: "file:///unused"}
+
+	err := p.ExportSolidity(context.Background(), circuit, &bytes.Buffer{})
+	if !errors.Is(err, prover.ErrSolidityUnsupported) {
+		t.Fatalf("Expected ErrSolidityUnsupported, got %v", err)
+	}
+}
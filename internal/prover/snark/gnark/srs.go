@@ -0,0 +1,374 @@
+package gnark
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	bls12377 "github.com/consensys/gnark-crypto/ecc/bls12-377"
+	frbls12377 "github.com/consensys/gnark-crypto/ecc/bls12-377/fr"
+	kzgbls12377 "github.com/consensys/gnark-crypto/ecc/bls12-377/kzg"
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	frbls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+	kzgbls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381/kzg"
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	frbn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	kzgbn254 "github.com/consensys/gnark-crypto/ecc/bn254/kzg"
+	bw6761 "github.com/consensys/gnark-crypto/ecc/bw6-761"
+	frbw6761 "github.com/consensys/gnark-crypto/ecc/bw6-761/fr"
+	kzgbw6761 "github.com/consensys/gnark-crypto/ecc/bw6-761/kzg"
+	"github.com/consensys/gnark-crypto/kzg"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/test/unsafekzg"
+)
+
+// ErrSRSIntegrity is returned when an SRS file fails its checksum or is not
+// a well-formed powers-of-tau sequence
+var ErrSRSIntegrity = errors.New("SRS integrity check failed")
+
+// SRSProvider supplies the KZG structured reference string used by PLONK
+// setup. One SRS serves every circuit on a curve, trimmed to each circuit.
+type SRSProvider interface {
+	// ID identifies the SRS for a curve, so keys set up from different
+	// SRS are never mixed up in the key cache
+	ID(curve ecc.ID) string
+
+	// SRS returns the canonical and Lagrange SRS sized for ccs
+	SRS(ccs constraint.ConstraintSystem) (canonical kzg.SRS, lagrange kzg.SRS, err error)
+}
+
+// srsSizes returns the Lagrange and canonical SRS sizes PLONK needs for ccs
+func srsSizes(ccs constraint.ConstraintSystem) (lagrange, canonical uint64) {
+	sizeSystem := ccs.GetNbConstraints() + ccs.GetNbPublicVariables()
+	lagrange = ecc.NextPowerOfTwo(uint64(sizeSystem))
+	return lagrange, lagrange + 3
+}
+
+// circuitCurve returns the curve a constraint system was compiled for
+func circuitCurve(ccs constraint.ConstraintSystem) (ecc.ID, error) {
+	for _, id := range SupportedCurves {
+		if id.ScalarField().Cmp(ccs.Field()) == 0 {
+			return id, nil
+		}
+	}
+	return ecc.UNKNOWN, fmt.Errorf("unsupported circuit field %s", ccs.Field())
+}
+
+// DevSRS generates a fresh test SRS whose toxic waste is only discarded,
+// never destroyed in a ceremony. It must not be used in production.
+type DevSRS struct{}
+
+// NewDevSRS creates a development SRS provider
+func NewDevSRS() *DevSRS {
+	return &DevSRS{}
+}
+
+// ID implements SRSProvider
+func (DevSRS) ID(curve ecc.ID) string {
+	return "unsafe-dev"
+}
+
+// SRS implements SRSProvider
+func (DevSRS) SRS(ccs constraint.ConstraintSystem) (kzg.SRS, kzg.SRS, error) {
+	canonical, lagrange, err := unsafekzg.NewSRS(ccs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate SRS: %w", err)
+	}
+	return canonical, lagrange, nil
+}
+
+// FileSRS serves KZG SRS loaded from ceremony transcripts converted to
+// gnark's kzg.SRS serialization, one file per curve named <curve>.srs
+// (e.g. bn254.srs). Files are checked once when loaded; Lagrange forms are
+// computed once per circuit size and shared.
+type FileSRS struct {
+	curves map[ecc.ID]*srsEntry
+}
+
+type srsEntry struct {
+	canonical kzg.SRS
+	digest    string
+
+	mu       sync.Mutex
+	lagrange map[uint64]kzg.SRS
+}
+
+// NewFileSRS loads and checks the SRS files in dir. checksums optionally
+// pins the hex SHA-256 of a curve's file; every file is also checked to be
+// a consistent powers-of-tau sequence. Curves without a file are not served.
+func NewFileSRS(dir string, checksums map[string]string) (*FileSRS, error) {
+	s := &FileSRS{curves: make(map[ecc.ID]*srsEntry)}
+
+	for _, curve := range SupportedCurves {
+		path := filepath.Join(dir, curve.String()+".srs")
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			if _, pinned := checksums[curve.String()]; pinned {
+				return nil, fmt.Errorf("missing SRS file %s", path)
+			}
+			continue
+		}
+
+		entry, err := loadSRSFile(curve, path, checksums[curve.String()])
+		if err != nil {
+			return nil, err
+		}
+		s.curves[curve] = entry
+	}
+
+	if len(s.curves) == 0 {
+		return nil, fmt.Errorf("no SRS files found in %s", dir)
+	}
+	return s, nil
+}
+
+// loadSRSFile reads one SRS file, verifying its checksum and structure
+func loadSRSFile(curve ecc.ID, path, checksum string) (*srsEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SRS file: %w", err)
+	}
+	defer f.Close()
+
+	// ReadFrom also checks every point is on the curve and in the subgroup
+	h := sha256.New()
+	srs := kzg.NewSRS(curve)
+	if _, err := srs.ReadFrom(io.TeeReader(f, h)); err != nil {
+		return nil, fmt.Errorf("failed to read SRS file %s: %w", path, err)
+	}
+	// Hash whatever follows too, so the checksum covers the whole file
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, fmt.Errorf("failed to read SRS file %s: %w", path, err)
+	}
+
+	digest := hex.EncodeToString(h.Sum(nil))
+	if checksum != "" && checksum != digest {
+		return nil, fmt.Errorf("%w: %s has sha256 %s, expected %s", ErrSRSIntegrity, path, digest, checksum)
+	}
+
+	if err := checkPowersOfTau(srs); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrSRSIntegrity, path, err)
+	}
+
+	return &srsEntry{
+		canonical: srs,
+		digest:    digest,
+		lagrange:  make(map[uint64]kzg.SRS),
+	}, nil
+}
+
+// ID implements SRSProvider
+func (s *FileSRS) ID(curve ecc.ID) string {
+	entry, ok := s.curves[curve]
+	if !ok {
+		return "missing"
+	}
+	return "sha256-" + entry.digest[:16]
+}
+
+// Curves returns the curves an SRS file was loaded for
+func (s *FileSRS) Curves() []ecc.ID {
+	var curves []ecc.ID
+	for _, curve := range SupportedCurves {
+		if _, ok := s.curves[curve]; ok {
+			curves = append(curves, curve)
+		}
+	}
+	return curves
+}
+
+// SRS implements SRSProvider
+func (s *FileSRS) SRS(ccs constraint.ConstraintSystem) (kzg.SRS, kzg.SRS, error) {
+	curve, err := circuitCurve(ccs)
+	if err != nil {
+		return nil, nil, err
+	}
+	entry, ok := s.curves[curve]
+	if !ok {
+		return nil, nil, fmt.Errorf("no SRS loaded for curve %s", curve)
+	}
+
+	sizeLagrange, sizeCanonical := srsSizes(ccs)
+	if available := uint64(srsLen(entry.canonical)); available < sizeCanonical {
+		return nil, nil, fmt.Errorf("SRS for %s has %d points, circuit needs %d", curve, available, sizeCanonical)
+	}
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	lagrange, ok := entry.lagrange[sizeLagrange]
+	if !ok {
+		lagrange, err = lagrangeSRS(entry.canonical, sizeLagrange)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to compute Lagrange SRS: %w", err)
+		}
+		entry.lagrange[sizeLagrange] = lagrange
+	}
+
+	return trimSRS(entry.canonical, sizeCanonical), lagrange, nil
+}
+
+// The helpers below repeat the same steps for each curve, since the
+// gnark-crypto kzg packages share an API but no common Go types.
+
+// srsLen returns the number of G1 points in a canonical SRS
+func srsLen(srs kzg.SRS) int {
+	switch s := srs.(type) {
+	case *kzgbn254.SRS:
+		return len(s.Pk.G1)
+	case *kzgbls12381.SRS:
+		return len(s.Pk.G1)
+	case *kzgbls12377.SRS:
+		return len(s.Pk.G1)
+	case *kzgbw6761.SRS:
+		return len(s.Pk.G1)
+	default:
+		return 0
+	}
+}
+
+// trimSRS returns the first size points of a canonical SRS, sharing memory
+func trimSRS(srs kzg.SRS, size uint64) kzg.SRS {
+	switch s := srs.(type) {
+	case *kzgbn254.SRS:
+		return &kzgbn254.SRS{Pk: kzgbn254.ProvingKey{G1: s.Pk.G1[:size]}, Vk: s.Vk}
+	case *kzgbls12381.SRS:
+		return &kzgbls12381.SRS{Pk: kzgbls12381.ProvingKey{G1: s.Pk.G1[:size]}, Vk: s.Vk}
+	case *kzgbls12377.SRS:
+		return &kzgbls12377.SRS{Pk: kzgbls12377.ProvingKey{G1: s.Pk.G1[:size]}, Vk: s.Vk}
+	case *kzgbw6761.SRS:
+		return &kzgbw6761.SRS{Pk: kzgbw6761.ProvingKey{G1: s.Pk.G1[:size]}, Vk: s.Vk}
+	default:
+		return nil
+	}
+}
+
+// lagrangeSRS converts the first size points of a canonical SRS to Lagrange form
+func lagrangeSRS(srs kzg.SRS, size uint64) (kzg.SRS, error) {
+	switch s := srs.(type) {
+	case *kzgbn254.SRS:
+		g1, err := kzgbn254.ToLagrangeG1(s.Pk.G1[:size])
+		return &kzgbn254.SRS{Pk: kzgbn254.ProvingKey{G1: g1}, Vk: s.Vk}, err
+	case *kzgbls12381.SRS:
+		g1, err := kzgbls12381.ToLagrangeG1(s.Pk.G1[:size])
+		return &kzgbls12381.SRS{Pk: kzgbls12381.ProvingKey{G1: g1}, Vk: s.Vk}, err
+	case *kzgbls12377.SRS:
+		g1, err := kzgbls12377.ToLagrangeG1(s.Pk.G1[:size])
+		return &kzgbls12377.SRS{Pk: kzgbls12377.ProvingKey{G1: g1}, Vk: s.Vk}, err
+	case *kzgbw6761.SRS:
+		g1, err := kzgbw6761.ToLagrangeG1(s.Pk.G1[:size])
+		return &kzgbw6761.SRS{Pk: kzgbw6761.ProvingKey{G1: g1}, Vk: s.Vk}, err
+	default:
+		return nil, fmt.Errorf("unsupported SRS type %T", srs)
+	}
+}
+
+// checkPowersOfTau checks that G1 = [G, τG, τ²G, ...] for the τ committed to
+// by the verifying key [H, τH]. With random r, it checks
+// e(Σ rᵢ·G1[i], τH) = e(Σ rᵢ·G1[i+1], H), which any point off the sequence
+// breaks except with negligible probability.
+func checkPowersOfTau(srs kzg.SRS) error {
+	switch s := srs.(type) {
+	case *kzgbn254.SRS:
+		if len(s.Pk.G1) < 2 || !s.Pk.G1[0].Equal(&s.Vk.G1) {
+			return fmt.Errorf("first G1 point is not the generator")
+		}
+		n := len(s.Pk.G1) - 1
+		r := make([]frbn254.Element, n)
+		for i := range r {
+			if _, err := r[i].SetRandom(); err != nil {
+				return err
+			}
+		}
+		var a, b bn254.G1Affine
+		if _, err := a.MultiExp(s.Pk.G1[:n], r, ecc.MultiExpConfig{}); err != nil {
+			return err
+		}
+		if _, err := b.MultiExp(s.Pk.G1[1:], r, ecc.MultiExpConfig{}); err != nil {
+			return err
+		}
+		b.Neg(&b)
+		return pairingResult(bn254.PairingCheck([]bn254.G1Affine{a, b}, []bn254.G2Affine{s.Vk.G2[1], s.Vk.G2[0]}))
+
+	case *kzgbls12381.SRS:
+		if len(s.Pk.G1) < 2 || !s.Pk.G1[0].Equal(&s.Vk.G1) {
+			return fmt.Errorf("first G1 point is not the generator")
+		}
+		n := len(s.Pk.G1) - 1
+		r := make([]frbls12381.Element, n)
+		for i := range r {
+			if _, err := r[i].SetRandom(); err != nil {
+				return err
+			}
+		}
+		var a, b bls12381.G1Affine
+		if _, err := a.MultiExp(s.Pk.G1[:n], r, ecc.MultiExpConfig{}); err != nil {
+			return err
+		}
+		if _, err := b.MultiExp(s.Pk.G1[1:], r, ecc.MultiExpConfig{}); err != nil {
+			return err
+		}
+		b.Neg(&b)
+		return pairingResult(bls12381.PairingCheck([]bls12381.G1Affine{a, b}, []bls12381.G2Affine{s.Vk.G2[1], s.Vk.G2[0]}))
+
+	case *kzgbls12377.SRS:
+		if len(s.Pk.G1) < 2 || !s.Pk.G1[0].Equal(&s.Vk.G1) {
+			return fmt.Errorf("first G1 point is not the generator")
+		}
+		n := len(s.Pk.G1) - 1
+		r := make([]frbls12377.Element, n)
+		for i := range r {
+			if _, err := r[i].SetRandom(); err != nil {
+				return err
+			}
+		}
+		var a, b bls12377.G1Affine
+		if _, err := a.MultiExp(s.Pk.G1[:n], r, ecc.MultiExpConfig{}); err != nil {
+			return err
+		}
+		if _, err := b.MultiExp(s.Pk.G1[1:], r, ecc.MultiExpConfig{}); err != nil {
+			return err
+		}
+		b.Neg(&b)
+		return pairingResult(bls12377.PairingCheck([]bls12377.G1Affine{a, b}, []bls12377.G2Affine{s.Vk.G2[1], s.Vk.G2[0]}))
+
+	case *kzgbw6761.SRS:
+		if len(s.Pk.G1) < 2 || !s.Pk.G1[0].Equal(&s.Vk.G1) {
+			return fmt.Errorf("first G1 point is not the generator")
+		}
+		n := len(s.Pk.G1) - 1
+		r := make([]frbw6761.Element, n)
+		for i := range r {
+			if _, err := r[i].SetRandom(); err != nil {
+				return err
+			}
+		}
+		var a, b bw6761.G1Affine
+		if _, err := a.MultiExp(s.Pk.G1[:n], r, ecc.MultiExpConfig{}); err != nil {
+			return err
+		}
+		if _, err := b.MultiExp(s.Pk.G1[1:], r, ecc.MultiExpConfig{}); err != nil {
+			return err
+		}
+		b.Neg(&b)
+		return pairingResult(bw6761.PairingCheck([]bw6761.G1Affine{a, b}, []bw6761.G2Affine{s.Vk.G2[1], s.Vk.G2[0]}))
+
+	default:
+		return fmt.Errorf("unsupported SRS type %T", srs)
+	}
+}
+
+func pairingResult(ok bool, err error) error {
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("G1 points are not successive powers of tau")
+	}
+	return nil
+}
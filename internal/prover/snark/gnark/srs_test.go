@@ -0,0 +1,156 @@
+package gnark
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	kzgbn254 "github.com/consensys/gnark-crypto/ecc/bn254/kzg"
+	"github.com/gabrielrondon/zapiki/internal/models"
+	"github.com/gabrielrondon/zapiki/internal/prover"
+)
+
+// writeTestSRS writes a BN254 SRS of the given size to dir and returns its checksum
+func writeTestSRS(t *testing.T, dir string, size uint64, tamper bool) string {
+	t.Helper()
+
+	srs, err := kzgbn254.NewSRS(size, big.NewInt(42))
+	if err != nil {
+		t.Fatalf("Failed to create SRS: %v", err)
+	}
+	if tamper {
+		srs.Pk.G1[2].Add(&srs.Pk.G1[2], &srs.Pk.G1[0])
+	}
+
+	f, err := os.Create(filepath.Join(dir, "bn254.srs"))
+	if err != nil {
+		t.Fatalf("Failed to create SRS file: %v", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := srs.WriteTo(f); err != nil {
+		t.Fatalf("Failed to write SRS: %v", err)
+	}
+	if _, err := srs.WriteTo(h); err != nil {
+		t.Fatalf("Failed to hash SRS: %v", err)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func TestPLONKProver_FileSRS(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	checksum := writeTestSRS(t, dir, 64, false)
+
+	srs, err := NewFileSRS(dir, map[string]string{"bn254": checksum})
+	if err != nil {
+		t.Fatalf("Failed to load SRS: %v", err)
+	}
+
+	p := NewPLONKProver()
+	p.SetSRS(srs)
+
+	circuitDefJSON, _ := json.Marshal(map[string]interface{}{"circuit_type": "simple"})
+	circuit := &models.Circuit{CircuitDefinition: circuitDefJSON}
+
+	setup, err := p.Setup(ctx, circuit)
+	if err != nil {
+		t.Fatalf("Failed to setup: %v", err)
+	}
+	if setup.Metadata["srs"] != srs.ID(DefaultCurve) {
+		t.Errorf("Expected srs %s in metadata, got %v", srs.ID(DefaultCurve), setup.Metadata["srs"])
+	}
+
+	resp := simpleCircuitProof(t, p, circuit, 3, 5, 15)
+	verifyResp, err := p.Verify(ctx, &prover.VerifyRequest{
+		Proof:           resp.Proof,
+		PublicInputs:    resp.PublicInputs,
+		VerificationKey: resp.VerificationKey,
+	})
+	if err != nil {
+		t.Fatalf("Failed to verify: %v", err)
+	}
+	if !verifyResp.Valid {
+		t.Errorf("Expected proof to be valid, got error: %s", verifyResp.ErrorMessage)
+	}
+}
+
+func TestPLONKProver_SRSKeysAreNotShared(t *testing.T) {
+	dir := t.TempDir()
+	writeTestSRS(t, dir, 64, false)
+
+	srs, err := NewFileSRS(dir, nil)
+	if err != nil {
+		t.Fatalf("Failed to load SRS: %v", err)
+	}
+
+	dev := NewPLONKProver()
+	file := NewPLONKProver()
+	file.SetSRS(srs)
+
+	if dev.cacheKey(DefaultCurve, "simple", nil).String() == file.cacheKey(DefaultCurve, "simple", nil).String() {
+		t.Error("Expected keys from different SRS to be cached separately")
+	}
+}
+
+func TestNewFileSRS_Integrity(t *testing.T) {
+	t.Run("tampered point", func(t *testing.T) {
+		dir := t.TempDir()
+		writeTestSRS(t, dir, 16, true)
+
+		_, err := NewFileSRS(dir, nil)
+		if !errors.Is(err, ErrSRSIntegrity) {
+			t.Fatalf("Expected ErrSRSIntegrity, got %v", err)
+		}
+	})
+
+	t.Run("checksum mismatch", func(t *testing.T) {
+		dir := t.TempDir()
+		writeTestSRS(t, dir, 16, false)
+
+		_, err := NewFileSRS(dir, map[string]string{"bn254": hex.EncodeToString(make([]byte, 32))})
+		if !errors.Is(err, ErrSRSIntegrity) {
+			t.Fatalf("Expected ErrSRSIntegrity, got %v", err)
+		}
+	})
+
+	t.Run("missing pinned file", func(t *testing.T) {
+		dir := t.TempDir()
+		writeTestSRS(t, dir, 16, false)
+
+		if _, err := NewFileSRS(dir, map[string]string{"bls12_381": "00"}); err == nil {
+			t.Fatal("Expected an error for a pinned curve without a file")
+		}
+	})
+
+	t.Run("empty directory", func(t *testing.T) {
+		if _, err := NewFileSRS(t.TempDir(), nil); err == nil {
+			t.Fatal("Expected an error for a directory without SRS files")
+		}
+	})
+}
+
+func TestFileSRS_TooSmall(t *testing.T) {
+	dir := t.TempDir()
+	writeTestSRS(t, dir, 4, false)
+
+	srs, err := NewFileSRS(dir, nil)
+	if err != nil {
+		t.Fatalf("Failed to load SRS: %v", err)
+	}
+
+	p := NewPLONKProver()
+	p.SetSRS(srs)
+
+	circuitDefJSON, _ := json.Marshal(map[string]interface{}{"circuit_type": "range_proof"})
+	if _, err := p.Setup(context.Background(), &models.Circuit{CircuitDefinition: circuitDefJSON}); err == nil {
+		t.Fatal("Expected setup to fail with an SRS smaller than the circuit")
+	}
+}
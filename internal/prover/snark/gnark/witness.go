@@ -0,0 +1,264 @@
+package gnark
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/schema"
+	"github.com/gabrielrondon/zapiki/internal/models"
+	"github.com/gabrielrondon/zapiki/internal/prover"
+)
+
+// decodeInputs parses proof inputs, keeping JSON numbers exact
+func decodeInputs(data json.RawMessage) (map[string]interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var inputs map[string]interface{}
+	if err := dec.Decode(&inputs); err != nil {
+		return nil, err
+	}
+	return inputs, nil
+}
+
+// Inputs reads proof inputs as elements of a scalar field. Missing and
+// malformed fields are collected so that Err names all of them.
+type Inputs struct {
+	values  map[string]interface{}
+	modulus *big.Int
+	errs    []string
+}
+
+// NewInputs reads values as elements of the field with the given modulus
+func NewInputs(values map[string]interface{}, modulus *big.Int) *Inputs {
+	return &Inputs{values: values, modulus: modulus}
+}
+
+// Values returns the inputs as decoded from JSON
+func (in *Inputs) Values() map[string]interface{} {
+	return in.values
+}
+
+// Element reads v as an element of the inputs' field
+func (in *Inputs) Element(v interface{}) (*big.Int, error) {
+	return parseFieldElement(v, in.modulus)
+}
+
+// Field returns the named field element, or nil after recording an error
+func (in *Inputs) Field(name string) *big.Int {
+	v, ok := in.values[name]
+	if !ok || v == nil {
+		in.errs = append(in.errs, fmt.Sprintf("%s is required", name))
+		return nil
+	}
+
+	n, err := parseFieldElement(v, in.modulus)
+	if err != nil {
+		in.errs = append(in.errs, fmt.Sprintf("%s: %v", name, err))
+		return nil
+	}
+	return n
+}
+
+// Fields returns the named array of length field elements, or nil after
+// recording an error
+func (in *Inputs) Fields(name string, length int) []frontend.Variable {
+	v, ok := in.values[name]
+	if !ok || v == nil {
+		in.errs = append(in.errs, fmt.Sprintf("%s is required", name))
+		return nil
+	}
+
+	items, ok := v.([]interface{})
+	if !ok || len(items) != length {
+		in.errs = append(in.errs, fmt.Sprintf("%s must be an array of %d field elements", name, length))
+		return nil
+	}
+
+	values := make([]frontend.Variable, length)
+	for i, item := range items {
+		n, err := parseFieldElement(item, in.modulus)
+		if err != nil {
+			in.errs = append(in.errs, fmt.Sprintf("%s[%d]: %v", name, i, err))
+			continue
+		}
+		values[i] = n
+	}
+	return values
+}
+
+// Err reports every missing or malformed field read so far
+func (in *Inputs) Err() error {
+	if len(in.errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", prover.ErrInvalidWitness, strings.Join(in.errs, "; "))
+}
+
+// parseFieldElement reads a JSON number, a decimal string, a 0x-prefixed
+// hex string or base64 big-endian bytes as an element of the field.
+// Strings of digits only are decimal, even when they are valid base64;
+// other strings of hex digits only are rejected as ambiguous.
+func parseFieldElement(v interface{}, modulus *big.Int) (*big.Int, error) {
+	var n *big.Int
+	switch v := v.(type) {
+	case json.Number:
+		var ok bool
+		if n, ok = new(big.Int).SetString(v.String(), 10); !ok {
+			return nil, fmt.Errorf("%s is not an integer", v)
+		}
+	case float64:
+		if v != math.Trunc(v) || math.Abs(v) > 1<<53 {
+			return nil, fmt.Errorf("%v is not an exact integer; pass large values as strings", v)
+		}
+		n = big.NewInt(int64(v))
+	case int:
+		n = big.NewInt(int64(v))
+	case int64:
+		n = big.NewInt(v)
+	case string:
+		var err error
+		if n, err = parseFieldString(v); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("must be a number or a string")
+	}
+
+	if n.Sign() < 0 {
+		return nil, fmt.Errorf("must not be negative")
+	}
+	if n.Cmp(modulus) >= 0 {
+		return nil, fmt.Errorf("exceeds the scalar field")
+	}
+	return n, nil
+}
+
+// parseFieldString reads a decimal, 0x-hex or base64 string
+func parseFieldString(s string) (*big.Int, error) {
+	switch {
+	case s == "":
+		return nil, fmt.Errorf("must not be empty")
+	case strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X"):
+		n, ok := new(big.Int).SetString(s[2:], 16)
+		if !ok || s[2:] == "" {
+			return nil, fmt.Errorf("%q is not a hex number", s)
+		}
+		return n, nil
+	case strings.Trim(s, "0123456789") == "":
+		n, _ := new(big.Int).SetString(s, 10)
+		return n, nil
+	case s[0] == '-':
+		if n, ok := new(big.Int).SetString(s, 10); ok {
+			return n, nil
+		}
+	case strings.Trim(s, "0123456789abcdefABCDEF") == "":
+		// Un-prefixed hex such as "deadbeef" is also valid base64
+		return nil, fmt.Errorf("%q could be hex or base64; prefix hex with 0x", s)
+	}
+
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding} {
+		if data, err := enc.DecodeString(s); err == nil {
+			return new(big.Int).SetBytes(data), nil
+		}
+	}
+	return nil, fmt.Errorf("%q is not a decimal, 0x-hex or base64 number", s)
+}
+
+// tVariable is the type of circuit inputs, the leaves of a circuit's schema
+var tVariable = reflect.TypeOf((*frontend.Variable)(nil)).Elem()
+
+// namedPublicInputs pairs a proof's public inputs with their names: the
+// public signals of a Circom circuit, the declared inputs of a custom
+// circuit, or the public inputs of a registered circuit type. It returns
+// nil when the names cannot be matched to the values.
+func namedPublicInputs(circuitDef *circuitDefinition, assignment frontend.Circuit, field *big.Int, publicWitness witness.Witness) []models.NamedPublicInput {
+	values, err := publicInputStrings(publicWitness)
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	named, ok := assignment.(interface{ PublicNames() []string })
+	switch {
+	case circuitDef.Circom != nil:
+		names = circuitDef.Circom.PublicSignals
+	case ok:
+		names = named.PublicNames()
+	default:
+		if spec, err := LookupCircuit(circuitDef.CircuitType); err == nil {
+			names = publicFieldNames(assignment, spec.PublicInputs)
+		}
+		if names == nil {
+			names = publicLeafNames(assignment, field)
+		}
+	}
+	if len(names) != len(values) {
+		return nil
+	}
+
+	inputs := make([]models.NamedPublicInput, len(values))
+	for i := range values {
+		inputs[i] = models.NamedPublicInput{Name: names[i], Value: values[i]}
+	}
+	return inputs
+}
+
+// publicFieldNames names the public witness entries of a circuit after
+// specNames, one per public field in declaration order: "name" for a
+// variable and "name[i]" for the elements of a slice. It returns nil when
+// the fields do not match specNames.
+func publicFieldNames(assignment frontend.Circuit, specNames []string) []string {
+	v := reflect.Indirect(reflect.ValueOf(assignment))
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var names []string
+	n := 0
+	for i := range v.NumField() {
+		if !strings.HasSuffix(v.Type().Field(i).Tag.Get("gnark"), ",public") {
+			continue
+		}
+		if n == len(specNames) {
+			return nil
+		}
+		switch f := v.Field(i); f.Kind() {
+		case reflect.Slice, reflect.Array:
+			for j := range f.Len() {
+				names = append(names, fmt.Sprintf("%s[%d]", specNames[n], j))
+			}
+		default:
+			names = append(names, specNames[n])
+		}
+		n++
+	}
+	if n != len(specNames) {
+		return nil
+	}
+	return names
+}
+
+// publicLeafNames names the public witness entries after the circuit's
+// fields, as gnark does
+func publicLeafNames(assignment frontend.Circuit, field *big.Int) []string {
+	var names []string
+	_, err := schema.Walk(field, assignment, tVariable, func(leaf schema.LeafInfo, _ reflect.Value) error {
+		if leaf.Visibility == schema.Public {
+			names = append(names, leaf.FullName())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil
+	}
+	return names
+}
@@ -0,0 +1,139 @@
+package gnark
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/gabrielrondon/zapiki/internal/models"
+	"github.com/gabrielrondon/zapiki/internal/prover"
+)
+
+func TestParseFieldElement(t *testing.T) {
+	modulus := ecc.BN254.ScalarField()
+	big255 := big.NewInt(255)
+
+	valid := map[string]struct {
+		value interface{}
+		want  *big.Int
+	}{
+		"json number":   {json.Number("255"), big255},
+		"float":         {float64(255), big255},
+		"decimal":       {"255", big255},
+		"hex":           {"0xff", big255},
+		"base64":        {"/w==", big255},
+		"raw base64":    {"_w", big255},
+		"wide decimal":  {"123456789012345678901234567890", mustBig("123456789012345678901234567890")},
+		"modulus - 1":   {new(big.Int).Sub(modulus, big.NewInt(1)).String(), new(big.Int).Sub(modulus, big.NewInt(1))},
+		"digits base64": {"1234", big.NewInt(1234)}, // digits are decimal
+	}
+	for name, tc := range valid {
+		got, err := parseFieldElement(tc.value, modulus)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+			continue
+		}
+		if got.Cmp(tc.want) != 0 {
+			t.Errorf("%s: got %s, want %s", name, got, tc.want)
+		}
+	}
+
+	invalid := map[string]interface{}{
+		"modulus":   modulus.String(),
+		"negative":  json.Number("-1"),
+		"fraction":  json.Number("1.5"),
+		"float":     1.5,
+		"empty":     "",
+		"bad hex":   "0xzz",
+		"not a num": "hello world!",
+		"bare hex":  "deadbeef",
+		"bool":      true,
+		"object":    map[string]interface{}{},
+	}
+	for name, value := range invalid {
+		if _, err := parseFieldElement(value, modulus); err == nil {
+			t.Errorf("%s: expected %v to be rejected", name, value)
+		}
+	}
+}
+
+func mustBig(s string) *big.Int {
+	n, _ := new(big.Int).SetString(s, 10)
+	return n
+}
+
+func TestInputs_ReportsEveryField(t *testing.T) {
+	in := NewInputs(map[string]interface{}{
+		"x":    "0xnope",
+		"path": []interface{}{"1", "oops!"},
+	}, ecc.BN254.ScalarField())
+
+	in.Field("x")
+	in.Field("y")
+	in.Fields("path", 2)
+
+	err := in.Err()
+	if !errors.Is(err, prover.ErrInvalidWitness) {
+		t.Fatalf("Expected ErrInvalidWitness, got %v", err)
+	}
+	for _, want := range []string{"x:", "y is required", "path[1]"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q in %q", want, err)
+		}
+	}
+}
+
+func TestGroth16Prover_HexWitness(t *testing.T) {
+	p := NewGroth16Prover()
+	ctx := context.Background()
+
+	inputJSON := []byte(`{
+		"allowed_country_code": 76,
+		"current_timestamp": 1700000000,
+		"user_country_code": "76",
+		"address_hash": "0x2a8d3f0e6b1c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f708192a3b4c5d"
+	}`)
+	resp, err := p.Generate(ctx, &prover.ProofRequest{
+		Data: &models.InputData{Type: models.DataTypeJSON, Value: inputJSON},
+	})
+	if err != nil {
+		t.Fatalf("Failed to generate proof: %v", err)
+	}
+
+	verifyResp, err := p.Verify(ctx, &prover.VerifyRequest{
+		Proof:           resp.Proof,
+		VerificationKey: resp.VerificationKey,
+		PublicInputs:    resp.PublicInputs,
+	})
+	if err != nil {
+		t.Fatalf("Failed to verify proof: %v", err)
+	}
+	if !verifyResp.Valid {
+		t.Errorf("Expected proof to be valid, got: %v", verifyResp.ErrorMessage)
+	}
+}
+
+func TestGroth16Prover_RejectsMalformedWitness(t *testing.T) {
+	p := NewGroth16Prover()
+
+	inputJSON := []byte(`{
+		"allowed_country_code": 76,
+		"user_country_code": "not a number",
+		"address_hash": "0x` + strings.Repeat("ff", 32) + `"
+	}`)
+	_, err := p.Generate(context.Background(), &prover.ProofRequest{
+		Data: &models.InputData{Type: models.DataTypeJSON, Value: inputJSON},
+	})
+	if !errors.Is(err, prover.ErrInvalidWitness) {
+		t.Fatalf("Expected ErrInvalidWitness, got %v", err)
+	}
+	for _, field := range []string{"current_timestamp", "user_country_code", "address_hash"} {
+		if !strings.Contains(err.Error(), field) {
+			t.Errorf("Expected %s to be reported in %q", field, err)
+		}
+	}
+}
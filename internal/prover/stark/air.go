@@ -0,0 +1,260 @@
+package stark
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	fr "github.com/consensys/gnark-crypto/field/goldilocks"
+)
+
+// AIR (algebraic intermediate representation) describes a computation as an
+// execution trace, a table of field elements, together with the polynomial
+// constraints every valid trace satisfies
+type AIR interface {
+	// Name identifies the computation in proofs and verification keys
+	Name() string
+	// PublicInputs are the public values the constraints depend on
+	PublicInputs() []string
+	// Width is the number of trace columns
+	Width() int
+	// TraceLength is the number of trace rows, a power of two
+	TraceLength() int
+	// PeriodicColumns are public columns whose values repeat; each has a
+	// power-of-two length dividing the trace length
+	PeriodicColumns() [][]fr.Element
+	// NumTransitions is the number of transition constraints
+	NumTransitions() int
+	// TransitionDegree bounds the total degree of the transition
+	// constraints in the trace and periodic columns
+	TransitionDegree() int
+	// EvaluateTransitions evaluates each transition constraint on a row, the
+	// row after it and the periodic columns' values, writing to result. A
+	// valid trace makes every constraint zero on all rows but the last.
+	EvaluateTransitions(current, next, periodic, result []ext)
+	// Boundaries pin trace cells to public values
+	Boundaries() []Boundary
+}
+
+// Boundary constrains the trace cell at Row in Column to Value
+type Boundary struct {
+	Column int
+	Row    int
+	Value  fr.Element
+}
+
+// minTraceLength is the smallest trace a proof is generated for
+const minTraceLength = 8
+
+// checkTrace reports the first constraint a trace violates
+func checkTrace(air AIR, trace [][]fr.Element) error {
+	if err := validateAIR(air); err != nil {
+		return err
+	}
+	n := air.TraceLength()
+	if len(trace) != air.Width() {
+		return fmt.Errorf("trace has %d columns, expected %d", len(trace), air.Width())
+	}
+	for j := range trace {
+		if len(trace[j]) != n {
+			return fmt.Errorf("trace column %d has %d rows, expected %d", j, len(trace[j]), n)
+		}
+	}
+
+	current := make([]ext, air.Width())
+	next := make([]ext, air.Width())
+	periodic := make([]ext, len(air.PeriodicColumns()))
+	result := make([]ext, air.NumTransitions())
+	for row := 0; row < n-1; row++ {
+		for j := range trace {
+			current[j] = lift(trace[j][row])
+			next[j] = lift(trace[j][row+1])
+		}
+		for k, column := range air.PeriodicColumns() {
+			periodic[k] = lift(column[row%len(column)])
+		}
+		air.EvaluateTransitions(current, next, periodic, result)
+		for k := range result {
+			if !result[k].IsZero() {
+				return fmt.Errorf("transition constraint %d fails at row %d", k, row)
+			}
+		}
+	}
+
+	for k, b := range air.Boundaries() {
+		if !trace[b.Column][b.Row].Equal(&b.Value) {
+			return fmt.Errorf("boundary constraint %d fails", k)
+		}
+	}
+	return nil
+}
+
+// Built-in computations. The prover recognizes their inputs and the
+// verifier rebuilds their AIR from the name and public inputs.
+
+const (
+	airMultiplication = "multiplication"
+	airHashPreimage   = "mimc_preimage"
+)
+
+// newAIR rebuilds a built-in AIR for verification
+func newAIR(name string, publicInputs []string) (AIR, error) {
+	switch name {
+	case airMultiplication:
+		if len(publicInputs) != 1 {
+			return nil, fmt.Errorf("%s expects 1 public input, got %d", name, len(publicInputs))
+		}
+		c, err := parseElement(publicInputs[0])
+		if err != nil {
+			return nil, err
+		}
+		return &multiplicationAIR{product: c}, nil
+	case airHashPreimage:
+		if len(publicInputs) != 2 {
+			return nil, fmt.Errorf("%s expects 2 public inputs, got %d", name, len(publicInputs))
+		}
+		var out [2]fr.Element
+		for i := range out {
+			var err error
+			if out[i], err = parseElement(publicInputs[i]); err != nil {
+				return nil, err
+			}
+		}
+		return &mimcAIR{output: out}, nil
+	default:
+		return nil, fmt.Errorf("unknown STARK computation %q", name)
+	}
+}
+
+// parseElement parses a canonical decimal field element
+func parseElement(s string) (fr.Element, error) {
+	var v uint64
+	if _, err := fmt.Sscan(s, &v); err != nil || fmt.Sprint(v) != s {
+		return fr.Element{}, fmt.Errorf("invalid field element %q", s)
+	}
+	return newElement(v)
+}
+
+func formatElement(x fr.Element) string {
+	return fmt.Sprint(x.Uint64())
+}
+
+// multiplicationAIR proves knowledge of private a and b with a*b = c for a
+// public c. Columns: a, b, c, each constant over the trace.
+type multiplicationAIR struct {
+	product fr.Element
+}
+
+func (a *multiplicationAIR) Name() string                    { return airMultiplication }
+func (a *multiplicationAIR) PublicInputs() []string          { return []string{formatElement(a.product)} }
+func (a *multiplicationAIR) Width() int                      { return 3 }
+func (a *multiplicationAIR) TraceLength() int                { return minTraceLength }
+func (a *multiplicationAIR) PeriodicColumns() [][]fr.Element { return nil }
+func (a *multiplicationAIR) NumTransitions() int             { return 4 }
+func (a *multiplicationAIR) TransitionDegree() int           { return 2 }
+
+func (a *multiplicationAIR) EvaluateTransitions(current, next, periodic, result []ext) {
+	for j := 0; j < 3; j++ {
+		result[j].Sub(&next[j], &current[j])
+	}
+	result[3].Mul(&current[0], &current[1])
+	result[3].Sub(&result[3], &current[2])
+}
+
+func (a *multiplicationAIR) Boundaries() []Boundary {
+	return []Boundary{{Column: 2, Row: 0, Value: a.product}}
+}
+
+func multiplicationTrace(x, y, product fr.Element) [][]fr.Element {
+	trace := make([][]fr.Element, 3)
+	for j, v := range []fr.Element{x, y, product} {
+		trace[j] = make([]fr.Element, minTraceLength)
+		for i := range trace[j] {
+			trace[j][i] = v
+		}
+	}
+	return trace
+}
+
+// mimcAIR proves knowledge of a preimage under a Feistel MiMC permutation
+// with the x^7 S-box (a permutation of Goldilocks), over mimcRounds rounds:
+//
+//	(l, r) -> (r + (l + k_i)^7, l)
+//
+// Columns: l, r. The round constants k_i are a periodic column.
+type mimcAIR struct {
+	output [2]fr.Element
+}
+
+const mimcRounds = 127
+
+// mimcConstants are the round constants, derived from SHA-256 in counter
+// mode. The last one is unused: it falls on the final row.
+var mimcConstants = func() []fr.Element {
+	constants := make([]fr.Element, mimcRounds+1)
+	for i := range constants {
+		for counter := uint64(0); ; counter++ {
+			sum := sha256.Sum256(binary.LittleEndian.AppendUint64([]byte(fmt.Sprintf("zapiki-mimc-%d-", i)), counter))
+			if v := binary.LittleEndian.Uint64(sum[:]); v < fieldModulus {
+				constants[i] = fr.NewElement(v)
+				break
+			}
+		}
+	}
+	return constants
+}()
+
+func (a *mimcAIR) Name() string { return airHashPreimage }
+func (a *mimcAIR) PublicInputs() []string {
+	return []string{formatElement(a.output[0]), formatElement(a.output[1])}
+}
+func (a *mimcAIR) Width() int                      { return 2 }
+func (a *mimcAIR) TraceLength() int                { return mimcRounds + 1 }
+func (a *mimcAIR) PeriodicColumns() [][]fr.Element { return [][]fr.Element{mimcConstants} }
+func (a *mimcAIR) NumTransitions() int             { return 2 }
+func (a *mimcAIR) TransitionDegree() int           { return 7 }
+
+func (a *mimcAIR) EvaluateTransitions(current, next, periodic, result []ext) {
+	var t, t2, t4 ext
+	t.Add(&current[0], &periodic[0])
+	t2.Square(&t)
+	t4.Square(&t2)
+	t.Mul(&t, &t2).Mul(&t, &t4) // (l + k)^7
+	t.Add(&t, &current[1])
+	result[0].Sub(&next[0], &t)
+	result[1].Sub(&next[1], &current[0])
+}
+
+func (a *mimcAIR) Boundaries() []Boundary {
+	last := mimcRounds
+	return []Boundary{
+		{Column: 0, Row: last, Value: a.output[0]},
+		{Column: 1, Row: last, Value: a.output[1]},
+	}
+}
+
+// mimcTrace runs the permutation from a private input, returning the AIR
+// for its output and the trace
+func mimcTrace(l, r fr.Element) (*mimcAIR, [][]fr.Element) {
+	trace := [][]fr.Element{make([]fr.Element, mimcRounds+1), make([]fr.Element, mimcRounds+1)}
+	trace[0][0], trace[1][0] = l, r
+	for i := 0; i < mimcRounds; i++ {
+		var t, t2, t4 fr.Element
+		t.Add(&trace[0][i], &mimcConstants[i])
+		t2.Square(&t)
+		t4.Square(&t2)
+		t.Mul(&t, &t2).Mul(&t, &t4)
+		trace[0][i+1].Add(&t, &trace[1][i])
+		trace[1][i+1] = trace[0][i]
+	}
+	return &mimcAIR{output: [2]fr.Element{trace[0][mimcRounds], trace[1][mimcRounds]}}, trace
+}
+
+// hashToElements maps arbitrary data to the two-element MiMC input
+func hashToElements(data []byte) (fr.Element, fr.Element) {
+	sum := sha256.Sum256(data)
+	var l, r fr.Element
+	l.SetBytes(sum[:16])
+	r.SetBytes(sum[16:])
+	return l, r
+}
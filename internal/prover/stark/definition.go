@@ -0,0 +1,468 @@
+package stark
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/bits"
+
+	fr "github.com/consensys/gnark-crypto/field/goldilocks"
+)
+
+// AIRDefinition describes a custom STARK computation in JSON. It is the
+// circuit_definition of "stark" circuits. For example, a Fibonacci sequence
+// whose 64th term is public:
+//
+//	{
+//	  "trace_length": 64,
+//	  "columns": ["a", "b"],
+//	  "public_inputs": ["result"],
+//	  "transitions": ["next.a = b", "next.b = a + b"],
+//	  "boundaries": [
+//	    {"column": "a", "row": 0, "value": 1},
+//	    {"column": "b", "row": 0, "value": 1},
+//	    {"column": "a", "row": -1, "value": "result"}
+//	  ]
+//	}
+type AIRDefinition struct {
+	// TraceLength is the number of trace rows, a power of two
+	TraceLength int `json:"trace_length"`
+	// Columns names the trace columns
+	Columns []string `json:"columns"`
+	// PeriodicColumns are public columns of constants, repeated over the trace
+	PeriodicColumns []PeriodicColumn `json:"periodic_columns,omitempty"`
+	// PublicInputs names the public values constraints may refer to
+	PublicInputs []string `json:"public_inputs,omitempty"`
+	// Transitions are equations ("lhs = rhs", or an expression equal to
+	// zero) between a row and the next, holding on every row but the last
+	Transitions []string `json:"transitions"`
+	// Boundaries pin trace cells to values
+	Boundaries []BoundaryDefinition `json:"boundaries,omitempty"`
+}
+
+// PeriodicColumn is a named column of constants; its length is a power of
+// two dividing the trace length
+type PeriodicColumn struct {
+	Name   string       `json:"name"`
+	Values []FieldValue `json:"values"`
+}
+
+// BoundaryDefinition constrains the trace cell at Row in Column
+type BoundaryDefinition struct {
+	Column string `json:"column"`
+	// Row counts from the end when negative: -1 is the last row
+	Row int `json:"row"`
+	// Value is an expression in constants and public inputs
+	Value FieldValue `json:"value"`
+}
+
+// FieldValue is a field element or expression, given as a JSON string or a
+// JSON number
+type FieldValue string
+
+// UnmarshalJSON accepts strings and numbers
+func (v *FieldValue) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*v = FieldValue(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("value must be a string or a number")
+	}
+	*v = FieldValue(n.String())
+	return nil
+}
+
+// Limits on custom computations
+const (
+	maxCustomTraceLength = 1 << 16
+	maxColumns           = 64
+	maxPeriodicColumns   = 16
+	maxPublicInputs      = 64
+	maxConstraints       = 128
+	maxTransitionDegree  = 16
+)
+
+// airCustomPrefix starts the name of custom AIRs, followed by the SHA-256
+// of their definition
+const airCustomPrefix = "custom:"
+
+// ParseAIRDefinition decodes and validates a custom computation
+func ParseAIRDefinition(data []byte) (*AIRDefinition, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var def AIRDefinition
+	if err := decoder.Decode(&def); err != nil {
+		return nil, fmt.Errorf("invalid AIR definition: %w", err)
+	}
+	if _, err := compileAIR(&def); err != nil {
+		return nil, err
+	}
+	return &def, nil
+}
+
+// customAIR is a compiled AIRDefinition with values for its public inputs
+type customAIR struct {
+	def         *AIRDefinition
+	name        string
+	periodic    [][]fr.Element
+	transitions []expr
+	// assignments[j] computes column j on the next row from the current
+	// one, when a transition has the form "next.<column> = ..."
+	assignments []expr
+	boundaries  []customBoundary
+	degree      int
+	public      []fr.Element
+}
+
+type customBoundary struct {
+	column, row int
+	value       expr
+}
+
+// compileAIR checks a definition and compiles its expressions
+func compileAIR(def *AIRDefinition) (*customAIR, error) {
+	n := def.TraceLength
+	switch {
+	case n < minTraceLength || n > maxCustomTraceLength || bits.OnesCount(uint(n)) != 1:
+		return nil, fmt.Errorf("trace_length %d must be a power of two between %d and %d", n, minTraceLength, maxCustomTraceLength)
+	case len(def.Columns) == 0 || len(def.Columns) > maxColumns:
+		return nil, fmt.Errorf("columns must list between 1 and %d trace columns", maxColumns)
+	case len(def.PeriodicColumns) > maxPeriodicColumns:
+		return nil, fmt.Errorf("at most %d periodic columns are supported", maxPeriodicColumns)
+	case len(def.PublicInputs) > maxPublicInputs:
+		return nil, fmt.Errorf("at most %d public inputs are supported", maxPublicInputs)
+	case len(def.Transitions) > maxConstraints || len(def.Boundaries) > maxConstraints:
+		return nil, fmt.Errorf("at most %d transition and %d boundary constraints are supported", maxConstraints, maxConstraints)
+	case len(def.Transitions)+len(def.Boundaries) == 0:
+		return nil, fmt.Errorf("computation has no constraints")
+	}
+
+	canonical, err := json.Marshal(def)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal AIR definition: %w", err)
+	}
+	sum := sha256.Sum256(canonical)
+	air := &customAIR{
+		def:         def,
+		name:        airCustomPrefix + hex.EncodeToString(sum[:]),
+		assignments: make([]expr, len(def.Columns)),
+		public:      make([]fr.Element, len(def.PublicInputs)),
+	}
+
+	// Names share one namespace
+	scope := exprScope{}
+	publicScope := exprScope{}
+	declare := func(name string, v varExpr) error {
+		if !isName(name) || name == "next" {
+			return fmt.Errorf("invalid name %q", name)
+		}
+		if _, ok := scope[name]; ok {
+			return fmt.Errorf("name %q is declared twice", name)
+		}
+		scope[name] = v
+		return nil
+	}
+	for j, name := range def.Columns {
+		if err := declare(name, varExpr{kind: varCurrent, index: j}); err != nil {
+			return nil, err
+		}
+	}
+	for k, column := range def.PeriodicColumns {
+		if err := declare(column.Name, varExpr{kind: varPeriodic, index: k}); err != nil {
+			return nil, err
+		}
+		size := len(column.Values)
+		if size == 0 || size > n || bits.OnesCount(uint(size)) != 1 {
+			return nil, fmt.Errorf("periodic column %q has %d values, which must be a power of two up to the trace length", column.Name, size)
+		}
+		values := make([]fr.Element, size)
+		for i, v := range column.Values {
+			if values[i], err = parseConstant(string(v)); err != nil {
+				return nil, fmt.Errorf("periodic column %q: %w", column.Name, err)
+			}
+		}
+		air.periodic = append(air.periodic, values)
+	}
+	for k, name := range def.PublicInputs {
+		v := varExpr{kind: varPublic, index: k}
+		if err := declare(name, v); err != nil {
+			return nil, err
+		}
+		publicScope[name] = v
+	}
+
+	for k, source := range def.Transitions {
+		left, right, err := parseEquation(source, scope)
+		if err != nil {
+			return nil, fmt.Errorf("transition %d: %w", k, err)
+		}
+		constraint := &binaryExpr{op: '-', left: left, right: right}
+		if !usesKind(constraint, varCurrent, varNext) {
+			return nil, fmt.Errorf("transition %d does not refer to the trace", k)
+		}
+		if d := constraint.degree(); d > maxTransitionDegree {
+			return nil, fmt.Errorf("transition %d has degree %d, above the maximum of %d", k, d, maxTransitionDegree)
+		}
+		air.degree = max(air.degree, constraint.degree())
+		air.transitions = append(air.transitions, constraint)
+
+		if v, ok := left.(*varExpr); ok && v.kind == varNext && air.assignments[v.index] == nil && !usesKind(right, varNext) {
+			air.assignments[v.index] = right
+		}
+	}
+
+	for k, b := range def.Boundaries {
+		column, ok := scope[b.Column]
+		if !ok || column.kind != varCurrent {
+			return nil, fmt.Errorf("boundary %d: unknown column %q", k, b.Column)
+		}
+		row := b.Row
+		if row < 0 {
+			row += n
+		}
+		if row < 0 || row >= n {
+			return nil, fmt.Errorf("boundary %d: row %d is outside the trace", k, b.Row)
+		}
+		value, err := parseExpr(string(b.Value), publicScope)
+		if err != nil {
+			return nil, fmt.Errorf("boundary %d: %w", k, err)
+		}
+		air.boundaries = append(air.boundaries, customBoundary{column: column.index, row: row, value: value})
+	}
+	return air, nil
+}
+
+func isName(s string) bool {
+	if s == "" || !isNameStart(s[0]) {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if !isNameStart(s[i]) && !isDigit(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// withPublicInputs returns a copy of the AIR for the given public values
+func (a *customAIR) withPublicInputs(values []fr.Element) *customAIR {
+	bound := *a
+	bound.public = values
+	return &bound
+}
+
+func (a *customAIR) Name() string { return a.name }
+
+func (a *customAIR) PublicInputs() []string {
+	inputs := make([]string, len(a.public))
+	for i, v := range a.public {
+		inputs[i] = formatElement(v)
+	}
+	return inputs
+}
+
+func (a *customAIR) Width() int                      { return len(a.def.Columns) }
+func (a *customAIR) TraceLength() int                { return a.def.TraceLength }
+func (a *customAIR) PeriodicColumns() [][]fr.Element { return a.periodic }
+func (a *customAIR) NumTransitions() int             { return len(a.transitions) }
+func (a *customAIR) TransitionDegree() int           { return max(a.degree, 1) }
+
+func (a *customAIR) EvaluateTransitions(current, next, periodic, result []ext) {
+	env := &exprEnv{current: current, next: next, periodic: periodic, public: a.publicValues()}
+	for k, constraint := range a.transitions {
+		result[k] = constraint.eval(env)
+	}
+}
+
+func (a *customAIR) Boundaries() []Boundary {
+	env := &exprEnv{public: a.publicValues()}
+	boundaries := make([]Boundary, len(a.boundaries))
+	for k, b := range a.boundaries {
+		boundaries[k] = Boundary{Column: b.column, Row: b.row, Value: b.value.eval(env).A0}
+	}
+	return boundaries
+}
+
+func (a *customAIR) publicValues() []ext {
+	values := make([]ext, len(a.public))
+	for i := range a.public {
+		values[i] = lift(a.public[i])
+	}
+	return values
+}
+
+// customInputs are the proof inputs for a custom computation: public input
+// values and either the full trace or its first row, from which the trace
+// is computed when every column has a "next.<column> = ..." transition.
+// Public inputs a boundary pins a cell to may be omitted and are read from
+// the trace.
+type customInputs struct {
+	PublicInputs map[string]FieldValue   `json:"public_inputs"`
+	Initial      map[string]FieldValue   `json:"initial"`
+	Trace        map[string][]FieldValue `json:"trace"`
+}
+
+// execute builds the trace and public inputs for a proof
+func (a *customAIR) execute(data []byte) (*customAIR, [][]fr.Element, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var inputs customInputs
+	if err := decoder.Decode(&inputs); err != nil {
+		return nil, nil, fmt.Errorf("invalid inputs: %w", err)
+	}
+
+	// Public inputs given in the request
+	public := make([]fr.Element, len(a.def.PublicInputs))
+	known := make([]bool, len(a.def.PublicInputs))
+	for name, value := range inputs.PublicInputs {
+		k := indexOf(a.def.PublicInputs, name)
+		if k < 0 {
+			return nil, nil, fmt.Errorf("unknown public input %q", name)
+		}
+		v, err := parseConstant(string(value))
+		if err != nil {
+			return nil, nil, fmt.Errorf("public input %q: %w", name, err)
+		}
+		public[k], known[k] = v, true
+	}
+
+	var trace [][]fr.Element
+	var err error
+	switch {
+	case inputs.Trace != nil && inputs.Initial == nil:
+		trace, err = a.readTrace(inputs.Trace)
+	case inputs.Initial != nil && inputs.Trace == nil:
+		trace, err = a.runTrace(inputs.Initial, public, known)
+	default:
+		err = fmt.Errorf("inputs must contain either trace or initial")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Read the remaining public inputs from the cells boundaries pin them to
+	for k := range public {
+		if known[k] {
+			continue
+		}
+		for _, b := range a.boundaries {
+			if v, ok := b.value.(*varExpr); ok && v.kind == varPublic && v.index == k {
+				public[k], known[k] = trace[b.column][b.row], true
+				break
+			}
+		}
+		if !known[k] {
+			return nil, nil, fmt.Errorf("public input %q is required", a.def.PublicInputs[k])
+		}
+	}
+	return a.withPublicInputs(public), trace, nil
+}
+
+func (a *customAIR) readTrace(columns map[string][]FieldValue) ([][]fr.Element, error) {
+	trace := make([][]fr.Element, len(a.def.Columns))
+	for name := range columns {
+		if indexOf(a.def.Columns, name) < 0 {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+	}
+	for j, name := range a.def.Columns {
+		values, ok := columns[name]
+		if !ok || len(values) != a.def.TraceLength {
+			return nil, fmt.Errorf("trace column %q must have %d values", name, a.def.TraceLength)
+		}
+		trace[j] = make([]fr.Element, len(values))
+		for i, v := range values {
+			var err error
+			if trace[j][i], err = parseConstant(string(v)); err != nil {
+				return nil, fmt.Errorf("trace column %q row %d: %w", name, i, err)
+			}
+		}
+	}
+	return trace, nil
+}
+
+func (a *customAIR) runTrace(initial map[string]FieldValue, public []fr.Element, known []bool) ([][]fr.Element, error) {
+	for name := range initial {
+		if indexOf(a.def.Columns, name) < 0 {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+	}
+	for j, assignment := range a.assignments {
+		if assignment == nil {
+			return nil, fmt.Errorf("column %q has no next.%s = ... transition, so the full trace is required", a.def.Columns[j], a.def.Columns[j])
+		}
+		var missing error
+		walkVars(assignment, func(v *varExpr) {
+			if v.kind == varPublic && !known[v.index] && missing == nil {
+				missing = fmt.Errorf("public input %q is required", a.def.PublicInputs[v.index])
+			}
+		})
+		if missing != nil {
+			return nil, missing
+		}
+	}
+
+	n := a.def.TraceLength
+	trace := make([][]fr.Element, len(a.def.Columns))
+	current := make([]ext, len(trace))
+	for j, name := range a.def.Columns {
+		value, ok := initial[name]
+		if !ok {
+			return nil, fmt.Errorf("initial value of column %q is required", name)
+		}
+		v, err := parseConstant(string(value))
+		if err != nil {
+			return nil, fmt.Errorf("initial value of column %q: %w", name, err)
+		}
+		trace[j] = make([]fr.Element, n)
+		trace[j][0] = v
+		current[j] = lift(v)
+	}
+
+	env := &exprEnv{current: current, periodic: make([]ext, len(a.periodic)), public: a.withPublicInputs(public).publicValues()}
+	next := make([]ext, len(trace))
+	for row := 0; row < n-1; row++ {
+		for k, column := range a.periodic {
+			env.periodic[k] = lift(column[row%len(column)])
+		}
+		for j, assignment := range a.assignments {
+			next[j] = assignment.eval(env)
+			trace[j][row+1] = next[j].A0
+		}
+		copy(current, next)
+	}
+	return trace, nil
+}
+
+// verifierAIR compiles a definition for verifying a proof with the given
+// public inputs
+func verifierAIR(def *AIRDefinition, publicInputs []string) (*customAIR, error) {
+	air, err := compileAIR(def)
+	if err != nil {
+		return nil, err
+	}
+	if len(publicInputs) != len(def.PublicInputs) {
+		return nil, fmt.Errorf("computation expects %d public inputs, got %d", len(def.PublicInputs), len(publicInputs))
+	}
+	public := make([]fr.Element, len(publicInputs))
+	for i, input := range publicInputs {
+		if public[i], err = parseElement(input); err != nil {
+			return nil, err
+		}
+	}
+	return air.withPublicInputs(public), nil
+}
+
+func indexOf(names []string, name string) int {
+	for i, n := range names {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,234 @@
+package stark
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	fr "github.com/consensys/gnark-crypto/field/goldilocks"
+	"github.com/gabrielrondon/zapiki/internal/models"
+	"github.com/gabrielrondon/zapiki/internal/prover"
+	"github.com/google/uuid"
+)
+
+const fibonacciDefinition = `{
+	"trace_length": 64,
+	"columns": ["a", "b"],
+	"public_inputs": ["result"],
+	"transitions": ["next.a = b", "next.b = a + b"],
+	"boundaries": [
+		{"column": "a", "row": 0, "value": 1},
+		{"column": "b", "row": 0, "value": 1},
+		{"column": "a", "row": -1, "value": "result"}
+	]
+}`
+
+func starkCircuit(definition string) *models.Circuit {
+	return &models.Circuit{
+		ID:                uuid.New(),
+		Name:              "Custom AIR",
+		ProofSystem:       models.ProofSystemSTARK,
+		CircuitDefinition: json.RawMessage(definition),
+	}
+}
+
+func generateCustom(t *testing.T, p *STARKProver, circuit *models.Circuit, inputs string) *prover.ProofResponse {
+	t.Helper()
+	resp, err := p.Generate(context.Background(), &prover.ProofRequest{
+		Circuit: circuit,
+		Data:    &models.InputData{Type: models.DataTypeJSON, Value: json.RawMessage(inputs)},
+	})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	return resp
+}
+
+func TestCustomAIR_Fibonacci(t *testing.T) {
+	p := NewSTARKProver()
+	circuit := starkCircuit(fibonacciDefinition)
+
+	// The trace is computed from the first row and the result read from it
+	resp := generateCustom(t, p, circuit, `{"initial": {"a": 1, "b": 1}}`)
+
+	var publicInputs []string
+	if err := json.Unmarshal(resp.PublicInputs, &publicInputs); err != nil {
+		t.Fatalf("Failed to parse public inputs: %v", err)
+	}
+	// a on row 63 is the 64th Fibonacci number, reduced mod p
+	if len(publicInputs) != 1 || publicInputs[0] != "10610209857723" {
+		t.Errorf("Expected result 10610209857723, got %v", publicInputs)
+	}
+
+	verifyResp, err := p.Verify(context.Background(), &prover.VerifyRequest{
+		Proof:           resp.Proof,
+		VerificationKey: resp.VerificationKey,
+	})
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if !verifyResp.Valid {
+		t.Errorf("Proof should be valid. Error: %s", verifyResp.ErrorMessage)
+	}
+}
+
+func TestCustomAIR_WrongPublicInput(t *testing.T) {
+	p := NewSTARKProver()
+	_, err := p.Generate(context.Background(), &prover.ProofRequest{
+		Circuit: starkCircuit(fibonacciDefinition),
+		Data: &models.InputData{
+			Type:  models.DataTypeJSON,
+			Value: json.RawMessage(`{"initial": {"a": 1, "b": 1}, "public_inputs": {"result": "42"}}`),
+		},
+	})
+	if err == nil {
+		t.Error("Generate should fail when the trace does not reach the public result")
+	}
+}
+
+func TestCustomAIR_FullTrace(t *testing.T) {
+	// A column squared on every row, with the round constant periodic
+	definition := `{
+		"trace_length": 8,
+		"columns": ["x"],
+		"periodic_columns": [{"name": "k", "values": [1, 2]}],
+		"transitions": ["next.x - (x^2 + k)"],
+		"boundaries": [{"column": "x", "row": 0, "value": "3"}]
+	}`
+	x := fr.NewElement(3)
+	values := []uint64{x.Uint64()}
+	for i := 0; i < 7; i++ {
+		k := fr.NewElement(uint64(1 + i%2))
+		x.Square(&x).Add(&x, &k)
+		values = append(values, x.Uint64())
+	}
+	trace, _ := json.Marshal(map[string]interface{}{"trace": map[string]interface{}{"x": values}})
+
+	p := NewSTARKProver()
+	resp := generateCustom(t, p, starkCircuit(definition), string(trace))
+
+	verifyResp, err := p.Verify(context.Background(), &prover.VerifyRequest{
+		Proof:           resp.Proof,
+		VerificationKey: resp.VerificationKey,
+	})
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if !verifyResp.Valid {
+		t.Errorf("Proof should be valid. Error: %s", verifyResp.ErrorMessage)
+	}
+
+	// The transition is not of the form next.x = ..., so the trace cannot
+	// be computed from its first row
+	_, err = p.Generate(context.Background(), &prover.ProofRequest{
+		Circuit: starkCircuit(definition),
+		Data:    &models.InputData{Type: models.DataTypeJSON, Value: json.RawMessage(`{"initial": {"x": 3}}`)},
+	})
+	if err == nil || !strings.Contains(err.Error(), "full trace is required") {
+		t.Errorf("Expected a full trace error, got %v", err)
+	}
+}
+
+func TestCustomAIR_SubstitutedDefinition(t *testing.T) {
+	p := NewSTARKProver()
+	resp := generateCustom(t, p, starkCircuit(fibonacciDefinition), `{"initial": {"a": 1, "b": 1}}`)
+
+	// Swap in a different computation with the same shape
+	var vk STARKVerificationKey
+	if err := json.Unmarshal(resp.VerificationKey, &vk); err != nil {
+		t.Fatalf("Failed to parse verification key: %v", err)
+	}
+	vk.Definition.Transitions[1] = "next.b = a + b + 1"
+	tampered, _ := json.Marshal(vk)
+
+	verifyResp, err := p.Verify(context.Background(), &prover.VerifyRequest{
+		Proof:           resp.Proof,
+		VerificationKey: tampered,
+	})
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if verifyResp.Valid {
+		t.Error("Proof should not verify against a different computation")
+	}
+}
+
+func TestCustomAIR_VerifyByCircuit(t *testing.T) {
+	ctx := context.Background()
+	p := NewSTARKProver()
+	circuit := starkCircuit(fibonacciDefinition)
+	resp := generateCustom(t, p, circuit, `{"initial": {"a": 1, "b": 1}}`)
+
+	setup, err := p.Setup(ctx, circuit)
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+
+	for name, req := range map[string]*prover.VerifyRequest{
+		"circuit":      {Proof: resp.Proof, PublicInputs: resp.PublicInputs, Circuit: circuit},
+		"setup key":    {Proof: resp.Proof, PublicInputs: resp.PublicInputs, VerificationKey: setup.VerificationKey},
+		"no statement": {Proof: resp.Proof, Circuit: circuit},
+	} {
+		verifyResp, err := p.Verify(ctx, req)
+		if err != nil {
+			t.Fatalf("%s: Verify failed: %v", name, err)
+		}
+		if !verifyResp.Valid {
+			t.Errorf("%s: proof should be valid. Error: %s", name, verifyResp.ErrorMessage)
+		}
+	}
+
+	// The proof must be for the public inputs being verified
+	verifyResp, err := p.Verify(ctx, &prover.VerifyRequest{
+		Proof:        resp.Proof,
+		PublicInputs: json.RawMessage(`["42"]`),
+		Circuit:      circuit,
+	})
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if verifyResp.Valid {
+		t.Error("Proof should not verify against other public inputs")
+	}
+
+	// and for the circuit's computation
+	other := starkCircuit(strings.Replace(fibonacciDefinition, "next.b = a + b", "next.b = a + b + 1", 1))
+	verifyResp, err = p.Verify(ctx, &prover.VerifyRequest{Proof: resp.Proof, PublicInputs: resp.PublicInputs, Circuit: other})
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if verifyResp.Valid {
+		t.Error("Proof should not verify against another circuit")
+	}
+}
+
+func TestSTARKProver_ValidateCircuit(t *testing.T) {
+	p := NewSTARKProver()
+	if err := p.ValidateCircuit(context.Background(), starkCircuit(fibonacciDefinition)); err != nil {
+		t.Errorf("Valid definition rejected: %v", err)
+	}
+
+	invalid := map[string]string{
+		"not a power of two": `{"trace_length": 10, "columns": ["a"], "transitions": ["next.a = a"]}`,
+		"unknown name":       `{"trace_length": 8, "columns": ["a"], "transitions": ["next.a = b"]}`,
+		"duplicate name":     `{"trace_length": 8, "columns": ["a", "a"], "transitions": ["next.a = a"]}`,
+		"reserved name":      `{"trace_length": 8, "columns": ["next"], "transitions": ["next.next = 1"]}`,
+		"degree too high":    `{"trace_length": 8, "columns": ["a"], "transitions": ["next.a = a^16 * a"]}`,
+		"no trace reference": `{"trace_length": 8, "columns": ["a"], "transitions": ["1 = 1"]}`,
+		"bad syntax":         `{"trace_length": 8, "columns": ["a"], "transitions": ["next.a = (a + 1"]}`,
+		"boundary column":    `{"trace_length": 8, "columns": ["a"], "boundaries": [{"column": "b", "row": 0, "value": 1}]}`,
+		"boundary row":       `{"trace_length": 8, "columns": ["a"], "boundaries": [{"column": "a", "row": 8, "value": 1}]}`,
+		"boundary on trace":  `{"trace_length": 8, "columns": ["a"], "boundaries": [{"column": "a", "row": 0, "value": "a"}]}`,
+		"unknown field":      `{"trace_length": 8, "columns": ["a"], "transitions": ["next.a = a"], "extra": 1}`,
+		"no constraints":     `{"trace_length": 8, "columns": ["a"]}`,
+		"not canonical":      `{"trace_length": 8, "columns": ["a"], "transitions": ["next.a = a + 18446744069414584321"]}`,
+	}
+	for name, definition := range invalid {
+		err := p.ValidateCircuit(context.Background(), starkCircuit(definition))
+		if !errors.Is(err, prover.ErrInvalidCircuit) {
+			t.Errorf("%s: expected ErrInvalidCircuit, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,378 @@
+package stark
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	fr "github.com/consensys/gnark-crypto/field/goldilocks"
+)
+
+// Constraint expressions of custom AIRs are arithmetic over the field:
+//
+//	expr    = term { ("+" | "-") term }
+//	term    = unary { "*" unary }
+//	unary   = "-" unary | power
+//	power   = primary [ "^" integer ]
+//	primary = number | name | "next." column | "(" expr ")"
+//
+// Names refer to a trace column on the current row, a periodic column or a
+// public input; "next.<column>" is a trace column on the next row.
+
+// maxExpressionLength bounds the source of a single expression
+const maxExpressionLength = 1024
+
+// maxExponent bounds the exponent of "^"
+const maxExponent = 16
+
+// degreeCap saturates degree computations, far above any accepted degree
+const degreeCap = 1 << 16
+
+// varKind is what a name in an expression refers to
+type varKind int
+
+const (
+	varCurrent varKind = iota
+	varNext
+	varPeriodic
+	varPublic
+)
+
+// exprEnv holds the values names evaluate to
+type exprEnv struct {
+	current, next, periodic, public []ext
+}
+
+type expr interface {
+	eval(env *exprEnv) ext
+	// degree is the total degree in the trace and periodic columns
+	degree() int
+}
+
+type constExpr struct{ value ext }
+
+type varExpr struct {
+	kind  varKind
+	index int
+}
+
+type binaryExpr struct {
+	op          byte
+	left, right expr
+}
+
+type negExpr struct{ arg expr }
+
+type powExpr struct {
+	base     expr
+	exponent int
+}
+
+func (e *constExpr) eval(env *exprEnv) ext { return e.value }
+func (e *constExpr) degree() int           { return 0 }
+
+func (e *varExpr) eval(env *exprEnv) ext {
+	switch e.kind {
+	case varCurrent:
+		return env.current[e.index]
+	case varNext:
+		return env.next[e.index]
+	case varPeriodic:
+		return env.periodic[e.index]
+	default:
+		return env.public[e.index]
+	}
+}
+
+func (e *varExpr) degree() int {
+	if e.kind == varPublic {
+		return 0
+	}
+	return 1
+}
+
+func (e *binaryExpr) eval(env *exprEnv) ext {
+	var result ext
+	left, right := e.left.eval(env), e.right.eval(env)
+	switch e.op {
+	case '+':
+		result.Add(&left, &right)
+	case '-':
+		result.Sub(&left, &right)
+	default:
+		result.Mul(&left, &right)
+	}
+	return result
+}
+
+func (e *binaryExpr) degree() int {
+	if e.op == '*' {
+		return min(e.left.degree()+e.right.degree(), degreeCap)
+	}
+	return max(e.left.degree(), e.right.degree())
+}
+
+func (e *negExpr) eval(env *exprEnv) ext {
+	var result ext
+	v := e.arg.eval(env)
+	result.Neg(&v)
+	return result
+}
+
+func (e *negExpr) degree() int { return e.arg.degree() }
+
+func (e *powExpr) eval(env *exprEnv) ext {
+	return expExt(e.base.eval(env), uint64(e.exponent))
+}
+
+func (e *powExpr) degree() int { return min(e.base.degree()*e.exponent, degreeCap) }
+
+// walkVars calls fn on every name an expression refers to
+func walkVars(e expr, fn func(v *varExpr)) {
+	switch e := e.(type) {
+	case *varExpr:
+		fn(e)
+	case *binaryExpr:
+		walkVars(e.left, fn)
+		walkVars(e.right, fn)
+	case *negExpr:
+		walkVars(e.arg, fn)
+	case *powExpr:
+		walkVars(e.base, fn)
+	}
+}
+
+// usesKind reports whether an expression refers to a name of one of kinds
+func usesKind(e expr, kinds ...varKind) bool {
+	found := false
+	walkVars(e, func(v *varExpr) {
+		for _, kind := range kinds {
+			found = found || v.kind == kind
+		}
+	})
+	return found
+}
+
+// exprScope resolves names while parsing
+type exprScope map[string]varExpr
+
+// exprParser is a recursive descent parser over a tokenized expression
+type exprParser struct {
+	tokens []string
+	pos    int
+	scope  exprScope
+}
+
+// parseExpr parses a single expression
+func parseExpr(source string, scope exprScope) (expr, error) {
+	p, err := newExprParser(source, scope)
+	if err != nil {
+		return nil, err
+	}
+	e, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos])
+	}
+	return e, nil
+}
+
+// parseEquation parses "lhs = rhs" (or a bare expression, equated to zero)
+// and returns both sides
+func parseEquation(source string, scope exprScope) (expr, expr, error) {
+	p, err := newExprParser(source, scope)
+	if err != nil {
+		return nil, nil, err
+	}
+	left, err := p.expr()
+	if err != nil {
+		return nil, nil, err
+	}
+	right := expr(&constExpr{})
+	if p.peek() == "=" {
+		p.pos++
+		if right, err = p.expr(); err != nil {
+			return nil, nil, err
+		}
+	}
+	if p.pos != len(p.tokens) {
+		return nil, nil, fmt.Errorf("unexpected %q", p.tokens[p.pos])
+	}
+	return left, right, nil
+}
+
+func newExprParser(source string, scope exprScope) (*exprParser, error) {
+	if len(source) > maxExpressionLength {
+		return nil, fmt.Errorf("expression is longer than %d characters", maxExpressionLength)
+	}
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+	return &exprParser{tokens: tokens, scope: scope}, nil
+}
+
+func tokenize(source string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(source); {
+		c := source[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case strings.IndexByte("+-*^()=.", c) >= 0:
+			tokens = append(tokens, string(c))
+			i++
+		case isDigit(c) || isNameStart(c):
+			j := i + 1
+			for j < len(source) && (isDigit(source[j]) || isNameStart(source[j])) {
+				j++
+			}
+			tokens = append(tokens, source[i:j])
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected character %q", c)
+		}
+	}
+	return tokens, nil
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func (p *exprParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *exprParser) next() (string, error) {
+	if p.pos >= len(p.tokens) {
+		return "", fmt.Errorf("unexpected end of expression")
+	}
+	p.pos++
+	return p.tokens[p.pos-1], nil
+}
+
+func (p *exprParser) expr() (expr, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peek(); op == "+" || op == "-"; op = p.peek() {
+		p.pos++
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: op[0], left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) term() (expr, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "*" {
+		p.pos++
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: '*', left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) unary() (expr, error) {
+	if p.peek() == "-" {
+		p.pos++
+		arg, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &negExpr{arg: arg}, nil
+	}
+	return p.power()
+}
+
+func (p *exprParser) power() (expr, error) {
+	base, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() != "^" {
+		return base, nil
+	}
+	p.pos++
+	token, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	var exponent int
+	if _, err := fmt.Sscan(token, &exponent); err != nil || fmt.Sprint(exponent) != token || exponent < 1 || exponent > maxExponent {
+		return nil, fmt.Errorf("exponent %q must be an integer between 1 and %d", token, maxExponent)
+	}
+	return &powExpr{base: base, exponent: exponent}, nil
+}
+
+func (p *exprParser) primary() (expr, error) {
+	token, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case token == "(":
+		e, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if token, err := p.next(); err != nil || token != ")" {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		return e, nil
+	case isDigit(token[0]):
+		value, err := parseConstant(token)
+		if err != nil {
+			return nil, err
+		}
+		return &constExpr{value: lift(value)}, nil
+	case token == "next":
+		if dot, err := p.next(); err != nil || dot != "." {
+			return nil, fmt.Errorf("expected \"next.<column>\"")
+		}
+		name, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		v, ok := p.scope[name]
+		if !ok || v.kind != varCurrent {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		return &varExpr{kind: varNext, index: v.index}, nil
+	case isNameStart(token[0]):
+		v, ok := p.scope[token]
+		if !ok {
+			return nil, fmt.Errorf("unknown name %q", token)
+		}
+		return &v, nil
+	default:
+		return nil, fmt.Errorf("unexpected %q", token)
+	}
+}
+
+// parseConstant parses a decimal field element
+func parseConstant(s string) (fr.Element, error) {
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok || v.Sign() < 0 || !v.IsUint64() {
+		return fr.Element{}, fmt.Errorf("invalid field element %q", s)
+	}
+	return newElement(v.Uint64())
+}
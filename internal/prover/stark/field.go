@@ -0,0 +1,178 @@
+package stark
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	fr "github.com/consensys/gnark-crypto/field/goldilocks"
+	"github.com/consensys/gnark-crypto/field/goldilocks/extensions"
+	"github.com/consensys/gnark-crypto/field/goldilocks/fft"
+)
+
+// Traces live in the Goldilocks field (p = 2^64 - 2^32 + 1). Verifier
+// challenges are drawn from its quadratic extension, so soundness is not
+// limited by the 64-bit base field.
+
+// ext is an element of the quadratic extension
+type ext = extensions.E2
+
+// fieldModulus is the Goldilocks prime
+const fieldModulus uint64 = 0xFFFFFFFF00000001
+
+// fieldName identifies the field in proofs and verification keys
+const fieldName = "goldilocks"
+
+// lift embeds a base field element in the extension
+func lift(x fr.Element) ext {
+	return ext{A0: x}
+}
+
+// newElement decodes a canonical base field element
+func newElement(v uint64) (fr.Element, error) {
+	if v >= fieldModulus {
+		return fr.Element{}, fmt.Errorf("%d is not a canonical field element", v)
+	}
+	return fr.NewElement(v), nil
+}
+
+// extValue is the JSON encoding of an extension element
+type extValue [2]uint64
+
+func encodeExt(x ext) extValue {
+	return extValue{x.A0.Uint64(), x.A1.Uint64()}
+}
+
+func decodeExt(v extValue) (ext, error) {
+	a0, err := newElement(v[0])
+	if err != nil {
+		return ext{}, err
+	}
+	a1, err := newElement(v[1])
+	if err != nil {
+		return ext{}, err
+	}
+	return ext{A0: a0, A1: a1}, nil
+}
+
+func encodeExts(xs []ext) []extValue {
+	out := make([]extValue, len(xs))
+	for i := range xs {
+		out[i] = encodeExt(xs[i])
+	}
+	return out
+}
+
+func decodeExts(vs []extValue) ([]ext, error) {
+	out := make([]ext, len(vs))
+	for i := range vs {
+		var err error
+		if out[i], err = decodeExt(vs[i]); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func decodeElements(vs []uint64) ([]fr.Element, error) {
+	out := make([]fr.Element, len(vs))
+	for i := range vs {
+		var err error
+		if out[i], err = newElement(vs[i]); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// appendElements appends the canonical little-endian encoding of xs
+func appendElements(buf []byte, xs ...fr.Element) []byte {
+	for i := range xs {
+		buf = binary.LittleEndian.AppendUint64(buf, xs[i].Uint64())
+	}
+	return buf
+}
+
+func appendExts(buf []byte, xs ...ext) []byte {
+	for i := range xs {
+		buf = appendElements(buf, xs[i].A0, xs[i].A1)
+	}
+	return buf
+}
+
+// evalAt evaluates a base field polynomial at an extension point
+func evalAt(coeffs []fr.Element, x ext) ext {
+	var acc ext
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		acc.Mul(&acc, &x)
+		acc.A0.Add(&acc.A0, &coeffs[i])
+	}
+	return acc
+}
+
+// evalExtAt evaluates an extension polynomial at a base field point
+func evalExtAt(coeffs []ext, x fr.Element) ext {
+	var acc ext
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		acc.MulByElement(&acc, &x)
+		acc.Add(&acc, &coeffs[i])
+	}
+	return acc
+}
+
+// expExt returns x^e
+func expExt(x ext, e uint64) ext {
+	var result ext
+	result.SetOne()
+	for ; e > 0; e >>= 1 {
+		if e&1 == 1 {
+			result.Mul(&result, &x)
+		}
+		x.Square(&x)
+	}
+	return result
+}
+
+// expElement returns x^e
+func expElement(x fr.Element, e uint64) fr.Element {
+	result := fr.One()
+	for ; e > 0; e >>= 1 {
+		if e&1 == 1 {
+			result.Mul(&result, &x)
+		}
+		x.Square(&x)
+	}
+	return result
+}
+
+// interpolate returns the coefficients of the polynomial taking the given
+// values on a domain (or its coset when onCoset is set), in natural order
+func interpolate(domain *fft.Domain, values []fr.Element, onCoset bool) []fr.Element {
+	coeffs := append([]fr.Element(nil), values...)
+	if onCoset {
+		domain.FFTInverse(coeffs, fft.DIF, fft.OnCoset())
+	} else {
+		domain.FFTInverse(coeffs, fft.DIF)
+	}
+	fft.BitReverse(coeffs)
+	return coeffs
+}
+
+// evaluate returns a polynomial's values on a domain's coset, in natural order
+func evaluate(domain *fft.Domain, coeffs []fr.Element) []fr.Element {
+	values := make([]fr.Element, domain.Cardinality)
+	copy(values, coeffs)
+	domain.FFT(values, fft.DIF, fft.OnCoset())
+	fft.BitReverse(values)
+	return values
+}
+
+// randomElements returns n uniformly random field elements
+func randomElements(n int) ([]fr.Element, error) {
+	out := make([]fr.Element, n)
+	for i := range out {
+		if _, err := out[i].SetRandom(); err != nil {
+			return nil, fmt.Errorf("failed to sample randomness: %w", err)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,174 @@
+package stark
+
+import (
+	"fmt"
+
+	fr "github.com/consensys/gnark-crypto/field/goldilocks"
+	"github.com/consensys/gnark-crypto/field/goldilocks/fft"
+)
+
+// FRI proves that a function on the LDE domain is close to a polynomial of
+// degree below the degree bound. Each round folds f(x) and f(-x) into a
+// function on the squared domain of half the size, with a random challenge,
+// and commits to it; the last one is sent as polynomial coefficients.
+
+// friCommitment is the prover's side of FRI
+type friCommitment struct {
+	layers    [][]ext // layers[0] is the input; the others are committed
+	trees     []*merkleTree
+	remainder []ext
+}
+
+// commitFRI folds values down to the remainder, committing each layer
+func (p *params) commitFRI(t *transcript, values []ext) (*friCommitment, error) {
+	fri := &friCommitment{layers: [][]ext{values}}
+
+	for l := 0; l < p.friLayers; l++ {
+		beta := t.drawExt()
+		current := fri.layers[l]
+		half := len(current) / 2
+
+		folded := make([]ext, half)
+		x := p.layerPoint(l, 0)
+		omega := expElement(p.ldeDomain.Generator, 1<<l)
+		for i := range folded {
+			folded[i] = foldPair(current[i], current[i+half], beta, x)
+			x.Mul(&x, &omega)
+		}
+
+		if l+1 == p.friLayers {
+			remainder, err := p.friRemainder(folded)
+			if err != nil {
+				return nil, err
+			}
+			fri.remainder = remainder
+			break
+		}
+
+		tree := commitExtPairs(folded)
+		t.absorb(tree.root())
+		fri.layers = append(fri.layers, folded)
+		fri.trees = append(fri.trees, tree)
+	}
+
+	t.absorb(appendExts(nil, fri.remainder...))
+	return fri, nil
+}
+
+// friRemainder interpolates the last layer, checking its degree
+func (p *params) friRemainder(values []ext) ([]ext, error) {
+	shift := expElement(p.ldeDomain.FrMultiplicativeGen, 1<<p.friLayers)
+	domain := fft.NewDomain(uint64(len(values)), fft.WithShift(shift))
+
+	a0 := make([]fr.Element, len(values))
+	a1 := make([]fr.Element, len(values))
+	for i := range values {
+		a0[i], a1[i] = values[i].A0, values[i].A1
+	}
+	c0 := interpolate(domain, a0, true)
+	c1 := interpolate(domain, a1, true)
+
+	size := p.degreeBound >> p.friLayers
+	for i := size; i < len(values); i++ {
+		if !c0[i].IsZero() || !c1[i].IsZero() {
+			return nil, fmt.Errorf("FRI remainder exceeds its degree bound")
+		}
+	}
+
+	remainder := make([]ext, size)
+	for i := range remainder {
+		remainder[i] = ext{A0: c0[i], A1: c1[i]}
+	}
+	return remainder, nil
+}
+
+// commitExtPairs commits to a layer, one leaf per pair of opposite points
+func commitExtPairs(values []ext) *merkleTree {
+	half := len(values) / 2
+	leaves := make([][]byte, half)
+	for i := range leaves {
+		leaves[i] = extPairLeaf([2]ext{values[i], values[i+half]})
+	}
+	return newMerkleTree(leaves)
+}
+
+// open returns the queried pairs of each committed layer
+func (fri *friCommitment) open(queries []int) []FRIOpening {
+	openings := make([]FRIOpening, len(fri.trees))
+	for l, tree := range fri.trees {
+		layer := fri.layers[l+1]
+		half := len(layer) / 2
+		indices := layerIndices(queries, half)
+		opening := FRIOpening{Proof: tree.prove(indices)}
+		for _, i := range indices {
+			opening.Values = append(opening.Values, [2]extValue{encodeExt(layer[i]), encodeExt(layer[i+half])})
+		}
+		openings[l] = opening
+	}
+	return openings
+}
+
+// layerIndices maps layer-0 pair indices to a layer's pair indices
+func layerIndices(queries []int, half int) []int {
+	indices := make([]int, len(queries))
+	for i, q := range queries {
+		indices[i] = q % half
+	}
+	return uniqueSorted(indices)
+}
+
+// verifyFRI checks the folding of each query's layer-0 pair down to the
+// remainder. first maps each query to its pair of layer-0 values.
+func (p *params) verifyFRI(proof *STARKProof, betas []ext, remainder []ext, queries []int, first map[int][2]ext) error {
+	type layerOpening map[int][2]ext
+	layers := make([]layerOpening, len(proof.FRIQueries))
+
+	for l, opening := range proof.FRIQueries {
+		half := p.ldeSize >> (l + 2)
+		indices := layerIndices(queries, half)
+		if len(opening.Values) != len(indices) {
+			return fmt.Errorf("FRI layer %d opens %d pairs, expected %d", l+1, len(opening.Values), len(indices))
+		}
+
+		values := make(layerOpening, len(indices))
+		leaves := make([][]byte, len(indices))
+		for k, i := range indices {
+			a, err := decodeExt(opening.Values[k][0])
+			if err != nil {
+				return err
+			}
+			b, err := decodeExt(opening.Values[k][1])
+			if err != nil {
+				return err
+			}
+			values[i] = [2]ext{a, b}
+			leaves[k] = extPairLeaf(values[i])
+		}
+		if err := verifyMerkle(proof.FRIRoots[l], half, indices, leaves, opening.Proof); err != nil {
+			return fmt.Errorf("FRI layer %d: %w", l+1, err)
+		}
+		layers[l] = values
+	}
+
+	for _, q := range queries {
+		pair := first[q]
+		index := q
+		value := foldPair(pair[0], pair[1], betas[0], p.layerPoint(0, index))
+
+		for l := 1; l < p.friLayers; l++ {
+			half := p.ldeSize >> (l + 1)
+			pair = layers[l-1][index%half]
+			if index < half && !pair[0].Equal(&value) || index >= half && !pair[1].Equal(&value) {
+				return fmt.Errorf("FRI layer %d is inconsistent with layer %d", l, l-1)
+			}
+			index %= half
+			value = foldPair(pair[0], pair[1], betas[l], p.layerPoint(l, index))
+		}
+
+		expected := evalExtAt(remainder, p.layerPoint(p.friLayers, index))
+		if !expected.Equal(&value) {
+			return fmt.Errorf("FRI remainder is inconsistent with the last layer")
+		}
+	}
+	return nil
+}
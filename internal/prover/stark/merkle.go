@@ -0,0 +1,139 @@
+package stark
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"sort"
+)
+
+// errMerkleProof is returned when openings do not match a commitment
+var errMerkleProof = errors.New("merkle proof does not match commitment")
+
+// merkleTree is a SHA-256 Merkle tree over a power-of-two number of leaves,
+// stored as a heap: node 1 is the root and leaf i is node size+i
+type merkleTree struct {
+	size  int
+	nodes [][]byte
+}
+
+func hashLeaf(data []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{0})
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func hashNode(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{1})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// newMerkleTree commits to leaves, given as their serialized contents
+func newMerkleTree(leaves [][]byte) *merkleTree {
+	size := len(leaves)
+	t := &merkleTree{size: size, nodes: make([][]byte, 2*size)}
+	for i, leaf := range leaves {
+		t.nodes[size+i] = hashLeaf(leaf)
+	}
+	for i := size - 1; i >= 1; i-- {
+		t.nodes[i] = hashNode(t.nodes[2*i], t.nodes[2*i+1])
+	}
+	return t
+}
+
+func (t *merkleTree) root() []byte {
+	return t.nodes[1]
+}
+
+// prove returns the sibling nodes needed to authenticate the leaves at
+// indices (sorted, unique) together, each shared node sent once
+func (t *merkleTree) prove(indices []int) [][]byte {
+	var proof [][]byte
+	positions := make([]int, len(indices))
+	for i, index := range indices {
+		positions[i] = t.size + index
+	}
+
+	for len(positions) > 0 && positions[0] > 1 {
+		var parents []int
+		for i := 0; i < len(positions); i++ {
+			pos := positions[i]
+			if i+1 < len(positions) && positions[i+1] == pos^1 {
+				i++ // both children are known
+			} else {
+				proof = append(proof, t.nodes[pos^1])
+			}
+			parents = append(parents, pos/2)
+		}
+		positions = parents
+	}
+	return proof
+}
+
+// verifyMerkle checks leaves (serialized, at sorted unique indices) against
+// a root produced by a tree of the given size
+func verifyMerkle(root []byte, size int, indices []int, leaves [][]byte, proof [][]byte) error {
+	if len(indices) == 0 || len(indices) != len(leaves) {
+		return errMerkleProof
+	}
+
+	positions := make([]int, len(indices))
+	hashes := make([][]byte, len(indices))
+	for i, index := range indices {
+		if index < 0 || index >= size || (i > 0 && index <= indices[i-1]) {
+			return errMerkleProof
+		}
+		positions[i] = size + index
+		hashes[i] = hashLeaf(leaves[i])
+	}
+
+	for positions[0] > 1 {
+		var parents []int
+		var parentHashes [][]byte
+		for i := 0; i < len(positions); i++ {
+			pos := positions[i]
+			var left, right []byte
+			if i+1 < len(positions) && positions[i+1] == pos^1 {
+				left, right = hashes[i], hashes[i+1]
+				i++
+			} else {
+				if len(proof) == 0 {
+					return errMerkleProof
+				}
+				sibling := proof[0]
+				proof = proof[1:]
+				if pos%2 == 0 {
+					left, right = hashes[i], sibling
+				} else {
+					left, right = sibling, hashes[i]
+				}
+			}
+			parents = append(parents, pos/2)
+			parentHashes = append(parentHashes, hashNode(left, right))
+		}
+		positions, hashes = parents, parentHashes
+	}
+
+	if len(proof) != 0 || !bytes.Equal(hashes[0], root) {
+		return errMerkleProof
+	}
+	return nil
+}
+
+// uniqueSorted returns the distinct values of indices in increasing order
+func uniqueSorted(indices []int) []int {
+	out := append([]int(nil), indices...)
+	sort.Ints(out)
+	n := 0
+	for i, v := range out {
+		if i == 0 || v != out[n-1] {
+			out[n] = v
+			n++
+		}
+	}
+	return out[:n]
+}
@@ -0,0 +1,362 @@
+package stark
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	fr "github.com/consensys/gnark-crypto/field/goldilocks"
+	"github.com/consensys/gnark-crypto/field/goldilocks/fft"
+)
+
+// proofVersion identifies the proof format and protocol
+const proofVersion = "2.0"
+
+// transcriptLabel domain-separates this protocol's Fiat-Shamir transcript
+const transcriptLabel = "zapiki-stark-v2"
+
+// maxTraceLength bounds the traces a proof is generated for
+const maxTraceLength = 1 << 20
+
+// friRemainderSize is the largest polynomial FRI sends in the clear
+const friRemainderSize = 8
+
+// STARKProof is a STARK proof: Merkle commitments to the randomized trace
+// and composition polynomials, their values at an out-of-domain point, and
+// a FRI proof that the DEEP composition of both is of low degree. It does
+// not contain the trace.
+type STARKProof struct {
+	ProofVersion string   `json:"proof_version"`
+	AIR          string   `json:"air"`
+	PublicInputs []string `json:"public_inputs"`
+	Options      Options  `json:"options"`
+
+	TraceRoot       []byte `json:"trace_root"`
+	CompositionRoot []byte `json:"composition_root"`
+
+	OODCurrent     []extValue `json:"ood_current"`
+	OODNext        []extValue `json:"ood_next"`
+	OODComposition []extValue `json:"ood_composition"`
+
+	FRIRoots     [][]byte   `json:"fri_roots"`
+	FRIRemainder []extValue `json:"fri_remainder"`
+	PowNonce     uint64     `json:"pow_nonce"`
+
+	TraceQueries       MerkleOpening `json:"trace_queries"`
+	CompositionQueries MerkleOpening `json:"composition_queries"`
+	FRIQueries         []FRIOpening  `json:"fri_queries"`
+}
+
+// MerkleOpening holds the base field values of the queried leaves, in
+// increasing leaf order, and a Merkle multiproof for them
+type MerkleOpening struct {
+	Values [][]uint64 `json:"values"`
+	Proof  [][]byte   `json:"proof"`
+}
+
+// FRIOpening holds the queried pairs of a FRI layer and their multiproof
+type FRIOpening struct {
+	Values [][2]extValue `json:"values"`
+	Proof  [][]byte      `json:"proof"`
+}
+
+// params are the protocol dimensions for an AIR under given options
+type params struct {
+	air     AIR
+	options Options
+
+	traceLength int // n
+	width       int
+	// maskDegree random coefficients randomize each trace polynomial off
+	// the trace domain, covering every point the proof reveals it at
+	maskDegree int
+	// degreeBound bounds the trace polynomials and composition segments
+	degreeBound int
+	segments    int // composition polynomial segments
+	ldeSize     int // low-degree extension domain size
+	step        int // LDE index offset between a row and the next
+	friLayers   int // FRI folding rounds
+
+	traceDomain *fft.Domain
+	ldeDomain   *fft.Domain
+	generator   fr.Element     // generates the trace domain
+	periodic    [][]fr.Element // periodic column polynomials, in x^(n/len)
+	boundaryAt  []fr.Element   // trace domain point of each boundary row
+}
+
+// validateAIR checks an AIR's shape
+func validateAIR(air AIR) error {
+	n := air.TraceLength()
+	if n < minTraceLength || n > maxTraceLength || bits.OnesCount(uint(n)) != 1 {
+		return fmt.Errorf("trace length %d must be a power of two between %d and %d", n, minTraceLength, maxTraceLength)
+	}
+	if air.Width() < 1 {
+		return fmt.Errorf("trace must have at least one column")
+	}
+	if air.TransitionDegree() < 1 && air.NumTransitions() > 0 {
+		return fmt.Errorf("transition degree must be at least 1")
+	}
+	for _, column := range air.PeriodicColumns() {
+		if len(column) == 0 || len(column) > n || bits.OnesCount(uint(len(column))) != 1 {
+			return fmt.Errorf("periodic column length %d must be a power of two dividing the trace length", len(column))
+		}
+	}
+	for k, b := range air.Boundaries() {
+		if b.Column < 0 || b.Column >= air.Width() || b.Row < 0 || b.Row >= n {
+			return fmt.Errorf("boundary constraint %d is outside the trace", k)
+		}
+	}
+	if air.NumTransitions()+len(air.Boundaries()) == 0 {
+		return fmt.Errorf("computation has no constraints")
+	}
+	return nil
+}
+
+func newParams(air AIR, options Options) (*params, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	if err := validateAIR(air); err != nil {
+		return nil, err
+	}
+
+	p := &params{
+		air:         air,
+		options:     options,
+		traceLength: air.TraceLength(),
+		width:       air.Width(),
+		// Each query opens the trace at x and -x, and the composition there
+		// depends on the next rows too; the out-of-domain sample adds two
+		maskDegree: 4*options.NumQueries + 2,
+		segments:   air.TransitionDegree(),
+	}
+	if p.segments < 1 {
+		p.segments = 1
+	}
+	p.degreeBound = int(ecc.NextPowerOfTwo(uint64(p.traceLength + p.maskDegree)))
+
+	// The composition polynomial must fit in the LDE domain
+	blowup := options.BlowupFactor
+	if needed := int(ecc.NextPowerOfTwo(uint64(p.segments))); needed > blowup {
+		blowup = needed
+	}
+	p.ldeSize = p.degreeBound * blowup
+	p.step = p.ldeSize / p.traceLength
+	for p.degreeBound>>p.friLayers > friRemainderSize {
+		p.friLayers++
+	}
+
+	p.traceDomain = fft.NewDomain(uint64(p.traceLength))
+	p.ldeDomain = fft.NewDomain(uint64(p.ldeSize))
+	p.generator = p.traceDomain.Generator
+
+	for _, column := range air.PeriodicColumns() {
+		domain := fft.NewDomain(uint64(len(column)))
+		p.periodic = append(p.periodic, interpolate(domain, column, false))
+	}
+	for _, b := range air.Boundaries() {
+		p.boundaryAt = append(p.boundaryAt, expElement(p.generator, uint64(b.Row)))
+	}
+	return p, nil
+}
+
+// transcript starts a Fiat-Shamir transcript bound to the statement
+func (p *params) transcript() *transcript {
+	t := newTranscript(transcriptLabel)
+	var shape []byte
+	for _, v := range []int{p.width, p.traceLength, p.options.BlowupFactor, p.options.NumQueries, p.options.GrindingBits} {
+		shape = binary.LittleEndian.AppendUint64(shape, uint64(v))
+	}
+	t.absorb([]byte(p.air.Name()), shape)
+	for _, input := range p.air.PublicInputs() {
+		t.absorb([]byte(input))
+	}
+	return t
+}
+
+// layerPoint returns the i-th point of FRI layer l's domain, the LDE coset
+// squared l times
+func (p *params) layerPoint(l, i int) fr.Element {
+	shift := expElement(p.ldeDomain.FrMultiplicativeGen, 1<<l)
+	x := expElement(p.ldeDomain.Generator, uint64(i)<<l)
+	x.Mul(&x, &shift)
+	return x
+}
+
+// drawOOD draws the out-of-domain point, outside the base field so it
+// avoids both the trace and LDE domains
+func (p *params) drawOOD(t *transcript) ext {
+	for {
+		if z := t.drawExt(); !z.A1.IsZero() {
+			return z
+		}
+	}
+}
+
+// periodicAt evaluates the periodic columns at x
+func (p *params) periodicAt(x ext) []ext {
+	values := make([]ext, len(p.periodic))
+	for k, coeffs := range p.periodic {
+		values[k] = evalAt(coeffs, expExt(x, uint64(p.traceLength/len(coeffs))))
+	}
+	return values
+}
+
+// periodicLDE evaluates the periodic columns on the LDE domain
+func (p *params) periodicLDE() [][]fr.Element {
+	values := make([][]fr.Element, len(p.periodic))
+	for k, coeffs := range p.periodic {
+		stride := p.traceLength / len(coeffs)
+		spread := make([]fr.Element, p.ldeSize)
+		for i := range coeffs {
+			spread[i*stride] = coeffs[i]
+		}
+		values[k] = evaluate(p.ldeDomain, spread)
+	}
+	return values
+}
+
+// composition evaluates the random linear combination of the constraint
+// quotients at x, given the trace at x and at the next row's point
+func (p *params) composition(coeffs []ext, x ext, current, next, periodic []ext) ext {
+	result := make([]ext, p.air.NumTransitions())
+	p.air.EvaluateTransitions(current, next, periodic, result)
+
+	var sum, term ext
+	for k := range result {
+		term.Mul(&coeffs[k], &result[k])
+		sum.Add(&sum, &term)
+	}
+
+	// Transitions hold on every row but the last: divide by
+	// (x^n - 1) / (x - g^(n-1))
+	if len(result) > 0 {
+		var den, num ext
+		one := ext{A0: fr.One()}
+		den = expExt(x, uint64(p.traceLength))
+		den.Sub(&den, &one)
+		last := lift(expElement(p.generator, uint64(p.traceLength-1)))
+		num.Sub(&x, &last)
+		den.Inverse(&den)
+		sum.Mul(&sum, &num).Mul(&sum, &den)
+	}
+
+	offset := len(result)
+	for k, b := range p.air.Boundaries() {
+		var num, den ext
+		value := lift(b.Value)
+		at := lift(p.boundaryAt[k])
+		num.Sub(&current[b.Column], &value)
+		den.Sub(&x, &at)
+		den.Inverse(&den)
+		term.Mul(&num, &den).Mul(&term, &coeffs[offset+k])
+		sum.Add(&sum, &term)
+	}
+	return sum
+}
+
+// oodFrame holds the out-of-domain point and the claimed values there
+type oodFrame struct {
+	z, gz       ext
+	current     []ext // trace at z
+	next        []ext // trace at g·z
+	composition []ext // composition segments at z
+}
+
+// combineSegments recombines the composition polynomial's value at z from
+// its segments' values: H(z) = Σ z^(s·D) (H_s0(z) + u·H_s1(z))
+func (p *params) combineSegments(z ext, segments []ext) ext {
+	u := ext{A1: fr.One()}
+	zD := expExt(z, uint64(p.degreeBound))
+	var sum, power, term ext
+	power.SetOne()
+	for s := 0; s < p.segments; s++ {
+		term.Mul(&u, &segments[2*s+1])
+		term.Add(&term, &segments[2*s])
+		term.Mul(&term, &power)
+		sum.Add(&sum, &term)
+		power.Mul(&power, &zD)
+	}
+	return sum
+}
+
+// deepValue evaluates the DEEP composition at an LDE point x from the trace
+// and composition values there (the last composition value is the mask)
+func (p *params) deepValue(coeffs []ext, x fr.Element, trace, composition []fr.Element, ood *oodFrame) ext {
+	var invZ, invGZ, diff, term, sum ext
+	lx := lift(x)
+	invZ.Sub(&lx, &ood.z).Inverse(&invZ)
+	invGZ.Sub(&lx, &ood.gz).Inverse(&invGZ)
+
+	for j := range trace {
+		v := lift(trace[j])
+		diff.Sub(&v, &ood.current[j])
+		term.Mul(&diff, &invZ).Mul(&term, &coeffs[2*j])
+		sum.Add(&sum, &term)
+		diff.Sub(&v, &ood.next[j])
+		term.Mul(&diff, &invGZ).Mul(&term, &coeffs[2*j+1])
+		sum.Add(&sum, &term)
+	}
+
+	offset := 2 * len(trace)
+	for s := range ood.composition {
+		v := lift(composition[s])
+		diff.Sub(&v, &ood.composition[s])
+		term.Mul(&diff, &invZ).Mul(&term, &coeffs[offset+s])
+		sum.Add(&sum, &term)
+	}
+
+	mask := lift(composition[len(ood.composition)])
+	term.Mul(&mask, &coeffs[len(coeffs)-1])
+	sum.Add(&sum, &term)
+	return sum
+}
+
+// foldPair folds f(x) and f(-x) into f'(x²) with the challenge beta
+func foldPair(a, b, beta ext, x fr.Element) ext {
+	var sum, diff, result ext
+	var xInv fr.Element
+	xInv.Inverse(&x)
+	sum.Add(&a, &b)
+	diff.Sub(&a, &b)
+	diff.MulByElement(&diff, &xInv).Mul(&diff, &beta)
+	result.Add(&sum, &diff)
+	result.Halve()
+	return result
+}
+
+// pairLeaf serializes the values at LDE index i and i + size/2 of columns
+func pairLeaf(columns [][]fr.Element, i int) []uint64 {
+	half := len(columns[0]) / 2
+	values := make([]uint64, 0, 2*len(columns))
+	for _, offset := range []int{i, i + half} {
+		for _, column := range columns {
+			values = append(values, column[offset].Uint64())
+		}
+	}
+	return values
+}
+
+func leafBytes(values []uint64) []byte {
+	buf := make([]byte, 0, 8*len(values))
+	for _, v := range values {
+		buf = binary.LittleEndian.AppendUint64(buf, v)
+	}
+	return buf
+}
+
+// commitColumns commits to columns of LDE values, one leaf per pair of
+// opposite points
+func commitColumns(columns [][]fr.Element) *merkleTree {
+	half := len(columns[0]) / 2
+	leaves := make([][]byte, half)
+	for i := range leaves {
+		leaves[i] = leafBytes(pairLeaf(columns, i))
+	}
+	return newMerkleTree(leaves)
+}
+
+func extPairLeaf(pair [2]ext) []byte {
+	return appendExts(nil, pair[0], pair[1])
+}
@@ -0,0 +1,183 @@
+package stark
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	fr "github.com/consensys/gnark-crypto/field/goldilocks"
+)
+
+// prove generates a proof that trace satisfies air
+func prove(air AIR, trace [][]fr.Element, options Options) (*STARKProof, error) {
+	if err := checkTrace(air, trace); err != nil {
+		return nil, fmt.Errorf("trace does not satisfy the computation: %w", err)
+	}
+	p, err := newParams(air, options)
+	if err != nil {
+		return nil, err
+	}
+	t := p.transcript()
+
+	// Commit to the randomized trace polynomials on the LDE domain
+	traceCoeffs := make([][]fr.Element, p.width)
+	traceLDE := make([][]fr.Element, p.width)
+	for j, column := range trace {
+		if traceCoeffs[j], err = p.maskedTrace(column); err != nil {
+			return nil, err
+		}
+		traceLDE[j] = evaluate(p.ldeDomain, traceCoeffs[j])
+	}
+	traceTree := commitColumns(traceLDE)
+	t.absorb(traceTree.root())
+
+	// Commit to the composition polynomial, split into segments of degree
+	// below the degree bound, plus a random mask for the DEEP composition
+	compCoeffs := t.drawExts(air.NumTransitions() + len(air.Boundaries()))
+	segments, err := p.compositionSegments(compCoeffs, traceLDE)
+	if err != nil {
+		return nil, err
+	}
+	mask, err := randomElements(p.degreeBound)
+	if err != nil {
+		return nil, err
+	}
+	compLDE := make([][]fr.Element, 0, len(segments)+1)
+	for _, segment := range append(segments, mask) {
+		compLDE = append(compLDE, evaluate(p.ldeDomain, segment))
+	}
+	compTree := commitColumns(compLDE)
+	t.absorb(compTree.root())
+
+	// Evaluate everything at the out-of-domain point
+	ood := &oodFrame{z: p.drawOOD(t)}
+	g := lift(p.generator)
+	ood.gz.Mul(&ood.z, &g)
+	for _, coeffs := range traceCoeffs {
+		ood.current = append(ood.current, evalAt(coeffs, ood.z))
+		ood.next = append(ood.next, evalAt(coeffs, ood.gz))
+	}
+	for _, coeffs := range segments {
+		ood.composition = append(ood.composition, evalAt(coeffs, ood.z))
+	}
+	t.absorb(appendExts(nil, ood.current...), appendExts(nil, ood.next...), appendExts(nil, ood.composition...))
+
+	// Prove the DEEP composition is of low degree
+	deepCoeffs := t.drawExts(2*p.width + len(compLDE))
+	deep := make([]ext, p.ldeSize)
+	traceRow := make([]fr.Element, p.width)
+	compRow := make([]fr.Element, len(compLDE))
+	x := p.layerPoint(0, 0)
+	for i := range deep {
+		for j := range traceLDE {
+			traceRow[j] = traceLDE[j][i]
+		}
+		for s := range compLDE {
+			compRow[s] = compLDE[s][i]
+		}
+		deep[i] = p.deepValue(deepCoeffs, x, traceRow, compRow, ood)
+		x.Mul(&x, &p.ldeDomain.Generator)
+	}
+	fri, err := p.commitFRI(t, deep)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := t.grind(options.GrindingBits)
+	t.absorb(binary.LittleEndian.AppendUint64(nil, nonce))
+	queries := uniqueSorted(t.drawIndices(options.NumQueries, p.ldeSize/2))
+
+	return &STARKProof{
+		ProofVersion:       proofVersion,
+		AIR:                air.Name(),
+		PublicInputs:       air.PublicInputs(),
+		Options:            options,
+		TraceRoot:          traceTree.root(),
+		CompositionRoot:    compTree.root(),
+		OODCurrent:         encodeExts(ood.current),
+		OODNext:            encodeExts(ood.next),
+		OODComposition:     encodeExts(ood.composition),
+		FRIRoots:           fri.roots(),
+		FRIRemainder:       encodeExts(fri.remainder),
+		PowNonce:           nonce,
+		TraceQueries:       openColumns(traceTree, traceLDE, queries),
+		CompositionQueries: openColumns(compTree, compLDE, queries),
+		FRIQueries:         fri.open(queries),
+	}, nil
+}
+
+// maskedTrace returns the coefficients of a trace column's polynomial plus
+// Z_H·r for a random r of degree below the mask degree, which leaves the
+// trace unchanged and hides it everywhere else
+func (p *params) maskedTrace(column []fr.Element) ([]fr.Element, error) {
+	coeffs := make([]fr.Element, p.degreeBound)
+	copy(coeffs, interpolate(p.traceDomain, column, false))
+
+	r, err := randomElements(p.maskDegree)
+	if err != nil {
+		return nil, err
+	}
+	for i := range r {
+		coeffs[i].Sub(&coeffs[i], &r[i])
+		coeffs[i+p.traceLength].Add(&coeffs[i+p.traceLength], &r[i])
+	}
+	return coeffs, nil
+}
+
+// compositionSegments evaluates the composition polynomial on the LDE domain
+// and splits its coefficients into base field segments, ordered as
+// combineSegments expects
+func (p *params) compositionSegments(coeffs []ext, traceLDE [][]fr.Element) ([][]fr.Element, error) {
+	periodicLDE := p.periodicLDE()
+	current := make([]ext, p.width)
+	next := make([]ext, p.width)
+	periodic := make([]ext, len(periodicLDE))
+	h0 := make([]fr.Element, p.ldeSize)
+	h1 := make([]fr.Element, p.ldeSize)
+
+	x := p.layerPoint(0, 0)
+	for i := 0; i < p.ldeSize; i++ {
+		n := (i + p.step) % p.ldeSize
+		for j := range traceLDE {
+			current[j] = lift(traceLDE[j][i])
+			next[j] = lift(traceLDE[j][n])
+		}
+		for k := range periodicLDE {
+			periodic[k] = lift(periodicLDE[k][i])
+		}
+		h := p.composition(coeffs, lift(x), current, next, periodic)
+		h0[i], h1[i] = h.A0, h.A1
+		x.Mul(&x, &p.ldeDomain.Generator)
+	}
+
+	c0 := interpolate(p.ldeDomain, h0, true)
+	c1 := interpolate(p.ldeDomain, h1, true)
+	for i := p.segments * p.degreeBound; i < p.ldeSize; i++ {
+		if !c0[i].IsZero() || !c1[i].IsZero() {
+			return nil, fmt.Errorf("composition polynomial exceeds its degree bound")
+		}
+	}
+
+	segments := make([][]fr.Element, 0, 2*p.segments)
+	for s := 0; s < p.segments; s++ {
+		lo, hi := s*p.degreeBound, (s+1)*p.degreeBound
+		segments = append(segments, c0[lo:hi], c1[lo:hi])
+	}
+	return segments, nil
+}
+
+// openColumns opens committed columns at the queried pair indices
+func openColumns(tree *merkleTree, columns [][]fr.Element, queries []int) MerkleOpening {
+	opening := MerkleOpening{Proof: tree.prove(queries)}
+	for _, q := range queries {
+		opening.Values = append(opening.Values, pairLeaf(columns, q))
+	}
+	return opening
+}
+
+func (fri *friCommitment) roots() [][]byte {
+	roots := make([][]byte, len(fri.trees))
+	for i, tree := range fri.trees {
+		roots[i] = tree.root()
+	}
+	return roots
+}
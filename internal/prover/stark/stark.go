@@ -1,35 +1,85 @@
 package stark
 
 import (
+	"bytes"
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"math/big"
+	"math/bits"
 	"time"
 
+	fr "github.com/consensys/gnark-crypto/field/goldilocks"
 	"github.com/gabrielrondon/zapiki/internal/models"
 	"github.com/gabrielrondon/zapiki/internal/prover"
 )
 
-// STARKProver implements transparent zero-knowledge proofs using STARK
-// This is a simplified STARK implementation for demonstration
-// In production, use a mature library like Winterfell or Cairo
+// STARKProver implements transparent zero-knowledge proofs using STARKs:
+// computations are described as AIRs over the Goldilocks field, and proofs
+// are Merkle commitments plus a FRI low-degree test, made non-interactive
+// with a SHA-256 Fiat-Shamir transcript
 type STARKProver struct {
-	fieldPrime *big.Int // Prime field size
+	options Options
 }
 
-// NewSTARKProver creates a new STARK prover
+// Options are the STARK security parameters
+type Options struct {
+	// BlowupFactor is the ratio of the LDE domain to the trace polynomials'
+	// degree bound, a power of two
+	BlowupFactor int `json:"blowup_factor"`
+	// NumQueries is the number of FRI query positions
+	NumQueries int `json:"num_queries"`
+	// GrindingBits is the proof-of-work difficulty added before queries
+	GrindingBits int `json:"grinding_bits"`
+}
+
+// DefaultOptions returns parameters for about 100 bits of security
+func DefaultOptions() Options {
+	return Options{
+		BlowupFactor: 8,
+		NumQueries:   28,
+		GrindingBits: 16,
+	}
+}
+
+// Validate checks the options are within supported bounds
+func (o Options) Validate() error {
+	if o.BlowupFactor < 2 || o.BlowupFactor > 64 || bits.OnesCount(uint(o.BlowupFactor)) != 1 {
+		return fmt.Errorf("blowup factor %d must be a power of two between 2 and 64", o.BlowupFactor)
+	}
+	if o.NumQueries < 1 || o.NumQueries > 128 {
+		return fmt.Errorf("number of queries %d must be between 1 and 128", o.NumQueries)
+	}
+	if o.GrindingBits < 0 || o.GrindingBits > 32 {
+		return fmt.Errorf("grinding bits %d must be between 0 and 32", o.GrindingBits)
+	}
+	return nil
+}
+
+// SecurityBits estimates the conjectured security level: each query
+// contributes log2(blowup) bits, plus the grinding bits, capped by the
+// 128-bit extension field challenges are drawn from
+func (o Options) SecurityBits() int {
+	security := o.NumQueries*(bits.Len(uint(o.BlowupFactor))-1) + o.GrindingBits
+	if security > 128 {
+		security = 128
+	}
+	return security
+}
+
+// NewSTARKProver creates a new STARK prover with the default options
 func NewSTARKProver() *STARKProver {
-	// Use a large prime for the finite field
-	// 2^256 - 189 (a safe prime)
-	prime := new(big.Int)
-	prime.SetString("115792089237316195423570985008687907853269984665640564039457584007913129639747", 10)
+	return &STARKProver{options: DefaultOptions()}
+}
 
-	return &STARKProver{
-		fieldPrime: prime,
+// SetOptions sets the security parameters for generated proofs. Proofs with
+// a lower security level than these options fail verification.
+func (p *STARKProver) SetOptions(options Options) error {
+	if err := options.Validate(); err != nil {
+		return fmt.Errorf("invalid STARK options: %w", err)
 	}
+	p.options = options
+	return nil
 }
 
 // Name returns the proof system name
@@ -40,17 +90,36 @@ func (p *STARKProver) Name() models.ProofSystemType {
 // Setup performs setup for STARK (no trusted setup needed!)
 func (p *STARKProver) Setup(ctx context.Context, circuit *models.Circuit) (*prover.SetupResult, error) {
 	// STARKs don't require trusted setup - this is their main advantage!
-	// We only need to configure parameters
+	// The public parameters are just the computation, the field and the
+	// security options
+
+	vk := &STARKVerificationKey{
+		ProofVersion: proofVersion,
+		Field:        fieldName,
+		Options:      p.options,
+	}
+	if hasDefinition(circuit) {
+		var err error
+		if vk, err = p.circuitKey(circuit); err != nil {
+			return nil, err
+		}
+	}
+
+	vkJSON, err := json.Marshal(vk)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal verification key: %w", err)
+	}
 
 	metadata := map[string]interface{}{
-		"circuit_id": circuit.ID.String(),
-		"message":    "STARK setup complete - no trusted setup required",
+		"circuit_id":    circuit.ID.String(),
+		"message":       "STARK setup complete - no trusted setup required",
 		"setup_time_ms": 0,
+		"security_bits": p.options.SecurityBits(),
 	}
 
 	return &prover.SetupResult{
 		ProvingKey:      json.RawMessage(`{"type":"stark","setup":"transparent"}`),
-		VerificationKey: json.RawMessage(`{"type":"stark","public_parameters":true}`),
+		VerificationKey: vkJSON,
 		Metadata:        metadata,
 	}, nil
 }
@@ -59,33 +128,41 @@ func (p *STARKProver) Setup(ctx context.Context, circuit *models.Circuit) (*prov
 func (p *STARKProver) Generate(ctx context.Context, req *prover.ProofRequest) (*prover.ProofResponse, error) {
 	startTime := time.Now()
 
-	// Parse input data
-	var inputData map[string]interface{}
-	if err := json.Unmarshal(req.Data.Value, &inputData); err != nil {
-		return nil, fmt.Errorf("failed to parse inputs: %w", err)
-	}
+	// Circuits define their own computation; other requests use a built-in one
+	var air AIR
+	var trace [][]fr.Element
+	var def *AIRDefinition
+	if hasDefinition(req.Circuit) {
+		var err error
+		if def, err = ParseAIRDefinition(req.Circuit.CircuitDefinition); err != nil {
+			return nil, err
+		}
+		custom, err := compileAIR(def)
+		if err != nil {
+			return nil, err
+		}
+		if air, trace, err = custom.execute(req.Data.Value); err != nil {
+			return nil, fmt.Errorf("failed to execute computation: %w", err)
+		}
+	} else {
+		// Parse input data
+		decoder := json.NewDecoder(bytes.NewReader(req.Data.Value))
+		decoder.UseNumber()
+		var inputData map[string]interface{}
+		if err := decoder.Decode(&inputData); err != nil {
+			return nil, fmt.Errorf("failed to parse inputs: %w", err)
+		}
 
-	// Execute computation trace
-	trace, publicInputs, err := p.executeComputationTrace(inputData)
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute computation: %w", err)
+		// Execute computation trace
+		var err error
+		if air, trace, err = executeComputationTrace(inputData); err != nil {
+			return nil, fmt.Errorf("failed to execute computation: %w", err)
+		}
 	}
 
-	// Generate FRI (Fast Reed-Solomon IOP) commitment
-	commitment := p.generateFRICommitment(trace)
-
-	// Generate random challenges using Fiat-Shamir transform
-	challenges := p.generateChallenges(commitment, publicInputs)
-
-	// Generate proof components
-	proofData := STARKProof{
-		Trace:         trace,
-		Commitment:    commitment,
-		Challenges:    challenges,
-		PublicInputs:  publicInputs,
-		Timestamp:     time.Now().Format(time.RFC3339),
-		FieldPrime:    p.fieldPrime.String(),
-		ProofVersion:  "1.0",
+	proofData, err := prove(air, trace, p.options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate proof: %w", err)
 	}
 
 	proofJSON, err := json.Marshal(proofData)
@@ -93,11 +170,14 @@ func (p *STARKProver) Generate(ctx context.Context, req *prover.ProofRequest) (*
 		return nil, fmt.Errorf("failed to marshal proof: %w", err)
 	}
 
-	// Verification key (public parameters)
+	// Verification key (public parameters and statement)
 	vkData := STARKVerificationKey{
-		FieldPrime:   p.fieldPrime.String(),
-		PublicInputs: publicInputs,
-		ProofVersion: "1.0",
+		ProofVersion: proofVersion,
+		AIR:          air.Name(),
+		Field:        fieldName,
+		PublicInputs: air.PublicInputs(),
+		Options:      p.options,
+		Definition:   def,
 	}
 
 	vkJSON, err := json.Marshal(vkData)
@@ -105,10 +185,16 @@ func (p *STARKProver) Generate(ctx context.Context, req *prover.ProofRequest) (*
 		return nil, fmt.Errorf("failed to marshal verification key: %w", err)
 	}
 
+	publicInputsJSON, err := json.Marshal(air.PublicInputs())
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal public inputs: %w", err)
+	}
+
 	generationTime := time.Since(startTime).Milliseconds()
 
 	return &prover.ProofResponse{
 		Proof:            proofJSON,
+		PublicInputs:     publicInputsJSON,
 		VerificationKey:  vkJSON,
 		GenerationTimeMs: generationTime,
 	}, nil
@@ -122,204 +208,221 @@ func (p *STARKProver) Verify(ctx context.Context, req *prover.VerifyRequest) (*p
 		return nil, fmt.Errorf("failed to parse proof: %w", err)
 	}
 
-	// Parse verification key
-	var vk STARKVerificationKey
-	if err := json.Unmarshal(req.VerificationKey, &vk); err != nil {
-		return nil, fmt.Errorf("failed to parse verification key: %w", err)
+	// Parse the verification key, or derive the circuit's when none is
+	// supplied: a circuit's key is its computation and the security options
+	vk := &STARKVerificationKey{}
+	if len(req.VerificationKey) > 0 {
+		if err := json.Unmarshal(req.VerificationKey, vk); err != nil {
+			return nil, fmt.Errorf("failed to parse verification key: %w", err)
+		}
+	} else if hasDefinition(req.Circuit) {
+		var err error
+		if vk, err = p.circuitKey(req.Circuit); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, fmt.Errorf("verification key is required")
 	}
 
-	// Verify proof version
-	if proof.ProofVersion != "1.0" {
+	if err := p.checkStatement(&proof, vk, req.PublicInputs); err != nil {
 		return &prover.VerifyResponse{
 			Valid:        false,
-			ErrorMessage: "Unsupported proof version",
+			ErrorMessage: err.Error(),
 		}, nil
 	}
 
-	// Verify field prime matches
-	if proof.FieldPrime != vk.FieldPrime {
+	air, err := statementAIR(&proof, vk)
+	if err != nil {
 		return &prover.VerifyResponse{
 			Valid:        false,
-			ErrorMessage: "Field prime mismatch",
+			ErrorMessage: err.Error(),
 		}, nil
 	}
 
-	// Verify public inputs match
-	if !equalStringArrays(proof.PublicInputs, vk.PublicInputs) {
+	if err := verify(air, &proof); err != nil {
 		return &prover.VerifyResponse{
 			Valid:        false,
-			ErrorMessage: "Public inputs mismatch",
+			ErrorMessage: fmt.Sprintf("STARK verification failed: %v", err),
 		}, nil
 	}
 
-	// Verify FRI commitment
-	expectedCommitment := p.generateFRICommitment(proof.Trace)
-	if proof.Commitment != expectedCommitment {
-		return &prover.VerifyResponse{
-			Valid:        false,
-			ErrorMessage: "FRI commitment verification failed",
-		}, nil
+	return &prover.VerifyResponse{
+		Valid: true,
+	}, nil
+}
+
+// checkStatement checks a proof is for the statement and parameters in the
+// verification key, at no less than the configured security level. Keys
+// returned with a proof fix its public inputs and options; circuit keys fix
+// only the computation, whose definition the public inputs are checked
+// against (see statementAIR), so the proof must match the public inputs
+// being verified, if given.
+func (p *STARKProver) checkStatement(proof *STARKProof, vk *STARKVerificationKey, publicInputs json.RawMessage) error {
+	switch {
+	case proof.ProofVersion != proofVersion || vk.ProofVersion != proofVersion:
+		return fmt.Errorf("unsupported proof version")
+	case vk.Field != fieldName:
+		return fmt.Errorf("field mismatch")
+	case proof.AIR != vk.AIR:
+		return fmt.Errorf("computation mismatch")
+	case proof.Options.SecurityBits() < p.options.SecurityBits():
+		return fmt.Errorf("proof security level of %d bits is below the required %d", proof.Options.SecurityBits(), p.options.SecurityBits())
 	}
 
-	// Verify challenges (Fiat-Shamir)
-	expectedChallenges := p.generateChallenges(proof.Commitment, proof.PublicInputs)
-	if !equalStringArrays(proof.Challenges, expectedChallenges) {
-		return &prover.VerifyResponse{
-			Valid:        false,
-			ErrorMessage: "Challenge verification failed",
-		}, nil
+	if vk.isCircuitKey() {
+		if len(publicInputs) == 0 {
+			return nil
+		}
+		var statement []string
+		if err := json.Unmarshal(publicInputs, &statement); err != nil {
+			return fmt.Errorf("failed to parse public inputs: %v", err)
+		}
+		if !equalStringArrays(proof.PublicInputs, statement) {
+			return fmt.Errorf("public inputs mismatch")
+		}
+		return nil
 	}
 
-	// Verify computation trace consistency
-	if !p.verifyTraceConsistency(proof.Trace, proof.PublicInputs) {
-		return &prover.VerifyResponse{
-			Valid:        false,
-			ErrorMessage: "Trace consistency check failed",
-		}, nil
+	switch {
+	case !equalStringArrays(proof.PublicInputs, vk.PublicInputs):
+		return fmt.Errorf("public inputs mismatch")
+	case proof.Options != vk.Options:
+		return fmt.Errorf("security parameters mismatch")
 	}
+	return nil
+}
 
-	return &prover.VerifyResponse{
-		Valid: true,
+// circuitKey returns the verification key of a circuit's computation
+func (p *STARKProver) circuitKey(circuit *models.Circuit) (*STARKVerificationKey, error) {
+	def, err := ParseAIRDefinition(circuit.CircuitDefinition)
+	if err != nil {
+		return nil, err
+	}
+	air, err := compileAIR(def)
+	if err != nil {
+		return nil, err
+	}
+
+	return &STARKVerificationKey{
+		ProofVersion: proofVersion,
+		AIR:          air.Name(),
+		Field:        fieldName,
+		Options:      p.options,
+		Definition:   def,
 	}, nil
 }
 
+// statementAIR rebuilds the computation a proof is for: the custom one in
+// the verification key, or a built-in one
+func statementAIR(proof *STARKProof, vk *STARKVerificationKey) (AIR, error) {
+	if vk.Definition == nil {
+		return newAIR(proof.AIR, proof.PublicInputs)
+	}
+	air, err := verifierAIR(vk.Definition, proof.PublicInputs)
+	if err != nil {
+		return nil, err
+	}
+	if air.Name() != vk.AIR {
+		return nil, fmt.Errorf("computation definition does not match the verification key")
+	}
+	return air, nil
+}
+
+// ValidateCircuit checks that a circuit's definition is a valid AIR
+func (p *STARKProver) ValidateCircuit(ctx context.Context, circuit *models.Circuit) error {
+	if !hasDefinition(circuit) {
+		return fmt.Errorf("%w: a STARK circuit_definition is required", prover.ErrInvalidCircuit)
+	}
+	if _, err := ParseAIRDefinition(circuit.CircuitDefinition); err != nil {
+		return fmt.Errorf("%w: %v", prover.ErrInvalidCircuit, err)
+	}
+	return nil
+}
+
+// hasDefinition reports whether a circuit carries a circuit_definition
+func hasDefinition(circuit *models.Circuit) bool {
+	return circuit != nil && len(circuit.CircuitDefinition) > 0 && string(circuit.CircuitDefinition) != "null"
+}
+
 // Capabilities returns STARK capabilities
 func (p *STARKProver) Capabilities() prover.Capabilities {
 	return prover.Capabilities{
-		SupportsSetup:        false, // No setup needed!
-		RequiresTrustedSetup: false, // Transparent!
+		SupportsSetup:          false, // No setup needed!
+		RequiresTrustedSetup:   false, // Transparent!
 		SupportsCustomCircuits: true,
-		AsyncOnly:            true,
-		TypicalGenerationTime: 40000, // ~40 seconds
-		MaxProofSize:         102400,  // ~100KB
+		AsyncOnly:              true,
+		TypicalGenerationTime:  1000,   // ~1 second
+		MaxProofSize:           102400, // ~100KB
+		Library:                "github.com/gabrielrondon/zapiki/internal/prover/stark",
 		Features: []string{
 			"transparent",
 			"no-trusted-setup",
 			"quantum-resistant",
 			"hash-based",
-			"fri-commitment",
+			"fri",
+			"zero-knowledge",
 		},
 	}
 }
 
-// executeComputationTrace executes the computation and generates trace
-func (p *STARKProver) executeComputationTrace(inputs map[string]interface{}) ([]string, []string, error) {
-	// This is a simplified computation trace
-	// In a real STARK system, this would be much more complex
-
-	trace := make([]string, 0)
-	publicInputs := make([]string, 0)
-
-	// Extract computation based on input type
+// executeComputationTrace selects the computation for the inputs and runs it
+func executeComputationTrace(inputs map[string]interface{}) (AIR, [][]fr.Element, error) {
+	// Simple value commitment: prove knowledge of a preimage of its hash
 	if value, ok := inputs["value"]; ok {
-		// Simple value commitment
-		valueStr := fmt.Sprintf("%v", value)
-		hash := sha256.Sum256([]byte(valueStr))
-		trace = append(trace, hex.EncodeToString(hash[:]))
-		publicInputs = append(publicInputs, hex.EncodeToString(hash[:]))
+		l, r := hashToElements([]byte(fmt.Sprintf("%v", value)))
+		air, trace := mimcTrace(l, r)
+		return air, trace, nil
 	}
 
 	if a, okA := inputs["a"]; okA {
 		if b, okB := inputs["b"]; okB {
 			if c, okC := inputs["c"]; okC {
-				// Multiplication: a * b = c
-				aVal := toBigInt(a)
-				bVal := toBigInt(b)
-				cVal := toBigInt(c)
-
-				// Generate trace: intermediate steps of multiplication
-				trace = append(trace, aVal.String())
-				trace = append(trace, bVal.String())
-
-				result := new(big.Int).Mul(aVal, bVal)
-				trace = append(trace, result.String())
-
-				// Public output
-				publicInputs = append(publicInputs, cVal.String())
+				// Multiplication: a * b = c, with c public
+				var values [3]fr.Element
+				for i, v := range []interface{}{a, b, c} {
+					var err error
+					if values[i], err = toElement(v); err != nil {
+						return nil, nil, err
+					}
+				}
+				var product fr.Element
+				product.Mul(&values[0], &values[1])
+				if !product.Equal(&values[2]) {
+					return nil, nil, fmt.Errorf("a * b does not equal c")
+				}
+				return &multiplicationAIR{product: values[2]}, multiplicationTrace(values[0], values[1], values[2]), nil
 			}
 		}
 	}
 
-	if len(trace) == 0 {
-		// Default: hash of all inputs
-		inputJSON, _ := json.Marshal(inputs)
-		hash := sha256.Sum256(inputJSON)
-		trace = append(trace, hex.EncodeToString(hash[:]))
-		publicInputs = append(publicInputs, hex.EncodeToString(hash[:]))
-	}
-
-	return trace, publicInputs, nil
-}
-
-// generateFRICommitment generates a FRI commitment to the trace
-func (p *STARKProver) generateFRICommitment(trace []string) string {
-	// Simplified FRI commitment using Merkle tree root
-	// In production, use full FRI protocol
-
-	hasher := sha256.New()
-	for _, step := range trace {
-		hasher.Write([]byte(step))
-	}
-
-	commitment := hasher.Sum(nil)
-	return hex.EncodeToString(commitment)
-}
-
-// generateChallenges generates random challenges using Fiat-Shamir transform
-func (p *STARKProver) generateChallenges(commitment string, publicInputs []string) []string {
-	// Fiat-Shamir: turn interactive protocol into non-interactive
-	// by using hash of transcript as random challenges
-
-	hasher := sha256.New()
-	hasher.Write([]byte(commitment))
-	for _, input := range publicInputs {
-		hasher.Write([]byte(input))
-	}
-
-	transcript := hasher.Sum(nil)
-
-	// Generate multiple challenges from transcript
-	challenges := make([]string, 3)
-	for i := 0; i < 3; i++ {
-		h := sha256.Sum256(append(transcript, byte(i)))
-		challenges[i] = hex.EncodeToString(h[:8]) // Use first 8 bytes
-	}
-
-	return challenges
-}
-
-// verifyTraceConsistency verifies the computation trace is consistent
-func (p *STARKProver) verifyTraceConsistency(trace []string, publicInputs []string) bool {
-	// Basic consistency checks
-	if len(trace) == 0 {
-		return false
-	}
-
-	if len(publicInputs) == 0 {
-		return false
+	// Default: hash of all inputs
+	inputJSON, err := json.Marshal(inputs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal inputs: %w", err)
 	}
-
-	// Trace should be well-formed
-	// In a real STARK, this would check polynomial constraints
-	return true
+	l, r := hashToElements(inputJSON)
+	air, trace := mimcTrace(l, r)
+	return air, trace, nil
 }
 
 // Helper functions
 
-func toBigInt(v interface{}) *big.Int {
+// toElement converts a JSON number or decimal string to a field element
+func toElement(v interface{}) (fr.Element, error) {
+	var s string
 	switch val := v.(type) {
-	case int:
-		return big.NewInt(int64(val))
-	case int64:
-		return big.NewInt(val)
-	case float64:
-		return big.NewInt(int64(val))
+	case json.Number:
+		s = val.String()
 	case string:
-		i, _ := new(big.Int).SetString(val, 10)
-		return i
+		s = val
 	default:
-		return big.NewInt(0)
+		return fr.Element{}, fmt.Errorf("invalid field element %v", v)
+	}
+	i, ok := new(big.Int).SetString(s, 10)
+	if !ok || i.Sign() < 0 || !i.IsUint64() {
+		return fr.Element{}, fmt.Errorf("invalid field element %q", s)
 	}
+	return newElement(i.Uint64())
 }
 
 func equalStringArrays(a, b []string) bool {
@@ -334,20 +437,20 @@ func equalStringArrays(a, b []string) bool {
 	return true
 }
 
-// STARKProof represents a STARK proof
-type STARKProof struct {
-	Trace        []string `json:"trace"`
-	Commitment   string   `json:"commitment"`
-	Challenges   []string `json:"challenges"`
-	PublicInputs []string `json:"public_inputs"`
-	Timestamp    string   `json:"timestamp"`
-	FieldPrime   string   `json:"field_prime"`
+// STARKVerificationKey represents STARK verification parameters: the
+// computation, its public inputs and the security options
+type STARKVerificationKey struct {
 	ProofVersion string   `json:"proof_version"`
+	AIR          string   `json:"air,omitempty"`
+	Field        string   `json:"field"`
+	PublicInputs []string `json:"public_inputs,omitempty"`
+	Options      Options  `json:"options"`
+	// Definition is the custom computation, absent for built-in ones
+	Definition *AIRDefinition `json:"definition,omitempty"`
 }
 
-// STARKVerificationKey represents STARK verification parameters
-type STARKVerificationKey struct {
-	FieldPrime   string   `json:"field_prime"`
-	PublicInputs []string `json:"public_inputs"`
-	ProofVersion string   `json:"proof_version"`
+// isCircuitKey reports whether the key is a circuit's, from Setup: one
+// that defines a computation but not the public inputs of one proof
+func (vk *STARKVerificationKey) isCircuitKey() bool {
+	return vk.Definition != nil && vk.PublicInputs == nil
 }
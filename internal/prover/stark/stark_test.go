@@ -3,8 +3,12 @@ package stark
 import (
 	"context"
 	"encoding/json"
+	"strconv"
+	"strings"
 	"testing"
 
+	fr "github.com/consensys/gnark-crypto/field/goldilocks"
+
 	"github.com/gabrielrondon/zapiki/internal/models"
 	"github.com/gabrielrondon/zapiki/internal/prover"
 	"github.com/google/uuid"
@@ -208,8 +212,8 @@ func TestSTARKProver_Verify_InvalidProof(t *testing.T) {
 	var proof STARKProof
 	json.Unmarshal(resp.Proof, &proof)
 
-	// Tamper with commitment
-	proof.Commitment = "tampered_commitment_value"
+	// Tamper with an out-of-domain trace value
+	proof.OODCurrent[0][0] = (proof.OODCurrent[0][0] + 1) % fieldModulus
 
 	tamperedProof, _ := json.Marshal(proof)
 
@@ -232,6 +236,128 @@ func TestSTARKProver_Verify_InvalidProof(t *testing.T) {
 	t.Logf("Correctly rejected tampered proof: %s", verifyResp.ErrorMessage)
 }
 
+func TestSTARKProver_Verify_TamperedQuery(t *testing.T) {
+	p := NewSTARKProver()
+	ctx := context.Background()
+
+	inputsJSON, _ := json.Marshal(map[string]interface{}{"a": 7, "b": 8, "c": 56})
+	resp, err := p.Generate(ctx, &prover.ProofRequest{
+		Data: &models.InputData{Type: models.DataTypeJSON, Value: inputsJSON},
+	})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	var proof STARKProof
+	if err := json.Unmarshal(resp.Proof, &proof); err != nil {
+		t.Fatalf("Failed to parse proof: %v", err)
+	}
+
+	// Tamper with an opened FRI value
+	proof.FRIQueries[0].Values[0][0][1] = (proof.FRIQueries[0].Values[0][0][1] + 1) % fieldModulus
+	tamperedProof, _ := json.Marshal(proof)
+
+	verifyResp, err := p.Verify(ctx, &prover.VerifyRequest{
+		Proof:           tamperedProof,
+		VerificationKey: resp.VerificationKey,
+	})
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if verifyResp.Valid {
+		t.Error("Proof with a tampered FRI opening should be invalid")
+	}
+}
+
+func TestSTARKProver_Generate_Unsatisfied(t *testing.T) {
+	p := NewSTARKProver()
+
+	inputsJSON, _ := json.Marshal(map[string]interface{}{"a": 7, "b": 8, "c": 57})
+	_, err := p.Generate(context.Background(), &prover.ProofRequest{
+		Data: &models.InputData{Type: models.DataTypeJSON, Value: inputsJSON},
+	})
+	if err == nil {
+		t.Error("Generate should fail when a * b != c")
+	}
+}
+
+func TestSTARKProver_ProofHidesTrace(t *testing.T) {
+	p := NewSTARKProver()
+
+	// Private factors that would stand out in the proof if it leaked the trace
+	a, b := uint64(3141592653), uint64(2718281)
+	var c fr.Element
+	c.Mul(new(fr.Element).SetUint64(a), new(fr.Element).SetUint64(b))
+
+	inputsJSON, _ := json.Marshal(map[string]interface{}{"a": a, "b": b, "c": c.Uint64()})
+	resp, err := p.Generate(context.Background(), &prover.ProofRequest{
+		Data: &models.InputData{Type: models.DataTypeJSON, Value: inputsJSON},
+	})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	for _, secret := range []uint64{a, b} {
+		if strings.Contains(string(resp.Proof), strconv.FormatUint(secret, 10)) {
+			t.Errorf("Proof contains the private input %d", secret)
+		}
+	}
+}
+
+func TestSTARKProver_Verify_InsufficientSecurity(t *testing.T) {
+	weak := NewSTARKProver()
+	if err := weak.SetOptions(Options{BlowupFactor: 4, NumQueries: 8, GrindingBits: 0}); err != nil {
+		t.Fatalf("Failed to set options: %v", err)
+	}
+	ctx := context.Background()
+
+	inputsJSON, _ := json.Marshal(map[string]interface{}{"value": "weak proof"})
+	resp, err := weak.Generate(ctx, &prover.ProofRequest{
+		Data: &models.InputData{Type: models.DataTypeJSON, Value: inputsJSON},
+	})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	verifyReq := &prover.VerifyRequest{
+		Proof:           resp.Proof,
+		VerificationKey: resp.VerificationKey,
+	}
+	verifyResp, err := weak.Verify(ctx, verifyReq)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if !verifyResp.Valid {
+		t.Errorf("Proof should be valid at its own security level. Error: %s", verifyResp.ErrorMessage)
+	}
+
+	verifyResp, err = NewSTARKProver().Verify(ctx, verifyReq)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if verifyResp.Valid {
+		t.Error("Proof below the configured security level should be invalid")
+	}
+}
+
+func TestOptions_Validate(t *testing.T) {
+	if err := DefaultOptions().Validate(); err != nil {
+		t.Errorf("Default options should be valid: %v", err)
+	}
+	if bits := DefaultOptions().SecurityBits(); bits < 100 {
+		t.Errorf("Default options give %d bits of security, expected at least 100", bits)
+	}
+	for _, options := range []Options{
+		{BlowupFactor: 6, NumQueries: 28},
+		{BlowupFactor: 8, NumQueries: 0},
+		{BlowupFactor: 8, NumQueries: 28, GrindingBits: 40},
+	} {
+		if err := options.Validate(); err == nil {
+			t.Errorf("Options %+v should be invalid", options)
+		}
+	}
+}
+
 func TestSTARKProver_Verify_MismatchedKeys(t *testing.T) {
 	p := NewSTARKProver()
 	ctx := context.Background()
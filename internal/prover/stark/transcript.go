@@ -0,0 +1,115 @@
+package stark
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math/bits"
+
+	fr "github.com/consensys/gnark-crypto/field/goldilocks"
+)
+
+// transcript derives the verifier's challenges from everything the prover
+// has sent so far (Fiat-Shamir), using SHA-256
+type transcript struct {
+	state   [sha256.Size]byte
+	counter uint64
+}
+
+func newTranscript(label string) *transcript {
+	t := &transcript{}
+	t.absorb([]byte(label))
+	return t
+}
+
+// absorb mixes length-prefixed messages into the state
+func (t *transcript) absorb(messages ...[]byte) {
+	h := sha256.New()
+	h.Write(t.state[:])
+	for _, m := range messages {
+		h.Write(binary.LittleEndian.AppendUint64(nil, uint64(len(m))))
+		h.Write(m)
+	}
+	copy(t.state[:], h.Sum(nil))
+	t.counter = 0
+}
+
+func (t *transcript) squeeze() [sha256.Size]byte {
+	h := sha256.New()
+	h.Write(t.state[:])
+	h.Write(binary.LittleEndian.AppendUint64(nil, t.counter))
+	t.counter++
+	var out [sha256.Size]byte
+	copy(out[:], h.Sum(nil))
+	return out
+}
+
+// drawElement draws a uniform base field element by rejection sampling
+func (t *transcript) drawElement() fr.Element {
+	for {
+		block := t.squeeze()
+		for i := 0; i+8 <= len(block); i += 8 {
+			if v := binary.LittleEndian.Uint64(block[i:]); v < fieldModulus {
+				return fr.NewElement(v)
+			}
+		}
+	}
+}
+
+func (t *transcript) drawExt() ext {
+	return ext{A0: t.drawElement(), A1: t.drawElement()}
+}
+
+func (t *transcript) drawExts(n int) []ext {
+	out := make([]ext, n)
+	for i := range out {
+		out[i] = t.drawExt()
+	}
+	return out
+}
+
+// drawIndices draws n indices below size, a power of two
+func (t *transcript) drawIndices(n, size int) []int {
+	out := make([]int, n)
+	for i := range out {
+		block := t.squeeze()
+		out[i] = int(binary.LittleEndian.Uint64(block[:]) & uint64(size-1))
+	}
+	return out
+}
+
+// grindingHash is the proof-of-work hash of a nonce against the current state
+func (t *transcript) grindingHash(nonce uint64) [sha256.Size]byte {
+	h := sha256.New()
+	h.Write([]byte("grinding"))
+	h.Write(t.state[:])
+	h.Write(binary.LittleEndian.AppendUint64(nil, nonce))
+	var out [sha256.Size]byte
+	copy(out[:], h.Sum(nil))
+	return out
+}
+
+// checkGrinding reports whether a nonce's hash has the required leading zero bits
+func (t *transcript) checkGrinding(nonce uint64, bitsRequired int) bool {
+	h := t.grindingHash(nonce)
+	return leadingZeros(h[:]) >= bitsRequired
+}
+
+// grind finds a nonce meeting the grinding requirement
+func (t *transcript) grind(bitsRequired int) uint64 {
+	for nonce := uint64(0); ; nonce++ {
+		if t.checkGrinding(nonce, bitsRequired) {
+			return nonce
+		}
+	}
+}
+
+func leadingZeros(b []byte) int {
+	n := 0
+	for _, v := range b {
+		if v != 0 {
+			return n + bits.LeadingZeros8(v)
+		}
+		n += 8
+	}
+	return n
+}
@@ -0,0 +1,127 @@
+package stark
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	fr "github.com/consensys/gnark-crypto/field/goldilocks"
+)
+
+// verify checks a proof against air, replaying the prover's transcript
+func verify(air AIR, proof *STARKProof) error {
+	if proof.ProofVersion != proofVersion {
+		return fmt.Errorf("unsupported proof version %q", proof.ProofVersion)
+	}
+	p, err := newParams(air, proof.Options)
+	if err != nil {
+		return err
+	}
+	if err := p.checkShape(proof); err != nil {
+		return err
+	}
+	t := p.transcript()
+
+	t.absorb(proof.TraceRoot)
+	compCoeffs := t.drawExts(air.NumTransitions() + len(air.Boundaries()))
+	t.absorb(proof.CompositionRoot)
+
+	// The claimed out-of-domain values must satisfy the constraints
+	ood := &oodFrame{z: p.drawOOD(t)}
+	g := lift(p.generator)
+	ood.gz.Mul(&ood.z, &g)
+	if ood.current, err = decodeExts(proof.OODCurrent); err != nil {
+		return err
+	}
+	if ood.next, err = decodeExts(proof.OODNext); err != nil {
+		return err
+	}
+	if ood.composition, err = decodeExts(proof.OODComposition); err != nil {
+		return err
+	}
+	expected := p.composition(compCoeffs, ood.z, ood.current, ood.next, p.periodicAt(ood.z))
+	claimed := p.combineSegments(ood.z, ood.composition)
+	if !expected.Equal(&claimed) {
+		return fmt.Errorf("out-of-domain values do not satisfy the constraints")
+	}
+	t.absorb(appendExts(nil, ood.current...), appendExts(nil, ood.next...), appendExts(nil, ood.composition...))
+
+	deepCoeffs := t.drawExts(2*p.width + 2*p.segments + 1)
+	betas := make([]ext, p.friLayers)
+	for l := range betas {
+		betas[l] = t.drawExt()
+		if l+1 < p.friLayers {
+			t.absorb(proof.FRIRoots[l])
+		}
+	}
+	remainder, err := decodeExts(proof.FRIRemainder)
+	if err != nil {
+		return err
+	}
+	t.absorb(appendExts(nil, remainder...))
+
+	if !t.checkGrinding(proof.PowNonce, proof.Options.GrindingBits) {
+		return fmt.Errorf("proof of work is invalid")
+	}
+	t.absorb(binary.LittleEndian.AppendUint64(nil, proof.PowNonce))
+	queries := uniqueSorted(t.drawIndices(proof.Options.NumQueries, p.ldeSize/2))
+
+	// Check the openings and compute the DEEP composition at each query
+	traceValues, err := p.checkOpening(proof.TraceRoot, proof.TraceQueries, queries, p.width)
+	if err != nil {
+		return fmt.Errorf("trace commitment: %w", err)
+	}
+	compValues, err := p.checkOpening(proof.CompositionRoot, proof.CompositionQueries, queries, 2*p.segments+1)
+	if err != nil {
+		return fmt.Errorf("composition commitment: %w", err)
+	}
+
+	first := make(map[int][2]ext, len(queries))
+	for k, q := range queries {
+		var pair [2]ext
+		for h, i := range []int{q, q + p.ldeSize/2} {
+			pair[h] = p.deepValue(deepCoeffs, p.layerPoint(0, i), traceValues[k][h], compValues[k][h], ood)
+		}
+		first[q] = pair
+	}
+	return p.verifyFRI(proof, betas, remainder, queries, first)
+}
+
+// checkShape checks that a proof's parts have the sizes the parameters imply
+func (p *params) checkShape(proof *STARKProof) error {
+	switch {
+	case len(proof.OODCurrent) != p.width || len(proof.OODNext) != p.width:
+		return fmt.Errorf("proof has %d/%d out-of-domain trace values, expected %d", len(proof.OODCurrent), len(proof.OODNext), p.width)
+	case len(proof.OODComposition) != 2*p.segments:
+		return fmt.Errorf("proof has %d out-of-domain composition values, expected %d", len(proof.OODComposition), 2*p.segments)
+	case len(proof.FRIRoots) != p.friLayers-1 || len(proof.FRIQueries) != p.friLayers-1:
+		return fmt.Errorf("proof has %d FRI layers, expected %d", len(proof.FRIRoots), p.friLayers-1)
+	case len(proof.FRIRemainder) != p.degreeBound>>p.friLayers:
+		return fmt.Errorf("proof has a FRI remainder of %d coefficients, expected %d", len(proof.FRIRemainder), p.degreeBound>>p.friLayers)
+	}
+	return nil
+}
+
+// checkOpening verifies the opened leaves of a commitment to columns
+// columns wide, returning each query's values at x and -x
+func (p *params) checkOpening(root []byte, opening MerkleOpening, queries []int, columns int) ([][2][]fr.Element, error) {
+	if len(opening.Values) != len(queries) {
+		return nil, fmt.Errorf("opens %d leaves, expected %d", len(opening.Values), len(queries))
+	}
+	values := make([][2][]fr.Element, len(queries))
+	leaves := make([][]byte, len(queries))
+	for k, leaf := range opening.Values {
+		if len(leaf) != 2*columns {
+			return nil, fmt.Errorf("leaf has %d values, expected %d", len(leaf), 2*columns)
+		}
+		elements, err := decodeElements(leaf)
+		if err != nil {
+			return nil, err
+		}
+		values[k] = [2][]fr.Element{elements[:columns], elements[columns:]}
+		leaves[k] = leafBytes(leaf)
+	}
+	if err := verifyMerkle(root, p.ldeSize/2, queries, leaves, opening.Proof); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
@@ -13,7 +13,9 @@ import (
 
 const (
 	// Task types
-	TypeProofGeneration = "proof:generate"
+	TypeProofGeneration  = "proof:generate"
+	TypeCircuitSetup     = "circuit:setup"
+	TypeProofAggregation = "proof:aggregate"
 )
 
 // Client wraps an asynq client for enqueueing jobs
@@ -84,6 +86,67 @@ func (c *Client) EnqueueProofGeneration(ctx context.Context, payload interface{}
 	return nil
 }
 
+// CircuitSetupPayload represents the payload for circuit setup jobs
+type CircuitSetupPayload struct {
+	CircuitID uuid.UUID `json:"circuit_id"`
+}
+
+// EnqueueCircuitSetup enqueues a circuit setup job
+func (c *Client) EnqueueCircuitSetup(ctx context.Context, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	task := asynq.NewTask(TypeCircuitSetup, data)
+
+	// Setup for large circuits can take far longer than a proof
+	opts := []asynq.Option{
+		asynq.Queue("setup"),
+		asynq.MaxRetry(2),
+		asynq.Timeout(2 * time.Hour),
+	}
+
+	info, err := c.client.EnqueueContext(ctx, task, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue task: %w", err)
+	}
+
+	fmt.Printf("Enqueued circuit setup job: %s (queue: %s)\n", info.ID, info.Queue)
+	return nil
+}
+
+// ProofAggregationPayload represents the payload for proof aggregation jobs
+type ProofAggregationPayload struct {
+	ProofID uuid.UUID `json:"proof_id"`
+}
+
+// EnqueueProofAggregation enqueues a proof aggregation job
+func (c *Client) EnqueueProofAggregation(ctx context.Context, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	task := asynq.NewTask(TypeProofAggregation, data)
+
+	// The first aggregation of a circuit runs setup for the outer circuit,
+	// which with field emulation takes far longer than a proof
+	opts := []asynq.Option{
+		asynq.Queue("proofs"),
+		asynq.MaxRetry(2),
+		asynq.Timeout(2 * time.Hour),
+	}
+
+	info, err := c.client.EnqueueContext(ctx, task, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue task: %w", err)
+	}
+
+	fmt.Printf("Enqueued proof aggregation job: %s (queue: %s)\n", info.ID, info.Queue)
+	return nil
+}
+
 // Server wraps an asynq server for processing jobs
 type Server struct {
 	server *asynq.Server
@@ -104,6 +167,7 @@ func NewServer(redisAddr, password string, concurrency int) *Server {
 				"proofs:high": 6, // High priority
 				"proofs":      3, // Normal priority
 				"proofs:low":  1, // Low priority
+				"setup":       2, // Circuit setup
 			},
 			ErrorHandler: asynq.ErrorHandlerFunc(func(ctx context.Context, task *asynq.Task, err error) {
 				fmt.Printf("Task %s failed: %v\n", task.Type(), err)
@@ -0,0 +1,69 @@
+package sanctions
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ofacNull marks empty fields in OFAC's CSV files
+const ofacNull = "-0-"
+
+// ofacIndividual is the SDN type of people, whose names OFAC lists as
+// "LAST, First"
+const ofacIndividual = "individual"
+
+// parseOFAC reads an OFAC CSV file (no header row) with the given column
+// count, taking names from nameColumn. typeColumn holds the SDN type, or is
+// -1 when the file has none and any name may be a person's.
+func parseOFAC(r io.Reader, columns, nameColumn, typeColumn int) ([]Entry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	var entries []Entry
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read OFAC CSV line %d: %w", line, err)
+		}
+
+		// The files end with a lone EOF marker record
+		if len(record) == 1 {
+			continue
+		}
+		if len(record) < columns {
+			return nil, fmt.Errorf("OFAC CSV line %d has %d columns, expected %d", line, len(record), columns)
+		}
+
+		name := strings.TrimSpace(record[nameColumn])
+		if name == "" || name == ofacNull {
+			continue
+		}
+		entry := Entry{Name: name}
+		if typeColumn < 0 || strings.TrimSpace(record[typeColumn]) == ofacIndividual {
+			if reordered, ok := firstNameFirst(name); ok {
+				entry.Aliases = []string{reordered}
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// firstNameFirst turns "LAST, First" into "First LAST", the order users
+// give their names in and that UN and EU lists use, so that both orders
+// are committed to
+func firstNameFirst(name string) (string, bool) {
+	last, first, ok := strings.Cut(name, ",")
+	last, first = strings.TrimSpace(last), strings.TrimSpace(first)
+	if !ok || strings.Contains(first, ",") || last == "" || first == "" {
+		return "", false
+	}
+	return first + " " + last, true
+}
@@ -0,0 +1,104 @@
+// Package sanctions parses published sanctions lists and maps their names
+// to the hashed identifiers committed to by sorted sanctions Merkle trees.
+package sanctions
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"unicode"
+
+	"github.com/gabrielrondon/zapiki/internal/prover/snark/gnark/circuits"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Format identifies a sanctions list file format
+type Format string
+
+const (
+	// FormatOFACSDN is OFAC's sdn.csv (primary names)
+	FormatOFACSDN Format = "ofac_sdn_csv"
+	// FormatOFACAlt is OFAC's alt.csv (aliases of SDN entries)
+	FormatOFACAlt Format = "ofac_alt_csv"
+	// FormatUN is the UN Security Council consolidated list XML
+	FormatUN Format = "un_xml"
+	// FormatEU is the EU financial sanctions consolidated list XML
+	FormatEU Format = "eu_xml"
+)
+
+// Formats lists the supported formats
+var Formats = []Format{FormatOFACSDN, FormatOFACAlt, FormatUN, FormatEU}
+
+// Entry is a listed person or organisation
+type Entry struct {
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// Names returns the entry's name followed by its aliases
+func (e Entry) Names() []string {
+	return append([]string{e.Name}, e.Aliases...)
+}
+
+// Parse reads the entries of a sanctions list file
+func Parse(format Format, r io.Reader) ([]Entry, error) {
+	switch format {
+	case FormatOFACSDN:
+		return parseOFAC(r, 12, 1, 2)
+	case FormatOFACAlt:
+		return parseOFAC(r, 5, 3, -1)
+	case FormatUN:
+		return parseUN(r)
+	case FormatEU:
+		return parseEU(r)
+	default:
+		return nil, fmt.Errorf("unsupported sanctions list format: %s", format)
+	}
+}
+
+// Normalize canonicalizes a name before hashing: diacritics are stripped,
+// letters upper-cased, punctuation dropped and whitespace collapsed, so
+// "José  O'Neil-Díaz" and "JOSE O NEIL DIAZ" share an identifier
+func Normalize(name string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range norm.NFKD.String(name) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// Combining marks left over from decomposition
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(unicode.ToUpper(r))
+		default:
+			space = true
+		}
+	}
+	return b.String()
+}
+
+// Identifier returns the hashed identifier of a name, the value users
+// prove non-membership of
+func Identifier(name string) *big.Int {
+	return circuits.HashIdentifier([]byte(Normalize(name)))
+}
+
+// Identifiers returns the de-duplicated identifiers of every name and alias
+func Identifiers(entries []Entry) []*big.Int {
+	seen := make(map[string]bool)
+	var ids []*big.Int
+	for _, entry := range entries {
+		for _, name := range entry.Names() {
+			normalized := Normalize(name)
+			if normalized == "" || seen[normalized] {
+				continue
+			}
+			seen[normalized] = true
+			ids = append(ids, circuits.HashIdentifier([]byte(normalized)))
+		}
+	}
+	return ids
+}
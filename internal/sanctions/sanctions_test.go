@@ -0,0 +1,152 @@
+package sanctions
+
+import (
+	"strings"
+	"testing"
+)
+
+const testSDN = `36,"AEROCARIBBEAN AIRLINES",-0- ,"CUBA",-0- ,-0- ,-0- ,-0- ,-0- ,-0- ,-0- ,-0- 
+173,"ANGLO-CARIBBEAN CO., LTD.",-0- ,"CUBA",-0- ,-0- ,-0- ,-0- ,-0- ,-0- ,-0- ,-0- 
+306,"BANCO NACIONAL DE CUBA",-0- ,"CUBA",-0- ,-0- ,-0- ,-0- ,-0- ,-0- ,-0- ,"a.k.a. 'BNC'."
+2674,"SMITH, John",individual,"SDGT",-0- ,-0- ,-0- ,-0- ,-0- ,-0- ,-0- ,-0- 
+` + "\x1a\n"
+
+const testAlt = `36,12,"aka","AERO-CARIBBEAN",-0- 
+306,220,"aka","NATIONAL BANK OF CUBA",-0- 
+2674,4410,"aka","SMYTH, Jon",-0- 
+`
+
+const testUN = `<?xml version="1.0" encoding="UTF-8"?>
+<CONSOLIDATED_LIST dateGenerated="2024-01-01T00:00:00">
+  <INDIVIDUALS>
+    <INDIVIDUAL>
+      <FIRST_NAME>ABDUL</FIRST_NAME>
+      <SECOND_NAME>RAHMAN</SECOND_NAME>
+      <THIRD_NAME></THIRD_NAME>
+      <INDIVIDUAL_ALIAS><QUALITY>Good</QUALITY><ALIAS_NAME>Abd al-Rahman</ALIAS_NAME></INDIVIDUAL_ALIAS>
+      <INDIVIDUAL_ALIAS><QUALITY>Low</QUALITY><ALIAS_NAME></ALIAS_NAME></INDIVIDUAL_ALIAS>
+    </INDIVIDUAL>
+  </INDIVIDUALS>
+  <ENTITIES>
+    <ENTITY>
+      <FIRST_NAME>AL-QAIDA</FIRST_NAME>
+      <ENTITY_ALIAS><ALIAS_NAME>The Base</ALIAS_NAME></ENTITY_ALIAS>
+    </ENTITY>
+  </ENTITIES>
+</CONSOLIDATED_LIST>`
+
+const testEU = `<?xml version="1.0" encoding="UTF-8"?>
+<export xmlns="http://eu.europa.ec/fpi/fsd/export" generationDate="2024-01-01T00:00:00">
+  <sanctionEntity logicalId="13">
+    <nameAlias firstName="Saddam" middleName="" lastName="Hussein Al-Tikriti" wholeName="Saddam Hussein Al-Tikriti"/>
+    <nameAlias firstName="" lastName="" wholeName="Abu Ali"/>
+  </sanctionEntity>
+  <sanctionEntity logicalId="14">
+    <nameAlias firstName="José" middleName="María" lastName="Núñez" wholeName=""/>
+  </sanctionEntity>
+</export>`
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		format Format
+		input  string
+		want   []Entry
+	}{
+		{FormatOFACSDN, testSDN, []Entry{
+			{Name: "AEROCARIBBEAN AIRLINES"},
+			{Name: "ANGLO-CARIBBEAN CO., LTD."},
+			{Name: "BANCO NACIONAL DE CUBA"},
+			{Name: "SMITH, John", Aliases: []string{"John SMITH"}},
+		}},
+		{FormatOFACAlt, testAlt, []Entry{
+			{Name: "AERO-CARIBBEAN"},
+			{Name: "NATIONAL BANK OF CUBA"},
+			{Name: "SMYTH, Jon", Aliases: []string{"Jon SMYTH"}},
+		}},
+		{FormatUN, testUN, []Entry{
+			{Name: "ABDUL RAHMAN", Aliases: []string{"Abd al-Rahman"}},
+			{Name: "AL-QAIDA", Aliases: []string{"The Base"}},
+		}},
+		{FormatEU, testEU, []Entry{
+			{Name: "Saddam Hussein Al-Tikriti", Aliases: []string{"Abu Ali"}},
+			{Name: "José María Núñez"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			entries, err := Parse(tt.format, strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("Failed to parse: %v", err)
+			}
+			if len(entries) != len(tt.want) {
+				t.Fatalf("Expected %d entries, got %d: %v", len(tt.want), len(entries), entries)
+			}
+			for i := range entries {
+				if strings.Join(entries[i].Names(), "|") != strings.Join(tt.want[i].Names(), "|") {
+					t.Errorf("Entry %d: expected %v, got %v", i, tt.want[i], entries[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParse_OFACIndividualsMatchFirstNameFirst(t *testing.T) {
+	entries, err := Parse(FormatOFACSDN, strings.NewReader(testSDN))
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	// An individual listed as "SMITH, John" is found by the name as users
+	// give it, as for UN and EU entries
+	listed := false
+	for _, id := range Identifiers(entries) {
+		listed = listed || id.Cmp(Identifier("John Smith")) == 0
+	}
+	if !listed {
+		t.Error(`Expected "John Smith" to match the OFAC entry "SMITH, John"`)
+	}
+}
+
+func TestParse_RejectsMalformedInput(t *testing.T) {
+	if _, err := Parse(FormatOFACSDN, strings.NewReader("1,\"SHORT\"\n")); err == nil {
+		t.Error("Expected a short OFAC record to be rejected")
+	}
+	if _, err := Parse(FormatUN, strings.NewReader("<CONSOLIDATED_LIST>")); err == nil {
+		t.Error("Expected truncated XML to be rejected")
+	}
+	if _, err := Parse("pdf", strings.NewReader("")); err == nil {
+		t.Error("Expected an unknown format to be rejected")
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"José  O'Neil-Díaz":         "JOSE O NEIL DIAZ",
+		"  banco nacional de cuba ": "BANCO NACIONAL DE CUBA",
+		"ANGLO-CARIBBEAN CO., LTD.": "ANGLO CARIBBEAN CO LTD",
+		"Ｆｕｌｌｗｉｄｔｈ":                 "FULLWIDTH",
+		"---":                       "",
+	}
+
+	for input, want := range tests {
+		if got := Normalize(input); got != want {
+			t.Errorf("Normalize(%q) = %q, expected %q", input, got, want)
+		}
+	}
+}
+
+func TestIdentifiers(t *testing.T) {
+	entries := []Entry{
+		{Name: "José Núñez", Aliases: []string{"JOSE NUNEZ", "Pepe"}},
+		{Name: "pepe"},
+	}
+
+	ids := Identifiers(entries)
+	if len(ids) != 2 {
+		t.Fatalf("Expected 2 distinct identifiers, got %d", len(ids))
+	}
+	if ids[0].Cmp(Identifier("jose nunez")) != 0 {
+		t.Error("Expected identifiers to hash normalized names")
+	}
+}
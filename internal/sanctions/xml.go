@@ -0,0 +1,115 @@
+package sanctions
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// unList is the subset of the UN consolidated list we read
+type unList struct {
+	Individuals []struct {
+		FirstName  string `xml:"FIRST_NAME"`
+		SecondName string `xml:"SECOND_NAME"`
+		ThirdName  string `xml:"THIRD_NAME"`
+		FourthName string `xml:"FOURTH_NAME"`
+		Aliases    []struct {
+			Name string `xml:"ALIAS_NAME"`
+		} `xml:"INDIVIDUAL_ALIAS"`
+	} `xml:"INDIVIDUALS>INDIVIDUAL"`
+	Entities []struct {
+		FirstName string `xml:"FIRST_NAME"`
+		Aliases   []struct {
+			Name string `xml:"ALIAS_NAME"`
+		} `xml:"ENTITY_ALIAS"`
+	} `xml:"ENTITIES>ENTITY"`
+}
+
+func parseUN(r io.Reader) ([]Entry, error) {
+	var list unList
+	if err := xml.NewDecoder(r).Decode(&list); err != nil {
+		return nil, fmt.Errorf("failed to parse UN list: %w", err)
+	}
+
+	var entries []Entry
+	for _, ind := range list.Individuals {
+		entry := Entry{Name: joinNames(ind.FirstName, ind.SecondName, ind.ThirdName, ind.FourthName)}
+		for _, alias := range ind.Aliases {
+			entry.Aliases = appendName(entry.Aliases, alias.Name)
+		}
+		entries = appendEntry(entries, entry)
+	}
+	for _, ent := range list.Entities {
+		entry := Entry{Name: strings.TrimSpace(ent.FirstName)}
+		for _, alias := range ent.Aliases {
+			entry.Aliases = appendName(entry.Aliases, alias.Name)
+		}
+		entries = appendEntry(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// euList is the subset of the EU consolidated list (FSF export) we read
+type euList struct {
+	Entities []struct {
+		NameAliases []struct {
+			WholeName  string `xml:"wholeName,attr"`
+			FirstName  string `xml:"firstName,attr"`
+			MiddleName string `xml:"middleName,attr"`
+			LastName   string `xml:"lastName,attr"`
+		} `xml:"nameAlias"`
+	} `xml:"sanctionEntity"`
+}
+
+func parseEU(r io.Reader) ([]Entry, error) {
+	var list euList
+	if err := xml.NewDecoder(r).Decode(&list); err != nil {
+		return nil, fmt.Errorf("failed to parse EU list: %w", err)
+	}
+
+	var entries []Entry
+	for _, ent := range list.Entities {
+		var names []string
+		for _, alias := range ent.NameAliases {
+			name := alias.WholeName
+			if strings.TrimSpace(name) == "" {
+				name = joinNames(alias.FirstName, alias.MiddleName, alias.LastName)
+			}
+			names = appendName(names, name)
+		}
+		if len(names) == 0 {
+			continue
+		}
+		entries = append(entries, Entry{Name: names[0], Aliases: names[1:]})
+	}
+
+	return entries, nil
+}
+
+func joinNames(parts ...string) string {
+	var names []string
+	for _, part := range parts {
+		names = appendName(names, part)
+	}
+	return strings.Join(names, " ")
+}
+
+func appendName(names []string, name string) []string {
+	if name = strings.TrimSpace(name); name != "" {
+		names = append(names, name)
+	}
+	return names
+}
+
+// appendEntry adds entry, promoting its first alias when it has no name
+func appendEntry(entries []Entry, entry Entry) []Entry {
+	if entry.Name == "" {
+		if len(entry.Aliases) == 0 {
+			return entries
+		}
+		entry.Name, entry.Aliases = entry.Aliases[0], entry.Aliases[1:]
+	}
+	return append(entries, entry)
+}
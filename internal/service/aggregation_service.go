@@ -0,0 +1,325 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gabrielrondon/zapiki/internal/models"
+	"github.com/gabrielrondon/zapiki/internal/prover"
+	"github.com/gabrielrondon/zapiki/internal/queue"
+	"github.com/gabrielrondon/zapiki/internal/storage/postgres"
+	"github.com/google/uuid"
+)
+
+// ErrAggregationUnsupported is returned when no enabled proof system can
+// aggregate proofs
+var ErrAggregationUnsupported = errors.New("proof aggregation is not supported")
+
+// MaxAggregatedProofs bounds the proofs in one aggregation, as the Groth16
+// prover does, so oversized requests fail before a job is queued
+const MaxAggregatedProofs = 32
+
+// AggregationService aggregates stored Groth16 proofs of one circuit into a
+// recursive proof that all of them verify
+type AggregationService struct {
+	factory     *prover.Factory
+	proofRepo   *postgres.ProofRepository
+	circuitRepo *postgres.CircuitRepository
+	queueClient interface {
+		EnqueueProofAggregation(ctx context.Context, payload interface{}) error
+	}
+}
+
+// NewAggregationService creates a new aggregation service
+func NewAggregationService(factory *prover.Factory, proofRepo *postgres.ProofRepository, circuitRepo *postgres.CircuitRepository) *AggregationService {
+	return &AggregationService{
+		factory:     factory,
+		proofRepo:   proofRepo,
+		circuitRepo: circuitRepo,
+	}
+}
+
+// SetQueueClient moves aggregation into background jobs. Without a queue
+// client the recursive proof is generated inside the request.
+func (s *AggregationService) SetQueueClient(queueClient interface {
+	EnqueueProofAggregation(ctx context.Context, payload interface{}) error
+}) {
+	s.queueClient = queueClient
+}
+
+// AggregateProofsRequest asks to aggregate stored proofs
+type AggregateProofsRequest struct {
+	UserID   uuid.UUID   `json:"user_id"`
+	ProofIDs []uuid.UUID `json:"proof_ids"`
+	// VerificationKey and Curve describe proofs generated without a
+	// circuit_id; proofs of a circuit use its stored key and curve
+	VerificationKey json.RawMessage `json:"verification_key,omitempty"`
+	Curve           string          `json:"curve,omitempty"`
+}
+
+// AggregateProofsResponse represents the response from proof aggregation
+type AggregateProofsResponse struct {
+	ProofID          uuid.UUID                `json:"proof_id"`
+	Status           models.ProofStatus       `json:"status"`
+	Proof            json.RawMessage          `json:"proof,omitempty"`
+	PublicInputs     json.RawMessage          `json:"public_inputs,omitempty"`
+	Aggregation      *models.ProofAggregation `json:"aggregation"`
+	GenerationTimeMs int64                    `json:"generation_time_ms,omitempty"`
+	Message          string                   `json:"message,omitempty"`
+}
+
+// Aggregate creates a proof record attesting that the given proofs verify
+// and generates it, or enqueues its generation
+func (s *AggregationService) Aggregate(ctx context.Context, req *AggregateProofsRequest) (*AggregateProofsResponse, error) {
+	aggregator, err := s.aggregator()
+	if err != nil {
+		return nil, err
+	}
+
+	inner, err := s.loadProofs(ctx, req.UserID, req.ProofIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	aggregation := &models.ProofAggregation{
+		ProofIDs:   req.ProofIDs,
+		CircuitID:  inner[0].CircuitID,
+		InnerCurve: req.Curve,
+	}
+	if aggregation.InnerCurve == "" {
+		aggregation.InnerCurve = proofCurve(inner[0])
+	}
+	if aggregation.CircuitID == nil {
+		if len(req.VerificationKey) == 0 {
+			return nil, fmt.Errorf("%w: verification_key is required for proofs without a circuit", prover.ErrInvalidProofAggregation)
+		}
+		aggregation.InnerVerificationKey = req.VerificationKey
+	}
+
+	aggregationJSON, err := json.Marshal(aggregation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode aggregation: %w", err)
+	}
+
+	proof := &models.Proof{
+		ID:          uuid.New(),
+		UserID:      req.UserID,
+		ProofSystem: models.ProofSystemGroth16,
+		Status:      models.ProofStatusPending,
+		Aggregation: aggregationJSON,
+		CreatedAt:   time.Now(),
+	}
+
+	if s.queueClient != nil {
+		if err := s.proofRepo.Create(ctx, proof); err != nil {
+			return nil, fmt.Errorf("failed to create proof record: %w", err)
+		}
+
+		payload := &queue.ProofAggregationPayload{ProofID: proof.ID}
+		if err := s.queueClient.EnqueueProofAggregation(ctx, payload); err != nil {
+			err = fmt.Errorf("failed to enqueue aggregation: %w", err)
+			s.markFailed(ctx, proof, err)
+			return nil, err
+		}
+
+		return &AggregateProofsResponse{
+			ProofID:     proof.ID,
+			Status:      models.ProofStatusPending,
+			Aggregation: aggregation,
+			Message:     "Proof aggregation started. Poll /api/v1/proofs/" + proof.ID.String() + " for status.",
+		}, nil
+	}
+
+	// No queue configured, so aggregate inline
+	proof.Status = models.ProofStatusProcessing
+	if err := s.proofRepo.Create(ctx, proof); err != nil {
+		return nil, fmt.Errorf("failed to create proof record: %w", err)
+	}
+
+	if err := s.aggregate(ctx, aggregator, proof, aggregation, inner); err != nil {
+		return nil, err
+	}
+
+	return &AggregateProofsResponse{
+		ProofID:          proof.ID,
+		Status:           proof.Status,
+		Proof:            proof.ProofData,
+		PublicInputs:     proof.PublicInputs,
+		Aggregation:      aggregation,
+		GenerationTimeMs: proof.GenerationTimeMs,
+	}, nil
+}
+
+// RunAggregation generates a pending aggregated proof and records the
+// outcome. It is called by the worker for proof aggregation jobs.
+func (s *AggregationService) RunAggregation(ctx context.Context, proofID uuid.UUID) error {
+	proof, err := s.proofRepo.GetByID(ctx, proofID)
+	if err != nil {
+		return fmt.Errorf("failed to get proof: %w", err)
+	}
+
+	// A retried job may find the work already done
+	if proof.Status == models.ProofStatusCompleted {
+		return nil
+	}
+
+	var aggregation models.ProofAggregation
+	if err := json.Unmarshal(proof.Aggregation, &aggregation); err != nil {
+		err = fmt.Errorf("failed to decode aggregation: %w", err)
+		s.markFailed(ctx, proof, err)
+		return err
+	}
+
+	aggregator, err := s.aggregator()
+	if err != nil {
+		s.markFailed(ctx, proof, err)
+		return err
+	}
+
+	inner, err := s.loadProofs(ctx, proof.UserID, aggregation.ProofIDs)
+	if err != nil {
+		s.markFailed(ctx, proof, err)
+		return err
+	}
+
+	proof.Status = models.ProofStatusProcessing
+	if err := s.proofRepo.Update(ctx, proof); err != nil {
+		return fmt.Errorf("failed to update proof status: %w", err)
+	}
+
+	return s.aggregate(ctx, aggregator, proof, &aggregation, inner)
+}
+
+// aggregate generates the recursive proof and stores it, with the
+// statements it covers, in the proof record
+func (s *AggregationService) aggregate(ctx context.Context, aggregator prover.ProofAggregator, proof *models.Proof, aggregation *models.ProofAggregation, inner []*models.Proof) error {
+	startTime := time.Now()
+
+	req := &prover.AggregationRequest{
+		VerificationKey: aggregation.InnerVerificationKey,
+		Curve:           aggregation.InnerCurve,
+	}
+	if aggregation.CircuitID != nil {
+		circuit, err := s.circuitRepo.GetByID(ctx, *aggregation.CircuitID)
+		if err != nil {
+			err = fmt.Errorf("failed to get circuit: %w", err)
+			s.markFailed(ctx, proof, err)
+			return err
+		}
+		req.Circuit = circuit
+		req.VerificationKeyURL = circuit.VerificationKeyURL
+	}
+	for _, p := range inner {
+		req.Proofs = append(req.Proofs, prover.AggregatedProof{
+			Proof:        p.ProofData,
+			PublicInputs: p.PublicInputs,
+		})
+	}
+
+	resp, err := aggregator.AggregateProofs(ctx, req)
+	if err != nil {
+		err = fmt.Errorf("failed to aggregate proofs: %w", err)
+		s.markFailed(ctx, proof, err)
+		return err
+	}
+
+	aggregation.Curve, _ = resp.Metadata["curve"].(string)
+	aggregation.InnerCurve, _ = resp.Metadata["inner_curve"].(string)
+	aggregation.Statements, _ = resp.Metadata["statements"].([][]string)
+	aggregation.VerificationKey = resp.VerificationKey
+
+	aggregationJSON, err := json.Marshal(aggregation)
+	if err != nil {
+		err = fmt.Errorf("failed to encode aggregation: %w", err)
+		s.markFailed(ctx, proof, err)
+		return err
+	}
+
+	proof.Status = models.ProofStatusCompleted
+	proof.ProofData = resp.Proof
+	proof.PublicInputs = resp.PublicInputs
+	proof.Aggregation = aggregationJSON
+	proof.GenerationTimeMs = time.Since(startTime).Milliseconds()
+	now := time.Now()
+	proof.CompletedAt = &now
+
+	// The recursive proof is a Groth16 proof of the aggregator's system
+	if system, ok := aggregator.(prover.ProofSystem); ok {
+		if proof.Envelope, err = NewProofEnvelope(system, proof, nil, resp, startTime); err != nil {
+			err = fmt.Errorf("failed to build proof envelope: %w", err)
+			s.markFailed(ctx, proof, err)
+			return err
+		}
+	}
+
+	if err := s.proofRepo.Update(ctx, proof); err != nil {
+		return fmt.Errorf("failed to update proof record: %w", err)
+	}
+	return nil
+}
+
+// loadProofs returns the user's completed Groth16 proofs by ID, checking
+// that they belong to the same circuit
+func (s *AggregationService) loadProofs(ctx context.Context, userID uuid.UUID, proofIDs []uuid.UUID) ([]*models.Proof, error) {
+	if len(proofIDs) == 0 || len(proofIDs) > MaxAggregatedProofs {
+		return nil, fmt.Errorf("%w: between 1 and %d proof_ids are required", prover.ErrInvalidProofAggregation, MaxAggregatedProofs)
+	}
+
+	proofs := make([]*models.Proof, len(proofIDs))
+	for i, id := range proofIDs {
+		proof, err := s.proofRepo.GetByID(ctx, id)
+		if err != nil || proof.UserID != userID {
+			return nil, fmt.Errorf("%w: proof %s not found", prover.ErrInvalidProofAggregation, id)
+		}
+
+		switch {
+		case proof.ProofSystem != models.ProofSystemGroth16:
+			return nil, fmt.Errorf("%w: proof %s is a %s proof, not groth16", prover.ErrInvalidProofAggregation, id, proof.ProofSystem)
+		case proof.Status != models.ProofStatusCompleted:
+			return nil, fmt.Errorf("%w: proof %s is %s", prover.ErrInvalidProofAggregation, id, proof.Status)
+		case len(proof.Aggregation) > 0:
+			return nil, fmt.Errorf("%w: proof %s is already an aggregated proof", prover.ErrInvalidProofAggregation, id)
+		case !sameCircuit(proof.CircuitID, proofs[0]):
+			return nil, fmt.Errorf("%w: proof %s is of a different circuit", prover.ErrInvalidProofAggregation, id)
+		}
+		proofs[i] = proof
+	}
+	return proofs, nil
+}
+
+// sameCircuit reports whether circuitID is the circuit of first, which is
+// nil while the first proof is being loaded
+func sameCircuit(circuitID *uuid.UUID, first *models.Proof) bool {
+	if first == nil {
+		return true
+	}
+	if circuitID == nil || first.CircuitID == nil {
+		return circuitID == nil && first.CircuitID == nil
+	}
+	return *circuitID == *first.CircuitID
+}
+
+// aggregator returns the Groth16 proof system's aggregator
+func (s *AggregationService) aggregator() (prover.ProofAggregator, error) {
+	system, err := s.factory.Get(models.ProofSystemGroth16)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrAggregationUnsupported, err)
+	}
+	aggregator, ok := system.(prover.ProofAggregator)
+	if !ok {
+		return nil, ErrAggregationUnsupported
+	}
+	return aggregator, nil
+}
+
+// markFailed records an aggregation error on the proof record
+func (s *AggregationService) markFailed(ctx context.Context, proof *models.Proof, err error) {
+	proof.Status = models.ProofStatusFailed
+	proof.ErrorMessage = err.Error()
+	now := time.Now()
+	proof.CompletedAt = &now
+	_ = s.proofRepo.Update(ctx, proof)
+}
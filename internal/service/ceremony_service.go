@@ -0,0 +1,326 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/gabrielrondon/zapiki/internal/models"
+	"github.com/gabrielrondon/zapiki/internal/prover"
+	"github.com/gabrielrondon/zapiki/internal/storage/artifact"
+	"github.com/gabrielrondon/zapiki/internal/storage/postgres"
+	"github.com/google/uuid"
+)
+
+// ErrCeremonyClosed is returned when a finalized ceremony is changed
+var ErrCeremonyClosed = errors.New("ceremony is finalized")
+
+// ErrCeremonyExists is returned when a circuit already has a ceremony
+var ErrCeremonyExists = errors.New("circuit already has a ceremony")
+
+// ErrNoContributions is returned when a ceremony is finalized before anyone contributed
+var ErrNoContributions = errors.New("ceremony has no contributions")
+
+// ErrNotCeremonyCoordinator is returned when someone other than the
+// circuit owner opens or finalizes a ceremony
+var ErrNotCeremonyCoordinator = errors.New("only the circuit owner can coordinate its ceremony")
+
+// CeremonyService runs multi-party setup ceremonies for circuits created
+// with "ceremony": true. The circuit owner opens and finalizes the ceremony;
+// anyone who can see the circuit may contribute.
+type CeremonyService struct {
+	factory        *prover.Factory
+	repo           *postgres.CeremonyRepository
+	circuitService *CircuitService
+	artifacts      artifact.Store
+}
+
+// NewCeremonyService creates a new ceremony service
+func NewCeremonyService(factory *prover.Factory, repo *postgres.CeremonyRepository, circuitService *CircuitService, artifacts artifact.Store) *CeremonyService {
+	return &CeremonyService{
+		factory:        factory,
+		repo:           repo,
+		circuitService: circuitService,
+		artifacts:      artifacts,
+	}
+}
+
+// CeremonyTranscript is the public record of a ceremony. With the states
+// it lists, anyone can re-verify every contribution and recompute the keys.
+type CeremonyTranscript struct {
+	Ceremony          *models.Ceremony               `json:"ceremony"`
+	ProofSystem       models.ProofSystemType         `json:"proof_system"`
+	CircuitDefinition json.RawMessage                `json:"circuit_definition"`
+	Contributions     []*models.CeremonyContribution `json:"contributions"`
+}
+
+// Open starts the ceremony for a circuit awaiting one
+func (s *CeremonyService) Open(ctx context.Context, circuitID, userID uuid.UUID) (*models.Ceremony, error) {
+	circuit, err := s.circuitService.Get(ctx, circuitID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if circuit.UserID != userID {
+		return nil, ErrNotCeremonyCoordinator
+	}
+	if circuit.SetupStatus != models.CircuitSetupCeremony {
+		return nil, fmt.Errorf("%w: circuit was not created for a setup ceremony", prover.ErrCeremonyUnsupported)
+	}
+
+	if _, err := s.repo.GetByCircuit(ctx, circuit.ID); err == nil {
+		return nil, ErrCeremonyExists
+	} else if !errors.Is(err, postgres.ErrCeremonyNotFound) {
+		return nil, err
+	}
+
+	system, err := s.ceremonySystem(circuit)
+	if err != nil {
+		return nil, err
+	}
+
+	state, base, err := system.CeremonyStart(ctx, circuit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start ceremony: %w", err)
+	}
+
+	ceremony := &models.Ceremony{
+		ID:          uuid.New(),
+		CircuitID:   circuit.ID,
+		UserID:      userID,
+		Status:      models.CeremonyStatusOpen,
+		Phase1:      base,
+		InitialHash: sha256Hex(state),
+	}
+
+	ceremony.InitialStateURL, err = s.artifacts.Put(ctx, stateKey(ceremony.ID, 0, ceremony.InitialHash), state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store ceremony state: %w", err)
+	}
+
+	if err := s.repo.Create(ctx, ceremony); err != nil {
+		_ = s.artifacts.Delete(ctx, ceremony.InitialStateURL)
+		return nil, err
+	}
+
+	return ceremony, nil
+}
+
+// Transcript returns a ceremony with its circuit and contributions
+func (s *CeremonyService) Transcript(ctx context.Context, ceremonyID, userID uuid.UUID) (*CeremonyTranscript, error) {
+	ceremony, circuit, err := s.load(ctx, ceremonyID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	contributions, err := s.repo.ListContributions(ctx, ceremony.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CeremonyTranscript{
+		Ceremony:          ceremony,
+		ProofSystem:       circuit.ProofSystem,
+		CircuitDefinition: circuit.CircuitDefinition,
+		Contributions:     contributions,
+	}, nil
+}
+
+// State returns the ceremony's latest state, the one the next participant
+// contributes to, and its index (0 for the initial state)
+func (s *CeremonyService) State(ctx context.Context, ceremonyID, userID uuid.UUID) ([]byte, int, error) {
+	ceremony, _, err := s.load(ctx, ceremonyID, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	state, err := s.state(ctx, ceremony, ceremony.ContributionCount)
+	if err != nil {
+		return nil, 0, err
+	}
+	return state, ceremony.ContributionCount, nil
+}
+
+// ContributionState returns the state after the index-th contribution
+// (0 for the initial state), so the transcript can be re-verified
+func (s *CeremonyService) ContributionState(ctx context.Context, ceremonyID, userID uuid.UUID, index int) ([]byte, error) {
+	ceremony, _, err := s.load(ctx, ceremonyID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if index < 0 || index > ceremony.ContributionCount {
+		return nil, fmt.Errorf("%w: no contribution %d", postgres.ErrCeremonyNotFound, index)
+	}
+
+	return s.state(ctx, ceremony, index)
+}
+
+// Contribute verifies a participant's contribution against the latest
+// state and makes it the new latest state
+func (s *CeremonyService) Contribute(ctx context.Context, ceremonyID, userID uuid.UUID, participant string, contribution []byte) (*models.CeremonyContribution, error) {
+	ceremony, circuit, err := s.load(ctx, ceremonyID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if ceremony.Status != models.CeremonyStatusOpen {
+		return nil, ErrCeremonyClosed
+	}
+
+	system, err := s.ceremonySystem(circuit)
+	if err != nil {
+		return nil, err
+	}
+
+	prev, err := s.state(ctx, ceremony, ceremony.ContributionCount)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := system.CeremonyVerify(ctx, prev, contribution)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &models.CeremonyContribution{
+		CeremonyID:  ceremony.ID,
+		Index:       ceremony.ContributionCount + 1,
+		UserID:      userID,
+		Participant: participant,
+		Hash:        sha256Hex(state),
+	}
+
+	// The hash keeps racing uploads for the same index from overwriting each other
+	record.StateURL, err = s.artifacts.Put(ctx, stateKey(ceremony.ID, record.Index, record.Hash), state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store ceremony state: %w", err)
+	}
+
+	if err := s.repo.AddContribution(ctx, record); err != nil {
+		_ = s.artifacts.Delete(ctx, record.StateURL)
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// Finalize verifies the whole ceremony, applies the public beacon and
+// installs the resulting keys on the circuit
+func (s *CeremonyService) Finalize(ctx context.Context, ceremonyID, userID uuid.UUID, beacon []byte) (*models.Ceremony, error) {
+	ceremony, circuit, err := s.load(ctx, ceremonyID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if circuit.UserID != userID {
+		return nil, ErrNotCeremonyCoordinator
+	}
+
+	// A finalized ceremony whose keys failed to install can be retried
+	// with the same beacon
+	retry := ceremony.Status == models.CeremonyStatusFinalized
+	if retry && (circuit.SetupStatus != models.CircuitSetupCeremony || ceremony.Beacon != hex.EncodeToString(beacon)) {
+		return nil, ErrCeremonyClosed
+	}
+	if ceremony.ContributionCount == 0 {
+		return nil, ErrNoContributions
+	}
+
+	system, err := s.ceremonySystem(circuit)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([][]byte, ceremony.ContributionCount)
+	for i := range states {
+		if states[i], err = s.state(ctx, ceremony, i+1); err != nil {
+			return nil, err
+		}
+	}
+
+	setupResult, err := system.CeremonyFinalize(ctx, circuit, states, beacon)
+	if err != nil {
+		return nil, fmt.Errorf("failed to finalize ceremony: %w", err)
+	}
+
+	// Close the ceremony first, so no contribution lands after the keys were derived
+	if !retry {
+		ceremony.Beacon = hex.EncodeToString(beacon)
+		ceremony.VerificationKeyHash = sha256Hex(setupResult.VerificationKey)
+		if err := s.repo.Finalize(ctx, ceremony); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.circuitService.InstallCeremonyKeys(ctx, circuit, setupResult); err != nil {
+		return nil, err
+	}
+
+	return ceremony, nil
+}
+
+// load retrieves a ceremony and its circuit, if the user can see the circuit
+func (s *CeremonyService) load(ctx context.Context, ceremonyID, userID uuid.UUID) (*models.Ceremony, *models.Circuit, error) {
+	ceremony, err := s.repo.GetByID(ctx, ceremonyID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	circuit, err := s.circuitService.Get(ctx, ceremony.CircuitID, userID)
+	if err != nil {
+		return nil, nil, postgres.ErrCeremonyNotFound
+	}
+
+	return ceremony, circuit, nil
+}
+
+// state loads the state after the index-th contribution and checks it
+// against the hash recorded when it was accepted
+func (s *CeremonyService) state(ctx context.Context, ceremony *models.Ceremony, index int) ([]byte, error) {
+	url, hash := ceremony.InitialStateURL, ceremony.InitialHash
+	if index > 0 {
+		contributions, err := s.repo.ListContributions(ctx, ceremony.ID)
+		if err != nil {
+			return nil, err
+		}
+		if index > len(contributions) {
+			return nil, fmt.Errorf("%w: no contribution %d", postgres.ErrCeremonyNotFound, index)
+		}
+		url, hash = contributions[index-1].StateURL, contributions[index-1].Hash
+	}
+
+	state, err := s.artifacts.Get(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ceremony state: %w", err)
+	}
+	if sha256Hex(state) != hash {
+		return nil, fmt.Errorf("ceremony state %d does not match its recorded hash", index)
+	}
+
+	return state, nil
+}
+
+// ceremonySystem returns the circuit's proof system as a SetupCeremony
+func (s *CeremonyService) ceremonySystem(circuit *models.Circuit) (prover.SetupCeremony, error) {
+	system, err := s.factory.Get(circuit.ProofSystem)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported proof system: %w", err)
+	}
+
+	ceremonySystem, ok := system.(prover.SetupCeremony)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s circuits", prover.ErrCeremonyUnsupported, circuit.ProofSystem)
+	}
+	return ceremonySystem, nil
+}
+
+// sha256Hex returns the hex SHA-256 of data. For a Groth16 ceremony state
+// this is the challenge the next contribution commits to.
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func stateKey(ceremonyID uuid.UUID, index int, hash string) string {
+	return fmt.Sprintf("ceremonies/%s/state-%04d-%s.bin", ceremonyID, index, hash[:16])
+}
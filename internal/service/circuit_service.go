@@ -4,9 +4,14 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"strings"
+	"time"
 
 	"github.com/gabrielrondon/zapiki/internal/models"
 	"github.com/gabrielrondon/zapiki/internal/prover"
+	"github.com/gabrielrondon/zapiki/internal/queue"
+	"github.com/gabrielrondon/zapiki/internal/storage/artifact"
 	"github.com/gabrielrondon/zapiki/internal/storage/postgres"
 	"github.com/google/uuid"
 )
@@ -15,16 +20,29 @@ import (
 type CircuitService struct {
 	factory     *prover.Factory
 	circuitRepo *postgres.CircuitRepository
+	artifacts   artifact.Store
+	queueClient interface {
+		EnqueueCircuitSetup(ctx context.Context, payload interface{}) error
+	}
 }
 
 // NewCircuitService creates a new circuit service
-func NewCircuitService(factory *prover.Factory, circuitRepo *postgres.CircuitRepository) *CircuitService {
+func NewCircuitService(factory *prover.Factory, circuitRepo *postgres.CircuitRepository, artifacts artifact.Store) *CircuitService {
 	return &CircuitService{
 		factory:     factory,
 		circuitRepo: circuitRepo,
+		artifacts:   artifacts,
 	}
 }
 
+// SetQueueClient moves setup into background jobs. Without a queue client
+// setup runs inside the create request.
+func (s *CircuitService) SetQueueClient(queueClient interface {
+	EnqueueCircuitSetup(ctx context.Context, payload interface{}) error
+}) {
+	s.queueClient = queueClient
+}
+
 // CreateCircuitRequest represents a request to create a circuit
 type CreateCircuitRequest struct {
 	UserID            uuid.UUID              `json:"user_id"`
@@ -33,6 +51,14 @@ type CreateCircuitRequest struct {
 	ProofSystem       models.ProofSystemType `json:"proof_system"`
 	CircuitDefinition json.RawMessage        `json:"circuit_definition"`
 	IsPublic          bool                   `json:"is_public"`
+
+	// Ceremony leaves the circuit without keys until a setup ceremony for
+	// it is finalized, instead of running a single-party setup
+	Ceremony bool `json:"ceremony,omitempty"`
+
+	// Files holds uploaded circuit files by name, such as a Circom "r1cs",
+	// for proof systems that import circuits
+	Files map[string][]byte `json:"-"`
 }
 
 // CreateCircuitResponse represents the response from circuit creation
@@ -61,39 +87,208 @@ func (s *CircuitService) Create(ctx context.Context, req *CreateCircuitRequest)
 		IsPublic:          req.IsPublic,
 	}
 
+	// Import uploaded circuit files, or reject definitions the proof
+	// system cannot prove
+	if len(req.Files) > 0 {
+		importer, ok := system.(prover.CircuitImporter)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s circuits", prover.ErrImportUnsupported, req.ProofSystem)
+		}
+		if err := importer.ImportCircuit(ctx, circuit, req.Files); err != nil {
+			return nil, err
+		}
+	} else if validator, ok := system.(prover.CircuitValidator); ok {
+		if err := validator.ValidateCircuit(ctx, circuit); err != nil {
+			return nil, err
+		}
+	}
+
 	// Check if setup is required
 	caps := system.Capabilities()
 	setupRequired := caps.SupportsSetup
+	setupInProgress := setupRequired && s.queueClient != nil && !req.Ceremony
 
-	if setupRequired {
-		// Run setup
-		_, err := system.Setup(ctx, circuit)
-		if err != nil {
-			return nil, fmt.Errorf("failed to run setup: %w", err)
+	if req.Ceremony {
+		if _, ok := system.(prover.SetupCeremony); !ok || !setupRequired {
+			return nil, fmt.Errorf("%w: %s circuits", prover.ErrCeremonyUnsupported, req.ProofSystem)
 		}
+	}
 
-		// Store keys (in production, upload to S3)
-		// For now, store serialized keys in database URLs
-		circuit.ProvingKeyURL = fmt.Sprintf("db:%s:pk", circuit.ID)
-		circuit.VerificationKeyURL = fmt.Sprintf("db:%s:vk", circuit.ID)
-
-		// TODO: Upload to S3 in production
-		// circuit.ProvingKeyURL = s.uploadToS3(setupResult.ProvingKey)
-		// circuit.VerificationKeyURL = s.uploadToS3(setupResult.VerificationKey)
+	switch {
+	case req.Ceremony:
+		circuit.SetupStatus = models.CircuitSetupCeremony
+	case !setupRequired:
+		circuit.SetupStatus = models.CircuitSetupReady
+	case setupInProgress:
+		circuit.SetupStatus = models.CircuitSetupPending
+	default:
+		// No queue configured, so run setup inline
+		if err := s.setup(ctx, system, circuit); err != nil {
+			s.deleteImport(ctx, system, circuit)
+			return nil, err
+		}
 	}
 
 	// Save circuit to database
 	if err := s.circuitRepo.Create(ctx, circuit); err != nil {
+		s.deleteKeys(ctx, circuit)
+		s.deleteImport(ctx, system, circuit)
 		return nil, fmt.Errorf("failed to create circuit: %w", err)
 	}
 
+	if setupInProgress {
+		payload := &queue.CircuitSetupPayload{CircuitID: circuit.ID}
+		if err := s.queueClient.EnqueueCircuitSetup(ctx, payload); err != nil {
+			s.markSetupFailed(ctx, circuit, err)
+			return nil, fmt.Errorf("failed to enqueue setup: %w", err)
+		}
+	}
+
 	return &CreateCircuitResponse{
 		Circuit:         circuit,
 		SetupRequired:   setupRequired,
-		SetupInProgress: false,
+		SetupInProgress: setupInProgress,
+	}, nil
+}
+
+// RunSetup runs the setup for a pending circuit and records the outcome.
+// It is called by the worker for circuit setup jobs. A failed setup leaves
+// the circuit pending, with the error recorded, unless lastAttempt is set:
+// the job will be retried until then.
+func (s *CircuitService) RunSetup(ctx context.Context, circuitID uuid.UUID, lastAttempt bool) error {
+	circuit, err := s.circuitRepo.GetByID(ctx, circuitID)
+	if err != nil {
+		return fmt.Errorf("failed to get circuit: %w", err)
+	}
+
+	// A retried job may find the work already done
+	if circuit.SetupStatus == models.CircuitSetupReady {
+		return nil
+	}
+
+	system, err := s.factory.Get(circuit.ProofSystem)
+	if err != nil {
+		err = fmt.Errorf("unsupported proof system: %w", err)
+		s.recordSetupError(ctx, circuit, err, lastAttempt)
+		return err
+	}
+
+	if err := s.setup(ctx, system, circuit); err != nil {
+		s.recordSetupError(ctx, circuit, err, lastAttempt)
+		return err
+	}
+
+	if err := s.circuitRepo.UpdateSetup(ctx, circuit); err != nil {
+		s.deleteKeys(ctx, circuit)
+		return err
+	}
+
+	return nil
+}
+
+// CircuitSetupStatusResponse describes the setup state of a circuit
+type CircuitSetupStatusResponse struct {
+	CircuitID   uuid.UUID                 `json:"circuit_id"`
+	Status      models.CircuitSetupStatus `json:"status"`
+	Error       string                    `json:"error,omitempty"`
+	CompletedAt *time.Time                `json:"completed_at,omitempty"`
+}
+
+// GetSetupStatus returns the setup state of a circuit
+func (s *CircuitService) GetSetupStatus(ctx context.Context, circuitID uuid.UUID, userID uuid.UUID) (*CircuitSetupStatusResponse, error) {
+	circuit, err := s.Get(ctx, circuitID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CircuitSetupStatusResponse{
+		CircuitID:   circuit.ID,
+		Status:      circuit.SetupStatus,
+		Error:       circuit.SetupError,
+		CompletedAt: circuit.SetupCompletedAt,
 	}, nil
 }
 
+// setup runs the proof system setup, stores the keys and marks the circuit ready
+func (s *CircuitService) setup(ctx context.Context, system prover.ProofSystem, circuit *models.Circuit) error {
+	setupResult, err := system.Setup(ctx, circuit)
+	if err != nil {
+		return fmt.Errorf("failed to run setup: %w", err)
+	}
+
+	return s.applySetup(ctx, circuit, setupResult)
+}
+
+// applySetup stores setup keys and marks the circuit ready
+func (s *CircuitService) applySetup(ctx context.Context, circuit *models.Circuit, setupResult *prover.SetupResult) error {
+	// Store keys in the artifact store
+	if err := s.storeKeys(ctx, circuit, setupResult); err != nil {
+		return err
+	}
+
+	// Proofs against the stored keys read the resolved params from the
+	// definition, so they compile the circuit the keys were made for
+	if len(setupResult.CircuitDefinition) > 0 {
+		circuit.CircuitDefinition = setupResult.CircuitDefinition
+	}
+
+	// Record what was compiled, e.g. the resolved params
+	if len(setupResult.Metadata) > 0 {
+		metadata, err := json.Marshal(setupResult.Metadata)
+		if err != nil {
+			return fmt.Errorf("failed to encode setup metadata: %w", err)
+		}
+		circuit.SetupMetadata = metadata
+	}
+
+	now := time.Now()
+	circuit.SetupStatus = models.CircuitSetupReady
+	circuit.SetupError = ""
+	circuit.SetupCompletedAt = &now
+	return nil
+}
+
+// InstallCeremonyKeys records the keys produced by a finalized setup
+// ceremony on the circuit it was run for
+func (s *CircuitService) InstallCeremonyKeys(ctx context.Context, circuit *models.Circuit, setupResult *prover.SetupResult) error {
+	if circuit.SetupStatus != models.CircuitSetupCeremony {
+		return fmt.Errorf("circuit setup is %s, not awaiting a ceremony", circuit.SetupStatus)
+	}
+
+	if err := s.applySetup(ctx, circuit, setupResult); err != nil {
+		return err
+	}
+
+	if err := s.circuitRepo.UpdateSetup(ctx, circuit); err != nil {
+		s.deleteKeys(ctx, circuit)
+		circuit.SetupStatus = models.CircuitSetupCeremony
+		return err
+	}
+
+	return nil
+}
+
+// recordSetupError records a failed setup attempt, marking the circuit
+// failed on the last attempt and leaving it pending otherwise
+func (s *CircuitService) recordSetupError(ctx context.Context, circuit *models.Circuit, setupErr error, lastAttempt bool) {
+	if lastAttempt {
+		s.markSetupFailed(ctx, circuit, setupErr)
+		return
+	}
+	circuit.SetupStatus = models.CircuitSetupPending
+	circuit.SetupError = setupErr.Error()
+	_ = s.circuitRepo.UpdateSetup(ctx, circuit)
+}
+
+// markSetupFailed records a setup failure on the circuit
+func (s *CircuitService) markSetupFailed(ctx context.Context, circuit *models.Circuit, setupErr error) {
+	now := time.Now()
+	circuit.SetupStatus = models.CircuitSetupFailed
+	circuit.SetupError = setupErr.Error()
+	circuit.SetupCompletedAt = &now
+	_ = s.circuitRepo.UpdateSetup(ctx, circuit)
+}
+
 // Get retrieves a circuit by ID
 func (s *CircuitService) Get(ctx context.Context, circuitID uuid.UUID, userID uuid.UUID) (*models.Circuit, error) {
 	circuit, err := s.circuitRepo.GetByID(ctx, circuitID)
@@ -102,13 +297,31 @@ func (s *CircuitService) Get(ctx context.Context, circuitID uuid.UUID, userID uu
 	}
 
 	// Check access: owner or public circuit
-	if circuit.UserID != userID && !circuit.IsPublic {
-		return nil, fmt.Errorf("unauthorized: circuit is private")
+	if err := checkCircuitAccess(circuit, userID); err != nil {
+		return nil, err
 	}
 
 	return circuit, nil
 }
 
+// ExportVerifier writes a Solidity verifier contract for a circuit's verification key
+func (s *CircuitService) ExportVerifier(ctx context.Context, circuit *models.Circuit, w io.Writer) error {
+	if err := checkCircuitReady(circuit); err != nil {
+		return err
+	}
+
+	system, err := s.factory.Get(circuit.ProofSystem)
+	if err != nil {
+		return fmt.Errorf("unsupported proof system: %w", err)
+	}
+	exporter, ok := system.(prover.SolidityExporter)
+	if !ok {
+		return fmt.Errorf("%w: %s circuits", prover.ErrSolidityUnsupported, circuit.ProofSystem)
+	}
+
+	return exporter.ExportSolidity(ctx, circuit, w)
+}
+
 // List lists circuits for a user
 func (s *CircuitService) List(ctx context.Context, userID uuid.UUID, includePublic bool) ([]*models.Circuit, error) {
 	if includePublic {
@@ -129,5 +342,58 @@ func (s *CircuitService) Delete(ctx context.Context, circuitID uuid.UUID, userID
 		return fmt.Errorf("unauthorized: circuit belongs to different user")
 	}
 
-	return s.circuitRepo.Delete(ctx, circuitID)
+	if err := s.circuitRepo.Delete(ctx, circuitID); err != nil {
+		return err
+	}
+
+	s.deleteKeys(ctx, circuit)
+	if system, err := s.factory.Get(circuit.ProofSystem); err == nil {
+		s.deleteImport(ctx, system, circuit)
+	}
+	return nil
+}
+
+// storeKeys uploads setup keys and records their URLs on the circuit
+func (s *CircuitService) storeKeys(ctx context.Context, circuit *models.Circuit, setupResult *prover.SetupResult) error {
+	if s.artifacts == nil {
+		return fmt.Errorf("no artifact store configured for circuit keys")
+	}
+
+	pkURL, err := s.artifacts.Put(ctx, fmt.Sprintf("circuits/%s/proving_key.json", circuit.ID), setupResult.ProvingKey)
+	if err != nil {
+		return fmt.Errorf("failed to store proving key: %w", err)
+	}
+
+	vkURL, err := s.artifacts.Put(ctx, fmt.Sprintf("circuits/%s/verification_key.json", circuit.ID), setupResult.VerificationKey)
+	if err != nil {
+		_ = s.artifacts.Delete(ctx, pkURL)
+		return fmt.Errorf("failed to store verification key: %w", err)
+	}
+
+	circuit.ProvingKeyURL = pkURL
+	circuit.VerificationKeyURL = vkURL
+	return nil
+}
+
+// deleteKeys removes a circuit's stored keys. Failures only leave orphaned
+// artifacts behind, so they are ignored.
+func (s *CircuitService) deleteKeys(ctx context.Context, circuit *models.Circuit) {
+	if s.artifacts == nil {
+		return
+	}
+
+	for _, url := range []string{circuit.ProvingKeyURL, circuit.VerificationKeyURL} {
+		if url == "" || strings.HasPrefix(url, "db:") {
+			continue
+		}
+		_ = s.artifacts.Delete(ctx, url)
+	}
+}
+
+// deleteImport removes the files stored for an imported circuit. Like
+// deleteKeys, failures only leave orphaned artifacts behind.
+func (s *CircuitService) deleteImport(ctx context.Context, system prover.ProofSystem, circuit *models.Circuit) {
+	if importer, ok := system.(prover.CircuitImporter); ok {
+		_ = importer.DeleteImport(ctx, circuit)
+	}
 }
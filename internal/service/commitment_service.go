@@ -0,0 +1,209 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/gabrielrondon/zapiki/internal/models"
+	"github.com/gabrielrondon/zapiki/internal/prover"
+)
+
+// ErrOpeningUnsupported is returned when the commitment proof system cannot
+// open its proofs
+var ErrOpeningUnsupported = errors.New("commitment openings are not supported")
+
+// Limits on batch commitments
+const (
+	// MaxBatchCommitmentItems bounds the items in one batch commitment
+	MaxBatchCommitmentItems = 1_000_000
+	// maxBatchItemSize bounds one NDJSON line
+	maxBatchItemSize = 1 << 20
+)
+
+// CommitmentService opens commitment proofs, proves that two commitments
+// hide equal data, commits to batches of items and aggregates Pedersen
+// commitments
+type CommitmentService struct {
+	factory *prover.Factory
+}
+
+// NewCommitmentService creates a new commitment service
+func NewCommitmentService(factory *prover.Factory) *CommitmentService {
+	return &CommitmentService{
+		factory: factory,
+	}
+}
+
+// EqualityRequest asks for a proof that two commitments hide the same data
+type EqualityRequest struct {
+	Openings []*prover.Opening `json:"openings"`
+}
+
+// AggregateRequest asks to sum Pedersen commitments
+type AggregateRequest struct {
+	Proofs []json.RawMessage `json:"proofs"`
+}
+
+// EqualityVerifyRequest asks to verify an equality proof
+type EqualityVerifyRequest struct {
+	Proof           json.RawMessage `json:"proof"`
+	VerificationKey json.RawMessage `json:"verification_key,omitempty"`
+}
+
+// Open checks that an opening's data and nonce are what its proof commits to
+func (s *CommitmentService) Open(ctx context.Context, opening *prover.Opening) (*VerifyResponse, error) {
+	opener, err := s.opener()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := opener.VerifyOpening(ctx, opening)
+	if err != nil {
+		return nil, fmt.Errorf("opening verification failed: %w", err)
+	}
+
+	return &VerifyResponse{
+		Valid:        resp.Valid,
+		ErrorMessage: resp.ErrorMessage,
+		VerifiedAt:   time.Now(),
+	}, nil
+}
+
+// ProveEquality proves that both openings reveal the same data; the proof
+// names the commitments but not the data
+func (s *CommitmentService) ProveEquality(ctx context.Context, req *EqualityRequest) (*prover.ProofResponse, error) {
+	if len(req.Openings) != 2 || req.Openings[0] == nil || req.Openings[1] == nil {
+		return nil, fmt.Errorf("%w: exactly two openings are required", prover.ErrInvalidOpening)
+	}
+
+	opener, err := s.opener()
+	if err != nil {
+		return nil, err
+	}
+	return opener.ProveEquality(ctx, req.Openings[0], req.Openings[1])
+}
+
+// VerifyEquality verifies an equality proof
+func (s *CommitmentService) VerifyEquality(ctx context.Context, req *EqualityVerifyRequest) (*VerifyResponse, error) {
+	opener, err := s.opener()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := opener.VerifyEquality(ctx, &prover.VerifyRequest{
+		Proof:           req.Proof,
+		VerificationKey: req.VerificationKey,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("verification failed: %w", err)
+	}
+
+	return &VerifyResponse{
+		Valid:        resp.Valid,
+		ErrorMessage: resp.ErrorMessage,
+		VerifiedAt:   time.Now(),
+	}, nil
+}
+
+// CommitBatch commits to the items of an NDJSON stream, one InputData
+// object per line, under one signed Merkle root
+func (s *CommitmentService) CommitBatch(ctx context.Context, r io.Reader) (prover.BatchCommitment, error) {
+	committer, err := s.committer()
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxBatchItemSize)
+	line, items := 0, 0
+	next := func() (*models.InputData, error) {
+		for scanner.Scan() {
+			line++
+			text := bytes.TrimSpace(scanner.Bytes())
+			if len(text) == 0 {
+				continue
+			}
+			if items == MaxBatchCommitmentItems {
+				return nil, fmt.Errorf("%w: a batch holds at most %d items", prover.ErrInvalidBatchItem, MaxBatchCommitmentItems)
+			}
+			var data models.InputData
+			if err := json.Unmarshal(text, &data); err != nil {
+				return nil, fmt.Errorf("%w: line %d: %v", prover.ErrInvalidBatchItem, line, err)
+			}
+			items++
+			return &data, nil
+		}
+		if err := scanner.Err(); err != nil {
+			if errors.Is(err, bufio.ErrTooLong) {
+				return nil, fmt.Errorf("%w: line %d is longer than %d bytes", prover.ErrInvalidBatchItem, line+1, maxBatchItemSize)
+			}
+			return nil, fmt.Errorf("failed to read batch: %w", err)
+		}
+		return nil, io.EOF
+	}
+
+	return committer.CommitBatch(ctx, next)
+}
+
+// VerifyInclusion verifies one item of a batch commitment
+func (s *CommitmentService) VerifyInclusion(ctx context.Context, req *prover.InclusionRequest) (*VerifyResponse, error) {
+	committer, err := s.committer()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := committer.VerifyInclusion(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("verification failed: %w", err)
+	}
+
+	return &VerifyResponse{
+		Valid:        resp.Valid,
+		ErrorMessage: resp.ErrorMessage,
+		VerifiedAt:   time.Now(),
+	}, nil
+}
+
+// Aggregate sums Pedersen commitment proofs into a commitment to the sum of
+// their values, which the total and summed blinding open
+func (s *CommitmentService) Aggregate(ctx context.Context, req *AggregateRequest) (*prover.ProofResponse, error) {
+	system, err := s.factory.Get(models.ProofSystemPedersen)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOpeningUnsupported, err)
+	}
+	aggregator, ok := system.(prover.CommitmentAggregator)
+	if !ok {
+		return nil, ErrOpeningUnsupported
+	}
+	return aggregator.Aggregate(ctx, req.Proofs)
+}
+
+func (s *CommitmentService) opener() (prover.CommitmentOpener, error) {
+	system, err := s.factory.Get(models.ProofSystemCommitment)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOpeningUnsupported, err)
+	}
+	opener, ok := system.(prover.CommitmentOpener)
+	if !ok {
+		return nil, ErrOpeningUnsupported
+	}
+	return opener, nil
+}
+
+func (s *CommitmentService) committer() (prover.BatchCommitter, error) {
+	system, err := s.factory.Get(models.ProofSystemCommitment)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOpeningUnsupported, err)
+	}
+	committer, ok := system.(prover.BatchCommitter)
+	if !ok {
+		return nil, ErrOpeningUnsupported
+	}
+	return committer, nil
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gabrielrondon/zapiki/internal/models"
+	"github.com/gabrielrondon/zapiki/internal/prover"
+)
+
+// ErrInvalidEnvelope is returned for proof envelopes that cannot be verified
+var ErrInvalidEnvelope = errors.New("invalid proof envelope")
+
+// workerID identifies this process in proof envelopes, as host:pid
+var workerID = sync.OnceValue(func() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s:%d", host, os.Getpid())
+})
+
+// NewProofEnvelope describes a completed proof generated by system from
+// the proof record, its circuit (nil for proofs without one) and the
+// prover's response. startedAt is when generation started.
+func NewProofEnvelope(system prover.ProofSystem, proof *models.Proof, circuit *models.Circuit, resp *prover.ProofResponse, startedAt time.Time) (json.RawMessage, error) {
+	library := system.Capabilities().Library
+	envelope := &models.ProofEnvelope{
+		Version:             models.ProofEnvelopeVersion,
+		ProofSystem:         system.Name(),
+		CircuitID:           proof.CircuitID,
+		VerificationKeyHash: jsonSHA256(resp.VerificationKey),
+		Proof:               proof.ProofData,
+		PublicInputs:        proof.PublicInputs,
+		NamedPublicInputs:   resp.NamedPublicInputs,
+		Prover:              models.ProverInfo{Library: library, Version: libraryVersion(library)},
+		WorkerID:            workerID(),
+		Timings: models.ProofTimings{
+			CreatedAt:        proof.CreatedAt,
+			StartedAt:        startedAt,
+			QueueTimeMs:      startedAt.Sub(proof.CreatedAt).Milliseconds(),
+			GenerationTimeMs: proof.GenerationTimeMs,
+		},
+	}
+	envelope.Curve, _ = resp.Metadata["curve"].(string)
+	envelope.CircuitType, _ = resp.Metadata["circuit_type"].(string)
+	envelope.KeyID, _ = resp.Metadata["key_id"].(string)
+	envelope.HashAlgorithm, _ = resp.Metadata["hash_algo"].(string)
+	envelope.SignatureAlgorithm, _ = resp.Metadata["sig_algo"].(string)
+	if circuit != nil {
+		envelope.CircuitHash = jsonSHA256(circuit.CircuitDefinition)
+	}
+	if proof.CompletedAt != nil {
+		envelope.Timings.CompletedAt = *proof.CompletedAt
+	}
+
+	return json.Marshal(envelope)
+}
+
+// ApplyEnvelope fills the request's proof system, proof, public inputs,
+// curve and circuit from its envelope, if any. Fields given alongside the
+// envelope must agree with it, and a verification key must match the
+// envelope's hash. The envelope is cleared once applied.
+func (r *VerifyRequest) ApplyEnvelope() error {
+	e := r.Envelope
+	if e == nil {
+		return nil
+	}
+
+	switch {
+	case e.Version != models.ProofEnvelopeVersion:
+		return fmt.Errorf("%w: envelope_version %d is not supported (expected %d)", ErrInvalidEnvelope, e.Version, models.ProofEnvelopeVersion)
+	case e.ProofSystem == "":
+		return fmt.Errorf("%w: proof_system is required", ErrInvalidEnvelope)
+	case len(e.Proof) == 0:
+		return fmt.Errorf("%w: proof is required", ErrInvalidEnvelope)
+	case r.ProofSystem != "" && r.ProofSystem != e.ProofSystem:
+		return fmt.Errorf("%w: proof_system %s does not match the envelope's %s", ErrInvalidEnvelope, r.ProofSystem, e.ProofSystem)
+	case r.Curve != "" && e.Curve != "" && r.Curve != e.Curve:
+		return fmt.Errorf("%w: curve %s does not match the envelope's %s", ErrInvalidEnvelope, r.Curve, e.Curve)
+	case hasJSON(r.Proof) || hasJSON(r.PublicInputs):
+		return fmt.Errorf("%w: proof and public_inputs are taken from the envelope", ErrInvalidEnvelope)
+	case r.Format != "" && r.Format != prover.FormatGnark:
+		return fmt.Errorf("%w: enveloped proofs are in gnark format", ErrInvalidEnvelope)
+	}
+	if len(r.VerificationKey) > 0 && e.VerificationKeyHash != "" && jsonSHA256(r.VerificationKey) != e.VerificationKeyHash {
+		return fmt.Errorf("%w: verification_key does not match the envelope's verification_key_sha256", ErrInvalidEnvelope)
+	}
+
+	r.ProofSystem = e.ProofSystem
+	r.Proof = e.Proof
+	r.PublicInputs = e.PublicInputs
+	if r.Curve == "" {
+		r.Curve = e.Curve
+	}
+	if len(r.VerificationKey) == 0 && r.CircuitID == nil {
+		r.CircuitID = e.CircuitID
+	}
+	r.Envelope = nil
+	return nil
+}
+
+// hasJSON reports whether a JSON value was given and is not null
+func hasJSON(raw json.RawMessage) bool {
+	trimmed := bytes.TrimSpace(raw)
+	return len(trimmed) > 0 && !bytes.Equal(trimmed, []byte("null"))
+}
+
+// jsonSHA256 returns the hex SHA-256 of a JSON value in compact form, so
+// that its hash does not depend on formatting. It returns "" for no value.
+func jsonSHA256(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var buf bytes.Buffer
+	data := []byte(raw)
+	if err := json.Compact(&buf, raw); err == nil {
+		data = buf.Bytes()
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// libraryVersion returns the version of the module providing a package,
+// as built into this binary: the Go version for the standard library, and
+// "unknown" when the build records no version
+func libraryVersion(pkg string) string {
+	if pkg == "" {
+		return "unknown"
+	}
+	if first, _, _ := strings.Cut(pkg, "/"); !strings.Contains(first, ".") {
+		return runtime.Version()
+	}
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	// The module providing pkg is the one with the longest matching path
+	var best *debug.Module
+	for _, m := range append([]*debug.Module{&info.Main}, info.Deps...) {
+		if (pkg == m.Path || strings.HasPrefix(pkg, m.Path+"/")) && (best == nil || len(m.Path) > len(best.Path)) {
+			best = m
+		}
+	}
+	switch {
+	case best == nil:
+		return "unknown"
+	case best.Replace != nil && best.Replace.Version != "":
+		return best.Replace.Version
+	case best.Version != "":
+		return best.Version
+	default:
+		return "unknown"
+	}
+}
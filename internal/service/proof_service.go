@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -18,6 +19,7 @@ type ProofService struct {
 	factory    *prover.Factory
 	proofRepo  *postgres.ProofRepository
 	jobRepo    *postgres.JobRepository
+	circuitRepo *postgres.CircuitRepository
 	queueClient interface {
 		EnqueueProofGeneration(ctx context.Context, payload interface{}, priority int) error
 	}
@@ -35,6 +37,62 @@ func NewProofService(factory *prover.Factory, proofRepo *postgres.ProofRepositor
 	}
 }
 
+// ErrCircuitNotReady is returned for proofs against a circuit whose setup
+// is still pending or has failed
+var ErrCircuitNotReady = errors.New("circuit is not ready")
+
+// checkCircuitReady rejects circuits whose setup has not completed
+func checkCircuitReady(circuit *models.Circuit) error {
+	switch circuit.SetupStatus {
+	case models.CircuitSetupReady, "":
+		return nil
+	case models.CircuitSetupFailed:
+		return fmt.Errorf("%w: setup failed: %s", ErrCircuitNotReady, circuit.SetupError)
+	case models.CircuitSetupCeremony:
+		return fmt.Errorf("%w: keys come from a setup ceremony that has not been finalized", ErrCircuitNotReady)
+	default:
+		return fmt.Errorf("%w: setup is %s, poll /api/v1/circuits/%s/setup", ErrCircuitNotReady, circuit.SetupStatus, circuit.ID)
+	}
+}
+
+// ErrCircuitAccessDenied is returned for a private circuit of another user
+var ErrCircuitAccessDenied = errors.New("unauthorized: circuit is private")
+
+// ErrCircuitProofSystem is returned for a circuit used with a proof system
+// other than its own
+var ErrCircuitProofSystem = errors.New("circuit belongs to another proof system")
+
+// checkCircuitAccess lets userID use circuits they own and public circuits
+func checkCircuitAccess(circuit *models.Circuit, userID uuid.UUID) error {
+	if circuit.UserID != userID && !circuit.IsPublic {
+		return ErrCircuitAccessDenied
+	}
+	return nil
+}
+
+// CheckProofCircuit checks that userID may prove or verify against circuit
+// and that system is the circuit's proof system, so a circuit's stored
+// keys only reach its owner's (or, when public, anyone's) proofs of its kind
+func CheckProofCircuit(circuit *models.Circuit, userID uuid.UUID, system models.ProofSystemType) error {
+	if err := checkCircuitAccess(circuit, userID); err != nil {
+		return err
+	}
+	if circuit.ProofSystem != system {
+		return fmt.Errorf("%w: circuit %s is a %s circuit, not %s", ErrCircuitProofSystem, circuit.ID, circuit.ProofSystem, system)
+	}
+	return nil
+}
+
+// ErrProofNotCompleted is returned when a proof's result is needed before
+// generation has finished
+var ErrProofNotCompleted = errors.New("proof is not completed")
+
+// SetCircuitRepository enables loading circuits (and their stored keys)
+// for proofs that reference a circuit_id
+func (s *ProofService) SetCircuitRepository(circuitRepo *postgres.CircuitRepository) {
+	s.circuitRepo = circuitRepo
+}
+
 // GenerateProofRequest represents a request to generate a proof
 type GenerateProofRequest struct {
 	UserID       uuid.UUID              `json:"user_id"`
@@ -52,6 +110,8 @@ type GenerateProofResponse struct {
 	VerificationKey  json.RawMessage      `json:"verification_key,omitempty"`
 	GenerationTimeMs int64                `json:"generation_time_ms,omitempty"`
 	Message          string               `json:"message,omitempty"`
+	// Envelope describes the completed proof (see models.ProofEnvelope)
+	Envelope json.RawMessage `json:"envelope,omitempty"`
 }
 
 // Generate generates a proof
@@ -62,6 +122,21 @@ func (s *ProofService) Generate(ctx context.Context, req *GenerateProofRequest)
 		return nil, fmt.Errorf("unsupported proof system: %w", err)
 	}
 
+	// Proofs against a circuit need its setup to have finished
+	var circuit *models.Circuit
+	if req.Options != nil && req.Options.CircuitID != nil && s.circuitRepo != nil {
+		circuit, err = s.circuitRepo.GetByID(ctx, *req.Options.CircuitID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get circuit: %w", err)
+		}
+		if err := CheckProofCircuit(circuit, req.UserID, req.ProofSystem); err != nil {
+			return nil, err
+		}
+		if err := checkCircuitReady(circuit); err != nil {
+			return nil, err
+		}
+	}
+
 	// Create proof record
 	proofID := uuid.New()
 	proof := &models.Proof{
@@ -77,6 +152,7 @@ func (s *ProofService) Generate(ctx context.Context, req *GenerateProofRequest)
 	if req.Options != nil {
 		proof.CircuitID = req.Options.CircuitID
 		proof.TemplateID = req.Options.TemplateID
+		proof.SanctionsListVersionID = req.Options.SanctionsListVersionID
 	}
 
 	// Check if this should be async
@@ -123,6 +199,9 @@ func (s *ProofService) Generate(ctx context.Context, req *GenerateProofRequest)
 				queuePayload.Options = map[string]interface{}{
 					"async": req.Options.Async,
 				}
+				if req.Options.Curve != "" {
+					queuePayload.Options["curve"] = req.Options.Curve
+				}
 			}
 
 			if err := s.queueClient.EnqueueProofGeneration(ctx, queuePayload, job.Priority); err != nil {
@@ -152,6 +231,7 @@ func (s *ProofService) Generate(ctx context.Context, req *GenerateProofRequest)
 	}
 
 	// Pass circuit/template information to prover
+	proverReq.Circuit = circuit
 	if req.Options != nil {
 		if req.Options.CircuitID != nil {
 			proverReq.Options["circuit_id"] = req.Options.CircuitID
@@ -160,6 +240,9 @@ func (s *ProofService) Generate(ctx context.Context, req *GenerateProofRequest)
 			proverReq.Options["template_id"] = req.Options.TemplateID
 		}
 		proverReq.Options["async"] = req.Options.Async
+		if req.Options.Curve != "" {
+			proverReq.Options["curve"] = req.Options.Curve
+		}
 	}
 
 	proverResp, err := system.Generate(ctx, proverReq)
@@ -182,6 +265,15 @@ func (s *ProofService) Generate(ctx context.Context, req *GenerateProofRequest)
 	now := time.Now()
 	proof.CompletedAt = &now
 
+	proof.Envelope, err = NewProofEnvelope(system, proof, circuit, proverResp, startTime)
+	if err != nil {
+		proof.Status = models.ProofStatusFailed
+		proof.ErrorMessage = err.Error()
+		_ = s.proofRepo.Update(ctx, proof)
+
+		return nil, fmt.Errorf("failed to build proof envelope: %w", err)
+	}
+
 	if err := s.proofRepo.Update(ctx, proof); err != nil {
 		return nil, fmt.Errorf("failed to update proof record: %w", err)
 	}
@@ -192,6 +284,7 @@ func (s *ProofService) Generate(ctx context.Context, req *GenerateProofRequest)
 		Proof:            proverResp.Proof,
 		VerificationKey:  proverResp.VerificationKey,
 		GenerationTimeMs: proof.GenerationTimeMs,
+		Envelope:         proof.Envelope,
 	}, nil
 }
 
@@ -229,3 +322,84 @@ func (s *ProofService) DeleteProof(ctx context.Context, proofID uuid.UUID, userI
 
 	return s.proofRepo.Delete(ctx, proofID)
 }
+
+// Calldata ABI-encodes a stored proof and its public inputs as a call to the
+// Solidity verifier exported for its circuit
+func (s *ProofService) Calldata(ctx context.Context, proof *models.Proof) (*prover.SolidityCalldata, error) {
+	if proof.Status != models.ProofStatusCompleted {
+		return nil, fmt.Errorf("%w: status is %s", ErrProofNotCompleted, proof.Status)
+	}
+
+	system, err := s.factory.Get(proof.ProofSystem)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported proof system: %w", err)
+	}
+	exporter, ok := system.(prover.SolidityExporter)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s proofs", prover.ErrSolidityUnsupported, proof.ProofSystem)
+	}
+
+	// The circuit, or the curve recorded with the proof, tells the prover
+	// which curve the proof is on
+	req := &prover.VerifyRequest{
+		Proof:        proof.ProofData,
+		PublicInputs: proof.PublicInputs,
+		Curve:        proofCurve(proof),
+	}
+	if proof.CircuitID != nil && s.circuitRepo != nil {
+		req.Circuit, err = s.circuitRepo.GetByID(ctx, *proof.CircuitID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get circuit: %w", err)
+		}
+	}
+
+	return exporter.SolidityCalldata(ctx, req)
+}
+
+// proofCurve returns the curve a stored proof was generated on, as recorded
+// in its envelope or, for aggregated proofs, its aggregation. It returns ""
+// when neither records one.
+func proofCurve(proof *models.Proof) string {
+	for _, recorded := range []json.RawMessage{proof.Envelope, proof.Aggregation} {
+		var fields struct {
+			Curve string `json:"curve"`
+		}
+		if len(recorded) > 0 && json.Unmarshal(recorded, &fields) == nil && fields.Curve != "" {
+			return fields.Curve
+		}
+	}
+	return ""
+}
+
+// Export converts a stored proof, its public inputs and its circuit's
+// verifying key to another tool's format
+func (s *ProofService) Export(ctx context.Context, proof *models.Proof, format string) (*prover.ExportedProof, error) {
+	if proof.Status != models.ProofStatusCompleted {
+		return nil, fmt.Errorf("%w: status is %s", ErrProofNotCompleted, proof.Status)
+	}
+
+	system, err := s.factory.Get(proof.ProofSystem)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported proof system: %w", err)
+	}
+	converter, ok := system.(prover.ProofConverter)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s proofs", prover.ErrFormatUnsupported, proof.ProofSystem)
+	}
+
+	// The circuit gives the curve and the verifying key to export
+	req := &prover.VerifyRequest{
+		Proof:        proof.ProofData,
+		PublicInputs: proof.PublicInputs,
+		Curve:        proofCurve(proof),
+	}
+	if proof.CircuitID != nil && s.circuitRepo != nil {
+		req.Circuit, err = s.circuitRepo.GetByID(ctx, *proof.CircuitID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get circuit: %w", err)
+		}
+		req.VerificationKeyURL = req.Circuit.VerificationKeyURL
+	}
+
+	return converter.ExportProof(ctx, req, format)
+}
@@ -0,0 +1,329 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/gabrielrondon/zapiki/internal/models"
+	"github.com/gabrielrondon/zapiki/internal/prover/snark/gnark/circuits"
+	"github.com/gabrielrondon/zapiki/internal/sanctions"
+	"github.com/gabrielrondon/zapiki/internal/storage/artifact"
+	"github.com/gabrielrondon/zapiki/internal/storage/postgres"
+	"github.com/google/uuid"
+)
+
+// sanctionsCurve is the scalar field sanctions trees are hashed over,
+// matching the Groth16 prover that runs aml_sanctions_check
+const sanctionsCurve = ecc.BN254
+
+// ErrIdentifierListed is returned when a witness is requested for an
+// identifier that is on the list
+var ErrIdentifierListed = errors.New("identifier is on the sanctions list")
+
+// ErrInvalidSanctionsRoot is returned for a malformed root or one that does
+// not match the requested list version
+var ErrInvalidSanctionsRoot = errors.New("invalid sanctions_list_root")
+
+// ErrInvalidIdentifier is returned for a malformed hashed identifier or salt
+var ErrInvalidIdentifier = errors.New("invalid sanctions identifier")
+
+var listNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,99}$`)
+
+// SanctionsService ingests sanctions lists and serves non-membership
+// witnesses against their versioned Merkle roots
+type SanctionsService struct {
+	repo      *postgres.SanctionsRepository
+	artifacts artifact.Store
+
+	// Rebuilt trees by list version ID
+	mu    sync.Mutex
+	trees map[uuid.UUID]*circuits.SortedMerkleTree
+}
+
+// NewSanctionsService creates a new sanctions service
+func NewSanctionsService(repo *postgres.SanctionsRepository, artifacts artifact.Store) *SanctionsService {
+	return &SanctionsService{
+		repo:      repo,
+		artifacts: artifacts,
+		trees:     make(map[uuid.UUID]*circuits.SortedMerkleTree),
+	}
+}
+
+// SanctionsSource is one list file to ingest
+type SanctionsSource struct {
+	Format sanctions.Format
+	Name   string // File name, recorded with the version
+	Reader io.Reader
+}
+
+// IngestSanctionsRequest represents a request to ingest a list version
+type IngestSanctionsRequest struct {
+	ListName      string
+	Sources       []SanctionsSource
+	EffectiveDate time.Time
+	TreeDepth     int               // Defaults to circuits.DefaultTreeDepth
+	Hash          circuits.HashFunc // Defaults to circuits.DefaultHash
+}
+
+// Ingest parses the sources, builds the sorted Merkle tree of their
+// hashed names and stores it as the list's next version
+func (s *SanctionsService) Ingest(ctx context.Context, req *IngestSanctionsRequest) (*models.SanctionsListVersion, error) {
+	if !listNamePattern.MatchString(req.ListName) {
+		return nil, fmt.Errorf("invalid list name %q: use lowercase letters, digits, '-' and '_'", req.ListName)
+	}
+	if len(req.Sources) == 0 {
+		return nil, fmt.Errorf("at least one source file is required")
+	}
+	if req.EffectiveDate.IsZero() {
+		return nil, fmt.Errorf("effective date is required")
+	}
+
+	depth := req.TreeDepth
+	if depth == 0 {
+		depth = circuits.DefaultTreeDepth
+	}
+	fn, err := circuits.ParseHashFunc(string(req.Hash))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []sanctions.Entry
+	var sourceNames []string
+	for _, source := range req.Sources {
+		parsed, err := sanctions.Parse(source.Format, source.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", source.Name, err)
+		}
+		entries = append(entries, parsed...)
+		sourceNames = append(sourceNames, fmt.Sprintf("%s:%s", source.Format, source.Name))
+	}
+
+	identifiers := sanctions.Identifiers(entries)
+	tree, err := circuits.NewSortedMerkleTree(sanctionsCurve, fn, depth, identifiers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build sanctions tree: %w", err)
+	}
+
+	// Persist the identifiers so the tree can be rebuilt for witnesses
+	data, err := json.Marshal(decimalStrings(identifiers))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode identifiers: %w", err)
+	}
+
+	version := &models.SanctionsListVersion{
+		ID:              uuid.New(),
+		ListName:        req.ListName,
+		Root:            tree.Root().String(),
+		Curve:           sanctionsCurve.String(),
+		HashFunc:        string(fn),
+		TreeDepth:       depth,
+		EntryCount:      len(entries),
+		IdentifierCount: tree.Len(),
+		Sources:         sourceNames,
+		EffectiveDate:   req.EffectiveDate.UTC().Truncate(24 * time.Hour),
+	}
+
+	version.IdentifiersURL, err = s.artifacts.Put(ctx, fmt.Sprintf("sanctions/%s/%s/identifiers.json", version.ListName, version.ID), data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store identifiers: %w", err)
+	}
+
+	if err := s.repo.Create(ctx, version); err != nil {
+		_ = s.artifacts.Delete(ctx, version.IdentifiersURL)
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.trees[version.ID] = tree
+	s.mu.Unlock()
+
+	return version, nil
+}
+
+// ListVersions lists ingested versions, optionally of one list
+func (s *SanctionsService) ListVersions(ctx context.Context, listName string) ([]*models.SanctionsListVersion, error) {
+	return s.repo.List(ctx, listName)
+}
+
+// GetVersion retrieves a list version by ID
+func (s *SanctionsService) GetVersion(ctx context.Context, id uuid.UUID) (*models.SanctionsListVersion, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+// GetEffectiveVersion retrieves the version of a list in effect now
+func (s *SanctionsService) GetEffectiveVersion(ctx context.Context, listName string) (*models.SanctionsListVersion, error) {
+	return s.repo.GetEffective(ctx, listName, time.Now())
+}
+
+// ResolveVersion finds the version a sanctions check refers to, by ID or
+// else by root
+func (s *SanctionsService) ResolveVersion(ctx context.Context, id *uuid.UUID, root string) (*models.SanctionsListVersion, error) {
+	if id != nil {
+		version, err := s.repo.GetByID(ctx, *id)
+		if err != nil {
+			return nil, err
+		}
+		if root != "" && !sameFieldElement(root, version.Root) {
+			return nil, fmt.Errorf("%w: it does not match list version %s", ErrInvalidSanctionsRoot, version.ID)
+		}
+		return version, nil
+	}
+
+	value, ok := new(big.Int).SetString(root, 0)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSanctionsRoot, root)
+	}
+	return s.repo.GetByRoot(ctx, value.String())
+}
+
+// SanctionsWitness is the non-membership witness of an identifier
+type SanctionsWitness struct {
+	ListVersion *models.SanctionsListVersion `json:"list_version"`
+	Witness     map[string]interface{}       `json:"witness"`
+}
+
+// Witness returns the neighbouring leaves and Merkle paths proving that
+// identifier (a decimal or 0x-prefixed hashed identifier) is not in the
+// list version, with the identifier's commitment under salt (see
+// CommitSanctionsIdentifier)
+func (s *SanctionsService) Witness(ctx context.Context, version *models.SanctionsListVersion, identifier, salt string) (*SanctionsWitness, error) {
+	value, ok := new(big.Int).SetString(identifier, 0)
+	if !ok {
+		return nil, fmt.Errorf("%w: user_identifier %s", ErrInvalidIdentifier, identifier)
+	}
+
+	commitment, salt, err := CommitSanctionsIdentifier(identifier, salt, version.HashFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := s.tree(ctx, version)
+	if err != nil {
+		return nil, err
+	}
+
+	if tree.Contains(value) {
+		return nil, ErrIdentifierListed
+	}
+
+	proof, err := tree.ProveNonMembership(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build witness: %w", err)
+	}
+
+	witness := proof.Inputs()
+	witness["hash"] = version.HashFunc
+	witness["identifier_salt"] = salt
+	witness["identifier_commitment"] = commitment
+
+	return &SanctionsWitness{
+		ListVersion: version,
+		Witness:     witness,
+	}, nil
+}
+
+// CommitSanctionsIdentifier returns the public commitment H(identifier,
+// salt) that binds a sanctions proof to a hashed identifier, and the salt
+// used: the given one, or a random one if empty. Relying parties that
+// learn the identifier and salt can check a proof is about that user.
+func CommitSanctionsIdentifier(identifier, salt, hash string) (string, string, error) {
+	value, ok := new(big.Int).SetString(identifier, 0)
+	if !ok {
+		return "", "", fmt.Errorf("%w: user_identifier %s", ErrInvalidIdentifier, identifier)
+	}
+
+	var saltValue *big.Int
+	if salt == "" {
+		var err error
+		if saltValue, err = circuits.NewIdentifierSalt(); err != nil {
+			return "", "", err
+		}
+	} else if saltValue, ok = new(big.Int).SetString(salt, 0); !ok {
+		return "", "", fmt.Errorf("%w: identifier_salt %s", ErrInvalidIdentifier, salt)
+	}
+
+	fn, err := circuits.ParseHashFunc(hash)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %v", ErrInvalidIdentifier, err)
+	}
+	commitment, err := circuits.CommitIdentifier(sanctionsCurve, fn, value, saltValue)
+	if err != nil {
+		// The identifier or salt is not a field element
+		return "", "", fmt.Errorf("%w: %v", ErrInvalidIdentifier, err)
+	}
+
+	return commitment.String(), saltValue.String(), nil
+}
+
+// tree returns the version's Merkle tree, rebuilding it from the stored
+// identifiers on first use
+func (s *SanctionsService) tree(ctx context.Context, version *models.SanctionsListVersion) (*circuits.SortedMerkleTree, error) {
+	s.mu.Lock()
+	tree, ok := s.trees[version.ID]
+	s.mu.Unlock()
+	if ok {
+		return tree, nil
+	}
+
+	data, err := s.artifacts.Get(ctx, version.IdentifiersURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load identifiers: %w", err)
+	}
+
+	var encoded []string
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return nil, fmt.Errorf("failed to decode identifiers: %w", err)
+	}
+
+	identifiers := make([]*big.Int, len(encoded))
+	for i, e := range encoded {
+		value, ok := new(big.Int).SetString(e, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid stored identifier: %s", e)
+		}
+		identifiers[i] = value
+	}
+
+	curve, err := ecc.IDFromString(version.Curve)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err = circuits.NewSortedMerkleTree(curve, circuits.HashFunc(version.HashFunc), version.TreeDepth, identifiers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rebuild sanctions tree: %w", err)
+	}
+
+	// Guard against identifiers that changed after ingestion
+	if tree.Root().String() != version.Root {
+		return nil, fmt.Errorf("stored identifiers do not match the root of list version %s", version.ID)
+	}
+
+	s.mu.Lock()
+	s.trees[version.ID] = tree
+	s.mu.Unlock()
+
+	return tree, nil
+}
+
+func sameFieldElement(a, b string) bool {
+	x, okX := new(big.Int).SetString(a, 0)
+	y, okY := new(big.Int).SetString(b, 0)
+	return okX && okY && x.Cmp(y) == 0
+}
+
+func decimalStrings(values []*big.Int) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = v.String()
+	}
+	return out
+}
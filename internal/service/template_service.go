@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"github.com/gabrielrondon/zapiki/internal/models"
+	"github.com/gabrielrondon/zapiki/internal/prover"
 	"github.com/gabrielrondon/zapiki/internal/storage/postgres"
 	"github.com/google/uuid"
 )
@@ -54,7 +55,7 @@ func (s *TemplateService) GenerateFromTemplate(
 	}
 
 	// Validate inputs against schema
-	if err := s.validateInputs(template, req.Inputs); err != nil {
+	if err := validateInputs(template.InputSchema, req.Inputs); err != nil {
 		return nil, fmt.Errorf("invalid inputs: %w", err)
 	}
 
@@ -64,6 +65,13 @@ func (s *TemplateService) GenerateFromTemplate(
 		return nil, fmt.Errorf("failed to get circuit: %w", err)
 	}
 
+	// The circuit type's registered schema applies too
+	if info, ok := s.circuitInfo(template.ProofSystem, circuit); ok && len(info.InputSchema) > 0 {
+		if err := validateInputs(info.InputSchema, req.Inputs); err != nil {
+			return nil, fmt.Errorf("invalid inputs for %s: %w", info.Name, err)
+		}
+	}
+
 	// Convert inputs to data format
 	inputJSON, err := json.Marshal(req.Inputs)
 	if err != nil {
@@ -105,11 +113,37 @@ func (s *TemplateService) Get(ctx context.Context, templateID uuid.UUID) (*model
 	return s.templateRepo.GetByID(ctx, templateID)
 }
 
-// validateInputs validates inputs against template schema
-func (s *TemplateService) validateInputs(template *models.Template, inputs map[string]interface{}) error {
+// circuitInfo returns the registered circuit type of a template's circuit
+func (s *TemplateService) circuitInfo(system models.ProofSystemType, circuit *models.Circuit) (prover.CircuitInfo, bool) {
+	var circuitDef struct {
+		CircuitType string `json:"circuit_type"`
+	}
+	if err := json.Unmarshal(circuit.CircuitDefinition, &circuitDef); err != nil {
+		return prover.CircuitInfo{}, false
+	}
+
+	proofSystem, err := s.proofService.factory.Get(system)
+	if err != nil {
+		return prover.CircuitInfo{}, false
+	}
+	catalog, ok := proofSystem.(prover.CircuitCatalog)
+	if !ok {
+		return prover.CircuitInfo{}, false
+	}
+
+	for _, info := range catalog.Circuits() {
+		if info.Name == circuitDef.CircuitType {
+			return info, true
+		}
+	}
+	return prover.CircuitInfo{}, false
+}
+
+// validateInputs validates inputs against a JSON schema's required fields
+func validateInputs(inputSchema json.RawMessage, inputs map[string]interface{}) error {
 	// Parse schema
 	var schema map[string]interface{}
-	if err := json.Unmarshal(template.InputSchema, &schema); err != nil {
+	if err := json.Unmarshal(inputSchema, &schema); err != nil {
 		return fmt.Errorf("failed to parse schema: %w", err)
 	}
 
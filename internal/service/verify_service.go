@@ -3,17 +3,22 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"runtime"
+	"sync"
 	"time"
 
 	"github.com/gabrielrondon/zapiki/internal/models"
 	"github.com/gabrielrondon/zapiki/internal/prover"
+	"github.com/gabrielrondon/zapiki/internal/storage/postgres"
 	"github.com/google/uuid"
 )
 
 // VerifyService handles proof verification logic
 type VerifyService struct {
-	factory *prover.Factory
+	factory     *prover.Factory
+	circuitRepo *postgres.CircuitRepository
 }
 
 // NewVerifyService creates a new verify service
@@ -23,12 +28,29 @@ func NewVerifyService(factory *prover.Factory) *VerifyService {
 	}
 }
 
+// SetCircuitRepository enables verifying against a circuit's stored key
+func (s *VerifyService) SetCircuitRepository(circuitRepo *postgres.CircuitRepository) {
+	s.circuitRepo = circuitRepo
+}
+
 // VerifyRequest represents a verification request
 type VerifyRequest struct {
 	ProofSystem     models.ProofSystemType `json:"proof_system"`
 	Proof           json.RawMessage        `json:"proof"`
 	VerificationKey json.RawMessage        `json:"verification_key"`
 	PublicInputs    json.RawMessage        `json:"public_inputs,omitempty"`
+	// CircuitID selects the circuit's stored key when VerificationKey is empty
+	CircuitID *uuid.UUID `json:"circuit_id,omitempty"`
+	// Curve is the curve a SNARK proof was generated on (default bn254)
+	Curve string `json:"curve,omitempty"`
+	// Format is the encoding of Proof, PublicInputs and VerificationKey:
+	// gnark (default), snarkjs or arkworks
+	Format string `json:"format,omitempty"`
+	// Envelope gives the proof with its proof system, public inputs, curve
+	// and circuit in place of those fields (see ApplyEnvelope)
+	Envelope *models.ProofEnvelope `json:"envelope,omitempty"`
+	// UserID is the caller, who must be able to use CircuitID's circuit
+	UserID uuid.UUID `json:"-"`
 }
 
 // VerifyResponse represents a verification response
@@ -40,17 +62,24 @@ type VerifyResponse struct {
 
 // Verify verifies a proof
 func (s *VerifyService) Verify(ctx context.Context, req *VerifyRequest) (*VerifyResponse, error) {
+	if err := req.ApplyEnvelope(); err != nil {
+		return nil, err
+	}
+
 	// Get the proof system
 	system, err := s.factory.Get(req.ProofSystem)
 	if err != nil {
 		return nil, fmt.Errorf("unsupported proof system: %w", err)
 	}
 
+	if err := checkProofFormat(system, req.Format); err != nil {
+		return nil, err
+	}
+
 	// Verify the proof
-	proverReq := &prover.VerifyRequest{
-		Proof:           req.Proof,
-		VerificationKey: req.VerificationKey,
-		PublicInputs:    req.PublicInputs,
+	proverReq, err := s.proverRequest(ctx, req, nil)
+	if err != nil {
+		return nil, err
 	}
 
 	proverResp, err := system.Verify(ctx, proverReq)
@@ -65,9 +94,242 @@ func (s *VerifyService) Verify(ctx context.Context, req *VerifyRequest) (*Verify
 	}, nil
 }
 
+// proverRequest builds the prover's request for a verification, loading
+// the circuit when its stored key is used. circuits, when not nil, caches
+// loaded circuits across the proofs of a batch.
+func (s *VerifyService) proverRequest(ctx context.Context, req *VerifyRequest, circuits map[uuid.UUID]*models.Circuit) (*prover.VerifyRequest, error) {
+	proverReq := &prover.VerifyRequest{
+		Proof:           req.Proof,
+		VerificationKey: req.VerificationKey,
+		PublicInputs:    req.PublicInputs,
+		Curve:           req.Curve,
+		Format:          req.Format,
+	}
+
+	if len(req.VerificationKey) == 0 && req.CircuitID != nil {
+		if s.circuitRepo == nil {
+			return nil, fmt.Errorf("circuit lookup is not available")
+		}
+		circuit, ok := circuits[*req.CircuitID]
+		if !ok {
+			var err error
+			circuit, err = s.circuitRepo.GetByID(ctx, *req.CircuitID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get circuit: %w", err)
+			}
+			if circuits != nil {
+				circuits[*req.CircuitID] = circuit
+			}
+		}
+		if err := CheckProofCircuit(circuit, req.UserID, req.ProofSystem); err != nil {
+			return nil, err
+		}
+		proverReq.VerificationKeyURL = circuit.VerificationKeyURL
+		proverReq.Circuit = circuit
+	}
+	return proverReq, nil
+}
+
+// checkProofFormat checks that a proof system reads proofs in format
+func checkProofFormat(system prover.ProofSystem, format string) error {
+	if format == "" || format == prover.FormatGnark {
+		return nil
+	}
+	if _, ok := system.(prover.ProofConverter); !ok {
+		return fmt.Errorf("%w: %s proofs cannot be read as %s", prover.ErrFormatUnsupported, system.Name(), format)
+	}
+	return nil
+}
+
 // VerifyProofByID verifies a proof by its ID
 func (s *VerifyService) VerifyProofByID(ctx context.Context, proofID uuid.UUID) (*VerifyResponse, error) {
 	// This would require fetching the proof from the database
 	// For now, return not implemented
 	return nil, fmt.Errorf("not implemented: use direct verification endpoint")
 }
+
+// ErrInvalidBatchVerify is returned for batch verification requests that
+// cannot be run
+var ErrInvalidBatchVerify = errors.New("invalid batch verification request")
+
+// Limits on batch verification
+const (
+	// MaxBatchVerifyProofs bounds the proofs in one batch verification
+	MaxBatchVerifyProofs = 5000
+	// batchVerifyChunk is how many proofs one worker hands to a proof
+	// system that verifies proofs together
+	batchVerifyChunk = 256
+)
+
+// BatchVerifyRequest asks to verify many proofs, of any proof systems
+type BatchVerifyRequest struct {
+	Proofs []VerifyRequest `json:"proofs"`
+	// UserID is the caller, see VerifyRequest
+	UserID uuid.UUID `json:"-"`
+}
+
+// BatchVerifyResult is the outcome for one proof of a batch
+type BatchVerifyResult struct {
+	Index        int    `json:"index"`
+	Valid        bool   `json:"valid"`
+	ErrorMessage string `json:"error_message,omitempty"`
+}
+
+// BatchVerifyResponse reports every proof of a batch, in request order
+type BatchVerifyResponse struct {
+	Results    []BatchVerifyResult `json:"results"`
+	Total      int                 `json:"total"`
+	Valid      int                 `json:"valid"`
+	Invalid    int                 `json:"invalid"`
+	VerifiedAt time.Time           `json:"verified_at"`
+}
+
+// batchVerifyJob is a share of a batch verified by one worker: one proof,
+// or a chunk of proofs for a proof system that verifies them together
+type batchVerifyJob struct {
+	system  prover.ProofSystem
+	indexes []int
+}
+
+// VerifyBatch verifies many proofs in parallel, at most
+// runtime.GOMAXPROCS(0) at a time. Proof systems implementing
+// prover.BatchVerifier receive their proofs in chunks. A proof that cannot
+// be verified is reported invalid in its result without failing the batch.
+func (s *VerifyService) VerifyBatch(ctx context.Context, req *BatchVerifyRequest) (*BatchVerifyResponse, error) {
+	if len(req.Proofs) == 0 || len(req.Proofs) > MaxBatchVerifyProofs {
+		return nil, fmt.Errorf("%w: between 1 and %d proofs are required", ErrInvalidBatchVerify, MaxBatchVerifyProofs)
+	}
+
+	results := make([]BatchVerifyResult, len(req.Proofs))
+	proverReqs := make([]*prover.VerifyRequest, len(req.Proofs))
+	circuits := make(map[uuid.UUID]*models.Circuit)
+	batches := make(map[models.ProofSystemType][]int)
+	var jobs []batchVerifyJob
+
+	for i := range req.Proofs {
+		item := &req.Proofs[i]
+		item.UserID = req.UserID
+		results[i].Index = i
+
+		if err := item.ApplyEnvelope(); err != nil {
+			results[i].ErrorMessage = err.Error()
+			continue
+		}
+
+		switch {
+		case item.ProofSystem == "":
+			results[i].ErrorMessage = "proof_system is required"
+			continue
+		case item.Proof == nil:
+			results[i].ErrorMessage = "proof is required"
+			continue
+		case item.VerificationKey == nil && item.CircuitID == nil:
+			results[i].ErrorMessage = "verification_key or circuit_id is required"
+			continue
+		}
+
+		system, err := s.factory.Get(item.ProofSystem)
+		if err != nil {
+			results[i].ErrorMessage = fmt.Sprintf("unsupported proof system: %v", err)
+			continue
+		}
+
+		if err := checkProofFormat(system, item.Format); err != nil {
+			results[i].ErrorMessage = err.Error()
+			continue
+		}
+
+		proverReqs[i], err = s.proverRequest(ctx, item, circuits)
+		if err != nil {
+			results[i].ErrorMessage = err.Error()
+			continue
+		}
+
+		if _, ok := system.(prover.BatchVerifier); ok {
+			batches[item.ProofSystem] = append(batches[item.ProofSystem], i)
+			continue
+		}
+		jobs = append(jobs, batchVerifyJob{system: system, indexes: []int{i}})
+	}
+
+	for systemType, indexes := range batches {
+		system, _ := s.factory.Get(systemType)
+		for start := 0; start < len(indexes); start += batchVerifyChunk {
+			end := min(start+batchVerifyChunk, len(indexes))
+			jobs = append(jobs, batchVerifyJob{system: system, indexes: indexes[start:end]})
+		}
+	}
+
+	jobCh := make(chan batchVerifyJob)
+	var wg sync.WaitGroup
+	for range min(runtime.GOMAXPROCS(0), len(jobs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobCh {
+				s.runBatchVerifyJob(ctx, job, proverReqs, results)
+			}
+		}()
+	}
+
+sendJobs:
+	for _, job := range jobs {
+		select {
+		case jobCh <- job:
+		case <-ctx.Done():
+			break sendJobs
+		}
+	}
+	close(jobCh)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	resp := &BatchVerifyResponse{
+		Results:    results,
+		Total:      len(results),
+		VerifiedAt: time.Now(),
+	}
+	for _, result := range results {
+		if result.Valid {
+			resp.Valid++
+		} else {
+			resp.Invalid++
+		}
+	}
+	return resp, nil
+}
+
+// runBatchVerifyJob verifies a job's proofs and records their results.
+// Jobs cover distinct indexes, so workers write results without locking.
+func (s *VerifyService) runBatchVerifyJob(ctx context.Context, job batchVerifyJob, proverReqs []*prover.VerifyRequest, results []BatchVerifyResult) {
+	if batchVerifier, ok := job.system.(prover.BatchVerifier); ok {
+		reqs := make([]*prover.VerifyRequest, len(job.indexes))
+		for i, index := range job.indexes {
+			reqs[i] = proverReqs[index]
+		}
+
+		resps, err := batchVerifier.VerifyBatch(ctx, reqs)
+		for i, index := range job.indexes {
+			if err != nil {
+				results[index].ErrorMessage = fmt.Sprintf("verification failed: %v", err)
+				continue
+			}
+			results[index].Valid = resps[i].Valid
+			results[index].ErrorMessage = resps[i].ErrorMessage
+		}
+		return
+	}
+
+	for _, index := range job.indexes {
+		resp, err := job.system.Verify(ctx, proverReqs[index])
+		if err != nil {
+			results[index].ErrorMessage = fmt.Sprintf("verification failed: %v", err)
+			continue
+		}
+		results[index].Valid = resp.Valid
+		results[index].ErrorMessage = resp.ErrorMessage
+	}
+}
@@ -0,0 +1,38 @@
+package artifact
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gabrielrondon/zapiki/internal/config"
+)
+
+// ErrNotFound is returned when an artifact does not exist
+var ErrNotFound = errors.New("artifact not found")
+
+// Store persists binary artifacts such as proving and verification keys.
+// Put returns a URL that can later be passed to Get or Delete; the URL is
+// what gets recorded on circuits (e.g. ProvingKeyURL).
+type Store interface {
+	// Put stores data under key and returns its URL
+	Put(ctx context.Context, key string, data []byte) (string, error)
+
+	// Get retrieves the artifact stored at url
+	Get(ctx context.Context, url string) ([]byte, error)
+
+	// Delete removes the artifact stored at url
+	Delete(ctx context.Context, url string) error
+}
+
+// New creates the artifact store selected by cfg.Backend
+func New(cfg *config.StorageConfig) (Store, error) {
+	switch cfg.Backend {
+	case "", "local":
+		return NewLocalStore(cfg.LocalDir)
+	case "s3":
+		return NewS3Store(cfg)
+	default:
+		return nil, fmt.Errorf("unsupported storage backend: %s", cfg.Backend)
+	}
+}
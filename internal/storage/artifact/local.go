@@ -0,0 +1,116 @@
+package artifact
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStore stores artifacts on the local filesystem under file:// URLs
+type LocalStore struct {
+	root string
+}
+
+// NewLocalStore creates a local store rooted at dir, creating it if needed
+func NewLocalStore(dir string) (*LocalStore, error) {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve artifact directory: %w", err)
+	}
+
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create artifact directory: %w", err)
+	}
+
+	return &LocalStore{root: root}, nil
+}
+
+// Put writes data under key
+func (s *LocalStore) Put(ctx context.Context, key string, data []byte) (string, error) {
+	path, err := s.resolve(key)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return "", fmt.Errorf("failed to create artifact directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to write artifact: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to write artifact: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write artifact: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("failed to write artifact: %w", err)
+	}
+
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String(), nil
+}
+
+// Get reads the artifact at rawURL
+func (s *LocalStore) Get(ctx context.Context, rawURL string) ([]byte, error) {
+	path, err := s.pathFromURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read artifact: %w", err)
+	}
+
+	return data, nil
+}
+
+// Delete removes the artifact at rawURL
+func (s *LocalStore) Delete(ctx context.Context, rawURL string) error {
+	path, err := s.pathFromURL(rawURL)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete artifact: %w", err)
+	}
+
+	return nil
+}
+
+// resolve maps a key to a path, refusing keys that escape the root
+func (s *LocalStore) resolve(key string) (string, error) {
+	path := filepath.Join(s.root, filepath.FromSlash(key))
+	if !strings.HasPrefix(path, s.root+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid artifact key: %s", key)
+	}
+	return path, nil
+}
+
+func (s *LocalStore) pathFromURL(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "file" {
+		return "", fmt.Errorf("unsupported artifact URL: %s", rawURL)
+	}
+
+	path := filepath.Clean(filepath.FromSlash(u.Path))
+	if !strings.HasPrefix(path, s.root+string(filepath.Separator)) {
+		return "", fmt.Errorf("artifact URL outside store root: %s", rawURL)
+	}
+
+	return path, nil
+}
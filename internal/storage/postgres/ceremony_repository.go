@@ -0,0 +1,176 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gabrielrondon/zapiki/internal/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrCeremonyNotFound is returned when no ceremony matches a lookup
+var ErrCeremonyNotFound = errors.New("ceremony not found")
+
+// ErrStaleCeremonyState is returned when a contribution was made on a state
+// that is no longer the ceremony's latest, or the ceremony has closed
+var ErrStaleCeremonyState = errors.New("ceremony state has changed")
+
+const ceremonyColumns = `
+	id, circuit_id, user_id, status, phase1, initial_hash, initial_state_url,
+	contribution_count, current_hash, beacon, verification_key_hash,
+	created_at, finalized_at`
+
+// CeremonyRepository handles setup ceremony database operations
+type CeremonyRepository struct {
+	store *Store
+}
+
+// NewCeremonyRepository creates a new ceremony repository
+func NewCeremonyRepository(store *Store) *CeremonyRepository {
+	return &CeremonyRepository{store: store}
+}
+
+// Create stores a newly opened ceremony
+func (r *CeremonyRepository) Create(ctx context.Context, c *models.Ceremony) error {
+	query := `
+		INSERT INTO setup_ceremonies (
+			id, circuit_id, user_id, status, phase1, initial_hash,
+			initial_state_url, contribution_count, current_hash, created_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, 0, $6, NOW()
+		)
+		RETURNING created_at
+	`
+
+	err := r.store.pool.QueryRow(ctx, query,
+		c.ID, c.CircuitID, c.UserID, c.Status, c.Phase1, c.InitialHash,
+		c.InitialStateURL,
+	).Scan(&c.CreatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create ceremony: %w", err)
+	}
+
+	c.CurrentHash = c.InitialHash
+	return nil
+}
+
+// GetByID retrieves a ceremony by ID
+func (r *CeremonyRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Ceremony, error) {
+	query := `SELECT ` + ceremonyColumns + ` FROM setup_ceremonies WHERE id = $1`
+	return r.getOne(ctx, query, id)
+}
+
+// GetByCircuit retrieves the ceremony for a circuit
+func (r *CeremonyRepository) GetByCircuit(ctx context.Context, circuitID uuid.UUID) (*models.Ceremony, error) {
+	query := `SELECT ` + ceremonyColumns + ` FROM setup_ceremonies WHERE circuit_id = $1`
+	return r.getOne(ctx, query, circuitID)
+}
+
+// AddContribution appends a contribution and advances the ceremony to its
+// state. It fails with ErrStaleCeremonyState unless the ceremony is open and
+// c.Index directly follows its latest contribution, so concurrent uploads
+// built on the same state cannot both be accepted.
+func (r *CeremonyRepository) AddContribution(ctx context.Context, c *models.CeremonyContribution) error {
+	query := `
+		WITH advanced AS (
+			UPDATE setup_ceremonies
+			SET contribution_count = $2, current_hash = $5
+			WHERE id = $1 AND status = 'open' AND contribution_count = $2 - 1
+			RETURNING id
+		)
+		INSERT INTO ceremony_contributions (
+			ceremony_id, contribution_index, user_id, participant, hash, state_url, created_at
+		)
+		SELECT id, $2, $3, $4, $5, $6, NOW() FROM advanced
+		RETURNING created_at
+	`
+
+	err := r.store.pool.QueryRow(ctx, query,
+		c.CeremonyID, c.Index, c.UserID, c.Participant, c.Hash, c.StateURL,
+	).Scan(&c.CreatedAt)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrStaleCeremonyState
+	}
+	if err != nil {
+		return fmt.Errorf("failed to add ceremony contribution: %w", err)
+	}
+
+	return nil
+}
+
+// ListContributions retrieves a ceremony's contributions in order
+func (r *CeremonyRepository) ListContributions(ctx context.Context, ceremonyID uuid.UUID) ([]*models.CeremonyContribution, error) {
+	query := `
+		SELECT ceremony_id, contribution_index, user_id, participant, hash, state_url, created_at
+		FROM ceremony_contributions
+		WHERE ceremony_id = $1
+		ORDER BY contribution_index
+	`
+
+	rows, err := r.store.pool.Query(ctx, query, ceremonyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ceremony contributions: %w", err)
+	}
+	defer rows.Close()
+
+	var contributions []*models.CeremonyContribution
+	for rows.Next() {
+		var c models.CeremonyContribution
+		err := rows.Scan(
+			&c.CeremonyID, &c.Index, &c.UserID, &c.Participant, &c.Hash, &c.StateURL, &c.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan ceremony contribution: %w", err)
+		}
+		contributions = append(contributions, &c)
+	}
+
+	return contributions, nil
+}
+
+// Finalize closes a ceremony with the beacon and resulting key hash. It
+// fails with ErrStaleCeremonyState if the ceremony is no longer open or has
+// received contributions beyond the ones that were finalized.
+func (r *CeremonyRepository) Finalize(ctx context.Context, c *models.Ceremony) error {
+	query := `
+		UPDATE setup_ceremonies
+		SET status = $2, beacon = $3, verification_key_hash = $4, finalized_at = NOW()
+		WHERE id = $1 AND status = 'open' AND contribution_count = $5
+		RETURNING finalized_at
+	`
+
+	err := r.store.pool.QueryRow(ctx, query,
+		c.ID, models.CeremonyStatusFinalized, c.Beacon, c.VerificationKeyHash, c.ContributionCount,
+	).Scan(&c.FinalizedAt)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrStaleCeremonyState
+	}
+	if err != nil {
+		return fmt.Errorf("failed to finalize ceremony: %w", err)
+	}
+
+	c.Status = models.CeremonyStatusFinalized
+	return nil
+}
+
+func (r *CeremonyRepository) getOne(ctx context.Context, query string, args ...interface{}) (*models.Ceremony, error) {
+	var c models.Ceremony
+	err := r.store.pool.QueryRow(ctx, query, args...).Scan(
+		&c.ID, &c.CircuitID, &c.UserID, &c.Status, &c.Phase1, &c.InitialHash, &c.InitialStateURL,
+		&c.ContributionCount, &c.CurrentHash, &c.Beacon, &c.VerificationKeyHash,
+		&c.CreatedAt, &c.FinalizedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrCeremonyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ceremony: %w", err)
+	}
+
+	return &c, nil
+}
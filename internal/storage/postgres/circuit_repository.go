@@ -24,9 +24,10 @@ func (r *CircuitRepository) Create(ctx context.Context, circuit *models.Circuit)
 		INSERT INTO circuits (
 			id, user_id, name, description, proof_system,
 			circuit_definition, proving_key_url, verification_key_url,
+			setup_status, setup_error, setup_completed_at, setup_metadata,
 			is_public, created_at, updated_at
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW()
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, NOW(), NOW()
 		)
 	`
 
@@ -34,7 +35,8 @@ func (r *CircuitRepository) Create(ctx context.Context, circuit *models.Circuit)
 		circuit.ID, circuit.UserID, circuit.Name, circuit.Description,
 		circuit.ProofSystem, circuit.CircuitDefinition,
 		circuit.ProvingKeyURL, circuit.VerificationKeyURL,
-		circuit.IsPublic,
+		circuit.SetupStatus, circuit.SetupError, circuit.SetupCompletedAt,
+		circuit.SetupMetadata, circuit.IsPublic,
 	)
 
 	if err != nil {
@@ -49,6 +51,7 @@ func (r *CircuitRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.
 	query := `
 		SELECT id, user_id, name, description, proof_system,
 			   circuit_definition, proving_key_url, verification_key_url,
+			   setup_status, setup_error, setup_completed_at, setup_metadata,
 			   is_public, created_at, updated_at
 		FROM circuits
 		WHERE id = $1
@@ -59,6 +62,7 @@ func (r *CircuitRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.
 		&circuit.ID, &circuit.UserID, &circuit.Name, &circuit.Description,
 		&circuit.ProofSystem, &circuit.CircuitDefinition,
 		&circuit.ProvingKeyURL, &circuit.VerificationKeyURL,
+		&circuit.SetupStatus, &circuit.SetupError, &circuit.SetupCompletedAt, &circuit.SetupMetadata,
 		&circuit.IsPublic, &circuit.CreatedAt, &circuit.UpdatedAt,
 	)
 
@@ -74,6 +78,7 @@ func (r *CircuitRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([
 	query := `
 		SELECT id, user_id, name, description, proof_system,
 			   circuit_definition, proving_key_url, verification_key_url,
+			   setup_status, setup_error, setup_completed_at, setup_metadata,
 			   is_public, created_at, updated_at
 		FROM circuits
 		WHERE user_id = $1
@@ -93,6 +98,7 @@ func (r *CircuitRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([
 			&circuit.ID, &circuit.UserID, &circuit.Name, &circuit.Description,
 			&circuit.ProofSystem, &circuit.CircuitDefinition,
 			&circuit.ProvingKeyURL, &circuit.VerificationKeyURL,
+			&circuit.SetupStatus, &circuit.SetupError, &circuit.SetupCompletedAt, &circuit.SetupMetadata,
 			&circuit.IsPublic, &circuit.CreatedAt, &circuit.UpdatedAt,
 		)
 		if err != nil {
@@ -109,6 +115,7 @@ func (r *CircuitRepository) ListByUserWithPublic(ctx context.Context, userID uui
 	query := `
 		SELECT id, user_id, name, description, proof_system,
 			   circuit_definition, proving_key_url, verification_key_url,
+			   setup_status, setup_error, setup_completed_at, setup_metadata,
 			   is_public, created_at, updated_at
 		FROM circuits
 		WHERE user_id = $1 OR is_public = true
@@ -128,6 +135,7 @@ func (r *CircuitRepository) ListByUserWithPublic(ctx context.Context, userID uui
 			&circuit.ID, &circuit.UserID, &circuit.Name, &circuit.Description,
 			&circuit.ProofSystem, &circuit.CircuitDefinition,
 			&circuit.ProvingKeyURL, &circuit.VerificationKeyURL,
+			&circuit.SetupStatus, &circuit.SetupError, &circuit.SetupCompletedAt, &circuit.SetupMetadata,
 			&circuit.IsPublic, &circuit.CreatedAt, &circuit.UpdatedAt,
 		)
 		if err != nil {
@@ -139,6 +147,32 @@ func (r *CircuitRepository) ListByUserWithPublic(ctx context.Context, userID uui
 	return circuits, nil
 }
 
+// UpdateSetup records the outcome of a circuit's setup
+func (r *CircuitRepository) UpdateSetup(ctx context.Context, circuit *models.Circuit) error {
+	query := `
+		UPDATE circuits
+		SET proving_key_url = $2, verification_key_url = $3,
+			setup_status = $4, setup_error = $5, setup_completed_at = $6,
+			setup_metadata = $7, circuit_definition = $8, updated_at = NOW()
+		WHERE id = $1
+	`
+
+	result, err := r.store.pool.Exec(ctx, query,
+		circuit.ID, circuit.ProvingKeyURL, circuit.VerificationKeyURL,
+		circuit.SetupStatus, circuit.SetupError, circuit.SetupCompletedAt,
+		circuit.SetupMetadata, circuit.CircuitDefinition,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update circuit setup: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("circuit not found")
+	}
+
+	return nil
+}
+
 // Delete deletes a circuit by ID
 func (r *CircuitRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	query := `DELETE FROM circuits WHERE id = $1`
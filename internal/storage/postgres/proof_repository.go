@@ -24,9 +24,9 @@ func (r *ProofRepository) Create(ctx context.Context, proof *models.Proof) error
 		INSERT INTO proofs (
 			id, user_id, circuit_id, template_id, proof_system, status,
 			input_data, proof_data, public_inputs, proof_url, error_message,
-			generation_time_ms, created_at, completed_at
+			generation_time_ms, sanctions_list_version_id, aggregation, envelope, created_at, completed_at
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17
 		)
 	`
 
@@ -34,7 +34,7 @@ func (r *ProofRepository) Create(ctx context.Context, proof *models.Proof) error
 		proof.ID, proof.UserID, proof.CircuitID, proof.TemplateID,
 		proof.ProofSystem, proof.Status, proof.InputData, proof.ProofData,
 		proof.PublicInputs, proof.ProofURL, proof.ErrorMessage,
-		proof.GenerationTimeMs, proof.CreatedAt, proof.CompletedAt,
+		proof.GenerationTimeMs, proof.SanctionsListVersionID, proof.Aggregation, proof.Envelope, proof.CreatedAt, proof.CompletedAt,
 	)
 
 	if err != nil {
@@ -49,7 +49,7 @@ func (r *ProofRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Pr
 	query := `
 		SELECT id, user_id, circuit_id, template_id, proof_system, status,
 			   input_data, proof_data, public_inputs, proof_url, error_message,
-			   generation_time_ms, created_at, completed_at
+			   generation_time_ms, sanctions_list_version_id, aggregation, envelope, created_at, completed_at
 		FROM proofs
 		WHERE id = $1
 	`
@@ -59,7 +59,7 @@ func (r *ProofRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Pr
 		&proof.ID, &proof.UserID, &proof.CircuitID, &proof.TemplateID,
 		&proof.ProofSystem, &proof.Status, &proof.InputData, &proof.ProofData,
 		&proof.PublicInputs, &proof.ProofURL, &proof.ErrorMessage,
-		&proof.GenerationTimeMs, &proof.CreatedAt, &proof.CompletedAt,
+		&proof.GenerationTimeMs, &proof.SanctionsListVersionID, &proof.Aggregation, &proof.Envelope, &proof.CreatedAt, &proof.CompletedAt,
 	)
 
 	if err != nil {
@@ -74,13 +74,15 @@ func (r *ProofRepository) Update(ctx context.Context, proof *models.Proof) error
 	query := `
 		UPDATE proofs
 		SET status = $1, proof_data = $2, public_inputs = $3, proof_url = $4,
-		    error_message = $5, generation_time_ms = $6, completed_at = $7
-		WHERE id = $8
+		    error_message = $5, generation_time_ms = $6, completed_at = $7,
+		    aggregation = $8, envelope = $9
+		WHERE id = $10
 	`
 
 	result, err := r.store.pool.Exec(ctx, query,
 		proof.Status, proof.ProofData, proof.PublicInputs, proof.ProofURL,
-		proof.ErrorMessage, proof.GenerationTimeMs, proof.CompletedAt, proof.ID,
+		proof.ErrorMessage, proof.GenerationTimeMs, proof.CompletedAt,
+		proof.Aggregation, proof.Envelope, proof.ID,
 	)
 
 	if err != nil {
@@ -99,7 +101,7 @@ func (r *ProofRepository) ListByUser(ctx context.Context, userID uuid.UUID, limi
 	query := `
 		SELECT id, user_id, circuit_id, template_id, proof_system, status,
 			   input_data, proof_data, public_inputs, proof_url, error_message,
-			   generation_time_ms, created_at, completed_at
+			   generation_time_ms, sanctions_list_version_id, aggregation, envelope, created_at, completed_at
 		FROM proofs
 		WHERE user_id = $1
 		ORDER BY created_at DESC
@@ -119,7 +121,7 @@ func (r *ProofRepository) ListByUser(ctx context.Context, userID uuid.UUID, limi
 			&proof.ID, &proof.UserID, &proof.CircuitID, &proof.TemplateID,
 			&proof.ProofSystem, &proof.Status, &proof.InputData, &proof.ProofData,
 			&proof.PublicInputs, &proof.ProofURL, &proof.ErrorMessage,
-			&proof.GenerationTimeMs, &proof.CreatedAt, &proof.CompletedAt,
+			&proof.GenerationTimeMs, &proof.SanctionsListVersionID, &proof.Aggregation, &proof.Envelope, &proof.CreatedAt, &proof.CompletedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan proof: %w", err)
@@ -0,0 +1,141 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gabrielrondon/zapiki/internal/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrSanctionsListNotFound is returned when no list version matches a lookup
+var ErrSanctionsListNotFound = errors.New("sanctions list version not found")
+
+const sanctionsListColumns = `
+	id, list_name, version, root, curve, hash_func, tree_depth,
+	entry_count, identifier_count, sources, identifiers_url,
+	effective_date, created_at`
+
+// SanctionsRepository handles sanctions list version database operations
+type SanctionsRepository struct {
+	store *Store
+}
+
+// NewSanctionsRepository creates a new sanctions repository
+func NewSanctionsRepository(store *Store) *SanctionsRepository {
+	return &SanctionsRepository{store: store}
+}
+
+// Create stores a new list version, numbering it after the list's latest
+// version. The assigned number is written back to v.Version.
+func (r *SanctionsRepository) Create(ctx context.Context, v *models.SanctionsListVersion) error {
+	query := `
+		INSERT INTO sanctions_list_versions (
+			id, list_name, version, root, curve, hash_func, tree_depth,
+			entry_count, identifier_count, sources, identifiers_url,
+			effective_date, created_at
+		) VALUES (
+			$1, $2,
+			(SELECT COALESCE(MAX(version), 0) + 1 FROM sanctions_list_versions WHERE list_name = $2),
+			$3, $4, $5, $6, $7, $8, $9, $10, $11, NOW()
+		)
+		RETURNING version, created_at
+	`
+
+	err := r.store.pool.QueryRow(ctx, query,
+		v.ID, v.ListName, v.Root, v.Curve, v.HashFunc, v.TreeDepth,
+		v.EntryCount, v.IdentifierCount, v.Sources, v.IdentifiersURL,
+		v.EffectiveDate,
+	).Scan(&v.Version, &v.CreatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create sanctions list version: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves a list version by ID
+func (r *SanctionsRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.SanctionsListVersion, error) {
+	query := `SELECT ` + sanctionsListColumns + ` FROM sanctions_list_versions WHERE id = $1`
+	return r.getOne(ctx, query, id)
+}
+
+// GetByRoot retrieves the most recent list version with the given root
+func (r *SanctionsRepository) GetByRoot(ctx context.Context, root string) (*models.SanctionsListVersion, error) {
+	query := `
+		SELECT ` + sanctionsListColumns + `
+		FROM sanctions_list_versions
+		WHERE root = $1
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+	return r.getOne(ctx, query, root)
+}
+
+// GetEffective retrieves the list version in effect at the given time
+func (r *SanctionsRepository) GetEffective(ctx context.Context, listName string, at time.Time) (*models.SanctionsListVersion, error) {
+	query := `
+		SELECT ` + sanctionsListColumns + `
+		FROM sanctions_list_versions
+		WHERE list_name = $1 AND effective_date <= $2
+		ORDER BY effective_date DESC, version DESC
+		LIMIT 1
+	`
+	return r.getOne(ctx, query, listName, at)
+}
+
+// List retrieves list versions, newest first, optionally for one list
+func (r *SanctionsRepository) List(ctx context.Context, listName string) ([]*models.SanctionsListVersion, error) {
+	query := `
+		SELECT ` + sanctionsListColumns + `
+		FROM sanctions_list_versions
+		WHERE $1 = '' OR list_name = $1
+		ORDER BY list_name, effective_date DESC, version DESC
+	`
+
+	rows, err := r.store.pool.Query(ctx, query, listName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sanctions list versions: %w", err)
+	}
+	defer rows.Close()
+
+	var versions []*models.SanctionsListVersion
+	for rows.Next() {
+		v, err := scanSanctionsListVersion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan sanctions list version: %w", err)
+		}
+		versions = append(versions, v)
+	}
+
+	return versions, nil
+}
+
+func (r *SanctionsRepository) getOne(ctx context.Context, query string, args ...interface{}) (*models.SanctionsListVersion, error) {
+	v, err := scanSanctionsListVersion(r.store.pool.QueryRow(ctx, query, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrSanctionsListNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sanctions list version: %w", err)
+	}
+
+	return v, nil
+}
+
+func scanSanctionsListVersion(row pgx.Row) (*models.SanctionsListVersion, error) {
+	var v models.SanctionsListVersion
+	err := row.Scan(
+		&v.ID, &v.ListName, &v.Version, &v.Root, &v.Curve, &v.HashFunc, &v.TreeDepth,
+		&v.EntryCount, &v.IdentifierCount, &v.Sources, &v.IdentifiersURL,
+		&v.EffectiveDate, &v.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
@@ -0,0 +1,42 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gabrielrondon/zapiki/internal/queue"
+	"github.com/gabrielrondon/zapiki/internal/service"
+	"github.com/hibiken/asynq"
+)
+
+// AggregationProcessor handles proof aggregation jobs
+type AggregationProcessor struct {
+	aggregationService *service.AggregationService
+}
+
+// NewAggregationProcessor creates a new proof aggregation processor
+func NewAggregationProcessor(aggregationService *service.AggregationService) *AggregationProcessor {
+	return &AggregationProcessor{
+		aggregationService: aggregationService,
+	}
+}
+
+// HandleProofAggregation processes proof aggregation jobs
+func (p *AggregationProcessor) HandleProofAggregation(ctx context.Context, task *asynq.Task) error {
+	var payload queue.ProofAggregationPayload
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", err)
+	}
+
+	fmt.Printf("Processing proof aggregation: %s\n", payload.ProofID)
+
+	startTime := time.Now()
+	if err := p.aggregationService.RunAggregation(ctx, payload.ProofID); err != nil {
+		return fmt.Errorf("proof aggregation failed: %w", err)
+	}
+
+	fmt.Printf("Proof aggregation completed: %s (took %dms)\n", payload.ProofID, time.Since(startTime).Milliseconds())
+	return nil
+}
@@ -9,23 +9,26 @@ import (
 	"github.com/gabrielrondon/zapiki/internal/models"
 	"github.com/gabrielrondon/zapiki/internal/prover"
 	"github.com/gabrielrondon/zapiki/internal/queue"
+	"github.com/gabrielrondon/zapiki/internal/service"
 	"github.com/gabrielrondon/zapiki/internal/storage/postgres"
 	"github.com/hibiken/asynq"
 )
 
 // Processor handles background job processing
 type Processor struct {
-	factory   *prover.Factory
-	proofRepo *postgres.ProofRepository
-	jobRepo   *postgres.JobRepository
+	factory     *prover.Factory
+	proofRepo   *postgres.ProofRepository
+	jobRepo     *postgres.JobRepository
+	circuitRepo *postgres.CircuitRepository
 }
 
 // NewProcessor creates a new job processor
-func NewProcessor(factory *prover.Factory, proofRepo *postgres.ProofRepository, jobRepo *postgres.JobRepository) *Processor {
+func NewProcessor(factory *prover.Factory, proofRepo *postgres.ProofRepository, jobRepo *postgres.JobRepository, circuitRepo *postgres.CircuitRepository) *Processor {
 	return &Processor{
-		factory:   factory,
-		proofRepo: proofRepo,
-		jobRepo:   jobRepo,
+		factory:     factory,
+		proofRepo:   proofRepo,
+		jobRepo:     jobRepo,
+		circuitRepo: circuitRepo,
 	}
 }
 
@@ -77,8 +80,22 @@ func (p *Processor) HandleProofGeneration(ctx context.Context, task *asynq.Task)
 	if proverReq.Options == nil {
 		proverReq.Options = make(map[string]interface{})
 	}
+	var circuit *models.Circuit
 	if payload.CircuitID != nil {
 		proverReq.Options["circuit_id"] = payload.CircuitID
+
+		// Load the circuit so the prover can use its stored keys
+		circuit, err = p.circuitRepo.GetByID(ctx, *payload.CircuitID)
+		if err != nil {
+			return p.handleError(ctx, proof, job, fmt.Errorf("failed to get circuit: %w", err))
+		}
+		if err := service.CheckProofCircuit(circuit, proof.UserID, payload.ProofSystem); err != nil {
+			return p.handleError(ctx, proof, job, err)
+		}
+		if circuit.SetupStatus != models.CircuitSetupReady {
+			return p.handleError(ctx, proof, job, fmt.Errorf("circuit is not ready: setup is %s", circuit.SetupStatus))
+		}
+		proverReq.Circuit = circuit
 	}
 	if payload.TemplateID != nil {
 		proverReq.Options["template_id"] = payload.TemplateID
@@ -97,6 +114,11 @@ func (p *Processor) HandleProofGeneration(ctx context.Context, task *asynq.Task)
 	now := time.Now()
 	proof.CompletedAt = &now
 
+	proof.Envelope, err = service.NewProofEnvelope(system, proof, circuit, proverResp, startTime)
+	if err != nil {
+		return p.handleError(ctx, proof, job, fmt.Errorf("failed to build proof envelope: %w", err))
+	}
+
 	if err := p.proofRepo.Update(ctx, proof); err != nil {
 		return fmt.Errorf("failed to update proof: %w", err)
 	}
@@ -0,0 +1,47 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gabrielrondon/zapiki/internal/queue"
+	"github.com/gabrielrondon/zapiki/internal/service"
+	"github.com/hibiken/asynq"
+)
+
+// SetupProcessor handles circuit setup jobs
+type SetupProcessor struct {
+	circuitService *service.CircuitService
+}
+
+// NewSetupProcessor creates a new circuit setup processor
+func NewSetupProcessor(circuitService *service.CircuitService) *SetupProcessor {
+	return &SetupProcessor{
+		circuitService: circuitService,
+	}
+}
+
+// HandleCircuitSetup processes circuit setup jobs
+func (p *SetupProcessor) HandleCircuitSetup(ctx context.Context, task *asynq.Task) error {
+	var payload queue.CircuitSetupPayload
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", err)
+	}
+
+	fmt.Printf("Processing circuit setup: %s\n", payload.CircuitID)
+
+	// The circuit stays pending while asynq has retries left
+	retried, _ := asynq.GetRetryCount(ctx)
+	maxRetry, ok := asynq.GetMaxRetry(ctx)
+	lastAttempt := !ok || retried >= maxRetry
+
+	startTime := time.Now()
+	if err := p.circuitService.RunSetup(ctx, payload.CircuitID, lastAttempt); err != nil {
+		return fmt.Errorf("circuit setup failed: %w", err)
+	}
+
+	fmt.Printf("Circuit setup completed: %s (took %dms)\n", payload.CircuitID, time.Since(startTime).Milliseconds())
+	return nil
+}
@@ -0,0 +1,107 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// CeremonyState is a serialized Groth16 ceremony state
+type CeremonyState struct {
+	Data  []byte
+	Index int    // contributions the state includes
+	Hash  string // hex SHA-256, the challenge the next contribution commits to
+}
+
+// CeremonyContribution is an accepted ceremony contribution
+type CeremonyContribution struct {
+	Index       int    `json:"index"`
+	Participant string `json:"participant,omitempty"`
+	Hash        string `json:"hash"`
+}
+
+// GetCeremonyState downloads the latest state of a setup ceremony and
+// checks it against the hash the server reports
+func (c *Client) GetCeremonyState(ctx context.Context, ceremonyID string) (*CeremonyState, error) {
+	req, err := c.newRawRequest(ctx, "GET", fmt.Sprintf("/api/v1/ceremonies/%s/state", ceremonyID), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ceremony state: %w", err)
+	}
+
+	state := &CeremonyState{Data: data, Hash: resp.Header.Get("X-Ceremony-State-Hash")}
+	sum := sha256.Sum256(data)
+	if state.Hash != hex.EncodeToString(sum[:]) {
+		return nil, fmt.Errorf("ceremony state does not match its reported hash")
+	}
+	if state.Index, err = strconv.Atoi(resp.Header.Get("X-Ceremony-Index")); err != nil {
+		return nil, fmt.Errorf("invalid ceremony index: %w", err)
+	}
+
+	return state, nil
+}
+
+// Contribute uploads a contribution made on the ceremony's latest state
+func (c *Client) Contribute(ctx context.Context, ceremonyID, participant string, contribution []byte) (*CeremonyContribution, error) {
+	path := fmt.Sprintf("/api/v1/ceremonies/%s/contributions", ceremonyID)
+	if participant != "" {
+		path += "?participant=" + url.QueryEscape(participant)
+	}
+
+	req, err := c.newRawRequest(ctx, "POST", path, bytes.NewReader(contribution))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	result := &CeremonyContribution{}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return result, nil
+}
+
+// newRawRequest creates an authenticated request with a non-JSON body
+func (c *Client) newRawRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+	return req, nil
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"time"
 )
 
@@ -43,6 +44,7 @@ const (
 	ProofSystemGroth16    ProofSystem = "groth16"
 	ProofSystemPLONK      ProofSystem = "plonk"
 	ProofSystemSTARK      ProofSystem = "stark"
+	ProofSystemPedersen   ProofSystem = "pedersen"
 )
 
 // GenerateProofRequest represents a request to generate a proof
@@ -68,14 +70,19 @@ type GenerateProofResponse struct {
 	GenerationTimeMs int64                  `json:"generation_time_ms,omitempty"`
 	JobID            string                 `json:"job_id,omitempty"`
 	Message          string                 `json:"message,omitempty"`
+	Envelope         json.RawMessage        `json:"envelope,omitempty"` // self-describing proof, once completed
 }
 
 // VerifyRequest represents a request to verify a proof
 type VerifyRequest struct {
-	ProofSystem     ProofSystem            `json:"proof_system"`
-	Proof           map[string]interface{} `json:"proof"`
-	VerificationKey map[string]interface{} `json:"verification_key"`
+	ProofSystem     ProofSystem            `json:"proof_system,omitempty"`
+	Proof           map[string]interface{} `json:"proof,omitempty"`
+	VerificationKey map[string]interface{} `json:"verification_key,omitempty"`
 	PublicInputs    []string               `json:"public_inputs,omitempty"`
+	Curve           string                 `json:"curve,omitempty"` // SNARK curve, default "bn254"
+	Format          string                 `json:"format,omitempty"` // "gnark" (default), "snarkjs" or "arkworks"
+	// Envelope, from a generated proof, replaces ProofSystem, Proof and PublicInputs
+	Envelope json.RawMessage `json:"envelope,omitempty"`
 }
 
 // VerifyResponse represents the response from verification
@@ -84,6 +91,21 @@ type VerifyResponse struct {
 	Error string `json:"error_message,omitempty"`
 }
 
+// BatchVerifyResult is the outcome for one proof of a batch
+type BatchVerifyResult struct {
+	Index int    `json:"index"` // position of the proof in the request
+	Valid bool   `json:"valid"`
+	Error string `json:"error_message,omitempty"`
+}
+
+// BatchVerifyResponse represents the response from batch verification
+type BatchVerifyResponse struct {
+	Results []BatchVerifyResult `json:"results"`
+	Total   int                 `json:"total"`
+	Valid   int                 `json:"valid"`
+	Invalid int                 `json:"invalid"`
+}
+
 // SystemInfo represents information about a proof system
 type SystemInfo struct {
 	Name         string       `json:"name"`
@@ -98,6 +120,7 @@ type Capabilities struct {
 	AsyncOnly              bool     `json:"async_only"`
 	TypicalGenerationTime  int64    `json:"typical_generation_time"`
 	MaxProofSize           int64    `json:"max_proof_size"`
+	Curves                 []string `json:"curves,omitempty"`
 	Features               []string `json:"features"`
 }
 
@@ -128,6 +151,39 @@ func (c *Client) GetProof(ctx context.Context, proofID string) (*GenerateProofRe
 	return resp, err
 }
 
+// Calldata is a call to a circuit's exported Solidity verifier
+type Calldata struct {
+	Function     string   `json:"function"`
+	Calldata     string   `json:"calldata"` // 0x-prefixed, including the selector
+	Proof        string   `json:"proof"`
+	PublicInputs []string `json:"public_inputs"`
+}
+
+// GetProofCalldata returns ABI-encoded calldata for verifying a proof on an EVM chain
+func (c *Client) GetProofCalldata(ctx context.Context, proofID string) (*Calldata, error) {
+	resp := &Calldata{}
+	err := c.doRequest(ctx, "GET", fmt.Sprintf("/api/v1/proofs/%s/calldata", proofID), nil, resp)
+	return resp, err
+}
+
+// ExportedProof is a proof converted for snarkjs or arkworks. With snarkjs
+// the fields are proof.json, public.json and verification_key.json; with
+// arkworks they hold base64 CanonicalSerialize bytes.
+type ExportedProof struct {
+	Format          string          `json:"format"`
+	Proof           json.RawMessage `json:"proof"`
+	PublicInputs    json.RawMessage `json:"public_inputs"`
+	VerificationKey json.RawMessage `json:"verification_key,omitempty"`
+}
+
+// ExportProof returns a Groth16 proof in format, "snarkjs" or "arkworks"
+func (c *Client) ExportProof(ctx context.Context, proofID, format string) (*ExportedProof, error) {
+	resp := &ExportedProof{}
+	path := fmt.Sprintf("/api/v1/proofs/%s?format=%s", proofID, url.QueryEscape(format))
+	err := c.doRequest(ctx, "GET", path, nil, resp)
+	return resp, err
+}
+
 // VerifyProof verifies a proof
 func (c *Client) VerifyProof(ctx context.Context, req *VerifyRequest) (*VerifyResponse, error) {
 	resp := &VerifyResponse{}
@@ -135,6 +191,14 @@ func (c *Client) VerifyProof(ctx context.Context, req *VerifyRequest) (*VerifyRe
 	return resp, err
 }
 
+// VerifyProofs verifies up to 5000 proofs in one request, reporting each
+func (c *Client) VerifyProofs(ctx context.Context, reqs []VerifyRequest) (*BatchVerifyResponse, error) {
+	resp := &BatchVerifyResponse{}
+	body := map[string]interface{}{"proofs": reqs}
+	err := c.doRequest(ctx, "POST", "/api/v1/verify/batch", body, resp)
+	return resp, err
+}
+
 // ListSystems lists available proof systems
 func (c *Client) ListSystems(ctx context.Context) ([]SystemInfo, error) {
 	var response struct {
@@ -0,0 +1,231 @@
+package client
+
+import (
+	"fmt"
+	stdhash "hash"
+	"math/big"
+	"strings"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/hash"
+
+	// Register the MiMC and Poseidon2 hashes of each curve
+	_ "github.com/consensys/gnark-crypto/ecc/bls12-377/fr/mimc"
+	_ "github.com/consensys/gnark-crypto/ecc/bls12-377/fr/poseidon2"
+	_ "github.com/consensys/gnark-crypto/ecc/bls12-381/fr/mimc"
+	_ "github.com/consensys/gnark-crypto/ecc/bls12-381/fr/poseidon2"
+	_ "github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+	_ "github.com/consensys/gnark-crypto/ecc/bn254/fr/poseidon2"
+	_ "github.com/consensys/gnark-crypto/ecc/bw6-761/fr/mimc"
+	_ "github.com/consensys/gnark-crypto/ecc/bw6-761/fr/poseidon2"
+)
+
+// HashFunction selects the SNARK-friendly hash of the hash_preimage and
+// merkle_proof circuits
+type HashFunction string
+
+const (
+	HashMiMC      HashFunction = "mimc"
+	HashPoseidon2 HashFunction = "poseidon2"
+)
+
+// maxMerkleDepth is the deepest merkle_proof circuit the API accepts
+const maxMerkleDepth = 32
+
+// hashes maps each curve to its MiMC and Poseidon2 hashes
+var hashes = map[ecc.ID]map[HashFunction]hash.Hash{
+	ecc.BN254:     {HashMiMC: hash.MIMC_BN254, HashPoseidon2: hash.POSEIDON2_BN254},
+	ecc.BLS12_381: {HashMiMC: hash.MIMC_BLS12_381, HashPoseidon2: hash.POSEIDON2_BLS12_381},
+	ecc.BLS12_377: {HashMiMC: hash.MIMC_BLS12_377, HashPoseidon2: hash.POSEIDON2_BLS12_377},
+	ecc.BW6_761:   {HashMiMC: hash.MIMC_BW6_761, HashPoseidon2: hash.POSEIDON2_BW6_761},
+}
+
+// hasher is a hash function over the scalar field of a curve, as the
+// circuits compute it: each value is written as one field element
+type hasher struct {
+	new     func() stdhash.Hash
+	modulus *big.Int
+}
+
+// newHasher returns fn over curve, a name such as "bn254" or "bls12_381"
+// ("" is BN254, the default curve of the Groth16 and PLONK provers)
+func newHasher(curve string, fn HashFunction) (*hasher, error) {
+	id := ecc.BN254
+	if curve != "" {
+		var err error
+		if id, err = ecc.IDFromString(strings.ReplaceAll(curve, "-", "_")); err != nil {
+			return nil, fmt.Errorf("unsupported curve %q", curve)
+		}
+	}
+	if fn == "" {
+		fn = HashMiMC
+	}
+
+	byFunc, ok := hashes[id]
+	if !ok {
+		return nil, fmt.Errorf("unsupported curve %q", curve)
+	}
+	h, ok := byFunc[fn]
+	if !ok {
+		return nil, fmt.Errorf("unsupported hash function: %s", fn)
+	}
+
+	return &hasher{new: h.New, modulus: id.ScalarField()}, nil
+}
+
+// sum hashes values, each a field element
+func (h *hasher) sum(values ...*big.Int) (*big.Int, error) {
+	d := h.new()
+	block := make([]byte, d.BlockSize())
+	for _, v := range values {
+		if v.Sign() < 0 || v.Cmp(h.modulus) >= 0 {
+			return nil, fmt.Errorf("value %s is not a field element", v)
+		}
+		v.FillBytes(block)
+		if _, err := d.Write(block); err != nil {
+			return nil, fmt.Errorf("failed to hash value: %w", err)
+		}
+	}
+
+	return new(big.Int).SetBytes(d.Sum(nil)), nil
+}
+
+// Hash computes the hash_value of a hash_preimage proof over the proof's
+// curve ("" for the default, BN254)
+func Hash(curve string, fn HashFunction, values ...*big.Int) (*big.Int, error) {
+	h, err := newHasher(curve, fn)
+	if err != nil {
+		return nil, err
+	}
+	return h.sum(values...)
+}
+
+// MerkleProof holds the merkle_proof inputs for one leaf
+type MerkleProof struct {
+	Root       *big.Int
+	Leaf       *big.Int
+	Path       []*big.Int
+	Directions []uint // 1 when the node is a right child
+}
+
+// Inputs returns the proof as merkle_proof witness inputs
+func (p *MerkleProof) Inputs() map[string]interface{} {
+	path := make([]string, len(p.Path))
+	for i, v := range p.Path {
+		path[i] = v.String()
+	}
+
+	return map[string]interface{}{
+		"root":       p.Root.String(),
+		"leaf":       p.Leaf.String(),
+		"path":       path,
+		"directions": p.Directions,
+	}
+}
+
+// NewMerkleProof builds a tree of the given depth over leaves (padded with
+// zeros) on curve and returns the proof for the leaf at index. Leaves are
+// hashed as H(v) and nodes as H(left, right), as in the merkle_proof circuit.
+func NewMerkleProof(curve string, fn HashFunction, depth int, leaves []*big.Int, index int) (*MerkleProof, error) {
+	if depth < 1 || depth > maxMerkleDepth {
+		return nil, fmt.Errorf("tree depth must be between 1 and %d", maxMerkleDepth)
+	}
+	if uint64(len(leaves)) > uint64(1)<<depth {
+		return nil, fmt.Errorf("%d leaves do not fit a tree of depth %d", len(leaves), depth)
+	}
+	if index < 0 || uint64(index) >= uint64(1)<<depth {
+		return nil, fmt.Errorf("leaf index %d is outside a tree of depth %d", index, depth)
+	}
+
+	h, err := newHasher(curve, fn)
+	if err != nil {
+		return nil, err
+	}
+
+	level := make([]*big.Int, len(leaves))
+	for i, leaf := range leaves {
+		if level[i], err = h.sum(leaf); err != nil {
+			return nil, err
+		}
+	}
+	pad, err := h.sum(big.NewInt(0))
+	if err != nil {
+		return nil, err
+	}
+
+	// Nodes past the end of a level are all padding
+	proof := &MerkleProof{
+		Leaf:       big.NewInt(0),
+		Path:       make([]*big.Int, depth),
+		Directions: make([]uint, depth),
+	}
+	if index < len(leaves) {
+		proof.Leaf = leaves[index]
+	}
+
+	node := uint64(index)
+	for d := 0; d < depth; d++ {
+		proof.Directions[d] = uint(node & 1)
+		proof.Path[d] = pad
+		if sibling := node ^ 1; sibling < uint64(len(level)) {
+			proof.Path[d] = level[sibling]
+		}
+
+		next := make([]*big.Int, (len(level)+1)/2)
+		for i := range next {
+			right := pad
+			if 2*i+1 < len(level) {
+				right = level[2*i+1]
+			}
+			if next[i], err = h.sum(level[2*i], right); err != nil {
+				return nil, err
+			}
+		}
+		if pad, err = h.sum(pad, pad); err != nil {
+			return nil, err
+		}
+
+		level = next
+		node >>= 1
+	}
+
+	// An empty tree is all padding
+	proof.Root = pad
+	if len(level) > 0 {
+		proof.Root = level[0]
+	}
+
+	return proof, nil
+}
+
+// MerkleRoot recomputes the root a merkle_proof is checked against
+func MerkleRoot(curve string, fn HashFunction, leaf *big.Int, directions []uint, path []*big.Int) (*big.Int, error) {
+	if len(directions) != len(path) {
+		return nil, fmt.Errorf("got %d directions for a path of %d", len(directions), len(path))
+	}
+
+	h, err := newHasher(curve, fn)
+	if err != nil {
+		return nil, err
+	}
+
+	node, err := h.sum(leaf)
+	if err != nil {
+		return nil, err
+	}
+	for i, sibling := range path {
+		switch directions[i] {
+		case 0:
+			node, err = h.sum(node, sibling)
+		case 1:
+			node, err = h.sum(sibling, node)
+		default:
+			return nil, fmt.Errorf("direction %d is not a bit", directions[i])
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return node, nil
+}
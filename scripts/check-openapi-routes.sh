@@ -12,6 +12,7 @@ fi
 required_paths=(
   "/health"
   "/metrics"
+  "/keys/{system}"
   "/portal"
   "/api/v1/systems"
   "/api/v1/plans"
@@ -20,12 +21,27 @@ required_paths=(
   "/api/v1/portal/overview"
   "/api/v1/proofs"
   "/api/v1/proofs/{id}"
+  "/api/v1/proofs/{id}/calldata"
   "/api/v1/proofs/batch"
   "/api/v1/verify"
+  "/api/v1/commitments/open"
+  "/api/v1/commitments/equality"
+  "/api/v1/commitments/equality/verify"
+  "/api/v1/commitments/batch"
+  "/api/v1/commitments/batch/verify"
+  "/api/v1/commitments/aggregate"
   "/api/v1/jobs"
   "/api/v1/jobs/{id}"
   "/api/v1/circuits"
   "/api/v1/circuits/{id}"
+  "/api/v1/circuits/{id}/setup"
+  "/api/v1/circuits/{id}/verifier.sol"
+  "/api/v1/circuits/{id}/ceremony"
+  "/api/v1/ceremonies/{id}"
+  "/api/v1/ceremonies/{id}/state"
+  "/api/v1/ceremonies/{id}/contributions"
+  "/api/v1/ceremonies/{id}/contributions/{index}"
+  "/api/v1/ceremonies/{id}/finalize"
   "/api/v1/templates"
   "/api/v1/templates/categories"
   "/api/v1/templates/{id}"
@@ -34,6 +50,9 @@ required_paths=(
   "/api/v1/aml/sanctions-check"
   "/api/v1/aml/residency-proof"
   "/api/v1/aml/income-verification"
+  "/api/v1/aml/sanctions-lists"
+  "/api/v1/aml/sanctions-lists/{id}"
+  "/api/v1/aml/sanctions-lists/{id}/witness"
 )
 
 missing=0